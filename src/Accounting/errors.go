@@ -0,0 +1,19 @@
+package Accounting
+
+import (
+	"errors"
+
+	"savannah/src/ErrorCodes"
+)
+
+var (
+	ErrorNotFound            = errors.New("sync item not found")
+	ErrorUnknownDocumentType = errors.New("unknown document type")
+)
+
+func init() {
+	ErrorCodes.Register("ACCOUNTING",
+		ErrorCodes.Def{N: 1, Slug: "SYNC_ITEM_NOT_FOUND", Err: ErrorNotFound},
+		ErrorCodes.Def{N: 2, Slug: "UNKNOWN_DOCUMENT_TYPE", Err: ErrorUnknownDocumentType},
+	)
+}
@@ -0,0 +1,41 @@
+package Accounting
+
+import (
+	"context"
+	"strings"
+
+	"savannah/src/Billing"
+)
+
+// Exporter posts billing documents to an accounting system and returns the
+// id the remote system assigned to the document. A document is posted again
+// when its sync couldn't be recorded, so exporters key each post by the
+// document's id to keep the remote system from booking it twice.
+type Exporter interface {
+	Name() string
+	PostInvoice(ctx context.Context, inv *Billing.Invoice) (string, error)
+	PostPayment(ctx context.Context, p *Billing.Payment, inv *Billing.Invoice) (string, error)
+}
+
+// taxMemo is the buyer/seller tax identity and reverse charge note of an
+// invoice as one line for the accounting system; empty when there is none.
+func taxMemo(inv *Billing.Invoice) string {
+	var parts []string
+	if inv.BuyerCompany != nil {
+		parts = append(parts, "Buyer: "+*inv.BuyerCompany)
+	}
+	if inv.BuyerTaxID != nil {
+		label := "Tax ID"
+		if inv.BuyerTaxIDType != nil {
+			label = *inv.BuyerTaxIDType
+		}
+		parts = append(parts, "Buyer "+label+": "+*inv.BuyerTaxID)
+	}
+	if inv.SellerTaxID != nil {
+		parts = append(parts, "Seller tax ID: "+*inv.SellerTaxID)
+	}
+	if inv.TaxNote != nil {
+		parts = append(parts, *inv.TaxNote)
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,46 @@
+package Accounting
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"savannah/src/Billing"
+)
+
+// FileExporter is the fallback when no accounting system is configured: it
+// appends documents as JSON lines to a daily file that finance can import.
+type FileExporter struct {
+	Dir string
+	mu  sync.Mutex
+}
+
+func NewFileExporter(dir string) *FileExporter { return &FileExporter{Dir: dir} }
+
+func (f *FileExporter) Name() string { return "file" }
+
+func (f *FileExporter) PostInvoice(ctx context.Context, inv *Billing.Invoice) (string, error) {
+	return "file:" + inv.ID.String(), f.append("invoices", inv)
+}
+
+func (f *FileExporter) PostPayment(ctx context.Context, p *Billing.Payment, inv *Billing.Invoice) (string, error) {
+	return "file:" + p.ID.String(), f.append("payments", map[string]interface{}{"payment": p, "invoice_number": inv.InvoiceNumber})
+}
+
+func (f *FileExporter) append(kind string, v interface{}) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := os.MkdirAll(f.Dir, 0o755); err != nil {
+		return err
+	}
+	name := filepath.Join(f.Dir, kind+"-"+time.Now().UTC().Format("20060102")+".jsonl")
+	file, err := os.OpenFile(name, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return json.NewEncoder(file).Encode(v)
+}
@@ -0,0 +1,87 @@
+package Accounting
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"savannah/src/ErrorCodes"
+	"savannah/src/Pagination"
+)
+
+type Handler struct {
+	svc Service
+	log *zap.Logger
+}
+
+func NewHandler(s Service, log *zap.Logger) *Handler {
+	return &Handler{svc: s, log: log}
+}
+
+// List godoc
+// @Summary      List accounting sync queue
+// @Description  Lists invoices/payments queued for the accounting system, optionally filtered by status (PENDING, SYNCED, FAILED)
+// @Tags         accounting
+// @Produce      json
+// @Param        status  query     string  false  "Sync status"
+// @Param        limit   query     int     false  "Page size (max 100)"
+// @Param        offset  query     int     false  "Offset"
+// @Param        page    query     int     false  "Page of limit items, 1-based; instead of offset"
+// @Success      200     {array}   SyncItem
+// @Header       200     {string}  Link    "first, prev, next and last pages (RFC 5988), with X-Total-Count, X-Page and X-Total-Pages"
+// @Router       /accounting/sync [get]
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	limit, offset := Pagination.Params(r.URL.Query(), 20, 100)
+	status := r.URL.Query().Get("status")
+	items, err := h.svc.List(r.Context(), status, limit, offset)
+	var total int
+	if err == nil {
+		total, err = h.svc.Count(r.Context(), status)
+	}
+	if err != nil {
+		h.log.Error("list accounting sync", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to list")
+		return
+	}
+	Pagination.Write(w, r, limit, offset, total)
+	h.writeJSON(w, http.StatusOK, items)
+}
+
+// Retry godoc
+// @Summary      Retry a document sync
+// @Description  Resets attempts and schedules the document for immediate sync
+// @Tags         accounting
+// @Param        id   path      string  true  "Sync item ID"
+// @Success      202
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /accounting/sync/{id}/retry [post]
+func (h *Handler) Retry(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	if err := h.svc.Retry(r.Context(), id); err != nil {
+		if err == ErrorNotFound {
+			h.writeError(w, http.StatusNotFound, "not found")
+			return
+		}
+		h.log.Error("retry accounting sync", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to retry")
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *Handler) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (h *Handler) writeError(w http.ResponseWriter, status int, msg string) {
+	h.writeJSON(w, status, map[string]interface{}{"error": msg, "code": ErrorCodes.Resolve("ACCOUNTING", status, msg), "timestamp": time.Now().UTC()})
+}
@@ -0,0 +1,36 @@
+package Accounting
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// document types
+const (
+	DocumentInvoice = "INVOICE"
+	DocumentPayment = "PAYMENT"
+)
+
+// sync statuses, mirrored onto invoices.erp_sync_status / payments.erp_sync_status
+const (
+	StatusPending = "PENDING"
+	StatusSynced  = "SYNCED"
+	StatusFailed  = "FAILED"
+)
+
+// SyncItem is one document waiting to be posted to the accounting system
+type SyncItem struct {
+	ID            uuid.UUID `db:"id" json:"id"`
+	DocumentType  string    `db:"document_type" json:"document_type"`
+	DocumentID    uuid.UUID `db:"document_id" json:"document_id"`
+	Status        string    `db:"status" json:"status"`
+	Attempts      int       `db:"attempts" json:"attempts"`
+	NextAttemptAt time.Time `db:"next_attempt_at" json:"next_attempt_at"`
+	ExternalID    *string   `db:"external_id" json:"external_id,omitempty"`
+	LastError     *string   `db:"last_error" json:"last_error,omitempty"`
+	CreatedAt     time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt     time.Time `db:"updated_at" json:"updated_at"`
+}
+
+const QueueTable = "accounting_sync_queue"
@@ -0,0 +1,112 @@
+package Accounting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"savannah/src/Billing"
+)
+
+// QuickBooksExporter posts to the QuickBooks Online accounting API. Orders
+// are not mapped to QuickBooks customers yet, so every document is booked
+// against a configured customer and sales item.
+type QuickBooksExporter struct {
+	BaseURL     string
+	RealmID     string
+	AccessToken string
+	CustomerRef string
+	ItemRef     string
+	client      *http.Client
+}
+
+func NewQuickBooksExporter(baseURL, realmID, accessToken, customerRef, itemRef string) *QuickBooksExporter {
+	if baseURL == "" {
+		baseURL = "https://quickbooks.api.intuit.com"
+	}
+	return &QuickBooksExporter{BaseURL: strings.TrimRight(baseURL, "/"), RealmID: realmID, AccessToken: accessToken, CustomerRef: customerRef, ItemRef: itemRef, client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+func (q *QuickBooksExporter) Name() string { return "quickbooks" }
+
+func (q *QuickBooksExporter) PostInvoice(ctx context.Context, inv *Billing.Invoice) (string, error) {
+	body := map[string]interface{}{
+		"DocNumber":   inv.InvoiceNumber,
+		"TxnDate":     inv.IssuedAt.Format("2006-01-02"),
+		"CustomerRef": map[string]string{"value": q.CustomerRef},
+		"CurrencyRef": map[string]string{"value": inv.Currency},
+		"Line": []map[string]interface{}{{
+			"Amount":              inv.Amount,
+			"DetailType":          "SalesItemLineDetail",
+			"Description":         "Order " + inv.OrderID.String(),
+			"SalesItemLineDetail": map[string]interface{}{"ItemRef": map[string]string{"value": q.ItemRef}},
+		}},
+	}
+	if inv.DueAt != nil {
+		body["DueDate"] = inv.DueAt.Format("2006-01-02")
+	}
+	if memo := taxMemo(inv); memo != "" {
+		body["CustomerMemo"] = map[string]string{"value": memo}
+	}
+	var out struct {
+		Invoice struct {
+			ID string `json:"Id"`
+		} `json:"Invoice"`
+	}
+	if err := q.post(ctx, "invoice", inv.ID.String(), body, &out); err != nil {
+		return "", err
+	}
+	return out.Invoice.ID, nil
+}
+
+func (q *QuickBooksExporter) PostPayment(ctx context.Context, p *Billing.Payment, inv *Billing.Invoice) (string, error) {
+	body := map[string]interface{}{
+		"TotalAmt":         p.Amount,
+		"TxnDate":          p.CreatedAt.Format("2006-01-02"),
+		"CustomerRef":      map[string]string{"value": q.CustomerRef},
+		"CurrencyRef":      map[string]string{"value": p.Currency},
+		"PaymentRefNum":    p.ID.String(),
+		"PrivateNote":      fmt.Sprintf("%s payment for invoice %s", p.Provider, inv.InvoiceNumber),
+		"PaymentMethodRef": map[string]string{"name": p.Provider},
+	}
+	var out struct {
+		Payment struct {
+			ID string `json:"Id"`
+		} `json:"Payment"`
+	}
+	if err := q.post(ctx, "payment", p.ID.String(), body, &out); err != nil {
+		return "", err
+	}
+	return out.Payment.ID, nil
+}
+
+// post creates an entity once per requestID: QuickBooks answers a repeated
+// request id with the entity it already created, so a document posted again
+// after its sync couldn't be recorded is not booked twice
+func (q *QuickBooksExporter) post(ctx context.Context, entity, requestID string, body interface{}, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	url := fmt.Sprintf("%s/v3/company/%s/%s?minorversion=65&requestid=%s", q.BaseURL, q.RealmID, entity, requestID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+q.AccessToken)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	res, err := q.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("quickbooks %s: status %d", entity, res.StatusCode)
+	}
+	return json.NewDecoder(res.Body).Decode(out)
+}
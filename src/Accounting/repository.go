@@ -0,0 +1,158 @@
+package Accounting
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+	"savannah/src/Billing"
+)
+
+type Repository interface {
+	Enqueue(ctx context.Context, documentType string, documentID uuid.UUID) error
+	ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]SyncItem, error)
+	MarkSynced(ctx context.Context, item *SyncItem, externalID string) error
+	MarkFailed(ctx context.Context, item *SyncItem, cause string, nextAttempt time.Time, dead bool) error
+	Requeue(ctx context.Context, id uuid.UUID) error
+	List(ctx context.Context, status string, limit, offset int) ([]SyncItem, error)
+	Count(ctx context.Context, status string) (int, error)
+	GetInvoice(ctx context.Context, id uuid.UUID) (*Billing.Invoice, error)
+	GetPayment(ctx context.Context, id uuid.UUID) (*Billing.Payment, error)
+}
+
+type repository struct {
+	db  *sqlx.DB
+	log *zap.Logger
+}
+
+func NewRepository(db *sqlx.DB, log *zap.Logger) Repository { return &repository{db: db, log: log} }
+
+const queueColumns = `id,document_type,document_id,status,attempts,next_attempt_at,external_id,last_error,created_at,updated_at`
+
+func (r *repository) Enqueue(ctx context.Context, documentType string, documentID uuid.UUID) error {
+	now := time.Now().UTC()
+	query := fmt.Sprintf(`INSERT INTO %s (id,document_type,document_id,status,attempts,next_attempt_at,created_at,updated_at) VALUES ($1,$2,$3,$4,0,$5,$5,$5) ON CONFLICT (document_type,document_id) DO NOTHING`, QueueTable)
+	_, err := r.db.ExecContext(ctx, query, uuid.New(), documentType, documentID, StatusPending, now)
+	return err
+}
+
+// ClaimDue leases due items by pushing next_attempt_at forward, so concurrent
+// workers never post the same document twice.
+func (r *repository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]SyncItem, error) {
+	query := fmt.Sprintf(`UPDATE %[1]s SET next_attempt_at=$1, updated_at=NOW() WHERE id IN (
+		SELECT id FROM %[1]s WHERE status=$2 AND next_attempt_at <= NOW() ORDER BY next_attempt_at LIMIT $3 FOR UPDATE SKIP LOCKED
+	) RETURNING %[2]s`, QueueTable, queueColumns)
+	items := []SyncItem{}
+	err := r.db.SelectContext(ctx, &items, query, time.Now().UTC().Add(lease), StatusPending, limit)
+	return items, err
+}
+
+func (r *repository) MarkSynced(ctx context.Context, item *SyncItem, externalID string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	query := fmt.Sprintf(`UPDATE %s SET status=$1, attempts=attempts+1, external_id=$2, last_error=NULL, updated_at=NOW() WHERE id=$3`, QueueTable)
+	if _, err = tx.ExecContext(ctx, query, StatusSynced, externalID, item.ID); err != nil {
+		return err
+	}
+	if err = r.setDocumentStatus(ctx, tx, item, StatusSynced); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *repository) MarkFailed(ctx context.Context, item *SyncItem, cause string, nextAttempt time.Time, dead bool) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	status := StatusPending
+	if dead {
+		status = StatusFailed
+	}
+	query := fmt.Sprintf(`UPDATE %s SET status=$1, attempts=attempts+1, last_error=$2, next_attempt_at=$3, updated_at=NOW() WHERE id=$4`, QueueTable)
+	if _, err = tx.ExecContext(ctx, query, status, cause, nextAttempt, item.ID); err != nil {
+		return err
+	}
+	if err = r.setDocumentStatus(ctx, tx, item, status); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *repository) setDocumentStatus(ctx context.Context, tx *sqlx.Tx, item *SyncItem, status string) error {
+	table := "invoices"
+	if item.DocumentType == DocumentPayment {
+		table = "payments"
+	}
+	_, err := tx.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET erp_sync_status=$1 WHERE id=$2`, table), status, item.DocumentID)
+	return err
+}
+
+func (r *repository) Requeue(ctx context.Context, id uuid.UUID) error {
+	query := fmt.Sprintf(`UPDATE %s SET status=$1, attempts=0, next_attempt_at=NOW(), updated_at=NOW() WHERE id=$2 AND status <> $3`, QueueTable)
+	res, err := r.db.ExecContext(ctx, query, StatusPending, id, StatusSynced)
+	if err != nil {
+		return err
+	}
+	n, _ := res.RowsAffected()
+	if n == 0 {
+		return ErrorNotFound
+	}
+	return nil
+}
+
+func (r *repository) List(ctx context.Context, status string, limit, offset int) ([]SyncItem, error) {
+	base := fmt.Sprintf(`SELECT %s FROM %s WHERE 1=1`, queueColumns, QueueTable)
+	args := []interface{}{}
+	idx := 1
+	if status != "" {
+		base += fmt.Sprintf(" AND status = $%d", idx)
+		args = append(args, status)
+		idx++
+	}
+	base += fmt.Sprintf(" ORDER BY updated_at DESC LIMIT $%d OFFSET $%d", idx, idx+1)
+	args = append(args, limit, offset)
+	items := []SyncItem{}
+	err := r.db.SelectContext(ctx, &items, base, args...)
+	return items, err
+}
+
+func (r *repository) Count(ctx context.Context, status string) (int, error) {
+	var n int
+	err := r.db.GetContext(ctx, &n, fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE ($1 = '' OR status = $1)`, QueueTable), status)
+	return n, err
+}
+
+func (r *repository) GetInvoice(ctx context.Context, id uuid.UUID) (*Billing.Invoice, error) {
+	var inv Billing.Invoice
+	err := r.db.GetContext(ctx, &inv, `SELECT `+Billing.InvoiceColumns+` FROM invoices WHERE id=$1`, id)
+	if err == sql.ErrNoRows {
+		return nil, ErrorNotFound
+	}
+	return &inv, err
+}
+
+func (r *repository) GetPayment(ctx context.Context, id uuid.UUID) (*Billing.Payment, error) {
+	var p Billing.Payment
+	err := r.db.GetContext(ctx, &p, `SELECT `+Billing.PaymentColumns+` FROM payments WHERE id=$1`, id)
+	if err == sql.ErrNoRows {
+		return nil, ErrorNotFound
+	}
+	return &p, err
+}
@@ -0,0 +1,112 @@
+package Accounting
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+type Service interface {
+	Enqueue(ctx context.Context, documentType string, documentID uuid.UUID) error
+	ProcessDue(ctx context.Context) error
+	Retry(ctx context.Context, id uuid.UUID) error
+	List(ctx context.Context, status string, limit, offset int) ([]SyncItem, error)
+	Count(ctx context.Context, status string) (int, error)
+}
+
+type service struct {
+	repo     Repository
+	exporter Exporter
+	log      *zap.Logger
+}
+
+func NewService(r Repository, e Exporter, log *zap.Logger) Service {
+	return &service{repo: r, exporter: e, log: log}
+}
+
+const (
+	batchSize   = 50
+	leaseFor    = 5 * time.Minute
+	maxAttempts = 8
+	maxBackoff  = 6 * time.Hour
+)
+
+func (s *service) Enqueue(ctx context.Context, documentType string, documentID uuid.UUID) error {
+	if documentType != DocumentInvoice && documentType != DocumentPayment {
+		return ErrorUnknownDocumentType
+	}
+	return s.repo.Enqueue(ctx, documentType, documentID)
+}
+
+// ProcessDue posts every due document; failures are rescheduled with
+// exponential backoff and parked as FAILED after maxAttempts.
+func (s *service) ProcessDue(ctx context.Context) error {
+	items, err := s.repo.ClaimDue(ctx, batchSize, leaseFor)
+	if err != nil {
+		return err
+	}
+	for i := range items {
+		item := &items[i]
+		externalID, err := s.post(ctx, item)
+		if err == nil {
+			if err := s.repo.MarkSynced(ctx, item, externalID); err != nil {
+				s.log.Error("mark accounting sync", zap.String("id", item.ID.String()), zap.Error(err))
+			}
+			continue
+		}
+		attempts := item.Attempts + 1
+		dead := attempts >= maxAttempts
+		s.log.Warn("accounting sync failed", zap.String("exporter", s.exporter.Name()), zap.String("document_type", item.DocumentType), zap.String("document_id", item.DocumentID.String()), zap.Int("attempts", attempts), zap.Bool("dead", dead), zap.Error(err))
+		if merr := s.repo.MarkFailed(ctx, item, err.Error(), time.Now().UTC().Add(backoff(attempts)), dead); merr != nil {
+			s.log.Error("mark accounting sync", zap.String("id", item.ID.String()), zap.Error(merr))
+		}
+	}
+	return nil
+}
+
+func (s *service) post(ctx context.Context, item *SyncItem) (string, error) {
+	switch item.DocumentType {
+	case DocumentInvoice:
+		inv, err := s.repo.GetInvoice(ctx, item.DocumentID)
+		if err != nil {
+			return "", err
+		}
+		return s.exporter.PostInvoice(ctx, inv)
+	case DocumentPayment:
+		p, err := s.repo.GetPayment(ctx, item.DocumentID)
+		if err != nil {
+			return "", err
+		}
+		inv, err := s.repo.GetInvoice(ctx, p.InvoiceID)
+		if err != nil {
+			return "", err
+		}
+		return s.exporter.PostPayment(ctx, p, inv)
+	}
+	return "", ErrorUnknownDocumentType
+}
+
+func backoff(attempts int) time.Duration {
+	d := time.Minute << uint(attempts)
+	if d <= 0 || d > maxBackoff {
+		return maxBackoff
+	}
+	return d
+}
+
+func (s *service) Retry(ctx context.Context, id uuid.UUID) error {
+	return s.repo.Requeue(ctx, id)
+}
+
+func (s *service) List(ctx context.Context, status string, limit, offset int) ([]SyncItem, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	return s.repo.List(ctx, status, limit, offset)
+}
+
+func (s *service) Count(ctx context.Context, status string) (int, error) {
+	return s.repo.Count(ctx, status)
+}
@@ -0,0 +1,115 @@
+package Accounting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"savannah/src/Billing"
+)
+
+// XeroExporter posts accounts-receivable invoices and their payments to Xero.
+type XeroExporter struct {
+	TenantID     string
+	AccessToken  string
+	ContactID    string
+	SalesAccount string
+	BankAccount  string
+	client       *http.Client
+}
+
+func NewXeroExporter(tenantID, accessToken, contactID, salesAccount, bankAccount string) *XeroExporter {
+	return &XeroExporter{TenantID: tenantID, AccessToken: accessToken, ContactID: contactID, SalesAccount: salesAccount, BankAccount: bankAccount, client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+func (x *XeroExporter) Name() string { return "xero" }
+
+func (x *XeroExporter) PostInvoice(ctx context.Context, inv *Billing.Invoice) (string, error) {
+	description := "Order " + inv.OrderID.String()
+	if memo := taxMemo(inv); memo != "" {
+		description += "\n" + memo
+	}
+	invoice := map[string]interface{}{
+		"Type":          "ACCREC",
+		"Contact":       map[string]string{"ContactID": x.ContactID},
+		"InvoiceNumber": inv.InvoiceNumber,
+		"Reference":     inv.OrderID.String(),
+		"CurrencyCode":  inv.Currency,
+		"Date":          inv.IssuedAt.Format("2006-01-02"),
+		"Status":        "AUTHORISED",
+		"LineItems": []map[string]interface{}{{
+			"Description": description,
+			"Quantity":    1,
+			"UnitAmount":  inv.Amount,
+			"AccountCode": x.SalesAccount,
+		}},
+	}
+	if inv.DueAt != nil {
+		invoice["DueDate"] = inv.DueAt.Format("2006-01-02")
+	}
+	var out struct {
+		Invoices []struct {
+			InvoiceID string `json:"InvoiceID"`
+		} `json:"Invoices"`
+	}
+	if err := x.put(ctx, "Invoices", inv.ID.String(), map[string]interface{}{"Invoices": []interface{}{invoice}}, &out); err != nil {
+		return "", err
+	}
+	if len(out.Invoices) == 0 {
+		return "", fmt.Errorf("xero Invoices: empty response")
+	}
+	return out.Invoices[0].InvoiceID, nil
+}
+
+func (x *XeroExporter) PostPayment(ctx context.Context, p *Billing.Payment, inv *Billing.Invoice) (string, error) {
+	payment := map[string]interface{}{
+		"Invoice":   map[string]string{"InvoiceNumber": inv.InvoiceNumber},
+		"Account":   map[string]string{"Code": x.BankAccount},
+		"Amount":    p.Amount,
+		"Date":      p.CreatedAt.Format("2006-01-02"),
+		"Reference": p.ID.String(),
+	}
+	var out struct {
+		Payments []struct {
+			PaymentID string `json:"PaymentID"`
+		} `json:"Payments"`
+	}
+	if err := x.put(ctx, "Payments", p.ID.String(), map[string]interface{}{"Payments": []interface{}{payment}}, &out); err != nil {
+		return "", err
+	}
+	if len(out.Payments) == 0 {
+		return "", fmt.Errorf("xero Payments: empty response")
+	}
+	return out.Payments[0].PaymentID, nil
+}
+
+// put creates documents once per idempotency key: Xero replays its first
+// response to a repeated key, so a document posted again after its sync
+// couldn't be recorded is not booked twice
+func (x *XeroExporter) put(ctx context.Context, endpoint, idempotencyKey string, body interface{}, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, "https://api.xero.com/api.xro/2.0/"+endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+x.AccessToken)
+	req.Header.Set("Xero-tenant-id", x.TenantID)
+	req.Header.Set("Idempotency-Key", idempotencyKey)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	res, err := x.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("xero %s: status %d", endpoint, res.StatusCode)
+	}
+	return json.NewDecoder(res.Body).Decode(out)
+}
@@ -0,0 +1,89 @@
+package Analytics
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Emitter buffers events in memory and flushes them to the sink in batches,
+// either when the batch is full or on every flush interval. Track never
+// blocks request handling: events are dropped when the buffer is full.
+type Emitter struct {
+	sink      Sink
+	log       *zap.Logger
+	events    chan Event
+	batchSize int
+	interval  time.Duration
+	wg        sync.WaitGroup
+}
+
+func NewEmitter(sink Sink, batchSize int, interval time.Duration, log *zap.Logger) *Emitter {
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	e := &Emitter{sink: sink, log: log, events: make(chan Event, batchSize*10), batchSize: batchSize, interval: interval}
+	e.wg.Add(1)
+	go e.run()
+	return e
+}
+
+// Track queues an event for delivery, with the anonymous id of the request
+// ctx belongs to.
+func (e *Emitter) Track(ctx context.Context, name string, customerID *uuid.UUID, properties map[string]interface{}) {
+	ev := Event{ID: uuid.New(), Name: name, CustomerID: customerID, AnonymousID: AnonymousID(ctx), Properties: properties, OccurredAt: time.Now().UTC()}
+	select {
+	case e.events <- ev:
+	default:
+		e.log.Warn("analytics buffer full, dropping event", zap.String("event", name))
+	}
+}
+
+// Close flushes pending events and stops the background worker.
+func (e *Emitter) Close() {
+	close(e.events)
+	e.wg.Wait()
+}
+
+func (e *Emitter) run() {
+	defer e.wg.Done()
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+	batch := make([]Event, 0, e.batchSize)
+	for {
+		select {
+		case ev, ok := <-e.events:
+			if !ok {
+				e.flush(batch)
+				return
+			}
+			batch = append(batch, ev)
+			if len(batch) >= e.batchSize {
+				e.flush(batch)
+				batch = make([]Event, 0, e.batchSize)
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				e.flush(batch)
+				batch = make([]Event, 0, e.batchSize)
+			}
+		}
+	}
+}
+
+func (e *Emitter) flush(batch []Event) {
+	if len(batch) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if err := e.sink.Send(ctx, batch); err != nil {
+		e.log.Error("analytics flush", zap.Int("events", len(batch)), zap.Error(err))
+	}
+}
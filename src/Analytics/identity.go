@@ -0,0 +1,52 @@
+package Analytics
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// a guest's anonymous id comes in this header, or in the cookie Segment's
+// analytics.js keeps it in, so server and browser events share one identity
+const (
+	AnonymousIDHeader = "X-Anonymous-ID"
+	AnonymousIDCookie = "ajs_anonymous_id"
+)
+
+// longer ids are not ids
+const maxAnonymousID = 128
+
+type anonymousKey struct{}
+
+// Identify keeps the anonymous id a request presents in its context, so the
+// events tracked while handling it join the guest's funnel.
+func Identify(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(AnonymousIDHeader)
+		if id == "" {
+			if c, err := r.Cookie(AnonymousIDCookie); err == nil {
+				// analytics.js may store the id url-encoded and JSON-quoted
+				if v, err := url.QueryUnescape(c.Value); err == nil {
+					id = strings.Trim(v, `"`)
+				}
+			}
+		}
+		if id != "" && len(id) <= maxAnonymousID {
+			r = r.WithContext(WithAnonymousID(r.Context(), id))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// WithAnonymousID sets the anonymous id events tracked with ctx carry
+func WithAnonymousID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, anonymousKey{}, id)
+}
+
+// AnonymousID returns the anonymous id kept in ctx; empty outside of a
+// request that presented one
+func AnonymousID(ctx context.Context) string {
+	id, _ := ctx.Value(anonymousKey{}).(string)
+	return id
+}
@@ -0,0 +1,33 @@
+package Analytics
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// storefront funnel events
+const (
+	EventProductViewed   = "product_viewed"
+	EventCartCreated     = "cart_created"
+	EventCheckoutStarted = "checkout_started"
+	EventOrderCompleted  = "order_completed"
+)
+
+// order lifecycle events
+const (
+	EventOrderCancelled    = "order_cancelled"
+	EventPreorderPlaced    = "preorder_placed"
+	EventPreorderAllocated = "preorder_allocated"
+)
+
+// Event is a single structured analytics event sent to the configured sink
+type Event struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"event"`
+	CustomerID *uuid.UUID `json:"customer_id,omitempty"`
+	// AnonymousID identifies a guest across events; see Identify
+	AnonymousID string                 `json:"anonymous_id,omitempty"`
+	Properties  map[string]interface{} `json:"properties,omitempty"`
+	OccurredAt  time.Time              `json:"occurred_at"`
+}
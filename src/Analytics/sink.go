@@ -0,0 +1,124 @@
+package Analytics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Sink delivers a batch of events to a downstream analytics system
+// (HTTP collector, Segment, a Kafka bridge, ...).
+type Sink interface {
+	Send(ctx context.Context, events []Event) error
+}
+
+// HTTPSink posts batches of events as JSON to a collector endpoint, such as
+// a Kafka REST proxy.
+type HTTPSink struct {
+	Endpoint string
+	WriteKey string
+	client   *http.Client
+}
+
+func NewHTTPSink(endpoint, writeKey string) *HTTPSink {
+	return &HTTPSink{Endpoint: endpoint, WriteKey: writeKey, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (s *HTTPSink) Send(ctx context.Context, events []Event) error {
+	return s.post(ctx, map[string]interface{}{"batch": events, "sent_at": time.Now().UTC()})
+}
+
+func (s *HTTPSink) post(ctx context.Context, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.WriteKey != "" {
+		req.SetBasicAuth(s.WriteKey, "")
+	}
+	res, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("analytics sink responded %d", res.StatusCode)
+	}
+	return nil
+}
+
+// SegmentSink posts batches to Segment's batch API as track calls
+type SegmentSink struct {
+	*HTTPSink
+}
+
+func NewSegmentSink(endpoint, writeKey string) *SegmentSink {
+	if endpoint == "" {
+		endpoint = "https://api.segment.io/v1/batch"
+	}
+	return &SegmentSink{HTTPSink: NewHTTPSink(endpoint, writeKey)}
+}
+
+// segmentTrack is a track call of Segment's batch API. Segment needs a
+// userId or an anonymousId; events of guests carry the anonymous id they
+// were tracked with, or their event id when the guest presented none.
+type segmentTrack struct {
+	Type        string                 `json:"type"`
+	MessageID   string                 `json:"messageId"`
+	Event       string                 `json:"event"`
+	UserID      string                 `json:"userId,omitempty"`
+	AnonymousID string                 `json:"anonymousId,omitempty"`
+	Properties  map[string]interface{} `json:"properties,omitempty"`
+	Timestamp   time.Time              `json:"timestamp"`
+}
+
+func (s *SegmentSink) Send(ctx context.Context, events []Event) error {
+	batch := make([]segmentTrack, 0, len(events))
+	for _, e := range events {
+		t := segmentTrack{Type: "track", MessageID: e.ID.String(), Event: e.Name, Properties: e.Properties, Timestamp: e.OccurredAt}
+		t.AnonymousID = e.AnonymousID
+		if e.CustomerID != nil {
+			t.UserID = e.CustomerID.String()
+		} else if t.AnonymousID == "" {
+			t.AnonymousID = e.ID.String()
+		}
+		batch = append(batch, t)
+	}
+	return s.post(ctx, map[string]interface{}{"batch": batch, "sentAt": time.Now().UTC()})
+}
+
+// LogSink writes events to the application log; useful locally and as a fallback.
+type LogSink struct {
+	log *zap.Logger
+}
+
+func NewLogSink(log *zap.Logger) *LogSink { return &LogSink{log: log} }
+
+func (s *LogSink) Send(ctx context.Context, events []Event) error {
+	for _, e := range events {
+		s.log.Info("analytics event", zap.String("event", e.Name), zap.Any("properties", e.Properties))
+	}
+	return nil
+}
+
+// NewSink builds the sink named by kind: "http", "segment" or "log" (default).
+func NewSink(kind, endpoint, writeKey string, log *zap.Logger) Sink {
+	switch kind {
+	case "http":
+		return NewHTTPSink(endpoint, writeKey)
+	case "segment":
+		return NewSegmentSink(endpoint, writeKey)
+	default:
+		return NewLogSink(log)
+	}
+}
@@ -0,0 +1,55 @@
+package Analytics
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestSegmentSinkKeepsGuestIdentity(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		calls []segmentTrack
+	)
+	segment := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Batch []segmentTrack `json:"batch"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decode batch: %v", err)
+		}
+		mu.Lock()
+		calls = append(calls, body.Batch...)
+		mu.Unlock()
+	}))
+	defer segment.Close()
+
+	emitter := NewEmitter(NewSegmentSink(segment.URL, "key"), 10, time.Minute, zap.NewNop())
+	// a guest views a product, then starts a cart, from the same browser
+	tracked := Identify(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		emitter.Track(r.Context(), r.URL.Query().Get("event"), nil, nil)
+	}))
+	view := httptest.NewRequest(http.MethodGet, "/?event="+EventProductViewed, nil)
+	view.Header.Set(AnonymousIDHeader, "guest-1")
+	tracked.ServeHTTP(httptest.NewRecorder(), view)
+	cart := httptest.NewRequest(http.MethodPost, "/?event="+EventCartCreated, nil)
+	cart.AddCookie(&http.Cookie{Name: AnonymousIDCookie, Value: "%22guest-1%22"})
+	tracked.ServeHTTP(httptest.NewRecorder(), cart)
+	emitter.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(calls) != 2 {
+		t.Fatalf("segment got %d track calls, want 2", len(calls))
+	}
+	for _, c := range calls {
+		if c.AnonymousID != "guest-1" {
+			t.Errorf("%s anonymousId = %q, want %q", c.Event, c.AnonymousID, "guest-1")
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package Approvals
+
+import (
+	"context"
+	"fmt"
+
+	"savannah/src/Billing"
+	"savannah/src/Catalog"
+	"savannah/src/Orders"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// NewActions wires the actions that need a second admin's approval
+func NewActions(billing Billing.Service, products Catalog.Service, orders Orders.Service) map[string]Action {
+	return map[string]Action{
+		ActionRefund: NewAction(
+			func(ctx context.Context, p RefundPayload) (string, error) {
+				if p.Amount == nil {
+					return fmt.Sprintf("full refund of order %s", p.OrderID), nil
+				}
+				return fmt.Sprintf("refund %s on order %s", p.Amount.StringFixed(2), p.OrderID), nil
+			},
+			func(ctx context.Context, p RefundPayload) (interface{}, error) {
+				return billing.RefundOrder(ctx, p.OrderID, p.Amount, p.Reason)
+			},
+			nil,
+		),
+		ActionBulkPriceChange: NewAction(
+			func(ctx context.Context, p PriceChangePayload) (string, error) {
+				return fmt.Sprintf("change the price of %d products", len(p.Changes)), nil
+			},
+			func(ctx context.Context, p PriceChangePayload) (interface{}, error) {
+				previous, err := products.UpdatePrices(ctx, p.Changes)
+				if err != nil {
+					return nil, err
+				}
+				report := &Report{}
+				for i, prev := range previous {
+					report.add(Change{ID: prev.ProductID, From: prev.Price.String(), To: p.Changes[i].Price.String()})
+				}
+				return report, nil
+			},
+			func(ctx context.Context, p PriceChangePayload) (*Report, error) {
+				return previewPrices(ctx, products, p.Changes)
+			},
+		),
+		ActionBulkCancel: NewAction(
+			func(ctx context.Context, p BulkCancelPayload) (string, error) {
+				return fmt.Sprintf("cancel %d orders", len(p.OrderIDs)), nil
+			},
+			func(ctx context.Context, p BulkCancelPayload) (interface{}, error) {
+				return transitionOrders(ctx, orders, p.OrderIDs, "CANCELLED", false)
+			},
+			func(ctx context.Context, p BulkCancelPayload) (*Report, error) {
+				return transitionOrders(ctx, orders, p.OrderIDs, "CANCELLED", true)
+			},
+		),
+		ActionBulkStatus: NewAction(
+			func(ctx context.Context, p BulkStatusPayload) (string, error) {
+				return fmt.Sprintf("move %d orders to %s", len(p.OrderIDs), p.Status), nil
+			},
+			func(ctx context.Context, p BulkStatusPayload) (interface{}, error) {
+				return transitionOrders(ctx, orders, p.OrderIDs, p.Status, false)
+			},
+			func(ctx context.Context, p BulkStatusPayload) (*Report, error) {
+				return transitionOrders(ctx, orders, p.OrderIDs, p.Status, true)
+			},
+		),
+		ActionBulkTag: NewAction(
+			func(ctx context.Context, p BulkTagPayload) (string, error) {
+				if p.Remove {
+					return fmt.Sprintf("remove tag %q from %d orders", p.Tag, len(p.OrderIDs)), nil
+				}
+				return fmt.Sprintf("tag %d orders %q", len(p.OrderIDs), p.Tag), nil
+			},
+			func(ctx context.Context, p BulkTagPayload) (interface{}, error) {
+				return tagOrders(ctx, orders, p, false)
+			},
+			func(ctx context.Context, p BulkTagPayload) (*Report, error) {
+				return tagOrders(ctx, orders, p, true)
+			},
+		),
+	}
+}
+
+// RefundRequester records refund approvals for Billing's refund threshold
+func RefundRequester(s Service) func(ctx context.Context, orderID uuid.UUID, amount decimal.Decimal, reason *string, requestedBy string) (interface{}, error) {
+	return func(ctx context.Context, orderID uuid.UUID, amount decimal.Decimal, reason *string, requestedBy string) (interface{}, error) {
+		return s.Request(ctx, ActionRefund, RefundPayload{OrderID: orderID, Amount: &amount, Reason: reason}, requestedBy)
+	}
+}
+
+// RefundRecorder audits the refunds Billing runs without approval
+func RefundRecorder(s Service) func(ctx context.Context, orderID uuid.UUID, amount *decimal.Decimal, reason *string, requestedBy, policy string, refunds interface{}, err error) error {
+	return func(ctx context.Context, orderID uuid.UUID, amount *decimal.Decimal, reason *string, requestedBy, policy string, refunds interface{}, err error) error {
+		_, rerr := s.Record(ctx, ActionRefund, RefundPayload{OrderID: orderID, Amount: amount, Reason: reason}, requestedBy, policy, refunds, err)
+		return rerr
+	}
+}
+
+// previewPrices compares each change with the stored price; UpdatePrices
+// changes nothing when one of them fails
+func previewPrices(ctx context.Context, products Catalog.Service, changes []Catalog.PriceUpdate) (*Report, error) {
+	ids := make([]uuid.UUID, len(changes))
+	for i, c := range changes {
+		ids[i] = c.ProductID
+	}
+	current, err := products.CurrentPrices(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	report := &Report{DryRun: true}
+	for _, c := range changes {
+		row := Change{ID: c.ProductID, To: c.Price.String()}
+		price, ok := current[c.ProductID]
+		switch {
+		case !ok:
+			row.Error = Catalog.ProductErrorNotFound.Error()
+		case c.Price.IsNegative():
+			row.Error = "negative price"
+		default:
+			row.From = price.String()
+		}
+		report.add(row)
+	}
+	return report, nil
+}
+
+// transitionOrders moves each order to status on its own, or with dryRun
+// only checks that it could; the report is returned even when some orders
+// could not be moved.
+func transitionOrders(ctx context.Context, orders Orders.Service, ids []uuid.UUID, status string, dryRun bool) (*Report, error) {
+	report := &Report{DryRun: dryRun}
+	for _, id := range unique(ids) {
+		row := Change{ID: id, To: status}
+		from, err := orders.CheckTransition(ctx, id, status)
+		row.From = from
+		if err == nil && !dryRun && from != status {
+			err = orders.Transition(ctx, id, status)
+		}
+		if err != nil {
+			row.Error = err.Error()
+		}
+		report.add(row)
+	}
+	if report.Failed > 0 && !dryRun {
+		return report, fmt.Errorf("%d of %d orders could not be moved to %s", report.Failed, report.Total, status)
+	}
+	return report, nil
+}
+
+// tagOrders tags or untags the orders, or with dryRun reports which would
+// change; ids that are not orders fail
+func tagOrders(ctx context.Context, orders Orders.Service, p BulkTagPayload, dryRun bool) (*Report, error) {
+	ids := unique(p.OrderIDs)
+	tagged, err := orders.OrderTags(ctx, ids, p.Tag)
+	if err != nil {
+		return nil, err
+	}
+	want := !p.Remove
+	changed := map[uuid.UUID]bool{}
+	for id, has := range tagged {
+		changed[id] = has != want
+	}
+	if !dryRun {
+		change := orders.TagOrders
+		if p.Remove {
+			change = orders.UntagOrders
+		}
+		done, err := change(ctx, ids, p.Tag, p.Reason)
+		if err != nil {
+			return nil, err
+		}
+		// what actually changed, should another admin have retagged meanwhile
+		for id := range changed {
+			changed[id] = false
+		}
+		for _, id := range done {
+			changed[id] = true
+		}
+	}
+	state := map[bool]string{true: "tagged", false: "untagged"}
+	report := &Report{DryRun: dryRun}
+	for _, id := range ids {
+		if _, ok := tagged[id]; !ok {
+			report.add(Change{ID: id, Error: Orders.ErrorNotFound.Error()})
+			continue
+		}
+		from := state[want]
+		if changed[id] {
+			from = state[!want]
+		}
+		report.add(Change{ID: id, From: from, To: state[want]})
+	}
+	return report, nil
+}
+
+func unique(ids []uuid.UUID) []uuid.UUID {
+	seen := make(map[uuid.UUID]bool, len(ids))
+	out := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	return out
+}
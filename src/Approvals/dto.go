@@ -0,0 +1,71 @@
+package Approvals
+
+import (
+	"encoding/json"
+
+	"savannah/src/Catalog"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+type CreateApprovalRequest struct {
+	Action      string          `json:"action" validate:"required,oneof=refund bulk_price_change bulk_cancel bulk_status_change bulk_tag"`
+	Payload     json.RawMessage `json:"payload" validate:"required"`
+	RequestedBy string          `json:"requested_by" validate:"required,max=200"`
+}
+
+// DryRunActionRequest previews an action before its approval is requested
+type DryRunActionRequest struct {
+	Action  string          `json:"action" validate:"required"`
+	Payload json.RawMessage `json:"payload" validate:"required"`
+}
+
+// DryRunRequest names who previews a pending approval, for the audit trail
+type DryRunRequest struct {
+	Actor string `json:"actor" validate:"required,max=200"`
+}
+
+type DecisionRequest struct {
+	DecidedBy string  `json:"decided_by" validate:"required,max=200"`
+	Note      *string `json:"note,omitempty" validate:"omitempty,max=1000"`
+}
+
+// ApprovalResponse is an approval with its audit trail
+type ApprovalResponse struct {
+	Approval
+	Events []Event `json:"events"`
+}
+
+// RefundPayload is the payload of a refund approval
+type RefundPayload struct {
+	OrderID uuid.UUID        `json:"order_id" validate:"required"`
+	Amount  *decimal.Decimal `json:"amount,omitempty"`
+	Reason  *string          `json:"reason,omitempty" validate:"omitempty,max=500"`
+}
+
+// PriceChangePayload is the payload of a bulk price change approval
+type PriceChangePayload struct {
+	Changes []Catalog.PriceUpdate `json:"changes" validate:"required,min=1,max=1000,dive"`
+}
+
+// BulkCancelPayload is the payload of a bulk cancellation approval
+type BulkCancelPayload struct {
+	OrderIDs []uuid.UUID `json:"order_ids" validate:"required,min=1,max=500"`
+	Reason   *string     `json:"reason,omitempty" validate:"omitempty,max=500"`
+}
+
+// BulkStatusPayload is the payload of a bulk order status change
+type BulkStatusPayload struct {
+	OrderIDs []uuid.UUID `json:"order_ids" validate:"required,min=1,max=500"`
+	Status   string      `json:"status" validate:"required,oneof=CONFIRMED PROCESSING SHIPPED COMPLETED CANCELLED"`
+	Reason   *string     `json:"reason,omitempty" validate:"omitempty,max=500"`
+}
+
+// BulkTagPayload is the payload of tagging, or with Remove untagging, orders
+type BulkTagPayload struct {
+	OrderIDs []uuid.UUID `json:"order_ids" validate:"required,min=1,max=1000"`
+	Tag      string      `json:"tag" validate:"required,max=64"`
+	Remove   bool        `json:"remove,omitempty"`
+	Reason   string      `json:"reason,omitempty" validate:"omitempty,max=500"`
+}
@@ -0,0 +1,27 @@
+package Approvals
+
+import (
+	"errors"
+
+	"savannah/src/ErrorCodes"
+)
+
+var (
+	ErrorNotFound       = errors.New("approval not found")
+	ErrorNotPending     = errors.New("approval has already been decided")
+	ErrorSelfApproval   = errors.New("an approval must be decided by someone other than the requester")
+	ErrorUnknownAction  = errors.New("unknown approval action")
+	ErrorInvalidPayload = errors.New("invalid approval payload")
+	ErrorNoDryRun       = errors.New("the action has no dry run")
+)
+
+func init() {
+	ErrorCodes.Register("APPROVALS",
+		ErrorCodes.Def{N: 1, Slug: "NOT_FOUND", Err: ErrorNotFound},
+		ErrorCodes.Def{N: 2, Slug: "NOT_PENDING", Err: ErrorNotPending},
+		ErrorCodes.Def{N: 3, Slug: "SELF_APPROVAL", Err: ErrorSelfApproval},
+		ErrorCodes.Def{N: 4, Slug: "UNKNOWN_ACTION", Err: ErrorUnknownAction},
+		ErrorCodes.Def{N: 5, Slug: "INVALID_PAYLOAD", Err: ErrorInvalidPayload},
+		ErrorCodes.Def{N: 6, Slug: "NO_DRY_RUN", Err: ErrorNoDryRun},
+	)
+}
@@ -0,0 +1,249 @@
+package Approvals
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"savannah/src/ErrorCodes"
+	"savannah/src/Pagination"
+)
+
+type Handler struct {
+	svc Service
+	log *zap.Logger
+	v   *validator.Validate
+}
+
+func NewHandler(s Service, log *zap.Logger) *Handler {
+	return &Handler{svc: s, log: log, v: validator.New()}
+}
+
+// Create godoc
+// @Summary      Request approval for a risky action
+// @Description  Creates a pending approval for a refund, bulk price change, bulk cancellation, bulk status change or bulk tagging; a different admin must approve it before it runs. Once run, bulk actions store a report of every target as the result.
+// @Tags         approvals
+// @Accept       json
+// @Produce      json
+// @Param        approval  body      CreateApprovalRequest  true  "Action and payload"
+// @Success      201       {object}  Approval
+// @Failure      400       {object}  map[string]interface{}
+// @Router       /approvals [post]
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	var dto CreateApprovalRequest
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if err := h.v.Struct(dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	a, err := h.svc.Request(r.Context(), dto.Action, dto.Payload, dto.RequestedBy)
+	if err != nil {
+		h.handleError(w, err, "request approval")
+		return
+	}
+	h.writeJSON(w, http.StatusCreated, a)
+}
+
+// DryRun godoc
+// @Summary      Preview a bulk action
+// @Description  Reports what the action would change, with counts and a sample of the targets, without changing or recording anything. Refunds have no dry run.
+// @Tags         approvals
+// @Accept       json
+// @Produce      json
+// @Param        action  body      DryRunActionRequest  true  "Action and payload"
+// @Success      200     {object}  Report
+// @Failure      400     {object}  map[string]interface{}
+// @Router       /approvals/dry-run [post]
+func (h *Handler) DryRun(w http.ResponseWriter, r *http.Request) {
+	var dto DryRunActionRequest
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if err := h.v.Struct(dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	report, err := h.svc.DryRun(r.Context(), dto.Action, dto.Payload)
+	if err != nil {
+		h.handleError(w, err, "dry run")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, report)
+}
+
+// DryRunApproval godoc
+// @Summary      Preview a pending approval
+// @Description  Reports what approving would change now. The report is saved on the approval's audit trail as a dry_run event.
+// @Tags         approvals
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string         true  "Approval ID"
+// @Param        dry_run  body      DryRunRequest  true  "Who previews"
+// @Success      200      {object}  Report
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      404      {object}  map[string]interface{}
+// @Failure      409      {object}  map[string]interface{}
+// @Router       /approvals/{id}/dry-run [post]
+func (h *Handler) DryRunApproval(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var dto DryRunRequest
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if err := h.v.Struct(dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	report, err := h.svc.DryRunApproval(r.Context(), id, dto)
+	if err != nil {
+		h.handleError(w, err, "dry run approval")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, report)
+}
+
+// List godoc
+// @Summary      List approvals
+// @Tags         approvals
+// @Produce      json
+// @Param        status  query     string  false  "PENDING, APPROVED, REJECTED, EXECUTED or FAILED"
+// @Param        limit   query     int     false  "Page size (max 100)"
+// @Param        offset  query     int     false  "Offset"
+// @Param        page    query     int     false  "Page of limit approvals, 1-based; instead of offset"
+// @Success      200     {array}   Approval
+// @Header       200     {string}  Link    "first, prev, next and last pages (RFC 5988), with X-Total-Count, X-Page and X-Total-Pages"
+// @Router       /approvals [get]
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	limit, offset := Pagination.Params(r.URL.Query(), 20, 100)
+	status := r.URL.Query().Get("status")
+	list, err := h.svc.List(r.Context(), status, limit, offset)
+	var total int
+	if err == nil {
+		total, err = h.svc.Count(r.Context(), status)
+	}
+	if err != nil {
+		h.log.Error("list approvals", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to list approvals")
+		return
+	}
+	Pagination.Write(w, r, limit, offset, total)
+	h.writeJSON(w, http.StatusOK, list)
+}
+
+// Get godoc
+// @Summary      Get an approval with its audit trail
+// @Tags         approvals
+// @Produce      json
+// @Param        id   path      string  true  "Approval ID"
+// @Success      200  {object}  ApprovalResponse
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /approvals/{id} [get]
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	a, err := h.svc.Get(r.Context(), id)
+	if err != nil {
+		h.handleError(w, err, "get approval")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, a)
+}
+
+// Approve godoc
+// @Summary      Approve and execute
+// @Description  The approver must differ from the requester. The action runs immediately; its result or error is stored on the approval.
+// @Tags         approvals
+// @Accept       json
+// @Produce      json
+// @Param        id        path      string           true  "Approval ID"
+// @Param        decision  body      DecisionRequest  true  "Approver"
+// @Success      200       {object}  Approval
+// @Failure      404       {object}  map[string]interface{}
+// @Failure      409       {object}  map[string]interface{}
+// @Failure      422       {object}  map[string]interface{}
+// @Router       /approvals/{id}/approve [post]
+func (h *Handler) Approve(w http.ResponseWriter, r *http.Request) {
+	h.decide(w, r, h.svc.Approve, "approve")
+}
+
+// Reject godoc
+// @Summary      Reject an approval
+// @Tags         approvals
+// @Accept       json
+// @Produce      json
+// @Param        id        path      string           true  "Approval ID"
+// @Param        decision  body      DecisionRequest  true  "Reviewer"
+// @Success      200       {object}  Approval
+// @Failure      404       {object}  map[string]interface{}
+// @Failure      409       {object}  map[string]interface{}
+// @Router       /approvals/{id}/reject [post]
+func (h *Handler) Reject(w http.ResponseWriter, r *http.Request) {
+	h.decide(w, r, h.svc.Reject, "reject")
+}
+
+func (h *Handler) decide(w http.ResponseWriter, r *http.Request, fn func(ctx context.Context, id uuid.UUID, req DecisionRequest) (*Approval, error), action string) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var dto DecisionRequest
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if err := h.v.Struct(dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	a, err := fn(r.Context(), id, dto)
+	if err != nil {
+		h.handleError(w, err, action+" approval")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, a)
+}
+
+func (h *Handler) handleError(w http.ResponseWriter, err error, action string) {
+	switch {
+	case errors.Is(err, ErrorNotFound):
+		h.writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrorNotPending):
+		h.writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, ErrorSelfApproval):
+		h.writeError(w, http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, ErrorUnknownAction), errors.Is(err, ErrorInvalidPayload), errors.Is(err, ErrorNoDryRun):
+		h.writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		h.log.Error(action, zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to "+action)
+	}
+}
+
+func (h *Handler) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (h *Handler) writeError(w http.ResponseWriter, status int, msg string) {
+	h.writeJSON(w, status, map[string]interface{}{"error": msg, "code": ErrorCodes.Resolve("APPROVALS", status, msg), "timestamp": time.Now().UTC()})
+}
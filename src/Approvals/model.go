@@ -0,0 +1,113 @@
+package Approvals
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx/types"
+)
+
+// actions that need a second admin's approval
+const (
+	ActionRefund          = "refund"
+	ActionBulkPriceChange = "bulk_price_change"
+	ActionBulkCancel      = "bulk_cancel"
+	ActionBulkStatus      = "bulk_status_change"
+	ActionBulkTag         = "bulk_tag"
+)
+
+// approval statuses
+const (
+	StatusPending  = "PENDING"
+	StatusApproved = "APPROVED" // approved and executing
+	StatusRejected = "REJECTED"
+	StatusExecuted = "EXECUTED"
+	StatusFailed   = "FAILED"
+)
+
+// audit events
+const (
+	EventRequested = "requested"
+	EventApproved  = "approved"
+	EventRejected  = "rejected"
+	EventExecuted  = "executed"
+	EventFailed    = "failed"
+	EventDryRun    = "dry_run" // data has the report
+	// within policy, the action ran without waiting for a second admin;
+	// data has the policy applied
+	EventAutoApproved = "auto_approved"
+)
+
+// AutoApprover decides approvals recorded by Record
+const AutoApprover = "auto"
+
+// Approval is a risky action waiting for, or decided by, a second admin
+type Approval struct {
+	ID           uuid.UUID      `db:"id" json:"id"`
+	Action       string         `db:"action" json:"action"`
+	Summary      string         `db:"summary" json:"summary"`
+	Payload      types.JSONText `db:"payload" json:"payload"`
+	Status       string         `db:"status" json:"status"`
+	RequestedBy  string         `db:"requested_by" json:"requested_by"`
+	DecidedBy    *string        `db:"decided_by" json:"decided_by,omitempty"`
+	DecisionNote *string        `db:"decision_note" json:"decision_note,omitempty"`
+	Result       types.JSONText `db:"result" json:"result,omitempty"`
+	Error        *string        `db:"error" json:"error,omitempty"`
+	CreatedAt    time.Time      `db:"created_at" json:"created_at"`
+	DecidedAt    *time.Time     `db:"decided_at" json:"decided_at,omitempty"`
+}
+
+// Event is one audit trail entry of an approval
+type Event struct {
+	ID         uuid.UUID      `db:"id" json:"id"`
+	ApprovalID uuid.UUID      `db:"approval_id" json:"approval_id"`
+	Event      string         `db:"event" json:"event"`
+	Actor      string         `db:"actor" json:"actor"`
+	Data       types.JSONText `db:"data" json:"data,omitempty"`
+	CreatedAt  time.Time      `db:"created_at" json:"created_at"`
+}
+
+// Report is what a bulk action changes, target by target: predicted by a
+// dry run, or stored as the approval's result once executed
+type Report struct {
+	DryRun    bool   `json:"dry_run,omitempty"`
+	Summary   string `json:"summary,omitempty"`
+	Total     int    `json:"total"`
+	Changed   int    `json:"changed"`
+	Unchanged int    `json:"unchanged"` // already as asked
+	Failed    int    `json:"failed"`
+	// every target of an execution; a dry run keeps a sample
+	Rows []Change `json:"rows"`
+}
+
+// Change is one target of a bulk action and its value before and after;
+// Error says why it fails
+type Change struct {
+	ID    uuid.UUID `json:"id"`
+	From  string    `json:"from,omitempty"`
+	To    string    `json:"to,omitempty"`
+	Error string    `json:"error,omitempty"`
+}
+
+// sampleRows caps the rows a dry run reports
+const sampleRows = 20
+
+func (r *Report) add(c Change) {
+	r.Total++
+	switch {
+	case c.Error != "":
+		r.Failed++
+	case c.From == c.To:
+		r.Unchanged++
+	default:
+		r.Changed++
+	}
+	if !r.DryRun || len(r.Rows) < sampleRows {
+		r.Rows = append(r.Rows, c)
+	}
+}
+
+const (
+	ApprovalTable = "approvals"
+	EventTable    = "approval_events"
+)
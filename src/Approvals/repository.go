@@ -0,0 +1,123 @@
+package Approvals
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+type Repository interface {
+	Create(ctx context.Context, a *Approval, events ...*Event) error
+	Get(ctx context.Context, id uuid.UUID) (*Approval, error)
+	List(ctx context.Context, status string, limit, offset int) ([]Approval, error)
+	Count(ctx context.Context, status string) (int, error)
+	Events(ctx context.Context, id uuid.UUID) ([]Event, error)
+	Transition(ctx context.Context, a *Approval, from string, e *Event) error
+	AddEvent(ctx context.Context, e *Event) error
+}
+
+type repository struct {
+	db  *sqlx.DB
+	log *zap.Logger
+}
+
+func NewRepository(db *sqlx.DB, log *zap.Logger) Repository { return &repository{db: db, log: log} }
+
+const (
+	approvalColumns = `id,action,summary,payload,status,requested_by,decided_by,decision_note,result,error,created_at,decided_at`
+	eventColumns    = `id,approval_id,event,actor,data,created_at`
+)
+
+// Create stores a with its first audit events
+func (r *repository) Create(ctx context.Context, a *Approval, events ...*Event) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	query := fmt.Sprintf(`INSERT INTO %s (%s) VALUES (:id,:action,:summary,:payload,:status,:requested_by,:decided_by,:decision_note,:result,:error,:created_at,:decided_at)`, ApprovalTable, approvalColumns)
+	if _, err = tx.NamedExecContext(ctx, query, a); err != nil {
+		return err
+	}
+	for _, e := range events {
+		if err = addEvent(ctx, tx, e); err != nil {
+			return err
+		}
+	}
+	err = tx.Commit()
+	return err
+}
+
+func (r *repository) Get(ctx context.Context, id uuid.UUID) (*Approval, error) {
+	var a Approval
+	err := r.db.GetContext(ctx, &a, fmt.Sprintf(`SELECT %s FROM %s WHERE id=$1`, approvalColumns, ApprovalTable), id)
+	if err == sql.ErrNoRows {
+		return nil, ErrorNotFound
+	}
+	return &a, err
+}
+
+func (r *repository) List(ctx context.Context, status string, limit, offset int) ([]Approval, error) {
+	out := []Approval{}
+	err := r.db.SelectContext(ctx, &out, fmt.Sprintf(`SELECT %s FROM %s WHERE ($1 = '' OR status = $1) ORDER BY created_at DESC LIMIT $2 OFFSET $3`, approvalColumns, ApprovalTable), status, limit, offset)
+	return out, err
+}
+
+func (r *repository) Count(ctx context.Context, status string) (int, error) {
+	var n int
+	err := r.db.GetContext(ctx, &n, fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE ($1 = '' OR status = $1)`, ApprovalTable), status)
+	return n, err
+}
+
+func (r *repository) Events(ctx context.Context, id uuid.UUID) ([]Event, error) {
+	out := []Event{}
+	err := r.db.SelectContext(ctx, &out, fmt.Sprintf(`SELECT %s FROM %s WHERE approval_id=$1 ORDER BY created_at`, eventColumns, EventTable), id)
+	return out, err
+}
+
+// Transition saves a's status and decision fields if it is still in status
+// from, recording e in the same transaction. ErrorNotPending means another
+// admin got there first.
+func (r *repository) Transition(ctx context.Context, a *Approval, from string, e *Event) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	res, err := tx.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET status=$1, decided_by=$2, decision_note=$3, result=$4, error=$5, decided_at=$6 WHERE id=$7 AND status=$8`, ApprovalTable),
+		a.Status, a.DecidedBy, a.DecisionNote, a.Result, a.Error, a.DecidedAt, a.ID, from)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		err = ErrorNotPending
+		return err
+	}
+	if err = addEvent(ctx, tx, e); err != nil {
+		return err
+	}
+	err = tx.Commit()
+	return err
+}
+
+// AddEvent records an audit entry that changes nothing else, such as a dry run
+func (r *repository) AddEvent(ctx context.Context, e *Event) error {
+	return addEvent(ctx, r.db, e)
+}
+
+func addEvent(ctx context.Context, db sqlx.ExtContext, e *Event) error {
+	_, err := sqlx.NamedExecContext(ctx, db, fmt.Sprintf(`INSERT INTO %s (%s) VALUES (:id,:approval_id,:event,:actor,:data,:created_at)`, EventTable, eventColumns), e)
+	return err
+}
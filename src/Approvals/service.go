@@ -0,0 +1,286 @@
+package Approvals
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Action validates and executes one kind of approval
+type Action struct {
+	// Describe validates the payload and returns a one-line summary for the approver
+	Describe func(ctx context.Context, payload json.RawMessage) (string, error)
+	// Execute performs the action once approved; the result is stored for the audit trail
+	Execute func(ctx context.Context, payload json.RawMessage) (interface{}, error)
+	// DryRun reports what Execute would change without changing it; nil
+	// when the action has none
+	DryRun func(ctx context.Context, payload json.RawMessage) (*Report, error)
+}
+
+// NewAction builds an Action over a typed, validated payload; dryRun may be nil
+func NewAction[T any](describe func(ctx context.Context, p T) (string, error), execute func(ctx context.Context, p T) (interface{}, error), dryRun func(ctx context.Context, p T) (*Report, error)) Action {
+	v := validator.New()
+	decode := func(payload json.RawMessage) (T, error) {
+		var p T
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return p, fmt.Errorf("%w: %v", ErrorInvalidPayload, err)
+		}
+		if err := v.Struct(p); err != nil {
+			return p, fmt.Errorf("%w: %v", ErrorInvalidPayload, err)
+		}
+		return p, nil
+	}
+	a := Action{
+		Describe: func(ctx context.Context, payload json.RawMessage) (string, error) {
+			p, err := decode(payload)
+			if err != nil {
+				return "", err
+			}
+			return describe(ctx, p)
+		},
+		Execute: func(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+			p, err := decode(payload)
+			if err != nil {
+				return nil, err
+			}
+			return execute(ctx, p)
+		},
+	}
+	if dryRun != nil {
+		a.DryRun = func(ctx context.Context, payload json.RawMessage) (*Report, error) {
+			p, err := decode(payload)
+			if err != nil {
+				return nil, err
+			}
+			summary, err := describe(ctx, p)
+			if err != nil {
+				return nil, err
+			}
+			r, err := dryRun(ctx, p)
+			if err != nil {
+				return nil, err
+			}
+			r.DryRun, r.Summary = true, summary
+			return r, nil
+		}
+	}
+	return a
+}
+
+type Service interface {
+	Request(ctx context.Context, action string, payload interface{}, requestedBy string) (*Approval, error)
+	// Record audits an action that ran without approval because policy
+	// allowed it, with its outcome
+	Record(ctx context.Context, action string, payload interface{}, requestedBy, policy string, result interface{}, execErr error) (*Approval, error)
+	Get(ctx context.Context, id uuid.UUID) (*ApprovalResponse, error)
+	List(ctx context.Context, status string, limit, offset int) ([]Approval, error)
+	Count(ctx context.Context, status string) (int, error)
+	Approve(ctx context.Context, id uuid.UUID, req DecisionRequest) (*Approval, error)
+	Reject(ctx context.Context, id uuid.UUID, req DecisionRequest) (*Approval, error)
+	// DryRun reports what the action would change, recording nothing
+	DryRun(ctx context.Context, action string, payload json.RawMessage) (*Report, error)
+	// DryRunApproval reports what approving would change now and saves the
+	// report on the approval's audit trail
+	DryRunApproval(ctx context.Context, id uuid.UUID, req DryRunRequest) (*Report, error)
+}
+
+type service struct {
+	repo    Repository
+	actions map[string]Action
+	log     *zap.Logger
+}
+
+func NewService(r Repository, actions map[string]Action, log *zap.Logger) Service {
+	return &service{repo: r, actions: actions, log: log}
+}
+
+// Request records a pending approval for the action after validating its payload
+func (s *service) Request(ctx context.Context, action string, payload interface{}, requestedBy string) (*Approval, error) {
+	act, ok := s.actions[action]
+	if !ok {
+		return nil, ErrorUnknownAction
+	}
+	raw, ok := payload.(json.RawMessage)
+	if !ok {
+		var err error
+		if raw, err = json.Marshal(payload); err != nil {
+			return nil, err
+		}
+	}
+	summary, err := act.Describe(ctx, raw)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	a := &Approval{ID: uuid.New(), Action: action, Summary: summary, Payload: []byte(raw), Status: StatusPending, RequestedBy: requestedBy, CreatedAt: now}
+	if err := s.repo.Create(ctx, a, s.event(a, EventRequested, requestedBy, nil, now)); err != nil {
+		return nil, err
+	}
+	s.log.Info("approval requested", zap.String("approval_id", a.ID.String()), zap.String("action", action), zap.String("requested_by", requestedBy))
+	return a, nil
+}
+
+// Record stores an already executed (or failed) approval decided by
+// AutoApprover, so actions let through by policy share the audit trail of
+// those a second admin approved
+func (s *service) Record(ctx context.Context, action string, payload interface{}, requestedBy, policy string, result interface{}, execErr error) (*Approval, error) {
+	act, ok := s.actions[action]
+	if !ok {
+		return nil, ErrorUnknownAction
+	}
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	summary, err := act.Describe(ctx, raw)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	decidedBy := AutoApprover
+	a := &Approval{ID: uuid.New(), Action: action, Summary: summary, Payload: raw, Status: StatusExecuted, RequestedBy: requestedBy, DecidedBy: &decidedBy, DecisionNote: &policy, CreatedAt: now, DecidedAt: &now}
+	if result != nil {
+		if a.Result, err = json.Marshal(result); err != nil {
+			return nil, err
+		}
+	}
+	event := EventExecuted
+	if execErr != nil {
+		msg := execErr.Error()
+		a.Status, a.Error, event = StatusFailed, &msg, EventFailed
+	}
+	policyData, _ := json.Marshal(map[string]string{"policy": policy})
+	if err := s.repo.Create(ctx, a, s.event(a, EventRequested, requestedBy, nil, now), s.event(a, EventAutoApproved, AutoApprover, policyData, now), s.event(a, event, AutoApprover, a.Result, now)); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (s *service) Get(ctx context.Context, id uuid.UUID) (*ApprovalResponse, error) {
+	a, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	events, err := s.repo.Events(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &ApprovalResponse{Approval: *a, Events: events}, nil
+}
+
+func (s *service) List(ctx context.Context, status string, limit, offset int) ([]Approval, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	return s.repo.List(ctx, status, limit, offset)
+}
+
+func (s *service) Count(ctx context.Context, status string) (int, error) {
+	return s.repo.Count(ctx, status)
+}
+
+// Approve claims the pending approval for a second admin and executes the
+// action. The outcome (result or error) is stored on the approval.
+func (s *service) Approve(ctx context.Context, id uuid.UUID, req DecisionRequest) (*Approval, error) {
+	a, err := s.decide(ctx, id, req, StatusApproved, EventApproved)
+	if err != nil {
+		return nil, err
+	}
+	act, ok := s.actions[a.Action]
+	if !ok {
+		return nil, ErrorUnknownAction
+	}
+	result, execErr := act.Execute(ctx, json.RawMessage(a.Payload))
+	event := EventExecuted
+	a.Status = StatusExecuted
+	if result != nil {
+		if a.Result, err = json.Marshal(result); err != nil {
+			return nil, err
+		}
+	}
+	if execErr != nil {
+		msg := execErr.Error()
+		a.Error = &msg
+		a.Status = StatusFailed
+		event = EventFailed
+		s.log.Warn("approved action failed", zap.String("approval_id", a.ID.String()), zap.String("action", a.Action), zap.Error(execErr))
+	}
+	if err := s.repo.Transition(ctx, a, StatusApproved, s.event(a, event, req.DecidedBy, a.Result, time.Now().UTC())); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (s *service) DryRun(ctx context.Context, action string, payload json.RawMessage) (*Report, error) {
+	act, ok := s.actions[action]
+	if !ok {
+		return nil, ErrorUnknownAction
+	}
+	if act.DryRun == nil {
+		return nil, ErrorNoDryRun
+	}
+	return act.DryRun(ctx, payload)
+}
+
+func (s *service) DryRunApproval(ctx context.Context, id uuid.UUID, req DryRunRequest) (*Report, error) {
+	a, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if a.Status != StatusPending {
+		return nil, ErrorNotPending
+	}
+	report, err := s.DryRun(ctx, a.Action, json.RawMessage(a.Payload))
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(report)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.AddEvent(ctx, s.event(a, EventDryRun, req.Actor, data, time.Now().UTC())); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func (s *service) Reject(ctx context.Context, id uuid.UUID, req DecisionRequest) (*Approval, error) {
+	return s.decide(ctx, id, req, StatusRejected, EventRejected)
+}
+
+// decide moves a pending approval to status on behalf of a second admin
+func (s *service) decide(ctx context.Context, id uuid.UUID, req DecisionRequest, status, event string) (*Approval, error) {
+	a, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if a.Status != StatusPending {
+		return nil, ErrorNotPending
+	}
+	if a.RequestedBy == req.DecidedBy {
+		return nil, ErrorSelfApproval
+	}
+	now := time.Now().UTC()
+	a.Status = status
+	a.DecidedBy = &req.DecidedBy
+	a.DecisionNote = req.Note
+	a.DecidedAt = &now
+	var data []byte
+	if req.Note != nil {
+		data, _ = json.Marshal(map[string]string{"note": *req.Note})
+	}
+	if err := s.repo.Transition(ctx, a, StatusPending, s.event(a, event, req.DecidedBy, data, now)); err != nil {
+		return nil, err
+	}
+	s.log.Info("approval decided", zap.String("approval_id", a.ID.String()), zap.String("status", status), zap.String("decided_by", req.DecidedBy))
+	return a, nil
+}
+
+func (s *service) event(a *Approval, event, actor string, data []byte, at time.Time) *Event {
+	return &Event{ID: uuid.New(), ApprovalID: a.ID, Event: event, Actor: actor, Data: data, CreatedAt: at}
+}
@@ -0,0 +1,168 @@
+package Billing
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+// autoCaptureWindow is how long before expiry an uncaptured authorization is
+// captured anyway, so the funds are not lost if the order ships late.
+const autoCaptureWindow = 24 * time.Hour
+
+// AuthorizeOrder invoices the order and places an authorization hold for the
+// full amount; funds are only taken by CaptureOrder. A declined hold voids the
+// invoice, so a retry for the same order starts from a fresh one.
+func (s *service) AuthorizeOrder(ctx context.Context, orderID uuid.UUID, amount decimal.Decimal, currency, provider string) (*Payment, error) {
+	inv, err := s.IssueInvoice(ctx, orderID, amount, currency, 0)
+	if err != nil {
+		return nil, err
+	}
+	metadata := Metadata{"order_id": orderID.String(), "invoice_number": inv.InvoiceNumber}
+	ref, expiresAt, err := s.provider.Authorize(ctx, provider, amount, currency, metadata)
+	if err != nil {
+		failed := &Payment{InvoiceID: inv.ID, Provider: provider, Amount: amount, Currency: currency, Status: PaymentFailed, Metadata: metadata}
+		if cerr := s.repo.CreatePayment(ctx, failed); cerr != nil {
+			s.log.Error("record failed authorization", zap.String("order_id", orderID.String()), zap.Error(cerr))
+		}
+		if verr := s.repo.UpdateInvoiceStatus(ctx, inv.ID, InvoiceVoid, nil); verr != nil {
+			s.log.Error("void declined invoice", zap.String("order_id", orderID.String()), zap.Error(verr))
+		}
+		return nil, err
+	}
+	now := time.Now().UTC()
+	p := &Payment{InvoiceID: inv.ID, Provider: provider, ProviderPaymentID: &ref, Amount: amount, Currency: currency, Status: PaymentAuthorized, Metadata: metadata, AuthorizedAt: &now, AuthorizationExpiresAt: &expiresAt}
+	if err := s.repo.CreatePayment(ctx, p); err != nil {
+		s.log.Error("record authorization", zap.String("order_id", orderID.String()), zap.String("provider_payment_id", ref), zap.Error(err))
+		return nil, err
+	}
+	return p, nil
+}
+
+func (s *service) openAuthorization(ctx context.Context, orderID uuid.UUID) (*Invoice, *Payment, error) {
+	inv, err := s.repo.GetInvoiceByOrder(ctx, orderID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, ErrorNoAuthorization
+		}
+		return nil, nil, err
+	}
+	p, err := s.repo.GetAuthorizedPayment(ctx, inv.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, ErrorNoAuthorization
+		}
+		return nil, nil, err
+	}
+	return inv, p, nil
+}
+
+// CaptureOrder takes the authorized funds and marks the invoice paid
+func (s *service) CaptureOrder(ctx context.Context, orderID uuid.UUID) (*Payment, error) {
+	inv, p, err := s.openAuthorization(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	return p, s.capture(ctx, inv, p)
+}
+
+func (s *service) capture(ctx context.Context, inv *Invoice, p *Payment) error {
+	if err := s.provider.Capture(ctx, p.Provider, *p.ProviderPaymentID, p.Amount, p.Currency); err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	p.Status = PaymentSuccess
+	p.CapturedAt = &now
+	if err := s.repo.SetPaymentStatus(ctx, p, PaymentAuthorized); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrorNoAuthorization
+		}
+		return err
+	}
+	if err := s.repo.UpdateInvoiceStatus(ctx, inv.ID, InvoicePaid, &now); err != nil {
+		return err
+	}
+	s.queueSync(ctx, "PAYMENT", p.ID)
+	if s.listener != nil {
+		if err := s.listener.InvoicePaid(ctx, inv.OrderID); err != nil {
+			s.log.Error("invoice paid listener", zap.String("order_id", inv.OrderID.String()), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// VoidOrder releases the authorization hold and voids the invoice
+func (s *service) VoidOrder(ctx context.Context, orderID uuid.UUID) (*Payment, error) {
+	inv, p, err := s.openAuthorization(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.provider.Void(ctx, p.Provider, *p.ProviderPaymentID); err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	p.Status = PaymentVoided
+	p.VoidedAt = &now
+	if err := s.repo.SetPaymentStatus(ctx, p, PaymentAuthorized); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrorNoAuthorization
+		}
+		return nil, err
+	}
+	if err := s.repo.UpdateInvoiceStatus(ctx, inv.ID, InvoiceVoid, nil); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// OrderShipped captures the order's authorization, if it still has one
+func (s *service) OrderShipped(ctx context.Context, orderID uuid.UUID) error {
+	_, err := s.CaptureOrder(ctx, orderID)
+	if errors.Is(err, ErrorNoAuthorization) {
+		return nil
+	}
+	return err
+}
+
+// OrderCancelled voids the order's authorization, if it still has one
+func (s *service) OrderCancelled(ctx context.Context, orderID uuid.UUID) error {
+	_, err := s.VoidOrder(ctx, orderID)
+	if errors.Is(err, ErrorNoAuthorization) {
+		return nil
+	}
+	return err
+}
+
+// AutoCapture captures authorizations about to expire. Holds that already
+// lapsed can no longer be captured and are marked EXPIRED.
+func (s *service) AutoCapture(ctx context.Context) error {
+	now := time.Now().UTC()
+	payments, err := s.repo.ListExpiringAuthorizations(ctx, now.Add(autoCaptureWindow), 100)
+	if err != nil {
+		return err
+	}
+	for i := range payments {
+		p := &payments[i]
+		if p.AuthorizationExpiresAt != nil && p.AuthorizationExpiresAt.Before(now) {
+			p.Status = PaymentExpired
+			if err := s.repo.SetPaymentStatus(ctx, p, PaymentAuthorized); err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+			s.log.Warn("payment authorization expired", zap.String("payment_id", p.ID.String()))
+			continue
+		}
+		inv, err := s.repo.GetInvoice(ctx, p.InvoiceID)
+		if err != nil {
+			return err
+		}
+		if err := s.capture(ctx, inv, p); err != nil {
+			s.log.Error("auto-capture", zap.String("payment_id", p.ID.String()), zap.Error(err))
+		}
+	}
+	return nil
+}
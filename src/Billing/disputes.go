@@ -0,0 +1,183 @@
+package Billing
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+// RecordDispute stores a dispute notification (created, updated or closed),
+// linking it to the disputed payment and its order. The order is frozen while
+// the dispute is open and released once no open disputes remain.
+func (s *service) RecordDispute(ctx context.Context, e DisputeEvent) (*Dispute, error) {
+	d := &Dispute{
+		Provider:          e.Provider,
+		ProviderDisputeID: e.ProviderDisputeID,
+		ProviderPaymentID: &e.ProviderPaymentID,
+		Reason:            e.Reason,
+		Status:            strings.ToUpper(e.Status),
+		Amount:            e.Amount,
+		Currency:          strings.ToUpper(e.Currency),
+		EvidenceDueBy:     e.EvidenceDueBy,
+	}
+	p, inv, err := s.repo.GetPaymentByProviderID(ctx, e.Provider, e.ProviderPaymentID)
+	switch {
+	case err == nil:
+		d.PaymentID = &p.ID
+		d.InvoiceID = &inv.ID
+		d.OrderID = &inv.OrderID
+	case errors.Is(err, sql.ErrNoRows):
+		// keep the dispute so it shows up in the list; it can't freeze anything
+		s.log.Warn("dispute for unknown payment", zap.String("provider", e.Provider), zap.String("provider_payment_id", e.ProviderPaymentID))
+	default:
+		return nil, err
+	}
+	if err := s.repo.UpsertDispute(ctx, d); err != nil {
+		return nil, err
+	}
+	if d.OrderID == nil || s.orders == nil {
+		return d, nil
+	}
+
+	if d.ClosedAt == nil {
+		if err := s.orders.Freeze(ctx, *d.OrderID, fmt.Sprintf("payment dispute %s (%s)", d.ProviderDisputeID, d.Status)); err != nil {
+			s.log.Error("freeze disputed order", zap.String("order_id", d.OrderID.String()), zap.Error(err))
+		}
+		return d, nil
+	}
+	open, err := s.repo.CountOpenDisputes(ctx, *d.OrderID)
+	if err != nil {
+		return d, err
+	}
+	if open == 0 {
+		if err := s.orders.Unfreeze(ctx, *d.OrderID); err != nil {
+			s.log.Error("unfreeze order", zap.String("order_id", d.OrderID.String()), zap.Error(err))
+		}
+	}
+	return d, nil
+}
+
+func (s *service) ListDisputes(ctx context.Context, open bool, limit, offset int) ([]Dispute, error) {
+	return s.repo.ListDisputes(ctx, open, limit, offset)
+}
+
+func (s *service) CountDisputes(ctx context.Context, open bool) (int, error) {
+	return s.repo.CountDisputes(ctx, open)
+}
+
+// stripeDispute is the part of a Stripe dispute object we use
+type stripeDispute struct {
+	ID              string `json:"id"`
+	Amount          int64  `json:"amount"`
+	Currency        string `json:"currency"`
+	Charge          string `json:"charge"`
+	PaymentIntent   string `json:"payment_intent"`
+	Reason          string `json:"reason"`
+	Status          string `json:"status"`
+	EvidenceDetails struct {
+		DueBy int64 `json:"due_by"`
+	} `json:"evidence_details"`
+}
+
+// ParseStripeDispute verifies the Stripe-Signature header and converts a
+// charge.dispute.* event into a DisputeEvent. Other event types return nil.
+func ParseStripeDispute(payload []byte, signature string, secret []byte, tolerance time.Duration) (*DisputeEvent, error) {
+	if err := verifyStripeSignature(payload, signature, secret, tolerance); err != nil {
+		return nil, err
+	}
+	var event struct {
+		Type string `json:"type"`
+		Data struct {
+			Object stripeDispute `json:"object"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(event.Type, "charge.dispute.") {
+		return nil, nil
+	}
+	obj := event.Data.Object
+	paymentID := obj.PaymentIntent
+	if paymentID == "" {
+		paymentID = obj.Charge
+	}
+	e := &DisputeEvent{
+		Provider:          "stripe",
+		ProviderDisputeID: obj.ID,
+		ProviderPaymentID: paymentID,
+		Status:            obj.Status,
+		Amount:            decimal.New(obj.Amount, -2), // minor units
+		Currency:          obj.Currency,
+	}
+	if obj.Reason != "" {
+		e.Reason = &obj.Reason
+	}
+	if obj.EvidenceDetails.DueBy > 0 {
+		due := time.Unix(obj.EvidenceDetails.DueBy, 0).UTC()
+		e.EvidenceDueBy = &due
+	}
+	return e, nil
+}
+
+// verifyStripeSignature checks a "t=<unix>,v1=<hex hmac>" header against
+// HMAC-SHA256(secret, "<t>.<payload>").
+func verifyStripeSignature(payload []byte, header string, secret []byte, tolerance time.Duration) error {
+	if len(secret) == 0 {
+		return ErrorInvalidSignature
+	}
+	var ts int64
+	var sigs []string
+	for _, part := range strings.Split(header, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch k {
+		case "t":
+			ts, _ = strconv.ParseInt(v, 10, 64)
+		case "v1":
+			sigs = append(sigs, v)
+		}
+	}
+	if ts == 0 || len(sigs) == 0 {
+		return ErrorInvalidSignature
+	}
+	if age := time.Since(time.Unix(ts, 0)); age > tolerance || age < -tolerance {
+		return ErrorInvalidSignature
+	}
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "%d.%s", ts, payload)
+	expected := hex.EncodeToString(mac.Sum(nil))
+	for _, sig := range sigs {
+		if hmac.Equal([]byte(sig), []byte(expected)) {
+			return nil
+		}
+	}
+	return ErrorInvalidSignature
+}
+
+// VerifySignature checks a hex HMAC-SHA256 of payload, as sent by the
+// generic chargeback webhook in X-Signature.
+func VerifySignature(payload []byte, signature string, secret []byte) error {
+	if len(secret) == 0 {
+		return ErrorInvalidSignature
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	if !hmac.Equal([]byte(signature), []byte(hex.EncodeToString(mac.Sum(nil)))) {
+		return ErrorInvalidSignature
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package Billing
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// RefundRequest refunds the given amount, or everything still refundable when
+// omitted. RequestedBy is required for refunds above the approval threshold.
+type RefundRequest struct {
+	Amount      *decimal.Decimal `json:"amount,omitempty"`
+	Reason      *string          `json:"reason,omitempty" validate:"omitempty,max=500"`
+	RequestedBy string           `json:"requested_by,omitempty" validate:"omitempty,max=200"`
+}
+
+// DisputeEvent is a provider dispute notification normalised for RecordDispute;
+// it is also the payload of the generic chargeback webhook.
+type DisputeEvent struct {
+	Provider          string          `json:"provider" validate:"required,max=50"`
+	ProviderDisputeID string          `json:"dispute_id" validate:"required,max=255"`
+	ProviderPaymentID string          `json:"payment_id" validate:"required,max=255"`
+	Reason            *string         `json:"reason,omitempty"`
+	Status            string          `json:"status" validate:"required,max=30"`
+	Amount            decimal.Decimal `json:"amount"`
+	Currency          string          `json:"currency" validate:"required,len=3"`
+	EvidenceDueBy     *time.Time      `json:"evidence_due_by,omitempty"`
+}
+
+// PaymentFilter narrows the payments list; Metadata matches payments whose
+// metadata contains every given key/value pair.
+type PaymentFilter struct {
+	InvoiceID *uuid.UUID
+	Provider  string
+	Status    string
+	Metadata  map[string]string
+	Limit     int
+	Offset    int
+}
+
+type CreateOfflineMethodRuleRequest struct {
+	Method       string  `json:"method" validate:"required,oneof=COD BANK_TRANSFER"`
+	Warehouse    *string `json:"warehouse,omitempty" validate:"omitempty,max=50"`
+	Region       *string `json:"region,omitempty" validate:"omitempty,max=100"`
+	Instructions *string `json:"instructions,omitempty"`
+	DueInDays    int     `json:"due_in_days" validate:"min=0,max=365"`
+}
+
+// SetCreditAccountRequest approves a customer for payment terms such as NET30
+type SetCreditAccountRequest struct {
+	PaymentTerms string          `json:"payment_terms" validate:"required,max=10"`
+	CreditLimit  decimal.Decimal `json:"credit_limit"`
+	Currency     string          `json:"currency" validate:"required,len=3"`
+	// BLOCK (default) refuses terms orders over the limit, FLAG accepts them
+	// and flags their invoices
+	OnExceed string `json:"on_exceed,omitempty" validate:"omitempty,oneof=BLOCK FLAG"`
+}
+
+// ExposureQuery filters the credit exposure report
+type ExposureQuery struct {
+	CustomerID *uuid.UUID
+	Currency   string
+	OverLimit  bool
+	Limit      int
+	Offset     int
+}
+
+// StatementQuery picks the period and currency of a customer statement; an
+// empty currency is that of the customer's credit account, else USD
+type StatementQuery struct {
+	From     time.Time
+	To       time.Time
+	Currency string
+}
+
+// RecordPaymentRequest settles (part of) an offline invoice
+type RecordPaymentRequest struct {
+	Method     string          `json:"method" validate:"required,oneof=COD BANK_TRANSFER"`
+	Amount     decimal.Decimal `json:"amount"`
+	Reference  string          `json:"reference" validate:"required,max=255"`
+	ReceivedAt *time.Time      `json:"received_at,omitempty"`
+	RecordedBy string          `json:"recorded_by" validate:"required,max=200"`
+	Note       *string         `json:"note,omitempty" validate:"omitempty,max=1000"`
+}
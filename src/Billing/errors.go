@@ -0,0 +1,60 @@
+package Billing
+
+import (
+	"errors"
+
+	"savannah/src/ErrorCodes"
+)
+
+var (
+	ErrorInvoiceNotFound    = errors.New("invoice not found")
+	ErrorNotRefundable      = errors.New("invoice is not paid")
+	ErrorRefundTooLarge     = errors.New("refund exceeds the refundable amount")
+	ErrorInvalidAmount      = errors.New("refund amount must be positive")
+	ErrorNoPaymentToRefund  = errors.New("no payment with a provider reference to refund")
+	ErrorInvalidSignature   = errors.New("invalid webhook signature")
+	ErrorNoAuthorization    = errors.New("no open payment authorization for order")
+	ErrorMethodUnavailable  = errors.New("payment method not available for this warehouse or region")
+	ErrorRuleNotFound       = errors.New("payment method rule not found")
+	ErrorDuplicateReference = errors.New("payment with this reference already recorded")
+	ErrorOverpayment        = errors.New("payment exceeds the outstanding invoice amount")
+	ErrorInvoiceSettled     = errors.New("invoice is not awaiting payment")
+	ErrorInvalidTerms       = errors.New("payment terms must be NET followed by 1 to 365 days")
+	ErrorInvalidCreditLimit = errors.New("credit limit must not be negative")
+	ErrorNoCreditAccount    = errors.New("customer is not approved for payment terms")
+	ErrorTermsNotApproved   = errors.New("payment terms are longer than the customer is approved for")
+	ErrorCreditCurrency     = errors.New("order currency differs from the customer's credit account")
+	ErrorCreditLimit        = errors.New("order exceeds the customer's available credit")
+	ErrorUnknownCustomer    = errors.New("customer not found")
+	ErrorStatementPeriod    = errors.New("statement period must end after it starts and span at most 366 days")
+	// ErrorChargeDeclined is wrapped by providers for definitive declines;
+	// any other charge error is treated as an unknown outcome and retried
+	// with the same idempotency key.
+	ErrorChargeDeclined = errors.New("charge declined")
+)
+
+func init() {
+	ErrorCodes.Register("BILLING",
+		ErrorCodes.Def{N: 1, Slug: "INVOICE_NOT_FOUND", Err: ErrorInvoiceNotFound},
+		ErrorCodes.Def{N: 2, Slug: "NOT_REFUNDABLE", Err: ErrorNotRefundable},
+		ErrorCodes.Def{N: 3, Slug: "REFUND_TOO_LARGE", Err: ErrorRefundTooLarge},
+		ErrorCodes.Def{N: 4, Slug: "INVALID_REFUND_AMOUNT", Err: ErrorInvalidAmount},
+		ErrorCodes.Def{N: 5, Slug: "NO_PAYMENT_TO_REFUND", Err: ErrorNoPaymentToRefund},
+		ErrorCodes.Def{N: 6, Slug: "INVALID_WEBHOOK_SIGNATURE", Err: ErrorInvalidSignature},
+		ErrorCodes.Def{N: 7, Slug: "NO_AUTHORIZATION", Err: ErrorNoAuthorization},
+		ErrorCodes.Def{N: 8, Slug: "METHOD_UNAVAILABLE", Err: ErrorMethodUnavailable},
+		ErrorCodes.Def{N: 9, Slug: "RULE_NOT_FOUND", Err: ErrorRuleNotFound},
+		ErrorCodes.Def{N: 10, Slug: "DUPLICATE_PAYMENT_REFERENCE", Err: ErrorDuplicateReference},
+		ErrorCodes.Def{N: 11, Slug: "OVERPAYMENT", Err: ErrorOverpayment},
+		ErrorCodes.Def{N: 12, Slug: "INVOICE_SETTLED", Err: ErrorInvoiceSettled},
+		ErrorCodes.Def{N: 13, Slug: "CHARGE_DECLINED", Err: ErrorChargeDeclined},
+		ErrorCodes.Def{N: 14, Slug: "INVALID_TERMS", Err: ErrorInvalidTerms},
+		ErrorCodes.Def{N: 15, Slug: "INVALID_CREDIT_LIMIT", Err: ErrorInvalidCreditLimit},
+		ErrorCodes.Def{N: 16, Slug: "NO_CREDIT_ACCOUNT", Err: ErrorNoCreditAccount},
+		ErrorCodes.Def{N: 17, Slug: "TERMS_NOT_APPROVED", Err: ErrorTermsNotApproved},
+		ErrorCodes.Def{N: 18, Slug: "CREDIT_CURRENCY_MISMATCH", Err: ErrorCreditCurrency},
+		ErrorCodes.Def{N: 19, Slug: "CREDIT_LIMIT_EXCEEDED", Err: ErrorCreditLimit},
+		ErrorCodes.Def{N: 20, Slug: "UNKNOWN_CUSTOMER", Err: ErrorUnknownCustomer},
+		ErrorCodes.Def{N: 21, Slug: "INVALID_STATEMENT_PERIOD", Err: ErrorStatementPeriod},
+	)
+}
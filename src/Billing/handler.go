@@ -0,0 +1,721 @@
+package Billing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+	"savannah/src/ErrorCodes"
+	"savannah/src/Pagination"
+)
+
+// WebhookSecrets authenticate inbound provider webhooks
+type WebhookSecrets struct {
+	Stripe     []byte
+	Chargeback []byte
+}
+
+// RefundApproval holds refunds above a threshold for a second admin. The
+// threshold is that of the caller's role in Roles, else Threshold; a zero
+// Threshold refunds everything right away, while a role set to zero needs
+// approval for any refund. Request records the pending approval and returns
+// it; Record, when set, audits every refund run without one.
+type RefundApproval struct {
+	Threshold decimal.Decimal
+	Roles     map[string]decimal.Decimal
+	Role      func(ctx context.Context) string
+	Request   func(ctx context.Context, orderID uuid.UUID, amount decimal.Decimal, reason *string, requestedBy string) (interface{}, error)
+	Record    func(ctx context.Context, orderID uuid.UUID, amount *decimal.Decimal, reason *string, requestedBy, policy string, refunds interface{}, err error) error
+}
+
+// limit returns the threshold for the caller and whether refunds above it
+// need approval, with the policy applied for the audit trail
+func (a RefundApproval) limit(ctx context.Context) (decimal.Decimal, bool, string) {
+	if a.Role != nil {
+		role := a.Role(ctx)
+		if t, ok := a.Roles[role]; ok {
+			return t, true, "role " + role + " refunds up to " + t.StringFixed(2)
+		}
+	}
+	if a.Threshold.IsPositive() {
+		return a.Threshold, true, "refunds up to " + a.Threshold.StringFixed(2)
+	}
+	return decimal.Zero, false, "no refund approval threshold"
+}
+
+type Handler struct {
+	svc      Service
+	webhooks WebhookSecrets
+	approval RefundApproval
+	log      *zap.Logger
+	v        *validator.Validate
+}
+
+func NewHandler(s Service, webhooks WebhookSecrets, approval RefundApproval, log *zap.Logger) *Handler {
+	return &Handler{svc: s, webhooks: webhooks, approval: approval, log: log, v: validator.New()}
+}
+
+// RefundOrder godoc
+// @Summary      Refund an order
+// @Description  Refunds to the original payment method(s) of the order's invoice; omit amount for a full refund.
+// @Description  Refunds above the approval threshold of the caller's role (else the default threshold) are not executed: a pending approval is returned with 202 and runs once a second admin approves it. Refunds within it run at once and are recorded as auto-approved approvals for the audit trail.
+// @Tags         billing
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string         true  "Order ID"
+// @Param        payload  body      RefundRequest  true  "Refund"
+// @Success      201      {array}   Refund
+// @Success      202      {object}  map[string]interface{}
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      404      {object}  map[string]interface{}
+// @Failure      409      {object}  map[string]interface{}
+// @Failure      502      {object}  map[string]interface{}
+// @Router       /orders/{id}/refunds [post]
+func (h *Handler) RefundOrder(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var dto RefundRequest
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if err := h.v.Struct(dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	threshold, limited, policy := h.approval.limit(r.Context())
+	if limited && h.holdRefund(w, r, id, dto, threshold) {
+		return
+	}
+	refunds, err := h.svc.RefundOrder(r.Context(), id, dto.Amount, dto.Reason)
+	h.auditRefund(r.Context(), id, dto, policy, refunds, err)
+	if err != nil {
+		h.refundError(w, id, err)
+		return
+	}
+	h.writeJSON(w, http.StatusCreated, refunds)
+}
+
+// auditRefund records a refund that ran without approval. Refusals that
+// change nothing, such as an amount over what is refundable, are not
+// recorded; a failed audit is logged, as the money has already moved.
+func (h *Handler) auditRefund(ctx context.Context, id uuid.UUID, dto RefundRequest, policy string, refunds []Refund, err error) {
+	if h.approval.Record == nil || errors.Is(err, ErrorInvoiceNotFound) || errors.Is(err, ErrorInvalidAmount) || errors.Is(err, ErrorRefundTooLarge) || errors.Is(err, ErrorNotRefundable) || errors.Is(err, ErrorNoPaymentToRefund) {
+		return
+	}
+	var result interface{}
+	if err == nil {
+		result = refunds
+	}
+	if rerr := h.approval.Record(ctx, id, dto.Amount, dto.Reason, dto.RequestedBy, policy, result, err); rerr != nil {
+		h.log.Error("audit refund", zap.String("order_id", id.String()), zap.Error(rerr))
+	}
+}
+
+// holdRefund requests approval for a refund above the threshold; it reports
+// whether the response was written.
+func (h *Handler) holdRefund(w http.ResponseWriter, r *http.Request, id uuid.UUID, dto RefundRequest, threshold decimal.Decimal) bool {
+	// check the amount now so that approvers only see refunds that can run
+	amount, err := h.svc.RefundableAmount(r.Context(), id)
+	if err != nil {
+		h.refundError(w, id, err)
+		return true
+	}
+	if dto.Amount != nil {
+		if !dto.Amount.IsPositive() {
+			h.refundError(w, id, ErrorInvalidAmount)
+			return true
+		}
+		if dto.Amount.GreaterThan(amount) {
+			h.refundError(w, id, ErrorRefundTooLarge)
+			return true
+		}
+		amount = *dto.Amount
+	}
+	if !amount.GreaterThan(threshold) {
+		return false
+	}
+	if dto.RequestedBy == "" {
+		h.writeError(w, http.StatusBadRequest, "requested_by is required for refunds above "+threshold.String())
+		return true
+	}
+	approval, err := h.approval.Request(r.Context(), id, amount, dto.Reason, dto.RequestedBy)
+	if err != nil {
+		h.log.Error("request refund approval", zap.String("order_id", id.String()), zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to request refund approval")
+		return true
+	}
+	h.writeJSON(w, http.StatusAccepted, approval)
+	return true
+}
+
+func (h *Handler) refundError(w http.ResponseWriter, id uuid.UUID, err error) {
+	switch {
+	case errors.Is(err, ErrorInvoiceNotFound):
+		h.writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrorInvalidAmount), errors.Is(err, ErrorRefundTooLarge):
+		h.writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrorNotRefundable), errors.Is(err, ErrorNoPaymentToRefund):
+		h.writeError(w, http.StatusConflict, err.Error())
+	default:
+		h.log.Error("refund order", zap.String("order_id", id.String()), zap.Error(err))
+		h.writeError(w, http.StatusBadGateway, "refund failed")
+	}
+}
+
+// ListRefunds godoc
+// @Summary      List refunds of an order
+// @Tags         billing
+// @Produce      json
+// @Param        id   path      string  true  "Order ID"
+// @Success      200  {array}   Refund
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /orders/{id}/refunds [get]
+func (h *Handler) ListRefunds(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	refunds, err := h.svc.ListRefunds(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, ErrorInvoiceNotFound) {
+			h.writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		h.log.Error("list refunds", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to list refunds")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, refunds)
+}
+
+// CapturePayment godoc
+// @Summary      Capture an order's payment authorization
+// @Tags         billing
+// @Produce      json
+// @Param        id   path      string  true  "Order ID"
+// @Success      200  {object}  Payment
+// @Failure      409  {object}  map[string]interface{}
+// @Failure      502  {object}  map[string]interface{}
+// @Router       /orders/{id}/payment/capture [post]
+func (h *Handler) CapturePayment(w http.ResponseWriter, r *http.Request) {
+	h.settle(w, r, "capture", h.svc.CaptureOrder)
+}
+
+// VoidPayment godoc
+// @Summary      Void an order's payment authorization
+// @Tags         billing
+// @Produce      json
+// @Param        id   path      string  true  "Order ID"
+// @Success      200  {object}  Payment
+// @Failure      409  {object}  map[string]interface{}
+// @Failure      502  {object}  map[string]interface{}
+// @Router       /orders/{id}/payment/void [post]
+func (h *Handler) VoidPayment(w http.ResponseWriter, r *http.Request) {
+	h.settle(w, r, "void", h.svc.VoidOrder)
+}
+
+func (h *Handler) settle(w http.ResponseWriter, r *http.Request, action string, fn func(ctx context.Context, orderID uuid.UUID) (*Payment, error)) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	p, err := fn(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, ErrorNoAuthorization) {
+			h.writeError(w, http.StatusConflict, err.Error())
+			return
+		}
+		h.log.Error(action+" payment", zap.String("order_id", id.String()), zap.Error(err))
+		h.writeError(w, http.StatusBadGateway, action+" failed")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, p)
+}
+
+// RecordPayment godoc
+// @Summary      Record an offline payment
+// @Description  Settles a COD, bank transfer or payment terms invoice with the collection/transfer reference; partial amounts are allowed
+// @Tags         billing
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                true  "Order ID"
+// @Param        payload  body      RecordPaymentRequest  true  "Payment"
+// @Success      201      {object}  Payment
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      404      {object}  map[string]interface{}
+// @Failure      409      {object}  map[string]interface{}
+// @Router       /orders/{id}/payment/record [post]
+func (h *Handler) RecordPayment(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var dto RecordPaymentRequest
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if err := h.v.Struct(dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	p, err := h.svc.RecordPayment(r.Context(), id, dto)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrorInvoiceNotFound):
+			h.writeError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, ErrorInvalidAmount), errors.Is(err, ErrorOverpayment):
+			h.writeError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, ErrorInvoiceSettled), errors.Is(err, ErrorDuplicateReference):
+			h.writeError(w, http.StatusConflict, err.Error())
+		default:
+			h.log.Error("record payment", zap.String("order_id", id.String()), zap.Error(err))
+			h.writeError(w, http.StatusInternalServerError, "failed to record payment")
+		}
+		return
+	}
+	h.writeJSON(w, http.StatusCreated, p)
+}
+
+// GetCreditAccount godoc
+// @Summary      Customer credit account
+// @Description  The payment terms the customer is approved for, the credit limit and the customer's exposure: what unpaid invoices in the account currency still owe, how much of that is overdue, and the credit left
+// @Tags         billing
+// @Produce      json
+// @Param        id   path      string  true  "Customer ID"
+// @Success      200  {object}  CreditAccount
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /customers/{id}/credit-account [get]
+func (h *Handler) GetCreditAccount(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	a, err := h.svc.GetCreditAccount(r.Context(), id)
+	if err != nil {
+		h.creditError(w, "get credit account", err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, a)
+}
+
+// SetCreditAccount godoc
+// @Summary      Approve a customer for payment terms
+// @Description  Creates or replaces the customer's credit account. Orders placed with payment_terms up to the approved terms are invoiced instead of charged, due after the terms. An order that would take unpaid invoices in the account currency over the credit limit is refused (on_exceed BLOCK, the default) or accepted with its invoice flagged over_credit_limit (FLAG).
+// @Tags         billing
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                   true  "Customer ID"
+// @Param        payload  body      SetCreditAccountRequest  true  "Terms and limit"
+// @Success      200      {object}  CreditAccount
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      404      {object}  map[string]interface{}
+// @Router       /customers/{id}/credit-account [put]
+func (h *Handler) SetCreditAccount(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var dto SetCreditAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if err := h.v.Struct(dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	a, err := h.svc.SetCreditAccount(r.Context(), id, dto)
+	if err != nil {
+		h.creditError(w, "set credit account", err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, a)
+}
+
+// DeleteCreditAccount godoc
+// @Summary      Withdraw a customer's payment terms
+// @Description  Later orders must be paid upfront; invoices already issued on terms stay due.
+// @Tags         billing
+// @Param        id   path  string  true  "Customer ID"
+// @Success      204
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /customers/{id}/credit-account [delete]
+func (h *Handler) DeleteCreditAccount(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	if err := h.svc.DeleteCreditAccount(r.Context(), id); err != nil {
+		h.creditError(w, "delete credit account", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CreditExposure godoc
+// @Summary      Credit exposure report
+// @Description  Every credit account with its customer's exposure, the furthest over (or closest to) its limit first. utilization is outstanding over the limit; flagged_invoices counts open invoices accepted over the limit.
+// @Tags         billing
+// @Produce      json
+// @Param        currency    query     string  false  "Only accounts in this currency"
+// @Param        over_limit  query     bool    false  "Only accounts whose exposure exceeds the limit"
+// @Param        limit       query     int     false  "Page size (max 500)"
+// @Param        offset      query     int     false  "Offset"
+// @Success      200         {array}   CreditAccount
+// @Router       /credit-exposure [get]
+func (h *Handler) CreditExposure(w http.ResponseWriter, r *http.Request) {
+	v := r.URL.Query()
+	q := ExposureQuery{Currency: v.Get("currency"), OverLimit: v.Get("over_limit") == "true"}
+	q.Limit, _ = strconv.Atoi(v.Get("limit"))
+	q.Offset, _ = strconv.Atoi(v.Get("offset"))
+	if q.Offset < 0 {
+		q.Offset = 0
+	}
+	accounts, err := h.svc.CreditExposure(r.Context(), q)
+	if err != nil {
+		h.log.Error("credit exposure", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to get credit exposure")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, accounts)
+}
+
+// Statement godoc
+// @Summary      Customer account statement
+// @Description  The customer's invoices, payments and credits in [from, to) in one currency with the running balance, opening with what was owed at from and closing with what is owed at to. A refund is listed as a credit note against its invoice and the matching refund paid out. The period defaults to the last 30 days and may span at most 366 days; the currency defaults to that of the customer's credit account, else USD.
+// @Tags         billing
+// @Produce      json
+// @Produce      text/csv
+// @Produce      application/pdf
+// @Param        id        path      string  true   "Customer ID"
+// @Param        from      query     string  false  "Start, RFC 3339"
+// @Param        to        query     string  false  "End (exclusive), RFC 3339"
+// @Param        currency  query     string  false  "ISO currency"
+// @Param        format    query     string  false  "json (default), csv or pdf"
+// @Success      200       {object}  Statement
+// @Failure      400       {object}  map[string]interface{}
+// @Router       /customers/{id}/statement [get]
+func (h *Handler) Statement(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	q := r.URL.Query()
+	sq := StatementQuery{To: time.Now().UTC(), Currency: q.Get("currency")}
+	sq.From = sq.To.AddDate(0, 0, -30)
+	if v := q.Get("from"); v != "" {
+		if sq.From, err = time.Parse(time.RFC3339, v); err != nil {
+			h.writeError(w, http.StatusBadRequest, "invalid from")
+			return
+		}
+	}
+	if v := q.Get("to"); v != "" {
+		if sq.To, err = time.Parse(time.RFC3339, v); err != nil {
+			h.writeError(w, http.StatusBadRequest, "invalid to")
+			return
+		}
+	}
+	format := q.Get("format")
+	if format != "" && format != "json" && format != "csv" && format != "pdf" {
+		h.writeError(w, http.StatusBadRequest, "format must be json, csv or pdf")
+		return
+	}
+	st, err := h.svc.Statement(r.Context(), id, sq)
+	if err != nil {
+		if errors.Is(err, ErrorStatementPeriod) {
+			h.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		h.log.Error("customer statement", zap.String("customer_id", id.String()), zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to build statement")
+		return
+	}
+	filename := fmt.Sprintf("statement-%s-%s.%s", id, st.To.Format("2006-01-02"), format)
+	switch format {
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		if err := st.WriteCSV(w); err != nil {
+			h.log.Error("write statement", zap.Error(err))
+		}
+	case "pdf":
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", filename))
+		_, _ = w.Write(st.PDF())
+	default:
+		h.writeJSON(w, http.StatusOK, st)
+	}
+}
+
+func (h *Handler) creditError(w http.ResponseWriter, op string, err error) {
+	switch {
+	case errors.Is(err, ErrorNoCreditAccount), errors.Is(err, ErrorUnknownCustomer):
+		h.writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrorInvalidTerms), errors.Is(err, ErrorInvalidCreditLimit):
+		h.writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		h.log.Error(op, zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to "+op)
+	}
+}
+
+// AvailablePaymentMethods godoc
+// @Summary      Offline payment methods available at checkout
+// @Tags         billing
+// @Produce      json
+// @Param        warehouse  query     string  false  "Warehouse code"
+// @Param        region     query     string  false  "Delivery region"
+// @Success      200        {array}   OfflineMethodRule
+// @Router       /payment-methods [get]
+func (h *Handler) AvailablePaymentMethods(w http.ResponseWriter, r *http.Request) {
+	list, err := h.svc.AvailableOfflineMethods(r.Context(), r.URL.Query().Get("warehouse"), r.URL.Query().Get("region"))
+	if err != nil {
+		h.log.Error("available payment methods", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to list payment methods")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, list)
+}
+
+// ListPaymentMethodRules godoc
+// @Summary      List offline payment method rules
+// @Tags         billing
+// @Produce      json
+// @Success      200  {array}  OfflineMethodRule
+// @Router       /payment-methods/rules [get]
+func (h *Handler) ListPaymentMethodRules(w http.ResponseWriter, r *http.Request) {
+	list, err := h.svc.ListOfflineMethodRules(r.Context())
+	if err != nil {
+		h.log.Error("list payment method rules", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to list rules")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, list)
+}
+
+// CreatePaymentMethodRule godoc
+// @Summary      Enable an offline payment method for a warehouse and/or region
+// @Tags         billing
+// @Accept       json
+// @Produce      json
+// @Param        payload  body      CreateOfflineMethodRuleRequest  true  "Rule"
+// @Success      201      {object}  OfflineMethodRule
+// @Failure      400      {object}  map[string]interface{}
+// @Router       /payment-methods/rules [post]
+func (h *Handler) CreatePaymentMethodRule(w http.ResponseWriter, r *http.Request) {
+	var dto CreateOfflineMethodRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if err := h.v.Struct(dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	rule, err := h.svc.CreateOfflineMethodRule(r.Context(), dto)
+	if err != nil {
+		h.log.Error("create payment method rule", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to create rule")
+		return
+	}
+	h.writeJSON(w, http.StatusCreated, rule)
+}
+
+// DeletePaymentMethodRule godoc
+// @Summary      Remove an offline payment method rule
+// @Tags         billing
+// @Param        id   path  string  true  "Rule ID"
+// @Success      204
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /payment-methods/rules/{id} [delete]
+func (h *Handler) DeletePaymentMethodRule(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	if err := h.svc.DeleteOfflineMethodRule(r.Context(), id); err != nil {
+		if errors.Is(err, ErrorRuleNotFound) {
+			h.writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		h.log.Error("delete payment method rule", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to delete rule")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListPayments godoc
+// @Summary      List payments
+// @Description  Filter by invoice_id, provider, status and metadata keys, e.g. ?metadata.channel=pos&metadata.till=3
+// @Tags         billing
+// @Produce      json
+// @Param        invoice_id  query     string  false  "Invoice ID"
+// @Param        provider    query     string  false  "Provider"
+// @Param        status      query     string  false  "Payment status"
+// @Param        limit       query     int     false  "Page size (max 200)"
+// @Param        offset      query     int     false  "Offset"
+// @Param        page        query     int     false  "Page of limit payments, 1-based; instead of offset"
+// @Success      200         {array}   Payment
+// @Header       200         {string}  Link    "first, prev, next and last pages (RFC 5988), with X-Total-Count, X-Page and X-Total-Pages"
+// @Router       /payments [get]
+func (h *Handler) ListPayments(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f := PaymentFilter{Provider: q.Get("provider"), Status: q.Get("status"), Metadata: map[string]string{}}
+	if v := q.Get("invoice_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			h.writeError(w, http.StatusBadRequest, "invalid invoice_id")
+			return
+		}
+		f.InvoiceID = &id
+	}
+	for key, values := range q {
+		if k, ok := strings.CutPrefix(key, "metadata."); ok && k != "" && len(values) > 0 {
+			f.Metadata[k] = values[0]
+		}
+	}
+	f.Limit, f.Offset = Pagination.Params(q, 50, 200)
+	list, err := h.svc.ListPayments(r.Context(), f)
+	var total int
+	if err == nil {
+		total, err = h.svc.CountPayments(r.Context(), f)
+	}
+	if err != nil {
+		h.log.Error("list payments", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to list payments")
+		return
+	}
+	Pagination.Write(w, r, f.Limit, f.Offset, total)
+	h.writeJSON(w, http.StatusOK, list)
+}
+
+// ListDisputes godoc
+// @Summary      List payment disputes
+// @Description  Disputes and chargebacks ordered by evidence due date; status=open hides closed ones
+// @Tags         billing
+// @Produce      json
+// @Param        status  query     string  false  "open"
+// @Param        limit   query     int     false  "Page size (max 200)"
+// @Param        offset  query     int     false  "Offset"
+// @Param        page    query     int     false  "Page of limit disputes, 1-based; instead of offset"
+// @Success      200     {array}   Dispute
+// @Header       200     {string}  Link    "first, prev, next and last pages (RFC 5988), with X-Total-Count, X-Page and X-Total-Pages"
+// @Router       /disputes [get]
+func (h *Handler) ListDisputes(w http.ResponseWriter, r *http.Request) {
+	limit, offset := Pagination.Params(r.URL.Query(), 50, 200)
+	open := r.URL.Query().Get("status") == "open"
+	list, err := h.svc.ListDisputes(r.Context(), open, limit, offset)
+	var total int
+	if err == nil {
+		total, err = h.svc.CountDisputes(r.Context(), open)
+	}
+	if err != nil {
+		h.log.Error("list disputes", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to list disputes")
+		return
+	}
+	Pagination.Write(w, r, limit, offset, total)
+	h.writeJSON(w, http.StatusOK, list)
+}
+
+// StripeWebhook godoc
+// @Summary      Stripe dispute webhook
+// @Description  Receives charge.dispute.* events; other event types are acknowledged and ignored
+// @Tags         webhooks
+// @Accept       json
+// @Success      200
+// @Failure      400  {object}  map[string]interface{}
+// @Router       /webhooks/stripe [post]
+func (h *Handler) StripeWebhook(w http.ResponseWriter, r *http.Request) {
+	payload, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	event, err := ParseStripeDispute(payload, r.Header.Get("Stripe-Signature"), h.webhooks.Stripe, 5*time.Minute)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if event != nil {
+		if _, err := h.svc.RecordDispute(r.Context(), *event); err != nil {
+			h.log.Error("record stripe dispute", zap.Error(err))
+			h.writeError(w, http.StatusInternalServerError, "failed to record dispute")
+			return
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// ChargebackWebhook godoc
+// @Summary      Card chargeback webhook
+// @Description  Normalised chargeback notifications from acquirers, signed with X-Signature (hex HMAC-SHA256 of the body)
+// @Tags         webhooks
+// @Accept       json
+// @Param        payload  body  DisputeEvent  true  "Chargeback"
+// @Success      200
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Router       /webhooks/chargebacks [post]
+func (h *Handler) ChargebackWebhook(w http.ResponseWriter, r *http.Request) {
+	payload, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	if VerifySignature(payload, r.Header.Get("X-Signature"), h.webhooks.Chargeback) != nil {
+		h.writeError(w, http.StatusUnauthorized, ErrorInvalidSignature.Error())
+		return
+	}
+	var dto DisputeEvent
+	if err := json.Unmarshal(payload, &dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if err := h.v.Struct(dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	d, err := h.svc.RecordDispute(r.Context(), dto)
+	if err != nil {
+		h.log.Error("record chargeback", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to record dispute")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, d)
+}
+
+func (h *Handler) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (h *Handler) writeError(w http.ResponseWriter, status int, msg string) {
+	h.writeJSON(w, status, map[string]interface{}{"error": msg, "code": ErrorCodes.Resolve("BILLING", status, msg), "timestamp": time.Now().UTC()})
+}
@@ -17,8 +17,24 @@ type Invoice struct {
 	IssuedAt      time.Time       `db:"issued_at" json:"issued_at"`
 	DueAt         *time.Time      `db:"due_at" json:"due_at,omitempty"`
 	PaidAt        *time.Time      `db:"paid_at" json:"paid_at,omitempty"`
+	ERPSyncStatus string          `db:"erp_sync_status" json:"erp_sync_status"`
+
+	// tax identity as issued, see TaxConfig
+	BuyerName      *string `db:"buyer_name" json:"buyer_name,omitempty"`
+	BuyerCompany   *string `db:"buyer_company" json:"buyer_company,omitempty"`
+	BuyerTaxID     *string `db:"buyer_tax_id" json:"buyer_tax_id,omitempty"`
+	BuyerTaxIDType *string `db:"buyer_tax_id_type" json:"buyer_tax_id_type,omitempty"`
+	BuyerCountry   *string `db:"buyer_country" json:"buyer_country,omitempty"`
+	SellerTaxID    *string `db:"seller_tax_id" json:"seller_tax_id,omitempty"`
+	ReverseCharge  bool    `db:"reverse_charge" json:"reverse_charge"`
+	TaxNote        *string `db:"tax_note" json:"tax_note,omitempty"`
+	// a payment terms invoice let through over the customer's credit limit
+	OverCreditLimit bool `db:"over_credit_limit" json:"over_credit_limit"`
 }
 
+// InvoiceColumns selects an Invoice from the invoices table
+const InvoiceColumns = `id,order_id,invoice_number,status,amount,currency,issued_at,due_at,paid_at,erp_sync_status,buyer_name,buyer_company,buyer_tax_id,buyer_tax_id_type,buyer_country,seller_tax_id,reverse_charge,tax_note,over_credit_limit`
+
 type Payment struct {
 	ID                uuid.UUID       `db:"id" json:"id"`
 	InvoiceID         uuid.UUID       `db:"invoice_id" json:"invoice_id"`
@@ -27,6 +43,197 @@ type Payment struct {
 	Amount            decimal.Decimal `db:"amount" json:"amount"`
 	Currency          string          `db:"currency" json:"currency"`
 	Status            string          `db:"status" json:"status"`
-	Metadata          []byte          `db:"metadata" json:"metadata"`
+	Metadata          Metadata        `db:"metadata" json:"metadata,omitempty"`
+	CreatedAt         time.Time       `db:"created_at" json:"created_at"`
+	ERPSyncStatus     string          `db:"erp_sync_status" json:"erp_sync_status"`
+
+	AuthorizedAt           *time.Time `db:"authorized_at" json:"authorized_at,omitempty"`
+	AuthorizationExpiresAt *time.Time `db:"authorization_expires_at" json:"authorization_expires_at,omitempty"`
+	CapturedAt             *time.Time `db:"captured_at" json:"captured_at,omitempty"`
+	VoidedAt               *time.Time `db:"voided_at" json:"voided_at,omitempty"`
+}
+
+// PaymentColumns selects a Payment from the payments table
+const PaymentColumns = `id,invoice_id,provider,provider_payment_id,amount,currency,status,metadata,created_at,erp_sync_status,authorized_at,authorization_expires_at,captured_at,voided_at`
+
+// payment statuses
+const (
+	PaymentProcessing = "PROCESSING"
+	PaymentAuthorized = "AUTHORIZED"
+	PaymentSuccess    = "SUCCESS"
+	PaymentFailed     = "FAILED"
+	PaymentVoided     = "VOIDED"
+	PaymentExpired    = "EXPIRED"
+)
+
+// Refund returns money to the payment that funded an invoice
+type Refund struct {
+	ID               uuid.UUID       `db:"id" json:"id"`
+	PaymentID        uuid.UUID       `db:"payment_id" json:"payment_id"`
+	InvoiceID        uuid.UUID       `db:"invoice_id" json:"invoice_id"`
+	Provider         string          `db:"provider" json:"provider"`
+	ProviderRefundID *string         `db:"provider_refund_id" json:"provider_refund_id,omitempty"`
+	Amount           decimal.Decimal `db:"amount" json:"amount"`
+	Currency         string          `db:"currency" json:"currency"`
+	Status           string          `db:"status" json:"status"`
+	Reason           *string         `db:"reason" json:"reason,omitempty"`
+	CreatedAt        time.Time       `db:"created_at" json:"created_at"`
+}
+
+// invoice statuses
+const (
+	InvoiceUnpaid            = "UNPAID"
+	InvoicePaid              = "PAID"
+	InvoicePartiallyRefunded = "PARTIALLY_REFUNDED"
+	InvoiceRefunded          = "REFUNDED"
+	InvoiceVoid              = "VOID"
+	InvoicePartiallyPaid     = "PARTIALLY_PAID"
+)
+
+// offline payment methods: the order ships with an unpaid invoice and the
+// payment is recorded manually once collected. TERMS is invoiced against the
+// customer's credit account, due after its net terms.
+const (
+	MethodCOD          = "COD"
+	MethodBankTransfer = "BANK_TRANSFER"
+	MethodTerms        = "TERMS"
+)
+
+// IsOfflineMethod reports whether method is settled manually rather than through a provider
+func IsOfflineMethod(method string) bool {
+	return method == MethodCOD || method == MethodBankTransfer || method == MethodTerms
+}
+
+// OfflineMethodRule enables an offline method for a warehouse and/or region;
+// an empty warehouse or region matches any.
+type OfflineMethodRule struct {
+	ID           uuid.UUID `db:"id" json:"id"`
+	Method       string    `db:"method" json:"method"`
+	Warehouse    *string   `db:"warehouse" json:"warehouse,omitempty"`
+	Region       *string   `db:"region" json:"region,omitempty"`
+	Instructions *string   `db:"instructions" json:"instructions,omitempty"`
+	DueInDays    int       `db:"due_in_days" json:"due_in_days"`
+	CreatedAt    time.Time `db:"created_at" json:"created_at"`
+}
+
+// what OnExceed does with a terms order over the credit limit
+const (
+	CreditBlock = "BLOCK"
+	CreditFlag  = "FLAG"
+)
+
+// CreditAccount approves a customer to order on payment terms while their
+// unpaid invoices in Currency (their exposure) stay within CreditLimit
+type CreditAccount struct {
+	CustomerID   uuid.UUID       `db:"customer_id" json:"customer_id"`
+	PaymentTerms string          `db:"payment_terms" json:"payment_terms"`
+	CreditLimit  decimal.Decimal `db:"credit_limit" json:"credit_limit"`
+	Currency     string          `db:"currency" json:"currency"`
+	OnExceed     string          `db:"on_exceed" json:"on_exceed"`
+	CreatedAt    time.Time       `db:"created_at" json:"created_at"`
+	UpdatedAt    time.Time       `db:"updated_at" json:"updated_at"`
+
+	// exposure: what is still owed on unpaid invoices in Currency, the part
+	// of it past due, and how many of those invoices there are and were let
+	// through over the limit
+	Outstanding     decimal.Decimal `db:"outstanding" json:"outstanding"`
+	Overdue         decimal.Decimal `db:"overdue" json:"overdue"`
+	OpenInvoices    int             `db:"open_invoices" json:"open_invoices"`
+	FlaggedInvoices int             `db:"flagged_invoices" json:"flagged_invoices"`
+	Available       decimal.Decimal `db:"-" json:"available"`
+	// Outstanding over CreditLimit; nil for a zero limit
+	Utilization *decimal.Decimal `db:"-" json:"utilization,omitempty"`
+	OverLimit   bool             `db:"-" json:"over_limit"`
+}
+
+// statement line types
+const (
+	StatementInvoice = "INVOICE"
+	StatementPayment = "PAYMENT"
+	StatementCredit  = "CREDIT"
+	StatementRefund  = "REFUND"
+)
+
+// StatementLine is one entry of a customer's account: an invoice is a debit
+// and a payment a credit. A refund gives two lines, the credit note against
+// the invoice and the money paid back to the customer, which cancel out.
+type StatementLine struct {
+	Date      time.Time       `db:"date" json:"date"`
+	Type      string          `db:"type" json:"type"`
+	Reference string          `db:"reference" json:"reference"`
+	InvoiceID uuid.UUID       `db:"invoice_id" json:"invoice_id"`
+	OrderID   uuid.UUID       `db:"order_id" json:"order_id"`
+	Debit     decimal.Decimal `db:"debit" json:"debit"`
+	Credit    decimal.Decimal `db:"credit" json:"credit"`
+	// what the customer owes after this line
+	Balance decimal.Decimal `db:"-" json:"balance"`
+}
+
+// Statement is a customer's account in one currency over [From, To):
+// what they owed at the start, every invoice, payment and credit in the
+// period with the running balance, and what they owe at the end
+type Statement struct {
+	CustomerID     uuid.UUID       `json:"customer_id"`
+	Currency       string          `json:"currency"`
+	From           time.Time       `json:"from"`
+	To             time.Time       `json:"to"`
+	OpeningBalance decimal.Decimal `json:"opening_balance"`
+	Debits         decimal.Decimal `json:"debits"`
+	Credits        decimal.Decimal `json:"credits"`
+	ClosingBalance decimal.Decimal `json:"closing_balance"`
+	Lines          []StatementLine `json:"lines"`
+}
+
+// dispute statuses; the provider's status is kept, these are the terminal ones
+const (
+	DisputeWon           = "WON"
+	DisputeLost          = "LOST"
+	DisputeWarningClosed = "WARNING_CLOSED"
+)
+
+// Dispute is a chargeback or payment dispute raised through the provider
+type Dispute struct {
+	ID                uuid.UUID       `db:"id" json:"id"`
+	Provider          string          `db:"provider" json:"provider"`
+	ProviderDisputeID string          `db:"provider_dispute_id" json:"provider_dispute_id"`
+	PaymentID         *uuid.UUID      `db:"payment_id" json:"payment_id,omitempty"`
+	InvoiceID         *uuid.UUID      `db:"invoice_id" json:"invoice_id,omitempty"`
+	OrderID           *uuid.UUID      `db:"order_id" json:"order_id,omitempty"`
+	ProviderPaymentID *string         `db:"provider_payment_id" json:"provider_payment_id,omitempty"`
+	Reason            *string         `db:"reason" json:"reason,omitempty"`
+	Status            string          `db:"status" json:"status"`
+	Amount            decimal.Decimal `db:"amount" json:"amount"`
+	Currency          string          `db:"currency" json:"currency"`
+	EvidenceDueBy     *time.Time      `db:"evidence_due_by" json:"evidence_due_by,omitempty"`
+	ClosedAt          *time.Time      `db:"closed_at" json:"closed_at,omitempty"`
 	CreatedAt         time.Time       `db:"created_at" json:"created_at"`
+	UpdatedAt         time.Time       `db:"updated_at" json:"updated_at"`
+}
+
+// Closed reports whether the dispute has reached a final outcome
+func (d *Dispute) Closed() bool {
+	return d.Status == DisputeWon || d.Status == DisputeLost || d.Status == DisputeWarningClosed
+}
+
+// attempt statuses
+const (
+	AttemptPending   = "PENDING"
+	AttemptSucceeded = "SUCCEEDED"
+	AttemptFailed    = "FAILED"
+)
+
+// PaymentAttempt is one charge attempt for an invoice. Its idempotency key is
+// sent to the provider, so replaying a PENDING attempt never charges twice.
+type PaymentAttempt struct {
+	ID                uuid.UUID  `db:"id" json:"id"`
+	InvoiceID         uuid.UUID  `db:"invoice_id" json:"invoice_id"`
+	Attempt           int        `db:"attempt" json:"attempt"`
+	IdempotencyKey    string     `db:"idempotency_key" json:"idempotency_key"`
+	Provider          string     `db:"provider" json:"provider"`
+	Status            string     `db:"status" json:"status"`
+	ProviderPaymentID *string    `db:"provider_payment_id" json:"provider_payment_id,omitempty"`
+	PaymentID         *uuid.UUID `db:"payment_id" json:"payment_id,omitempty"`
+	Error             *string    `db:"error" json:"error,omitempty"`
+	CreatedAt         time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt         time.Time  `db:"updated_at" json:"updated_at"`
 }
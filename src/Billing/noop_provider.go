@@ -2,13 +2,32 @@ package Billing
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
 )
 
 type NoopProvider struct{}
 
-func (n *NoopProvider) Charge(ctx context.Context, provider string, amount decimal.Decimal, currency string, metadata map[string]interface{}) (string, error) {
-	// immediate success with generated id
-	return "noop-" + uuid.New().String(), nil
-}
\ No newline at end of file
+func (n *NoopProvider) Charge(ctx context.Context, provider string, amount decimal.Decimal, currency string, idempotencyKey string, metadata map[string]interface{}) (string, error) {
+	// immediate success; the id is derived from the key like a real provider replaying a request
+	return "noop-" + idempotencyKey, nil
+}
+
+func (n *NoopProvider) Refund(ctx context.Context, provider, providerPaymentID string, amount decimal.Decimal, currency string) (string, error) {
+	return "noop-refund-" + uuid.New().String(), nil
+}
+
+func (n *NoopProvider) Authorize(ctx context.Context, provider string, amount decimal.Decimal, currency string, metadata map[string]interface{}) (string, time.Time, error) {
+	// card authorizations typically hold for 7 days
+	return "noop-auth-" + uuid.New().String(), time.Now().UTC().AddDate(0, 0, 7), nil
+}
+
+func (n *NoopProvider) Capture(ctx context.Context, provider, providerPaymentID string, amount decimal.Decimal, currency string) error {
+	return nil
+}
+
+func (n *NoopProvider) Void(ctx context.Context, provider, providerPaymentID string) error {
+	return nil
+}
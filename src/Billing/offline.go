@@ -0,0 +1,99 @@
+package Billing
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+// OpenOfflineInvoice invoices an order paid by COD or bank transfer. The
+// method must be enabled for the warehouse/region; the invoice stays UNPAID
+// until RecordPayment settles it.
+func (s *service) OpenOfflineInvoice(ctx context.Context, orderID uuid.UUID, amount decimal.Decimal, currency, method, warehouse, region string) (*Invoice, error) {
+	rule, err := s.repo.MatchOfflineMethodRule(ctx, method, warehouse, region)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrorMethodUnavailable
+		}
+		return nil, err
+	}
+	return s.IssueInvoice(ctx, orderID, amount, currency, rule.DueInDays)
+}
+
+// AvailableOfflineMethods returns the rule that applies for each offline
+// method enabled at the warehouse/region (instructions, due days).
+func (s *service) AvailableOfflineMethods(ctx context.Context, warehouse, region string) ([]OfflineMethodRule, error) {
+	out := []OfflineMethodRule{}
+	for _, method := range []string{MethodCOD, MethodBankTransfer} {
+		rule, err := s.repo.MatchOfflineMethodRule(ctx, method, warehouse, region)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *rule)
+	}
+	return out, nil
+}
+
+func (s *service) ListOfflineMethodRules(ctx context.Context) ([]OfflineMethodRule, error) {
+	return s.repo.ListOfflineMethodRules(ctx)
+}
+
+func (s *service) CreateOfflineMethodRule(ctx context.Context, req CreateOfflineMethodRuleRequest) (*OfflineMethodRule, error) {
+	rule := &OfflineMethodRule{Method: req.Method, Warehouse: req.Warehouse, Region: req.Region, Instructions: req.Instructions, DueInDays: req.DueInDays}
+	if err := s.repo.CreateOfflineMethodRule(ctx, rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (s *service) DeleteOfflineMethodRule(ctx context.Context, id uuid.UUID) error {
+	return s.repo.DeleteOfflineMethodRule(ctx, id)
+}
+
+// RecordPayment records cash collected on delivery or a received bank
+// transfer against the order's invoice. Partial amounts leave the invoice
+// PARTIALLY_PAID; once fully paid the usual paid-invoice flow runs. The
+// invoice is locked while the payment is checked and stored, so payments
+// recorded at once can't overpay it or repeat a reference.
+func (s *service) RecordPayment(ctx context.Context, orderID uuid.UUID, req RecordPaymentRequest) (*Payment, error) {
+	if !req.Amount.IsPositive() {
+		return nil, ErrorInvalidAmount
+	}
+	inv, err := s.repo.GetInvoiceByOrder(ctx, orderID)
+	if err != nil {
+		return nil, invoiceError(err)
+	}
+	reference := strings.TrimSpace(req.Reference)
+	received := time.Now().UTC()
+	if req.ReceivedAt != nil {
+		received = req.ReceivedAt.UTC()
+	}
+	metadata := Metadata{"recorded_by": req.RecordedBy, "received_at": received.Format(time.RFC3339)}
+	if req.Note != nil {
+		metadata["note"] = *req.Note
+	}
+	p := &Payment{InvoiceID: inv.ID, Provider: req.Method, ProviderPaymentID: &reference, Amount: req.Amount, Currency: inv.Currency, Status: PaymentSuccess, Metadata: metadata}
+	paid, err := s.repo.RecordOfflinePayment(ctx, p, received)
+	if err != nil {
+		return nil, err
+	}
+	s.queueSync(ctx, "PAYMENT", p.ID)
+	if !paid {
+		return p, nil
+	}
+	if s.listener != nil {
+		if err := s.listener.InvoicePaid(ctx, inv.OrderID); err != nil {
+			s.log.Error("invoice paid listener", zap.String("order_id", inv.OrderID.String()), zap.Error(err))
+		}
+	}
+	return p, nil
+}
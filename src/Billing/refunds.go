@@ -0,0 +1,149 @@
+package Billing
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+// RefundOrder refunds amount (or everything still refundable when nil) back
+// to the payments that funded the order's invoice, newest payment first. Each
+// provider refund is recorded with its provider reference, and the invoice
+// becomes PARTIALLY_REFUNDED or REFUNDED.
+// The invoice stays locked from working out what is refundable until the
+// refunds are recorded, so two refunds of the same order can't both take
+// what is left. Each refund is recorded as soon as the provider makes it,
+// outside the lock's transaction, so a later failure doesn't lose it.
+func (s *service) RefundOrder(ctx context.Context, orderID uuid.UUID, amount *decimal.Decimal, reason *string) ([]Refund, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+	inv, err := s.repo.LockInvoiceByOrder(ctx, tx, orderID)
+	if err != nil {
+		return nil, invoiceError(err)
+	}
+	payments, refunded, refundable, err := s.refundable(ctx, inv)
+	if err != nil {
+		return nil, err
+	}
+	requested := refundable
+	if amount != nil {
+		if !amount.IsPositive() {
+			return nil, ErrorInvalidAmount
+		}
+		if amount.GreaterThan(refundable) {
+			return nil, ErrorRefundTooLarge
+		}
+		requested = *amount
+	}
+
+	var out []Refund
+	remaining := requested
+	for i := len(payments) - 1; i >= 0 && remaining.IsPositive(); i-- {
+		p := payments[i]
+		available := p.Amount.Sub(refunded[p.ID])
+		if p.ProviderPaymentID == nil || !available.IsPositive() {
+			continue
+		}
+		part := decimal.Min(available, remaining)
+		rf, err := s.refundPayment(ctx, &p, part, reason)
+		if err != nil {
+			return out, err
+		}
+		out = append(out, *rf)
+		refunded[p.ID] = refunded[p.ID].Add(part)
+		remaining = remaining.Sub(part)
+	}
+
+	status := InvoicePartiallyRefunded
+	if requested.Equal(refundable) {
+		status = InvoiceRefunded
+	}
+	if err := s.repo.UpdateInvoiceStatusTx(ctx, tx, inv.ID, status, inv.PaidAt); err != nil {
+		return out, err
+	}
+	if err := tx.Commit(); err != nil {
+		return out, err
+	}
+	if s.refunds != nil {
+		s.refunds.Refunded(ctx, orderID, requested, inv.Currency)
+	}
+	return out, nil
+}
+
+// RefundableAmount is what a full refund of the order would return
+func (s *service) RefundableAmount(ctx context.Context, orderID uuid.UUID) (decimal.Decimal, error) {
+	inv, err := s.repo.GetInvoiceByOrder(ctx, orderID)
+	if err != nil {
+		return decimal.Zero, invoiceError(err)
+	}
+	_, _, refundable, err := s.refundable(ctx, inv)
+	return refundable, err
+}
+
+func invoiceError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrorInvoiceNotFound
+	}
+	return err
+}
+
+// refundable loads the payments of the paid invoice inv, what was already
+// refunded per payment, and what is left to refund.
+func (s *service) refundable(ctx context.Context, inv *Invoice) ([]Payment, map[uuid.UUID]decimal.Decimal, decimal.Decimal, error) {
+	if inv.Status != InvoicePaid && inv.Status != InvoicePartiallyRefunded {
+		return nil, nil, decimal.Zero, ErrorNotRefundable
+	}
+	payments, err := s.repo.ListSuccessfulPayments(ctx, inv.ID)
+	if err != nil {
+		return nil, nil, decimal.Zero, err
+	}
+	refunded, err := s.repo.RefundedByPayment(ctx, inv.ID)
+	if err != nil {
+		return nil, nil, decimal.Zero, err
+	}
+	refundable := decimal.Zero
+	for _, p := range payments {
+		if p.ProviderPaymentID != nil {
+			refundable = refundable.Add(p.Amount.Sub(refunded[p.ID]))
+		}
+	}
+	if refundable.IsZero() {
+		return nil, nil, decimal.Zero, ErrorNoPaymentToRefund
+	}
+	return payments, refunded, refundable, nil
+}
+
+func (s *service) refundPayment(ctx context.Context, p *Payment, amount decimal.Decimal, reason *string) (*Refund, error) {
+	rf := &Refund{PaymentID: p.ID, InvoiceID: p.InvoiceID, Provider: p.Provider, Amount: amount, Currency: p.Currency, Status: "SUCCESS", Reason: reason}
+	ref, err := s.provider.Refund(ctx, p.Provider, *p.ProviderPaymentID, amount, p.Currency)
+	if err != nil {
+		rf.Status = "FAILED"
+		if rerr := s.repo.CreateRefund(ctx, rf); rerr != nil {
+			s.log.Error("record failed refund", zap.String("payment_id", p.ID.String()), zap.Error(rerr))
+		}
+		return nil, fmt.Errorf("refund payment %s via %s: %w", p.ID, p.Provider, err)
+	}
+	rf.ProviderRefundID = &ref
+	if err := s.repo.CreateRefund(ctx, rf); err != nil {
+		// the provider has moved the money, keep its reference in the log
+		s.log.Error("record refund", zap.String("payment_id", p.ID.String()), zap.String("provider_refund_id", ref), zap.Error(err))
+		return nil, err
+	}
+	return rf, nil
+}
+
+func (s *service) ListRefunds(ctx context.Context, orderID uuid.UUID) ([]Refund, error) {
+	inv, err := s.repo.GetInvoiceByOrder(ctx, orderID)
+	if err != nil {
+		return nil, invoiceError(err)
+	}
+	return s.repo.ListRefunds(ctx, inv.ID)
+}
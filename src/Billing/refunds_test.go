@@ -0,0 +1,165 @@
+package Billing
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+// txDB is a database whose transactions commit without doing anything; the
+// fake repository below keeps the data
+type txDB struct{}
+
+func (txDB) Connect(context.Context) (driver.Conn, error) { return txConn{}, nil }
+func (txDB) Driver() driver.Driver                        { return txDriver{} }
+
+type txDriver struct{}
+
+func (txDriver) Open(string) (driver.Conn, error) { return txConn{}, nil }
+
+type txConn struct{}
+
+func (txConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("no statements") }
+func (txConn) Close() error                        { return nil }
+func (txConn) Begin() (driver.Tx, error)           { return txConn{}, nil }
+func (txConn) Commit() error                       { return nil }
+func (txConn) Rollback() error                     { return nil }
+
+// fakeRepository keeps one order's invoice, payments and refunds in memory;
+// the methods it doesn't override panic through the nil Repository
+type fakeRepository struct {
+	Repository
+	mu       sync.Mutex
+	invoice  Invoice
+	payments []Payment
+	refunds  []Refund
+}
+
+func (r *fakeRepository) GetInvoiceByOrder(ctx context.Context, orderID uuid.UUID) (*Invoice, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if orderID != r.invoice.OrderID {
+		return nil, sql.ErrNoRows
+	}
+	inv := r.invoice
+	return &inv, nil
+}
+
+func (r *fakeRepository) LockInvoiceByOrder(ctx context.Context, tx *sqlx.Tx, orderID uuid.UUID) (*Invoice, error) {
+	return r.GetInvoiceByOrder(ctx, orderID)
+}
+
+func (r *fakeRepository) UpdateInvoiceStatusTx(ctx context.Context, tx *sqlx.Tx, id uuid.UUID, status string, paidAt *time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.invoice.Status, r.invoice.PaidAt = status, paidAt
+	return nil
+}
+
+func (r *fakeRepository) ListSuccessfulPayments(ctx context.Context, invoiceID uuid.UUID) ([]Payment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Payment(nil), r.payments...), nil
+}
+
+func (r *fakeRepository) RefundedByPayment(ctx context.Context, invoiceID uuid.UUID) (map[uuid.UUID]decimal.Decimal, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := map[uuid.UUID]decimal.Decimal{}
+	for _, rf := range r.refunds {
+		if rf.Status == "SUCCESS" {
+			out[rf.PaymentID] = out[rf.PaymentID].Add(rf.Amount)
+		}
+	}
+	return out, nil
+}
+
+func (r *fakeRepository) CreateRefund(ctx context.Context, rf *Refund) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rf.ID = uuid.New()
+	r.refunds = append(r.refunds, *rf)
+	return nil
+}
+
+func (r *fakeRepository) refunded() decimal.Decimal {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	total := decimal.Zero
+	for _, rf := range r.refunds {
+		total = total.Add(rf.Amount)
+	}
+	return total
+}
+
+// fakeProvider refunds anything and counts the refunds it made
+type fakeProvider struct {
+	Provider
+	mu      sync.Mutex
+	refunds int
+}
+
+func (p *fakeProvider) Refund(ctx context.Context, provider, providerPaymentID string, amount decimal.Decimal, currency string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.refunds++
+	return fmt.Sprintf("re_%d", p.refunds), nil
+}
+
+func newRefundService(t *testing.T, paid decimal.Decimal) (*service, *fakeRepository, *fakeProvider, uuid.UUID) {
+	t.Helper()
+	db := sqlx.NewDb(sql.OpenDB(txDB{}), "postgres")
+	t.Cleanup(func() { db.Close() })
+	orderID, invoiceID := uuid.New(), uuid.New()
+	ref := "pi_1"
+	repo := &fakeRepository{
+		invoice:  Invoice{ID: invoiceID, OrderID: orderID, Status: InvoicePaid, Amount: paid, Currency: "KES"},
+		payments: []Payment{{ID: uuid.New(), InvoiceID: invoiceID, Provider: "stripe", ProviderPaymentID: &ref, Amount: paid, Currency: "KES", Status: PaymentSuccess}},
+	}
+	provider := &fakeProvider{}
+	s := NewService(repo, db, provider, nil, nil, nil, nil, TaxConfig{}, nil, zap.NewNop()).(*service)
+	return s, repo, provider, orderID
+}
+
+func TestRefundOrderRejectsRefundPastInvoiceAmount(t *testing.T) {
+	s, repo, provider, orderID := newRefundService(t, decimal.NewFromInt(100))
+	ctx := context.Background()
+	amount := decimal.NewFromInt(60)
+
+	if _, err := s.RefundOrder(ctx, orderID, &amount, nil); err != nil {
+		t.Fatalf("first refund: %v", err)
+	}
+	if _, err := s.RefundOrder(ctx, orderID, &amount, nil); !errors.Is(err, ErrorRefundTooLarge) {
+		t.Fatalf("second refund err = %v, want %v", err, ErrorRefundTooLarge)
+	}
+	if got := repo.refunded(); !got.Equal(amount) {
+		t.Errorf("refunded = %s, want %s", got, amount)
+	}
+	if provider.refunds != 1 {
+		t.Errorf("provider refunds = %d, want 1", provider.refunds)
+	}
+	if repo.invoice.Status != InvoicePartiallyRefunded {
+		t.Errorf("invoice status = %s, want %s", repo.invoice.Status, InvoicePartiallyRefunded)
+	}
+
+	// what is left can still be refunded in full
+	if _, err := s.RefundOrder(ctx, orderID, nil, nil); err != nil {
+		t.Fatalf("refund of the rest: %v", err)
+	}
+	if got := repo.refunded(); !got.Equal(decimal.NewFromInt(100)) {
+		t.Errorf("refunded = %s, want 100", got)
+	}
+	if repo.invoice.Status != InvoiceRefunded {
+		t.Errorf("invoice status = %s, want %s", repo.invoice.Status, InvoiceRefunded)
+	}
+}
@@ -3,18 +3,70 @@ package Billing
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/shopspring/decimal"
 	"go.uber.org/zap"
 )
 
 type Repository interface {
 	CreateInvoice(ctx context.Context, inv *Invoice) error
 	GetInvoiceByOrder(ctx context.Context, orderID uuid.UUID) (*Invoice, error)
+	GetInvoice(ctx context.Context, id uuid.UUID) (*Invoice, error)
 	CreatePayment(ctx context.Context, p *Payment) error
 	UpdateInvoiceStatus(ctx context.Context, id uuid.UUID, status string, paidAt *time.Time) error
+	// LockInvoiceByOrder locks the order's current invoice within tx. Rows
+	// referring to the invoice can still be inserted outside tx meanwhile.
+	LockInvoiceByOrder(ctx context.Context, tx *sqlx.Tx, orderID uuid.UUID) (*Invoice, error)
+	UpdateInvoiceStatusTx(ctx context.Context, tx *sqlx.Tx, id uuid.UUID, status string, paidAt *time.Time) error
+	GetPayment(ctx context.Context, id uuid.UUID) (*Payment, error)
+	ListPayments(ctx context.Context, f PaymentFilter) ([]Payment, error)
+	CountPayments(ctx context.Context, f PaymentFilter) (int, error)
+
+	ListOfflineMethodRules(ctx context.Context) ([]OfflineMethodRule, error)
+	MatchOfflineMethodRule(ctx context.Context, method, warehouse, region string) (*OfflineMethodRule, error)
+	CreateOfflineMethodRule(ctx context.Context, rule *OfflineMethodRule) error
+	DeleteOfflineMethodRule(ctx context.Context, id uuid.UUID) error
+	// RecordOfflinePayment stores p against its unpaid or partly paid
+	// invoice unless p would overpay it or repeats a reference of its
+	// method, and moves the invoice to PARTIALLY_PAID, or to PAID at paidAt
+	// once it is settled, which it reports
+	RecordOfflinePayment(ctx context.Context, p *Payment, paidAt time.Time) (bool, error)
+
+	LatestAttempt(ctx context.Context, invoiceID uuid.UUID) (*PaymentAttempt, error)
+	CreateAttempt(ctx context.Context, a *PaymentAttempt) (*PaymentAttempt, error)
+	FailAttempt(ctx context.Context, id uuid.UUID, cause string) error
+	CompleteAttempt(ctx context.Context, a *PaymentAttempt, p *Payment, paidAt time.Time) error
+	ListSuccessfulPayments(ctx context.Context, invoiceID uuid.UUID) ([]Payment, error)
+	RefundedByPayment(ctx context.Context, invoiceID uuid.UUID) (map[uuid.UUID]decimal.Decimal, error)
+	CreateRefund(ctx context.Context, rf *Refund) error
+	ListRefunds(ctx context.Context, invoiceID uuid.UUID) ([]Refund, error)
+
+	GetAuthorizedPayment(ctx context.Context, invoiceID uuid.UUID) (*Payment, error)
+	ListExpiringAuthorizations(ctx context.Context, before time.Time, limit int) ([]Payment, error)
+	SetPaymentStatus(ctx context.Context, p *Payment, from string) error
+
+	GetPaymentByProviderID(ctx context.Context, provider, providerPaymentID string) (*Payment, *Invoice, error)
+	UpsertDispute(ctx context.Context, d *Dispute) error
+	ListDisputes(ctx context.Context, open bool, limit, offset int) ([]Dispute, error)
+	CountDisputes(ctx context.Context, open bool) (int, error)
+	CountOpenDisputes(ctx context.Context, orderID uuid.UUID) (int, error)
+
+	GetCreditAccount(ctx context.Context, customerID uuid.UUID) (*CreditAccount, error)
+	PutCreditAccount(ctx context.Context, a *CreditAccount) error
+	DeleteCreditAccount(ctx context.Context, customerID uuid.UUID) error
+	Exposure(ctx context.Context, q ExposureQuery) ([]CreditAccount, error)
+	Statement(ctx context.Context, customerID uuid.UUID, currency string, from, to time.Time) (decimal.Decimal, []StatementLine, error)
+	// CreateTermsInvoice stores inv unless it would take the customer's
+	// unpaid invoices over the credit limit of an account that blocks; an
+	// account that flags gets the invoice stored with OverCreditLimit set
+	CreateTermsInvoice(ctx context.Context, inv *Invoice, customerID uuid.UUID) error
 }
 
 type repository struct {
@@ -25,31 +77,537 @@ type repository struct {
 func NewRepository(db *sqlx.DB, log *zap.Logger) Repository { return &repository{db: db, log: log} }
 
 func (r *repository) CreateInvoice(ctx context.Context, inv *Invoice) error {
+	return insertInvoice(ctx, r.db, inv)
+}
+
+func insertInvoice(ctx context.Context, db sqlx.ExecerContext, inv *Invoice) error {
 	inv.ID = uuid.New()
 	inv.IssuedAt = time.Now().UTC()
-	_, err := r.db.ExecContext(ctx, `INSERT INTO invoices (id,order_id,invoice_number,status,amount,currency,issued_at,due_at,paid_at) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)`, inv.ID, inv.OrderID, inv.InvoiceNumber, inv.Status, inv.Amount, inv.Currency, inv.IssuedAt, inv.DueAt, inv.PaidAt)
+	_, err := db.ExecContext(ctx, `INSERT INTO invoices (id,order_id,invoice_number,status,amount,currency,issued_at,due_at,paid_at,buyer_name,buyer_company,buyer_tax_id,buyer_tax_id_type,buyer_country,seller_tax_id,reverse_charge,tax_note,over_credit_limit) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18)`,
+		inv.ID, inv.OrderID, inv.InvoiceNumber, inv.Status, inv.Amount, inv.Currency, inv.IssuedAt, inv.DueAt, inv.PaidAt,
+		inv.BuyerName, inv.BuyerCompany, inv.BuyerTaxID, inv.BuyerTaxIDType, inv.BuyerCountry, inv.SellerTaxID, inv.ReverseCharge, inv.TaxNote, inv.OverCreditLimit)
 	return err
 }
 
+// currentInvoice picks the order's current invoice: invoices voided by a
+// declined payment attempt only count when there is nothing newer
+const currentInvoice = `SELECT ` + InvoiceColumns + ` FROM invoices WHERE order_id=$1 ORDER BY status='VOID', issued_at DESC LIMIT 1`
+
 func (r *repository) GetInvoiceByOrder(ctx context.Context, orderID uuid.UUID) (*Invoice, error) {
 	var inv Invoice
-	if err := r.db.GetContext(ctx, &inv, `SELECT id,order_id,invoice_number,status,amount,currency,issued_at,due_at,paid_at FROM invoices WHERE order_id=$1`, orderID); err != nil {
-		if err == sql.ErrNoRows {
-			return nil, sql.ErrNoRows
-		}
+	if err := r.db.GetContext(ctx, &inv, currentInvoice, orderID); err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
+
+// LockInvoiceByOrder takes a NO KEY UPDATE lock, which still lets the
+// foreign keys of payments and refunds inserted by other transactions see
+// the invoice.
+func (r *repository) LockInvoiceByOrder(ctx context.Context, tx *sqlx.Tx, orderID uuid.UUID) (*Invoice, error) {
+	var inv Invoice
+	if err := tx.GetContext(ctx, &inv, currentInvoice+` FOR NO KEY UPDATE`, orderID); err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
+
+func (r *repository) GetInvoice(ctx context.Context, id uuid.UUID) (*Invoice, error) {
+	var inv Invoice
+	if err := r.db.GetContext(ctx, &inv, `SELECT `+InvoiceColumns+` FROM invoices WHERE id=$1`, id); err != nil {
 		return nil, err
 	}
 	return &inv, nil
 }
 
 func (r *repository) CreatePayment(ctx context.Context, p *Payment) error {
+	return insertPayment(ctx, r.db, p)
+}
+
+func insertPayment(ctx context.Context, db sqlx.ExecerContext, p *Payment) error {
 	p.ID = uuid.New()
 	p.CreatedAt = time.Now().UTC()
-	_, err := r.db.ExecContext(ctx, `INSERT INTO payments (id,invoice_id,provider,provider_payment_id,amount,currency,status,metadata,created_at) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)`, p.ID, p.InvoiceID, p.Provider, p.ProviderPaymentID, p.Amount, p.Currency, p.Status, p.Metadata, p.CreatedAt)
+	_, err := db.ExecContext(ctx, `INSERT INTO payments (id,invoice_id,provider,provider_payment_id,amount,currency,status,metadata,created_at,authorized_at,authorization_expires_at) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)`, p.ID, p.InvoiceID, p.Provider, p.ProviderPaymentID, p.Amount, p.Currency, p.Status, p.Metadata, p.CreatedAt, p.AuthorizedAt, p.AuthorizationExpiresAt)
 	return err
 }
 
 func (r *repository) UpdateInvoiceStatus(ctx context.Context, id uuid.UUID, status string, paidAt *time.Time) error {
-	_, err := r.db.ExecContext(ctx, `UPDATE invoices SET status=$1, paid_at=$2 WHERE id=$3`, status, paidAt, id)
+	return updateInvoiceStatus(ctx, r.db, id, status, paidAt)
+}
+
+func (r *repository) UpdateInvoiceStatusTx(ctx context.Context, tx *sqlx.Tx, id uuid.UUID, status string, paidAt *time.Time) error {
+	return updateInvoiceStatus(ctx, tx, id, status, paidAt)
+}
+
+func updateInvoiceStatus(ctx context.Context, db sqlx.ExecerContext, id uuid.UUID, status string, paidAt *time.Time) error {
+	_, err := db.ExecContext(ctx, `UPDATE invoices SET status=$1, paid_at=$2 WHERE id=$3`, status, paidAt, id)
+	return err
+}
+
+func (r *repository) ListSuccessfulPayments(ctx context.Context, invoiceID uuid.UUID) ([]Payment, error) {
+	var out []Payment
+	err := r.db.SelectContext(ctx, &out, `SELECT `+PaymentColumns+` FROM payments WHERE invoice_id=$1 AND status='SUCCESS' ORDER BY created_at`, invoiceID)
+	return out, err
+}
+
+// RefundedByPayment sums successful refunds per payment of the invoice
+func (r *repository) RefundedByPayment(ctx context.Context, invoiceID uuid.UUID) (map[uuid.UUID]decimal.Decimal, error) {
+	var rows []struct {
+		PaymentID uuid.UUID       `db:"payment_id"`
+		Amount    decimal.Decimal `db:"amount"`
+	}
+	if err := r.db.SelectContext(ctx, &rows, `SELECT payment_id, SUM(amount) AS amount FROM refunds WHERE invoice_id=$1 AND status='SUCCESS' GROUP BY payment_id`, invoiceID); err != nil {
+		return nil, err
+	}
+	out := make(map[uuid.UUID]decimal.Decimal, len(rows))
+	for _, row := range rows {
+		out[row.PaymentID] = row.Amount
+	}
+	return out, nil
+}
+
+func (r *repository) CreateRefund(ctx context.Context, rf *Refund) error {
+	rf.ID = uuid.New()
+	rf.CreatedAt = time.Now().UTC()
+	_, err := r.db.ExecContext(ctx, `INSERT INTO refunds (id,payment_id,invoice_id,provider,provider_refund_id,amount,currency,status,reason,created_at) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)`, rf.ID, rf.PaymentID, rf.InvoiceID, rf.Provider, rf.ProviderRefundID, rf.Amount, rf.Currency, rf.Status, rf.Reason, rf.CreatedAt)
+	return err
+}
+
+func (r *repository) ListRefunds(ctx context.Context, invoiceID uuid.UUID) ([]Refund, error) {
+	out := []Refund{}
+	err := r.db.SelectContext(ctx, &out, `SELECT id,payment_id,invoice_id,provider,provider_refund_id,amount,currency,status,reason,created_at FROM refunds WHERE invoice_id=$1 ORDER BY created_at`, invoiceID)
+	return out, err
+}
+
+func (r *repository) GetAuthorizedPayment(ctx context.Context, invoiceID uuid.UUID) (*Payment, error) {
+	var p Payment
+	if err := r.db.GetContext(ctx, &p, `SELECT `+PaymentColumns+` FROM payments WHERE invoice_id=$1 AND status='AUTHORIZED' ORDER BY created_at DESC LIMIT 1`, invoiceID); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// ListExpiringAuthorizations returns open authorizations that lapse before the given time
+func (r *repository) ListExpiringAuthorizations(ctx context.Context, before time.Time, limit int) ([]Payment, error) {
+	var out []Payment
+	err := r.db.SelectContext(ctx, &out, `SELECT `+PaymentColumns+` FROM payments WHERE status='AUTHORIZED' AND authorization_expires_at < $1 ORDER BY authorization_expires_at LIMIT $2`, before, limit)
+	return out, err
+}
+
+// SetPaymentStatus moves the payment out of status from; it fails with
+// sql.ErrNoRows when another request got there first.
+func (r *repository) SetPaymentStatus(ctx context.Context, p *Payment, from string) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE payments SET status=$1, captured_at=$2, voided_at=$3 WHERE id=$4 AND status=$5`, p.Status, p.CapturedAt, p.VoidedAt, p.ID, from)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetPaymentByProviderID finds the payment (and its invoice) a provider reference belongs to
+func (r *repository) GetPaymentByProviderID(ctx context.Context, provider, providerPaymentID string) (*Payment, *Invoice, error) {
+	var p Payment
+	if err := r.db.GetContext(ctx, &p, `SELECT `+PaymentColumns+` FROM payments WHERE provider=$1 AND provider_payment_id=$2`, provider, providerPaymentID); err != nil {
+		return nil, nil, err
+	}
+	inv, err := r.GetInvoice(ctx, p.InvoiceID)
+	if err != nil {
+		return &p, nil, err
+	}
+	return &p, inv, nil
+}
+
+const disputeColumns = `id,provider,provider_dispute_id,payment_id,invoice_id,order_id,provider_payment_id,reason,status,amount,currency,evidence_due_by,closed_at,created_at,updated_at`
+
+// UpsertDispute inserts the dispute or updates status, due date and outcome
+// of the one already recorded for the provider reference. d is refreshed
+// from the stored row.
+func (r *repository) UpsertDispute(ctx context.Context, d *Dispute) error {
+	now := time.Now().UTC()
+	var closedAt *time.Time
+	if d.Closed() {
+		closedAt = &now
+	}
+	return r.db.GetContext(ctx, d, `INSERT INTO disputes (id,provider,provider_dispute_id,payment_id,invoice_id,order_id,provider_payment_id,reason,status,amount,currency,evidence_due_by,closed_at,created_at,updated_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$14)
+		ON CONFLICT (provider,provider_dispute_id) DO UPDATE SET status=EXCLUDED.status, reason=EXCLUDED.reason, amount=EXCLUDED.amount,
+			evidence_due_by=EXCLUDED.evidence_due_by, closed_at=COALESCE(disputes.closed_at, EXCLUDED.closed_at), updated_at=EXCLUDED.updated_at
+		RETURNING `+disputeColumns,
+		uuid.New(), d.Provider, d.ProviderDisputeID, d.PaymentID, d.InvoiceID, d.OrderID, d.ProviderPaymentID, d.Reason, d.Status, d.Amount, d.Currency, d.EvidenceDueBy, closedAt, now)
+}
+
+// ListDisputes lists disputes, soonest evidence deadline first
+func (r *repository) ListDisputes(ctx context.Context, open bool, limit, offset int) ([]Dispute, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	where := ""
+	if open {
+		where = "WHERE closed_at IS NULL"
+	}
+	out := []Dispute{}
+	err := r.db.SelectContext(ctx, &out, `SELECT `+disputeColumns+` FROM disputes `+where+` ORDER BY evidence_due_by ASC NULLS LAST, created_at DESC LIMIT $1 OFFSET $2`, limit, offset)
+	return out, err
+}
+
+func (r *repository) CountDisputes(ctx context.Context, open bool) (int, error) {
+	where := ""
+	if open {
+		where = "WHERE closed_at IS NULL"
+	}
+	var n int
+	err := r.db.GetContext(ctx, &n, `SELECT COUNT(*) FROM disputes `+where)
+	return n, err
+}
+
+func (r *repository) CountOpenDisputes(ctx context.Context, orderID uuid.UUID) (int, error) {
+	var n int
+	err := r.db.GetContext(ctx, &n, `SELECT COUNT(*) FROM disputes WHERE order_id=$1 AND closed_at IS NULL`, orderID)
+	return n, err
+}
+
+func (r *repository) GetPayment(ctx context.Context, id uuid.UUID) (*Payment, error) {
+	var p Payment
+	if err := r.db.GetContext(ctx, &p, `SELECT `+PaymentColumns+` FROM payments WHERE id=$1`, id); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *repository) ListPayments(ctx context.Context, f PaymentFilter) ([]Payment, error) {
+	where, args, err := paymentFilter(f)
+	if err != nil {
+		return nil, err
+	}
+	limit := f.Limit
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	query := `SELECT ` + PaymentColumns + ` FROM payments` + where
+	query += fmt.Sprintf(" ORDER BY created_at DESC LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+	args = append(args, limit, f.Offset)
+	out := []Payment{}
+	err = r.db.SelectContext(ctx, &out, query, args...)
+	return out, err
+}
+
+func (r *repository) CountPayments(ctx context.Context, f PaymentFilter) (int, error) {
+	where, args, err := paymentFilter(f)
+	if err != nil {
+		return 0, err
+	}
+	var n int
+	err = r.db.GetContext(ctx, &n, `SELECT COUNT(*) FROM payments`+where, args...)
+	return n, err
+}
+
+// paymentFilter is the WHERE clause of the payment list filters
+func paymentFilter(f PaymentFilter) (string, []interface{}, error) {
+	query := ` WHERE 1=1`
+	args := []interface{}{}
+	if f.InvoiceID != nil {
+		args = append(args, *f.InvoiceID)
+		query += fmt.Sprintf(" AND invoice_id = $%d", len(args))
+	}
+	if f.Provider != "" {
+		args = append(args, f.Provider)
+		query += fmt.Sprintf(" AND provider = $%d", len(args))
+	}
+	if f.Status != "" {
+		args = append(args, f.Status)
+		query += fmt.Sprintf(" AND status = $%d", len(args))
+	}
+	if len(f.Metadata) > 0 {
+		contains, err := json.Marshal(f.Metadata)
+		if err != nil {
+			return "", nil, err
+		}
+		args = append(args, string(contains))
+		query += fmt.Sprintf(" AND metadata @> $%d::jsonb", len(args))
+	}
+	return query, args, nil
+}
+
+const attemptColumns = `id,invoice_id,attempt,idempotency_key,provider,status,provider_payment_id,payment_id,error,created_at,updated_at`
+
+func (r *repository) LatestAttempt(ctx context.Context, invoiceID uuid.UUID) (*PaymentAttempt, error) {
+	var a PaymentAttempt
+	if err := r.db.GetContext(ctx, &a, `SELECT `+attemptColumns+` FROM payment_attempts WHERE invoice_id=$1 ORDER BY attempt DESC LIMIT 1`, invoiceID); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// CreateAttempt inserts the attempt; when a concurrent request already
+// created the same attempt number, that row is returned instead.
+func (r *repository) CreateAttempt(ctx context.Context, a *PaymentAttempt) (*PaymentAttempt, error) {
+	now := time.Now().UTC()
+	_, err := r.db.ExecContext(ctx, `INSERT INTO payment_attempts (id,invoice_id,attempt,idempotency_key,provider,status,created_at,updated_at) VALUES ($1,$2,$3,$4,$5,$6,$7,$7) ON CONFLICT (invoice_id,attempt) DO NOTHING`, uuid.New(), a.InvoiceID, a.Attempt, a.IdempotencyKey, a.Provider, a.Status, now)
+	if err != nil {
+		return nil, err
+	}
+	var out PaymentAttempt
+	if err := r.db.GetContext(ctx, &out, `SELECT `+attemptColumns+` FROM payment_attempts WHERE invoice_id=$1 AND attempt=$2`, a.InvoiceID, a.Attempt); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (r *repository) FailAttempt(ctx context.Context, id uuid.UUID, cause string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE payment_attempts SET status=$1, error=$2, updated_at=NOW() WHERE id=$3 AND status=$4`, AttemptFailed, cause, id, AttemptPending)
+	return err
+}
+
+// CompleteAttempt records the payment, marks the invoice paid and closes the
+// attempt in one transaction.
+func (r *repository) CompleteAttempt(ctx context.Context, a *PaymentAttempt, p *Payment, paidAt time.Time) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	p.ID = uuid.New()
+	p.CreatedAt = paidAt
+	if _, err = tx.ExecContext(ctx, `INSERT INTO payments (id,invoice_id,provider,provider_payment_id,amount,currency,status,metadata,created_at) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)`, p.ID, p.InvoiceID, p.Provider, p.ProviderPaymentID, p.Amount, p.Currency, p.Status, p.Metadata, p.CreatedAt); err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, `UPDATE invoices SET status=$1, paid_at=$2 WHERE id=$3`, InvoicePaid, paidAt, p.InvoiceID); err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, `UPDATE payment_attempts SET status=$1, provider_payment_id=$2, payment_id=$3, updated_at=$4 WHERE id=$5`, AttemptSucceeded, p.ProviderPaymentID, p.ID, paidAt, a.ID); err != nil {
+		return err
+	}
+	err = tx.Commit()
+	return err
+}
+
+const offlineRuleColumns = `id,method,warehouse,region,instructions,due_in_days,created_at`
+
+func (r *repository) ListOfflineMethodRules(ctx context.Context) ([]OfflineMethodRule, error) {
+	out := []OfflineMethodRule{}
+	err := r.db.SelectContext(ctx, &out, `SELECT `+offlineRuleColumns+` FROM offline_payment_methods ORDER BY method, warehouse NULLS LAST, region NULLS LAST`)
+	return out, err
+}
+
+// MatchOfflineMethodRule returns the most specific rule enabling method for
+// the warehouse and region, or sql.ErrNoRows.
+func (r *repository) MatchOfflineMethodRule(ctx context.Context, method, warehouse, region string) (*OfflineMethodRule, error) {
+	var rule OfflineMethodRule
+	err := r.db.GetContext(ctx, &rule, `SELECT `+offlineRuleColumns+` FROM offline_payment_methods
+		WHERE method=$1 AND (warehouse IS NULL OR warehouse=$2) AND (region IS NULL OR region=$3)
+		ORDER BY (warehouse IS NOT NULL)::int + (region IS NOT NULL)::int DESC LIMIT 1`, method, warehouse, region)
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (r *repository) CreateOfflineMethodRule(ctx context.Context, rule *OfflineMethodRule) error {
+	rule.ID = uuid.New()
+	rule.CreatedAt = time.Now().UTC()
+	_, err := r.db.ExecContext(ctx, `INSERT INTO offline_payment_methods (id,method,warehouse,region,instructions,due_in_days,created_at) VALUES ($1,$2,$3,$4,$5,$6,$7)`, rule.ID, rule.Method, rule.Warehouse, rule.Region, rule.Instructions, rule.DueInDays, rule.CreatedAt)
+	return err
+}
+
+func (r *repository) DeleteOfflineMethodRule(ctx context.Context, id uuid.UUID) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM offline_payment_methods WHERE id=$1`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrorRuleNotFound
+	}
+	return nil
+}
+
+// RecordOfflinePayment locks the invoice while it checks and records the
+// payment, so two payments recorded at once cannot overpay it together
+func (r *repository) RecordOfflinePayment(ctx context.Context, p *Payment, paidAt time.Time) (paid bool, err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	var inv Invoice
+	if err = tx.GetContext(ctx, &inv, `SELECT `+InvoiceColumns+` FROM invoices WHERE id=$1 FOR UPDATE`, p.InvoiceID); err != nil {
+		return false, err
+	}
+	if inv.Status != InvoiceUnpaid && inv.Status != InvoicePartiallyPaid {
+		err = ErrorInvoiceSettled
+		return false, err
+	}
+	var exists bool
+	if err = tx.GetContext(ctx, &exists, `SELECT EXISTS (SELECT 1 FROM payments WHERE provider=$1 AND provider_payment_id=$2)`, p.Provider, p.ProviderPaymentID); err != nil {
+		return false, err
+	}
+	if exists {
+		err = ErrorDuplicateReference
+		return false, err
+	}
+	var total decimal.Decimal
+	if err = tx.GetContext(ctx, &total, `SELECT COALESCE(SUM(amount), 0) FROM payments WHERE invoice_id=$1 AND status='SUCCESS'`, inv.ID); err != nil {
+		return false, err
+	}
+	total = total.Add(p.Amount)
+	if total.GreaterThan(inv.Amount) {
+		err = ErrorOverpayment
+		return false, err
+	}
+	if err = insertPayment(ctx, tx, p); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			err = ErrorDuplicateReference
+		}
+		return false, err
+	}
+	paid = total.Equal(inv.Amount)
+	if paid {
+		err = updateInvoiceStatus(ctx, tx, inv.ID, InvoicePaid, &paidAt)
+	} else {
+		err = updateInvoiceStatus(ctx, tx, inv.ID, InvoicePartiallyPaid, nil)
+	}
+	if err != nil {
+		return false, err
+	}
+	err = tx.Commit()
+	return paid, err
+}
+
+const creditAccountColumns = `customer_id,payment_terms,credit_limit,currency,on_exceed,created_at,updated_at`
+
+func (r *repository) GetCreditAccount(ctx context.Context, customerID uuid.UUID) (*CreditAccount, error) {
+	var a CreditAccount
+	if err := r.db.GetContext(ctx, &a, `SELECT `+creditAccountColumns+` FROM credit_accounts WHERE customer_id=$1`, customerID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrorNoCreditAccount
+		}
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *repository) PutCreditAccount(ctx context.Context, a *CreditAccount) error {
+	err := r.db.GetContext(ctx, a, `INSERT INTO credit_accounts (customer_id,payment_terms,credit_limit,currency,on_exceed) VALUES ($1,$2,$3,$4,$5)
+		ON CONFLICT (customer_id) DO UPDATE SET payment_terms=EXCLUDED.payment_terms, credit_limit=EXCLUDED.credit_limit, currency=EXCLUDED.currency, on_exceed=EXCLUDED.on_exceed, updated_at=NOW()
+		RETURNING `+creditAccountColumns, a.CustomerID, a.PaymentTerms, a.CreditLimit, a.Currency, a.OnExceed)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+		return ErrorUnknownCustomer
+	}
+	return err
+}
+
+func (r *repository) DeleteCreditAccount(ctx context.Context, customerID uuid.UUID) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM credit_accounts WHERE customer_id=$1`, customerID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrorNoCreditAccount
+	}
+	return nil
+}
+
+// openInvoices are the unpaid and partially paid invoices with their
+// customer and what is still owed on them
+const openInvoices = `SELECT i.id, o.customer_id, i.currency, i.due_at, i.over_credit_limit,
+		i.amount - COALESCE((SELECT SUM(p.amount) FROM payments p WHERE p.invoice_id=i.id AND p.status='SUCCESS'), 0) AS due
+	FROM invoices i JOIN orders o ON o.id = i.order_id
+	WHERE i.status IN ('UNPAID','PARTIALLY_PAID')`
+
+// statementEntries are the debits and credits on the account of customer
+// $1 in currency $2, archived orders included. Void invoices were never
+// owed and are left out.
+const statementEntries = `WITH inv AS (
+		SELECT i.id, i.order_id, i.invoice_number, i.amount, i.issued_at, i.status FROM invoices i
+		WHERE i.currency = $2 AND i.order_id IN (SELECT id FROM orders WHERE customer_id = $1 UNION ALL SELECT id FROM orders_archive WHERE customer_id = $1)
+	), entries AS (
+		SELECT issued_at AS date, 'INVOICE' AS type, invoice_number AS reference, id AS invoice_id, order_id, amount AS debit, 0::numeric AS credit
+		FROM inv WHERE status <> 'VOID'
+		UNION ALL
+		SELECT COALESCE(p.captured_at, p.created_at), 'PAYMENT', COALESCE(p.provider_payment_id, p.provider), inv.id, inv.order_id, 0, p.amount
+		FROM payments p JOIN inv ON inv.id = p.invoice_id WHERE p.status = 'SUCCESS'
+		UNION ALL
+		SELECT rf.created_at, t.type, COALESCE(rf.provider_refund_id, rf.provider), inv.id, inv.order_id,
+			CASE t.type WHEN 'REFUND' THEN rf.amount ELSE 0 END, CASE t.type WHEN 'CREDIT' THEN rf.amount ELSE 0 END
+		FROM refunds rf JOIN inv ON inv.id = rf.invoice_id CROSS JOIN (VALUES ('CREDIT'), ('REFUND')) t(type) WHERE rf.status = 'SUCCESS'
+	)`
+
+// Statement returns the balance of the customer's account before from and
+// its entries in [from, to), oldest first
+func (r *repository) Statement(ctx context.Context, customerID uuid.UUID, currency string, from, to time.Time) (decimal.Decimal, []StatementLine, error) {
+	var opening decimal.Decimal
+	if err := r.db.GetContext(ctx, &opening, statementEntries+` SELECT COALESCE(SUM(debit - credit), 0) FROM entries WHERE date < $3`, customerID, currency, from); err != nil {
+		return opening, nil, err
+	}
+	lines := []StatementLine{}
+	err := r.db.SelectContext(ctx, &lines, statementEntries+` SELECT date, type, reference, invoice_id, order_id, debit, credit FROM entries
+		WHERE date >= $3 AND date < $4 ORDER BY date, type, reference`, customerID, currency, from, to)
+	return opening, lines, err
+}
+
+// Exposure reports credit accounts with what their customers owe in the
+// account currency, the most over (or least under) their limit first
+func (r *repository) Exposure(ctx context.Context, q ExposureQuery) ([]CreditAccount, error) {
+	out := []CreditAccount{}
+	err := r.db.SelectContext(ctx, &out, `SELECT a.customer_id, a.payment_terms, a.credit_limit, a.currency, a.on_exceed, a.created_at, a.updated_at,
+			COALESCE(SUM(b.due), 0) AS outstanding,
+			COALESCE(SUM(b.due) FILTER (WHERE b.due_at < NOW()), 0) AS overdue,
+			COUNT(b.id) AS open_invoices,
+			COUNT(b.id) FILTER (WHERE b.over_credit_limit) AS flagged_invoices
+		FROM credit_accounts a
+		LEFT JOIN (`+openInvoices+`) b ON b.customer_id = a.customer_id AND b.currency = a.currency
+		WHERE ($1::uuid IS NULL OR a.customer_id = $1) AND ($2::text = '' OR a.currency = $2)
+		GROUP BY a.customer_id
+		HAVING NOT $3::bool OR COALESCE(SUM(b.due), 0) > a.credit_limit
+		ORDER BY COALESCE(SUM(b.due), 0) - a.credit_limit DESC, a.customer_id
+		LIMIT $4 OFFSET $5`, q.CustomerID, q.Currency, q.OverLimit, q.Limit, q.Offset)
+	return out, err
+}
+
+// CreateTermsInvoice locks the credit account while it checks and invoices,
+// so two orders placed at once cannot both spend the same credit
+func (r *repository) CreateTermsInvoice(ctx context.Context, inv *Invoice, customerID uuid.UUID) (err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	var account CreditAccount
+	if err = tx.GetContext(ctx, &account, `SELECT `+creditAccountColumns+` FROM credit_accounts WHERE customer_id=$1 FOR UPDATE`, customerID); err != nil {
+		if err == sql.ErrNoRows {
+			err = ErrorNoCreditAccount
+		}
+		return err
+	}
+	var outstanding decimal.Decimal
+	if err = tx.GetContext(ctx, &outstanding, `SELECT COALESCE(SUM(due), 0) FROM (`+openInvoices+`) b WHERE customer_id=$1 AND currency=$2`, customerID, inv.Currency); err != nil {
+		return err
+	}
+	if outstanding.Add(inv.Amount).GreaterThan(account.CreditLimit) {
+		if account.OnExceed != CreditFlag {
+			err = ErrorCreditLimit
+			return err
+		}
+		inv.OverCreditLimit = true
+	}
+	if err = insertInvoice(ctx, tx, inv); err != nil {
+		return err
+	}
+	err = tx.Commit()
 	return err
 }
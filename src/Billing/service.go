@@ -2,26 +2,107 @@ package Billing
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
 	"github.com/shopspring/decimal"
 	"go.uber.org/zap"
 )
 
 type Provider interface {
-	Charge(ctx context.Context, provider string, amount decimal.Decimal, currency string, metadata map[string]interface{}) (string, error)
+	// Charge must be idempotent per idempotencyKey: repeating a call with the
+	// same key returns the original result instead of charging again.
+	Charge(ctx context.Context, provider string, amount decimal.Decimal, currency string, idempotencyKey string, metadata map[string]interface{}) (string, error)
+	Refund(ctx context.Context, provider, providerPaymentID string, amount decimal.Decimal, currency string) (string, error)
+	// Authorize places a hold and returns its reference and expiry
+	Authorize(ctx context.Context, provider string, amount decimal.Decimal, currency string, metadata map[string]interface{}) (string, time.Time, error)
+	Capture(ctx context.Context, provider, providerPaymentID string, amount decimal.Decimal, currency string) error
+	Void(ctx context.Context, provider, providerPaymentID string) error
+}
+
+// SyncQueue queues invoices and payments for posting to the accounting system
+type SyncQueue interface {
+	Enqueue(ctx context.Context, documentType string, documentID uuid.UUID) error
+}
+
+// PaymentListener is notified once an order's invoice has been paid
+type PaymentListener interface {
+	InvoicePaid(ctx context.Context, orderID uuid.UUID) error
+}
+
+// RefundListener is told about every refund of an order, with the total
+// refunded by one RefundOrder call
+type RefundListener interface {
+	Refunded(ctx context.Context, orderID uuid.UUID, amount decimal.Decimal, currency string)
+}
+
+type Service interface {
+	IssueInvoice(ctx context.Context, orderID uuid.UUID, amount decimal.Decimal, currency string, dueInDays int) (*Invoice, error)
+	PayInvoice(ctx context.Context, invoiceID uuid.UUID, provider string, metadata map[string]interface{}) (*Payment, error)
+	RefundOrder(ctx context.Context, orderID uuid.UUID, amount *decimal.Decimal, reason *string) ([]Refund, error)
+	ListRefunds(ctx context.Context, orderID uuid.UUID) ([]Refund, error)
+	RefundableAmount(ctx context.Context, orderID uuid.UUID) (decimal.Decimal, error)
+	ListPayments(ctx context.Context, f PaymentFilter) ([]Payment, error)
+	CountPayments(ctx context.Context, f PaymentFilter) (int, error)
+	OpenOfflineInvoice(ctx context.Context, orderID uuid.UUID, amount decimal.Decimal, currency, method, warehouse, region string) (*Invoice, error)
+	AvailableOfflineMethods(ctx context.Context, warehouse, region string) ([]OfflineMethodRule, error)
+	ListOfflineMethodRules(ctx context.Context) ([]OfflineMethodRule, error)
+	CreateOfflineMethodRule(ctx context.Context, req CreateOfflineMethodRuleRequest) (*OfflineMethodRule, error)
+	DeleteOfflineMethodRule(ctx context.Context, id uuid.UUID) error
+	RecordPayment(ctx context.Context, orderID uuid.UUID, req RecordPaymentRequest) (*Payment, error)
+	OpenTermsInvoice(ctx context.Context, orderID, customerID uuid.UUID, amount decimal.Decimal, currency, terms string) (*Invoice, error)
+	GetCreditAccount(ctx context.Context, customerID uuid.UUID) (*CreditAccount, error)
+	SetCreditAccount(ctx context.Context, customerID uuid.UUID, req SetCreditAccountRequest) (*CreditAccount, error)
+	DeleteCreditAccount(ctx context.Context, customerID uuid.UUID) error
+	CreditExposure(ctx context.Context, q ExposureQuery) ([]CreditAccount, error)
+	Statement(ctx context.Context, customerID uuid.UUID, q StatementQuery) (*Statement, error)
+	AuthorizeOrder(ctx context.Context, orderID uuid.UUID, amount decimal.Decimal, currency, provider string) (*Payment, error)
+	CaptureOrder(ctx context.Context, orderID uuid.UUID) (*Payment, error)
+	VoidOrder(ctx context.Context, orderID uuid.UUID) (*Payment, error)
+	OrderShipped(ctx context.Context, orderID uuid.UUID) error
+	OrderCancelled(ctx context.Context, orderID uuid.UUID) error
+	AutoCapture(ctx context.Context) error
+	RecordDispute(ctx context.Context, e DisputeEvent) (*Dispute, error)
+	ListDisputes(ctx context.Context, open bool, limit, offset int) ([]Dispute, error)
+	CountDisputes(ctx context.Context, open bool) (int, error)
+}
+
+// OrderHold freezes orders while a payment dispute is open
+type OrderHold interface {
+	Freeze(ctx context.Context, id uuid.UUID, reason string) error
+	Unfreeze(ctx context.Context, id uuid.UUID) error
 }
 
 type service struct {
 	repo     Repository
+	db       *sqlx.DB
 	provider Provider
+	erp      SyncQueue
+	listener PaymentListener
+	orders   OrderHold
+	buyers   BuyerDirectory
+	tax      TaxConfig
+	refunds  RefundListener
 	log      *zap.Logger
 }
 
-func NewService(r Repository, p Provider, log *zap.Logger) *service {
-	return &service{repo: r, provider: p, log: log}
+func NewService(r Repository, db *sqlx.DB, p Provider, erp SyncQueue, listener PaymentListener, orders OrderHold, buyers BuyerDirectory, tax TaxConfig, refunds RefundListener, log *zap.Logger) Service {
+	return &service{repo: r, db: db, provider: p, erp: erp, listener: listener, orders: orders, buyers: buyers, tax: tax, refunds: refunds, log: log}
+}
+
+// queueSync never fails the billing operation; the document stays PENDING
+// and can be re-queued from the accounting sync endpoints.
+func (s *service) queueSync(ctx context.Context, documentType string, id uuid.UUID) {
+	if s.erp == nil {
+		return
+	}
+	if err := s.erp.Enqueue(ctx, documentType, id); err != nil {
+		s.log.Error("queue accounting sync", zap.String("document_type", documentType), zap.String("document_id", id.String()), zap.Error(err))
+	}
 }
 
 func (s *service) IssueInvoice(ctx context.Context, orderID uuid.UUID, amount decimal.Decimal, currency string, dueInDays int) (*Invoice, error) {
@@ -30,33 +111,85 @@ func (s *service) IssueInvoice(ctx context.Context, orderID uuid.UUID, amount de
 		d := time.Now().UTC().AddDate(0, 0, dueInDays)
 		inv.DueAt = &d
 	}
+	if err := s.applyTaxIdentity(ctx, inv); err != nil {
+		return nil, err
+	}
 	if err := s.repo.CreateInvoice(ctx, inv); err != nil {
 		return nil, err
 	}
+	s.queueSync(ctx, "INVOICE", inv.ID)
 	return inv, nil
 }
 
+// PayInvoice charges the invoice and is safe to retry: an attempt whose
+// outcome is unknown is replayed with the same idempotency key, a new key is
+// only used after a definitive decline, and once paid the recorded payment is
+// returned.
 func (s *service) PayInvoice(ctx context.Context, invoiceID uuid.UUID, provider string, metadata map[string]interface{}) (*Payment, error) {
-	inv, err := s.repo.GetInvoiceByOrder(ctx, invoiceID)
+	inv, err := s.repo.GetInvoice(ctx, invoiceID)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrorInvoiceNotFound
+		}
 		return nil, err
 	}
-	if inv.Status == "PAID" {
+	last, err := s.repo.LatestAttempt(ctx, inv.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if last != nil && last.PaymentID != nil {
+		return s.repo.GetPayment(ctx, *last.PaymentID)
+	}
+	if inv.Status != InvoiceUnpaid {
 		return nil, errors.New("invoice already paid")
 	}
-	// call provider
-	amount := inv.Amount
-	ppid, perr := s.provider.Charge(ctx, provider, amount, inv.Currency, metadata)
+	attempt, err := s.nextAttempt(ctx, inv, last, provider)
+	if err != nil {
+		return nil, err
+	}
+
+	ppid, perr := s.provider.Charge(ctx, attempt.Provider, inv.Amount, inv.Currency, attempt.IdempotencyKey, metadata)
 	if perr != nil {
+		if errors.Is(perr, ErrorChargeDeclined) {
+			if err := s.repo.FailAttempt(ctx, attempt.ID, perr.Error()); err != nil {
+				s.log.Error("record declined attempt", zap.String("attempt_id", attempt.ID.String()), zap.Error(err))
+			}
+		}
 		return nil, perr
 	}
-	p := &Payment{InvoiceID: inv.ID, Provider: provider, ProviderPaymentID: &ppid, Amount: amount, Currency: inv.Currency, Status: "SUCCESS", Metadata: nil}
-	if err := s.repo.CreatePayment(ctx, p); err != nil {
+	p := &Payment{InvoiceID: inv.ID, Provider: attempt.Provider, ProviderPaymentID: &ppid, Amount: inv.Amount, Currency: inv.Currency, Status: PaymentSuccess, Metadata: metadata}
+	if err := s.repo.CompleteAttempt(ctx, attempt, p, time.Now().UTC()); err != nil {
+		// the attempt stays PENDING, a retry replays the key and records it
+		s.log.Error("record payment", zap.String("invoice_id", inv.ID.String()), zap.String("provider_payment_id", ppid), zap.Error(err))
 		return nil, err
 	}
-	paidAt := time.Now().UTC()
-	if err := s.repo.UpdateInvoiceStatus(ctx, inv.ID, "PAID", &paidAt); err != nil {
-		return nil, err
+	s.queueSync(ctx, "PAYMENT", p.ID)
+	if s.listener != nil {
+		if err := s.listener.InvoicePaid(ctx, inv.OrderID); err != nil {
+			s.log.Error("invoice paid listener", zap.String("order_id", inv.OrderID.String()), zap.Error(err))
+		}
 	}
 	return p, nil
 }
+
+// nextAttempt returns the attempt to run: the latest one unless it failed, in
+// which case a new attempt (and key) is started.
+func (s *service) nextAttempt(ctx context.Context, inv *Invoice, last *PaymentAttempt, provider string) (*PaymentAttempt, error) {
+	if last != nil && last.Status != AttemptFailed {
+		return last, nil
+	}
+	n := 1
+	if last != nil {
+		n = last.Attempt + 1
+	}
+	a := &PaymentAttempt{InvoiceID: inv.ID, Attempt: n, IdempotencyKey: fmt.Sprintf("inv-%s-%d", inv.ID, n), Provider: provider, Status: AttemptPending}
+	return s.repo.CreateAttempt(ctx, a)
+}
+
+func (s *service) ListPayments(ctx context.Context, f PaymentFilter) ([]Payment, error) {
+	return s.repo.ListPayments(ctx, f)
+}
+
+func (s *service) CountPayments(ctx context.Context, f PaymentFilter) (int, error) {
+	return s.repo.CountPayments(ctx, f)
+}
@@ -0,0 +1,95 @@
+package Billing
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"savannah/src/PDF"
+)
+
+// maxStatementPeriod bounds one statement; longer histories are read a year
+// at a time
+const maxStatementPeriod = 366 * 24 * time.Hour
+
+// Statement lists the customer's invoices, payments and credits in the
+// period with the running balance, for customers reconciling their account
+// against their own ledger
+func (s *service) Statement(ctx context.Context, customerID uuid.UUID, q StatementQuery) (*Statement, error) {
+	if !q.From.Before(q.To) || q.To.Sub(q.From) > maxStatementPeriod {
+		return nil, ErrorStatementPeriod
+	}
+	currency := strings.ToUpper(q.Currency)
+	if currency == "" {
+		currency = "USD"
+		account, err := s.repo.GetCreditAccount(ctx, customerID)
+		switch {
+		case err == nil:
+			currency = account.Currency
+		case !errors.Is(err, ErrorNoCreditAccount):
+			return nil, err
+		}
+	}
+	opening, lines, err := s.repo.Statement(ctx, customerID, currency, q.From, q.To)
+	if err != nil {
+		return nil, err
+	}
+	st := &Statement{CustomerID: customerID, Currency: currency, From: q.From, To: q.To, OpeningBalance: opening, Debits: decimal.Zero, Credits: decimal.Zero, Lines: lines}
+	balance := opening
+	for i := range st.Lines {
+		l := &st.Lines[i]
+		balance = balance.Add(l.Debit).Sub(l.Credit)
+		l.Balance = balance
+		st.Debits = st.Debits.Add(l.Debit)
+		st.Credits = st.Credits.Add(l.Credit)
+	}
+	st.ClosingBalance = balance
+	return st, nil
+}
+
+var statementHeader = []string{"date", "type", "reference", "invoice_id", "order_id", "debit", "credit", "balance"}
+
+// WriteCSV writes the statement as CSV: the opening balance, a row per
+// line and the closing balance
+func (st *Statement) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	_ = cw.Write(statementHeader)
+	_ = cw.Write([]string{st.From.Format(time.RFC3339), "OPENING_BALANCE", "", "", "", "", "", st.OpeningBalance.StringFixed(2)})
+	for _, l := range st.Lines {
+		_ = cw.Write([]string{l.Date.Format(time.RFC3339), l.Type, l.Reference, l.InvoiceID.String(), l.OrderID.String(), l.Debit.StringFixed(2), l.Credit.StringFixed(2), l.Balance.StringFixed(2)})
+	}
+	_ = cw.Write([]string{st.To.Format(time.RFC3339), "CLOSING_BALANCE", "", "", "", st.Debits.StringFixed(2), st.Credits.StringFixed(2), st.ClosingBalance.StringFixed(2)})
+	cw.Flush()
+	return cw.Error()
+}
+
+// PDF renders the statement for printing
+func (st *Statement) PDF() []byte {
+	var doc PDF.Text
+	doc.Line("ACCOUNT STATEMENT")
+	doc.Line("")
+	doc.Line("Customer: %s", st.CustomerID)
+	doc.Line("Period: %s to %s", st.From.Format("2006-01-02"), st.To.Format("2006-01-02"))
+	doc.Line("Currency: %s", st.Currency)
+	doc.Line("")
+	doc.Line("%-10s %-8s %-36s %12s %12s %12s", "DATE", "TYPE", "REFERENCE", "DEBIT", "CREDIT", "BALANCE")
+	doc.Line("%-10s %-8s %-36s %12s %12s %12s", st.From.Format("2006-01-02"), "", "Opening balance", "", "", st.OpeningBalance.StringFixed(2))
+	for _, l := range st.Lines {
+		doc.Line("%-10s %-8s %-36s %12s %12s %12s", l.Date.Format("2006-01-02"), l.Type, l.Reference, amountOrBlank(l.Debit), amountOrBlank(l.Credit), l.Balance.StringFixed(2))
+	}
+	doc.Line("")
+	doc.Line("%-10s %-8s %-36s %12s %12s %12s", st.To.Format("2006-01-02"), "", "Closing balance", st.Debits.StringFixed(2), st.Credits.StringFixed(2), st.ClosingBalance.StringFixed(2))
+	return doc.Bytes()
+}
+
+func amountOrBlank(d decimal.Decimal) string {
+	if d.IsZero() {
+		return ""
+	}
+	return d.StringFixed(2)
+}
@@ -0,0 +1,69 @@
+package Billing
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Buyer is who an order is invoiced to. TaxID, TaxIDType (VAT, PIN) and
+// Country are set for registered businesses.
+type Buyer struct {
+	Name      string
+	Company   *string
+	TaxID     *string
+	TaxIDType *string
+	Country   *string
+}
+
+// BuyerDirectory finds the buyer of an order; it returns nil for guest orders
+type BuyerDirectory interface {
+	Buyer(ctx context.Context, orderID uuid.UUID) (*Buyer, error)
+}
+
+// TaxConfig is the seller's own tax registration printed on every invoice
+type TaxConfig struct {
+	SellerTaxID   string
+	SellerCountry string
+}
+
+const reverseChargeNote = "Reverse charge: VAT to be accounted for by the recipient"
+
+// applyTaxIdentity copies the buyer and seller tax identity onto the invoice.
+// A VAT-registered business buyer in another country than the seller is
+// invoiced under the reverse charge.
+func (s *service) applyTaxIdentity(ctx context.Context, inv *Invoice) error {
+	if s.tax.SellerTaxID != "" {
+		inv.SellerTaxID = &s.tax.SellerTaxID
+	}
+	if s.buyers == nil {
+		return nil
+	}
+	b, err := s.buyers.Buyer(ctx, inv.OrderID)
+	if err != nil || b == nil {
+		return err
+	}
+	if b.Name != "" {
+		inv.BuyerName = &b.Name
+	}
+	inv.BuyerCompany = b.Company
+	inv.BuyerTaxID = b.TaxID
+	inv.BuyerTaxIDType = b.TaxIDType
+	inv.BuyerCountry = b.Country
+	if s.reverseCharge(b) {
+		inv.ReverseCharge = true
+		note := reverseChargeNote
+		inv.TaxNote = &note
+	}
+	return nil
+}
+
+func (s *service) reverseCharge(b *Buyer) bool {
+	if s.tax.SellerTaxID == "" || s.tax.SellerCountry == "" {
+		return false
+	}
+	if b.TaxID == nil || b.TaxIDType == nil || *b.TaxIDType != "VAT" || b.Country == nil {
+		return false
+	}
+	return *b.Country != s.tax.SellerCountry
+}
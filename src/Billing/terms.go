@@ -0,0 +1,122 @@
+package Billing
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+// ParseTerms reads net payment terms such as NET30 into the days an invoice
+// is due after it is issued
+func ParseTerms(terms string) (int, error) {
+	rest, ok := strings.CutPrefix(strings.ToUpper(terms), "NET")
+	days, err := strconv.Atoi(rest)
+	if !ok || err != nil || days < 1 || days > 365 {
+		return 0, ErrorInvalidTerms
+	}
+	return days, nil
+}
+
+// GetCreditAccount returns the customer's credit account with its exposure
+func (s *service) GetCreditAccount(ctx context.Context, customerID uuid.UUID) (*CreditAccount, error) {
+	accounts, err := s.repo.Exposure(ctx, ExposureQuery{CustomerID: &customerID, Limit: 1})
+	if err != nil {
+		return nil, err
+	}
+	if len(accounts) == 0 {
+		return nil, ErrorNoCreditAccount
+	}
+	exposure(&accounts[0])
+	return &accounts[0], nil
+}
+
+// CreditExposure is the finance report of what customers on payment terms
+// owe against their credit limits
+func (s *service) CreditExposure(ctx context.Context, q ExposureQuery) ([]CreditAccount, error) {
+	if q.Limit <= 0 || q.Limit > 500 {
+		q.Limit = 100
+	}
+	q.Currency = strings.ToUpper(q.Currency)
+	accounts, err := s.repo.Exposure(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	for i := range accounts {
+		exposure(&accounts[i])
+	}
+	return accounts, nil
+}
+
+// SetCreditAccount approves the customer for payment terms or changes their
+// terms and limit. Lowering the limit below what is outstanding only stops
+// new terms orders.
+func (s *service) SetCreditAccount(ctx context.Context, customerID uuid.UUID, req SetCreditAccountRequest) (*CreditAccount, error) {
+	if _, err := ParseTerms(req.PaymentTerms); err != nil {
+		return nil, err
+	}
+	if req.CreditLimit.IsNegative() {
+		return nil, ErrorInvalidCreditLimit
+	}
+	a := &CreditAccount{CustomerID: customerID, PaymentTerms: strings.ToUpper(req.PaymentTerms), CreditLimit: req.CreditLimit, Currency: strings.ToUpper(req.Currency), OnExceed: req.OnExceed}
+	if a.OnExceed == "" {
+		a.OnExceed = CreditBlock
+	}
+	if err := s.repo.PutCreditAccount(ctx, a); err != nil {
+		return nil, err
+	}
+	return s.GetCreditAccount(ctx, customerID)
+}
+
+func (s *service) DeleteCreditAccount(ctx context.Context, customerID uuid.UUID) error {
+	return s.repo.DeleteCreditAccount(ctx, customerID)
+}
+
+// exposure derives what is left of the limit and how much of it is used
+func exposure(a *CreditAccount) {
+	a.Available = decimal.Max(a.CreditLimit.Sub(a.Outstanding), decimal.Zero)
+	a.OverLimit = a.Outstanding.GreaterThan(a.CreditLimit)
+	if a.CreditLimit.IsPositive() {
+		u := a.Outstanding.Div(a.CreditLimit).Round(4)
+		a.Utilization = &u
+	}
+}
+
+// OpenTermsInvoice invoices an order placed on payment terms instead of
+// taking payment: the customer must be approved for terms at least as long
+// as those asked for, in the order's currency, and have enough credit left
+// unless their account flags rather than blocks orders over the limit. The
+// invoice stays UNPAID, due after the terms, until RecordPayment settles it.
+func (s *service) OpenTermsInvoice(ctx context.Context, orderID, customerID uuid.UUID, amount decimal.Decimal, currency, terms string) (*Invoice, error) {
+	days, err := ParseTerms(terms)
+	if err != nil {
+		return nil, err
+	}
+	account, err := s.repo.GetCreditAccount(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+	if approved, err := ParseTerms(account.PaymentTerms); err != nil || days > approved {
+		return nil, ErrorTermsNotApproved
+	}
+	if !strings.EqualFold(account.Currency, currency) {
+		return nil, ErrorCreditCurrency
+	}
+	due := time.Now().UTC().AddDate(0, 0, days)
+	inv := &Invoice{OrderID: orderID, InvoiceNumber: uuid.New().String(), Status: InvoiceUnpaid, Amount: amount, Currency: currency, DueAt: &due}
+	if err := s.applyTaxIdentity(ctx, inv); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateTermsInvoice(ctx, inv, customerID); err != nil {
+		return nil, err
+	}
+	if inv.OverCreditLimit {
+		s.log.Warn("terms order over credit limit", zap.String("order_id", orderID.String()), zap.String("customer_id", customerID.String()), zap.String("amount", amount.String()))
+	}
+	s.queueSync(ctx, "INVOICE", inv.ID)
+	return inv, nil
+}
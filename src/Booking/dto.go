@@ -0,0 +1,20 @@
+package Booking
+
+import "time"
+
+// CreateSlotsRequest adds slots to a product's calendar
+type CreateSlotsRequest struct {
+	Slots []SlotRequest `json:"slots" validate:"required,min=1,max=500,dive"`
+}
+
+type SlotRequest struct {
+	StartsAt time.Time `json:"starts_at" validate:"required"`
+	EndsAt   time.Time `json:"ends_at" validate:"required"`
+	Capacity int       `json:"capacity" validate:"required,min=1,max=100000"`
+}
+
+// UpdateSlotRequest changes a slot's capacity; it can't go below what is
+// booked
+type UpdateSlotRequest struct {
+	Capacity int `json:"capacity" validate:"required,min=1,max=100000"`
+}
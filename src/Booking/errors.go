@@ -0,0 +1,33 @@
+package Booking
+
+import (
+	"errors"
+
+	"savannah/src/ErrorCodes"
+)
+
+var (
+	ErrorNotFound       = errors.New("slot not found")
+	ErrorInvalidPayload = errors.New("invalid slot payload")
+	ErrorUnknownProduct = errors.New("product not found")
+	ErrorSlotExists     = errors.New("the product already has a slot starting at that time")
+	ErrorFull           = errors.New("not enough capacity left in the slot")
+	ErrorStarted        = errors.New("the slot has already started")
+	ErrorWrongProduct   = errors.New("the slot is not one of the product's")
+	ErrorSlotRequired   = errors.New("bookable products must be ordered for a slot")
+	ErrorBooked         = errors.New("the slot has bookings")
+)
+
+func init() {
+	ErrorCodes.Register("BOOKING",
+		ErrorCodes.Def{N: 1, Slug: "NOT_FOUND", Err: ErrorNotFound},
+		ErrorCodes.Def{N: 2, Slug: "INVALID_PAYLOAD", Err: ErrorInvalidPayload},
+		ErrorCodes.Def{N: 3, Slug: "UNKNOWN_PRODUCT", Err: ErrorUnknownProduct},
+		ErrorCodes.Def{N: 4, Slug: "SLOT_EXISTS", Err: ErrorSlotExists},
+		ErrorCodes.Def{N: 5, Slug: "SLOT_FULL", Err: ErrorFull},
+		ErrorCodes.Def{N: 6, Slug: "SLOT_STARTED", Err: ErrorStarted},
+		ErrorCodes.Def{N: 7, Slug: "WRONG_PRODUCT", Err: ErrorWrongProduct},
+		ErrorCodes.Def{N: 8, Slug: "SLOT_REQUIRED", Err: ErrorSlotRequired},
+		ErrorCodes.Def{N: 9, Slug: "SLOT_BOOKED", Err: ErrorBooked},
+	)
+}
@@ -0,0 +1,178 @@
+package Booking
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"savannah/src/ErrorCodes"
+)
+
+type Handler struct {
+	svc Service
+	log *zap.Logger
+	v   *validator.Validate
+}
+
+func NewHandler(s Service, log *zap.Logger) *Handler {
+	return &Handler{svc: s, log: log, v: validator.New()}
+}
+
+// CreateSlots godoc
+// @Summary      Add slots to a product's calendar
+// @Description  Makes the product bookable: its order lines must then name one of its slots. Slots of a product may not start at the same time.
+// @Tags         booking
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string              true  "Product ID"
+// @Param        payload  body      CreateSlotsRequest  true  "Slots"
+// @Success      201      {array}   Slot
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      404      {object}  map[string]interface{}
+// @Failure      409      {object}  map[string]interface{}
+// @Router       /products/{id}/slots [post]
+func (h *Handler) CreateSlots(w http.ResponseWriter, r *http.Request) {
+	productID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var dto CreateSlotsRequest
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if err := h.v.Struct(dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	slots, err := h.svc.CreateSlots(r.Context(), productID, dto)
+	if err != nil {
+		h.slotError(w, "create slots", err)
+		return
+	}
+	h.writeJSON(w, http.StatusCreated, slots)
+}
+
+// Calendar godoc
+// @Summary      A product's availability calendar
+// @Description  Its slots overlapping [from, to) with the capacity left in each. The window may span at most 92 days.
+// @Tags         booking
+// @Produce      json
+// @Param        id    path      string  true   "Product ID"
+// @Param        from  query     string  false  "RFC3339 start (default now)"
+// @Param        to    query     string  false  "RFC3339 end (default 30 days after from)"
+// @Success      200   {array}   Slot
+// @Failure      400   {object}  map[string]interface{}
+// @Router       /products/{id}/slots [get]
+func (h *Handler) Calendar(w http.ResponseWriter, r *http.Request) {
+	productID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	q := r.URL.Query()
+	var from, to time.Time
+	if v := q.Get("from"); v != "" {
+		if from, err = time.Parse(time.RFC3339, v); err != nil {
+			h.writeError(w, http.StatusBadRequest, "invalid from")
+			return
+		}
+	}
+	if v := q.Get("to"); v != "" {
+		if to, err = time.Parse(time.RFC3339, v); err != nil {
+			h.writeError(w, http.StatusBadRequest, "invalid to")
+			return
+		}
+	}
+	slots, err := h.svc.Calendar(r.Context(), productID, from, to)
+	if err != nil {
+		h.slotError(w, "read calendar", err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, slots)
+}
+
+// UpdateSlot godoc
+// @Summary      Change a slot's capacity
+// @Tags         booking
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string             true  "Slot ID"
+// @Param        payload  body      UpdateSlotRequest  true  "Capacity"
+// @Success      200      {object}  Slot
+// @Failure      404      {object}  map[string]interface{}
+// @Failure      409      {object}  map[string]interface{}
+// @Router       /slots/{id} [patch]
+func (h *Handler) UpdateSlot(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var dto UpdateSlotRequest
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if err := h.v.Struct(dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	slot, err := h.svc.UpdateSlot(r.Context(), id, dto)
+	if err != nil {
+		h.slotError(w, "update slot", err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, slot)
+}
+
+// DeleteSlot godoc
+// @Summary      Delete a slot nobody booked
+// @Tags         booking
+// @Param        id   path  string  true  "Slot ID"
+// @Success      204
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      409  {object}  map[string]interface{}
+// @Router       /slots/{id} [delete]
+func (h *Handler) DeleteSlot(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	if err := h.svc.DeleteSlot(r.Context(), id); err != nil {
+		h.slotError(w, "delete slot", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) slotError(w http.ResponseWriter, op string, err error) {
+	switch {
+	case errors.Is(err, ErrorNotFound), errors.Is(err, ErrorUnknownProduct):
+		h.writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrorInvalidPayload):
+		h.writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrorSlotExists), errors.Is(err, ErrorBooked):
+		h.writeError(w, http.StatusConflict, err.Error())
+	default:
+		h.log.Error(op, zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to "+op)
+	}
+}
+
+func (h *Handler) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (h *Handler) writeError(w http.ResponseWriter, status int, msg string) {
+	h.writeJSON(w, status, map[string]interface{}{"error": msg, "code": ErrorCodes.Resolve("BOOKING", status, msg), "timestamp": time.Now().UTC()})
+}
@@ -0,0 +1,36 @@
+package Booking
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Slot is a period a bookable product (a rental, an appointment) can be
+// ordered for, by up to Capacity units across all orders. A product is
+// bookable while it has slots that have not ended; its order lines then
+// name the slot they book.
+type Slot struct {
+	ID        uuid.UUID `db:"id" json:"id"`
+	ProductID uuid.UUID `db:"product_id" json:"product_id"`
+	StartsAt  time.Time `db:"starts_at" json:"starts_at"`
+	EndsAt    time.Time `db:"ends_at" json:"ends_at"`
+	Capacity  int       `db:"capacity" json:"capacity"`
+	Booked    int       `db:"booked" json:"booked"`
+	Available int       `db:"-" json:"available"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+const TableName = "booking_slots"
+
+const (
+	// MaxSlots caps the slots one request creates
+	MaxSlots = 500
+	// MaxCalendarDays caps the window of one calendar read
+	MaxCalendarDays = 92
+)
+
+func (s *Slot) fill() {
+	s.Available = max(s.Capacity-s.Booked, 0)
+}
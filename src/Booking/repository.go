@@ -0,0 +1,151 @@
+package Booking
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+type Repository interface {
+	Create(ctx context.Context, slots []Slot) error
+	Get(ctx context.Context, id uuid.UUID) (*Slot, error)
+	Calendar(ctx context.Context, productID uuid.UUID, from, to time.Time) ([]Slot, error)
+	SetCapacity(ctx context.Context, id uuid.UUID, capacity int) (*Slot, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+	Book(ctx context.Context, id, productID uuid.UUID, qty int, now time.Time) error
+	Unbook(ctx context.Context, id uuid.UUID, qty int) error
+	Bookable(ctx context.Context, productIDs []uuid.UUID, now time.Time) ([]uuid.UUID, error)
+}
+
+type repository struct {
+	db  *sqlx.DB
+	log *zap.Logger
+}
+
+func NewRepository(db *sqlx.DB, log *zap.Logger) Repository { return &repository{db: db, log: log} }
+
+const slotColumns = `id,product_id,starts_at,ends_at,capacity,booked,created_at,updated_at`
+
+// Create stores the slots together; one clashing with an existing slot of
+// the product stores none
+func (r *repository) Create(ctx context.Context, slots []Slot) (err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	query := fmt.Sprintf(`INSERT INTO %s (%s) VALUES (:id,:product_id,:starts_at,:ends_at,:capacity,:booked,:created_at,:updated_at)`, TableName, slotColumns)
+	for i := range slots {
+		if _, err = tx.NamedExecContext(ctx, query, slots[i]); err != nil {
+			var pqErr *pq.Error
+			if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+				err = ErrorSlotExists
+			} else if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+				err = ErrorUnknownProduct
+			}
+			return err
+		}
+	}
+	err = tx.Commit()
+	return err
+}
+
+func (r *repository) Get(ctx context.Context, id uuid.UUID) (*Slot, error) {
+	var s Slot
+	if err := r.db.GetContext(ctx, &s, fmt.Sprintf(`SELECT %s FROM %s WHERE id=$1`, slotColumns, TableName), id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrorNotFound
+		}
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Calendar lists the product's slots overlapping [from, to) in time order
+func (r *repository) Calendar(ctx context.Context, productID uuid.UUID, from, to time.Time) ([]Slot, error) {
+	out := []Slot{}
+	err := r.db.SelectContext(ctx, &out, fmt.Sprintf(`SELECT %s FROM %s WHERE product_id=$1 AND starts_at < $3 AND ends_at > $2 ORDER BY starts_at`, slotColumns, TableName), productID, from, to)
+	return out, err
+}
+
+func (r *repository) SetCapacity(ctx context.Context, id uuid.UUID, capacity int) (*Slot, error) {
+	var s Slot
+	err := r.db.GetContext(ctx, &s, fmt.Sprintf(`UPDATE %s SET capacity=$2, updated_at=NOW() WHERE id=$1 AND booked <= $2 RETURNING %s`, TableName, slotColumns), id, capacity)
+	if err == sql.ErrNoRows {
+		if _, err = r.Get(ctx, id); err != nil {
+			return nil, err
+		}
+		return nil, ErrorBooked
+	}
+	return &s, err
+}
+
+// Delete removes a slot nobody booked
+func (r *repository) Delete(ctx context.Context, id uuid.UUID) error {
+	res, err := r.db.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE id=$1 AND booked = 0`, TableName), id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		if _, err := r.Get(ctx, id); err != nil {
+			return err
+		}
+		return ErrorBooked
+	}
+	return nil
+}
+
+// Book takes qty units of a slot of the product that has not started. The
+// check and the update are one statement, so concurrent orders can't
+// overbook it.
+func (r *repository) Book(ctx context.Context, id, productID uuid.UUID, qty int, now time.Time) error {
+	res, err := r.db.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET booked=booked+$3, updated_at=$4
+		WHERE id=$1 AND product_id=$2 AND starts_at > $4 AND booked+$3 <= capacity`, TableName), id, productID, qty, now)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		return nil
+	}
+	s, err := r.Get(ctx, id)
+	switch {
+	case err != nil:
+		return err
+	case s.ProductID != productID:
+		return ErrorWrongProduct
+	case !s.StartsAt.After(now):
+		return ErrorStarted
+	default:
+		return ErrorFull
+	}
+}
+
+func (r *repository) Unbook(ctx context.Context, id uuid.UUID, qty int) error {
+	_, err := r.db.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET booked=GREATEST(booked-$2, 0), updated_at=NOW() WHERE id=$1`, TableName), id, qty)
+	return err
+}
+
+// Bookable returns those of productIDs with a slot that has not ended
+func (r *repository) Bookable(ctx context.Context, productIDs []uuid.UUID, now time.Time) ([]uuid.UUID, error) {
+	out := []uuid.UUID{}
+	if len(productIDs) == 0 {
+		return out, nil
+	}
+	query, args, err := sqlx.In(fmt.Sprintf(`SELECT DISTINCT product_id FROM %s WHERE product_id IN (?) AND ends_at > ?`, TableName), productIDs, now)
+	if err != nil {
+		return nil, err
+	}
+	err = r.db.SelectContext(ctx, &out, r.db.Rebind(query), args...)
+	return out, err
+}
@@ -0,0 +1,111 @@
+package Booking
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Service keeps the slot calendars of bookable products; Orders books and
+// frees slots as orders are placed and cancelled
+type Service interface {
+	CreateSlots(ctx context.Context, productID uuid.UUID, req CreateSlotsRequest) ([]Slot, error)
+	Calendar(ctx context.Context, productID uuid.UUID, from, to time.Time) ([]Slot, error)
+	UpdateSlot(ctx context.Context, id uuid.UUID, req UpdateSlotRequest) (*Slot, error)
+	DeleteSlot(ctx context.Context, id uuid.UUID) error
+
+	Book(ctx context.Context, slotID, productID uuid.UUID, qty int) error
+	Unbook(ctx context.Context, slotID uuid.UUID, qty int) error
+	Bookable(ctx context.Context, productIDs []uuid.UUID) (map[uuid.UUID]bool, error)
+}
+
+type service struct {
+	repo Repository
+	log  *zap.Logger
+}
+
+func NewService(r Repository, log *zap.Logger) Service {
+	return &service{repo: r, log: log}
+}
+
+func (s *service) CreateSlots(ctx context.Context, productID uuid.UUID, req CreateSlotsRequest) ([]Slot, error) {
+	if len(req.Slots) > MaxSlots {
+		return nil, fmt.Errorf("%w: at most %d slots at a time", ErrorInvalidPayload, MaxSlots)
+	}
+	now := time.Now().UTC()
+	slots := make([]Slot, len(req.Slots))
+	for i, sr := range req.Slots {
+		if !sr.EndsAt.After(sr.StartsAt) {
+			return nil, fmt.Errorf("%w: slot %d must end after it starts", ErrorInvalidPayload, i)
+		}
+		slots[i] = Slot{ID: uuid.New(), ProductID: productID, StartsAt: sr.StartsAt.UTC(), EndsAt: sr.EndsAt.UTC(),
+			Capacity: sr.Capacity, CreatedAt: now, UpdatedAt: now}
+		slots[i].fill()
+	}
+	if err := s.repo.Create(ctx, slots); err != nil {
+		return nil, err
+	}
+	return slots, nil
+}
+
+// Calendar lists the product's slots between from and to, by default the
+// next 30 days
+func (s *service) Calendar(ctx context.Context, productID uuid.UUID, from, to time.Time) ([]Slot, error) {
+	if from.IsZero() {
+		from = time.Now().UTC()
+	}
+	if to.IsZero() {
+		to = from.AddDate(0, 0, 30)
+	}
+	if !from.Before(to) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrorInvalidPayload)
+	}
+	if to.Sub(from) > MaxCalendarDays*24*time.Hour {
+		return nil, fmt.Errorf("%w: at most %d days at a time", ErrorInvalidPayload, MaxCalendarDays)
+	}
+	slots, err := s.repo.Calendar(ctx, productID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	for i := range slots {
+		slots[i].fill()
+	}
+	return slots, nil
+}
+
+func (s *service) UpdateSlot(ctx context.Context, id uuid.UUID, req UpdateSlotRequest) (*Slot, error) {
+	slot, err := s.repo.SetCapacity(ctx, id, req.Capacity)
+	if err != nil {
+		return nil, err
+	}
+	slot.fill()
+	return slot, nil
+}
+
+func (s *service) DeleteSlot(ctx context.Context, id uuid.UUID) error {
+	return s.repo.Delete(ctx, id)
+}
+
+func (s *service) Book(ctx context.Context, slotID, productID uuid.UUID, qty int) error {
+	return s.repo.Book(ctx, slotID, productID, qty, time.Now().UTC())
+}
+
+func (s *service) Unbook(ctx context.Context, slotID uuid.UUID, qty int) error {
+	return s.repo.Unbook(ctx, slotID, qty)
+}
+
+// Bookable reports which of productIDs are ordered for a slot
+func (s *service) Bookable(ctx context.Context, productIDs []uuid.UUID) (map[uuid.UUID]bool, error) {
+	ids, err := s.repo.Bookable(ctx, productIDs, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		out[id] = true
+	}
+	return out, nil
+}
@@ -0,0 +1,48 @@
+package Cart
+
+import (
+	"github.com/google/uuid"
+	"savannah/src/Orders"
+)
+
+// CreateCartRequest starts a cart; a guest cart leaves out the customer
+type CreateCartRequest struct {
+	CustomerID *uuid.UUID `json:"customer_id,omitempty"`
+}
+
+// AddItemRequest adds to the cart; a product and variant already in it has
+// its quantity raised instead
+type AddItemRequest struct {
+	ProductID uuid.UUID  `json:"product_id" validate:"required"`
+	VariantID *uuid.UUID `json:"variant_id,omitempty"`
+	Quantity  int        `json:"quantity" validate:"required,min=1,max=10000"`
+}
+
+type UpdateItemRequest struct {
+	Quantity int `json:"quantity" validate:"required,min=1,max=10000"`
+}
+
+// MergeCartRequest names the customer who logged in with a guest cart
+type MergeCartRequest struct {
+	CustomerID uuid.UUID `json:"customer_id" validate:"required"`
+}
+
+// CheckoutRequest is how the cart's order is paid and delivered
+type CheckoutRequest struct {
+	Warehouse     string `json:"warehouse,omitempty"`
+	Country       string `json:"country,omitempty" validate:"omitempty,len=2"`
+	Region        string `json:"region,omitempty" validate:"omitempty,max=100"`
+	PaymentMethod string `json:"payment_method" validate:"required_without=PaymentTerms,max=50"`
+	Priority      string `json:"priority,omitempty" validate:"omitempty,oneof=standard express"`
+	Channel       string `json:"channel,omitempty" validate:"omitempty,oneof=web mobile"`
+	PONumber      string `json:"po_number,omitempty" validate:"omitempty,max=64"`
+	PaymentTerms  string `json:"payment_terms,omitempty" validate:"omitempty,max=10"`
+	// a stock hold taken for the cart at POST /inventory/holds
+	HoldID *uuid.UUID `json:"hold_id,omitempty"`
+	// a coupon issued at POST /coupons
+	CouponCode string `json:"coupon_code,omitempty" validate:"omitempty,max=40"`
+	// a gift card paying what it can of the total
+	GiftCardCode string `json:"gift_card_code,omitempty" validate:"omitempty,max=64"`
+	// read off the request, see Orders.ClientFrom
+	Client *Orders.ClientContext `json:"-"`
+}
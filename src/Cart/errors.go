@@ -0,0 +1,35 @@
+package Cart
+
+import (
+	"errors"
+
+	"savannah/src/ErrorCodes"
+)
+
+var (
+	ErrorNotFound        = errors.New("cart not found")
+	ErrorItemNotFound    = errors.New("cart item not found")
+	ErrorInvalidPayload  = errors.New("invalid cart payload")
+	ErrorUnknownCustomer = errors.New("customer not found")
+	ErrorUnknownProduct  = errors.New("product or variant not found")
+	ErrorNotActive       = errors.New("cart is no longer active")
+	ErrorExpired         = errors.New("cart has expired")
+	ErrorEmpty           = errors.New("cart is empty")
+	ErrorTooManyItems    = errors.New("cart has too many lines")
+	ErrorPricesChanged   = errors.New("prices changed since the items were added, review the cart and check out again")
+)
+
+func init() {
+	ErrorCodes.Register("CARTS",
+		ErrorCodes.Def{N: 1, Slug: "NOT_FOUND", Err: ErrorNotFound},
+		ErrorCodes.Def{N: 2, Slug: "ITEM_NOT_FOUND", Err: ErrorItemNotFound},
+		ErrorCodes.Def{N: 3, Slug: "INVALID_PAYLOAD", Err: ErrorInvalidPayload},
+		ErrorCodes.Def{N: 4, Slug: "UNKNOWN_CUSTOMER", Err: ErrorUnknownCustomer},
+		ErrorCodes.Def{N: 5, Slug: "UNKNOWN_PRODUCT", Err: ErrorUnknownProduct},
+		ErrorCodes.Def{N: 6, Slug: "NOT_ACTIVE", Err: ErrorNotActive},
+		ErrorCodes.Def{N: 7, Slug: "EXPIRED", Err: ErrorExpired},
+		ErrorCodes.Def{N: 8, Slug: "EMPTY", Err: ErrorEmpty},
+		ErrorCodes.Def{N: 9, Slug: "TOO_MANY_ITEMS", Err: ErrorTooManyItems},
+		ErrorCodes.Def{N: 10, Slug: "PRICES_CHANGED", Err: ErrorPricesChanged},
+	)
+}
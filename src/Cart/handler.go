@@ -0,0 +1,321 @@
+package Cart
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"savannah/src/Billing"
+	"savannah/src/Booking"
+	"savannah/src/Coupons"
+	"savannah/src/ErrorCodes"
+	"savannah/src/GiftCards"
+	"savannah/src/Inventory"
+	"savannah/src/Orders"
+)
+
+type Handler struct {
+	svc Service
+	log *zap.Logger
+	v   *validator.Validate
+}
+
+func NewHandler(s Service, log *zap.Logger) *Handler {
+	return &Handler{svc: s, log: log, v: validator.New()}
+}
+
+// CheckoutResponse is a checked out cart with the order it was turned into
+type CheckoutResponse struct {
+	Cart  *Cart         `json:"cart"`
+	Order *Orders.Order `json:"order"`
+}
+
+// Create godoc
+// @Summary      Create a cart
+// @Description  Starts a guest cart, or a customer's cart when customer_id is given. A customer has at most one active cart: when they already have one it is returned with 200 instead. Carts expire after a period without changes (7 days for guests, 30 for customers by default).
+// @Tags         carts
+// @Accept       json
+// @Produce      json
+// @Param        payload  body      CreateCartRequest  false  "Cart"
+// @Success      201      {object}  Cart
+// @Success      200      {object}  Cart
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      422      {object}  map[string]interface{}
+// @Router       /carts [post]
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	var dto CreateCartRequest
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil && !errors.Is(err, io.EOF) {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	c, created, err := h.svc.Create(r.Context(), dto)
+	if err != nil {
+		h.cartError(w, "create cart", err)
+		return
+	}
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	h.writeJSON(w, status, c)
+}
+
+// Get godoc
+// @Summary      Get a cart
+// @Description  The cart with its lines at the prices snapshotted when they were added. An active cart past its expiry is reported as EXPIRED.
+// @Tags         carts
+// @Produce      json
+// @Param        id   path      string  true  "Cart ID"
+// @Success      200  {object}  Cart
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /carts/{id} [get]
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.id(w, r, "id")
+	if !ok {
+		return
+	}
+	c, err := h.svc.Get(r.Context(), id)
+	if err != nil {
+		h.cartError(w, "get cart", err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, c)
+}
+
+// AddItem godoc
+// @Summary      Add to a cart
+// @Description  Adds the product (or variant) at its current catalog price. When the cart already holds it the quantity is added to that line, which takes the newer price. A cart holds at most 100 lines.
+// @Tags         carts
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string          true  "Cart ID"
+// @Param        payload  body      AddItemRequest  true  "Item"
+// @Success      200      {object}  Cart
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      404      {object}  map[string]interface{}
+// @Failure      409      {object}  map[string]interface{}
+// @Failure      410      {object}  map[string]interface{}
+// @Failure      422      {object}  map[string]interface{}
+// @Router       /carts/{id}/items [post]
+func (h *Handler) AddItem(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.id(w, r, "id")
+	if !ok {
+		return
+	}
+	var dto AddItemRequest
+	if !h.decode(w, r, &dto) {
+		return
+	}
+	c, err := h.svc.AddItem(r.Context(), id, dto)
+	if err != nil {
+		h.cartError(w, "add cart item", err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, c)
+}
+
+// UpdateItem godoc
+// @Summary      Change a cart line's quantity
+// @Tags         carts
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string             true  "Cart ID"
+// @Param        item_id  path      string             true  "Item ID"
+// @Param        payload  body      UpdateItemRequest  true  "Quantity"
+// @Success      200      {object}  Cart
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      404      {object}  map[string]interface{}
+// @Failure      409      {object}  map[string]interface{}
+// @Failure      410      {object}  map[string]interface{}
+// @Router       /carts/{id}/items/{item_id} [patch]
+func (h *Handler) UpdateItem(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.id(w, r, "id")
+	if !ok {
+		return
+	}
+	itemID, ok := h.id(w, r, "item_id")
+	if !ok {
+		return
+	}
+	var dto UpdateItemRequest
+	if !h.decode(w, r, &dto) {
+		return
+	}
+	c, err := h.svc.UpdateItem(r.Context(), id, itemID, dto)
+	if err != nil {
+		h.cartError(w, "update cart item", err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, c)
+}
+
+// RemoveItem godoc
+// @Summary      Remove a cart line
+// @Tags         carts
+// @Produce      json
+// @Param        id       path      string  true  "Cart ID"
+// @Param        item_id  path      string  true  "Item ID"
+// @Success      200      {object}  Cart
+// @Failure      404      {object}  map[string]interface{}
+// @Failure      409      {object}  map[string]interface{}
+// @Failure      410      {object}  map[string]interface{}
+// @Router       /carts/{id}/items/{item_id} [delete]
+func (h *Handler) RemoveItem(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.id(w, r, "id")
+	if !ok {
+		return
+	}
+	itemID, ok := h.id(w, r, "item_id")
+	if !ok {
+		return
+	}
+	c, err := h.svc.RemoveItem(r.Context(), id, itemID)
+	if err != nil {
+		h.cartError(w, "remove cart item", err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, c)
+}
+
+// Merge godoc
+// @Summary      Merge a guest cart on login
+// @Description  Moves the guest cart's lines into the customer's active cart, adding up the quantities of products both hold, and returns that cart; the guest cart is left MERGED. A customer without an active cart takes the guest cart over.
+// @Tags         carts
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string            true  "Guest cart ID"
+// @Param        payload  body      MergeCartRequest  true  "Customer"
+// @Success      200      {object}  Cart
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      404      {object}  map[string]interface{}
+// @Failure      409      {object}  map[string]interface{}
+// @Failure      410      {object}  map[string]interface{}
+// @Failure      422      {object}  map[string]interface{}
+// @Router       /carts/{id}/merge [post]
+func (h *Handler) Merge(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.id(w, r, "id")
+	if !ok {
+		return
+	}
+	var dto MergeCartRequest
+	if !h.decode(w, r, &dto) {
+		return
+	}
+	c, err := h.svc.Merge(r.Context(), id, dto)
+	if err != nil {
+		h.cartError(w, "merge cart", err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, c)
+}
+
+// Checkout godoc
+// @Summary      Check out a cart
+// @Description  Places an order for the cart's lines, priced from the catalog like POST /orders. When a price changed since a line was added the cart is answered with 409 and the changes, its lines now at the new prices; checking out again accepts them. When the payment fails the answer is 402 with the order, which stays linked to the cart and can be paid again.
+// @Tags         carts
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string           true  "Cart ID"
+// @Param        payload  body      CheckoutRequest  true  "Payment and delivery"
+// @Success      201      {object}  CheckoutResponse
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      402      {object}  map[string]interface{}
+// @Failure      404      {object}  map[string]interface{}
+// @Failure      409      {object}  map[string]interface{}
+// @Failure      410      {object}  map[string]interface{}
+// @Failure      422      {object}  map[string]interface{}
+// @Router       /carts/{id}/checkout [post]
+func (h *Handler) Checkout(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.id(w, r, "id")
+	if !ok {
+		return
+	}
+	var dto CheckoutRequest
+	if !h.decode(w, r, &dto) {
+		return
+	}
+	dto.Client = Orders.ClientFrom(r)
+	c, o, changes, err := h.svc.Checkout(r.Context(), id, dto)
+	var payment *Orders.PaymentError
+	var limit *Orders.LimitError
+	var mismatch *Orders.CurrencyError
+	switch {
+	case errors.Is(err, ErrorPricesChanged):
+		code, _ := ErrorCodes.For(err)
+		h.writeJSON(w, http.StatusConflict, map[string]interface{}{"error": err.Error(), "code": code, "cart": c, "changes": changes, "timestamp": time.Now().UTC()})
+	case errors.As(err, &payment):
+		code, _ := ErrorCodes.For(payment)
+		h.writeJSON(w, http.StatusPaymentRequired, map[string]interface{}{"error": payment.Error(), "code": code, "cart": c, "order": payment.Order, "timestamp": time.Now().UTC()})
+	case errors.As(err, &mismatch):
+		code, _ := ErrorCodes.For(err)
+		h.writeJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{"error": mismatch.Error(), "code": code, "currency": mismatch.Currency, "lines": mismatch.Lines, "timestamp": time.Now().UTC()})
+	case errors.Is(err, Inventory.ErrorInsufficientStock):
+		h.writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, Billing.ErrorInvalidTerms):
+		h.writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, Orders.ErrorFraudRejected):
+		h.log.Info("cart checkout declined by fraud screening", zap.Error(err))
+		h.writeError(w, http.StatusUnprocessableEntity, Orders.ErrorFraudRejected.Error())
+	case errors.As(err, &limit), errors.Is(err, Orders.ErrorUnknownProduct), errors.Is(err, Orders.ErrorUnknownVariant), errors.Is(err, Inventory.ErrorNoWarehouse), errors.Is(err, Orders.ErrorTermsNeedCustomer), errors.Is(err, Orders.ErrorMixedPreorder), errors.Is(err, Booking.ErrorSlotRequired), Coupons.Rejected(err), GiftCards.Rejected(err):
+		h.writeError(w, http.StatusUnprocessableEntity, err.Error())
+	case err != nil:
+		h.cartError(w, "check out cart", err)
+	default:
+		h.writeJSON(w, http.StatusCreated, CheckoutResponse{Cart: c, Order: o})
+	}
+}
+
+func (h *Handler) id(w http.ResponseWriter, r *http.Request, param string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, param))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid "+param)
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func (h *Handler) decode(w http.ResponseWriter, r *http.Request, dto interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return false
+	}
+	if err := h.v.Struct(dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return false
+	}
+	return true
+}
+
+func (h *Handler) cartError(w http.ResponseWriter, op string, err error) {
+	switch {
+	case errors.Is(err, ErrorNotFound), errors.Is(err, ErrorItemNotFound):
+		h.writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrorInvalidPayload):
+		h.writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrorUnknownCustomer), errors.Is(err, ErrorUnknownProduct), errors.Is(err, ErrorTooManyItems), errors.Is(err, ErrorEmpty):
+		h.writeError(w, http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, ErrorNotActive):
+		h.writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, ErrorExpired):
+		h.writeError(w, http.StatusGone, err.Error())
+	default:
+		h.log.Error(op, zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to "+op)
+	}
+}
+
+func (h *Handler) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (h *Handler) writeError(w http.ResponseWriter, status int, msg string) {
+	h.writeJSON(w, status, map[string]interface{}{"error": msg, "code": ErrorCodes.Resolve("CARTS", status, msg), "timestamp": time.Now().UTC()})
+}
@@ -0,0 +1,92 @@
+package Cart
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// cart statuses; StatusExpired is stored by the expiry job, and an active
+// cart past its expiry is reported as expired before the job gets to it
+const (
+	StatusActive     = "ACTIVE"
+	StatusMerged     = "MERGED"
+	StatusCheckedOut = "CHECKED_OUT"
+	StatusExpired    = "EXPIRED"
+)
+
+// Cart is a shopper's basket ahead of checkout. A guest cart has no
+// customer; on login it is merged into the customer's cart, of which there
+// is at most one active at a time.
+type Cart struct {
+	ID         uuid.UUID  `db:"id" json:"id"`
+	CustomerID *uuid.UUID `db:"customer_id" json:"customer_id,omitempty"`
+	Status     string     `db:"status" json:"status"`
+	// the cart a merged guest cart went into
+	MergedInto *uuid.UUID      `db:"merged_into" json:"merged_into,omitempty"`
+	OrderID    *uuid.UUID      `db:"order_id" json:"order_id,omitempty"`
+	Subtotal   decimal.Decimal `db:"-" json:"subtotal"`
+	ExpiresAt  time.Time       `db:"expires_at" json:"expires_at"`
+	CreatedAt  time.Time       `db:"created_at" json:"created_at"`
+	UpdatedAt  time.Time       `db:"updated_at" json:"updated_at"`
+	Items      []Item          `db:"-" json:"items"`
+}
+
+// effectiveStatus reports an active cart past its expiry as expired
+func (c *Cart) effectiveStatus(now time.Time) string {
+	if c.Status == StatusActive && !now.Before(c.ExpiresAt) {
+		return StatusExpired
+	}
+	return c.Status
+}
+
+// total sums the lines at their snapshot prices
+func (c *Cart) total() {
+	c.Subtotal = decimal.Zero
+	for _, it := range c.Items {
+		c.Subtotal = c.Subtotal.Add(it.total())
+	}
+}
+
+// Item is a cart line. UnitPrice, SKU and Name are snapshots taken when the
+// line was added or last repriced, shown to the shopper until checkout
+// checks them against the catalog.
+type Item struct {
+	ID        uuid.UUID       `db:"id" json:"id"`
+	CartID    uuid.UUID       `db:"cart_id" json:"-"`
+	ProductID uuid.UUID       `db:"product_id" json:"product_id"`
+	VariantID *uuid.UUID      `db:"variant_id" json:"variant_id,omitempty"`
+	SKU       *string         `db:"sku" json:"sku,omitempty"`
+	Name      *string         `db:"name" json:"name,omitempty"`
+	UnitPrice decimal.Decimal `db:"unit_price" json:"unit_price"`
+	Quantity  int             `db:"quantity" json:"quantity"`
+	PricedAt  time.Time       `db:"priced_at" json:"priced_at"`
+	CreatedAt time.Time       `db:"created_at" json:"created_at"`
+}
+
+func (it Item) total() decimal.Decimal {
+	return it.UnitPrice.Mul(decimal.NewFromInt(int64(it.Quantity)))
+}
+
+// same reports whether two lines are of the same product and variant
+func (it Item) same(o Item) bool {
+	if it.ProductID != o.ProductID || (it.VariantID == nil) != (o.VariantID == nil) {
+		return false
+	}
+	return it.VariantID == nil || *it.VariantID == *o.VariantID
+}
+
+// PriceChange is a line whose catalog price moved since it was snapshotted
+type PriceChange struct {
+	ItemID   uuid.UUID       `json:"item_id"`
+	Previous decimal.Decimal `json:"previous"`
+	Current  decimal.Decimal `json:"current"`
+}
+
+const (
+	TableName = "carts"
+	ItemTable = "cart_items"
+	// MaxItems matches the lines an order may have
+	MaxItems = 100
+)
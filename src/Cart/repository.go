@@ -0,0 +1,253 @@
+package Cart
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+type Repository interface {
+	Create(ctx context.Context, c *Cart) (bool, error)
+	Get(ctx context.Context, id uuid.UUID) (*Cart, error)
+	ActiveFor(ctx context.Context, customerID uuid.UUID) (*Cart, error)
+	AddItem(ctx context.Context, c *Cart, it *Item) error
+	SetQuantity(ctx context.Context, c *Cart, itemID uuid.UUID, quantity int) error
+	DeleteItem(ctx context.Context, c *Cart, itemID uuid.UUID) error
+	Reprice(ctx context.Context, c *Cart, items []Item) error
+	Merge(ctx context.Context, guest, into *Cart) error
+	Assign(ctx context.Context, c *Cart, customerID uuid.UUID) error
+	Claim(ctx context.Context, id uuid.UUID, now time.Time) error
+	Release(ctx context.Context, id uuid.UUID) error
+	SetOrder(ctx context.Context, id, orderID uuid.UUID) error
+	Expire(ctx context.Context, now time.Time) (int64, error)
+}
+
+type repository struct {
+	db  *sqlx.DB
+	log *zap.Logger
+}
+
+func NewRepository(db *sqlx.DB, log *zap.Logger) Repository { return &repository{db: db, log: log} }
+
+const (
+	cartColumns = `id,customer_id,status,merged_into,order_id,expires_at,created_at,updated_at`
+	itemColumns = `id,cart_id,product_id,variant_id,sku,name,unit_price,quantity,priced_at,created_at`
+)
+
+// Create stores the cart unless its customer already has an active one, in
+// which case it reports false. A customer's active cart past its expiry is
+// expired first so it doesn't stand in the way.
+func (r *repository) Create(ctx context.Context, c *Cart) (created bool, err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	if c.CustomerID != nil {
+		if _, err = tx.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET status='%s', updated_at=$2 WHERE customer_id=$1 AND status='%s' AND expires_at <= $2`, TableName, StatusExpired, StatusActive),
+			c.CustomerID, c.CreatedAt); err != nil {
+			return false, err
+		}
+	}
+	res, err := tx.NamedExecContext(ctx, fmt.Sprintf(`INSERT INTO %s (%s) VALUES (:id,:customer_id,:status,:merged_into,:order_id,:expires_at,:created_at,:updated_at)
+		ON CONFLICT (customer_id) WHERE status='%s' DO NOTHING`, TableName, cartColumns, StatusActive), c)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	err = tx.Commit()
+	return n > 0, err
+}
+
+func (r *repository) Get(ctx context.Context, id uuid.UUID) (*Cart, error) {
+	return r.get(ctx, `id=$1`, id)
+}
+
+// ActiveFor returns the customer's active cart
+func (r *repository) ActiveFor(ctx context.Context, customerID uuid.UUID) (*Cart, error) {
+	return r.get(ctx, fmt.Sprintf(`customer_id=$1 AND status='%s'`, StatusActive), customerID)
+}
+
+func (r *repository) get(ctx context.Context, where string, arg interface{}) (*Cart, error) {
+	var c Cart
+	if err := r.db.GetContext(ctx, &c, fmt.Sprintf(`SELECT %s FROM %s WHERE %s`, cartColumns, TableName, where), arg); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrorNotFound
+		}
+		return nil, err
+	}
+	c.Items = []Item{}
+	if err := r.db.SelectContext(ctx, &c.Items, fmt.Sprintf(`SELECT %s FROM %s WHERE cart_id=$1 ORDER BY created_at, id`, itemColumns, ItemTable), c.ID); err != nil {
+		return nil, err
+	}
+	c.total()
+	return &c, nil
+}
+
+// AddItem adds the line, or raises the quantity of the cart's line of the
+// same product and variant, keeping whichever price snapshot is newer
+func (r *repository) AddItem(ctx context.Context, c *Cart, it *Item) error {
+	return r.change(ctx, c, func(tx *sqlx.Tx) error {
+		return addItem(ctx, tx, c.ID, it)
+	})
+}
+
+func addItem(ctx context.Context, tx *sqlx.Tx, cartID uuid.UUID, it *Item) error {
+	it.CartID = cartID
+	return tx.QueryRowxContext(ctx, fmt.Sprintf(`INSERT INTO %[1]s (%[2]s) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)
+		ON CONFLICT (cart_id, product_id, (COALESCE(variant_id, '00000000-0000-0000-0000-000000000000'))) DO UPDATE SET
+			quantity = %[1]s.quantity + EXCLUDED.quantity,
+			unit_price = CASE WHEN EXCLUDED.priced_at > %[1]s.priced_at THEN EXCLUDED.unit_price ELSE %[1]s.unit_price END,
+			sku = CASE WHEN EXCLUDED.priced_at > %[1]s.priced_at THEN EXCLUDED.sku ELSE %[1]s.sku END,
+			name = CASE WHEN EXCLUDED.priced_at > %[1]s.priced_at THEN EXCLUDED.name ELSE %[1]s.name END,
+			priced_at = GREATEST(EXCLUDED.priced_at, %[1]s.priced_at)
+		RETURNING %[2]s`, ItemTable, itemColumns),
+		it.ID, it.CartID, it.ProductID, it.VariantID, it.SKU, it.Name, it.UnitPrice, it.Quantity, it.PricedAt, it.CreatedAt).StructScan(it)
+}
+
+func (r *repository) SetQuantity(ctx context.Context, c *Cart, itemID uuid.UUID, quantity int) error {
+	return r.change(ctx, c, func(tx *sqlx.Tx) error {
+		return itemChanged(tx.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET quantity=$3 WHERE cart_id=$1 AND id=$2`, ItemTable), c.ID, itemID, quantity))
+	})
+}
+
+func (r *repository) DeleteItem(ctx context.Context, c *Cart, itemID uuid.UUID) error {
+	return r.change(ctx, c, func(tx *sqlx.Tx) error {
+		return itemChanged(tx.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE cart_id=$1 AND id=$2`, ItemTable), c.ID, itemID))
+	})
+}
+
+func itemChanged(res sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrorItemNotFound
+	}
+	return nil
+}
+
+// Reprice stores fresh price snapshots of the items
+func (r *repository) Reprice(ctx context.Context, c *Cart, items []Item) error {
+	return r.change(ctx, c, func(tx *sqlx.Tx) error {
+		for _, it := range items {
+			if _, err := tx.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET unit_price=$3, sku=$4, name=$5, priced_at=$6 WHERE cart_id=$1 AND id=$2`, ItemTable),
+				c.ID, it.ID, it.UnitPrice, it.SKU, it.Name, it.PricedAt); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Merge moves the lines of an active guest cart into the active cart into,
+// adding up the quantities of lines both have, and marks the guest cart
+// merged
+func (r *repository) Merge(ctx context.Context, guest, into *Cart) error {
+	return r.change(ctx, into, func(tx *sqlx.Tx) error {
+		res, err := tx.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET status='%s', merged_into=$2, updated_at=$3
+			WHERE id=$1 AND status='%s' AND customer_id IS NULL AND expires_at > $3`, TableName, StatusMerged, StatusActive),
+			guest.ID, into.ID, into.UpdatedAt)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return ErrorNotActive
+		}
+		for i := range guest.Items {
+			it := guest.Items[i]
+			it.ID = uuid.New()
+			if err := addItem(ctx, tx, into.ID, &it); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Assign makes an active guest cart the customer's
+func (r *repository) Assign(ctx context.Context, c *Cart, customerID uuid.UUID) error {
+	res, err := r.db.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET customer_id=$2, expires_at=$3, updated_at=$4
+		WHERE id=$1 AND status='%s' AND customer_id IS NULL AND expires_at > $4`, TableName, StatusActive),
+		c.ID, customerID, c.ExpiresAt, c.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrorNotActive
+	}
+	return nil
+}
+
+// change runs fn on an active, unexpired cart, moving its expiry to
+// c.ExpiresAt. The cart row stays locked until fn is done, so changes to one
+// cart happen one at a time.
+func (r *repository) change(ctx context.Context, c *Cart, fn func(tx *sqlx.Tx) error) (err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	res, err := tx.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET expires_at=$2, updated_at=$3 WHERE id=$1 AND status='%s' AND expires_at > $3`, TableName, StatusActive),
+		c.ID, c.ExpiresAt, c.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		err = ErrorNotActive
+		return err
+	}
+	if err = fn(tx); err != nil {
+		return err
+	}
+	err = tx.Commit()
+	return err
+}
+
+// Claim marks an active, unexpired cart checked out, so only one checkout
+// can go on to place an order
+func (r *repository) Claim(ctx context.Context, id uuid.UUID, now time.Time) error {
+	res, err := r.db.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET status='%s', updated_at=$2 WHERE id=$1 AND status='%s' AND expires_at > $2`, TableName, StatusCheckedOut, StatusActive), id, now)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrorNotActive
+	}
+	return nil
+}
+
+// Release reactivates a claimed cart whose order could not be placed
+func (r *repository) Release(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET status='%s', updated_at=NOW() WHERE id=$1 AND status='%s' AND order_id IS NULL`, TableName, StatusActive, StatusCheckedOut), id)
+	return err
+}
+
+func (r *repository) SetOrder(ctx context.Context, id, orderID uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET order_id=$2, updated_at=NOW() WHERE id=$1`, TableName), id, orderID)
+	return err
+}
+
+// Expire marks the active carts past their expiry expired; their lines are
+// kept for abandoned cart reporting
+func (r *repository) Expire(ctx context.Context, now time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET status='%s', updated_at=$1 WHERE status='%s' AND expires_at <= $1`, TableName, StatusExpired, StatusActive), now)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,385 @@
+package Cart
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"savannah/src/Analytics"
+	"savannah/src/Catalog"
+	"savannah/src/Customer"
+	"savannah/src/Orders"
+)
+
+// Service keeps shoppers' carts: lines are added at the catalog price of
+// the moment, a guest cart is merged into the customer's when they log in,
+// and checkout turns the cart into an order once its prices are confirmed.
+type Service interface {
+	Create(ctx context.Context, req CreateCartRequest) (*Cart, bool, error)
+	Get(ctx context.Context, id uuid.UUID) (*Cart, error)
+	AddItem(ctx context.Context, id uuid.UUID, req AddItemRequest) (*Cart, error)
+	UpdateItem(ctx context.Context, id, itemID uuid.UUID, req UpdateItemRequest) (*Cart, error)
+	RemoveItem(ctx context.Context, id, itemID uuid.UUID) (*Cart, error)
+	Merge(ctx context.Context, id uuid.UUID, req MergeCartRequest) (*Cart, error)
+	Checkout(ctx context.Context, id uuid.UUID, req CheckoutRequest) (*Cart, *Orders.Order, []PriceChange, error)
+	ExpireIdle(ctx context.Context) error
+}
+
+// Products is what carts need from the catalog to snapshot prices and names
+type Products interface {
+	GetProduct(ctx context.Context, id uuid.UUID, pricing Catalog.Pricing) (*Catalog.Product, error)
+	GetVariant(ctx context.Context, productID, id uuid.UUID) (*Catalog.ProductVariant, error)
+}
+
+type Customers interface {
+	Get(ctx context.Context, id uuid.UUID) (*Customer.Customer, error)
+}
+
+// OrderPlacer places the order a checked out cart turns into, pricing it
+// from the catalog
+type OrderPlacer interface {
+	Checkout(ctx context.Context, req Orders.CreateOrderRequest) (*Orders.Order, error)
+}
+
+// EventTracker records storefront funnel analytics events
+type EventTracker interface {
+	Track(ctx context.Context, name string, customerID *uuid.UUID, properties map[string]interface{})
+}
+
+// Config is how long a cart lives after its last change
+type Config struct {
+	GuestTTL    time.Duration
+	CustomerTTL time.Duration
+}
+
+type service struct {
+	repo      Repository
+	products  Products
+	customers Customers
+	orders    OrderPlacer
+	tracker   EventTracker
+	cfg       Config
+	log       *zap.Logger
+}
+
+func NewService(r Repository, products Products, customers Customers, orders OrderPlacer, tracker EventTracker, cfg Config, log *zap.Logger) Service {
+	if cfg.GuestTTL <= 0 {
+		cfg.GuestTTL = 7 * 24 * time.Hour
+	}
+	if cfg.CustomerTTL <= 0 {
+		cfg.CustomerTTL = 30 * 24 * time.Hour
+	}
+	return &service{repo: r, products: products, customers: customers, orders: orders, tracker: tracker, cfg: cfg, log: log}
+}
+
+// touch moves the cart's expiry a TTL past now
+func (s *service) touch(c *Cart, now time.Time) {
+	ttl := s.cfg.GuestTTL
+	if c.CustomerID != nil {
+		ttl = s.cfg.CustomerTTL
+	}
+	c.UpdatedAt, c.ExpiresAt = now, now.Add(ttl)
+}
+
+// Create starts a cart. A customer who already has an active cart gets that
+// one back, reported by false.
+func (s *service) Create(ctx context.Context, req CreateCartRequest) (*Cart, bool, error) {
+	if req.CustomerID != nil {
+		if err := s.customer(ctx, *req.CustomerID); err != nil {
+			return nil, false, err
+		}
+	}
+	now := time.Now().UTC()
+	c := &Cart{ID: uuid.New(), CustomerID: req.CustomerID, Status: StatusActive, CreatedAt: now, Items: []Item{}}
+	s.touch(c, now)
+	created, err := s.repo.Create(ctx, c)
+	if err != nil {
+		return c, false, err
+	}
+	if created {
+		if s.tracker != nil {
+			s.tracker.Track(ctx, Analytics.EventCartCreated, c.CustomerID, map[string]interface{}{"cart_id": c.ID})
+		}
+		return c, true, nil
+	}
+	c, err = s.repo.ActiveFor(ctx, *req.CustomerID)
+	return c, false, err
+}
+
+func (s *service) customer(ctx context.Context, id uuid.UUID) error {
+	if _, err := s.customers.Get(ctx, id); err != nil {
+		if errors.Is(err, Customer.ErrorNotFound) {
+			return ErrorUnknownCustomer
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *service) Get(ctx context.Context, id uuid.UUID) (*Cart, error) {
+	c, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	c.Status = c.effectiveStatus(time.Now())
+	return c, nil
+}
+
+// active loads a cart that can still be changed
+func (s *service) active(ctx context.Context, id uuid.UUID, now time.Time) (*Cart, error) {
+	c, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	switch c.effectiveStatus(now) {
+	case StatusActive:
+		return c, nil
+	case StatusExpired:
+		return nil, ErrorExpired
+	default:
+		return nil, ErrorNotActive
+	}
+}
+
+// AddItem snapshots the product's current price and name into the cart
+func (s *service) AddItem(ctx context.Context, id uuid.UUID, req AddItemRequest) (*Cart, error) {
+	now := time.Now().UTC()
+	c, err := s.active(ctx, id, now)
+	if err != nil {
+		return nil, err
+	}
+	it := Item{ID: uuid.New(), ProductID: req.ProductID, VariantID: req.VariantID, Quantity: req.Quantity, CreatedAt: now}
+	if err := s.price(ctx, &it, now); err != nil {
+		return nil, err
+	}
+	if len(c.Items) >= MaxItems && !c.has(it) {
+		return nil, fmt.Errorf("%w: at most %d", ErrorTooManyItems, MaxItems)
+	}
+	s.touch(c, now)
+	if err := s.repo.AddItem(ctx, c, &it); err != nil {
+		return nil, err
+	}
+	return s.Get(ctx, id)
+}
+
+func (c *Cart) has(it Item) bool {
+	for _, o := range c.Items {
+		if o.same(it) {
+			return true
+		}
+	}
+	return false
+}
+
+// price snapshots the catalog price, SKU and name of the item's product or
+// variant; a variant without a price of its own sells at the product's
+func (s *service) price(ctx context.Context, it *Item, now time.Time) error {
+	p, err := s.products.GetProduct(ctx, it.ProductID, Catalog.Pricing{})
+	if err != nil {
+		if errors.Is(err, Catalog.ProductErrorNotFound) {
+			return ErrorUnknownProduct
+		}
+		return err
+	}
+	sku, name, price := p.SKU, p.Name, p.Price
+	if it.VariantID != nil {
+		v, err := s.products.GetVariant(ctx, it.ProductID, *it.VariantID)
+		if err != nil {
+			if errors.Is(err, Catalog.VariantErrorNotFound) {
+				return ErrorUnknownProduct
+			}
+			return err
+		}
+		sku, name = v.SKU, v.Name
+		if v.Price != nil {
+			price = *v.Price
+		}
+	}
+	it.SKU, it.Name, it.UnitPrice, it.PricedAt = &sku, &name, price, now
+	return nil
+}
+
+func (s *service) UpdateItem(ctx context.Context, id, itemID uuid.UUID, req UpdateItemRequest) (*Cart, error) {
+	now := time.Now().UTC()
+	c, err := s.active(ctx, id, now)
+	if err != nil {
+		return nil, err
+	}
+	s.touch(c, now)
+	if err := s.repo.SetQuantity(ctx, c, itemID, req.Quantity); err != nil {
+		return nil, err
+	}
+	return s.Get(ctx, id)
+}
+
+func (s *service) RemoveItem(ctx context.Context, id, itemID uuid.UUID) (*Cart, error) {
+	now := time.Now().UTC()
+	c, err := s.active(ctx, id, now)
+	if err != nil {
+		return nil, err
+	}
+	s.touch(c, now)
+	if err := s.repo.DeleteItem(ctx, c, itemID); err != nil {
+		return nil, err
+	}
+	return s.Get(ctx, id)
+}
+
+// Merge hands the guest cart id to the customer who just logged in. Its
+// lines join the customer's active cart, quantities adding up where both
+// have the product, and the guest cart is left MERGED pointing at it. A
+// customer without an active cart simply takes the guest cart over.
+func (s *service) Merge(ctx context.Context, id uuid.UUID, req MergeCartRequest) (*Cart, error) {
+	now := time.Now().UTC()
+	guest, err := s.active(ctx, id, now)
+	if err != nil {
+		return nil, err
+	}
+	if guest.CustomerID != nil {
+		if *guest.CustomerID == req.CustomerID {
+			return guest, nil
+		}
+		return nil, fmt.Errorf("%w: cart belongs to another customer", ErrorInvalidPayload)
+	}
+	if err := s.customer(ctx, req.CustomerID); err != nil {
+		return nil, err
+	}
+	into, err := s.repo.ActiveFor(ctx, req.CustomerID)
+	if err == nil && into.effectiveStatus(now) == StatusExpired {
+		// the expiry job hasn't got to it yet
+		into, err = nil, ErrorNotFound
+	}
+	if errors.Is(err, ErrorNotFound) {
+		guest.CustomerID = &req.CustomerID
+		s.touch(guest, now)
+		if err := s.repo.Assign(ctx, guest, req.CustomerID); err != nil {
+			return nil, err
+		}
+		return s.Get(ctx, guest.ID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	lines := len(into.Items)
+	for _, it := range guest.Items {
+		if !into.has(it) {
+			lines++
+		}
+	}
+	if lines > MaxItems {
+		return nil, fmt.Errorf("%w: the merged cart would have %d lines, at most %d", ErrorTooManyItems, lines, MaxItems)
+	}
+	s.touch(into, now)
+	if err := s.repo.Merge(ctx, guest, into); err != nil {
+		return nil, err
+	}
+	return s.Get(ctx, into.ID)
+}
+
+// Checkout places the cart's order. The lines are repriced from the catalog
+// first: when a price moved since it was snapshotted the new prices are
+// stored and returned with ErrorPricesChanged instead, so the shopper
+// confirms them by checking out again. The claim on the cart is atomic, so
+// it turns into one order; when the order cannot be placed the cart is
+// reactivated, and when only its payment failed the cart stays checked out
+// with the order, whose *Orders.PaymentError is returned alongside it.
+func (s *service) Checkout(ctx context.Context, id uuid.UUID, req CheckoutRequest) (*Cart, *Orders.Order, []PriceChange, error) {
+	now := time.Now().UTC()
+	c, err := s.active(ctx, id, now)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if len(c.Items) == 0 {
+		return nil, nil, nil, ErrorEmpty
+	}
+	changes, err := s.reprice(ctx, c, now)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if len(changes) > 0 {
+		c, err = s.Get(ctx, id)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return c, nil, changes, ErrorPricesChanged
+	}
+	if err := s.repo.Claim(ctx, c.ID, now); err != nil {
+		return nil, nil, nil, err
+	}
+	items := make([]Orders.CreateOrderItem, 0, len(c.Items))
+	for _, it := range c.Items {
+		productID := it.ProductID
+		items = append(items, Orders.CreateOrderItem{ProductID: &productID, VariantID: it.VariantID, Quantity: it.Quantity})
+	}
+	order, err := s.orders.Checkout(ctx, Orders.CreateOrderRequest{
+		CustomerID:    c.CustomerID,
+		Warehouse:     req.Warehouse,
+		Country:       req.Country,
+		Region:        req.Region,
+		PaymentMethod: req.PaymentMethod,
+		Priority:      req.Priority,
+		Channel:       req.Channel,
+		Items:         items,
+		PONumber:      req.PONumber,
+		PaymentTerms:  req.PaymentTerms,
+		HoldID:        req.HoldID,
+		CouponCode:    req.CouponCode,
+		GiftCardCode:  req.GiftCardCode,
+		Client:        req.Client,
+	})
+	var payment *Orders.PaymentError
+	if errors.As(err, &payment) {
+		order = payment.Order
+	} else if err != nil {
+		if rerr := s.repo.Release(ctx, c.ID); rerr != nil {
+			s.log.Error("reactivate cart", zap.String("cart_id", c.ID.String()), zap.Error(rerr))
+		}
+		return nil, nil, nil, err
+	}
+	if lerr := s.repo.SetOrder(ctx, c.ID, order.ID); lerr != nil {
+		s.log.Error("link cart to order", zap.String("cart_id", c.ID.String()), zap.String("order_id", order.ID.String()), zap.Error(lerr))
+	}
+	c.Status, c.OrderID, c.UpdatedAt = StatusCheckedOut, &order.ID, now
+	return c, order, nil, err
+}
+
+// reprice takes fresh snapshots of the cart's lines and stores those whose
+// price moved
+func (s *service) reprice(ctx context.Context, c *Cart, now time.Time) ([]PriceChange, error) {
+	var changes []PriceChange
+	var moved []Item
+	for _, it := range c.Items {
+		fresh := it
+		if err := s.price(ctx, &fresh, now); err != nil {
+			return nil, err
+		}
+		if !fresh.UnitPrice.Equal(it.UnitPrice) {
+			changes = append(changes, PriceChange{ItemID: it.ID, Previous: it.UnitPrice, Current: fresh.UnitPrice})
+			moved = append(moved, fresh)
+		}
+	}
+	if len(moved) == 0 {
+		return nil, nil
+	}
+	s.touch(c, now)
+	if err := s.repo.Reprice(ctx, c, moved); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// ExpireIdle expires the carts left untouched past their TTL; run
+// periodically by the scheduler
+func (s *service) ExpireIdle(ctx context.Context) error {
+	n, err := s.repo.Expire(ctx, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		s.log.Info("carts expired", zap.Int64("carts", n))
+	}
+	return nil
+}
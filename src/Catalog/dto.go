@@ -1,8 +1,12 @@
 package Catalog
 
 import (
+	"encoding/json"
+	"time"
+
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
+	"savannah/src/MergePatch"
 )
 
 type CreateProductRequest struct {
@@ -11,14 +15,70 @@ type CreateProductRequest struct {
 	CategoryID  *uuid.UUID      `json:"category_id" validate:"required"`
 	Price       decimal.Decimal `json:"price" validate:"required"`
 	Currency    string          `json:"currency"`
+	ProductType string          `json:"product_type,omitempty" validate:"omitempty,oneof=PHYSICAL DIGITAL SERVICE"`
+	HSCode          *string     `json:"hs_code,omitempty"`
+	CountryOfOrigin *string     `json:"country_of_origin,omitempty"`
+	Barcode         *string     `json:"barcode,omitempty"`
+	WeightGrams     *int        `json:"weight_grams,omitempty"`
+	LengthMM        *int        `json:"length_mm,omitempty"`
+	WidthMM         *int        `json:"width_mm,omitempty"`
+	HeightMM        *int        `json:"height_mm,omitempty"`
+	ShippingClass   *string     `json:"shipping_class,omitempty"`
+	Preorder        bool        `json:"preorder,omitempty"`
+	ReleaseDate     *time.Time  `json:"release_date,omitempty"`
+	// defaults to true; false creates the product embargoed
+	Published *bool `json:"published,omitempty"`
 }
+// CreateCategoryRequest creates a category; without a slug one is made from
+// the name, suffixed with -2, -3, ... when that is taken.
 type CreateCategoryRequest struct{
 	Name        string     `json:"name" validate:"required,min=2,max=100"`
-	Slug        string     `json:"slug" validate:"required,min=2,max=100"`
+	Slug        string     `json:"slug,omitempty"`
 	Description *string    `json:"description,omitempty"`
 	ParentID    *uuid.UUID `json:"parent_id,omitempty"`
 }
 
+// ReorderCategoriesRequest lists every subcategory of one parent (or every
+// top-level category) in the new display order
+type ReorderCategoriesRequest struct {
+	CategoryIDs []uuid.UUID `json:"category_ids"`
+}
+
+// PatchProductRequest is a JSON merge patch of a product: members left out
+// keep their value and null clears the optional ones (description, the
+// customs and shipping data, barcode and release_date).
+type PatchProductRequest struct {
+	Name        MergePatch.Field[string]          `json:"name"`
+	Description MergePatch.Field[string]          `json:"description"`
+	CategoryID  MergePatch.Field[uuid.UUID]       `json:"category_id"`
+	Price       MergePatch.Field[decimal.Decimal] `json:"price"`
+	Currency    MergePatch.Field[string]          `json:"currency"`
+	ProductType MergePatch.Field[string]          `json:"product_type"`
+	HSCode          MergePatch.Field[string]      `json:"hs_code"`
+	CountryOfOrigin MergePatch.Field[string]      `json:"country_of_origin"`
+	Barcode         MergePatch.Field[string]      `json:"barcode"`
+	WeightGrams     MergePatch.Field[int]         `json:"weight_grams"`
+	LengthMM        MergePatch.Field[int]         `json:"length_mm"`
+	WidthMM         MergePatch.Field[int]         `json:"width_mm"`
+	HeightMM        MergePatch.Field[int]         `json:"height_mm"`
+	ShippingClass   MergePatch.Field[string]      `json:"shipping_class"`
+	Preorder        MergePatch.Field[bool]        `json:"preorder"`
+	ReleaseDate     MergePatch.Field[time.Time]   `json:"release_date"`
+	Published       MergePatch.Field[bool]        `json:"published"`
+	// optional; when sent it must match the stored version
+	Version MergePatch.Field[int] `json:"version"`
+}
+
+// PatchCategoryRequest is a JSON merge patch of a category; null clears the
+// description or moves the category to the top level.
+type PatchCategoryRequest struct {
+	Name        MergePatch.Field[string]    `json:"name"`
+	Slug        MergePatch.Field[string]    `json:"slug"`
+	Description MergePatch.Field[string]    `json:"description"`
+	ParentID    MergePatch.Field[uuid.UUID] `json:"parent_id"`
+	Version     MergePatch.Field[int]       `json:"version"`
+}
+
 type ProductResponse struct{
 	ID uuid.UUID `json:"id"`
 	Name        string          `json:"name"`
@@ -26,6 +86,7 @@ type ProductResponse struct{
 	CategoryID  *uuid.UUID      `json:"category_id"`
 	Price       decimal.Decimal `json:"price"`
 	Currency    string          `json:"currency"`
+	ProductType string          `json:"product_type"`
 	CreatedAt string    `json:"created_at"`
 	UpdatedAt string    `json:"updated_at"`
 }
@@ -34,4 +95,172 @@ type ListProductsQuery struct {
 	Limit  int    `schema:"limit"`
 	Offset int    `schema:"offset"`
 	Search string `schema:"search"`	
+	CategoryID *uuid.UUID      `schema:"category_id"`
+	// with CategoryID, also list products of every category below it
+	IncludeSubcategories bool `schema:"include_subcategories"`
+	// only these products; set by the batch get
+	IDs []uuid.UUID `schema:"-"`
+	Attributes []AttributeFilter `schema:"-"`
+	// hides unpublished products and those disabled for the channel; empty
+	// lists every product
+	Channel string `schema:"channel"`
+	// admin only: also list soft deleted products
+	IncludeDeleted bool `schema:"include_deleted"`
+	// price products in Currency, for CustomerGroup; products without a
+	// price in Currency are left out
+	Currency      string `schema:"currency"`
+	CustomerGroup string `schema:"customer_group"`
+	// SortNewest (default) or SortPopularity
+	Sort string `schema:"sort"`
+}
+
+// product list orders
+const (
+	SortNewest     = "newest"
+	SortPopularity = "popularity"
+)
+
+// RecordViewsRequest reports product page views counted elsewhere, such as
+// by a storefront serving cached pages
+type RecordViewsRequest struct {
+	Views map[uuid.UUID]int64 `json:"views"`
+}
+
+// Pricing picks the price a product is shown at: the entry of the customer
+// group's list in Currency, else of the currency's default list, else the
+// product's own price when it is in Currency. Without a Currency each product
+// is priced in its own currency.
+type Pricing struct {
+	Currency      string
+	CustomerGroup string
+}
+
+// Pricing is how q prices the products it lists
+func (q ListProductsQuery) Pricing() Pricing {
+	return Pricing{Currency: q.Currency, CustomerGroup: q.CustomerGroup}
+}
+
+// BatchGetProductsRequest names up to 100 products to fetch at once
+type BatchGetProductsRequest struct {
+	IDs []uuid.UUID `json:"ids"`
+}
+
+// BatchGetProductsResponse lists the products found in the requested order;
+// NotFound has the ids that do not exist or are hidden by the filters.
+type BatchGetProductsResponse struct {
+	Products []Product   `json:"products"`
+	NotFound []uuid.UUID `json:"not_found"`
+}
+
+// AttributeFilter keeps products whose attribute Key equals one of Values
+// and, for numbers, lies within Min and Max (both inclusive).
+type AttributeFilter struct {
+	Key    string
+	Values []string
+	Min    *decimal.Decimal
+	Max    *decimal.Decimal
+}
+
+// CreatePriceListRequest leaves CustomerGroup out for the currency's default list
+type CreatePriceListRequest struct {
+	Name          string  `json:"name"`
+	Currency      string  `json:"currency"`
+	CustomerGroup *string `json:"customer_group,omitempty"`
+}
+
+// PriceUpdate sets the price of one product in a bulk price change
+type PriceUpdate struct {
+	ProductID uuid.UUID       `json:"product_id" validate:"required"`
+	Price     decimal.Decimal `json:"price"`
+}
+
+// CreateCampaignRequest needs at least one product or category
+type CreateCampaignRequest struct {
+	Name            string          `json:"name" validate:"required,min=2,max=100"`
+	DiscountPercent decimal.Decimal `json:"discount_percent"`
+	StartsAt        time.Time       `json:"starts_at" validate:"required"`
+	EndsAt          time.Time       `json:"ends_at" validate:"required"`
+	ProductIDs      []uuid.UUID     `json:"product_ids,omitempty"`
+	CategoryIDs     []uuid.UUID     `json:"category_ids,omitempty"`
+}
+
+// CreatePublicationRequest schedules publishing (publish or unpublish) of a
+// product or campaign. The moment is either At with its own offset, or
+// LocalTime (2006-01-02T15:04) on the wall clock of Timezone, an IANA zone
+// such as Africa/Nairobi; Timezone defaults to UTC.
+type CreatePublicationRequest struct {
+	TargetType string     `json:"target_type"`
+	TargetID   uuid.UUID  `json:"target_id"`
+	Action     string     `json:"action"`
+	At         *time.Time `json:"at,omitempty"`
+	LocalTime  string     `json:"local_time,omitempty"`
+	Timezone   string     `json:"timezone,omitempty"`
+}
+
+// ImageUploadRequest asks for a signed URL to upload an image directly to storage
+type ImageUploadRequest struct {
+	ContentType string `json:"content_type"`
+}
+
+// ImageUpload is where and until when the client may PUT the image; the
+// upload is confirmed with the complete endpoint.
+type ImageUpload struct {
+	Image     *ProductImage `json:"image"`
+	UploadURL string        `json:"upload_url"`
+	Method    string        `json:"method"`
+	ExpiresAt time.Time     `json:"expires_at"`
+}
+
+// ReorderImagesRequest lists every image of a product in the new gallery order
+type ReorderImagesRequest struct {
+	ImageIDs []uuid.UUID `json:"image_ids"`
+}
+
+// CreateVariantRequest adds a variant; without a price it sells at the product price
+type CreateVariantRequest struct {
+	SKU     string            `json:"sku"`
+	Name    string            `json:"name"`
+	Options map[string]string `json:"options"`
+	Price   *decimal.Decimal  `json:"price,omitempty"`
+}
+
+// UpdateVariantRequest replaces a variant; Version must match the stored one
+type UpdateVariantRequest struct {
+	CreateVariantRequest
+	Version int `json:"version"`
+}
+
+// CreateAttributeRequest defines an attribute of a category's products.
+// Type is string, number, bool or enum; enum needs EnumValues.
+type CreateAttributeRequest struct {
+	Key        string   `json:"key"`
+	Label      string   `json:"label"`
+	Type       string   `json:"type"`
+	EnumValues []string `json:"enum_values,omitempty"`
+	Required   bool     `json:"required,omitempty"`
+}
+
+// TranslationRequest sets the text of a product or category in the locale
+// of the path. A category translation without a slug gets one made from the
+// name; a product translation only has the slug given.
+type TranslationRequest struct {
+	Name        string  `json:"name"`
+	Description *string `json:"description,omitempty"`
+	Slug        *string `json:"slug,omitempty"`
+}
+
+// SetCostRequest sets the unit cost of a product; null clears it
+type SetCostRequest struct {
+	Cost *decimal.Decimal `json:"cost"`
+}
+
+// SetChannelsRequest enables (true) or disables (false) a product per sales
+// channel; channels left out keep their setting
+type SetChannelsRequest struct {
+	Channels map[string]bool `json:"channels"`
+}
+
+// SetAttributesRequest replaces all attribute values of a product
+type SetAttributesRequest struct {
+	Attributes map[string]json.RawMessage `json:"attributes"`
 }
@@ -1,15 +1,134 @@
 package Catalog
 
-import "errors"
+import (
+	"errors"
+
+	"savannah/src/ErrorCodes"
+)
 
 // Product related errors
 var (
 	ProductErrorNotFound     = errors.New("product not found")
 	ProductErrorInvalidPayload = errors.New("invalid product payload")
+	ProductErrorNotDigital     = errors.New("files can only be attached to digital products")
+	ProductErrorVersionConflict = errors.New("product was modified concurrently")
+	ProductErrorDuplicateBarcode = errors.New("barcode is already used by another product")
 )
 
 // Category related errors
 var (
 	CategoryErrorNotFound     = errors.New("category not found")
 	CategoryErrorInvalidPayload = errors.New("invalid category payload")
+	CategoryErrorNotEmpty       = errors.New("category still has products or subcategories")
+	CategoryErrorVersionConflict = errors.New("category was modified concurrently")
+	CategoryErrorDuplicateSlug   = errors.New("slug is already used by another category")
+	CategoryErrorOrderMismatch   = errors.New("category_ids must list every subcategory of one parent exactly once")
+)
+
+// Product image related errors
+var (
+	ImageErrorNotFound    = errors.New("image not found")
+	ImageErrorUnsupported = errors.New("unsupported image type")
+	ImageErrorOrderMismatch = errors.New("image_ids must list every image of the product exactly once")
+	ImageErrorNotUploaded   = errors.New("image has not been uploaded")
+	ImageErrorSignedUpload  = errors.New("storage does not support signed uploads")
+)
+
+// Campaign related errors
+var (
+	CampaignErrorNotFound       = errors.New("campaign not found")
+	CampaignErrorInvalidPayload = errors.New("invalid campaign payload")
+	CampaignErrorEnded          = errors.New("campaign has already ended")
+)
+
+// Publication schedule related errors
+var (
+	ScheduleErrorNotFound       = errors.New("publication schedule not found")
+	ScheduleErrorInvalidPayload = errors.New("invalid publication schedule payload")
+	ScheduleErrorNotPending     = errors.New("publication schedule has already run or been cancelled")
+)
+
+// Product variant related errors
+var (
+	VariantErrorNotFound        = errors.New("variant not found")
+	VariantErrorInvalidPayload  = errors.New("invalid variant payload")
+	VariantErrorDuplicateSKU    = errors.New("sku already in use")
+	VariantErrorVersionConflict = errors.New("variant was modified concurrently")
+)
+
+// Attribute related errors
+var (
+	AttributeErrorNotFound       = errors.New("attribute not defined")
+	AttributeErrorDuplicate      = errors.New("attribute already defined for this category")
+	AttributeErrorInvalidPayload = errors.New("invalid attribute payload")
+	AttributeErrorInvalidValue   = errors.New("invalid attribute value")
+)
+
+// Translation related errors
+var (
+	TranslationErrorNotFound       = errors.New("translation not found")
+	TranslationErrorInvalidPayload = errors.New("invalid translation payload")
+	TranslationErrorDuplicateSlug  = errors.New("slug is already used by another translation in this locale")
+)
+
+// Price list related errors
+var (
+	PriceListErrorNotFound       = errors.New("price list not found")
+	PriceListErrorInvalidPayload = errors.New("invalid price list payload")
+	PriceListErrorDuplicate      = errors.New("a price list for this currency and customer group already exists")
+	PriceListErrorNoPrice        = errors.New("product has no price in this currency")
 )
+
+// Sales channel related errors
+var (
+	ChannelErrorUnknown = errors.New("unknown sales channel")
+)
+
+// Conditional request errors
+var (
+	ETagErrorPrecondition = errors.New("resource has changed since the If-Match entity tag was read")
+)
+
+func init() {
+	ErrorCodes.Register("CATALOG",
+		ErrorCodes.Def{N: 1, Slug: "PRODUCT_NOT_FOUND", Err: ProductErrorNotFound},
+		ErrorCodes.Def{N: 2, Slug: "INVALID_PRODUCT_PAYLOAD", Err: ProductErrorInvalidPayload},
+		ErrorCodes.Def{N: 3, Slug: "PRODUCT_NOT_DIGITAL", Err: ProductErrorNotDigital},
+		ErrorCodes.Def{N: 4, Slug: "CATEGORY_NOT_FOUND", Err: CategoryErrorNotFound},
+		ErrorCodes.Def{N: 5, Slug: "INVALID_CATEGORY_PAYLOAD", Err: CategoryErrorInvalidPayload},
+		ErrorCodes.Def{N: 6, Slug: "CATEGORY_NOT_EMPTY", Err: CategoryErrorNotEmpty},
+		ErrorCodes.Def{N: 7, Slug: "IMAGE_NOT_FOUND", Err: ImageErrorNotFound},
+		ErrorCodes.Def{N: 8, Slug: "UNSUPPORTED_IMAGE_TYPE", Err: ImageErrorUnsupported},
+		ErrorCodes.Def{N: 9, Slug: "IMAGE_ORDER_MISMATCH", Err: ImageErrorOrderMismatch},
+		ErrorCodes.Def{N: 10, Slug: "IMAGE_NOT_UPLOADED", Err: ImageErrorNotUploaded},
+		ErrorCodes.Def{N: 11, Slug: "SIGNED_UPLOAD_UNSUPPORTED", Err: ImageErrorSignedUpload},
+		ErrorCodes.Def{N: 12, Slug: "CAMPAIGN_NOT_FOUND", Err: CampaignErrorNotFound},
+		ErrorCodes.Def{N: 13, Slug: "INVALID_CAMPAIGN_PAYLOAD", Err: CampaignErrorInvalidPayload},
+		ErrorCodes.Def{N: 14, Slug: "CAMPAIGN_ENDED", Err: CampaignErrorEnded},
+		ErrorCodes.Def{N: 15, Slug: "VARIANT_NOT_FOUND", Err: VariantErrorNotFound},
+		ErrorCodes.Def{N: 16, Slug: "INVALID_VARIANT_PAYLOAD", Err: VariantErrorInvalidPayload},
+		ErrorCodes.Def{N: 17, Slug: "DUPLICATE_SKU", Err: VariantErrorDuplicateSKU},
+		ErrorCodes.Def{N: 18, Slug: "VARIANT_VERSION_CONFLICT", Err: VariantErrorVersionConflict},
+		ErrorCodes.Def{N: 19, Slug: "ATTRIBUTE_NOT_FOUND", Err: AttributeErrorNotFound},
+		ErrorCodes.Def{N: 20, Slug: "DUPLICATE_ATTRIBUTE", Err: AttributeErrorDuplicate},
+		ErrorCodes.Def{N: 21, Slug: "INVALID_ATTRIBUTE_PAYLOAD", Err: AttributeErrorInvalidPayload},
+		ErrorCodes.Def{N: 22, Slug: "INVALID_ATTRIBUTE_VALUE", Err: AttributeErrorInvalidValue},
+		ErrorCodes.Def{N: 23, Slug: "UNKNOWN_CHANNEL", Err: ChannelErrorUnknown},
+		ErrorCodes.Def{N: 24, Slug: "PRODUCT_VERSION_CONFLICT", Err: ProductErrorVersionConflict},
+		ErrorCodes.Def{N: 25, Slug: "CATEGORY_VERSION_CONFLICT", Err: CategoryErrorVersionConflict},
+		ErrorCodes.Def{N: 26, Slug: "PRICE_LIST_NOT_FOUND", Err: PriceListErrorNotFound},
+		ErrorCodes.Def{N: 27, Slug: "INVALID_PRICE_LIST_PAYLOAD", Err: PriceListErrorInvalidPayload},
+		ErrorCodes.Def{N: 28, Slug: "DUPLICATE_PRICE_LIST", Err: PriceListErrorDuplicate},
+		ErrorCodes.Def{N: 29, Slug: "NO_PRICE_IN_CURRENCY", Err: PriceListErrorNoPrice},
+		ErrorCodes.Def{N: 30, Slug: "PRECONDITION_FAILED", Err: ETagErrorPrecondition},
+		ErrorCodes.Def{N: 31, Slug: "DUPLICATE_SLUG", Err: CategoryErrorDuplicateSlug},
+		ErrorCodes.Def{N: 32, Slug: "SCHEDULE_NOT_FOUND", Err: ScheduleErrorNotFound},
+		ErrorCodes.Def{N: 33, Slug: "INVALID_SCHEDULE_PAYLOAD", Err: ScheduleErrorInvalidPayload},
+		ErrorCodes.Def{N: 34, Slug: "SCHEDULE_NOT_PENDING", Err: ScheduleErrorNotPending},
+		ErrorCodes.Def{N: 35, Slug: "DUPLICATE_BARCODE", Err: ProductErrorDuplicateBarcode},
+		ErrorCodes.Def{N: 36, Slug: "CATEGORY_ORDER_MISMATCH", Err: CategoryErrorOrderMismatch},
+		ErrorCodes.Def{N: 37, Slug: "TRANSLATION_NOT_FOUND", Err: TranslationErrorNotFound},
+		ErrorCodes.Def{N: 38, Slug: "INVALID_TRANSLATION_PAYLOAD", Err: TranslationErrorInvalidPayload},
+		ErrorCodes.Def{N: 39, Slug: "DUPLICATE_TRANSLATION_SLUG", Err: TranslationErrorDuplicateSlug},
+	)
+}
@@ -0,0 +1,110 @@
+package Catalog
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Entity tags of single resources are "<version>-<digest>". The version lets
+// a client send the tag it read back in If-Match instead of a version
+// number; the digest of the body covers what changes without a new version,
+// such as sale and price list prices. Lists are tagged by digest alone.
+
+func versionETag(version int, body []byte) string {
+	return `"` + strconv.Itoa(version) + "-" + digest(body) + `"`
+}
+
+func listETag(body []byte) string {
+	return `"` + digest(body) + `"`
+}
+
+func digest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:8])
+}
+
+// etagVersion reads the version out of a tag made by versionETag. Weak tags
+// never match If-Match, so they are rejected like foreign tags.
+func etagVersion(tag string) (int, bool) {
+	if !strings.HasPrefix(tag, `"`) || !strings.HasSuffix(tag, `"`) || len(tag) < 2 {
+		return 0, false
+	}
+	v, _, found := strings.Cut(tag[1:len(tag)-1], "-")
+	if !found {
+		return 0, false
+	}
+	n, err := strconv.Atoi(v)
+	return n, err == nil
+}
+
+// noneMatch reports whether If-None-Match lists etag, comparing weakly as
+// RFC 9110 asks for GET
+func noneMatch(r *http.Request, etag string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimPrefix(strings.TrimSpace(t), "W/")
+		if t == "*" || t == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// ifMatch returns the version an If-Match precondition requires, nil when
+// there is none or it is "*" (the resource only has to exist). It answers
+// the request itself and returns false when the header cannot be honoured.
+func (h *Handler) ifMatch(w http.ResponseWriter, r *http.Request) (*int, bool) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return nil, true
+	}
+	if strings.Contains(header, ",") {
+		h.writeError(w, http.StatusBadRequest, "If-Match must be * or a single entity tag")
+		return nil, false
+	}
+	v, ok := etagVersion(header)
+	if !ok {
+		h.writeError(w, http.StatusPreconditionFailed, ETagErrorPrecondition.Error())
+		return nil, false
+	}
+	return &v, true
+}
+
+// writeVersioned writes a single resource with its entity tag, or 304 when a
+// GET already has it
+func (h *Handler) writeVersioned(w http.ResponseWriter, r *http.Request, version int, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		h.writeJSON(w, http.StatusOK, v)
+		return
+	}
+	h.writeTagged(w, r, versionETag(version, body), body)
+}
+
+// writeList writes a list response tagged by its content
+func (h *Handler) writeList(w http.ResponseWriter, r *http.Request, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		h.writeJSON(w, http.StatusOK, v)
+		return
+	}
+	h.writeTagged(w, r, listETag(body), body)
+}
+
+func (h *Handler) writeTagged(w http.ResponseWriter, r *http.Request, etag string, body []byte) {
+	w.Header().Set("ETag", etag)
+	if (r.Method == http.MethodGet || r.Method == http.MethodHead) && noneMatch(r, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(append(body, '\n'))
+}
@@ -0,0 +1,71 @@
+package Catalog
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// maxFacetValues caps the values listed per attribute, most common first
+	maxFacetValues = 50
+	maxPriceEdges  = 20
+)
+
+// defaultPriceEdges bound the price buckets when a request names none
+var defaultPriceEdges = []decimal.Decimal{
+	decimal.NewFromInt(10), decimal.NewFromInt(25), decimal.NewFromInt(50), decimal.NewFromInt(100),
+	decimal.NewFromInt(250), decimal.NewFromInt(500), decimal.NewFromInt(1000),
+}
+
+// ProductFacets counts the products matching q per category, currency,
+// price bucket and attribute value, so a storefront draws its filter sidebar
+// from one request. Every filter of q applies to every count. Prices are
+// bucketed by priceEdges, ascending and positive, in each product's own
+// currency; category labels are translated into the first of locales that
+// has them.
+func (s *service) ProductFacets(ctx context.Context, q ListProductsQuery, priceEdges []decimal.Decimal, locales []string) (*ProductFacets, error) {
+	if len(priceEdges) == 0 {
+		priceEdges = defaultPriceEdges
+	}
+	if len(priceEdges) > maxPriceEdges {
+		return nil, fmt.Errorf("%w: at most %d price bucket edges", ProductErrorInvalidPayload, maxPriceEdges)
+	}
+	for i, e := range priceEdges {
+		if !e.IsPositive() || (i > 0 && !e.GreaterThan(priceEdges[i-1])) {
+			return nil, fmt.Errorf("%w: price bucket edges must be positive and ascending", ProductErrorInvalidPayload)
+		}
+	}
+	q.Attributes = canonicalAttributeValues(q.Attributes)
+	f, err := s.repository.ProductFacets(ctx, q, priceEdges)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range f.Attributes {
+		if len(values) > maxFacetValues {
+			f.Attributes[key] = values[:maxFacetValues]
+		}
+	}
+	if len(locales) == 0 || len(f.Categories) == 0 {
+		return f, nil
+	}
+	ids := make([]uuid.UUID, 0, len(f.Categories))
+	for _, c := range f.Categories {
+		if id, err := uuid.Parse(c.Value); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	best, err := s.bestTranslations(ctx, CategoryTranslationName, ids, locales)
+	if err != nil {
+		return nil, err
+	}
+	for i, c := range f.Categories {
+		id, _ := uuid.Parse(c.Value)
+		if t, ok := best[id]; ok {
+			f.Categories[i].Label = t.Name
+		}
+	}
+	return f, nil
+}
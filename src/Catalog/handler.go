@@ -1,36 +1,58 @@
 package Catalog
 
 import (
+	"bytes"
+	"context"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
 	"go.uber.org/zap"
+	"savannah/src/Analytics"
+	"savannah/src/ErrorCodes"
+	"savannah/src/MergePatch"
+	"savannah/src/Pagination"
+	"savannah/src/Storage"
 )
 
+// EventTracker records storefront analytics events
+type EventTracker interface {
+	Track(ctx context.Context, name string, customerID *uuid.UUID, properties map[string]interface{})
+}
+
 type Handler struct {
 	service Service
+	tracker EventTracker
 	log *zap.Logger
 }
 
-func NewHandler(s Service, log *zap.Logger) *Handler {
-	return &Handler{service: s, log: log}
+func NewHandler(s Service, tracker EventTracker, log *zap.Logger) *Handler {
+	return &Handler{service: s, tracker: tracker, log: log}
 }
 
 // ---------------- CATEGORY -----------------
 
 // CreateCategory godoc
 // @Summary      Create a new category
-// @Description  Creates a category with name, slug, description
+// @Description  Creates a category with name, slug, description. The slug is lowercase letters and digits in hyphenated words (e.g. "home-garden"); when it is left out one is made from the name, with accents transliterated and -2, -3, ... appended when it is taken.
 // @Tags         categories
 // @Accept       json
 // @Produce      json
 // @Param        category  body      CreateCategoryRequest  true  "Category payload"
 // @Success      201       {object}  Category
 // @Failure      400       {object}  map[string]interface{}
+// @Failure      409       {object}  map[string]interface{}
 // @Failure      500       {object}  map[string]interface{}
 // @Router       /categories [post]
 
@@ -42,8 +64,15 @@ func (h *Handler) CreateCategory(w http.ResponseWriter, r *http.Request) {
 	}
 	c, err := h.service.CreateCategory(r.Context(), dto)
 	if err != nil {
-		h.log.Error("create category", zap.Error(err))
-		h.writeError(w, http.StatusInternalServerError, "failed to create category")
+		switch {
+		case errors.Is(err, CategoryErrorInvalidPayload):
+			h.writeError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, CategoryErrorDuplicateSlug):
+			h.writeError(w, http.StatusConflict, err.Error())
+		default:
+			h.log.Error("create category", zap.Error(err))
+			h.writeError(w, http.StatusInternalServerError, "failed to create category")
+		}
 		return
 	}
 	h.writeJSON(w, http.StatusCreated, c)
@@ -54,7 +83,11 @@ func (h *Handler) CreateCategory(w http.ResponseWriter, r *http.Request) {
 // @Tags         products
 // @Produce      json
 // @Param        id   path      string  true  "ID"
+// @Param        locale           query   string  false  "Locale to read in; overrides Accept-Language"
+// @Param        Accept-Language  header  string  false  "Preferred locales; untranslated text is the fallback"
+// @Param        If-None-Match  header  string  false  "ETag of a copy the client holds"
 // @Success      200  {object}  Category
+// @Success      304
 // @Failure      400  {object}  map[string]interface{}
 // @Failure      404  {object}  map[string]interface{}
 // @Router       /products/{id} [get]
@@ -66,6 +99,10 @@ func (h *Handler) GetCategory(w http.ResponseWriter, r *http.Request) {
 		h.writeError(w, http.StatusBadRequest, "invalid id")
 		return
 	}
+	locales, ok := h.locales(w, r)
+	if !ok {
+		return
+	}
 	c, err := h.service.GetCategory(r.Context(), id)
 	if err != nil {
 		if err == CategoryErrorNotFound {
@@ -76,6 +113,171 @@ func (h *Handler) GetCategory(w http.ResponseWriter, r *http.Request) {
 		h.writeError(w, http.StatusInternalServerError, "failed to get category")
 		return
 	}
+	localized := []Category{*c}
+	if err := h.service.LocalizeCategories(r.Context(), localized, locales); err != nil {
+		h.log.Error("localize category", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to get category")
+		return
+	}
+	h.writeVersioned(w, r, c.Version, localized[0])
+}
+
+// ListCategories godoc
+// @Summary      List categories
+// @Description  Live categories in display order (position, then name), e.g. for menus, each with product_count (published products filed directly under it) and product_count_recursive (including its subcategories)
+// @Tags         categories
+// @Produce      json
+// @Param        parent_id  query     string  false  "Only the children of this category"
+// @Param        top_level  query     bool    false  "Only top-level categories"
+// @Param        exclude_empty  query  bool   false  "Leave out categories with no published product in them or their subcategories"
+// @Param        locale           query   string  false  "Locale to read in; overrides Accept-Language"
+// @Param        Accept-Language  header  string  false  "Preferred locales; untranslated text is the fallback"
+// @Param        If-None-Match  header  string  false  "ETag of a copy the client holds"
+// @Success      200  {array}   Category
+// @Success      304
+// @Failure      400  {object}  map[string]interface{}
+// @Router       /categories [get]
+func (h *Handler) ListCategories(w http.ResponseWriter, r *http.Request) {
+	var parentID *uuid.UUID
+	if raw := r.URL.Query().Get("parent_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			h.writeError(w, http.StatusBadRequest, "invalid parent_id")
+			return
+		}
+		parentID = &id
+	}
+	locales, ok := h.locales(w, r)
+	if !ok {
+		return
+	}
+	categories, err := h.service.ListCategories(r.Context(), parentID, r.URL.Query().Get("top_level") == "true", r.URL.Query().Get("exclude_empty") == "true")
+	if err == nil {
+		err = h.service.LocalizeCategories(r.Context(), categories, locales)
+	}
+	if err != nil {
+		h.log.Error("list categories", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to list categories")
+		return
+	}
+	h.writeList(w, r, categories)
+}
+
+// ReorderCategories godoc
+// @Summary      Reorder categories
+// @Description  Sets the display order of one parent's subcategories, or of the top-level categories; every one of them must be listed exactly once
+// @Tags         categories
+// @Accept       json
+// @Produce      json
+// @Param        order  body      ReorderCategoriesRequest  true  "Every sibling id in the new order"
+// @Success      200    {array}   Category
+// @Failure      400    {object}  map[string]interface{}
+// @Failure      404    {object}  map[string]interface{}
+// @Router       /categories/reorder [patch]
+func (h *Handler) ReorderCategories(w http.ResponseWriter, r *http.Request) {
+	var dto ReorderCategoriesRequest
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	categories, err := h.service.ReorderCategories(r.Context(), dto.CategoryIDs)
+	switch {
+	case err == nil:
+		h.writeJSON(w, http.StatusOK, categories)
+	case errors.Is(err, CategoryErrorNotFound):
+		h.writeError(w, http.StatusNotFound, "category not found")
+	case errors.Is(err, CategoryErrorOrderMismatch):
+		h.writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		h.log.Error("reorder categories", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to reorder categories")
+	}
+}
+
+// CategoryPath godoc
+// @Summary      Category breadcrumb
+// @Description  The category's ancestors from the root down, ending with the category itself
+// @Tags         categories
+// @Produce      json
+// @Param        id   path      string  true  "Category ID"
+// @Param        locale           query   string  false  "Locale to read in; overrides Accept-Language"
+// @Param        Accept-Language  header  string  false  "Preferred locales; untranslated text is the fallback"
+// @Param        If-None-Match  header  string  false  "ETag of a copy the client holds"
+// @Success      200  {array}   Category
+// @Success      304
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /categories/{id}/path [get]
+func (h *Handler) CategoryPath(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	locales, ok := h.locales(w, r)
+	if !ok {
+		return
+	}
+	path, err := h.service.CategoryPath(r.Context(), id)
+	if err == nil {
+		err = h.service.LocalizeCategories(r.Context(), path, locales)
+	}
+	if err != nil {
+		if errors.Is(err, CategoryErrorNotFound) {
+			h.writeError(w, http.StatusNotFound, "category not found")
+			return
+		}
+		h.log.Error("get category path", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to get category path")
+		return
+	}
+	h.writeList(w, r, path)
+}
+
+// DeleteCategory godoc
+// @Summary      Delete category
+// @Description  Soft deletes the category; it must have no live products or subcategories
+// @Tags         categories
+// @Param        id   path  string  true  "Category ID"
+// @Success      204
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      409  {object}  map[string]interface{}
+// @Router       /categories/{id} [delete]
+func (h *Handler) DeleteCategory(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	if err := h.service.DeleteCategory(r.Context(), id); err != nil {
+		h.deleteError(w, "delete category", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RestoreCategory godoc
+// @Summary      Restore category
+// @Description  Undoes a soft delete
+// @Tags         categories
+// @Produce      json
+// @Param        id   path      string  true  "Category ID"
+// @Success      200  {object}  Category
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /categories/{id}/restore [post]
+func (h *Handler) RestoreCategory(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	c, err := h.service.RestoreCategory(r.Context(), id)
+	if err != nil {
+		h.deleteError(w, "restore category", err)
+		return
+	}
 	h.writeJSON(w, http.StatusOK, c)
 }
 
@@ -89,6 +291,14 @@ func (h *Handler) CreateProduct(w http.ResponseWriter, r *http.Request) {
 	}
 	p, err := h.service.CreateProduct(r.Context(), dto)
 	if err != nil {
+		if errors.Is(err, ProductErrorInvalidPayload) {
+			h.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, ProductErrorDuplicateBarcode) {
+			h.writeError(w, http.StatusConflict, err.Error())
+			return
+		}
 		h.log.Error("create product", zap.Error(err))
 		h.writeError(w, http.StatusInternalServerError, "failed to create product")
 		return
@@ -100,8 +310,15 @@ func (h *Handler) CreateProduct(w http.ResponseWriter, r *http.Request) {
 // @Description  Returns a single product by its UUID
 // @Tags         products
 // @Produce      json
-// @Param        id   path      string  true  "Product ID"
+// @Param        id       path      string  true   "Product ID"
+// @Param        channel  query     string  false  "Sales channel; a product disabled for it is not found"
+// @Param        currency        query  string  false  "Price in this currency from the price lists; a product without such a price is not found"
+// @Param        customer_group  query  string  false  "Prefer this customer group's price list (ignored on the storefront)"
+// @Param        locale           query   string  false  "Locale to read in; overrides Accept-Language"
+// @Param        Accept-Language  header  string  false  "Preferred locales; untranslated text is the fallback"
+// @Param        If-None-Match   header  string  false  "ETag of a copy the client holds"
 // @Success      200  {object}  Product
+// @Success      304
 // @Failure      400  {object}  map[string]interface{}
 // @Failure      404  {object}  map[string]interface{}
 // @Router       /products/{id} [get]
@@ -112,41 +329,2054 @@ func (h *Handler) GetProduct(w http.ResponseWriter, r *http.Request) {
 		h.writeError(w, http.StatusBadRequest, "invalid id")
 		return
 	}
-	p, err := h.service.GetProduct(r.Context(), id)
+	channel, err := salesChannel(r)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if channel != "" {
+		visible, err := h.service.ProductVisible(r.Context(), id, channel)
+		if err != nil {
+			h.log.Error("product visibility", zap.Error(err))
+			h.writeError(w, http.StatusInternalServerError, "failed to get product")
+			return
+		}
+		if !visible {
+			h.writeError(w, http.StatusNotFound, "product not found")
+			return
+		}
+	}
+	pricing, err := productPricing(r)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	locales, ok := h.locales(w, r)
+	if !ok {
+		return
+	}
+	p, err := h.service.GetProduct(r.Context(), id, pricing)
+	if err == nil {
+		localized := []Product{*p}
+		err = h.service.LocalizeProducts(r.Context(), localized, locales)
+		p = &localized[0]
+	}
 	if err != nil {
 		if err == ProductErrorNotFound {
 			h.writeError(w, http.StatusNotFound, "product not found")
 			return
 		}
+		if err == PriceListErrorNoPrice {
+			h.writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
 		h.log.Error("get product", zap.Error(err))
 		h.writeError(w, http.StatusInternalServerError, "failed to get product")
 		return
 	}
-	h.writeJSON(w, http.StatusOK, p)
+	if h.tracker != nil {
+		h.tracker.Track(r.Context(), Analytics.EventProductViewed, nil, map[string]interface{}{"product_id": p.ID, "sku": p.SKU, "price": p.Price, "currency": p.Currency})
+	}
+	if _, storefront := r.Context().Value(storefrontKey{}).(string); storefront {
+		h.service.RecordViews(map[uuid.UUID]int64{p.ID: 1})
+	}
+	h.writeVersioned(w, r, p.Version, p)
+}
+
+// ProductByBarcode godoc
+// @Summary      Get product by barcode
+// @Description  Looks up the product carrying a scanned GTIN (EAN-8, UPC-A, EAN-13 or GTIN-14); shorter codes match their zero-padded longer forms
+// @Tags         products
+// @Produce      json
+// @Param        code     path      string  true   "Barcode"
+// @Param        channel  query     string  false  "Sales channel; a product disabled for it is not found"
+// @Param        currency        query  string  false  "Price in this currency from the price lists"
+// @Param        customer_group  query  string  false  "Prefer this customer group's price list"
+// @Success      200  {object}  Product
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /products/barcode/{code} [get]
+func (h *Handler) ProductByBarcode(w http.ResponseWriter, r *http.Request) {
+	channel, err := salesChannel(r)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	pricing, err := productPricing(r)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	p, err := h.service.ProductByBarcode(r.Context(), chi.URLParam(r, "code"), pricing)
+	if err == nil && channel != "" {
+		var visible bool
+		if visible, err = h.service.ProductVisible(r.Context(), p.ID, channel); err == nil && !visible {
+			err = ProductErrorNotFound
+		}
+	}
+	switch {
+	case err == nil:
+		h.writeVersioned(w, r, p.Version, p)
+	case errors.Is(err, ProductErrorInvalidPayload):
+		h.writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, ProductErrorNotFound), errors.Is(err, PriceListErrorNoPrice):
+		h.writeError(w, http.StatusNotFound, err.Error())
+	default:
+		h.log.Error("get product by barcode", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to get product")
+	}
 }
 
 func (h *Handler) ListProducts(w http.ResponseWriter, r *http.Request) {
-    q := ListProductsQuery{Limit: 20} // default
-
-    if l := r.URL.Query().Get("limit"); l != "" {
-        if limit, err := strconv.Atoi(l); err == nil {
-            if limit > 0 && limit <= 100 {
-                q.Limit = limit
-            }
-        }
+    q, err := productQuery(r)
+    if err != nil {
+        h.writeError(w, http.StatusBadRequest, err.Error())
+        return
     }
-
-    if s := r.URL.Query().Get("search"); s != "" {
-        q.Search = s
+    q.Limit, q.Offset = Pagination.Params(r.URL.Query(), 20, 100)
+    switch q.Sort = r.URL.Query().Get("sort"); q.Sort {
+    case "", SortNewest, SortPopularity:
+    default:
+        h.writeError(w, http.StatusBadRequest, "sort must be newest or popularity")
+        return
     }
 
+    locales, ok := h.locales(w, r)
+    if !ok {
+        return
+    }
     products, err := h.service.ListProducts(r.Context(), q)
+    if err == nil {
+        err = h.service.LocalizeProducts(r.Context(), products, locales)
+    }
+    var total int
+    if err == nil {
+        total, err = h.service.CountProducts(r.Context(), q)
+    }
     if err != nil {
         h.log.Error("list products", zap.Error(err))
         h.writeError(w, http.StatusInternalServerError, "failed to list products")
         return
     }
-    h.writeJSON(w, http.StatusOK, products)
+    Pagination.Write(w, r, q.Limit, q.Offset, total)
+    h.writeList(w, r, products)
+}
+
+// maxReportedViews caps the products of one views report
+const maxReportedViews = 1000
+
+// RecordProductViews godoc
+// @Summary      Report product views
+// @Description  Adds views counted outside the API, e.g. by a storefront serving cached product pages. Storefront product reads (/public/products/{id}) are counted already. Counts are written in batches and feed the popularity sort and suggestions.
+// @Tags         products
+// @Accept       json
+// @Param        views  body  RecordViewsRequest  true  "Views per product ID"
+// @Success      202
+// @Failure      400    {object}  map[string]interface{}
+// @Router       /products/views [post]
+func (h *Handler) RecordProductViews(w http.ResponseWriter, r *http.Request) {
+	var dto RecordViewsRequest
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if len(dto.Views) == 0 || len(dto.Views) > maxReportedViews {
+		h.writeError(w, http.StatusBadRequest, fmt.Sprintf("views must list 1 to %d products", maxReportedViews))
+		return
+	}
+	for _, n := range dto.Views {
+		if n < 1 {
+			h.writeError(w, http.StatusBadRequest, "view counts must be positive")
+			return
+		}
+	}
+	h.service.RecordViews(dto.Views)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// SuggestProducts godoc
+// @Summary      Autocomplete product names
+// @Description  Products whose name contains q, names starting with it first, then the most viewed
+// @Tags         products
+// @Produce      json
+// @Param        q        query     string  true   "At least 2 characters"
+// @Param        limit    query     int     false  "Max suggestions (default 10, max 20)"
+// @Param        channel  query     string  false  "Sales channel"
+// @Success      200      {array}   ProductSuggestion
+// @Failure      400      {object}  map[string]interface{}
+// @Router       /products/suggest [get]
+func (h *Handler) SuggestProducts(w http.ResponseWriter, r *http.Request) {
+	channel, err := salesChannel(r)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	suggestions, err := h.service.SuggestProducts(r.Context(), strings.TrimSpace(r.URL.Query().Get("q")), channel, limit)
+	if err != nil {
+		if errors.Is(err, ProductErrorInvalidPayload) {
+			h.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		h.log.Error("suggest products", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to suggest products")
+		return
+	}
+	h.writeList(w, r, suggestions)
+}
+
+// ProductFacets godoc
+// @Summary      Count products by filter value
+// @Description  Counts the products matching the list filters per category, currency, price bucket and attribute value (the 50 most common per attribute), for a storefront's filter sidebar. Prices are bucketed in each product's own currency.
+// @Tags         products
+// @Produce      json
+// @Param        search         query     string  false  "Name or SKU contains"
+// @Param        category_id    query     string  false  "Category ID"
+// @Param        include_subcategories  query  bool  false  "With category_id, also products of its subcategories"
+// @Param        channel        query     string  false  "Sales channel"
+// @Param        currency       query     string  false  "Only products with a price in this currency"
+// @Param        price_buckets  query     string  false  "Comma separated ascending bucket edges (default 10,25,50,100,250,500,1000)"
+// @Param        locale         query     string  false  "Label categories in this locale instead of by Accept-Language"
+// @Success      200            {object}  ProductFacets
+// @Failure      400            {object}  map[string]interface{}
+// @Router       /products/facets [get]
+func (h *Handler) ProductFacets(w http.ResponseWriter, r *http.Request) {
+	q, err := productQuery(r)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var edges []decimal.Decimal
+	if v := r.URL.Query().Get("price_buckets"); v != "" {
+		for _, s := range strings.Split(v, ",") {
+			d, err := decimal.NewFromString(strings.TrimSpace(s))
+			if err != nil {
+				h.writeError(w, http.StatusBadRequest, "price_buckets must be numbers")
+				return
+			}
+			edges = append(edges, d)
+		}
+	}
+	locales, ok := h.locales(w, r)
+	if !ok {
+		return
+	}
+	facets, err := h.service.ProductFacets(r.Context(), q, edges, locales)
+	switch {
+	case err == nil:
+		h.writeJSON(w, http.StatusOK, facets)
+	case errors.Is(err, ProductErrorInvalidPayload):
+		h.writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		h.log.Error("product facets", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to count products")
+	}
+}
+
+// BatchGetProducts godoc
+// @Summary      Get many products by ID
+// @Description  Fetches up to 100 products in one query, in the order asked for. The list filters (channel, currency, ...) apply; ids not found or filtered out are listed in not_found.
+// @Tags         products
+// @Accept       json
+// @Produce      json
+// @Param        ids       body      BatchGetProductsRequest  true   "Product IDs"
+// @Param        channel   query     string                   false  "Sales channel"
+// @Param        currency  query     string                   false  "Price in this currency from the price lists"
+// @Success      200       {object}  BatchGetProductsResponse
+// @Failure      400       {object}  map[string]interface{}
+// @Router       /products/batch-get [post]
+func (h *Handler) BatchGetProducts(w http.ResponseWriter, r *http.Request) {
+	q, err := productQuery(r)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var dto BatchGetProductsRequest
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	q.IDs = dto.IDs
+	res, err := h.service.BatchGetProducts(r.Context(), q)
+	if err != nil {
+		if errors.Is(err, ProductErrorInvalidPayload) {
+			h.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		h.log.Error("batch get products", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to get products")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, res)
+}
+
+// DeleteProduct godoc
+// @Summary      Delete product
+// @Description  Soft deletes the product: it disappears from the catalog but past orders keep referencing it
+// @Tags         products
+// @Param        id   path  string  true  "Product ID"
+// @Success      204
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /products/{id} [delete]
+func (h *Handler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	if err := h.service.DeleteProduct(r.Context(), id); err != nil {
+		h.deleteError(w, "delete product", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RestoreProduct godoc
+// @Summary      Restore product
+// @Description  Undoes a soft delete
+// @Tags         products
+// @Produce      json
+// @Param        id   path      string  true  "Product ID"
+// @Success      200  {object}  Product
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /products/{id}/restore [post]
+func (h *Handler) RestoreProduct(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	p, err := h.service.RestoreProduct(r.Context(), id)
+	if err != nil {
+		h.deleteError(w, "restore product", err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, p)
+}
+
+// PatchCategory godoc
+// @Summary      Partially update a category
+// @Description  JSON merge patch: absent members are unchanged, null clears description or parent_id. An optional version, or the ETag in If-Match, must match the stored one.
+// @Tags         categories
+// @Accept       application/merge-patch+json
+// @Produce      json
+// @Param        id     path      string                true  "Category ID"
+// @Param        patch  body      PatchCategoryRequest  true  "Merge patch"
+// @Param        If-Match  header  string  false  "ETag the patch applies to"
+// @Success      200    {object}  Category
+// @Failure      400    {object}  map[string]interface{}
+// @Failure      404    {object}  map[string]interface{}
+// @Failure      409    {object}  map[string]interface{}
+// @Failure      412    {object}  map[string]interface{}
+// @Failure      415    {object}  map[string]interface{}
+// @Router       /categories/{id} [patch]
+func (h *Handler) PatchCategory(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	version, ok := h.ifMatch(w, r)
+	if !ok {
+		return
+	}
+	var patch PatchCategoryRequest
+	if !h.decodePatch(w, r, &patch) {
+		return
+	}
+	if !h.matchPatchVersion(w, version, &patch.Version) {
+		return
+	}
+	c, err := h.service.PatchCategory(r.Context(), id, patch)
+	if err != nil {
+		h.patchError(w, "patch category", preconditionError(version, err))
+		return
+	}
+	h.writeVersioned(w, r, c.Version, c)
+}
+
+// PatchProduct godoc
+// @Summary      Partially update a product
+// @Description  JSON merge patch: absent members are unchanged, null clears description, hs_code, country_of_origin, barcode, release_date, weight_grams, the dimensions or shipping_class. An optional version, or the ETag in If-Match, must match the stored one.
+// @Tags         products
+// @Accept       application/merge-patch+json
+// @Produce      json
+// @Param        id     path      string               true  "Product ID"
+// @Param        patch  body      PatchProductRequest  true  "Merge patch"
+// @Param        If-Match  header  string  false  "ETag the patch applies to"
+// @Success      200    {object}  Product
+// @Failure      400    {object}  map[string]interface{}
+// @Failure      404    {object}  map[string]interface{}
+// @Failure      409    {object}  map[string]interface{}
+// @Failure      412    {object}  map[string]interface{}
+// @Failure      415    {object}  map[string]interface{}
+// @Router       /products/{id} [patch]
+func (h *Handler) PatchProduct(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	version, ok := h.ifMatch(w, r)
+	if !ok {
+		return
+	}
+	var patch PatchProductRequest
+	if !h.decodePatch(w, r, &patch) {
+		return
+	}
+	if !h.matchPatchVersion(w, version, &patch.Version) {
+		return
+	}
+	p, err := h.service.PatchProduct(r.Context(), id, patch)
+	if err != nil {
+		h.patchError(w, "patch product", preconditionError(version, err))
+		return
+	}
+	h.writeVersioned(w, r, p.Version, p)
+}
+
+func (h *Handler) decodePatch(w http.ResponseWriter, r *http.Request, dst interface{}) bool {
+	err := MergePatch.Decode(r, dst)
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, MergePatch.ErrorContentType):
+		h.writeError(w, http.StatusUnsupportedMediaType, err.Error())
+	default:
+		h.writeError(w, http.StatusBadRequest, "invalid json: "+err.Error())
+	}
+	return false
+}
+
+// matchPatchVersion makes the If-Match version the one the patch must apply
+// to; a version member naming another one cannot be satisfied.
+func (h *Handler) matchPatchVersion(w http.ResponseWriter, version *int, field *MergePatch.Field[int]) bool {
+	if version == nil {
+		return true
+	}
+	if field.Set && (field.Null || field.Value != *version) {
+		h.writeError(w, http.StatusPreconditionFailed, ETagErrorPrecondition.Error())
+		return false
+	}
+	*field = MergePatch.Field[int]{Set: true, Value: *version}
+	return true
+}
+
+// preconditionError reports a version conflict as a failed precondition when
+// the version came from If-Match
+func preconditionError(version *int, err error) error {
+	if version != nil && (errors.Is(err, ProductErrorVersionConflict) || errors.Is(err, CategoryErrorVersionConflict) || errors.Is(err, VariantErrorVersionConflict)) {
+		return ETagErrorPrecondition
+	}
+	return err
+}
+
+func (h *Handler) patchError(w http.ResponseWriter, op string, err error) {
+	switch {
+	case errors.Is(err, ETagErrorPrecondition):
+		h.writeError(w, http.StatusPreconditionFailed, err.Error())
+	case errors.Is(err, ProductErrorNotFound), errors.Is(err, CategoryErrorNotFound):
+		h.writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ProductErrorInvalidPayload), errors.Is(err, CategoryErrorInvalidPayload):
+		h.writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, ProductErrorVersionConflict), errors.Is(err, CategoryErrorVersionConflict), errors.Is(err, CategoryErrorDuplicateSlug), errors.Is(err, ProductErrorDuplicateBarcode):
+		h.writeError(w, http.StatusConflict, err.Error())
+	default:
+		h.log.Error(op, zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, op+" failed")
+	}
+}
+
+func (h *Handler) deleteError(w http.ResponseWriter, op string, err error) {
+	switch {
+	case errors.Is(err, ProductErrorNotFound):
+		h.writeError(w, http.StatusNotFound, "product not found")
+	case errors.Is(err, CategoryErrorNotFound):
+		h.writeError(w, http.StatusNotFound, "category not found")
+	case errors.Is(err, CategoryErrorNotEmpty):
+		h.writeError(w, http.StatusConflict, err.Error())
+	default:
+		h.log.Error(op, zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, op+" failed")
+	}
+}
+
+// productQuery reads the catalog filters shared by the list and the export:
+// search, category_id, attribute filters, the sales channel and, outside the
+// storefront, include_deleted.
+func productQuery(r *http.Request) (ListProductsQuery, error) {
+	q := ListProductsQuery{Search: r.URL.Query().Get("search")}
+	if _, storefront := r.Context().Value(storefrontKey{}).(string); !storefront {
+		q.IncludeDeleted = r.URL.Query().Get("include_deleted") == "true"
+	}
+	if c := r.URL.Query().Get("category_id"); c != "" {
+		id, err := uuid.Parse(c)
+		if err != nil {
+			return q, errors.New("invalid category_id")
+		}
+		q.CategoryID = &id
+		q.IncludeSubcategories = r.URL.Query().Get("include_subcategories") == "true"
+	}
+	filters, err := attributeFilters(r.URL.Query())
+	if err != nil {
+		return q, err
+	}
+	q.Attributes = filters
+	pricing, err := productPricing(r)
+	if err != nil {
+		return q, err
+	}
+	q.Currency, q.CustomerGroup = pricing.Currency, pricing.CustomerGroup
+	q.Channel, err = salesChannel(r)
+	return q, err
+}
+
+// productPricing reads the currency to price products in and, outside the
+// storefront where anyone could claim a group, the customer group.
+func productPricing(r *http.Request) (Pricing, error) {
+	p := Pricing{Currency: strings.ToUpper(r.URL.Query().Get("currency"))}
+	if p.Currency != "" && !currencyCode.MatchString(p.Currency) {
+		return p, errors.New("invalid currency")
+	}
+	if _, storefront := r.Context().Value(storefrontKey{}).(string); !storefront {
+		p.CustomerGroup = r.URL.Query().Get("customer_group")
+	}
+	return p, nil
+}
+
+// ExportProducts godoc
+// @Summary      Export products
+// @Description  Streams every product matching the list filters, in id order, as CSV or JSON lines
+// @Tags         products
+// @Produce      text/csv
+// @Produce      application/x-ndjson
+// @Param        format       query     string  false  "csv (default) or jsonl"
+// @Param        search       query     string  false  "Name or SKU contains"
+// @Param        category_id  query     string  false  "Category ID"
+// @Param        include_subcategories  query  bool  false  "With category_id, also products of its subcategories"
+// @Param        channel      query     string  false  "Sales channel"
+// @Param        currency     query     string  false  "Export prices in this currency, leaving out products without one"
+// @Success      200
+// @Failure      400          {object}  map[string]interface{}
+// @Router       /products/export [get]
+func (h *Handler) ExportProducts(w http.ResponseWriter, r *http.Request) {
+	q, err := productQuery(r)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	var cw *csv.Writer
+	switch format {
+	case "csv":
+		cw = csv.NewWriter(w)
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	case "jsonl":
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	default:
+		h.writeError(w, http.StatusBadRequest, "format must be csv or jsonl")
+		return
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "products-"+time.Now().UTC().Format("2006-01-02")+"."+format))
+	if cw != nil {
+		_ = cw.Write(productHeader)
+	}
+	enc := json.NewEncoder(w)
+	flusher, _ := w.(http.Flusher)
+	started := false
+	err = h.service.ExportProducts(r.Context(), q, func(batch []Product) error {
+		started = true
+		for _, p := range batch {
+			if cw == nil {
+				if err := enc.Encode(p); err != nil {
+					return err
+				}
+			} else if err := cw.Write(productRecord(p)); err != nil {
+				return err
+			}
+		}
+		if cw != nil {
+			cw.Flush()
+			if err := cw.Error(); err != nil {
+				return err
+			}
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		h.log.Error("export products", zap.Error(err))
+		if !started {
+			w.Header().Del("Content-Disposition")
+			h.writeError(w, http.StatusInternalServerError, "failed to export products")
+		}
+		// otherwise the status is already sent and the client sees a truncated file
+		return
+	}
+	if cw != nil {
+		cw.Flush()
+	}
+}
+
+var productHeader = []string{"id", "sku", "name", "description", "category_id", "product_type", "price", "currency", "created_at", "updated_at"}
+
+func productRecord(p Product) []string {
+	description, category := "", ""
+	if p.Description != nil {
+		description = *p.Description
+	}
+	if p.CategoryID != nil {
+		category = p.CategoryID.String()
+	}
+	return []string{p.ID.String(), p.SKU, p.Name, description, category, p.ProductType, p.Price.String(), p.Currency, p.CreatedAt.Format(time.RFC3339), p.UpdatedAt.Format(time.RFC3339)}
+}
+
+type storefrontKey struct{}
+
+// Storefront marks the catalog routes it wraps as the customer facing scope:
+// they list products of channel when the caller names none, and never show
+// soft deleted products.
+func Storefront(channel string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), storefrontKey{}, channel)))
+		})
+	}
+}
+
+type replicaKey struct{}
+
+// ReplicaReads marks GET and HEAD requests as fine with reading a replica
+// that may lag the primary by a moment. Other requests read the primary so
+// they see their own writes, and so do jobs and other packages' checkouts.
+func ReplicaReads(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			r = r.WithContext(context.WithValue(r.Context(), replicaKey{}, true))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// salesChannel is the channel query parameter, else the storefront default;
+// empty means every channel. Order channels such as marketplace-amazon are
+// accepted and map to the marketplace channel.
+func salesChannel(r *http.Request) (string, error) {
+	channel := r.URL.Query().Get("channel")
+	if channel == "" {
+		channel, _ = r.Context().Value(storefrontKey{}).(string)
+	}
+	if strings.HasPrefix(channel, ChannelMarketplace+"-") {
+		channel = ChannelMarketplace
+	}
+	if channel != "" && !ValidChannel(channel) {
+		return "", fmt.Errorf("%w: %s", ChannelErrorUnknown, channel)
+	}
+	return channel, nil
+}
+
+// attributeFilters reads attr.<key>=v1,v2 (any of the values) and
+// attr.<key>.min / attr.<key>.max (number ranges) from the query string.
+func attributeFilters(query url.Values) ([]AttributeFilter, error) {
+	byKey := map[string]*AttributeFilter{}
+	var keys []string
+	for param, values := range query {
+		rest, ok := strings.CutPrefix(param, "attr.")
+		if !ok || len(values) == 0 {
+			continue
+		}
+		key, bound, _ := strings.Cut(rest, ".")
+		f := byKey[key]
+		if f == nil {
+			f = &AttributeFilter{Key: key}
+			byKey[key] = f
+			keys = append(keys, key)
+		}
+		switch bound {
+		case "":
+			for _, v := range values {
+				f.Values = append(f.Values, strings.Split(v, ",")...)
+			}
+		case "min", "max":
+			d, err := decimal.NewFromString(values[0])
+			if err != nil {
+				return nil, fmt.Errorf("attr.%s.%s must be a number", key, bound)
+			}
+			if bound == "min" {
+				f.Min = &d
+			} else {
+				f.Max = &d
+			}
+		default:
+			return nil, fmt.Errorf("unknown attribute filter %s", param)
+		}
+	}
+	sort.Strings(keys)
+	filters := make([]AttributeFilter, 0, len(keys))
+	for _, k := range keys {
+		filters = append(filters, *byKey[k])
+	}
+	return filters, nil
+}
+
+// ---------------- ATTRIBUTES -----------------
+
+// DefineAttribute godoc
+// @Summary      Define a category attribute
+// @Description  Adds a typed attribute (string, number, bool or enum) to the schema of the category's products
+// @Tags         categories
+// @Accept       json
+// @Produce      json
+// @Param        id         path      string                  true  "Category ID"
+// @Param        attribute  body      CreateAttributeRequest  true  "Attribute definition"
+// @Success      201        {object}  AttributeDefinition
+// @Failure      400        {object}  map[string]interface{}
+// @Failure      404        {object}  map[string]interface{}
+// @Failure      409        {object}  map[string]interface{}
+// @Router       /categories/{id}/attributes [post]
+func (h *Handler) DefineAttribute(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var dto CreateAttributeRequest
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	d, err := h.service.DefineAttribute(r.Context(), id, dto)
+	if err != nil {
+		h.attributeError(w, "define attribute", err)
+		return
+	}
+	h.writeJSON(w, http.StatusCreated, d)
+}
+
+// ListAttributes godoc
+// @Summary      Category attribute schema
+// @Tags         categories
+// @Produce      json
+// @Param        id   path      string  true  "Category ID"
+// @Success      200  {array}   AttributeDefinition
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /categories/{id}/attributes [get]
+func (h *Handler) ListAttributes(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	defs, err := h.service.ListAttributes(r.Context(), id)
+	if err != nil {
+		h.attributeError(w, "list attributes", err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, defs)
+}
+
+// DeleteAttribute godoc
+// @Summary      Remove a category attribute
+// @Description  Also removes the values the category's products had for it
+// @Tags         categories
+// @Param        id   path  string  true  "Category ID"
+// @Param        key  path  string  true  "Attribute key"
+// @Success      204
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /categories/{id}/attributes/{key} [delete]
+func (h *Handler) DeleteAttribute(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	if err := h.service.DeleteAttribute(r.Context(), id, chi.URLParam(r, "key")); err != nil {
+		if errors.Is(err, AttributeErrorNotFound) {
+			h.writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		h.attributeError(w, "delete attribute", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SetProductAttributes godoc
+// @Summary      Set product attributes
+// @Description  Replaces the product's attribute values; keys and types follow the schema of its category
+// @Tags         products
+// @Accept       json
+// @Produce      json
+// @Param        id          path      string                true  "Product ID"
+// @Param        attributes  body      SetAttributesRequest  true  "Values by key"
+// @Success      200         {object}  map[string]interface{}
+// @Failure      400         {object}  map[string]interface{}
+// @Failure      404         {object}  map[string]interface{}
+// @Router       /products/{id}/attributes [put]
+func (h *Handler) SetProductAttributes(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var dto SetAttributesRequest
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	attrs, err := h.service.SetProductAttributes(r.Context(), id, dto.Attributes)
+	if err != nil {
+		h.attributeError(w, "set product attributes", err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, map[string]interface{}{"attributes": attrs})
+}
+
+// ProductCost godoc
+// @Summary      Get product unit cost
+// @Description  What a unit costs the business, used for margin reporting; null when not set
+// @Tags         products
+// @Produce      json
+// @Param        id   path      string  true  "Product ID"
+// @Success      200  {object}  ProductCost
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /products/{id}/cost [get]
+func (h *Handler) ProductCost(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	c, err := h.service.ProductCost(r.Context(), id)
+	if err != nil {
+		h.costError(w, "get product cost", err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, c)
+}
+
+// SetProductCost godoc
+// @Summary      Set product unit cost
+// @Description  Orders placed from now on capture this cost on their items; null clears it
+// @Tags         products
+// @Accept       json
+// @Produce      json
+// @Param        id    path      string          true  "Product ID"
+// @Param        cost  body      SetCostRequest  true  "Unit cost"
+// @Success      200   {object}  ProductCost
+// @Failure      400   {object}  map[string]interface{}
+// @Failure      404   {object}  map[string]interface{}
+// @Router       /products/{id}/cost [put]
+func (h *Handler) SetProductCost(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var dto SetCostRequest
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	c, err := h.service.SetProductCost(r.Context(), id, dto.Cost)
+	if err != nil {
+		h.costError(w, "set product cost", err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, c)
+}
+
+func (h *Handler) costError(w http.ResponseWriter, op string, err error) {
+	switch {
+	case errors.Is(err, ProductErrorNotFound):
+		h.writeError(w, http.StatusNotFound, "product not found")
+	case errors.Is(err, ProductErrorInvalidPayload):
+		h.writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		h.log.Error(op, zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, op+" failed")
+	}
+}
+
+// ProductChannels godoc
+// @Summary      Get product sales channels
+// @Description  Whether the product is listed in each sales channel (web, mobile, pos, marketplace)
+// @Tags         products
+// @Produce      json
+// @Param        id   path      string  true  "Product ID"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /products/{id}/channels [get]
+func (h *Handler) ProductChannels(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	channels, err := h.service.ProductChannels(r.Context(), id)
+	if err != nil {
+		h.channelError(w, "get product channels", err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, map[string]interface{}{"channels": channels})
+}
+
+// SetProductChannels godoc
+// @Summary      Enable or disable a product per sales channel
+// @Description  Products are listed in every channel by default; channels left out of the body keep their setting
+// @Tags         products
+// @Accept       json
+// @Produce      json
+// @Param        id        path      string              true  "Product ID"
+// @Param        channels  body      SetChannelsRequest  true  "Enabled flag by channel"
+// @Success      200       {object}  map[string]interface{}
+// @Failure      400       {object}  map[string]interface{}
+// @Failure      404       {object}  map[string]interface{}
+// @Router       /products/{id}/channels [put]
+func (h *Handler) SetProductChannels(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var dto SetChannelsRequest
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	channels, err := h.service.SetProductChannels(r.Context(), id, dto.Channels)
+	if err != nil {
+		h.channelError(w, "set product channels", err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, map[string]interface{}{"channels": channels})
+}
+
+func (h *Handler) channelError(w http.ResponseWriter, op string, err error) {
+	switch {
+	case errors.Is(err, ProductErrorNotFound):
+		h.writeError(w, http.StatusNotFound, "product not found")
+	case errors.Is(err, ChannelErrorUnknown):
+		h.writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		h.log.Error(op, zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, op+" failed")
+	}
+}
+
+func (h *Handler) attributeError(w http.ResponseWriter, op string, err error) {
+	switch {
+	case errors.Is(err, CategoryErrorNotFound):
+		h.writeError(w, http.StatusNotFound, "category not found")
+	case errors.Is(err, ProductErrorNotFound):
+		h.writeError(w, http.StatusNotFound, "product not found")
+	case errors.Is(err, AttributeErrorDuplicate):
+		h.writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, AttributeErrorNotFound), errors.Is(err, AttributeErrorInvalidPayload), errors.Is(err, AttributeErrorInvalidValue):
+		h.writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		h.log.Error(op, zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, op+" failed")
+	}
+}
+
+// ---------------- TRANSLATIONS -----------------
+
+// locales reads the locales a read asks for (see Locales), answering the
+// request itself when the locale parameter is invalid. Responses negotiated
+// from Accept-Language vary by it.
+func (h *Handler) locales(w http.ResponseWriter, r *http.Request) ([]string, bool) {
+	param := r.URL.Query().Get("locale")
+	if param == "" {
+		w.Header().Add("Vary", "Accept-Language")
+	}
+	locales, err := Locales(param, r.Header.Get("Accept-Language"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	return locales, true
+}
+
+// SetProductTranslation godoc
+// @Summary      Translate a product
+// @Description  Creates or replaces the product's name, description and slug in a locale (BCP 47, e.g. fr or pt-BR); reads in that locale use them
+// @Tags         products
+// @Accept       json
+// @Produce      json
+// @Param        id           path      string              true  "Product ID"
+// @Param        locale       path      string              true  "Locale"
+// @Param        translation  body      TranslationRequest  true  "Text in the locale"
+// @Success      200          {object}  Translation
+// @Failure      400          {object}  map[string]interface{}
+// @Failure      404          {object}  map[string]interface{}
+// @Failure      409          {object}  map[string]interface{}
+// @Router       /products/{id}/translations/{locale} [put]
+func (h *Handler) SetProductTranslation(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var dto TranslationRequest
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	t, err := h.service.SetProductTranslation(r.Context(), id, chi.URLParam(r, "locale"), dto)
+	if err != nil {
+		h.translationError(w, "set product translation", err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, t)
+}
+
+// ListProductTranslations godoc
+// @Summary      List product translations
+// @Tags         products
+// @Produce      json
+// @Param        id   path      string  true  "Product ID"
+// @Success      200  {array}   Translation
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /products/{id}/translations [get]
+func (h *Handler) ListProductTranslations(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	ts, err := h.service.ListProductTranslations(r.Context(), id)
+	if err != nil {
+		h.translationError(w, "list product translations", err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, ts)
+}
+
+// DeleteProductTranslation godoc
+// @Summary      Remove a product translation
+// @Tags         products
+// @Param        id      path  string  true  "Product ID"
+// @Param        locale  path  string  true  "Locale"
+// @Success      204
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /products/{id}/translations/{locale} [delete]
+func (h *Handler) DeleteProductTranslation(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	if err := h.service.DeleteProductTranslation(r.Context(), id, chi.URLParam(r, "locale")); err != nil {
+		h.translationError(w, "delete product translation", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SetCategoryTranslation godoc
+// @Summary      Translate a category
+// @Description  Creates or replaces the category's name, description and slug in a locale (BCP 47); without a slug one is made from the name
+// @Tags         categories
+// @Accept       json
+// @Produce      json
+// @Param        id           path      string              true  "Category ID"
+// @Param        locale       path      string              true  "Locale"
+// @Param        translation  body      TranslationRequest  true  "Text in the locale"
+// @Success      200          {object}  Translation
+// @Failure      400          {object}  map[string]interface{}
+// @Failure      404          {object}  map[string]interface{}
+// @Failure      409          {object}  map[string]interface{}
+// @Router       /categories/{id}/translations/{locale} [put]
+func (h *Handler) SetCategoryTranslation(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var dto TranslationRequest
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	t, err := h.service.SetCategoryTranslation(r.Context(), id, chi.URLParam(r, "locale"), dto)
+	if err != nil {
+		h.translationError(w, "set category translation", err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, t)
+}
+
+// ListCategoryTranslations godoc
+// @Summary      List category translations
+// @Tags         categories
+// @Produce      json
+// @Param        id   path      string  true  "Category ID"
+// @Success      200  {array}   Translation
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /categories/{id}/translations [get]
+func (h *Handler) ListCategoryTranslations(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	ts, err := h.service.ListCategoryTranslations(r.Context(), id)
+	if err != nil {
+		h.translationError(w, "list category translations", err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, ts)
+}
+
+// DeleteCategoryTranslation godoc
+// @Summary      Remove a category translation
+// @Tags         categories
+// @Param        id      path  string  true  "Category ID"
+// @Param        locale  path  string  true  "Locale"
+// @Success      204
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /categories/{id}/translations/{locale} [delete]
+func (h *Handler) DeleteCategoryTranslation(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	if err := h.service.DeleteCategoryTranslation(r.Context(), id, chi.URLParam(r, "locale")); err != nil {
+		h.translationError(w, "delete category translation", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) translationError(w http.ResponseWriter, op string, err error) {
+	switch {
+	case errors.Is(err, CategoryErrorNotFound):
+		h.writeError(w, http.StatusNotFound, "category not found")
+	case errors.Is(err, ProductErrorNotFound):
+		h.writeError(w, http.StatusNotFound, "product not found")
+	case errors.Is(err, TranslationErrorNotFound):
+		h.writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, TranslationErrorInvalidPayload):
+		h.writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, TranslationErrorDuplicateSlug):
+		h.writeError(w, http.StatusConflict, err.Error())
+	default:
+		h.log.Error(op, zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, op+" failed")
+	}
+}
+
+// ---------------- PRODUCT IMAGES -----------------
+
+const maxImageUpload = 10 << 20
+
+// UploadProductImage godoc
+// @Summary      Upload a product image
+// @Description  Accepts a JPEG or PNG as multipart field "file"; thumbnail/medium/large renditions are generated asynchronously
+// @Tags         products
+// @Accept       mpfd
+// @Produce      json
+// @Param        id    path      string  true  "Product ID"
+// @Param        file  formData  file    true  "Image file"
+// @Success      202   {object}  ProductImage
+// @Failure      400   {object}  map[string]interface{}
+// @Failure      404   {object}  map[string]interface{}
+// @Router       /products/{id}/images [post]
+func (h *Handler) UploadProductImage(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxImageUpload)
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "file is required (max 10MB)")
+		return
+	}
+	defer file.Close()
+	// sniff the type instead of trusting the client header
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(file, head)
+	contentType := http.DetectContentType(head[:n])
+	img, err := h.service.UploadProductImage(r.Context(), id, contentType, io.MultiReader(bytes.NewReader(head[:n]), file))
+	if err != nil {
+		switch err {
+		case ProductErrorNotFound:
+			h.writeError(w, http.StatusNotFound, "product not found")
+		case ImageErrorUnsupported:
+			h.writeError(w, http.StatusBadRequest, "only jpeg and png images are supported")
+		default:
+			h.log.Error("upload product image", zap.Error(err))
+			h.writeError(w, http.StatusInternalServerError, "failed to upload image")
+		}
+		return
+	}
+	h.writeJSON(w, http.StatusAccepted, img)
+}
+
+// RequestImageUpload godoc
+// @Summary      Signed product image upload
+// @Description  Returns a short-lived URL to PUT a JPEG or PNG straight to storage; confirm with the complete endpoint afterwards. Needs S3 storage.
+// @Tags         products
+// @Accept       json
+// @Produce      json
+// @Param        id      path      string              true  "Product ID"
+// @Param        upload  body      ImageUploadRequest  true  "Image content type"
+// @Success      201     {object}  ImageUpload
+// @Failure      400     {object}  map[string]interface{}
+// @Failure      404     {object}  map[string]interface{}
+// @Failure      501     {object}  map[string]interface{}
+// @Router       /products/{id}/images/uploads [post]
+func (h *Handler) RequestImageUpload(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var dto ImageUploadRequest
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	upload, err := h.service.RequestImageUpload(r.Context(), id, dto.ContentType)
+	if err != nil {
+		h.imageError(w, "request image upload", err)
+		return
+	}
+	h.writeJSON(w, http.StatusCreated, upload)
+}
+
+// CompleteImageUpload godoc
+// @Summary      Confirm a signed image upload
+// @Description  Checks the uploaded file and queues rendition generation
+// @Tags         products
+// @Produce      json
+// @Param        id       path      string  true  "Product ID"
+// @Param        imageID  path      string  true  "Image ID"
+// @Success      202      {object}  ProductImage
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      404      {object}  map[string]interface{}
+// @Failure      409      {object}  map[string]interface{}
+// @Router       /products/{id}/images/{imageID}/complete [post]
+func (h *Handler) CompleteImageUpload(w http.ResponseWriter, r *http.Request) {
+	id, imageID, ok := h.imagePath(w, r)
+	if !ok {
+		return
+	}
+	img, err := h.service.CompleteImageUpload(r.Context(), id, imageID)
+	if err != nil {
+		h.imageError(w, "complete image upload", err)
+		return
+	}
+	h.writeJSON(w, http.StatusAccepted, img)
+}
+
+// ListProductImages godoc
+// @Summary      Product gallery
+// @Description  Images in display order, primary first
+// @Tags         products
+// @Produce      json
+// @Param        id   path      string  true  "Product ID"
+// @Success      200  {array}   ProductImage
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /products/{id}/images [get]
+func (h *Handler) ListProductImages(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	images, err := h.service.ListProductImages(r.Context(), id)
+	if err != nil {
+		h.imageError(w, "list product images", err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, images)
+}
+
+// ReorderProductImages godoc
+// @Summary      Reorder the product gallery
+// @Tags         products
+// @Accept       json
+// @Produce      json
+// @Param        id     path      string                true  "Product ID"
+// @Param        order  body      ReorderImagesRequest  true  "Every image id in the new order"
+// @Success      200    {array}   ProductImage
+// @Failure      400    {object}  map[string]interface{}
+// @Router       /products/{id}/images/order [put]
+func (h *Handler) ReorderProductImages(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var dto ReorderImagesRequest
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	images, err := h.service.ReorderProductImages(r.Context(), id, dto.ImageIDs)
+	if err != nil {
+		h.imageError(w, "reorder product images", err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, images)
+}
+
+// SetPrimaryImage godoc
+// @Summary      Set the primary product image
+// @Tags         products
+// @Produce      json
+// @Param        id       path      string  true  "Product ID"
+// @Param        imageID  path      string  true  "Image ID"
+// @Success      200      {array}   ProductImage
+// @Failure      404      {object}  map[string]interface{}
+// @Router       /products/{id}/images/{imageID}/primary [post]
+func (h *Handler) SetPrimaryImage(w http.ResponseWriter, r *http.Request) {
+	id, imageID, ok := h.imagePath(w, r)
+	if !ok {
+		return
+	}
+	images, err := h.service.SetPrimaryImage(r.Context(), id, imageID)
+	if err != nil {
+		h.imageError(w, "set primary image", err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, images)
+}
+
+// DeleteProductImage godoc
+// @Summary      Delete a product image
+// @Description  Removes the image and its renditions; the next image becomes primary when the primary is deleted
+// @Tags         products
+// @Param        id       path  string  true  "Product ID"
+// @Param        imageID  path  string  true  "Image ID"
+// @Success      204
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /products/{id}/images/{imageID} [delete]
+func (h *Handler) DeleteProductImage(w http.ResponseWriter, r *http.Request) {
+	id, imageID, ok := h.imagePath(w, r)
+	if !ok {
+		return
+	}
+	if err := h.service.DeleteProductImage(r.Context(), id, imageID); err != nil {
+		h.imageError(w, "delete product image", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) imagePath(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return uuid.Nil, uuid.Nil, false
+	}
+	imageID, err := uuid.Parse(chi.URLParam(r, "imageID"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid image id")
+		return uuid.Nil, uuid.Nil, false
+	}
+	return id, imageID, true
+}
+
+func (h *Handler) imageError(w http.ResponseWriter, op string, err error) {
+	switch err {
+	case ProductErrorNotFound:
+		h.writeError(w, http.StatusNotFound, "product not found")
+	case ImageErrorNotFound:
+		h.writeError(w, http.StatusNotFound, "image not found")
+	case ImageErrorUnsupported:
+		h.writeError(w, http.StatusBadRequest, "only jpeg and png images are supported")
+	case ImageErrorOrderMismatch:
+		h.writeError(w, http.StatusBadRequest, err.Error())
+	case ImageErrorNotUploaded:
+		h.writeError(w, http.StatusConflict, err.Error())
+	case ImageErrorSignedUpload:
+		h.writeError(w, http.StatusNotImplemented, err.Error())
+	default:
+		h.log.Error(op, zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, op+" failed")
+	}
+}
+
+// ServeProductImage godoc
+// @Summary      Image proxy
+// @Description  Streams an image rendition (thumbnail, medium, large or original)
+// @Tags         products
+// @Produce      image/jpeg
+// @Param        id    path   string  true   "Image ID"
+// @Param        size  query  string  false  "thumbnail|medium|large|original"
+// @Success      200
+// @Failure      404   {object}  map[string]interface{}
+// @Router       /images/{id} [get]
+func (h *Handler) ServeProductImage(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	size := r.URL.Query().Get("size")
+	if size == "" {
+		size = "medium"
+	}
+	rc, contentType, err := h.service.OpenProductImage(r.Context(), id, size)
+	if err != nil {
+		if err == ImageErrorNotFound || err == Storage.ErrBlobNotFound {
+			h.writeError(w, http.StatusNotFound, "image not found")
+			return
+		}
+		h.log.Error("serve product image", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to load image")
+		return
+	}
+	defer rc.Close()
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.WriteHeader(http.StatusOK)
+	_, _ = io.Copy(w, rc)
+}
+
+// ---------------- DIGITAL FILES -----------------
+
+const maxFileUpload = 200 << 20
+
+// AttachProductFile godoc
+// @Summary      Attach a downloadable file to a digital product
+// @Tags         products
+// @Accept       mpfd
+// @Produce      json
+// @Param        id    path      string  true  "Product ID"
+// @Param        file  formData  file    true  "File"
+// @Success      201   {object}  ProductFile
+// @Failure      400   {object}  map[string]interface{}
+// @Failure      404   {object}  map[string]interface{}
+// @Failure      422   {object}  map[string]interface{}
+// @Router       /products/{id}/files [post]
+func (h *Handler) AttachProductFile(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxFileUpload)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "file is required")
+		return
+	}
+	defer file.Close()
+	contentType := header.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	f, err := h.service.AttachProductFile(r.Context(), id, header.Filename, contentType, file)
+	if err != nil {
+		switch err {
+		case ProductErrorNotFound:
+			h.writeError(w, http.StatusNotFound, "product not found")
+		case ProductErrorNotDigital:
+			h.writeError(w, http.StatusUnprocessableEntity, err.Error())
+		default:
+			h.log.Error("attach product file", zap.Error(err))
+			h.writeError(w, http.StatusInternalServerError, "failed to attach file")
+		}
+		return
+	}
+	h.writeJSON(w, http.StatusCreated, f)
+}
+
+// ListProductFiles godoc
+// @Summary      List files of a digital product
+// @Tags         products
+// @Produce      json
+// @Param        id   path      string  true  "Product ID"
+// @Success      200  {array}   ProductFile
+// @Router       /products/{id}/files [get]
+func (h *Handler) ListProductFiles(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	files, err := h.service.ListProductFiles(r.Context(), id)
+	if err != nil {
+		h.log.Error("list product files", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to list files")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, files)
+}
+
+// ---------------- VARIANTS -----------------
+
+// CreateVariant godoc
+// @Summary      Add a product variant
+// @Description  A variant has its own SKU (unique across products and variants), options such as size or colour, and optionally its own price
+// @Tags         products
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                true  "Product ID"
+// @Param        variant  body      CreateVariantRequest  true  "Variant payload"
+// @Success      201      {object}  ProductVariant
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      404      {object}  map[string]interface{}
+// @Failure      409      {object}  map[string]interface{}
+// @Router       /products/{id}/variants [post]
+func (h *Handler) CreateVariant(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var dto CreateVariantRequest
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	v, err := h.service.CreateVariant(r.Context(), id, dto)
+	if err != nil {
+		h.variantError(w, "create variant", err)
+		return
+	}
+	h.writeJSON(w, http.StatusCreated, v)
+}
+
+// ListVariants godoc
+// @Summary      List product variants
+// @Tags         products
+// @Produce      json
+// @Param        id   path      string  true  "Product ID"
+// @Success      200  {array}   ProductVariant
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /products/{id}/variants [get]
+func (h *Handler) ListVariants(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	variants, err := h.service.ListVariants(r.Context(), id)
+	if err != nil {
+		h.variantError(w, "list variants", err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, variants)
+}
+
+// GetVariant godoc
+// @Summary      Get a product variant
+// @Tags         products
+// @Produce      json
+// @Param        id         path      string  true  "Product ID"
+// @Param        variantID  path      string  true  "Variant ID"
+// @Param        If-None-Match  header  string  false  "ETag of a copy the client holds"
+// @Success      200        {object}  ProductVariant
+// @Success      304
+// @Failure      404        {object}  map[string]interface{}
+// @Router       /products/{id}/variants/{variantID} [get]
+func (h *Handler) GetVariant(w http.ResponseWriter, r *http.Request) {
+	id, variantID, ok := h.variantPath(w, r)
+	if !ok {
+		return
+	}
+	v, err := h.service.GetVariant(r.Context(), id, variantID)
+	if err != nil {
+		h.variantError(w, "get variant", err)
+		return
+	}
+	h.writeVersioned(w, r, v.Version, v)
+}
+
+// UpdateVariant godoc
+// @Summary      Replace a product variant
+// @Description  Optimistic locking: send the version last read, or its ETag in If-Match
+// @Tags         products
+// @Accept       json
+// @Produce      json
+// @Param        id         path      string                true  "Product ID"
+// @Param        variantID  path      string                true  "Variant ID"
+// @Param        variant    body      UpdateVariantRequest  true  "Variant payload"
+// @Param        If-Match   header    string                false "ETag the update applies to"
+// @Success      200        {object}  ProductVariant
+// @Failure      400        {object}  map[string]interface{}
+// @Failure      404        {object}  map[string]interface{}
+// @Failure      409        {object}  map[string]interface{}
+// @Failure      412        {object}  map[string]interface{}
+// @Router       /products/{id}/variants/{variantID} [put]
+func (h *Handler) UpdateVariant(w http.ResponseWriter, r *http.Request) {
+	id, variantID, ok := h.variantPath(w, r)
+	if !ok {
+		return
+	}
+	version, ok := h.ifMatch(w, r)
+	if !ok {
+		return
+	}
+	var dto UpdateVariantRequest
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if version != nil {
+		if dto.Version != 0 && dto.Version != *version {
+			h.writeError(w, http.StatusPreconditionFailed, ETagErrorPrecondition.Error())
+			return
+		}
+		dto.Version = *version
+	}
+	v, err := h.service.UpdateVariant(r.Context(), id, variantID, dto)
+	if err != nil {
+		h.variantError(w, "update variant", preconditionError(version, err))
+		return
+	}
+	h.writeVersioned(w, r, v.Version, v)
+}
+
+// DeleteVariant godoc
+// @Summary      Delete a product variant
+// @Description  Removes the variant and its stock; past orders keep its SKU and name
+// @Tags         products
+// @Param        id         path  string  true  "Product ID"
+// @Param        variantID  path  string  true  "Variant ID"
+// @Success      204
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /products/{id}/variants/{variantID} [delete]
+func (h *Handler) DeleteVariant(w http.ResponseWriter, r *http.Request) {
+	id, variantID, ok := h.variantPath(w, r)
+	if !ok {
+		return
+	}
+	if err := h.service.DeleteVariant(r.Context(), id, variantID); err != nil {
+		h.variantError(w, "delete variant", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) variantPath(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return uuid.Nil, uuid.Nil, false
+	}
+	variantID, err := uuid.Parse(chi.URLParam(r, "variantID"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid variant id")
+		return uuid.Nil, uuid.Nil, false
+	}
+	return id, variantID, true
+}
+
+func (h *Handler) variantError(w http.ResponseWriter, op string, err error) {
+	switch {
+	case errors.Is(err, ETagErrorPrecondition):
+		h.writeError(w, http.StatusPreconditionFailed, err.Error())
+	case errors.Is(err, ProductErrorNotFound):
+		h.writeError(w, http.StatusNotFound, "product not found")
+	case errors.Is(err, VariantErrorNotFound):
+		h.writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, VariantErrorInvalidPayload):
+		h.writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, VariantErrorDuplicateSKU), errors.Is(err, VariantErrorVersionConflict):
+		h.writeError(w, http.StatusConflict, err.Error())
+	default:
+		h.log.Error(op, zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, op+" failed")
+	}
+}
+
+// ---------------- PRICE LISTS -----------------
+
+// CreatePriceList godoc
+// @Summary      Create a price list
+// @Description  Prices products in a currency, for a customer group or, without customer_group, for everyone buying in that currency. One list per currency and group.
+// @Tags         price-lists
+// @Accept       json
+// @Produce      json
+// @Param        list  body      CreatePriceListRequest  true  "Price list payload"
+// @Success      201   {object}  PriceList
+// @Failure      400   {object}  map[string]interface{}
+// @Failure      409   {object}  map[string]interface{}
+// @Router       /price-lists [post]
+func (h *Handler) CreatePriceList(w http.ResponseWriter, r *http.Request) {
+	var dto CreatePriceListRequest
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	l, err := h.service.CreatePriceList(r.Context(), dto)
+	if err != nil {
+		h.priceListError(w, "create price list", err)
+		return
+	}
+	h.writeJSON(w, http.StatusCreated, l)
+}
+
+// ListPriceLists godoc
+// @Summary      List price lists
+// @Tags         price-lists
+// @Produce      json
+// @Success      200  {array}  PriceList
+// @Router       /price-lists [get]
+func (h *Handler) ListPriceLists(w http.ResponseWriter, r *http.Request) {
+	lists, err := h.service.ListPriceLists(r.Context())
+	if err != nil {
+		h.priceListError(w, "list price lists", err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, lists)
+}
+
+// GetPriceList godoc
+// @Summary      Get a price list
+// @Tags         price-lists
+// @Produce      json
+// @Param        id   path      string  true  "Price list ID"
+// @Success      200  {object}  PriceList
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /price-lists/{id} [get]
+func (h *Handler) GetPriceList(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	l, err := h.service.GetPriceList(r.Context(), id)
+	if err != nil {
+		h.priceListError(w, "get price list", err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, l)
+}
+
+// DeletePriceList godoc
+// @Summary      Delete a price list
+// @Description  Its products fall back to the currency's default list or their own price
+// @Tags         price-lists
+// @Param        id  path  string  true  "Price list ID"
+// @Success      204
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /price-lists/{id} [delete]
+func (h *Handler) DeletePriceList(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	if err := h.service.DeletePriceList(r.Context(), id); err != nil {
+		h.priceListError(w, "delete price list", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListPriceListEntries godoc
+// @Summary      List the prices of a price list
+// @Tags         price-lists
+// @Produce      json
+// @Param        id   path      string  true  "Price list ID"
+// @Success      200  {array}   PriceListEntry
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /price-lists/{id}/prices [get]
+func (h *Handler) ListPriceListEntries(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	entries, err := h.service.ListPriceListEntries(r.Context(), id)
+	if err != nil {
+		h.priceListError(w, "list prices", err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, entries)
+}
+
+// SetPriceListEntries godoc
+// @Summary      Set product prices in a price list
+// @Description  Adds or changes the listed prices in one transaction; other entries are kept. A price covers the product and all its variants.
+// @Tags         price-lists
+// @Accept       json
+// @Produce      json
+// @Param        id      path      string            true  "Price list ID"
+// @Param        prices  body      []PriceListEntry  true  "Product prices"
+// @Success      200     {array}   PriceListEntry
+// @Failure      400     {object}  map[string]interface{}
+// @Failure      404     {object}  map[string]interface{}
+// @Router       /price-lists/{id}/prices [put]
+func (h *Handler) SetPriceListEntries(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var entries []PriceListEntry
+	if err := json.NewDecoder(r.Body).Decode(&entries); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	out, err := h.service.SetPriceListEntries(r.Context(), id, entries)
+	if err != nil {
+		h.priceListError(w, "set prices", err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, out)
+}
+
+// DeletePriceListEntry godoc
+// @Summary      Remove a product from a price list
+// @Tags         price-lists
+// @Param        id         path  string  true  "Price list ID"
+// @Param        productID  path  string  true  "Product ID"
+// @Success      204
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /price-lists/{id}/prices/{productID} [delete]
+func (h *Handler) DeletePriceListEntry(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	productID, err := uuid.Parse(chi.URLParam(r, "productID"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid product id")
+		return
+	}
+	err = h.service.DeletePriceListEntry(r.Context(), id, productID)
+	switch {
+	case errors.Is(err, ProductErrorNotFound):
+		h.writeError(w, http.StatusNotFound, "product has no price in this list")
+		return
+	case err != nil:
+		h.priceListError(w, "delete price", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) priceListError(w http.ResponseWriter, op string, err error) {
+	switch {
+	case errors.Is(err, PriceListErrorNotFound):
+		h.writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, PriceListErrorDuplicate):
+		h.writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, PriceListErrorInvalidPayload):
+		h.writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, ProductErrorNotFound):
+		h.writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		h.log.Error(op, zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, op+" failed")
+	}
+}
+
+// ---------------- CAMPAIGNS -----------------
+
+// CreateCampaign godoc
+// @Summary      Schedule a sale campaign
+// @Description  Discounts the listed products and every product of the listed categories by discount_percent between starts_at and ends_at. Catalog responses show sale_price next to price and checkout charges the sale price while the campaign runs.
+// @Tags         campaigns
+// @Accept       json
+// @Produce      json
+// @Param        campaign  body      CreateCampaignRequest  true  "Campaign payload"
+// @Success      201       {object}  Campaign
+// @Failure      400       {object}  map[string]interface{}
+// @Router       /campaigns [post]
+func (h *Handler) CreateCampaign(w http.ResponseWriter, r *http.Request) {
+	var dto CreateCampaignRequest
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	c, err := h.service.CreateCampaign(r.Context(), dto)
+	if err != nil {
+		h.campaignError(w, "create campaign", err)
+		return
+	}
+	h.writeJSON(w, http.StatusCreated, c)
+}
+
+// ListCampaigns godoc
+// @Summary      List campaigns
+// @Description  Past, running and scheduled campaigns, latest start first
+// @Tags         campaigns
+// @Produce      json
+// @Success      200  {array}   Campaign
+// @Router       /campaigns [get]
+func (h *Handler) ListCampaigns(w http.ResponseWriter, r *http.Request) {
+	campaigns, err := h.service.ListCampaigns(r.Context())
+	if err != nil {
+		h.campaignError(w, "list campaigns", err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, campaigns)
+}
+
+// GetCampaign godoc
+// @Summary      Get campaign
+// @Tags         campaigns
+// @Produce      json
+// @Param        id   path      string  true  "Campaign ID"
+// @Success      200  {object}  Campaign
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /campaigns/{id} [get]
+func (h *Handler) GetCampaign(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	c, err := h.service.GetCampaign(r.Context(), id)
+	if err != nil {
+		h.campaignError(w, "get campaign", err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, c)
+}
+
+// EndCampaign godoc
+// @Summary      End a campaign now
+// @Description  Stops a running campaign, or cancels a scheduled one; prices revert immediately
+// @Tags         campaigns
+// @Produce      json
+// @Param        id   path      string  true  "Campaign ID"
+// @Success      200  {object}  Campaign
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      409  {object}  map[string]interface{}
+// @Router       /campaigns/{id}/end [post]
+func (h *Handler) EndCampaign(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	c, err := h.service.EndCampaign(r.Context(), id)
+	if err != nil {
+		h.campaignError(w, "end campaign", err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, c)
+}
+
+func (h *Handler) campaignError(w http.ResponseWriter, op string, err error) {
+	switch {
+	case errors.Is(err, CampaignErrorNotFound):
+		h.writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, CampaignErrorEnded):
+		h.writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, CampaignErrorInvalidPayload), errors.Is(err, ProductErrorNotFound), errors.Is(err, CategoryErrorNotFound):
+		h.writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		h.log.Error(op, zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, op+" failed")
+	}
+}
+
+// ---------------- PUBLISHING -----------------
+
+// SchedulePublication godoc
+// @Summary      Schedule publishing a product or campaign
+// @Description  Publishes or unpublishes a product, or starts or ends a campaign, at the given moment: either at with its offset, or local_time (2006-01-02T15:04) in an IANA timezone. Unpublished products are hidden from sales channels and can't be ordered.
+// @Tags         publishing
+// @Accept       json
+// @Produce      json
+// @Param        schedule  body      CreatePublicationRequest  true  "Schedule payload"
+// @Success      201       {object}  PublicationSchedule
+// @Failure      400       {object}  map[string]interface{}
+// @Failure      404       {object}  map[string]interface{}
+// @Router       /publishing [post]
+func (h *Handler) SchedulePublication(w http.ResponseWriter, r *http.Request) {
+	var dto CreatePublicationRequest
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	p, err := h.service.SchedulePublication(r.Context(), dto)
+	if err != nil {
+		h.publicationError(w, "schedule publication", err)
+		return
+	}
+	h.writeJSON(w, http.StatusCreated, p)
+}
+
+// ListPublications godoc
+// @Summary      List publication schedules
+// @Description  Soonest first
+// @Tags         publishing
+// @Produce      json
+// @Param        status     query     string  false  "PENDING, DONE, SKIPPED or CANCELLED"
+// @Param        target_id  query     string  false  "Product or campaign ID"
+// @Success      200        {array}   PublicationSchedule
+// @Router       /publishing [get]
+func (h *Handler) ListPublications(w http.ResponseWriter, r *http.Request) {
+	var targetID *uuid.UUID
+	if raw := r.URL.Query().Get("target_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			h.writeError(w, http.StatusBadRequest, "invalid target_id")
+			return
+		}
+		targetID = &id
+	}
+	out, err := h.service.ListPublications(r.Context(), r.URL.Query().Get("status"), targetID)
+	if err != nil {
+		h.publicationError(w, "list publications", err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, out)
+}
+
+// CancelPublication godoc
+// @Summary      Cancel a publication schedule
+// @Tags         publishing
+// @Param        id   path  string  true  "Schedule ID"
+// @Success      204
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      409  {object}  map[string]interface{}
+// @Router       /publishing/{id} [delete]
+func (h *Handler) CancelPublication(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	if err := h.service.CancelPublication(r.Context(), id); err != nil {
+		h.publicationError(w, "cancel publication", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) publicationError(w http.ResponseWriter, op string, err error) {
+	switch {
+	case errors.Is(err, ScheduleErrorNotFound), errors.Is(err, ProductErrorNotFound), errors.Is(err, CampaignErrorNotFound):
+		h.writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ScheduleErrorNotPending):
+		h.writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, ScheduleErrorInvalidPayload):
+		h.writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		h.log.Error(op, zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, op+" failed")
+	}
 }
 
 // ---------------- UTIL -----------------
@@ -160,6 +2390,7 @@ func (h *Handler) writeJSON(w http.ResponseWriter, status int, v interface{}) {
 func (h *Handler) writeError(w http.ResponseWriter, status int, msg string) {
 	h.writeJSON(w, status, map[string]interface{}{
 		"error":     msg,
+		"code":      ErrorCodes.Resolve("CATALOG", status, msg),
 		"timestamp": time.Now().UTC(),
 	})
 }
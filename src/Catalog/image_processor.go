@@ -0,0 +1,182 @@
+package Catalog
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// BlobStore is the object storage used for product media
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader, contentType string) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	URL(key string) string
+}
+
+// rendition sizes, bounded by the longest edge in pixels
+var renditions = []struct {
+	Name    string
+	MaxEdge int
+}{
+	{"thumbnail", 150},
+	{"medium", 600},
+	{"large", 1200},
+}
+
+func renditionKey(img *ProductImage, size string) string {
+	return fmt.Sprintf("products/%s/%s/%s.jpg", img.ProductID, img.ID, size)
+}
+
+// ImageProcessor generates renditions for uploaded product images in the
+// background. Uploads are queued in memory; ProcessStale picks up anything
+// that was lost (e.g. on restart) and is meant to run from the job scheduler.
+type ImageProcessor struct {
+	repo  Repository
+	blobs BlobStore
+	log   *zap.Logger
+	queue chan uuid.UUID
+	wg    sync.WaitGroup
+}
+
+func NewImageProcessor(r Repository, blobs BlobStore, log *zap.Logger) *ImageProcessor {
+	return &ImageProcessor{repo: r, blobs: blobs, log: log, queue: make(chan uuid.UUID, 256)}
+}
+
+func (p *ImageProcessor) Start(workers int) {
+	for i := 0; i < workers; i++ {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			for id := range p.queue {
+				if err := p.Process(context.Background(), id); err != nil {
+					p.log.Error("process product image", zap.String("image_id", id.String()), zap.Error(err))
+				}
+			}
+		}()
+	}
+}
+
+func (p *ImageProcessor) Stop() {
+	close(p.queue)
+	p.wg.Wait()
+}
+
+// Enqueue schedules rendition generation; when the queue is full the image
+// stays PROCESSING and is picked up by ProcessStale.
+func (p *ImageProcessor) Enqueue(id uuid.UUID) {
+	select {
+	case p.queue <- id:
+	default:
+		p.log.Warn("image queue full, deferring to sweep", zap.String("image_id", id.String()))
+	}
+}
+
+// ProcessStale re-processes images stuck in PROCESSING for over a minute.
+func (p *ImageProcessor) ProcessStale(ctx context.Context) error {
+	images, err := p.repo.ListProcessingImages(ctx, time.Now().UTC().Add(-time.Minute), 50)
+	if err != nil {
+		return err
+	}
+	for _, img := range images {
+		if err := p.Process(ctx, img.ID); err != nil {
+			p.log.Error("process product image", zap.String("image_id", img.ID.String()), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+func (p *ImageProcessor) Process(ctx context.Context, id uuid.UUID) error {
+	img, err := p.repo.GetProductImage(ctx, id)
+	if err != nil {
+		return err
+	}
+	if img.Status != "PROCESSING" {
+		return nil
+	}
+	src, err := p.decode(ctx, img)
+	if err != nil {
+		img.Status = "FAILED"
+		_ = p.repo.UpdateProductImage(ctx, img)
+		return err
+	}
+	urls := map[string]*string{}
+	for _, r := range renditions {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, resize(src, r.MaxEdge), &jpeg.Options{Quality: 85}); err != nil {
+			return err
+		}
+		key := renditionKey(img, r.Name)
+		if err := p.blobs.Put(ctx, key, &buf, "image/jpeg"); err != nil {
+			return err
+		}
+		url := p.blobs.URL(key)
+		urls[r.Name] = &url
+	}
+	img.ThumbnailURL = urls["thumbnail"]
+	img.MediumURL = urls["medium"]
+	img.LargeURL = urls["large"]
+	img.Status = "READY"
+	return p.repo.UpdateProductImage(ctx, img)
+}
+
+func (p *ImageProcessor) decode(ctx context.Context, img *ProductImage) (image.Image, error) {
+	rc, err := p.blobs.Open(ctx, img.OriginalKey)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	src, _, err := image.Decode(rc)
+	return src, err
+}
+
+// resize downscales src so its longest edge fits maxEdge, averaging every
+// source pixel that falls into a destination pixel (box filter). Images
+// already small enough are returned unchanged.
+func resize(src image.Image, maxEdge int) image.Image {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= maxEdge && h <= maxEdge {
+		return src
+	}
+	dw, dh := maxEdge, h*maxEdge/w
+	if h > w {
+		dw, dh = w*maxEdge/h, maxEdge
+	}
+	if dw < 1 {
+		dw = 1
+	}
+	if dh < 1 {
+		dh = 1
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := b.Min.Y+y*h/dh, b.Min.Y+(y+1)*h/dh
+		for x := 0; x < dw; x++ {
+			x0, x1 := b.Min.X+x*w/dw, b.Min.X+(x+1)*w/dw
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, bl, a = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			if n == 0 {
+				continue
+			}
+			dst.SetRGBA(x, y, color.RGBA{R: uint8(r / n >> 8), G: uint8(g / n >> 8), B: uint8(bl / n >> 8), A: uint8(a / n >> 8)})
+		}
+	}
+	return dst
+}
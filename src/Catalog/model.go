@@ -4,6 +4,8 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx/types"
+	"github.com/lib/pq"
 	"github.com/shopspring/decimal"
 )
 
@@ -13,9 +15,18 @@ type Category struct {
 	Slug        string     `db:"slug" json:"slug"`
 	Description *string    `db:"description" json:"description,omitempty"`
 	ParentID    *uuid.UUID `db:"parent_id" json:"parent_id,omitempty"`
+	// display order among the siblings, lowest first
+	Position    int        `db:"position" json:"position"`
+	// the translation applied when read in a locale, else empty
+	Locale      string     `db:"-" json:"locale,omitempty"`
+	// set in lists: the live, published products filed directly under the
+	// category, and under it or any of its subcategories
+	ProductCount          *int `db:"product_count" json:"product_count,omitempty"`
+	ProductCountRecursive *int `db:"product_count_recursive" json:"product_count_recursive,omitempty"`
 	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
 	UpdatedAt   time.Time  `db:"updated_at" json:"updated_at"`
 	Version int `db:"version" json:"version"` 
+	DeletedAt   *time.Time `db:"deleted_at" json:"deleted_at,omitempty"`
 }
 const CategoryName = "categories";
 
@@ -27,8 +38,329 @@ type Product struct {
 	CategoryID  *uuid.UUID      `db:"category_id" json:"category_id,omitempty"`
 	Price       decimal.Decimal `db:"price" json:"price"`
 	Currency    string          `db:"currency" json:"currency"`
+	ProductType string          `db:"product_type" json:"product_type"` // PHYSICAL, DIGITAL, SERVICE
+	// customs data international shipments declare: the Harmonized System
+	// code (6 to 10 digits) and the ISO country the product was made in
+	HSCode          *string `db:"hs_code" json:"hs_code,omitempty"`
+	CountryOfOrigin *string `db:"country_of_origin" json:"country_of_origin,omitempty"`
+	// GTIN printed on the product (EAN-8, UPC-A, EAN-13 or GTIN-14)
+	Barcode *string `db:"barcode" json:"barcode,omitempty"`
+	// what shipping is priced on: the packed weight in grams, the packed
+	// dimensions in millimetres and a class carriers surcharge, such as
+	// "oversize" or "hazmat"
+	WeightGrams   *int    `db:"weight_grams" json:"weight_grams,omitempty"`
+	LengthMM      *int    `db:"length_mm" json:"length_mm,omitempty"`
+	WidthMM       *int    `db:"width_mm" json:"width_mm,omitempty"`
+	HeightMM      *int    `db:"height_mm" json:"height_mm,omitempty"`
+	ShippingClass *string `db:"shipping_class" json:"shipping_class,omitempty"`
+	// a pre-order product takes orders without stock until its release date;
+	// they are allocated once it is released and in stock
+	Preorder    bool       `db:"preorder" json:"preorder"`
+	ReleaseDate *time.Time `db:"release_date" json:"release_date,omitempty"`
+	// an unpublished (embargoed) product is hidden from sales channels and
+	// checkout; publication schedules flip it at a set moment
+	Published bool `db:"published" json:"published"`
 	CreatedAt   time.Time       `db:"created_at" json:"created_at"`
 	UpdatedAt   time.Time       `db:"updated_at" json:"updated_at"`
 	Version int `db:"version" json:"version"` 
+	// set when soft deleted; only admin lists with include_deleted show such products
+	DeletedAt *time.Time `db:"deleted_at" json:"deleted_at,omitempty"`
+	// set while a campaign discounts the product; Price stays the original
+	SalePrice  *decimal.Decimal `db:"-" json:"sale_price,omitempty"`
+	// set when Price and Currency come from a price list rather than the product
+	PriceListID *uuid.UUID `db:"-" json:"price_list_id,omitempty"`
+	CampaignID *uuid.UUID       `db:"-" json:"campaign_id,omitempty"`
+	// gallery in display order, primary image first
+	Images []ProductImage `db:"-" json:"images,omitempty"`
+	Variants []ProductVariant `db:"-" json:"variants,omitempty"`
+	// typed values of the category's attributes, by key
+	Attributes map[string]interface{} `db:"-" json:"attributes,omitempty"`
+	// set when read in a locale with a translation: the locale applied and
+	// the product's slug in it
+	Locale string  `db:"-" json:"locale,omitempty"`
+	Slug   *string `db:"-" json:"slug,omitempty"`
 }
-const ProductName="products"
\ No newline at end of file
+const ProductName="products"
+
+// ProductCost is what a unit of the product costs the business. It is kept
+// off Product so it never reaches the storefront; orders copy it onto their
+// items when placed.
+type ProductCost struct {
+	ProductID uuid.UUID        `db:"id" json:"product_id"`
+	Cost      *decimal.Decimal `db:"cost" json:"cost"`
+}
+
+// sales channels; a product is listed in every channel it is not disabled for
+const (
+	ChannelWeb         = "web"
+	ChannelMobile      = "mobile"
+	ChannelPOS         = "pos"
+	ChannelMarketplace = "marketplace"
+)
+
+// Channels lists every sales channel a product can be enabled or disabled for
+var Channels = []string{ChannelWeb, ChannelMobile, ChannelPOS, ChannelMarketplace}
+
+// ProductChannel enables or disables a product in one sales channel
+type ProductChannel struct {
+	ProductID uuid.UUID `db:"product_id"`
+	Channel   string    `db:"channel"`
+	Enabled   bool      `db:"enabled"`
+}
+
+const ProductChannelName = "product_channels"
+
+// ProductSuggestion completes a search box
+type ProductSuggestion struct {
+	ID   uuid.UUID `db:"id" json:"id"`
+	SKU  string    `db:"sku" json:"sku"`
+	Name string    `db:"name" json:"name"`
+}
+
+// ProductFacets counts the products matching a list filter by the values a
+// storefront narrows it by. Paging and sort don't apply.
+type ProductFacets struct {
+	Total      int                     `json:"total"`
+	Categories []FacetCount            `json:"categories"`
+	Currencies []FacetCount            `json:"currencies"`
+	Prices     []PriceBucket           `json:"prices"`
+	Attributes map[string][]FacetCount `json:"attributes"`
+}
+
+// FacetCount is how many products have Value, most first; Label is the
+// name of a category
+type FacetCount struct {
+	Value string `json:"value"`
+	Label string `json:"label,omitempty"`
+	Count int    `json:"count"`
+}
+
+// PriceBucket counts the products whose own price, in Currency, is at least
+// Min and below Max; the highest bucket has no Max
+type PriceBucket struct {
+	Currency string           `json:"currency"`
+	Min      *decimal.Decimal `json:"min"`
+	Max      *decimal.Decimal `json:"max,omitempty"`
+	Count    int              `json:"count"`
+}
+
+// ProductViewName counts product page views, for the popularity sort
+const ProductViewName = "product_views"
+
+// product types; only physical products are stocked and shipped, digital
+// ones are delivered as download links and services need neither
+const (
+	ProductTypePhysical = "PHYSICAL"
+	ProductTypeDigital  = "DIGITAL"
+	ProductTypeService  = "SERVICE"
+)
+
+// ProductVariant is a sellable version of a product (a size, a colour) with
+// its own SKU and stock. Price overrides the product price when set.
+type ProductVariant struct {
+	ID        uuid.UUID        `db:"id" json:"id"`
+	ProductID uuid.UUID        `db:"product_id" json:"product_id"`
+	SKU       string           `db:"sku" json:"sku"`
+	Name      string           `db:"name" json:"name"`
+	Options   types.JSONText   `db:"options" json:"options"`
+	Price     *decimal.Decimal `db:"price" json:"price,omitempty"`
+	CreatedAt time.Time        `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time        `db:"updated_at" json:"updated_at"`
+	Version   int              `db:"version" json:"version"`
+	// set while a campaign discounts the product
+	SalePrice *decimal.Decimal `db:"-" json:"sale_price,omitempty"`
+}
+
+const ProductVariantName = "product_variants"
+
+type ProductImage struct {
+	ID           uuid.UUID `db:"id" json:"id"`
+	ProductID    uuid.UUID `db:"product_id" json:"product_id"`
+	OriginalKey  string    `db:"original_key" json:"-"`
+	ContentType  string    `db:"content_type" json:"content_type"`
+	Status       string    `db:"status" json:"status"` // UPLOADING, PROCESSING, READY, FAILED
+	URL          string    `db:"url" json:"url"`
+	ThumbnailURL *string   `db:"thumbnail_url" json:"thumbnail_url,omitempty"`
+	MediumURL    *string   `db:"medium_url" json:"medium_url,omitempty"`
+	LargeURL     *string   `db:"large_url" json:"large_url,omitempty"`
+	Position     int       `db:"position" json:"position"`
+	IsPrimary    bool      `db:"is_primary" json:"is_primary"`
+	CreatedAt    time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt    time.Time `db:"updated_at" json:"updated_at"`
+}
+
+const ProductImageName = "product_images"
+
+// ProductFile is a downloadable asset of a digital product
+type ProductFile struct {
+	ID          uuid.UUID `db:"id" json:"id"`
+	ProductID   uuid.UUID `db:"product_id" json:"product_id"`
+	FileKey     string    `db:"file_key" json:"-"`
+	FileName    string    `db:"file_name" json:"file_name"`
+	ContentType string    `db:"content_type" json:"content_type"`
+	SizeBytes   int64     `db:"size_bytes" json:"size_bytes"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+}
+
+const ProductFileName = "product_files"
+
+// Campaign discounts the listed products and every product of the listed
+// categories by DiscountPercent between StartsAt and EndsAt. When campaigns
+// overlap a product gets the largest discount.
+type Campaign struct {
+	ID              uuid.UUID       `db:"id" json:"id"`
+	Name            string          `db:"name" json:"name"`
+	DiscountPercent decimal.Decimal `db:"discount_percent" json:"discount_percent"`
+	StartsAt        time.Time       `db:"starts_at" json:"starts_at"`
+	EndsAt          time.Time       `db:"ends_at" json:"ends_at"`
+	ProductIDs      []uuid.UUID     `db:"-" json:"product_ids"`
+	CategoryIDs     []uuid.UUID     `db:"-" json:"category_ids"`
+	CreatedAt       time.Time       `db:"created_at" json:"created_at"`
+	UpdatedAt       time.Time       `db:"updated_at" json:"updated_at"`
+}
+
+const CampaignName = "campaigns"
+
+// publication targets and actions
+const (
+	TargetProduct   = "product"
+	TargetCampaign  = "campaign"
+	ActionPublish   = "publish"
+	ActionUnpublish = "unpublish"
+)
+
+// publication schedule statuses
+const (
+	PublicationPending   = "PENDING"
+	PublicationDone      = "DONE"
+	PublicationSkipped   = "SKIPPED"
+	PublicationCancelled = "CANCELLED"
+)
+
+// PublicationSchedule publishes or unpublishes a product or campaign at
+// RunAt. Timezone is the zone it was scheduled in; LocalRunAt shows RunAt
+// on that zone's wall clock.
+type PublicationSchedule struct {
+	ID         uuid.UUID  `db:"id" json:"id"`
+	TargetType string     `db:"target_type" json:"target_type"`
+	TargetID   uuid.UUID  `db:"target_id" json:"target_id"`
+	Action     string     `db:"action" json:"action"`
+	RunAt      time.Time  `db:"run_at" json:"run_at"`
+	Timezone   string     `db:"timezone" json:"timezone"`
+	LocalRunAt string     `db:"-" json:"local_run_at"`
+	Status     string     `db:"status" json:"status"`
+	ExecutedAt *time.Time `db:"executed_at" json:"executed_at,omitempty"`
+	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+}
+
+const PublicationScheduleName = "publication_schedules"
+
+// Discount is the running campaign discount of one product
+type Discount struct {
+	ProductID       uuid.UUID       `db:"product_id"`
+	CampaignID      uuid.UUID       `db:"campaign_id"`
+	DiscountPercent decimal.Decimal `db:"discount_percent"`
+}
+
+// SalePrice applies the discount to price, rounded to cents
+func (d Discount) SalePrice(price decimal.Decimal) decimal.Decimal {
+	hundred := decimal.NewFromInt(100)
+	return price.Mul(hundred.Sub(d.DiscountPercent)).Div(hundred).Round(2)
+}
+
+// attribute types
+const (
+	AttributeString = "string"
+	AttributeNumber = "number"
+	AttributeBool   = "bool"
+	AttributeEnum   = "enum"
+)
+
+// AttributeDefinition declares an attribute products of a category may carry;
+// enum attributes only take one of EnumValues.
+type AttributeDefinition struct {
+	ID         uuid.UUID      `db:"id" json:"id"`
+	CategoryID uuid.UUID      `db:"category_id" json:"category_id"`
+	Key        string         `db:"key" json:"key"`
+	Label      string         `db:"label" json:"label"`
+	Type       string         `db:"type" json:"type"`
+	EnumValues pq.StringArray `db:"enum_values" json:"enum_values,omitempty"`
+	Required   bool           `db:"required" json:"required"`
+	CreatedAt  time.Time      `db:"created_at" json:"created_at"`
+	UpdatedAt  time.Time      `db:"updated_at" json:"updated_at"`
+}
+
+const AttributeDefinitionName = "attribute_definitions"
+
+// ProductAttribute is one stored attribute value, see the migration for the encoding
+type ProductAttribute struct {
+	ProductID   uuid.UUID        `db:"product_id"`
+	Key         string           `db:"key"`
+	Type        string           `db:"type"`
+	Value       string           `db:"value"`
+	NumberValue *decimal.Decimal `db:"number_value"`
+}
+
+const ProductAttributeName = "product_attributes"
+
+// Typed returns the value as JSON would show it
+func (a ProductAttribute) Typed() interface{} {
+	switch {
+	case a.Type == AttributeNumber && a.NumberValue != nil:
+		return *a.NumberValue
+	case a.Type == AttributeBool:
+		return a.Value == "true"
+	default:
+		return a.Value
+	}
+}
+
+// PriceList prices products in one currency, for one customer group or, with
+// no group, for everyone buying in that currency.
+type PriceList struct {
+	ID            uuid.UUID `db:"id" json:"id"`
+	Name          string    `db:"name" json:"name"`
+	Currency      string    `db:"currency" json:"currency"`
+	CustomerGroup *string   `db:"customer_group" json:"customer_group,omitempty"`
+	CreatedAt     time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt     time.Time `db:"updated_at" json:"updated_at"`
+}
+
+const PriceListName = "price_lists"
+
+// PriceListEntry is what a product and all its variants cost in a price list
+type PriceListEntry struct {
+	PriceListID uuid.UUID       `db:"price_list_id" json:"-"`
+	ProductID   uuid.UUID       `db:"product_id" json:"product_id"`
+	Price       decimal.Decimal `db:"price" json:"price"`
+}
+
+const PriceListEntryName = "price_list_entries"
+
+// ListPrice is an entry with the currency and group of its list, as read
+// when resolving product prices
+type ListPrice struct {
+	PriceListID   uuid.UUID       `db:"price_list_id"`
+	ProductID     uuid.UUID       `db:"product_id"`
+	Currency      string          `db:"currency"`
+	CustomerGroup *string         `db:"customer_group"`
+	Price         decimal.Decimal `db:"price"`
+}
+
+// Translation is a product's or category's text in one locale, a BCP 47
+// tag such as "fr" or "pt-BR". A missing description falls back to the
+// untranslated one.
+type Translation struct {
+	OwnerID     uuid.UUID `db:"owner_id" json:"-"`
+	Locale      string    `db:"locale" json:"locale"`
+	Name        string    `db:"name" json:"name"`
+	Description *string   `db:"description" json:"description,omitempty"`
+	Slug        *string   `db:"slug" json:"slug,omitempty"`
+	UpdatedAt   time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// translation tables and the column naming their owner
+const (
+	ProductTranslationName  = "product_translations"
+	CategoryTranslationName = "category_translations"
+)
@@ -0,0 +1,104 @@
+package Catalog
+
+import (
+	"context"
+	"fmt"
+	"time"
+	// zone rules are compiled in so timezones resolve on hosts without them
+	_ "time/tzdata"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// publicationBatch caps how many due schedules one run applies
+const publicationBatch = 100
+
+// localTimeLayout is how schedules given in wall-clock time are written
+const localTimeLayout = "2006-01-02T15:04"
+
+// SchedulePublication schedules a product or campaign to be published or
+// unpublished. The moment is At, or LocalTime on the wall clock of
+// Timezone; a local time skipped or repeated by a clock change resolves to
+// one of its offsets.
+func (s *service) SchedulePublication(ctx context.Context, dto CreatePublicationRequest) (*PublicationSchedule, error) {
+	if dto.TargetType != TargetProduct && dto.TargetType != TargetCampaign {
+		return nil, fmt.Errorf("%w: target_type must be %s or %s", ScheduleErrorInvalidPayload, TargetProduct, TargetCampaign)
+	}
+	if dto.Action != ActionPublish && dto.Action != ActionUnpublish {
+		return nil, fmt.Errorf("%w: action must be %s or %s", ScheduleErrorInvalidPayload, ActionPublish, ActionUnpublish)
+	}
+	if dto.Timezone == "" {
+		dto.Timezone = "UTC"
+	}
+	loc, err := time.LoadLocation(dto.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unknown timezone %q", ScheduleErrorInvalidPayload, dto.Timezone)
+	}
+	var at time.Time
+	switch {
+	case dto.At != nil && dto.LocalTime != "":
+		return nil, fmt.Errorf("%w: give at or local_time, not both", ScheduleErrorInvalidPayload)
+	case dto.At != nil:
+		at = *dto.At
+	case dto.LocalTime != "":
+		if at, err = time.ParseInLocation(localTimeLayout, dto.LocalTime, loc); err != nil {
+			return nil, fmt.Errorf("%w: local_time must look like %s", ScheduleErrorInvalidPayload, localTimeLayout)
+		}
+	default:
+		return nil, fmt.Errorf("%w: at or local_time required", ScheduleErrorInvalidPayload)
+	}
+	if !at.After(time.Now()) {
+		return nil, fmt.Errorf("%w: the schedule must be in the future", ScheduleErrorInvalidPayload)
+	}
+	p := &PublicationSchedule{
+		TargetType: dto.TargetType,
+		TargetID:   dto.TargetID,
+		Action:     dto.Action,
+		RunAt:      at.UTC(),
+		Timezone:   loc.String(),
+	}
+	if err := s.repository.CreatePublication(ctx, p); err != nil {
+		return nil, err
+	}
+	localize(p)
+	return p, nil
+}
+
+// ListPublications lists schedules, soonest first; status and targetID
+// narrow the list when set
+func (s *service) ListPublications(ctx context.Context, status string, targetID *uuid.UUID) ([]PublicationSchedule, error) {
+	out, err := s.repository.ListPublications(ctx, status, targetID)
+	for i := range out {
+		localize(&out[i])
+	}
+	return out, err
+}
+
+// CancelPublication withdraws a schedule that has not run yet
+func (s *service) CancelPublication(ctx context.Context, id uuid.UUID) error {
+	return s.repository.CancelPublication(ctx, id)
+}
+
+// RunPublications applies the schedules that are due, oldest first; run by
+// the scheduler. Products flip their published flag; a published campaign
+// starts now if it had not yet, an unpublished one ends now. Schedules whose
+// target is gone or already ended are marked SKIPPED.
+func (s *service) RunPublications(ctx context.Context) error {
+	ran, err := s.repository.RunDuePublications(ctx, time.Now().UTC(), publicationBatch)
+	for _, p := range ran {
+		if p.Status == PublicationSkipped {
+			s.log.Warn("publication skipped", zap.String("schedule_id", p.ID.String()), zap.String("target_type", p.TargetType), zap.String("target_id", p.TargetID.String()))
+		}
+	}
+	return err
+}
+
+// localize fills LocalRunAt from RunAt in the schedule's timezone
+func localize(p *PublicationSchedule) {
+	loc, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	p.LocalRunAt = p.RunAt.In(loc).Format(time.RFC3339)
+}
@@ -3,32 +3,132 @@ package Catalog
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/shopspring/decimal"
 	"go.uber.org/zap"
 )
 
 type Repository interface {
 	CreateCategory(ctx context.Context, c *Category) error
 	GetCategory(ctx context.Context, id uuid.UUID) (*Category, error)
+	// CategoryPath returns the category and its ancestors, root first
+	CategoryPath(ctx context.Context, id uuid.UUID) ([]Category, error)
+	ListCategories(ctx context.Context, parentID *uuid.UUID, topLevel, nonEmpty bool) ([]Category, error)
+	ReorderCategories(ctx context.Context, categoryIDs []uuid.UUID) (*uuid.UUID, error)
+	DeleteCategory(ctx context.Context, id uuid.UUID) error
+	RestoreCategory(ctx context.Context, id uuid.UUID) error
+	UpdateCategory(ctx context.Context, c *Category) error
+	// CategorySlugs returns the slugs, deleted categories' included, that are
+	// base or base followed by separator and a suffix
+	CategorySlugs(ctx context.Context, base, separator string) ([]string, error)
 
 	CreateProduct(ctx context.Context, p *Product) error
 
 	GetProduct(ctx context.Context, id uuid.UUID) (*Product, error)
+	ProductIDByBarcode(ctx context.Context, code string) (uuid.UUID, error)
+	UpdateProduct(ctx context.Context, p *Product) error
 	ListProducts(ctx context.Context, q ListProductsQuery) ([]Product, error)
+	CountProducts(ctx context.Context, q ListProductsQuery) (int, error)
+	ProductsAfter(ctx context.Context, q ListProductsQuery, afterID uuid.UUID, limit int) ([]Product, error)
+	// ProductFacets counts the products matching q by category, currency,
+	// price bucket (see PriceBucket) and attribute value
+	ProductFacets(ctx context.Context, q ListProductsQuery, priceEdges []decimal.Decimal) (*ProductFacets, error)
+	DeleteProduct(ctx context.Context, id uuid.UUID) error
+	RestoreProduct(ctx context.Context, id uuid.UUID) error
+	UpdatePrices(ctx context.Context, updates []PriceUpdate) ([]PriceUpdate, error)
+	// CurrentPrices returns the price of each of ids that is a product
+	CurrentPrices(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]decimal.Decimal, error)
+	ProductChanges(ctx context.Context, since time.Time, afterID uuid.UUID, until time.Time, limit int) ([]Product, error)
+
+	CreateProductImage(ctx context.Context, img *ProductImage) error
+	GetProductImage(ctx context.Context, id uuid.UUID) (*ProductImage, error)
+	UpdateProductImage(ctx context.Context, img *ProductImage) error
+	ListProcessingImages(ctx context.Context, olderThan time.Time, limit int) ([]ProductImage, error)
+	ListProductImages(ctx context.Context, productIDs []uuid.UUID) ([]ProductImage, error)
+	ReorderProductImages(ctx context.Context, productID uuid.UUID, imageIDs []uuid.UUID) error
+	SetPrimaryImage(ctx context.Context, productID, imageID uuid.UUID) error
+	DeleteProductImage(ctx context.Context, productID, imageID uuid.UUID) (*ProductImage, error)
+
+	CreateProductFile(ctx context.Context, f *ProductFile) error
+	ListProductFiles(ctx context.Context, productID uuid.UUID) ([]ProductFile, error)
+
+	CreateVariant(ctx context.Context, v *ProductVariant) error
+	GetVariant(ctx context.Context, productID, id uuid.UUID) (*ProductVariant, error)
+	ListVariants(ctx context.Context, productIDs []uuid.UUID) ([]ProductVariant, error)
+	UpdateVariant(ctx context.Context, v *ProductVariant) error
+	DeleteVariant(ctx context.Context, productID, id uuid.UUID) error
+	SKUInUse(ctx context.Context, sku string, exceptID uuid.UUID) (bool, error)
+
+	CreateAttributeDefinition(ctx context.Context, d *AttributeDefinition) error
+	ListAttributeDefinitions(ctx context.Context, categoryID uuid.UUID) ([]AttributeDefinition, error)
+	DeleteAttributeDefinition(ctx context.Context, categoryID uuid.UUID, key string) error
+	ReplaceProductAttributes(ctx context.Context, productID uuid.UUID, attrs []ProductAttribute) error
+	ListProductAttributes(ctx context.Context, productIDs []uuid.UUID) ([]ProductAttribute, error)
+	// translation methods take ProductTranslationName or CategoryTranslationName
+	UpsertTranslation(ctx context.Context, table string, t *Translation) error
+	ListTranslations(ctx context.Context, table string, ownerIDs []uuid.UUID, locales []string) ([]Translation, error)
+	DeleteTranslation(ctx context.Context, table string, ownerID uuid.UUID, locale string) error
+
+	GetProductCost(ctx context.Context, productID uuid.UUID) (*ProductCost, error)
+	SetProductCost(ctx context.Context, productID uuid.UUID, cost *decimal.Decimal) error
+
+	ListProductChannels(ctx context.Context, productID uuid.UUID) ([]ProductChannel, error)
+	SetProductChannels(ctx context.Context, channels []ProductChannel) error
+	ProductVisible(ctx context.Context, productID uuid.UUID, channel string) (bool, error)
+
+	CreatePriceList(ctx context.Context, l *PriceList) error
+	GetPriceList(ctx context.Context, id uuid.UUID) (*PriceList, error)
+	FindPriceList(ctx context.Context, currency string, customerGroup *string) (*PriceList, error)
+	ListPriceLists(ctx context.Context) ([]PriceList, error)
+	DeletePriceList(ctx context.Context, id uuid.UUID) error
+	SetPriceListEntries(ctx context.Context, entries []PriceListEntry) error
+	ListPriceListEntries(ctx context.Context, priceListID uuid.UUID) ([]PriceListEntry, error)
+	DeletePriceListEntry(ctx context.Context, priceListID, productID uuid.UUID) error
+	ListPrices(ctx context.Context, productIDs []uuid.UUID, currency, customerGroup string) ([]ListPrice, error)
+
+	CreateCampaign(ctx context.Context, c *Campaign) error
+	GetCampaign(ctx context.Context, id uuid.UUID) (*Campaign, error)
+	ListCampaigns(ctx context.Context) ([]Campaign, error)
+	EndCampaign(ctx context.Context, id uuid.UUID, at time.Time) error
+	ActiveDiscounts(ctx context.Context, productIDs []uuid.UUID) ([]Discount, error)
+
+	AddProductViews(ctx context.Context, views map[uuid.UUID]int64) error
+	SuggestProducts(ctx context.Context, prefix, channel string, limit int) ([]ProductSuggestion, error)
+
+	CreatePublication(ctx context.Context, p *PublicationSchedule) error
+	ListPublications(ctx context.Context, status string, targetID *uuid.UUID) ([]PublicationSchedule, error)
+	CancelPublication(ctx context.Context, id uuid.UUID) error
+	RunDuePublications(ctx context.Context, now time.Time, limit int) ([]PublicationSchedule, error)
 }
 
 type repository struct {
-	db  *sqlx.DB
-	log *zap.Logger
+	db      *sqlx.DB
+	replica *sqlx.DB
+	log     *zap.Logger
 }
 
-func NewRepository(db *sqlx.DB, log *zap.Logger) Repository {
-	return &repository{db: db, log: log}
+// NewRepository writes to db and reads from it too unless a replica is
+// given: reads made in a ReplicaReads request then go to the replica.
+func NewRepository(db, replica *sqlx.DB, log *zap.Logger) Repository {
+	return &repository{db: db, replica: replica, log: log}
+}
+
+// read is the handle list and get queries run on
+func (r *repository) read(ctx context.Context) *sqlx.DB {
+	if r.replica != nil && ctx.Value(replicaKey{}) != nil {
+		return r.replica
+	}
+	return r.db
 }
 
 func (r *repository) CreateCategory(ctx context.Context, c *Category) error {
@@ -37,16 +137,59 @@ func (r *repository) CreateCategory(ctx context.Context, c *Category) error {
 	c.CreatedAt = now
 	c.UpdatedAt = now
 	c.Version = 1
+	// a new category goes after its siblings
 	query := fmt.Sprintf(
-		`INSERT INTO %s (id,name,slug,description,parent_id,created_at,updated_at,version)
-		 VALUES ($1,$2,$3,$4,$5,$6,$7,$8)`, CategoryName)
-	_, err := r.db.ExecContext(
+		`INSERT INTO %[1]s (id,name,slug,description,parent_id,position,created_at,updated_at,version)
+		 VALUES ($1,$2,$3,$4,$5,(SELECT COALESCE(MAX(position)+1, 0) FROM %[1]s WHERE parent_id IS NOT DISTINCT FROM $5 AND deleted_at IS NULL),$6,$7,$8)
+		 RETURNING position`, CategoryName)
+	err := r.read(ctx).GetContext(
 		ctx,
+		&c.Position,
 		query,
 		c.ID, c.Name, c.Slug, c.Description, c.ParentID, c.CreatedAt, c.UpdatedAt, c.Version)
+	return slugConflict(err)
+}
+
+// slugConflict reports a clash with the unique slug index, which also covers
+// soft deleted categories, as CategoryErrorDuplicateSlug
+func slugConflict(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return CategoryErrorDuplicateSlug
+	}
+	return err
+}
+
+// barcodeConflict reports a clash with the unique GTIN index, which also
+// covers soft deleted products, as ProductErrorDuplicateBarcode
+func barcodeConflict(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "products_gtin_key" {
+		return ProductErrorDuplicateBarcode
+	}
 	return err
 }
 
+// ProductIDByBarcode finds the live product carrying the GTIN, whichever
+// length it was stored or scanned at
+func (r *repository) ProductIDByBarcode(ctx context.Context, code string) (uuid.UUID, error) {
+	var id uuid.UUID
+	err := r.read(ctx).GetContext(ctx, &id, fmt.Sprintf(`SELECT id FROM %s WHERE lpad(barcode, 14, '0') = lpad($1, 14, '0') AND deleted_at IS NULL`, ProductName), code)
+	if err == sql.ErrNoRows {
+		return uuid.Nil, ProductErrorNotFound
+	}
+	return id, err
+}
+
+// CategorySlugs implements Repository.
+func (r *repository) CategorySlugs(ctx context.Context, base, separator string) ([]string, error) {
+	slugs := []string{}
+	prefix := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(base + separator)
+	query := fmt.Sprintf(`SELECT slug FROM %s WHERE slug=$1 OR slug LIKE $2`, CategoryName)
+	err := r.read(ctx).SelectContext(ctx, &slugs, query, base, prefix+"%")
+	return slugs, err
+}
+
 // CreateProduct implements Repository.
 func (r *repository) CreateProduct(ctx context.Context, p *Product) error {
 	p.ID = uuid.New()
@@ -57,24 +200,24 @@ func (r *repository) CreateProduct(ctx context.Context, p *Product) error {
 
 	query := fmt.Sprintf(`
 	INSERT INTO %s 
-	(id, sku, name, description, category_id, price, currency, created_at, updated_at, version)
-	VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10)`, ProductName)
+	(id, sku, name, description, category_id, price, currency, product_type, hs_code, country_of_origin, preorder, release_date, published, barcode, weight_grams, length_mm, width_mm, height_mm, shipping_class, created_at, updated_at, version)
+	VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19,$20,$21,$22)`, ProductName)
 
 	_, err := r.db.ExecContext(ctx, query,
 		p.ID, p.SKU, p.Name, p.Description, p.CategoryID,
-		p.Price, p.Currency, p.CreatedAt, p.UpdatedAt, p.Version,
+		p.Price, p.Currency, p.ProductType, p.HSCode, p.CountryOfOrigin, p.Preorder, p.ReleaseDate, p.Published, p.Barcode, p.WeightGrams, p.LengthMM, p.WidthMM, p.HeightMM, p.ShippingClass, p.CreatedAt, p.UpdatedAt, p.Version,
 	)
-	return err
+	return barcodeConflict(err)
 }
 
 // GetCategory implements Repository.ows }
 
 func (r *repository) GetCategory(ctx context.Context, id uuid.UUID) (*Category, error) {
 	var category Category
-	query := fmt.Sprintf(`SELECT id,name,slug,description,parent_id,created_at,updated_at,version 
-		FROM %s WHERE id=$1`, CategoryName)
+	query := fmt.Sprintf(`SELECT id,name,slug,description,parent_id,position,created_at,updated_at,version 
+		FROM %s WHERE id=$1 AND deleted_at IS NULL`, CategoryName)
 
-	err := r.db.GetContext(
+	err := r.read(ctx).GetContext(
 		ctx,
 		&category,
 		query, id)
@@ -84,12 +227,125 @@ func (r *repository) GetCategory(ctx context.Context, id uuid.UUID) (*Category,
 	return &category, err
 }
 
+// CategoryPath implements Repository. The walk stops after 64 levels so a
+// parent loop written around checkParent cannot run away.
+func (r *repository) CategoryPath(ctx context.Context, id uuid.UUID) ([]Category, error) {
+	path := []Category{}
+	query := fmt.Sprintf(`WITH RECURSIVE chain AS (
+			SELECT id,name,slug,description,parent_id,position,created_at,updated_at,version, 0 AS depth
+			FROM %[1]s WHERE id=$1 AND deleted_at IS NULL
+			UNION ALL
+			SELECT c.id,c.name,c.slug,c.description,c.parent_id,c.position,c.created_at,c.updated_at,c.version, chain.depth+1
+			FROM %[1]s c JOIN chain ON c.id = chain.parent_id
+			WHERE c.deleted_at IS NULL AND chain.depth < 64
+		)
+		SELECT id,name,slug,description,parent_id,position,created_at,updated_at,version FROM chain ORDER BY depth DESC`, CategoryName)
+	if err := r.read(ctx).SelectContext(ctx, &path, query, id); err != nil {
+		return nil, err
+	}
+	if len(path) == 0 {
+		return nil, CategoryErrorNotFound
+	}
+	return path, nil
+}
+
+// ListCategories lists live categories in display order: the children of
+// parentID, the top level when topLevel is set, else every category.
+func (r *repository) ListCategories(ctx context.Context, parentID *uuid.UUID, topLevel, nonEmpty bool) ([]Category, error) {
+	categories := []Category{}
+	// tree pairs each listed category with itself and every live descendant
+	query := fmt.Sprintf(`WITH RECURSIVE listed AS (
+			SELECT id,name,slug,description,parent_id,position,created_at,updated_at,version FROM %[1]s
+			WHERE deleted_at IS NULL AND ($1::uuid IS NULL OR parent_id = $1) AND (NOT $2 OR parent_id IS NULL)
+		), tree AS (
+			SELECT id AS root, id FROM listed
+			UNION ALL
+			SELECT t.root, c.id FROM %[1]s c JOIN tree t ON c.parent_id = t.id WHERE c.deleted_at IS NULL
+		), counts AS (
+			SELECT category_id, COUNT(*)::int AS n FROM %[2]s WHERE deleted_at IS NULL AND published AND category_id IS NOT NULL GROUP BY category_id
+		), totals AS (
+			SELECT t.root, COALESCE(SUM(n.n), 0)::int AS n FROM tree t LEFT JOIN counts n ON n.category_id = t.id GROUP BY t.root
+		)
+		SELECT l.*, COALESCE(d.n, 0) AS product_count, tot.n AS product_count_recursive
+		FROM listed l JOIN totals tot ON tot.root = l.id LEFT JOIN counts d ON d.category_id = l.id
+		WHERE NOT $3 OR tot.n > 0
+		ORDER BY l.position, l.name`, CategoryName, ProductName)
+	err := r.read(ctx).SelectContext(ctx, &categories, query, parentID, topLevel, nonEmpty)
+	return categories, err
+}
+
+// ReorderCategories sets the display order of one parent's children;
+// categoryIDs must list every live child exactly once.
+func (r *repository) ReorderCategories(ctx context.Context, categoryIDs []uuid.UUID) (parentID *uuid.UUID, err error) {
+	if len(categoryIDs) == 0 {
+		return nil, CategoryErrorOrderMismatch
+	}
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	if err = tx.GetContext(ctx, &parentID, fmt.Sprintf(`SELECT parent_id FROM %s WHERE id=$1 AND deleted_at IS NULL`, CategoryName), categoryIDs[0]); err != nil {
+		if err == sql.ErrNoRows {
+			err = CategoryErrorNotFound
+		}
+		return nil, err
+	}
+	var current []uuid.UUID
+	if err = tx.SelectContext(ctx, &current, fmt.Sprintf(`SELECT id FROM %s WHERE parent_id IS NOT DISTINCT FROM $1 AND deleted_at IS NULL FOR UPDATE`, CategoryName), parentID); err != nil {
+		return nil, err
+	}
+	listed := make(map[uuid.UUID]bool, len(categoryIDs))
+	for _, id := range categoryIDs {
+		listed[id] = true
+	}
+	if len(listed) != len(categoryIDs) || len(current) != len(categoryIDs) {
+		return nil, CategoryErrorOrderMismatch
+	}
+	for _, id := range current {
+		if !listed[id] {
+			return nil, CategoryErrorOrderMismatch
+		}
+	}
+	query := fmt.Sprintf(`UPDATE %s SET position=$1, updated_at=NOW(), version=version+1 WHERE id=$2 AND position <> $1`, CategoryName)
+	for i, id := range categoryIDs {
+		if _, err = tx.ExecContext(ctx, query, i, id); err != nil {
+			return nil, err
+		}
+	}
+	err = tx.Commit()
+	return parentID, err
+}
+
+// UpdateCategory saves c if its version is still the stored one
+func (r *repository) UpdateCategory(ctx context.Context, c *Category) error {
+	c.UpdatedAt = time.Now().UTC()
+	query := fmt.Sprintf(`UPDATE %s SET name=$1, slug=$2, description=$3, parent_id=$4, updated_at=$5, version=version+1
+		WHERE id=$6 AND version=$7 AND deleted_at IS NULL`, CategoryName)
+	res, err := r.db.ExecContext(ctx, query, c.Name, c.Slug, c.Description, c.ParentID, c.UpdatedAt, c.ID, c.Version)
+	if err != nil {
+		return slugConflict(err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		if _, err := r.GetCategory(ctx, c.ID); err != nil {
+			return err
+		}
+		return CategoryErrorVersionConflict
+	}
+	c.Version++
+	return nil
+}
+
 // GetProduct implements Repository.
 func (r *repository) GetProduct(ctx context.Context, id uuid.UUID) (*Product, error) {
 	var product Product
-	query := fmt.Sprintf(`SELECT id,sku,name,description,category_id,price,currency,created_at,updated_at,version
-		FROM %s WHERE id=$1`, ProductName)
-	err := r.db.GetContext(
+	query := fmt.Sprintf(`SELECT id,sku,name,description,category_id,price,currency,product_type,hs_code,country_of_origin,preorder,release_date,published,barcode,weight_grams,length_mm,width_mm,height_mm,shipping_class,created_at,updated_at,version
+		FROM %s WHERE id=$1 AND deleted_at IS NULL`, ProductName)
+	err := r.read(ctx).GetContext(
 		ctx,
 		&product,
 		query, id)
@@ -99,20 +355,1135 @@ func (r *repository) GetProduct(ctx context.Context, id uuid.UUID) (*Product, er
 	return &product, err
 }
 
+// UpdateProduct saves p if its version is still the stored one
+func (r *repository) UpdateProduct(ctx context.Context, p *Product) error {
+	p.UpdatedAt = time.Now().UTC()
+	query := fmt.Sprintf(`UPDATE %s SET name=$1, description=$2, category_id=$3, price=$4, currency=$5, product_type=$6, hs_code=$7, country_of_origin=$8, preorder=$9, release_date=$10, published=$11, barcode=$12, weight_grams=$13, length_mm=$14, width_mm=$15, height_mm=$16, shipping_class=$17, updated_at=$18, version=version+1
+		WHERE id=$19 AND version=$20 AND deleted_at IS NULL`, ProductName)
+	res, err := r.db.ExecContext(ctx, query, p.Name, p.Description, p.CategoryID, p.Price, p.Currency, p.ProductType, p.HSCode, p.CountryOfOrigin, p.Preorder, p.ReleaseDate, p.Published, p.Barcode, p.WeightGrams, p.LengthMM, p.WidthMM, p.HeightMM, p.ShippingClass, p.UpdatedAt, p.ID, p.Version)
+	if err != nil {
+		return barcodeConflict(err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		if _, err := r.GetProduct(ctx, p.ID); err != nil {
+			return err
+		}
+		return ProductErrorVersionConflict
+	}
+	p.Version++
+	return nil
+}
+
 // ListProducts implements Repository.
 func (r *repository) ListProducts(ctx context.Context, q ListProductsQuery) ([]Product, error) {
-	base := fmt.Sprintf(`SELECT id,sku,name,description,category_id,price,currency,created_at,updated_at,version FROM %s WHERE 1=1`, ProductName)
+	where, args := productFilters(q)
+	base := fmt.Sprintf(`SELECT id,sku,name,description,category_id,price,currency,product_type,hs_code,country_of_origin,preorder,release_date,published,barcode,weight_grams,length_mm,width_mm,height_mm,shipping_class,created_at,updated_at,version,deleted_at FROM %s WHERE 1=1`, ProductName) + where
+	if q.Sort == SortPopularity {
+		base += fmt.Sprintf(" ORDER BY (SELECT v.views FROM %s v WHERE v.product_id = %s.id) DESC NULLS LAST, created_at DESC", ProductViewName, ProductName)
+	} else {
+		base += " ORDER BY created_at DESC"
+	}
+	base += fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+	args = append(args, q.Limit, q.Offset)
+
+	products := []Product{}
+	err := r.read(ctx).SelectContext(ctx, &products, base, args...)
+	return products, err
+}
+
+// CountProducts counts the products ListProducts pages through
+func (r *repository) CountProducts(ctx context.Context, q ListProductsQuery) (int, error) {
+	where, args := productFilters(q)
+	var n int
+	err := r.read(ctx).GetContext(ctx, &n, fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE 1=1`, ProductName)+where, args...)
+	return n, err
+}
+
+// ProductsAfter returns the next page of products matching q with an id
+// greater than afterID, in id order, so exports can walk the whole catalog.
+func (r *repository) ProductsAfter(ctx context.Context, q ListProductsQuery, afterID uuid.UUID, limit int) ([]Product, error) {
+	where, args := productFilters(q)
+	base := fmt.Sprintf(`SELECT id,sku,name,description,category_id,price,currency,product_type,hs_code,country_of_origin,preorder,release_date,published,barcode,weight_grams,length_mm,width_mm,height_mm,shipping_class,created_at,updated_at,version,deleted_at FROM %s WHERE 1=1`, ProductName) + where
+	base += fmt.Sprintf(" AND id > $%d ORDER BY id LIMIT $%d", len(args)+1, len(args)+2)
+	args = append(args, afterID, limit)
+
+	products := []Product{}
+	err := r.read(ctx).SelectContext(ctx, &products, base, args...)
+	return products, err
+}
+
+// ProductFacets implements Repository. The filters run once, in a CTE every
+// count reads from, and the counts come back in one result.
+func (r *repository) ProductFacets(ctx context.Context, q ListProductsQuery, priceEdges []decimal.Decimal) (*ProductFacets, error) {
+	where, args := productFilters(q)
+	edges := make(pq.StringArray, len(priceEdges))
+	for i, e := range priceEdges {
+		edges[i] = e.String()
+	}
+	args = append(args, edges)
+	query := fmt.Sprintf(`WITH matched AS MATERIALIZED (SELECT id, category_id, price, currency FROM %[1]s WHERE 1=1%[2]s)
+		SELECT 'total' AS facet, '' AS key, '' AS value, '' AS label, COUNT(*) AS count FROM matched
+		UNION ALL
+		SELECT 'category', m.category_id::text, '', COALESCE(MAX(c.name), ''), COUNT(*) FROM matched m LEFT JOIN %[3]s c ON c.id = m.category_id
+		WHERE m.category_id IS NOT NULL GROUP BY m.category_id
+		UNION ALL
+		SELECT 'currency', currency, '', '', COUNT(*) FROM matched GROUP BY currency
+		UNION ALL
+		SELECT 'price', currency, width_bucket(price, $%[5]d::numeric[])::text, '', COUNT(*) FROM matched GROUP BY currency, width_bucket(price, $%[5]d::numeric[])
+		UNION ALL
+		SELECT 'attribute', pa.key, pa.value, '', COUNT(*) FROM %[4]s pa JOIN matched m ON m.id = pa.product_id GROUP BY pa.key, pa.value
+		ORDER BY 1, 2, 5 DESC, 3`, ProductName, where, CategoryName, ProductAttributeName, len(args))
+	rows := []struct {
+		Facet string `db:"facet"`
+		Key   string `db:"key"`
+		Value string `db:"value"`
+		Label string `db:"label"`
+		Count int    `db:"count"`
+	}{}
+	if err := r.read(ctx).SelectContext(ctx, &rows, query, args...); err != nil {
+		return nil, err
+	}
+	f := &ProductFacets{Categories: []FacetCount{}, Currencies: []FacetCount{}, Prices: []PriceBucket{}, Attributes: map[string][]FacetCount{}}
+	for _, row := range rows {
+		switch row.Facet {
+		case "total":
+			f.Total = row.Count
+		case "category":
+			f.Categories = append(f.Categories, FacetCount{Value: row.Key, Label: row.Label, Count: row.Count})
+		case "currency":
+			f.Currencies = append(f.Currencies, FacetCount{Value: row.Key, Count: row.Count})
+		case "price":
+			// width_bucket numbers the buckets from 0, below the first edge
+			i, err := strconv.Atoi(row.Value)
+			if err != nil {
+				return nil, err
+			}
+			min := decimal.Zero
+			if i > 0 {
+				min = priceEdges[i-1]
+			}
+			b := PriceBucket{Currency: row.Key, Min: &min, Count: row.Count}
+			if i < len(priceEdges) {
+				b.Max = &priceEdges[i]
+			}
+			f.Prices = append(f.Prices, b)
+		case "attribute":
+			f.Attributes[row.Key] = append(f.Attributes[row.Key], FacetCount{Value: row.Value, Count: row.Count})
+		}
+	}
+	sort.SliceStable(f.Prices, func(i, j int) bool {
+		if f.Prices[i].Currency != f.Prices[j].Currency {
+			return f.Prices[i].Currency < f.Prices[j].Currency
+		}
+		return f.Prices[i].Min.LessThan(*f.Prices[j].Min)
+	})
+	return f, nil
+}
+
+// productFilters builds the AND conditions of q, numbering placeholders from $1
+func productFilters(q ListProductsQuery) (string, []interface{}) {
+	base := ""
 	args := []interface{}{}
 	idx := 1
+	if !q.IncludeDeleted {
+		base += " AND deleted_at IS NULL"
+	}
 	if q.Search != "" {
 		base += fmt.Sprintf(" AND (name ILIKE $%d OR sku ILIKE $%d)", idx, idx+1)
 		args = append(args, "%"+q.Search+"%", "%"+q.Search+"%")
 		idx += 2
 	}
-	base += fmt.Sprintf(" ORDER BY created_at DESC LIMIT $%d OFFSET $%d", idx, idx+1)
-	args = append(args, q.Limit, q.Offset)
+	if q.CategoryID != nil && q.IncludeSubcategories {
+		// the depth cap guards against a parent cycle, like CategoryPath
+		base += fmt.Sprintf(` AND category_id IN (WITH RECURSIVE tree AS (
+			SELECT id, 0 AS depth FROM %[1]s WHERE id = $%[2]d AND deleted_at IS NULL
+			UNION ALL
+			SELECT c.id, tree.depth+1 FROM %[1]s c JOIN tree ON c.parent_id = tree.id
+			WHERE c.deleted_at IS NULL AND tree.depth < 64
+		) SELECT id FROM tree)`, CategoryName, idx)
+		args = append(args, *q.CategoryID)
+		idx++
+	} else if q.CategoryID != nil {
+		base += fmt.Sprintf(" AND category_id = $%d", idx)
+		args = append(args, *q.CategoryID)
+		idx++
+	}
+	if len(q.IDs) > 0 {
+		ids := make(pq.StringArray, 0, len(q.IDs))
+		for _, id := range q.IDs {
+			ids = append(ids, id.String())
+		}
+		base += fmt.Sprintf(" AND id = ANY($%d::uuid[])", idx)
+		args = append(args, ids)
+		idx++
+	}
+	for _, f := range q.Attributes {
+		cond := fmt.Sprintf("pa.key = $%d", idx)
+		args = append(args, f.Key)
+		idx++
+		if len(f.Values) > 0 {
+			cond += fmt.Sprintf(" AND pa.value = ANY($%d)", idx)
+			args = append(args, pq.StringArray(f.Values))
+			idx++
+		}
+		if f.Min != nil {
+			cond += fmt.Sprintf(" AND pa.number_value >= $%d", idx)
+			args = append(args, *f.Min)
+			idx++
+		}
+		if f.Max != nil {
+			cond += fmt.Sprintf(" AND pa.number_value <= $%d", idx)
+			args = append(args, *f.Max)
+			idx++
+		}
+		base += fmt.Sprintf(" AND EXISTS (SELECT 1 FROM %s pa WHERE pa.product_id = %s.id AND %s)", ProductAttributeName, ProductName, cond)
+	}
+	if q.Channel != "" {
+		base += fmt.Sprintf(" AND published AND NOT EXISTS (SELECT 1 FROM %s pc WHERE pc.product_id = %s.id AND pc.channel = $%d AND NOT pc.enabled)", ProductChannelName, ProductName, idx)
+		args = append(args, q.Channel)
+		idx++
+	}
+	if q.Currency != "" {
+		base += fmt.Sprintf(` AND (currency = $%[3]d OR EXISTS (SELECT 1 FROM %[1]s e JOIN %[2]s l ON l.id = e.price_list_id
+			WHERE e.product_id = %[5]s.id AND l.currency = $%[3]d AND (l.customer_group IS NULL OR l.customer_group = $%[4]d)))`,
+			PriceListEntryName, PriceListName, idx, idx+1, ProductName)
+		args = append(args, q.Currency, q.CustomerGroup)
+		idx += 2
+	}
+	return base, args
+}
+
+// DeleteProduct soft deletes the product; it stays referenced by past orders.
+func (r *repository) DeleteProduct(ctx context.Context, id uuid.UUID) error {
+	res, err := r.db.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET deleted_at=NOW(), updated_at=NOW(), version=version+1 WHERE id=$1 AND deleted_at IS NULL`, ProductName), id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ProductErrorNotFound
+	}
+	return nil
+}
+
+// RestoreProduct undoes DeleteProduct; ProductErrorNotFound when the product is not deleted.
+func (r *repository) RestoreProduct(ctx context.Context, id uuid.UUID) error {
+	res, err := r.db.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET deleted_at=NULL, updated_at=NOW(), version=version+1 WHERE id=$1 AND deleted_at IS NOT NULL`, ProductName), id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ProductErrorNotFound
+	}
+	return nil
+}
+
+// DeleteCategory soft deletes the category unless live products or
+// subcategories still point at it.
+func (r *repository) DeleteCategory(ctx context.Context, id uuid.UUID) error {
+	res, err := r.db.ExecContext(ctx, fmt.Sprintf(`UPDATE %s c SET deleted_at=NOW(), updated_at=NOW(), version=version+1 WHERE id=$1 AND deleted_at IS NULL
+		AND NOT EXISTS (SELECT 1 FROM %s WHERE category_id=c.id AND deleted_at IS NULL)
+		AND NOT EXISTS (SELECT 1 FROM %s WHERE parent_id=c.id AND deleted_at IS NULL)`, CategoryName, ProductName, CategoryName), id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		if _, err := r.GetCategory(ctx, id); err != nil {
+			return err
+		}
+		return CategoryErrorNotEmpty
+	}
+	return nil
+}
+
+// RestoreCategory undoes DeleteCategory; CategoryErrorNotFound when the category is not deleted.
+func (r *repository) RestoreCategory(ctx context.Context, id uuid.UUID) error {
+	res, err := r.db.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET deleted_at=NULL, updated_at=NOW(), version=version+1 WHERE id=$1 AND deleted_at IS NOT NULL`, CategoryName), id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return CategoryErrorNotFound
+	}
+	return nil
+}
 
+// ProductChanges lists products updated after the (since, afterID) watermark
+// and before until, in watermark order.
+func (r *repository) ProductChanges(ctx context.Context, since time.Time, afterID uuid.UUID, until time.Time, limit int) ([]Product, error) {
+	query := fmt.Sprintf(`SELECT id,sku,name,description,category_id,price,currency,product_type,hs_code,country_of_origin,preorder,release_date,published,barcode,weight_grams,length_mm,width_mm,height_mm,shipping_class,created_at,updated_at,version,deleted_at FROM %s
+		WHERE (updated_at, id) > ($1, $2) AND updated_at < $3 ORDER BY updated_at, id LIMIT $4`, ProductName)
 	products := []Product{}
-	err := r.db.SelectContext(ctx, &products, base, args...)
+	err := r.read(ctx).SelectContext(ctx, &products, query, since, afterID, until, limit)
 	return products, err
 }
+
+// UpdatePrices sets every price in one transaction and returns the previous
+// prices; nothing changes when one of the products does not exist.
+func (r *repository) UpdatePrices(ctx context.Context, updates []PriceUpdate) (previous []PriceUpdate, err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	query := fmt.Sprintf(`UPDATE %s p SET price=$2, updated_at=NOW(), version=p.version+1
+		FROM (SELECT id, price FROM %s WHERE id=$1 AND deleted_at IS NULL FOR UPDATE) old
+		WHERE p.id=old.id RETURNING old.price`, ProductName, ProductName)
+	for _, u := range updates {
+		prev := PriceUpdate{ProductID: u.ProductID}
+		if err = tx.GetContext(ctx, &prev.Price, query, u.ProductID, u.Price); err != nil {
+			if err == sql.ErrNoRows {
+				err = fmt.Errorf("%w: %s", ProductErrorNotFound, u.ProductID)
+			}
+			return nil, err
+		}
+		previous = append(previous, prev)
+	}
+	return previous, tx.Commit()
+}
+
+// CurrentPrices implements Repository.
+func (r *repository) CurrentPrices(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]decimal.Decimal, error) {
+	rows := []struct {
+		ID    uuid.UUID       `db:"id"`
+		Price decimal.Decimal `db:"price"`
+	}{}
+	query := fmt.Sprintf(`SELECT id, price FROM %s WHERE id = ANY($1::uuid[]) AND deleted_at IS NULL`, ProductName)
+	if err := r.read(ctx).SelectContext(ctx, &rows, query, pq.Array(ids)); err != nil {
+		return nil, err
+	}
+	out := make(map[uuid.UUID]decimal.Decimal, len(rows))
+	for _, row := range rows {
+		out[row.ID] = row.Price
+	}
+	return out, nil
+}
+
+const productImageColumns = `id,product_id,original_key,content_type,status,url,thumbnail_url,medium_url,large_url,position,is_primary,created_at,updated_at`
+
+// CreateProductImage implements Repository.
+func (r *repository) CreateProductImage(ctx context.Context, img *ProductImage) error {
+	now := time.Now().UTC()
+	img.CreatedAt = now
+	img.UpdatedAt = now
+	// new images go last; the first image of a product becomes its primary
+	query := fmt.Sprintf(`INSERT INTO %[1]s (%[2]s) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,
+		(SELECT COALESCE(MAX(position)+1, 0) FROM %[1]s WHERE product_id=$2),
+		NOT EXISTS (SELECT 1 FROM %[1]s WHERE product_id=$2 AND is_primary),
+		$10,$11) RETURNING position, is_primary`, ProductImageName, productImageColumns)
+	return r.read(ctx).QueryRowxContext(ctx, query,
+		img.ID, img.ProductID, img.OriginalKey, img.ContentType, img.Status, img.URL,
+		img.ThumbnailURL, img.MediumURL, img.LargeURL, img.CreatedAt, img.UpdatedAt,
+	).Scan(&img.Position, &img.IsPrimary)
+}
+
+// GetProductImage implements Repository.
+func (r *repository) GetProductImage(ctx context.Context, id uuid.UUID) (*ProductImage, error) {
+	var img ProductImage
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE id=$1`, productImageColumns, ProductImageName)
+	err := r.read(ctx).GetContext(ctx, &img, query, id)
+	if err == sql.ErrNoRows {
+		return nil, ImageErrorNotFound
+	}
+	return &img, err
+}
+
+// UpdateProductImage implements Repository.
+func (r *repository) UpdateProductImage(ctx context.Context, img *ProductImage) error {
+	img.UpdatedAt = time.Now().UTC()
+	query := fmt.Sprintf(`UPDATE %s SET status=$1, thumbnail_url=$2, medium_url=$3, large_url=$4, updated_at=$5 WHERE id=$6`, ProductImageName)
+	_, err := r.db.ExecContext(ctx, query, img.Status, img.ThumbnailURL, img.MediumURL, img.LargeURL, img.UpdatedAt, img.ID)
+	return err
+}
+
+// ListProcessingImages implements Repository.
+func (r *repository) ListProcessingImages(ctx context.Context, olderThan time.Time, limit int) ([]ProductImage, error) {
+	images := []ProductImage{}
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE status='PROCESSING' AND created_at < $1 ORDER BY created_at LIMIT $2`, productImageColumns, ProductImageName)
+	err := r.read(ctx).SelectContext(ctx, &images, query, olderThan, limit)
+	return images, err
+}
+
+// ListProductImages returns the galleries of the given products, primary
+// image first, leaving out uploads that were never confirmed or failed.
+func (r *repository) ListProductImages(ctx context.Context, productIDs []uuid.UUID) ([]ProductImage, error) {
+	images := []ProductImage{}
+	if len(productIDs) == 0 {
+		return images, nil
+	}
+	query, args, err := sqlx.In(fmt.Sprintf(`SELECT %s FROM %s WHERE product_id IN (?) AND status IN ('PROCESSING','READY')
+		ORDER BY product_id, is_primary DESC, position, created_at`, productImageColumns, ProductImageName), productIDs)
+	if err != nil {
+		return nil, err
+	}
+	err = r.read(ctx).SelectContext(ctx, &images, r.db.Rebind(query), args...)
+	return images, err
+}
+
+// ReorderProductImages sets the gallery order; imageIDs must list every image
+// of the product exactly once.
+func (r *repository) ReorderProductImages(ctx context.Context, productID uuid.UUID, imageIDs []uuid.UUID) (err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	var current []uuid.UUID
+	if err = tx.SelectContext(ctx, &current, fmt.Sprintf(`SELECT id FROM %s WHERE product_id=$1 FOR UPDATE`, ProductImageName), productID); err != nil {
+		return err
+	}
+	listed := make(map[uuid.UUID]bool, len(imageIDs))
+	for _, id := range imageIDs {
+		listed[id] = true
+	}
+	if len(listed) != len(imageIDs) || len(current) != len(imageIDs) {
+		return ImageErrorOrderMismatch
+	}
+	for _, id := range current {
+		if !listed[id] {
+			return ImageErrorOrderMismatch
+		}
+	}
+	query := fmt.Sprintf(`UPDATE %s SET position=$1, updated_at=NOW() WHERE id=$2`, ProductImageName)
+	for i, id := range imageIDs {
+		if _, err = tx.ExecContext(ctx, query, i, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// SetPrimaryImage makes imageID the only primary image of the product.
+func (r *repository) SetPrimaryImage(ctx context.Context, productID, imageID uuid.UUID) error {
+	res, err := r.db.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET is_primary = (id=$2), updated_at=NOW()
+		WHERE product_id=$1 AND EXISTS (SELECT 1 FROM %s WHERE id=$2 AND product_id=$1)`, ProductImageName, ProductImageName), productID, imageID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ImageErrorNotFound
+	}
+	return nil
+}
+
+// DeleteProductImage removes the image and returns it so its files can be
+// deleted; when it was the primary image the next one in order takes over.
+func (r *repository) DeleteProductImage(ctx context.Context, productID, imageID uuid.UUID) (img *ProductImage, err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	img = &ProductImage{}
+	if err = tx.GetContext(ctx, img, fmt.Sprintf(`DELETE FROM %s WHERE id=$1 AND product_id=$2 RETURNING %s`, ProductImageName, productImageColumns), imageID, productID); err != nil {
+		if err == sql.ErrNoRows {
+			err = ImageErrorNotFound
+		}
+		return nil, err
+	}
+	if img.IsPrimary {
+		if _, err = tx.ExecContext(ctx, fmt.Sprintf(`UPDATE %[1]s SET is_primary=TRUE, updated_at=NOW() WHERE id =
+			(SELECT id FROM %[1]s WHERE product_id=$1 ORDER BY position, created_at LIMIT 1)`, ProductImageName), productID); err != nil {
+			return nil, err
+		}
+	}
+	return img, tx.Commit()
+}
+
+// CreateProductFile implements Repository.
+func (r *repository) CreateProductFile(ctx context.Context, f *ProductFile) error {
+	f.CreatedAt = time.Now().UTC()
+	query := fmt.Sprintf(`INSERT INTO %s (id,product_id,file_key,file_name,content_type,size_bytes,created_at) VALUES ($1,$2,$3,$4,$5,$6,$7)`, ProductFileName)
+	_, err := r.db.ExecContext(ctx, query, f.ID, f.ProductID, f.FileKey, f.FileName, f.ContentType, f.SizeBytes, f.CreatedAt)
+	return err
+}
+
+// ListProductFiles implements Repository.
+func (r *repository) ListProductFiles(ctx context.Context, productID uuid.UUID) ([]ProductFile, error) {
+	files := []ProductFile{}
+	query := fmt.Sprintf(`SELECT id,product_id,file_key,file_name,content_type,size_bytes,created_at FROM %s WHERE product_id=$1 ORDER BY created_at`, ProductFileName)
+	err := r.read(ctx).SelectContext(ctx, &files, query, productID)
+	return files, err
+}
+
+const productVariantColumns = `id,product_id,sku,name,options,price,created_at,updated_at,version`
+
+// CreateVariant implements Repository.
+func (r *repository) CreateVariant(ctx context.Context, v *ProductVariant) error {
+	v.ID = uuid.New()
+	v.CreatedAt = time.Now().UTC()
+	v.UpdatedAt = v.CreatedAt
+	v.Version = 1
+	query := fmt.Sprintf(`INSERT INTO %s (%s) VALUES (:id,:product_id,:sku,:name,:options,:price,:created_at,:updated_at,:version)`, ProductVariantName, productVariantColumns)
+	_, err := r.db.NamedExecContext(ctx, query, v)
+	return err
+}
+
+// GetVariant implements Repository.
+func (r *repository) GetVariant(ctx context.Context, productID, id uuid.UUID) (*ProductVariant, error) {
+	var v ProductVariant
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE id=$1 AND product_id=$2`, productVariantColumns, ProductVariantName)
+	if err := r.read(ctx).GetContext(ctx, &v, query, id, productID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, VariantErrorNotFound
+		}
+		return nil, err
+	}
+	return &v, nil
+}
+
+// ListVariants returns the variants of the given products, oldest first.
+func (r *repository) ListVariants(ctx context.Context, productIDs []uuid.UUID) ([]ProductVariant, error) {
+	variants := []ProductVariant{}
+	if len(productIDs) == 0 {
+		return variants, nil
+	}
+	query, args, err := sqlx.In(fmt.Sprintf(`SELECT %s FROM %s WHERE product_id IN (?) ORDER BY product_id, created_at, sku`, productVariantColumns, ProductVariantName), productIDs)
+	if err != nil {
+		return nil, err
+	}
+	err = r.read(ctx).SelectContext(ctx, &variants, r.db.Rebind(query), args...)
+	return variants, err
+}
+
+// UpdateVariant saves v if nobody changed it since v.Version was read.
+func (r *repository) UpdateVariant(ctx context.Context, v *ProductVariant) error {
+	v.UpdatedAt = time.Now().UTC()
+	query := fmt.Sprintf(`UPDATE %s SET sku=$1, name=$2, options=$3, price=$4, updated_at=$5, version=version+1
+		WHERE id=$6 AND product_id=$7 AND version=$8`, ProductVariantName)
+	res, err := r.db.ExecContext(ctx, query, v.SKU, v.Name, v.Options, v.Price, v.UpdatedAt, v.ID, v.ProductID, v.Version)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		if _, err := r.GetVariant(ctx, v.ProductID, v.ID); err != nil {
+			return err
+		}
+		return VariantErrorVersionConflict
+	}
+	v.Version++
+	return nil
+}
+
+// DeleteVariant removes the variant with its stock rows; past order items
+// keep their SKU and name.
+func (r *repository) DeleteVariant(ctx context.Context, productID, id uuid.UUID) error {
+	res, err := r.db.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE id=$1 AND product_id=$2`, ProductVariantName), id, productID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return VariantErrorNotFound
+	}
+	return nil
+}
+
+// SKUInUse reports whether a product or another variant already has sku;
+// marketplace and stock feeds address items by SKU, so it is unique across both.
+func (r *repository) SKUInUse(ctx context.Context, sku string, exceptID uuid.UUID) (bool, error) {
+	var exists bool
+	query := fmt.Sprintf(`SELECT EXISTS (SELECT 1 FROM %s WHERE sku=$1) OR EXISTS (SELECT 1 FROM %s WHERE sku=$1 AND id<>$2)`, ProductName, ProductVariantName)
+	err := r.read(ctx).GetContext(ctx, &exists, query, sku, exceptID)
+	return exists, err
+}
+
+const attributeDefinitionColumns = `id,category_id,key,label,type,enum_values,required,created_at,updated_at`
+
+// CreateAttributeDefinition implements Repository.
+func (r *repository) CreateAttributeDefinition(ctx context.Context, d *AttributeDefinition) error {
+	d.ID = uuid.New()
+	d.CreatedAt = time.Now().UTC()
+	d.UpdatedAt = d.CreatedAt
+	query := fmt.Sprintf(`INSERT INTO %s (%s) VALUES (:id,:category_id,:key,:label,:type,:enum_values,:required,:created_at,:updated_at)
+		ON CONFLICT (category_id, key) DO NOTHING`, AttributeDefinitionName, attributeDefinitionColumns)
+	res, err := r.db.NamedExecContext(ctx, query, d)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return AttributeErrorDuplicate
+	}
+	return nil
+}
+
+// ListAttributeDefinitions implements Repository.
+func (r *repository) ListAttributeDefinitions(ctx context.Context, categoryID uuid.UUID) ([]AttributeDefinition, error) {
+	defs := []AttributeDefinition{}
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE category_id=$1 ORDER BY key`, attributeDefinitionColumns, AttributeDefinitionName)
+	err := r.read(ctx).SelectContext(ctx, &defs, query, categoryID)
+	return defs, err
+}
+
+// DeleteAttributeDefinition drops the definition and the values the
+// category's products had for it.
+func (r *repository) DeleteAttributeDefinition(ctx context.Context, categoryID uuid.UUID, key string) (err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	res, err := tx.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE category_id=$1 AND key=$2`, AttributeDefinitionName), categoryID, key)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		err = AttributeErrorNotFound
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s pa USING %s p WHERE pa.product_id = p.id AND p.category_id=$1 AND pa.key=$2`, ProductAttributeName, ProductName), categoryID, key); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ReplaceProductAttributes sets attrs as the only attributes of the product.
+func (r *repository) ReplaceProductAttributes(ctx context.Context, productID uuid.UUID, attrs []ProductAttribute) (err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	if _, err = tx.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE product_id=$1`, ProductAttributeName), productID); err != nil {
+		return err
+	}
+	query := fmt.Sprintf(`INSERT INTO %s (product_id,key,type,value,number_value) VALUES (:product_id,:key,:type,:value,:number_value)`, ProductAttributeName)
+	for _, a := range attrs {
+		if _, err = tx.NamedExecContext(ctx, query, a); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ListProductAttributes implements Repository.
+func (r *repository) ListProductAttributes(ctx context.Context, productIDs []uuid.UUID) ([]ProductAttribute, error) {
+	attrs := []ProductAttribute{}
+	if len(productIDs) == 0 {
+		return attrs, nil
+	}
+	query, args, err := sqlx.In(fmt.Sprintf(`SELECT product_id,key,type,value,number_value FROM %s WHERE product_id IN (?)`, ProductAttributeName), productIDs)
+	if err != nil {
+		return nil, err
+	}
+	err = r.read(ctx).SelectContext(ctx, &attrs, r.db.Rebind(query), args...)
+	return attrs, err
+}
+
+// translationOwners names the owner column of each translation table
+var translationOwners = map[string]string{
+	ProductTranslationName:  "product_id",
+	CategoryTranslationName: "category_id",
+}
+
+// UpsertTranslation implements Repository.
+func (r *repository) UpsertTranslation(ctx context.Context, table string, t *Translation) error {
+	query := fmt.Sprintf(`INSERT INTO %[1]s (%[2]s,locale,name,description,slug,updated_at) VALUES ($1,$2,$3,$4,$5,NOW())
+		ON CONFLICT (%[2]s,locale) DO UPDATE SET name=EXCLUDED.name, description=EXCLUDED.description, slug=EXCLUDED.slug, updated_at=EXCLUDED.updated_at
+		RETURNING updated_at`, table, translationOwners[table])
+	err := r.read(ctx).GetContext(ctx, &t.UpdatedAt, query, t.OwnerID, t.Locale, t.Name, t.Description, t.Slug)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return TranslationErrorDuplicateSlug
+	}
+	return err
+}
+
+// ListTranslations returns the translations of the owners, all locales when
+// locales is empty
+func (r *repository) ListTranslations(ctx context.Context, table string, ownerIDs []uuid.UUID, locales []string) ([]Translation, error) {
+	out := []Translation{}
+	if len(ownerIDs) == 0 {
+		return out, nil
+	}
+	query := fmt.Sprintf(`SELECT %s AS owner_id,locale,name,description,slug,updated_at FROM %s WHERE %[1]s IN (?)`, translationOwners[table], table)
+	args := []interface{}{ownerIDs}
+	if len(locales) > 0 {
+		query += ` AND locale IN (?)`
+		args = append(args, locales)
+	}
+	query, args, err := sqlx.In(query+` ORDER BY locale`, args...)
+	if err != nil {
+		return nil, err
+	}
+	err = r.read(ctx).SelectContext(ctx, &out, r.db.Rebind(query), args...)
+	return out, err
+}
+
+// DeleteTranslation implements Repository.
+func (r *repository) DeleteTranslation(ctx context.Context, table string, ownerID uuid.UUID, locale string) error {
+	res, err := r.db.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE %s=$1 AND locale=$2`, table, translationOwners[table]), ownerID, locale)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return TranslationErrorNotFound
+	}
+	return nil
+}
+
+// GetProductCost returns the unit cost of a product that is not deleted.
+func (r *repository) GetProductCost(ctx context.Context, productID uuid.UUID) (*ProductCost, error) {
+	var c ProductCost
+	err := r.read(ctx).GetContext(ctx, &c, fmt.Sprintf(`SELECT id,cost FROM %s WHERE id=$1 AND deleted_at IS NULL`, ProductName), productID)
+	if err == sql.ErrNoRows {
+		return nil, ProductErrorNotFound
+	}
+	return &c, err
+}
+
+// SetProductCost changes the cost without touching the product version: it
+// is not part of what storefront clients edit.
+func (r *repository) SetProductCost(ctx context.Context, productID uuid.UUID, cost *decimal.Decimal) error {
+	res, err := r.db.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET cost=$1 WHERE id=$2 AND deleted_at IS NULL`, ProductName), cost, productID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ProductErrorNotFound
+	}
+	return nil
+}
+
+// ListProductChannels returns the channels the product was explicitly enabled or disabled for.
+func (r *repository) ListProductChannels(ctx context.Context, productID uuid.UUID) ([]ProductChannel, error) {
+	channels := []ProductChannel{}
+	err := r.read(ctx).SelectContext(ctx, &channels, fmt.Sprintf(`SELECT product_id,channel,enabled FROM %s WHERE product_id=$1`, ProductChannelName), productID)
+	return channels, err
+}
+
+// SetProductChannels upserts every setting in one transaction.
+func (r *repository) SetProductChannels(ctx context.Context, channels []ProductChannel) (err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	query := fmt.Sprintf(`INSERT INTO %s (product_id,channel,enabled,updated_at) VALUES ($1,$2,$3,NOW())
+		ON CONFLICT (product_id, channel) DO UPDATE SET enabled=EXCLUDED.enabled, updated_at=EXCLUDED.updated_at`, ProductChannelName)
+	for _, c := range channels {
+		if _, err = tx.ExecContext(ctx, query, c.ProductID, c.Channel, c.Enabled); err != nil {
+			return err
+		}
+	}
+	err = tx.Commit()
+	return err
+}
+
+// ProductVisible reports whether the product is published and not disabled
+// for channel.
+func (r *repository) ProductVisible(ctx context.Context, productID uuid.UUID, channel string) (bool, error) {
+	var visible bool
+	err := r.read(ctx).GetContext(ctx, &visible, fmt.Sprintf(`SELECT EXISTS (SELECT 1 FROM %s WHERE id=$1 AND published)
+		AND NOT EXISTS (SELECT 1 FROM %s WHERE product_id=$1 AND channel=$2 AND NOT enabled)`, ProductName, ProductChannelName), productID, channel)
+	return visible, err
+}
+
+const campaignColumns = `id,name,discount_percent,starts_at,ends_at,created_at,updated_at`
+
+// CreateCampaign stores the campaign and its targets in one transaction; an
+// unknown product or category fails the whole campaign.
+func (r *repository) CreateCampaign(ctx context.Context, c *Campaign) (err error) {
+	c.ID = uuid.New()
+	c.CreatedAt = time.Now().UTC()
+	c.UpdatedAt = c.CreatedAt
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	if _, err = tx.NamedExecContext(ctx, `INSERT INTO campaigns (`+campaignColumns+`) VALUES (:id,:name,:discount_percent,:starts_at,:ends_at,:created_at,:updated_at)`, c); err != nil {
+		return err
+	}
+	if err = insertTargets(ctx, tx, `INSERT INTO campaign_products (campaign_id, product_id) SELECT ?, id FROM products WHERE id IN (?) AND deleted_at IS NULL`, c.ID, c.ProductIDs, ProductErrorNotFound); err != nil {
+		return err
+	}
+	if err = insertTargets(ctx, tx, `INSERT INTO campaign_categories (campaign_id, category_id) SELECT ?, id FROM categories WHERE id IN (?) AND deleted_at IS NULL`, c.ID, c.CategoryIDs, CategoryErrorNotFound); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// insertTargets links the ids that exist and fails with notFound when some do not
+func insertTargets(ctx context.Context, tx *sqlx.Tx, query string, campaignID uuid.UUID, ids []uuid.UUID, notFound error) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	query, args, err := sqlx.In(query, campaignID, ids)
+	if err != nil {
+		return err
+	}
+	res, err := tx.ExecContext(ctx, tx.Rebind(query), args...)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if int(n) != len(ids) {
+		return notFound
+	}
+	return nil
+}
+
+// GetCampaign implements Repository.
+func (r *repository) GetCampaign(ctx context.Context, id uuid.UUID) (*Campaign, error) {
+	var c Campaign
+	if err := r.read(ctx).GetContext(ctx, &c, `SELECT `+campaignColumns+` FROM campaigns WHERE id=$1`, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, CampaignErrorNotFound
+		}
+		return nil, err
+	}
+	campaigns := []Campaign{c}
+	if err := r.loadTargets(ctx, campaigns); err != nil {
+		return nil, err
+	}
+	return &campaigns[0], nil
+}
+
+// ListCampaigns returns every campaign, latest start first.
+func (r *repository) ListCampaigns(ctx context.Context) ([]Campaign, error) {
+	campaigns := []Campaign{}
+	if err := r.read(ctx).SelectContext(ctx, &campaigns, `SELECT `+campaignColumns+` FROM campaigns ORDER BY starts_at DESC`); err != nil {
+		return nil, err
+	}
+	return campaigns, r.loadTargets(ctx, campaigns)
+}
+
+func (r *repository) loadTargets(ctx context.Context, campaigns []Campaign) error {
+	if len(campaigns) == 0 {
+		return nil
+	}
+	byID := make(map[uuid.UUID]*Campaign, len(campaigns))
+	ids := make([]uuid.UUID, 0, len(campaigns))
+	for i := range campaigns {
+		campaigns[i].ProductIDs = []uuid.UUID{}
+		campaigns[i].CategoryIDs = []uuid.UUID{}
+		byID[campaigns[i].ID] = &campaigns[i]
+		ids = append(ids, campaigns[i].ID)
+	}
+	var targets []struct {
+		CampaignID uuid.UUID `db:"campaign_id"`
+		TargetID   uuid.UUID `db:"target_id"`
+		Kind       string    `db:"kind"`
+	}
+	query, args, err := sqlx.In(`SELECT campaign_id, product_id AS target_id, 'product' AS kind FROM campaign_products WHERE campaign_id IN (?)
+		UNION ALL SELECT campaign_id, category_id, 'category' FROM campaign_categories WHERE campaign_id IN (?)`, ids, ids)
+	if err != nil {
+		return err
+	}
+	if err := r.read(ctx).SelectContext(ctx, &targets, r.db.Rebind(query), args...); err != nil {
+		return err
+	}
+	for _, t := range targets {
+		c := byID[t.CampaignID]
+		if t.Kind == "product" {
+			c.ProductIDs = append(c.ProductIDs, t.TargetID)
+		} else {
+			c.CategoryIDs = append(c.CategoryIDs, t.TargetID)
+		}
+	}
+	return nil
+}
+
+// EndCampaign moves the end of a running campaign to at; a campaign that has
+// not started yet gets an empty window and never runs.
+func (r *repository) EndCampaign(ctx context.Context, id uuid.UUID, at time.Time) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE campaigns SET ends_at=GREATEST($2, starts_at), updated_at=NOW() WHERE id=$1 AND ends_at > $2`, id, at)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		if _, err := r.GetCampaign(ctx, id); err != nil {
+			return err
+		}
+		return CampaignErrorEnded
+	}
+	return nil
+}
+
+const priceListColumns = `id,name,currency,customer_group,created_at,updated_at`
+
+// CreatePriceList implements Repository.
+func (r *repository) CreatePriceList(ctx context.Context, l *PriceList) error {
+	l.ID = uuid.New()
+	l.CreatedAt = time.Now().UTC()
+	l.UpdatedAt = l.CreatedAt
+	_, err := r.db.NamedExecContext(ctx, fmt.Sprintf(`INSERT INTO %s (%s) VALUES (:id,:name,:currency,:customer_group,:created_at,:updated_at)`, PriceListName, priceListColumns), l)
+	return err
+}
+
+// GetPriceList implements Repository.
+func (r *repository) GetPriceList(ctx context.Context, id uuid.UUID) (*PriceList, error) {
+	var l PriceList
+	err := r.read(ctx).GetContext(ctx, &l, fmt.Sprintf(`SELECT %s FROM %s WHERE id=$1`, priceListColumns, PriceListName), id)
+	if err == sql.ErrNoRows {
+		return nil, PriceListErrorNotFound
+	}
+	return &l, err
+}
+
+// FindPriceList returns the list of the currency for the group, or its
+// default list when customerGroup is nil.
+func (r *repository) FindPriceList(ctx context.Context, currency string, customerGroup *string) (*PriceList, error) {
+	var l PriceList
+	err := r.read(ctx).GetContext(ctx, &l, fmt.Sprintf(`SELECT %s FROM %s WHERE currency=$1 AND customer_group IS NOT DISTINCT FROM $2`, priceListColumns, PriceListName), currency, customerGroup)
+	if err == sql.ErrNoRows {
+		return nil, PriceListErrorNotFound
+	}
+	return &l, err
+}
+
+// ListPriceLists implements Repository.
+func (r *repository) ListPriceLists(ctx context.Context) ([]PriceList, error) {
+	lists := []PriceList{}
+	err := r.read(ctx).SelectContext(ctx, &lists, fmt.Sprintf(`SELECT %s FROM %s ORDER BY currency, customer_group NULLS FIRST`, priceListColumns, PriceListName))
+	return lists, err
+}
+
+// DeletePriceList removes the list with its entries
+func (r *repository) DeletePriceList(ctx context.Context, id uuid.UUID) error {
+	res, err := r.db.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE id=$1`, PriceListName), id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return PriceListErrorNotFound
+	}
+	return nil
+}
+
+// SetPriceListEntries upserts every entry in one transaction and fails
+// without changes when a product does not exist.
+func (r *repository) SetPriceListEntries(ctx context.Context, entries []PriceListEntry) (err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	query := fmt.Sprintf(`INSERT INTO %s (price_list_id,product_id,price,updated_at)
+		SELECT $1, id, $3, NOW() FROM %s WHERE id=$2 AND deleted_at IS NULL
+		ON CONFLICT (price_list_id, product_id) DO UPDATE SET price=EXCLUDED.price, updated_at=EXCLUDED.updated_at`, PriceListEntryName, ProductName)
+	for _, e := range entries {
+		res, err := tx.ExecContext(ctx, query, e.PriceListID, e.ProductID, e.Price)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return fmt.Errorf("%w: %s", ProductErrorNotFound, e.ProductID)
+		}
+	}
+	if _, err = tx.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET updated_at=NOW() WHERE id=$1`, PriceListName), entries[0].PriceListID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ListPriceListEntries implements Repository.
+func (r *repository) ListPriceListEntries(ctx context.Context, priceListID uuid.UUID) ([]PriceListEntry, error) {
+	entries := []PriceListEntry{}
+	err := r.read(ctx).SelectContext(ctx, &entries, fmt.Sprintf(`SELECT price_list_id,product_id,price FROM %s WHERE price_list_id=$1 ORDER BY product_id`, PriceListEntryName), priceListID)
+	return entries, err
+}
+
+// DeletePriceListEntry implements Repository.
+func (r *repository) DeletePriceListEntry(ctx context.Context, priceListID, productID uuid.UUID) error {
+	res, err := r.db.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE price_list_id=$1 AND product_id=$2`, PriceListEntryName), priceListID, productID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ProductErrorNotFound
+	}
+	return nil
+}
+
+// ListPrices returns the entries of the products in the default lists and
+// the lists of customerGroup, limited to currency unless it is empty.
+func (r *repository) ListPrices(ctx context.Context, productIDs []uuid.UUID, currency, customerGroup string) ([]ListPrice, error) {
+	out := []ListPrice{}
+	if len(productIDs) == 0 {
+		return out, nil
+	}
+	query := fmt.Sprintf(`SELECT e.price_list_id, e.product_id, l.currency, l.customer_group, e.price
+		FROM %s e JOIN %s l ON l.id = e.price_list_id
+		WHERE e.product_id IN (?) AND (l.customer_group IS NULL OR l.customer_group = ?)`, PriceListEntryName, PriceListName)
+	args := []interface{}{productIDs, customerGroup}
+	if currency != "" {
+		query += ` AND l.currency = ?`
+		args = append(args, currency)
+	}
+	query, args, err := sqlx.In(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	err = r.read(ctx).SelectContext(ctx, &out, r.db.Rebind(query), args...)
+	return out, err
+}
+
+// ActiveDiscounts returns the running discount of the given products that are on sale
+func (r *repository) ActiveDiscounts(ctx context.Context, productIDs []uuid.UUID) ([]Discount, error) {
+	out := []Discount{}
+	if len(productIDs) == 0 {
+		return out, nil
+	}
+	query, args, err := sqlx.In(`SELECT product_id, campaign_id, discount_percent FROM active_product_discounts WHERE product_id IN (?)`, productIDs)
+	if err != nil {
+		return nil, err
+	}
+	err = r.read(ctx).SelectContext(ctx, &out, r.db.Rebind(query), args...)
+	return out, err
+}
+
+// AddProductViews adds to the view counts in one statement; products that
+// don't exist (any more) are skipped
+func (r *repository) AddProductViews(ctx context.Context, views map[uuid.UUID]int64) error {
+	ids := make(pq.StringArray, 0, len(views))
+	counts := make(pq.Int64Array, 0, len(views))
+	for id, n := range views {
+		ids = append(ids, id.String())
+		counts = append(counts, n)
+	}
+	_, err := r.db.ExecContext(ctx, fmt.Sprintf(`INSERT INTO %[1]s (product_id,views,updated_at)
+		SELECT p.id, v.n, NOW() FROM unnest($1::uuid[], $2::bigint[]) AS v(id, n) JOIN %[2]s p ON p.id = v.id
+		ON CONFLICT (product_id) DO UPDATE SET views = %[1]s.views + EXCLUDED.views, updated_at = EXCLUDED.updated_at`, ProductViewName, ProductName), ids, counts)
+	return err
+}
+
+// SuggestProducts matches live product names containing prefix, those
+// starting with it first, then by views
+func (r *repository) SuggestProducts(ctx context.Context, prefix, channel string, limit int) ([]ProductSuggestion, error) {
+	escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(prefix)
+	out := []ProductSuggestion{}
+	err := r.read(ctx).SelectContext(ctx, &out, fmt.Sprintf(`SELECT p.id, p.sku, p.name FROM %[1]s p
+		LEFT JOIN %[2]s v ON v.product_id = p.id
+		WHERE p.deleted_at IS NULL AND p.name ILIKE $1
+		AND ($3 = '' OR p.published AND NOT EXISTS (SELECT 1 FROM %[3]s pc WHERE pc.product_id = p.id AND pc.channel = $3 AND NOT pc.enabled))
+		ORDER BY p.name ILIKE $2 DESC, COALESCE(v.views, 0) DESC, p.name
+		LIMIT $4`, ProductName, ProductViewName, ProductChannelName), "%"+escaped+"%", escaped+"%", channel, limit)
+	return out, err
+}
+
+const publicationColumns = `id,target_type,target_id,action,run_at,timezone,status,executed_at,created_at`
+
+// CreatePublication stores the schedule if its target exists
+func (r *repository) CreatePublication(ctx context.Context, p *PublicationSchedule) error {
+	p.ID = uuid.New()
+	p.Status = PublicationPending
+	p.CreatedAt = time.Now().UTC()
+	target, notFound := fmt.Sprintf(`SELECT 1 FROM %s WHERE id=$3 AND deleted_at IS NULL`, ProductName), ProductErrorNotFound
+	if p.TargetType == TargetCampaign {
+		target, notFound = fmt.Sprintf(`SELECT 1 FROM %s WHERE id=$3`, CampaignName), CampaignErrorNotFound
+	}
+	res, err := r.db.ExecContext(ctx, fmt.Sprintf(`INSERT INTO %s (%s) SELECT $1,$2,$3,$4,$5,$6,$7,NULL,$8 WHERE EXISTS (%s)`, PublicationScheduleName, publicationColumns, target),
+		p.ID, p.TargetType, p.TargetID, p.Action, p.RunAt, p.Timezone, p.Status, p.CreatedAt)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return notFound
+	}
+	return nil
+}
+
+// ListPublications implements Repository.
+func (r *repository) ListPublications(ctx context.Context, status string, targetID *uuid.UUID) ([]PublicationSchedule, error) {
+	out := []PublicationSchedule{}
+	err := r.read(ctx).SelectContext(ctx, &out, fmt.Sprintf(`SELECT %s FROM %s WHERE ($1 = '' OR status = $1) AND ($2::uuid IS NULL OR target_id = $2)
+		ORDER BY run_at, created_at`, publicationColumns, PublicationScheduleName), status, targetID)
+	return out, err
+}
+
+// CancelPublication implements Repository.
+func (r *repository) CancelPublication(ctx context.Context, id uuid.UUID) error {
+	res, err := r.db.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET status=$2 WHERE id=$1 AND status=$3`, PublicationScheduleName), id, PublicationCancelled, PublicationPending)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		var exists bool
+		if err := r.read(ctx).GetContext(ctx, &exists, fmt.Sprintf(`SELECT EXISTS (SELECT 1 FROM %s WHERE id=$1)`, PublicationScheduleName), id); err != nil {
+			return err
+		}
+		if !exists {
+			return ScheduleErrorNotFound
+		}
+		return ScheduleErrorNotPending
+	}
+	return nil
+}
+
+// RunDuePublications applies up to limit schedules due by now, in run_at
+// order, each with its target change in one transaction. Schedules locked
+// by a concurrent run are left to it.
+func (r *repository) RunDuePublications(ctx context.Context, now time.Time, limit int) (ran []PublicationSchedule, err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	if err = tx.SelectContext(ctx, &ran, fmt.Sprintf(`SELECT %s FROM %s WHERE status=$1 AND run_at <= $2 ORDER BY run_at, created_at LIMIT $3 FOR UPDATE SKIP LOCKED`,
+		publicationColumns, PublicationScheduleName), PublicationPending, now, limit); err != nil {
+		return nil, err
+	}
+	for i := range ran {
+		p := &ran[i]
+		var res sql.Result
+		switch {
+		case p.TargetType == TargetProduct:
+			res, err = tx.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET published=$2, updated_at=$3, version=version+1 WHERE id=$1 AND deleted_at IS NULL`, ProductName),
+				p.TargetID, p.Action == ActionPublish, now)
+		case p.Action == ActionPublish:
+			res, err = tx.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET starts_at=LEAST(starts_at, $2), updated_at=$2 WHERE id=$1 AND ends_at > $2`, CampaignName), p.TargetID, now)
+		default:
+			res, err = tx.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET ends_at=GREATEST($2, starts_at), updated_at=$2 WHERE id=$1 AND ends_at > $2`, CampaignName), p.TargetID, now)
+		}
+		if err != nil {
+			return nil, err
+		}
+		p.Status = PublicationDone
+		if n, _ := res.RowsAffected(); n == 0 {
+			p.Status = PublicationSkipped
+		}
+		p.ExecutedAt = &now
+		if _, err = tx.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET status=$2, executed_at=$3 WHERE id=$1`, PublicationScheduleName), p.ID, p.Status, now); err != nil {
+			return nil, err
+		}
+	}
+	err = tx.Commit()
+	return ran, err
+}
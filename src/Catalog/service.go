@@ -2,46 +2,192 @@ package Catalog
 
 import (
 	"context"
-	
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/shopspring/decimal"
 	"go.uber.org/zap"
+	"savannah/src/MergePatch"
+	"savannah/src/Storage"
 )
 
 type Service interface {
 	CreateCategory(ctx context.Context, dto CreateCategoryRequest) (*Category, error)
 	GetCategory(ctx context.Context, id uuid.UUID) (*Category, error)
+	CategoryPath(ctx context.Context, id uuid.UUID) ([]Category, error)
+	ListCategories(ctx context.Context, parentID *uuid.UUID, topLevel, nonEmpty bool) ([]Category, error)
+	SetCategoryTranslation(ctx context.Context, categoryID uuid.UUID, locale string, dto TranslationRequest) (*Translation, error)
+	ListCategoryTranslations(ctx context.Context, categoryID uuid.UUID) ([]Translation, error)
+	DeleteCategoryTranslation(ctx context.Context, categoryID uuid.UUID, locale string) error
+	SetProductTranslation(ctx context.Context, productID uuid.UUID, locale string, dto TranslationRequest) (*Translation, error)
+	ListProductTranslations(ctx context.Context, productID uuid.UUID) ([]Translation, error)
+	DeleteProductTranslation(ctx context.Context, productID uuid.UUID, locale string) error
+	// LocalizeProducts and LocalizeCategories apply translations in place,
+	// trying locales (see Locales) in order
+	LocalizeProducts(ctx context.Context, products []Product, locales []string) error
+	LocalizeCategories(ctx context.Context, categories []Category, locales []string) error
+	ReorderCategories(ctx context.Context, categoryIDs []uuid.UUID) ([]Category, error)
+	DeleteCategory(ctx context.Context, id uuid.UUID) error
+	RestoreCategory(ctx context.Context, id uuid.UUID) (*Category, error)
+	PatchCategory(ctx context.Context, id uuid.UUID, patch PatchCategoryRequest) (*Category, error)
 	CreateProduct(ctx context.Context, dto CreateProductRequest) (*Product, error)
-	GetProduct(ctx context.Context, id uuid.UUID) (*Product, error)
+	GetProduct(ctx context.Context, id uuid.UUID, pricing Pricing) (*Product, error)
+	ProductByBarcode(ctx context.Context, code string, pricing Pricing) (*Product, error)
 	ListProducts(ctx context.Context, q ListProductsQuery) ([]Product, error)
+	CountProducts(ctx context.Context, q ListProductsQuery) (int, error)
+	BatchGetProducts(ctx context.Context, q ListProductsQuery) (*BatchGetProductsResponse, error)
+	DeleteProduct(ctx context.Context, id uuid.UUID) error
+	RestoreProduct(ctx context.Context, id uuid.UUID) (*Product, error)
+	PatchProduct(ctx context.Context, id uuid.UUID, patch PatchProductRequest) (*Product, error)
+	ExportProducts(ctx context.Context, q ListProductsQuery, fn func([]Product) error) error
+	UpdatePrices(ctx context.Context, updates []PriceUpdate) ([]PriceUpdate, error)
+	// CurrentPrices returns the stored price of each of ids that is a live
+	// product, to preview a bulk price change
+	CurrentPrices(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]decimal.Decimal, error)
+	ProductChanges(ctx context.Context, since time.Time, afterID uuid.UUID, until time.Time, limit int) ([]Product, error)
+
+	UploadProductImage(ctx context.Context, productID uuid.UUID, contentType string, body io.Reader) (*ProductImage, error)
+	OpenProductImage(ctx context.Context, id uuid.UUID, size string) (io.ReadCloser, string, error)
+	RequestImageUpload(ctx context.Context, productID uuid.UUID, contentType string) (*ImageUpload, error)
+	CompleteImageUpload(ctx context.Context, productID, imageID uuid.UUID) (*ProductImage, error)
+	ListProductImages(ctx context.Context, productID uuid.UUID) ([]ProductImage, error)
+	ReorderProductImages(ctx context.Context, productID uuid.UUID, imageIDs []uuid.UUID) ([]ProductImage, error)
+	SetPrimaryImage(ctx context.Context, productID, imageID uuid.UUID) ([]ProductImage, error)
+	DeleteProductImage(ctx context.Context, productID, imageID uuid.UUID) error
+
+	AttachProductFile(ctx context.Context, productID uuid.UUID, fileName, contentType string, body io.Reader) (*ProductFile, error)
+	ListProductFiles(ctx context.Context, productID uuid.UUID) ([]ProductFile, error)
+
+	CreateVariant(ctx context.Context, productID uuid.UUID, dto CreateVariantRequest) (*ProductVariant, error)
+	GetVariant(ctx context.Context, productID, id uuid.UUID) (*ProductVariant, error)
+	ListVariants(ctx context.Context, productID uuid.UUID) ([]ProductVariant, error)
+	UpdateVariant(ctx context.Context, productID, id uuid.UUID, dto UpdateVariantRequest) (*ProductVariant, error)
+	DeleteVariant(ctx context.Context, productID, id uuid.UUID) error
+
+	DefineAttribute(ctx context.Context, categoryID uuid.UUID, dto CreateAttributeRequest) (*AttributeDefinition, error)
+	ListAttributes(ctx context.Context, categoryID uuid.UUID) ([]AttributeDefinition, error)
+	DeleteAttribute(ctx context.Context, categoryID uuid.UUID, key string) error
+	SetProductAttributes(ctx context.Context, productID uuid.UUID, values map[string]json.RawMessage) (map[string]interface{}, error)
+
+	ProductCost(ctx context.Context, productID uuid.UUID) (*ProductCost, error)
+	SetProductCost(ctx context.Context, productID uuid.UUID, cost *decimal.Decimal) (*ProductCost, error)
+
+	ProductChannels(ctx context.Context, productID uuid.UUID) (map[string]bool, error)
+	SetProductChannels(ctx context.Context, productID uuid.UUID, channels map[string]bool) (map[string]bool, error)
+	ProductVisible(ctx context.Context, productID uuid.UUID, channel string) (bool, error)
+
+	CreatePriceList(ctx context.Context, dto CreatePriceListRequest) (*PriceList, error)
+	GetPriceList(ctx context.Context, id uuid.UUID) (*PriceList, error)
+	ListPriceLists(ctx context.Context) ([]PriceList, error)
+	DeletePriceList(ctx context.Context, id uuid.UUID) error
+	SetPriceListEntries(ctx context.Context, priceListID uuid.UUID, entries []PriceListEntry) ([]PriceListEntry, error)
+	ListPriceListEntries(ctx context.Context, priceListID uuid.UUID) ([]PriceListEntry, error)
+	DeletePriceListEntry(ctx context.Context, priceListID, productID uuid.UUID) error
+
+	CreateCampaign(ctx context.Context, dto CreateCampaignRequest) (*Campaign, error)
+	GetCampaign(ctx context.Context, id uuid.UUID) (*Campaign, error)
+	ListCampaigns(ctx context.Context) ([]Campaign, error)
+	EndCampaign(ctx context.Context, id uuid.UUID) (*Campaign, error)
+
+	SchedulePublication(ctx context.Context, dto CreatePublicationRequest) (*PublicationSchedule, error)
+	ListPublications(ctx context.Context, status string, targetID *uuid.UUID) ([]PublicationSchedule, error)
+	CancelPublication(ctx context.Context, id uuid.UUID) error
+	RunPublications(ctx context.Context) error
+
+	RecordViews(views map[uuid.UUID]int64)
+	FlushViews(ctx context.Context) error
+	SuggestProducts(ctx context.Context, prefix, channel string, limit int) ([]ProductSuggestion, error)
+	ProductFacets(ctx context.Context, q ListProductsQuery, priceEdges []decimal.Decimal, locales []string) (*ProductFacets, error)
+}
+
+// ImageQueue schedules rendition generation for uploaded images
+type ImageQueue interface {
+	Enqueue(id uuid.UUID)
+}
+
+// uploadPresigner is implemented by stores clients can upload to directly (S3)
+type uploadPresigner interface {
+	PresignPut(key string, ttl time.Duration) (string, error)
 }
 
 type service struct {
 	repository Repository
+	blobs      BlobStore
+	images     ImageQueue
+	slugs      SlugRules
+	views      viewCounter
 	log        *zap.Logger
 }
 
 
 
-func NewService(r Repository, log *zap.Logger) Service {
-	return &service{repository: r, log: log}
+func NewService(r Repository, blobs BlobStore, images ImageQueue, slugs SlugRules, log *zap.Logger) Service {
+	return &service{repository: r, blobs: blobs, images: images, slugs: slugs, log: log}
 }
 
-// CreateCategory implements Service.
+// generatedSlugAttempts bounds retries when a concurrent create takes the
+// slug picked for a category
+const generatedSlugAttempts = 3
+
+// CreateCategory implements Service. A slug that is given must follow the
+// slug rules; one that is not is generated from the name.
 func (s *service) CreateCategory(ctx context.Context, dto CreateCategoryRequest) (*Category, error) {
+	if n := utf8.RuneCountInString(strings.TrimSpace(dto.Name)); n < 2 || n > 100 {
+		return nil, fmt.Errorf("%w: name must be 2 to 100 characters", CategoryErrorInvalidPayload)
+	}
 	category:=&Category{
 		Name: dto.Name,
 		Slug: dto.Slug,
 		Description: dto.Description,
 		ParentID: dto.ParentID,
 	}
-	if err:=s.repository.CreateCategory(ctx,category);err!=nil{
+	if dto.Slug != "" {
+		if !s.slugs.Valid(dto.Slug) {
+			return nil, s.invalidSlug()
+		}
+		if err:=s.repository.CreateCategory(ctx,category);err!=nil{
+			return nil,err
+		}
+		return category,nil
+	}
+	base := s.slugs.Make(dto.Name)
+	if len(base) < 2 {
+		base = s.slugs.fit([]string{"category", base}, s.slugs.MaxLength)
+	}
+	var err error
+	for attempt := 0; attempt < generatedSlugAttempts; attempt++ {
+		taken, lerr := s.repository.CategorySlugs(ctx, base, s.slugs.Separator)
+		if lerr != nil {
+			return nil, lerr
+		}
+		category.Slug = s.slugs.Unique(base, taken)
+		if err = s.repository.CreateCategory(ctx, category); !errors.Is(err, CategoryErrorDuplicateSlug) {
+			break
+		}
+	}
+	if err != nil {
 		s.log.Error("Create category",zap.Error(err))
 		return nil,err
 	}
 	return category,nil
 }
 
+func (s *service) invalidSlug() error {
+	return fmt.Errorf("%w: slug must be 2 to %d lowercase letters and digits in words joined by %q", CategoryErrorInvalidPayload, s.slugs.MaxLength, s.slugs.Separator)
+}
+
 // CreateProduct implements Service.
 func (s *service) CreateProduct(ctx context.Context, dto CreateProductRequest) (*Product, error) {
 	product:=&Product{
@@ -50,6 +196,33 @@ func (s *service) CreateProduct(ctx context.Context, dto CreateProductRequest) (
 		CategoryID:dto.CategoryID,
 		Price: dto.Price,
 		Currency: dto.Currency,
+		ProductType: dto.ProductType,
+		Preorder: dto.Preorder,
+		ReleaseDate: dto.ReleaseDate,
+		Published: dto.Published == nil || *dto.Published,
+	}
+	if product.ProductType == "" {
+		product.ProductType = ProductTypePhysical
+	}
+	if !validProductType(product.ProductType) {
+		return nil, productTypeError
+	}
+	var err error
+	if product.HSCode, err = customsField(normalizeHSCode, dto.HSCode); err != nil {
+		return nil, err
+	}
+	if product.CountryOfOrigin, err = customsField(normalizeCountry, dto.CountryOfOrigin); err != nil {
+		return nil, err
+	}
+	if product.Barcode, err = customsField(normalizeBarcode, dto.Barcode); err != nil {
+		return nil, err
+	}
+	product.WeightGrams, product.LengthMM, product.WidthMM, product.HeightMM = dto.WeightGrams, dto.LengthMM, dto.WidthMM, dto.HeightMM
+	if product.ShippingClass, err = customsField(normalizeShippingClass, dto.ShippingClass); err != nil {
+		return nil, err
+	}
+	if err := checkPackage(product); err != nil {
+		return nil, err
 	}
 	if err:=s.repository.CreateProduct(ctx,product);err != nil {
 		s.log.Error("Create Product")
@@ -63,9 +236,151 @@ func (s *service) GetCategory(ctx context.Context, id uuid.UUID) (*Category, err
 	return s.repository.GetCategory(ctx,id)
 }
 
-// GetProduct implements Service.
-func (s *service) GetProduct(ctx context.Context, id uuid.UUID) (*Product, error) {
-	return s.repository.GetProduct(ctx,id)
+// ListCategories lists live categories with their product counts; nonEmpty
+// leaves out those without a published product in their subtree.
+func (s *service) ListCategories(ctx context.Context, parentID *uuid.UUID, topLevel, nonEmpty bool) ([]Category, error) {
+	return s.repository.ListCategories(ctx, parentID, topLevel, nonEmpty)
+}
+
+// ReorderCategories sets the display order of siblings and returns them in
+// it.
+func (s *service) ReorderCategories(ctx context.Context, categoryIDs []uuid.UUID) ([]Category, error) {
+	parentID, err := s.repository.ReorderCategories(ctx, categoryIDs)
+	if err != nil {
+		return nil, err
+	}
+	return s.repository.ListCategories(ctx, parentID, parentID == nil, false)
+}
+
+// CategoryPath returns the ancestors of the category from the root down,
+// ending with the category itself.
+func (s *service) CategoryPath(ctx context.Context, id uuid.UUID) ([]Category, error) {
+	return s.repository.CategoryPath(ctx, id)
+}
+
+// DeleteCategory implements Service.
+func (s *service) DeleteCategory(ctx context.Context, id uuid.UUID) error {
+	return s.repository.DeleteCategory(ctx, id)
+}
+
+// RestoreCategory brings back a soft deleted category; restoring a live one is a no-op.
+func (s *service) RestoreCategory(ctx context.Context, id uuid.UUID) (*Category, error) {
+	if err := s.repository.RestoreCategory(ctx, id); err != nil && !errors.Is(err, CategoryErrorNotFound) {
+		return nil, err
+	}
+	return s.repository.GetCategory(ctx, id)
+}
+
+// PatchCategory applies a merge patch; a new parent must exist and must not
+// be the category itself or one of its subcategories.
+func (s *service) PatchCategory(ctx context.Context, id uuid.UUID, patch PatchCategoryRequest) (*Category, error) {
+	c, err := s.repository.GetCategory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if patch.Version.Set && (patch.Version.Null || patch.Version.Value != c.Version) {
+		return nil, CategoryErrorVersionConflict
+	}
+	if err := requireText(CategoryErrorInvalidPayload, "name", patch.Name, 2, 100); err != nil {
+		return nil, err
+	}
+	if err := requireText(CategoryErrorInvalidPayload, "slug", patch.Slug, 2, s.slugs.MaxLength); err != nil {
+		return nil, err
+	}
+	if patch.Slug.Set && patch.Slug.Value != c.Slug && !s.slugs.Valid(patch.Slug.Value) {
+		return nil, s.invalidSlug()
+	}
+	if patch.Name.Set {
+		c.Name = patch.Name.Value
+	}
+	if patch.Slug.Set {
+		c.Slug = patch.Slug.Value
+	}
+	if patch.Description.Set {
+		c.Description = patch.Description.Ptr()
+	}
+	if patch.ParentID.Set {
+		if !patch.ParentID.Null {
+			if err := s.checkParent(ctx, id, patch.ParentID.Value); err != nil {
+				return nil, err
+			}
+		}
+		c.ParentID = patch.ParentID.Ptr()
+	}
+	if err := s.repository.UpdateCategory(ctx, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// checkParent walks up from parent and refuses to close a loop back to id
+func (s *service) checkParent(ctx context.Context, id, parent uuid.UUID) error {
+	for next := &parent; next != nil; {
+		if *next == id {
+			return fmt.Errorf("%w: parent_id cannot be the category or one of its subcategories", CategoryErrorInvalidPayload)
+		}
+		p, err := s.repository.GetCategory(ctx, *next)
+		if errors.Is(err, CategoryErrorNotFound) {
+			return fmt.Errorf("%w: parent category %s not found", CategoryErrorInvalidPayload, *next)
+		}
+		if err != nil {
+			return err
+		}
+		next = p.ParentID
+	}
+	return nil
+}
+
+var productTypeError = fmt.Errorf("%w: product_type must be %s, %s or %s", ProductErrorInvalidPayload, ProductTypePhysical, ProductTypeDigital, ProductTypeService)
+
+func validProductType(t string) bool {
+	return t == ProductTypePhysical || t == ProductTypeDigital || t == ProductTypeService
+}
+
+// requireText refuses null and values outside min..max runes for a member
+// that may not be cleared
+func requireText(invalid error, name string, f MergePatch.Field[string], min, max int) error {
+	if !f.Set {
+		return nil
+	}
+	if f.Null {
+		return fmt.Errorf("%w: %s cannot be null", invalid, name)
+	}
+	if n := utf8.RuneCountInString(f.Value); n < min || n > max {
+		return fmt.Errorf("%w: %s must be %d to %d characters", invalid, name, min, max)
+	}
+	return nil
+}
+
+// ProductByBarcode looks a scanned GTIN up; an EAN-13 scan finds the product
+// stored with its UPC-A and the other way round.
+func (s *service) ProductByBarcode(ctx context.Context, code string, pricing Pricing) (*Product, error) {
+	code, err := normalizeBarcode(code)
+	if err != nil {
+		return nil, err
+	}
+	id, err := s.repository.ProductIDByBarcode(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetProduct(ctx, id, pricing)
+}
+
+// GetProduct returns the product priced as pricing asks, or
+// PriceListErrorNoPrice when it has no price in the requested currency.
+func (s *service) GetProduct(ctx context.Context, id uuid.UUID, pricing Pricing) (*Product, error) {
+	product, err := s.repository.GetProduct(ctx,id)
+	if err != nil {
+		return nil, err
+	}
+	products := []Product{*product}
+	if err := s.decorate(ctx, products, pricing); err != nil {
+		return nil, err
+	}
+	if pricing.Currency != "" && products[0].Currency != pricing.Currency {
+		return nil, PriceListErrorNoPrice
+	}
+	return &products[0], nil
 }
 
 // ListProducts implements Service.
@@ -73,5 +388,1109 @@ func (s *service) ListProducts(ctx context.Context, q ListProductsQuery) ([]Prod
 	if q.Limit<=0 || q.Limit>100 {
 		q.Limit=20		
 	}
-	return s.repository.ListProducts(ctx,q)
+	q.Attributes = canonicalAttributeValues(q.Attributes)
+	products, err := s.repository.ListProducts(ctx,q)
+	if err != nil {
+		return nil, err
+	}
+	return products, s.decorate(ctx, products, q.Pricing())
+}
+
+// CountProducts counts the products matching the list filters, ignoring
+// the page
+func (s *service) CountProducts(ctx context.Context, q ListProductsQuery) (int, error) {
+	q.Attributes = canonicalAttributeValues(q.Attributes)
+	return s.repository.CountProducts(ctx, q)
+}
+
+// maxBatchGet caps the ids of one batch get
+const maxBatchGet = 100
+
+// BatchGetProducts fetches the products in q.IDs with one query, applying
+// the other filters of q, and returns them in the order asked for.
+func (s *service) BatchGetProducts(ctx context.Context, q ListProductsQuery) (*BatchGetProductsResponse, error) {
+	ids := make([]uuid.UUID, 0, len(q.IDs))
+	seen := make(map[uuid.UUID]bool, len(q.IDs))
+	for _, id := range q.IDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 || len(ids) > maxBatchGet {
+		return nil, fmt.Errorf("%w: ids must list 1 to %d products", ProductErrorInvalidPayload, maxBatchGet)
+	}
+	q.IDs, q.Limit, q.Offset = ids, len(ids), 0
+	q.Attributes = canonicalAttributeValues(q.Attributes)
+	products, err := s.repository.ListProducts(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.decorate(ctx, products, q.Pricing()); err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]Product, len(products))
+	for _, p := range products {
+		byID[p.ID] = p
+	}
+	out := &BatchGetProductsResponse{Products: make([]Product, 0, len(products)), NotFound: []uuid.UUID{}}
+	for _, id := range ids {
+		if p, ok := byID[id]; ok {
+			out.Products = append(out.Products, p)
+		} else {
+			out.NotFound = append(out.NotFound, id)
+		}
+	}
+	return out, nil
+}
+
+// canonicalAttributeValues also matches numbers in the canonical form they
+// are stored in, so "1.50" finds "1.5"
+func canonicalAttributeValues(filters []AttributeFilter) []AttributeFilter {
+	for i, f := range filters {
+		values := f.Values
+		for _, v := range f.Values {
+			if d, err := decimal.NewFromString(v); err == nil && d.String() != v {
+				values = append(values, d.String())
+			}
+		}
+		filters[i].Values = values
+	}
+	return filters
+}
+
+// DeleteProduct implements Service.
+func (s *service) DeleteProduct(ctx context.Context, id uuid.UUID) error {
+	return s.repository.DeleteProduct(ctx, id)
+}
+
+// RestoreProduct brings back a soft deleted product; restoring a live one is a no-op.
+func (s *service) RestoreProduct(ctx context.Context, id uuid.UUID) (*Product, error) {
+	if err := s.repository.RestoreProduct(ctx, id); err != nil && !errors.Is(err, ProductErrorNotFound) {
+		return nil, err
+	}
+	return s.GetProduct(ctx, id, Pricing{})
+}
+
+// PatchProduct applies a merge patch. Only description is nullable; a new
+// category must exist.
+func (s *service) PatchProduct(ctx context.Context, id uuid.UUID, patch PatchProductRequest) (*Product, error) {
+	p, err := s.repository.GetProduct(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if patch.Version.Set && (patch.Version.Null || patch.Version.Value != p.Version) {
+		return nil, ProductErrorVersionConflict
+	}
+	if err := requireText(ProductErrorInvalidPayload, "name", patch.Name, 2, 100); err != nil {
+		return nil, err
+	}
+	if err := requireText(ProductErrorInvalidPayload, "currency", patch.Currency, 3, 3); err != nil {
+		return nil, err
+	}
+	if patch.ProductType.Set && !validProductType(patch.ProductType.Value) {
+		return nil, productTypeError
+	}
+	if patch.Price.Set && (patch.Price.Null || patch.Price.Value.IsNegative()) {
+		return nil, fmt.Errorf("%w: price must be zero or more", ProductErrorInvalidPayload)
+	}
+	if patch.CategoryID.Set {
+		if patch.CategoryID.Null {
+			return nil, fmt.Errorf("%w: category_id cannot be null", ProductErrorInvalidPayload)
+		}
+		if _, err := s.repository.GetCategory(ctx, patch.CategoryID.Value); err != nil {
+			if errors.Is(err, CategoryErrorNotFound) {
+				return nil, fmt.Errorf("%w: category %s not found", ProductErrorInvalidPayload, patch.CategoryID.Value)
+			}
+			return nil, err
+		}
+		p.CategoryID = patch.CategoryID.Ptr()
+	}
+	if patch.Name.Set {
+		p.Name = patch.Name.Value
+	}
+	if patch.Description.Set {
+		p.Description = patch.Description.Ptr()
+	}
+	if patch.Price.Set {
+		p.Price = patch.Price.Value
+	}
+	if patch.Currency.Set {
+		p.Currency = strings.ToUpper(patch.Currency.Value)
+	}
+	if patch.ProductType.Set {
+		p.ProductType = patch.ProductType.Value
+	}
+	if patch.HSCode.Set {
+		if p.HSCode, err = customsField(normalizeHSCode, patch.HSCode.Ptr()); err != nil {
+			return nil, err
+		}
+	}
+	if patch.CountryOfOrigin.Set {
+		if p.CountryOfOrigin, err = customsField(normalizeCountry, patch.CountryOfOrigin.Ptr()); err != nil {
+			return nil, err
+		}
+	}
+	if patch.Barcode.Set {
+		if p.Barcode, err = customsField(normalizeBarcode, patch.Barcode.Ptr()); err != nil {
+			return nil, err
+		}
+	}
+	for _, f := range []struct {
+		field MergePatch.Field[int]
+		dst   **int
+	}{{patch.WeightGrams, &p.WeightGrams}, {patch.LengthMM, &p.LengthMM}, {patch.WidthMM, &p.WidthMM}, {patch.HeightMM, &p.HeightMM}} {
+		if f.field.Set {
+			*f.dst = f.field.Ptr()
+		}
+	}
+	if patch.ShippingClass.Set {
+		if p.ShippingClass, err = customsField(normalizeShippingClass, patch.ShippingClass.Ptr()); err != nil {
+			return nil, err
+		}
+	}
+	if err := checkPackage(p); err != nil {
+		return nil, err
+	}
+	if patch.Preorder.Set {
+		if patch.Preorder.Null {
+			return nil, fmt.Errorf("%w: preorder may not be null", ProductErrorInvalidPayload)
+		}
+		p.Preorder = patch.Preorder.Value
+	}
+	if patch.ReleaseDate.Set {
+		p.ReleaseDate = patch.ReleaseDate.Ptr()
+	}
+	if patch.Published.Set {
+		if patch.Published.Null {
+			return nil, fmt.Errorf("%w: published may not be null", ProductErrorInvalidPayload)
+		}
+		p.Published = patch.Published.Value
+	}
+	if err := s.repository.UpdateProduct(ctx, p); err != nil {
+		return nil, err
+	}
+	return s.GetProduct(ctx, id, Pricing{})
+}
+
+// customsField normalizes an optional customs value; nil stays nil
+func customsField(normalize func(string) (string, error), v *string) (*string, error) {
+	if v == nil {
+		return nil, nil
+	}
+	n, err := normalize(*v)
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+// checkPackage requires a positive weight and dimensions, with the three
+// dimensions given together so the volume can be worked out
+func checkPackage(p *Product) error {
+	for _, v := range []*int{p.WeightGrams, p.LengthMM, p.WidthMM, p.HeightMM} {
+		if v != nil && *v <= 0 {
+			return fmt.Errorf("%w: weight_grams, length_mm, width_mm and height_mm must be positive", ProductErrorInvalidPayload)
+		}
+	}
+	if (p.LengthMM == nil) != (p.WidthMM == nil) || (p.WidthMM == nil) != (p.HeightMM == nil) {
+		return fmt.Errorf("%w: length_mm, width_mm and height_mm must be set together", ProductErrorInvalidPayload)
+	}
+	return nil
+}
+
+// normalizeShippingClass lowercases a shipping class, which is 1 to 32
+// letters, digits, dashes and underscores
+func normalizeShippingClass(class string) (string, error) {
+	class = strings.ToLower(strings.TrimSpace(class))
+	if len(class) == 0 || len(class) > 32 {
+		return "", fmt.Errorf("%w: shipping_class must be 1 to 32 letters, digits, dashes and underscores", ProductErrorInvalidPayload)
+	}
+	for _, r := range class {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return "", fmt.Errorf("%w: shipping_class must be 1 to 32 letters, digits, dashes and underscores", ProductErrorInvalidPayload)
+		}
+	}
+	return class, nil
+}
+
+// normalizeHSCode accepts an HS code written with or without the dots and
+// spaces of the tariff schedule ("6109.10.00") and stores its digits
+func normalizeHSCode(code string) (string, error) {
+	digits := strings.Map(func(r rune) rune {
+		if r == '.' || r == ' ' {
+			return -1
+		}
+		return r
+	}, code)
+	if len(digits) < 6 || len(digits) > 10 {
+		return "", fmt.Errorf("%w: hs_code must have 6 to 10 digits", ProductErrorInvalidPayload)
+	}
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return "", fmt.Errorf("%w: hs_code must have 6 to 10 digits", ProductErrorInvalidPayload)
+		}
+	}
+	return digits, nil
+}
+
+// normalizeBarcode accepts a GTIN with or without spaces and dashes and
+// stores its digits once the length and GS1 check digit are right
+func normalizeBarcode(code string) (string, error) {
+	digits := strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, code)
+	invalid := fmt.Errorf("%w: barcode must be an EAN-8, UPC-A, EAN-13 or GTIN-14 with a valid check digit", ProductErrorInvalidPayload)
+	switch len(digits) {
+	case 8, 12, 13, 14:
+	default:
+		return "", invalid
+	}
+	// weights alternate 3 and 1 from the digit left of the check digit
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if d < 0 || d > 9 {
+			return "", invalid
+		}
+		if i == len(digits)-1 {
+			continue
+		}
+		if (len(digits)-1-i)%2 == 1 {
+			d *= 3
+		}
+		sum += d
+	}
+	if (10-sum%10)%10 != int(digits[len(digits)-1]-'0') {
+		return "", invalid
+	}
+	return digits, nil
+}
+
+func normalizeCountry(country string) (string, error) {
+	country = strings.ToUpper(strings.TrimSpace(country))
+	if len(country) != 2 || country[0] < 'A' || country[0] > 'Z' || country[1] < 'A' || country[1] > 'Z' {
+		return "", fmt.Errorf("%w: country_of_origin must be an ISO 3166 alpha-2 code", ProductErrorInvalidPayload)
+	}
+	return country, nil
+}
+
+// exportBatchSize is how many products ExportProducts holds in memory at once
+const exportBatchSize = 500
+
+// ExportProducts walks every product matching q (Limit and Offset are
+// ignored) in id order, handing fn one batch at a time.
+func (s *service) ExportProducts(ctx context.Context, q ListProductsQuery, fn func([]Product) error) error {
+	q.Attributes = canonicalAttributeValues(q.Attributes)
+	after := uuid.Nil
+	for {
+		batch, err := s.repository.ProductsAfter(ctx, q, after, exportBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := s.applyPriceLists(ctx, batch, q.Pricing()); err != nil {
+			return err
+		}
+		if err := fn(batch); err != nil {
+			return err
+		}
+		if len(batch) < exportBatchSize {
+			return nil
+		}
+		after = batch[len(batch)-1].ID
+	}
+}
+
+// decorate adds what storefront responses show beyond the product row
+func (s *service) decorate(ctx context.Context, products []Product, pricing Pricing) error {
+	if err := s.attachVariants(ctx, products); err != nil {
+		return err
+	}
+	// campaigns discount the price the customer is shown
+	if err := s.applyPriceLists(ctx, products, pricing); err != nil {
+		return err
+	}
+	if err := s.applyDiscounts(ctx, products); err != nil {
+		return err
+	}
+	if err := s.attachAttributes(ctx, products); err != nil {
+		return err
+	}
+	return s.attachImages(ctx, products)
+}
+
+// attachAttributes embeds each product's attribute values
+func (s *service) attachAttributes(ctx context.Context, products []Product) error {
+	ids := make([]uuid.UUID, 0, len(products))
+	for _, p := range products {
+		ids = append(ids, p.ID)
+	}
+	attrs, err := s.repository.ListProductAttributes(ctx, ids)
+	if err != nil {
+		return err
+	}
+	byProduct := make(map[uuid.UUID]map[string]interface{}, len(products))
+	for _, a := range attrs {
+		if byProduct[a.ProductID] == nil {
+			byProduct[a.ProductID] = map[string]interface{}{}
+		}
+		byProduct[a.ProductID][a.Key] = a.Typed()
+	}
+	for i := range products {
+		products[i].Attributes = byProduct[products[i].ID]
+	}
+	return nil
+}
+
+// attachVariants embeds each product's variants
+func (s *service) attachVariants(ctx context.Context, products []Product) error {
+	ids := make([]uuid.UUID, 0, len(products))
+	for _, p := range products {
+		ids = append(ids, p.ID)
+	}
+	variants, err := s.repository.ListVariants(ctx, ids)
+	if err != nil {
+		return err
+	}
+	byProduct := make(map[uuid.UUID][]ProductVariant, len(products))
+	for _, v := range variants {
+		byProduct[v.ProductID] = append(byProduct[v.ProductID], v)
+	}
+	for i := range products {
+		products[i].Variants = byProduct[products[i].ID]
+	}
+	return nil
+}
+
+// attachImages embeds each product's gallery
+func (s *service) attachImages(ctx context.Context, products []Product) error {
+	ids := make([]uuid.UUID, 0, len(products))
+	for _, p := range products {
+		ids = append(ids, p.ID)
+	}
+	images, err := s.repository.ListProductImages(ctx, ids)
+	if err != nil {
+		return err
+	}
+	byProduct := make(map[uuid.UUID][]ProductImage, len(products))
+	for _, img := range images {
+		byProduct[img.ProductID] = append(byProduct[img.ProductID], img)
+	}
+	for i := range products {
+		products[i].Images = byProduct[products[i].ID]
+	}
+	return nil
+}
+
+// applyDiscounts sets the sale price of the products a campaign is running
+// for, and of their variants
+func (s *service) applyDiscounts(ctx context.Context, products []Product) error {
+	ids := make([]uuid.UUID, 0, len(products))
+	for _, p := range products {
+		ids = append(ids, p.ID)
+	}
+	discounts, err := s.repository.ActiveDiscounts(ctx, ids)
+	if err != nil {
+		return err
+	}
+	byID := make(map[uuid.UUID]Discount, len(discounts))
+	for _, d := range discounts {
+		byID[d.ProductID] = d
+	}
+	for i := range products {
+		if d, ok := byID[products[i].ID]; ok {
+			sale := d.SalePrice(products[i].Price)
+			products[i].SalePrice = &sale
+			products[i].CampaignID = &d.CampaignID
+			for j := range products[i].Variants {
+				v := &products[i].Variants[j]
+				price := products[i].Price
+				if v.Price != nil {
+					price = *v.Price
+				}
+				variantSale := d.SalePrice(price)
+				v.SalePrice = &variantSale
+			}
+		}
+	}
+	return nil
+}
+
+// applyPriceLists replaces the price of products that have an entry in a
+// list matching pricing; see Pricing for the order lists are tried in.
+func (s *service) applyPriceLists(ctx context.Context, products []Product, pricing Pricing) error {
+	if pricing.Currency == "" && pricing.CustomerGroup == "" {
+		return nil
+	}
+	ids := make([]uuid.UUID, 0, len(products))
+	for _, p := range products {
+		ids = append(ids, p.ID)
+	}
+	prices, err := s.repository.ListPrices(ctx, ids, pricing.Currency, pricing.CustomerGroup)
+	if err != nil {
+		return err
+	}
+	type key struct {
+		productID uuid.UUID
+		currency  string
+	}
+	best := make(map[key]ListPrice, len(prices))
+	for _, lp := range prices {
+		k := key{lp.ProductID, lp.Currency}
+		if cur, ok := best[k]; !ok || (cur.CustomerGroup == nil && lp.CustomerGroup != nil) {
+			best[k] = lp
+		}
+	}
+	for i := range products {
+		p := &products[i]
+		currency := pricing.Currency
+		if currency == "" {
+			currency = p.Currency
+		}
+		lp, ok := best[key{p.ID, currency}]
+		if !ok {
+			continue
+		}
+		p.Price, p.Currency, p.PriceListID = lp.Price, lp.Currency, &lp.PriceListID
+		// variant prices are in the product's own currency and list
+		for j := range p.Variants {
+			p.Variants[j].Price = nil
+		}
+	}
+	return nil
+}
+
+// ProductChanges implements Service.
+func (s *service) ProductChanges(ctx context.Context, since time.Time, afterID uuid.UUID, until time.Time, limit int) ([]Product, error) {
+	return s.repository.ProductChanges(ctx, since, afterID, until, limit)
+}
+
+// UpdatePrices changes several product prices at once and returns the previous prices.
+func (s *service) UpdatePrices(ctx context.Context, updates []PriceUpdate) ([]PriceUpdate, error) {
+	for _, u := range updates {
+		if u.Price.IsNegative() {
+			return nil, fmt.Errorf("%w: negative price for %s", ProductErrorInvalidPayload, u.ProductID)
+		}
+	}
+	previous, err := s.repository.UpdatePrices(ctx, updates)
+	if err != nil {
+		s.log.Error("Update prices", zap.Error(err))
+		return nil, err
+	}
+	return previous, nil
+}
+
+// CurrentPrices implements Service.
+func (s *service) CurrentPrices(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]decimal.Decimal, error) {
+	return s.repository.CurrentPrices(ctx, ids)
+}
+
+var imageExtensions = map[string]string{"image/jpeg": "jpg", "image/png": "png"}
+
+// UploadProductImage stores the original and queues rendition generation.
+func (s *service) UploadProductImage(ctx context.Context, productID uuid.UUID, contentType string, body io.Reader) (*ProductImage, error) {
+	ext, ok := imageExtensions[contentType]
+	if !ok {
+		return nil, ImageErrorUnsupported
+	}
+	if _, err := s.repository.GetProduct(ctx, productID); err != nil {
+		return nil, err
+	}
+	img := &ProductImage{ID: uuid.New(), ProductID: productID, ContentType: contentType, Status: "PROCESSING"}
+	img.OriginalKey = fmt.Sprintf("products/%s/%s/original.%s", productID, img.ID, ext)
+	if err := s.blobs.Put(ctx, img.OriginalKey, body, contentType); err != nil {
+		s.log.Error("store product image", zap.Error(err))
+		return nil, err
+	}
+	img.URL = s.blobs.URL(img.OriginalKey)
+	if err := s.repository.CreateProductImage(ctx, img); err != nil {
+		s.log.Error("create product image", zap.Error(err))
+		_ = s.blobs.Delete(ctx, img.OriginalKey)
+		return nil, err
+	}
+	s.images.Enqueue(img.ID)
+	return img, nil
+}
+
+// OpenProductImage returns the requested rendition, falling back to the
+// original while renditions are still being generated.
+func (s *service) OpenProductImage(ctx context.Context, id uuid.UUID, size string) (io.ReadCloser, string, error) {
+	img, err := s.repository.GetProductImage(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+	if img.Status == "READY" {
+		for _, r := range renditions {
+			if r.Name == size {
+				rc, err := s.blobs.Open(ctx, renditionKey(img, size))
+				return rc, "image/jpeg", err
+			}
+		}
+	}
+	rc, err := s.blobs.Open(ctx, img.OriginalKey)
+	return rc, img.ContentType, err
+}
+
+const imageUploadTTL = 15 * time.Minute
+
+// RequestImageUpload reserves an image for a direct upload to storage; it
+// shows in the gallery once CompleteImageUpload confirms the upload.
+func (s *service) RequestImageUpload(ctx context.Context, productID uuid.UUID, contentType string) (*ImageUpload, error) {
+	presigner, ok := s.blobs.(uploadPresigner)
+	if !ok {
+		return nil, ImageErrorSignedUpload
+	}
+	ext, ok := imageExtensions[contentType]
+	if !ok {
+		return nil, ImageErrorUnsupported
+	}
+	if _, err := s.repository.GetProduct(ctx, productID); err != nil {
+		return nil, err
+	}
+	img := &ProductImage{ID: uuid.New(), ProductID: productID, ContentType: contentType, Status: "UPLOADING"}
+	img.OriginalKey = fmt.Sprintf("products/%s/%s/original.%s", productID, img.ID, ext)
+	img.URL = s.blobs.URL(img.OriginalKey)
+	uploadURL, err := presigner.PresignPut(img.OriginalKey, imageUploadTTL)
+	if err != nil {
+		s.log.Error("presign image upload", zap.Error(err))
+		return nil, err
+	}
+	if err := s.repository.CreateProductImage(ctx, img); err != nil {
+		s.log.Error("create product image", zap.Error(err))
+		return nil, err
+	}
+	return &ImageUpload{Image: img, UploadURL: uploadURL, Method: http.MethodPut, ExpiresAt: time.Now().UTC().Add(imageUploadTTL)}, nil
+}
+
+// CompleteImageUpload checks that the uploaded object is the announced image
+// type and queues rendition generation. Completing twice is harmless.
+func (s *service) CompleteImageUpload(ctx context.Context, productID, imageID uuid.UUID) (*ProductImage, error) {
+	img, err := s.repository.GetProductImage(ctx, imageID)
+	if err != nil {
+		return nil, err
+	}
+	if img.ProductID != productID {
+		return nil, ImageErrorNotFound
+	}
+	if img.Status != "UPLOADING" {
+		return img, nil
+	}
+	rc, err := s.blobs.Open(ctx, img.OriginalKey)
+	if err != nil {
+		if errors.Is(err, Storage.ErrBlobNotFound) {
+			return nil, ImageErrorNotUploaded
+		}
+		return nil, err
+	}
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(rc, head)
+	rc.Close()
+	if http.DetectContentType(head[:n]) != img.ContentType {
+		return nil, ImageErrorUnsupported
+	}
+	img.Status = "PROCESSING"
+	if err := s.repository.UpdateProductImage(ctx, img); err != nil {
+		return nil, err
+	}
+	s.images.Enqueue(img.ID)
+	return img, nil
+}
+
+// ListProductImages returns the gallery of one product
+func (s *service) ListProductImages(ctx context.Context, productID uuid.UUID) ([]ProductImage, error) {
+	if _, err := s.repository.GetProduct(ctx, productID); err != nil {
+		return nil, err
+	}
+	return s.repository.ListProductImages(ctx, []uuid.UUID{productID})
+}
+
+// ReorderProductImages implements Service.
+func (s *service) ReorderProductImages(ctx context.Context, productID uuid.UUID, imageIDs []uuid.UUID) ([]ProductImage, error) {
+	if err := s.repository.ReorderProductImages(ctx, productID, imageIDs); err != nil {
+		return nil, err
+	}
+	return s.repository.ListProductImages(ctx, []uuid.UUID{productID})
+}
+
+// SetPrimaryImage implements Service.
+func (s *service) SetPrimaryImage(ctx context.Context, productID, imageID uuid.UUID) ([]ProductImage, error) {
+	if err := s.repository.SetPrimaryImage(ctx, productID, imageID); err != nil {
+		return nil, err
+	}
+	return s.repository.ListProductImages(ctx, []uuid.UUID{productID})
+}
+
+// DeleteProductImage removes the image and then its stored files; files that
+// cannot be deleted are only logged, the image is already gone.
+func (s *service) DeleteProductImage(ctx context.Context, productID, imageID uuid.UUID) error {
+	img, err := s.repository.DeleteProductImage(ctx, productID, imageID)
+	if err != nil {
+		return err
+	}
+	keys := []string{img.OriginalKey}
+	for _, r := range renditions {
+		keys = append(keys, renditionKey(img, r.Name))
+	}
+	for _, key := range keys {
+		if err := s.blobs.Delete(ctx, key); err != nil && !errors.Is(err, Storage.ErrBlobNotFound) {
+			s.log.Warn("delete image file", zap.String("key", key), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// countingReader tracks how many bytes were streamed to storage
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// AttachProductFile stores a downloadable file for a digital product.
+func (s *service) AttachProductFile(ctx context.Context, productID uuid.UUID, fileName, contentType string, body io.Reader) (*ProductFile, error) {
+	p, err := s.repository.GetProduct(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	if p.ProductType != ProductTypeDigital {
+		return nil, ProductErrorNotDigital
+	}
+	f := &ProductFile{ID: uuid.New(), ProductID: productID, FileName: path.Base(fileName), ContentType: contentType}
+	f.FileKey = fmt.Sprintf("downloads/%s/%s/%s", productID, f.ID, f.FileName)
+	counter := &countingReader{r: body}
+	if err := s.blobs.Put(ctx, f.FileKey, counter, contentType); err != nil {
+		s.log.Error("store product file", zap.Error(err))
+		return nil, err
+	}
+	f.SizeBytes = counter.n
+	if err := s.repository.CreateProductFile(ctx, f); err != nil {
+		s.log.Error("create product file", zap.Error(err))
+		_ = s.blobs.Delete(ctx, f.FileKey)
+		return nil, err
+	}
+	return f, nil
+}
+
+// ListProductFiles implements Service.
+func (s *service) ListProductFiles(ctx context.Context, productID uuid.UUID) ([]ProductFile, error) {
+	return s.repository.ListProductFiles(ctx, productID)
+}
+
+var currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// CreatePriceList adds a list for a currency and, optionally, a customer
+// group; each pair has at most one list.
+func (s *service) CreatePriceList(ctx context.Context, dto CreatePriceListRequest) (*PriceList, error) {
+	l := &PriceList{Name: strings.TrimSpace(dto.Name), Currency: strings.ToUpper(dto.Currency)}
+	if n := utf8.RuneCountInString(l.Name); n < 2 || n > 100 {
+		return nil, fmt.Errorf("%w: name must be 2 to 100 characters", PriceListErrorInvalidPayload)
+	}
+	if !currencyCode.MatchString(l.Currency) {
+		return nil, fmt.Errorf("%w: currency must be an ISO 4217 code", PriceListErrorInvalidPayload)
+	}
+	if dto.CustomerGroup != nil {
+		if group := strings.TrimSpace(*dto.CustomerGroup); group != "" {
+			if len(group) > 64 {
+				return nil, fmt.Errorf("%w: customer_group is at most 64 characters", PriceListErrorInvalidPayload)
+			}
+			l.CustomerGroup = &group
+		}
+	}
+	if _, err := s.repository.FindPriceList(ctx, l.Currency, l.CustomerGroup); err == nil {
+		return nil, PriceListErrorDuplicate
+	} else if !errors.Is(err, PriceListErrorNotFound) {
+		return nil, err
+	}
+	if err := s.repository.CreatePriceList(ctx, l); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// GetPriceList implements Service.
+func (s *service) GetPriceList(ctx context.Context, id uuid.UUID) (*PriceList, error) {
+	return s.repository.GetPriceList(ctx, id)
+}
+
+// ListPriceLists implements Service.
+func (s *service) ListPriceLists(ctx context.Context) ([]PriceList, error) {
+	return s.repository.ListPriceLists(ctx)
+}
+
+// DeletePriceList implements Service.
+func (s *service) DeletePriceList(ctx context.Context, id uuid.UUID) error {
+	return s.repository.DeletePriceList(ctx, id)
+}
+
+// SetPriceListEntries adds or changes the listed prices and returns every
+// entry of the list; prices not listed are kept.
+func (s *service) SetPriceListEntries(ctx context.Context, priceListID uuid.UUID, entries []PriceListEntry) ([]PriceListEntry, error) {
+	if _, err := s.repository.GetPriceList(ctx, priceListID); err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("%w: at least one price required", PriceListErrorInvalidPayload)
+	}
+	seen := make(map[uuid.UUID]bool, len(entries))
+	for i, e := range entries {
+		if e.ProductID == uuid.Nil || seen[e.ProductID] {
+			return nil, fmt.Errorf("%w: each entry needs a distinct product_id", PriceListErrorInvalidPayload)
+		}
+		if e.Price.IsNegative() {
+			return nil, fmt.Errorf("%w: negative price for %s", PriceListErrorInvalidPayload, e.ProductID)
+		}
+		seen[e.ProductID] = true
+		entries[i].PriceListID = priceListID
+	}
+	if err := s.repository.SetPriceListEntries(ctx, entries); err != nil {
+		return nil, err
+	}
+	return s.repository.ListPriceListEntries(ctx, priceListID)
+}
+
+// ListPriceListEntries implements Service.
+func (s *service) ListPriceListEntries(ctx context.Context, priceListID uuid.UUID) ([]PriceListEntry, error) {
+	if _, err := s.repository.GetPriceList(ctx, priceListID); err != nil {
+		return nil, err
+	}
+	return s.repository.ListPriceListEntries(ctx, priceListID)
+}
+
+// DeletePriceListEntry implements Service.
+func (s *service) DeletePriceListEntry(ctx context.Context, priceListID, productID uuid.UUID) error {
+	if _, err := s.repository.GetPriceList(ctx, priceListID); err != nil {
+		return err
+	}
+	return s.repository.DeletePriceListEntry(ctx, priceListID, productID)
+}
+
+// CreateCampaign schedules a discount; it starts and ends on its own.
+func (s *service) CreateCampaign(ctx context.Context, dto CreateCampaignRequest) (*Campaign, error) {
+	if !dto.DiscountPercent.IsPositive() || dto.DiscountPercent.GreaterThan(decimal.NewFromInt(100)) {
+		return nil, fmt.Errorf("%w: discount_percent must be above 0 and at most 100", CampaignErrorInvalidPayload)
+	}
+	if !dto.EndsAt.After(dto.StartsAt) || !dto.EndsAt.After(time.Now()) {
+		return nil, fmt.Errorf("%w: ends_at must be after starts_at and in the future", CampaignErrorInvalidPayload)
+	}
+	c := &Campaign{
+		Name:            dto.Name,
+		DiscountPercent: dto.DiscountPercent,
+		StartsAt:        dto.StartsAt.UTC(),
+		EndsAt:          dto.EndsAt.UTC(),
+		ProductIDs:      unique(dto.ProductIDs),
+		CategoryIDs:     unique(dto.CategoryIDs),
+	}
+	if len(c.ProductIDs) == 0 && len(c.CategoryIDs) == 0 {
+		return nil, fmt.Errorf("%w: product_ids or category_ids required", CampaignErrorInvalidPayload)
+	}
+	if err := s.repository.CreateCampaign(ctx, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func unique(ids []uuid.UUID) []uuid.UUID {
+	seen := make(map[uuid.UUID]bool, len(ids))
+	out := []uuid.UUID{}
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// GetCampaign implements Service.
+func (s *service) GetCampaign(ctx context.Context, id uuid.UUID) (*Campaign, error) {
+	return s.repository.GetCampaign(ctx, id)
+}
+
+// ListCampaigns implements Service.
+func (s *service) ListCampaigns(ctx context.Context) ([]Campaign, error) {
+	return s.repository.ListCampaigns(ctx)
+}
+
+// EndCampaign stops a campaign now; prices revert immediately.
+func (s *service) EndCampaign(ctx context.Context, id uuid.UUID) (*Campaign, error) {
+	if err := s.repository.EndCampaign(ctx, id, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	return s.repository.GetCampaign(ctx, id)
+}
+
+// validateVariant checks a variant payload and that its SKU is free
+func (s *service) validateVariant(ctx context.Context, dto CreateVariantRequest, exceptID uuid.UUID) error {
+	if dto.SKU == "" || len(dto.SKU) > 64 || dto.Name == "" || len(dto.Name) > 255 {
+		return fmt.Errorf("%w: sku (max 64) and name (max 255) are required", VariantErrorInvalidPayload)
+	}
+	if len(dto.Options) == 0 {
+		return fmt.Errorf("%w: options required, e.g. {\"size\": \"M\"}", VariantErrorInvalidPayload)
+	}
+	if dto.Price != nil && dto.Price.IsNegative() {
+		return fmt.Errorf("%w: negative price", VariantErrorInvalidPayload)
+	}
+	taken, err := s.repository.SKUInUse(ctx, dto.SKU, exceptID)
+	if err != nil {
+		return err
+	}
+	if taken {
+		return VariantErrorDuplicateSKU
+	}
+	return nil
+}
+
+// CreateVariant implements Service.
+func (s *service) CreateVariant(ctx context.Context, productID uuid.UUID, dto CreateVariantRequest) (*ProductVariant, error) {
+	if _, err := s.repository.GetProduct(ctx, productID); err != nil {
+		return nil, err
+	}
+	if err := s.validateVariant(ctx, dto, uuid.Nil); err != nil {
+		return nil, err
+	}
+	options, err := json.Marshal(dto.Options)
+	if err != nil {
+		return nil, err
+	}
+	v := &ProductVariant{ProductID: productID, SKU: dto.SKU, Name: dto.Name, Options: options, Price: dto.Price}
+	if err := s.repository.CreateVariant(ctx, v); err != nil {
+		s.log.Error("create variant", zap.Error(err))
+		return nil, err
+	}
+	return v, nil
+}
+
+// GetVariant implements Service.
+func (s *service) GetVariant(ctx context.Context, productID, id uuid.UUID) (*ProductVariant, error) {
+	return s.repository.GetVariant(ctx, productID, id)
+}
+
+// ListVariants implements Service.
+func (s *service) ListVariants(ctx context.Context, productID uuid.UUID) ([]ProductVariant, error) {
+	if _, err := s.repository.GetProduct(ctx, productID); err != nil {
+		return nil, err
+	}
+	return s.repository.ListVariants(ctx, []uuid.UUID{productID})
+}
+
+// UpdateVariant implements Service.
+func (s *service) UpdateVariant(ctx context.Context, productID, id uuid.UUID, dto UpdateVariantRequest) (*ProductVariant, error) {
+	v, err := s.repository.GetVariant(ctx, productID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validateVariant(ctx, dto.CreateVariantRequest, id); err != nil {
+		return nil, err
+	}
+	options, err := json.Marshal(dto.Options)
+	if err != nil {
+		return nil, err
+	}
+	v.SKU, v.Name, v.Options, v.Price, v.Version = dto.SKU, dto.Name, options, dto.Price, dto.Version
+	if err := s.repository.UpdateVariant(ctx, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// DeleteVariant implements Service.
+func (s *service) DeleteVariant(ctx context.Context, productID, id uuid.UUID) error {
+	return s.repository.DeleteVariant(ctx, productID, id)
+}
+
+// ValidChannel reports whether channel is one of Channels
+func ValidChannel(channel string) bool {
+	for _, c := range Channels {
+		if c == channel {
+			return true
+		}
+	}
+	return false
+}
+
+// ProductCost implements Service.
+func (s *service) ProductCost(ctx context.Context, productID uuid.UUID) (*ProductCost, error) {
+	return s.repository.GetProductCost(ctx, productID)
+}
+
+// SetProductCost sets or, with nil, clears the unit cost. Orders already
+// placed keep the cost they were placed with.
+func (s *service) SetProductCost(ctx context.Context, productID uuid.UUID, cost *decimal.Decimal) (*ProductCost, error) {
+	if cost != nil && cost.IsNegative() {
+		return nil, fmt.Errorf("%w: negative cost", ProductErrorInvalidPayload)
+	}
+	if err := s.repository.SetProductCost(ctx, productID, cost); err != nil {
+		return nil, err
+	}
+	return &ProductCost{ProductID: productID, Cost: cost}, nil
+}
+
+// ProductChannels returns whether the product is enabled in each sales channel
+func (s *service) ProductChannels(ctx context.Context, productID uuid.UUID) (map[string]bool, error) {
+	if _, err := s.repository.GetProduct(ctx, productID); err != nil {
+		return nil, err
+	}
+	rows, err := s.repository.ListProductChannels(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	channels := make(map[string]bool, len(Channels))
+	for _, c := range Channels {
+		channels[c] = true
+	}
+	for _, row := range rows {
+		channels[row.Channel] = row.Enabled
+	}
+	return channels, nil
+}
+
+// SetProductChannels enables or disables the product in the given channels
+func (s *service) SetProductChannels(ctx context.Context, productID uuid.UUID, channels map[string]bool) (map[string]bool, error) {
+	if _, err := s.repository.GetProduct(ctx, productID); err != nil {
+		return nil, err
+	}
+	rows := make([]ProductChannel, 0, len(channels))
+	for channel, enabled := range channels {
+		if !ValidChannel(channel) {
+			return nil, fmt.Errorf("%w: %s", ChannelErrorUnknown, channel)
+		}
+		rows = append(rows, ProductChannel{ProductID: productID, Channel: channel, Enabled: enabled})
+	}
+	if err := s.repository.SetProductChannels(ctx, rows); err != nil {
+		return nil, err
+	}
+	return s.ProductChannels(ctx, productID)
+}
+
+// ProductVisible implements Service.
+func (s *service) ProductVisible(ctx context.Context, productID uuid.UUID, channel string) (bool, error) {
+	return s.repository.ProductVisible(ctx, productID, channel)
+}
+
+var attributeKey = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// DefineAttribute adds an attribute to the category's schema
+func (s *service) DefineAttribute(ctx context.Context, categoryID uuid.UUID, dto CreateAttributeRequest) (*AttributeDefinition, error) {
+	if !attributeKey.MatchString(dto.Key) {
+		return nil, fmt.Errorf("%w: key must be lower-case letters, digits and underscores (max 64)", AttributeErrorInvalidPayload)
+	}
+	if dto.Label == "" || len(dto.Label) > 100 {
+		return nil, fmt.Errorf("%w: label required (max 100)", AttributeErrorInvalidPayload)
+	}
+	switch dto.Type {
+	case AttributeString, AttributeNumber, AttributeBool:
+		if len(dto.EnumValues) > 0 {
+			return nil, fmt.Errorf("%w: enum_values only apply to enum attributes", AttributeErrorInvalidPayload)
+		}
+	case AttributeEnum:
+		if len(dto.EnumValues) == 0 {
+			return nil, fmt.Errorf("%w: enum attributes need enum_values", AttributeErrorInvalidPayload)
+		}
+	default:
+		return nil, fmt.Errorf("%w: type must be string, number, bool or enum", AttributeErrorInvalidPayload)
+	}
+	if _, err := s.repository.GetCategory(ctx, categoryID); err != nil {
+		return nil, err
+	}
+	d := &AttributeDefinition{CategoryID: categoryID, Key: dto.Key, Label: dto.Label, Type: dto.Type, EnumValues: pq.StringArray(dto.EnumValues), Required: dto.Required}
+	if d.EnumValues == nil {
+		d.EnumValues = pq.StringArray{}
+	}
+	if err := s.repository.CreateAttributeDefinition(ctx, d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// ListAttributes implements Service.
+func (s *service) ListAttributes(ctx context.Context, categoryID uuid.UUID) ([]AttributeDefinition, error) {
+	if _, err := s.repository.GetCategory(ctx, categoryID); err != nil {
+		return nil, err
+	}
+	return s.repository.ListAttributeDefinitions(ctx, categoryID)
+}
+
+// DeleteAttribute implements Service.
+func (s *service) DeleteAttribute(ctx context.Context, categoryID uuid.UUID, key string) error {
+	return s.repository.DeleteAttributeDefinition(ctx, categoryID, key)
+}
+
+// SetProductAttributes validates values against the schema of the product's
+// category and replaces the product's attributes with them.
+func (s *service) SetProductAttributes(ctx context.Context, productID uuid.UUID, values map[string]json.RawMessage) (map[string]interface{}, error) {
+	product, err := s.repository.GetProduct(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	var defs []AttributeDefinition
+	if product.CategoryID != nil {
+		if defs, err = s.repository.ListAttributeDefinitions(ctx, *product.CategoryID); err != nil {
+			return nil, err
+		}
+	}
+	byKey := make(map[string]AttributeDefinition, len(defs))
+	for _, d := range defs {
+		byKey[d.Key] = d
+		if _, ok := values[d.Key]; d.Required && !ok {
+			return nil, fmt.Errorf("%w: %s is required", AttributeErrorInvalidValue, d.Key)
+		}
+	}
+	attrs := make([]ProductAttribute, 0, len(values))
+	out := make(map[string]interface{}, len(values))
+	for key, raw := range values {
+		d, ok := byKey[key]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", AttributeErrorNotFound, key)
+		}
+		a, err := parseAttribute(d, raw)
+		if err != nil {
+			return nil, err
+		}
+		a.ProductID = productID
+		attrs = append(attrs, a)
+		out[key] = a.Typed()
+	}
+	if err := s.repository.ReplaceProductAttributes(ctx, productID, attrs); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// parseAttribute checks raw against the definition and encodes it for storage
+func parseAttribute(d AttributeDefinition, raw json.RawMessage) (ProductAttribute, error) {
+	a := ProductAttribute{Key: d.Key, Type: d.Type}
+	invalid := fmt.Errorf("%w: %s must be a %s", AttributeErrorInvalidValue, d.Key, d.Type)
+	switch d.Type {
+	case AttributeNumber:
+		var n decimal.Decimal
+		if len(raw) == 0 || raw[0] == '"' || json.Unmarshal(raw, &n) != nil {
+			return a, invalid
+		}
+		a.Value, a.NumberValue = n.String(), &n
+	case AttributeBool:
+		var b bool
+		if json.Unmarshal(raw, &b) != nil {
+			return a, invalid
+		}
+		a.Value = strconv.FormatBool(b)
+	default:
+		if json.Unmarshal(raw, &a.Value) != nil {
+			return a, invalid
+		}
+		if d.Type == AttributeEnum && !slices.Contains(d.EnumValues, a.Value) {
+			return a, fmt.Errorf("%w: %s must be one of %s", AttributeErrorInvalidValue, d.Key, strings.Join(d.EnumValues, ", "))
+		}
+	}
+	return a, nil
 }
@@ -0,0 +1,129 @@
+package Catalog
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// SlugRules shape category slugs: lowercase ASCII letters and digits in
+// words joined by Separator, at most MaxLength long. Reserved slugs are never
+// generated and cannot be chosen, for paths a storefront routes itself.
+type SlugRules struct {
+	Separator string
+	MaxLength int
+	Reserved  []string
+}
+
+var DefaultSlugRules = SlugRules{Separator: "-", MaxLength: 100, Reserved: []string{"new", "search", "all"}}
+
+// letters the decomposition below cannot reduce to ASCII
+var transliterations = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'Æ': "ae", 'œ': "oe", 'Œ': "oe", 'ø': "o", 'Ø': "o",
+	'đ': "d", 'Đ': "d", 'ł': "l", 'Ł': "l", 'þ': "th", 'Þ': "th", 'ð': "d", 'Ð': "d",
+	'&': "and", '@': "at",
+}
+
+// Make turns a name into a slug: accents are stripped ("Crème Brûlée" becomes
+// "creme-brulee"), anything else that is not a letter or digit separates
+// words, and the result is cut at a word boundary to fit. It returns "" when
+// nothing of the name survives.
+func (s SlugRules) Make(name string) string {
+	var words []string
+	var word strings.Builder
+	flush := func() {
+		if word.Len() > 0 {
+			words = append(words, word.String())
+			word.Reset()
+		}
+	}
+	for _, r := range norm.NFKD.String(name) {
+		switch {
+		case unicode.Is(unicode.Mn, r), r == '\'', r == '’':
+			// marks left by decomposition; apostrophes keep "men's" one word
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			word.WriteRune(unicode.ToLower(r))
+		case transliterations[r] != "":
+			if r == '&' || r == '@' {
+				flush()
+				words = append(words, transliterations[r])
+				continue
+			}
+			word.WriteString(transliterations[r])
+		default:
+			flush()
+		}
+	}
+	flush()
+	return s.fit(words, s.MaxLength)
+}
+
+// fit joins as many whole words as fit in max, cutting a single overlong word
+func (s SlugRules) fit(words []string, max int) string {
+	slug := ""
+	for _, w := range words {
+		next := w
+		if slug != "" {
+			next = slug + s.Separator + w
+		}
+		if len(next) > max {
+			if slug == "" {
+				slug = w[:max]
+			}
+			break
+		}
+		slug = next
+	}
+	return slug
+}
+
+// Valid reports whether slug follows the rules
+func (s SlugRules) Valid(slug string) bool {
+	if len(slug) < 2 || len(slug) > s.MaxLength || s.reserved(slug) {
+		return false
+	}
+	for _, w := range strings.Split(slug, s.Separator) {
+		if w == "" {
+			return false
+		}
+		for _, r := range w {
+			if (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Unique returns base, or base with the lowest numeric suffix ("-2", "-3",
+// ...) that is not taken, still within MaxLength
+func (s SlugRules) Unique(base string, taken []string) string {
+	used := make(map[string]bool, len(taken))
+	for _, t := range taken {
+		used[t] = true
+	}
+	if !used[base] && !s.reserved(base) {
+		return base
+	}
+	for n := 2; ; n++ {
+		suffix := s.Separator + strconv.Itoa(n)
+		stem := base
+		if len(stem)+len(suffix) > s.MaxLength {
+			stem = strings.TrimRight(stem[:s.MaxLength-len(suffix)], s.Separator)
+		}
+		if candidate := stem + suffix; !used[candidate] {
+			return candidate
+		}
+	}
+}
+
+func (s SlugRules) reserved(slug string) bool {
+	for _, r := range s.Reserved {
+		if r == slug {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,209 @@
+package Catalog
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"golang.org/x/text/language"
+)
+
+// maxLocales caps the locales, parents included, one read looks up
+const maxLocales = 10
+
+// Locales resolves what a read asks for into the locales to try, best
+// first: the locale parameter when given, else the Accept-Language header
+// by preference. Each tag is followed by its parents, so "pt-BR" falls back
+// to "pt"; past the last one the untranslated text is used. An unreadable
+// header counts as no preference.
+func Locales(param, acceptLanguage string) ([]string, error) {
+	var tags []language.Tag
+	if param != "" {
+		t, err := language.Parse(param)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid locale %q", TranslationErrorInvalidPayload, param)
+		}
+		tags = []language.Tag{t}
+	} else if acceptLanguage != "" {
+		parsed, q, _ := language.ParseAcceptLanguage(acceptLanguage)
+		for i, t := range parsed {
+			if q[i] > 0 {
+				tags = append(tags, t)
+			}
+		}
+	}
+	seen := map[string]bool{}
+	var out []string
+	for _, t := range tags {
+		for ; t != language.Und && len(out) < maxLocales; t = t.Parent() {
+			if s := t.String(); !seen[s] {
+				seen[s] = true
+				out = append(out, s)
+			}
+		}
+	}
+	return out, nil
+}
+
+// canonicalLocale validates a locale from a path and returns it in the form
+// translations are stored under
+func canonicalLocale(raw string) (string, error) {
+	t, err := language.Parse(raw)
+	if err != nil || t == language.Und {
+		return "", fmt.Errorf("%w: invalid locale %q", TranslationErrorInvalidPayload, raw)
+	}
+	return t.String(), nil
+}
+
+// SetProductTranslation creates or replaces the product's text in locale
+func (s *service) SetProductTranslation(ctx context.Context, productID uuid.UUID, locale string, dto TranslationRequest) (*Translation, error) {
+	if _, err := s.repository.GetProduct(ctx, productID); err != nil {
+		return nil, err
+	}
+	return s.setTranslation(ctx, ProductTranslationName, productID, locale, dto)
+}
+
+// SetCategoryTranslation creates or replaces the category's text in locale
+func (s *service) SetCategoryTranslation(ctx context.Context, categoryID uuid.UUID, locale string, dto TranslationRequest) (*Translation, error) {
+	if _, err := s.repository.GetCategory(ctx, categoryID); err != nil {
+		return nil, err
+	}
+	if dto.Slug == nil {
+		if slug := s.slugs.Make(dto.Name); len(slug) >= 2 {
+			dto.Slug = &slug
+		}
+	}
+	return s.setTranslation(ctx, CategoryTranslationName, categoryID, locale, dto)
+}
+
+func (s *service) setTranslation(ctx context.Context, table string, ownerID uuid.UUID, locale string, dto TranslationRequest) (*Translation, error) {
+	locale, err := canonicalLocale(locale)
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimSpace(dto.Name)
+	if n := utf8.RuneCountInString(name); n < 2 || n > 255 {
+		return nil, fmt.Errorf("%w: name must be 2 to 255 characters", TranslationErrorInvalidPayload)
+	}
+	if dto.Slug != nil && !s.slugs.Valid(*dto.Slug) {
+		return nil, fmt.Errorf("%w: slug must be 2 to %d lowercase letters and digits in words joined by %q", TranslationErrorInvalidPayload, s.slugs.MaxLength, s.slugs.Separator)
+	}
+	t := &Translation{OwnerID: ownerID, Locale: locale, Name: name, Description: dto.Description, Slug: dto.Slug}
+	if err := s.repository.UpsertTranslation(ctx, table, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// ListProductTranslations lists the product's translations by locale
+func (s *service) ListProductTranslations(ctx context.Context, productID uuid.UUID) ([]Translation, error) {
+	if _, err := s.repository.GetProduct(ctx, productID); err != nil {
+		return nil, err
+	}
+	return s.repository.ListTranslations(ctx, ProductTranslationName, []uuid.UUID{productID}, nil)
+}
+
+// ListCategoryTranslations lists the category's translations by locale
+func (s *service) ListCategoryTranslations(ctx context.Context, categoryID uuid.UUID) ([]Translation, error) {
+	if _, err := s.repository.GetCategory(ctx, categoryID); err != nil {
+		return nil, err
+	}
+	return s.repository.ListTranslations(ctx, CategoryTranslationName, []uuid.UUID{categoryID}, nil)
+}
+
+// DeleteProductTranslation removes the product's text in locale
+func (s *service) DeleteProductTranslation(ctx context.Context, productID uuid.UUID, locale string) error {
+	locale, err := canonicalLocale(locale)
+	if err != nil {
+		return err
+	}
+	return s.repository.DeleteTranslation(ctx, ProductTranslationName, productID, locale)
+}
+
+// DeleteCategoryTranslation removes the category's text in locale
+func (s *service) DeleteCategoryTranslation(ctx context.Context, categoryID uuid.UUID, locale string) error {
+	locale, err := canonicalLocale(locale)
+	if err != nil {
+		return err
+	}
+	return s.repository.DeleteTranslation(ctx, CategoryTranslationName, categoryID, locale)
+}
+
+// LocalizeProducts replaces the name and description of each product with
+// its translation in the first of locales that has one, setting Locale and
+// Slug. Products with none keep their text.
+func (s *service) LocalizeProducts(ctx context.Context, products []Product, locales []string) error {
+	if len(locales) == 0 || len(products) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, len(products))
+	for i := range products {
+		ids[i] = products[i].ID
+	}
+	best, err := s.bestTranslations(ctx, ProductTranslationName, ids, locales)
+	if err != nil {
+		return err
+	}
+	for i := range products {
+		if t, ok := best[products[i].ID]; ok {
+			products[i].Name = t.Name
+			if t.Description != nil {
+				products[i].Description = t.Description
+			}
+			products[i].Slug = t.Slug
+			products[i].Locale = t.Locale
+		}
+	}
+	return nil
+}
+
+// LocalizeCategories is LocalizeProducts for categories; a translation
+// without a slug keeps the category's own.
+func (s *service) LocalizeCategories(ctx context.Context, categories []Category, locales []string) error {
+	if len(locales) == 0 || len(categories) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, len(categories))
+	for i := range categories {
+		ids[i] = categories[i].ID
+	}
+	best, err := s.bestTranslations(ctx, CategoryTranslationName, ids, locales)
+	if err != nil {
+		return err
+	}
+	for i := range categories {
+		if t, ok := best[categories[i].ID]; ok {
+			categories[i].Name = t.Name
+			if t.Description != nil {
+				categories[i].Description = t.Description
+			}
+			if t.Slug != nil {
+				categories[i].Slug = *t.Slug
+			}
+			categories[i].Locale = t.Locale
+		}
+	}
+	return nil
+}
+
+// bestTranslations picks for each owner its translation in the earliest of
+// locales
+func (s *service) bestTranslations(ctx context.Context, table string, ids []uuid.UUID, locales []string) (map[uuid.UUID]Translation, error) {
+	found, err := s.repository.ListTranslations(ctx, table, ids, locales)
+	if err != nil {
+		return nil, err
+	}
+	rank := make(map[string]int, len(locales))
+	for i, l := range locales {
+		rank[l] = i
+	}
+	best := map[uuid.UUID]Translation{}
+	for _, t := range found {
+		if b, ok := best[t.OwnerID]; !ok || rank[t.Locale] < rank[b.Locale] {
+			best[t.OwnerID] = t
+		}
+	}
+	return best, nil
+}
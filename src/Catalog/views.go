@@ -0,0 +1,73 @@
+package Catalog
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// Product views are counted in memory and added to product_views by
+// FlushViews, so a busy product page costs no write per request. Views
+// counted since the last flush are lost if the instance dies.
+
+// maxPendingViews bounds the products counted between flushes; views of
+// further products are dropped until the next flush
+const maxPendingViews = 100000
+
+type viewCounter struct {
+	mu      sync.Mutex
+	pending map[uuid.UUID]int64
+}
+
+// RecordViews counts views per product; unknown products are dropped when
+// flushed
+func (s *service) RecordViews(views map[uuid.UUID]int64) {
+	s.views.mu.Lock()
+	defer s.views.mu.Unlock()
+	if s.views.pending == nil {
+		s.views.pending = map[uuid.UUID]int64{}
+	}
+	for id, n := range views {
+		if _, ok := s.views.pending[id]; !ok && len(s.views.pending) >= maxPendingViews {
+			continue
+		}
+		if n > 0 {
+			s.views.pending[id] += n
+		}
+	}
+}
+
+// FlushViews adds the counted views to the stored ones; on failure they are
+// kept for the next run
+func (s *service) FlushViews(ctx context.Context) error {
+	s.views.mu.Lock()
+	pending := s.views.pending
+	s.views.pending = nil
+	s.views.mu.Unlock()
+	if len(pending) == 0 {
+		return nil
+	}
+	if err := s.repository.AddProductViews(ctx, pending); err != nil {
+		s.RecordViews(pending)
+		return err
+	}
+	return nil
+}
+
+// maxSuggestions caps one autocomplete answer
+const maxSuggestions = 20
+
+// SuggestProducts completes a search prefix with product names: names
+// starting with it first, then names containing it, the most viewed first
+// within each.
+func (s *service) SuggestProducts(ctx context.Context, prefix, channel string, limit int) ([]ProductSuggestion, error) {
+	if len([]rune(prefix)) < 2 {
+		return nil, fmt.Errorf("%w: q needs at least 2 characters", ProductErrorInvalidPayload)
+	}
+	if limit <= 0 || limit > maxSuggestions {
+		limit = 10
+	}
+	return s.repository.SuggestProducts(ctx, prefix, channel, limit)
+}
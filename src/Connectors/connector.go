@@ -0,0 +1,16 @@
+package Connectors
+
+import (
+	"context"
+	"time"
+)
+
+// Connector is implemented by every marketplace integration. Orders are
+// pulled into the Orders module and stock levels are pushed back.
+type Connector interface {
+	Name() string
+	// Warehouse is the warehouse that fulfils this channel's orders.
+	Warehouse() string
+	FetchOrders(ctx context.Context, since time.Time) ([]ExternalOrder, error)
+	PushStock(ctx context.Context, levels []StockLevel) error
+}
@@ -0,0 +1,268 @@
+package Connectors
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// JumiaConnector integrates with the Jumia Seller Center API. Requests are
+// signed with HMAC-SHA256 over the sorted, url-encoded parameters. Order
+// times come back without a zone and are read in the seller's zone.
+type JumiaConnector struct {
+	BaseURL   string
+	UserID    string
+	APIKey    string
+	Currency  string
+	warehouse string
+	zone      *time.Location
+	client    *http.Client
+}
+
+func NewJumiaConnector(baseURL, userID, apiKey, currency, warehouse string, zone *time.Location) *JumiaConnector {
+	return &JumiaConnector{BaseURL: strings.TrimRight(baseURL, "/"), UserID: userID, APIKey: apiKey, Currency: currency, warehouse: warehouse, zone: zone, client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+func (j *JumiaConnector) Name() string      { return "jumia" }
+func (j *JumiaConnector) Warehouse() string { return j.warehouse }
+
+type jumiaOrder struct {
+	OrderID     json.Number `json:"OrderId"`
+	OrderNumber json.Number `json:"OrderNumber"`
+	CreatedAt   string      `json:"CreatedAt"`
+}
+
+type jumiaOrderItem struct {
+	OrderID   json.Number `json:"OrderId"`
+	Sku       string      `json:"Sku"`
+	Name      string      `json:"Name"`
+	ItemPrice string      `json:"ItemPrice"`
+	Currency  string      `json:"Currency"`
+}
+
+// FetchOrders pages through GetOrders and loads items with GetMultipleOrderItems.
+// Jumia returns one row per unit, so rows are folded into quantities per SKU.
+func (j *JumiaConnector) FetchOrders(ctx context.Context, since time.Time) ([]ExternalOrder, error) {
+	const pageSize = 100
+	out := []ExternalOrder{}
+	for offset := 0; ; offset += pageSize {
+		var body struct {
+			Orders struct {
+				Order oneOrMany[jumiaOrder] `json:"Order"`
+			} `json:"Orders"`
+		}
+		params := url.Values{"CreatedAfter": {since.UTC().Format(time.RFC3339)}, "Limit": {fmt.Sprint(pageSize)}, "Offset": {fmt.Sprint(offset)}}
+		if err := j.call(ctx, http.MethodGet, "GetOrders", params, nil, &body); err != nil {
+			return nil, err
+		}
+		page := body.Orders.Order
+		if len(page) == 0 {
+			break
+		}
+		first := len(out)
+		ids := make([]string, 0, len(page))
+		for _, o := range page {
+			order := ExternalOrder{Reference: o.OrderNumber.String(), Currency: j.Currency}
+			created, err := time.ParseInLocation("2006-01-02 15:04:05", o.CreatedAt, j.zone)
+			if err != nil {
+				order.Invalid = fmt.Sprintf("created at %q: %v", o.CreatedAt, err)
+			} else {
+				order.CreatedAt = created
+			}
+			out = append(out, order)
+			ids = append(ids, o.OrderID.String())
+		}
+		byID := map[string]*ExternalOrder{}
+		for i, id := range ids {
+			byID[id] = &out[first+i]
+		}
+		if err := j.loadItems(ctx, ids, byID); err != nil {
+			return nil, err
+		}
+		if len(page) < pageSize {
+			break
+		}
+	}
+	return out, nil
+}
+
+func (j *JumiaConnector) loadItems(ctx context.Context, ids []string, byID map[string]*ExternalOrder) error {
+	var body struct {
+		Orders struct {
+			Order oneOrMany[struct {
+				OrderID    json.Number `json:"OrderId"`
+				OrderItems struct {
+					OrderItem oneOrMany[jumiaOrderItem] `json:"OrderItem"`
+				} `json:"OrderItems"`
+			}] `json:"Order"`
+		} `json:"Orders"`
+	}
+	params := url.Values{"OrderIdList": {"[" + strings.Join(ids, ",") + "]"}}
+	if err := j.call(ctx, http.MethodGet, "GetMultipleOrderItems", params, nil, &body); err != nil {
+		return err
+	}
+	for _, o := range body.Orders.Order {
+		ext, ok := byID[o.OrderID.String()]
+		if !ok {
+			continue
+		}
+		index := map[string]int{}
+		for _, it := range o.OrderItems.OrderItem {
+			if it.Currency != "" {
+				ext.Currency = it.Currency
+			}
+			if i, seen := index[it.Sku]; seen {
+				ext.Items[i].Quantity++
+				continue
+			}
+			price, err := decimal.NewFromString(it.ItemPrice)
+			if err != nil {
+				return fmt.Errorf("jumia order %s: invalid price %q", ext.Reference, it.ItemPrice)
+			}
+			index[it.Sku] = len(ext.Items)
+			ext.Items = append(ext.Items, ExternalOrderItem{SKU: it.Sku, Name: it.Name, UnitPrice: price, Quantity: 1})
+		}
+	}
+	return nil
+}
+
+type jumiaProductUpdate struct {
+	XMLName  xml.Name `xml:"Request"`
+	Products []struct {
+		SellerSku string `xml:"SellerSku"`
+		Quantity  int    `xml:"Quantity"`
+	} `xml:"Product"`
+}
+
+// PushStock sends ProductUpdate requests in chunks of 100 SKUs.
+func (j *JumiaConnector) PushStock(ctx context.Context, levels []StockLevel) error {
+	const chunk = 100
+	for start := 0; start < len(levels); start += chunk {
+		end := start + chunk
+		if end > len(levels) {
+			end = len(levels)
+		}
+		var req jumiaProductUpdate
+		for _, l := range levels[start:end] {
+			req.Products = append(req.Products, struct {
+				SellerSku string `xml:"SellerSku"`
+				Quantity  int    `xml:"Quantity"`
+			}{SellerSku: l.SKU, Quantity: l.Available})
+		}
+		payload, err := xml.Marshal(req)
+		if err != nil {
+			return err
+		}
+		if err := j.call(ctx, http.MethodPost, "ProductUpdate", url.Values{}, payload, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (j *JumiaConnector) call(ctx context.Context, method, action string, params url.Values, payload []byte, out interface{}) error {
+	params.Set("Action", action)
+	params.Set("Format", "JSON")
+	params.Set("Timestamp", time.Now().UTC().Format(time.RFC3339))
+	params.Set("UserID", j.UserID)
+	params.Set("Version", "1.0")
+	params.Set("Signature", j.sign(params))
+
+	var body *bytes.Reader
+	if payload != nil {
+		body = bytes.NewReader(append([]byte(xml.Header), payload...))
+	} else {
+		body = bytes.NewReader(nil)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, j.BaseURL+"/?"+params.Encode(), body)
+	if err != nil {
+		return err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/xml")
+	}
+	res, err := j.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	var envelope struct {
+		SuccessResponse *struct {
+			Body json.RawMessage `json:"Body"`
+		} `json:"SuccessResponse"`
+		ErrorResponse *struct {
+			Head struct {
+				ErrorMessage string `json:"ErrorMessage"`
+			} `json:"Head"`
+		} `json:"ErrorResponse"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("jumia %s: %w", action, err)
+	}
+	if envelope.ErrorResponse != nil {
+		return fmt.Errorf("jumia %s: %s", action, envelope.ErrorResponse.Head.ErrorMessage)
+	}
+	if envelope.SuccessResponse == nil {
+		return fmt.Errorf("jumia %s: unexpected response (status %d)", action, res.StatusCode)
+	}
+	if out == nil || len(envelope.SuccessResponse.Body) == 0 {
+		return nil
+	}
+	return json.Unmarshal(envelope.SuccessResponse.Body, out)
+}
+
+func (j *JumiaConnector) sign(params url.Values) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		if k != "Signature" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, url.QueryEscape(k)+"="+url.QueryEscape(params.Get(k)))
+	}
+	mac := hmac.New(sha256.New, []byte(j.APIKey))
+	mac.Write([]byte(strings.Join(parts, "&")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// oneOrMany decodes Jumia collections, which collapse to a bare object when
+// they hold a single element.
+type oneOrMany[T any] []T
+
+func (o *oneOrMany[T]) UnmarshalJSON(data []byte) error {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 || string(trimmed) == "null" || string(trimmed) == `""` {
+		*o = nil
+		return nil
+	}
+	if trimmed[0] == '[' {
+		var many []T
+		if err := json.Unmarshal(trimmed, &many); err != nil {
+			return err
+		}
+		*o = many
+		return nil
+	}
+	var one T
+	if err := json.Unmarshal(trimmed, &one); err != nil {
+		return err
+	}
+	*o = []T{one}
+	return nil
+}
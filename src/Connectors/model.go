@@ -0,0 +1,45 @@
+package Connectors
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// ExternalOrder is an order as reported by a marketplace, keyed by SKU
+type ExternalOrder struct {
+	Reference string
+	Currency  string
+	CreatedAt time.Time
+	Items     []ExternalOrderItem
+	// Invalid says why the marketplace's data can't be imported, if it can't
+	Invalid string `json:",omitempty"`
+}
+
+type ExternalOrderItem struct {
+	SKU       string
+	Name      string
+	UnitPrice decimal.Decimal
+	Quantity  int
+}
+
+// StockLevel is the sellable quantity we publish for a SKU
+type StockLevel struct {
+	SKU       string `db:"sku"`
+	Available int    `db:"available"`
+}
+
+type SyncState struct {
+	Connector      string     `db:"connector"`
+	OrdersSyncedAt *time.Time `db:"orders_synced_at"`
+	StockSyncedAt  *time.Time `db:"stock_synced_at"`
+	LastError      *string    `db:"last_error"`
+	UpdatedAt      time.Time  `db:"updated_at"`
+}
+
+// CatalogItem is what a marketplace SKU sells: a product, or one variant of it
+type CatalogItem struct {
+	ProductID uuid.UUID  `db:"product_id"`
+	VariantID *uuid.UUID `db:"variant_id"`
+}
@@ -0,0 +1,113 @@
+package Connectors
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+type Repository interface {
+	GetSyncState(ctx context.Context, connector string) (*SyncState, error)
+	SaveSyncState(ctx context.Context, st *SyncState) error
+	ItemsBySKU(ctx context.Context, skus []string) (map[string]CatalogItem, error)
+	ListStockLevels(ctx context.Context, warehouse string) ([]StockLevel, error)
+
+	// FailedOrders lists the connector's orders waiting to be retried, as
+	// they were reported when they failed
+	FailedOrders(ctx context.Context, connector string) ([]ExternalOrder, error)
+	// SaveFailedOrder keeps o to be retried, counting the attempt
+	SaveFailedOrder(ctx context.Context, connector string, o ExternalOrder, cause string) error
+	DeleteFailedOrder(ctx context.Context, connector, reference string) error
+}
+
+type repository struct {
+	db  *sqlx.DB
+	log *zap.Logger
+}
+
+func NewRepository(db *sqlx.DB, log *zap.Logger) Repository { return &repository{db: db, log: log} }
+
+func (r *repository) GetSyncState(ctx context.Context, connector string) (*SyncState, error) {
+	var st SyncState
+	err := r.db.GetContext(ctx, &st, `SELECT connector,orders_synced_at,stock_synced_at,last_error,updated_at FROM connector_sync_state WHERE connector=$1`, connector)
+	if err == sql.ErrNoRows {
+		return &SyncState{Connector: connector}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
+
+func (r *repository) SaveSyncState(ctx context.Context, st *SyncState) error {
+	st.UpdatedAt = time.Now().UTC()
+	_, err := r.db.ExecContext(ctx, `INSERT INTO connector_sync_state (connector,orders_synced_at,stock_synced_at,last_error,updated_at) VALUES ($1,$2,$3,$4,$5) ON CONFLICT (connector) DO UPDATE SET orders_synced_at=EXCLUDED.orders_synced_at, stock_synced_at=EXCLUDED.stock_synced_at, last_error=EXCLUDED.last_error, updated_at=EXCLUDED.updated_at`, st.Connector, st.OrdersSyncedAt, st.StockSyncedAt, st.LastError, st.UpdatedAt)
+	return err
+}
+
+// ItemsBySKU resolves product and variant SKUs to what they sell
+func (r *repository) ItemsBySKU(ctx context.Context, skus []string) (map[string]CatalogItem, error) {
+	out := map[string]CatalogItem{}
+	if len(skus) == 0 {
+		return out, nil
+	}
+	query, args, err := sqlx.In(`SELECT id AS product_id, NULL::uuid AS variant_id, sku FROM products WHERE sku IN (?)
+		UNION ALL SELECT product_id, id, sku FROM product_variants WHERE sku IN (?)`, skus, skus)
+	if err != nil {
+		return nil, err
+	}
+	rows := []struct {
+		CatalogItem
+		SKU string `db:"sku"`
+	}{}
+	if err := r.db.SelectContext(ctx, &rows, r.db.Rebind(query), args...); err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		out[row.SKU] = row.CatalogItem
+	}
+	return out, nil
+}
+
+func (r *repository) ListStockLevels(ctx context.Context, warehouse string) ([]StockLevel, error) {
+	levels := []StockLevel{}
+	// deleted products are pushed as sold out so marketplaces stop selling them
+	err := r.db.SelectContext(ctx, &levels, `SELECT COALESCE(v.sku, p.sku) AS sku, CASE WHEN p.deleted_at IS NULL THEN GREATEST(i.quantity - i.reserved, 0) ELSE 0 END AS available FROM inventory i
+		JOIN products p ON p.id = i.product_id LEFT JOIN product_variants v ON v.id = i.variant_id WHERE i.warehouse=$1`, warehouse)
+	return levels, err
+}
+
+func (r *repository) FailedOrders(ctx context.Context, connector string) ([]ExternalOrder, error) {
+	var payloads [][]byte
+	if err := r.db.SelectContext(ctx, &payloads, `SELECT payload FROM connector_failed_orders WHERE connector=$1 ORDER BY first_failed_at`, connector); err != nil {
+		return nil, err
+	}
+	out := make([]ExternalOrder, 0, len(payloads))
+	for _, p := range payloads {
+		var o ExternalOrder
+		if err := json.Unmarshal(p, &o); err != nil {
+			return nil, err
+		}
+		out = append(out, o)
+	}
+	return out, nil
+}
+
+func (r *repository) SaveFailedOrder(ctx context.Context, connector string, o ExternalOrder, cause string) error {
+	payload, err := json.Marshal(o)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, `INSERT INTO connector_failed_orders (connector,reference,payload,error) VALUES ($1,$2,$3,$4)
+		ON CONFLICT (connector, reference) DO UPDATE SET payload=EXCLUDED.payload, error=EXCLUDED.error, attempts=connector_failed_orders.attempts+1, last_failed_at=NOW()`, connector, o.Reference, payload, cause)
+	return err
+}
+
+func (r *repository) DeleteFailedOrder(ctx context.Context, connector, reference string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM connector_failed_orders WHERE connector=$1 AND reference=$2`, connector, reference)
+	return err
+}
@@ -0,0 +1,174 @@
+package Connectors
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"savannah/src/Orders"
+)
+
+// OrderImporter is the Orders module entry point used for pulled orders
+type OrderImporter interface {
+	Import(ctx context.Context, orders []Orders.ImportOrderRequest) []Orders.ImportOrderResult
+}
+
+type Service interface {
+	SyncOrders(ctx context.Context, c Connector) error
+	PushStock(ctx context.Context, c Connector) error
+}
+
+type service struct {
+	repo   Repository
+	orders OrderImporter
+	log    *zap.Logger
+}
+
+func NewService(r Repository, orders OrderImporter, log *zap.Logger) Service {
+	return &service{repo: r, orders: orders, log: log}
+}
+
+const (
+	// initial look-back when a connector has never synced
+	firstSyncWindow = 24 * time.Hour
+	// how far before the newest order fetched the next sync starts again, so
+	// orders the marketplace indexes late are not missed
+	syncOverlap = 10 * time.Minute
+)
+
+// SyncOrders pulls orders created since the last successful sync and imports
+// them. Import is idempotent by reference, so overlapping windows are safe.
+// The cursor follows the marketplace's own creation times rather than the
+// local clock. An order that can't be imported, for invalid data, an unknown
+// SKU or a failed import, doesn't hold the cursor back: it is kept with the reason
+// and retried on every sync until it imports.
+func (s *service) SyncOrders(ctx context.Context, c Connector) error {
+	st, err := s.repo.GetSyncState(ctx, c.Name())
+	if err != nil {
+		return err
+	}
+	since := time.Now().UTC().Add(-firstSyncWindow)
+	if st.OrdersSyncedAt != nil {
+		since = *st.OrdersSyncedAt
+	}
+	external, err := c.FetchOrders(ctx, since)
+	if err != nil {
+		return s.fail(ctx, st, err)
+	}
+	next := since
+	fetched := make(map[string]bool, len(external))
+	for _, o := range external {
+		fetched[o.Reference] = true
+		if o.CreatedAt.IsZero() {
+			continue
+		}
+		if cursor := o.CreatedAt.UTC().Add(-syncOverlap); cursor.After(next) {
+			next = cursor
+		}
+	}
+	retries, err := s.repo.FailedOrders(ctx, c.Name())
+	if err != nil {
+		return s.fail(ctx, st, err)
+	}
+	retried := make(map[string]bool, len(retries))
+	for _, o := range retries {
+		retried[o.Reference] = true
+		if !fetched[o.Reference] {
+			external = append(external, o)
+		}
+	}
+
+	skus := []string{}
+	for _, o := range external {
+		for _, it := range o.Items {
+			skus = append(skus, it.SKU)
+		}
+	}
+	items, err := s.repo.ItemsBySKU(ctx, skus)
+	if err != nil {
+		return s.fail(ctx, st, err)
+	}
+
+	// reference -> why the order was not imported
+	failures := map[string]string{}
+	batch := make([]Orders.ImportOrderRequest, 0, len(external))
+	for _, o := range external {
+		if o.Invalid != "" {
+			failures[o.Reference] = o.Invalid
+			continue
+		}
+		req := Orders.ImportOrderRequest{ExternalReference: o.Reference, Source: c.Name(), Currency: o.Currency, Warehouse: c.Warehouse()}
+		for _, it := range o.Items {
+			item, ok := items[it.SKU]
+			if !ok {
+				failures[o.Reference] = fmt.Sprintf("unknown sku %q", it.SKU)
+				break
+			}
+			sku, name := it.SKU, it.Name
+			req.Items = append(req.Items, Orders.CreateOrderItem{ProductID: &item.ProductID, VariantID: item.VariantID, SKU: &sku, Name: &name, UnitPrice: it.UnitPrice, Quantity: it.Quantity})
+		}
+		if _, failed := failures[o.Reference]; !failed && len(req.Items) > 0 {
+			batch = append(batch, req)
+		}
+	}
+	imported := 0
+	for _, res := range s.orders.Import(ctx, batch) {
+		if res.Status == Orders.ImportStatusFailed {
+			failures[res.ExternalReference] = res.Error
+		} else {
+			imported++
+		}
+	}
+
+	for _, o := range external {
+		cause, failed := failures[o.Reference]
+		switch {
+		case failed:
+			s.log.Warn("connector order not imported", zap.String("connector", c.Name()), zap.String("reference", o.Reference), zap.String("reason", cause))
+			if err := s.repo.SaveFailedOrder(ctx, c.Name(), o, cause); err != nil {
+				// the cursor stays put, or the order would be lost
+				return s.fail(ctx, st, err)
+			}
+		case retried[o.Reference]:
+			if err := s.repo.DeleteFailedOrder(ctx, c.Name(), o.Reference); err != nil {
+				s.log.Error("clear connector order retry", zap.String("connector", c.Name()), zap.String("reference", o.Reference), zap.Error(err))
+			}
+		}
+	}
+	s.log.Info("connector orders synced", zap.String("connector", c.Name()), zap.Int("fetched", len(fetched)), zap.Int("retried", len(retries)), zap.Int("imported", imported), zap.Int("failed", len(failures)))
+	st.OrdersSyncedAt = &next
+	if len(failures) > 0 {
+		return s.fail(ctx, st, fmt.Errorf("%d orders could not be imported and will be retried", len(failures)))
+	}
+	st.LastError = nil
+	return s.repo.SaveSyncState(ctx, st)
+}
+
+// PushStock publishes the connector warehouse's available stock.
+func (s *service) PushStock(ctx context.Context, c Connector) error {
+	st, err := s.repo.GetSyncState(ctx, c.Name())
+	if err != nil {
+		return err
+	}
+	levels, err := s.repo.ListStockLevels(ctx, c.Warehouse())
+	if err != nil {
+		return s.fail(ctx, st, err)
+	}
+	if err := c.PushStock(ctx, levels); err != nil {
+		return s.fail(ctx, st, err)
+	}
+	now := time.Now().UTC()
+	st.StockSyncedAt = &now
+	st.LastError = nil
+	return s.repo.SaveSyncState(ctx, st)
+}
+
+func (s *service) fail(ctx context.Context, st *SyncState, cause error) error {
+	msg := cause.Error()
+	st.LastError = &msg
+	if err := s.repo.SaveSyncState(ctx, st); err != nil {
+		s.log.Error("save connector state", zap.String("connector", st.Connector), zap.Error(err))
+	}
+	return cause
+}
@@ -0,0 +1,33 @@
+package Coupons
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// CreateCouponRequest issues a coupon; codes are case-insensitive
+type CreateCouponRequest struct {
+	Code               string          `json:"code" validate:"required,min=3,max=40"`
+	Type               string          `json:"type" validate:"required,oneof=PERCENT FIXED"`
+	Value              decimal.Decimal `json:"value"`
+	Currency           string          `json:"currency,omitempty" validate:"required_if=Type FIXED,omitempty,len=3"`
+	MaxUses            *int            `json:"max_uses,omitempty" validate:"omitempty,min=1"`
+	MaxUsesPerCustomer *int            `json:"max_uses_per_customer,omitempty" validate:"omitempty,min=1"`
+	StartsAt           *time.Time      `json:"starts_at,omitempty"`
+	EndsAt             *time.Time      `json:"ends_at,omitempty"`
+	ProductIDs         []uuid.UUID     `json:"product_ids,omitempty" validate:"max=500"`
+	CategoryIDs        []uuid.UUID     `json:"category_ids,omitempty" validate:"max=500"`
+}
+
+// UpdateCouponRequest changes a coupon's limits, window or whether it can be
+// redeemed; absent fields are kept. What it takes off and on what is fixed
+// once issued.
+type UpdateCouponRequest struct {
+	Active             *bool      `json:"active,omitempty"`
+	MaxUses            *int       `json:"max_uses,omitempty" validate:"omitempty,min=1"`
+	MaxUsesPerCustomer *int       `json:"max_uses_per_customer,omitempty" validate:"omitempty,min=1"`
+	StartsAt           *time.Time `json:"starts_at,omitempty"`
+	EndsAt             *time.Time `json:"ends_at,omitempty"`
+}
@@ -0,0 +1,52 @@
+package Coupons
+
+import (
+	"errors"
+
+	"savannah/src/ErrorCodes"
+)
+
+var (
+	ErrorNotFound         = errors.New("coupon not found")
+	ErrorInvalidPayload   = errors.New("invalid coupon payload")
+	ErrorCodeExists       = errors.New("a coupon with that code already exists")
+	ErrorUnknownTarget    = errors.New("coupon product or category not found")
+	ErrorInactive         = errors.New("the coupon is disabled")
+	ErrorNotStarted       = errors.New("the coupon is not valid yet")
+	ErrorExpired          = errors.New("the coupon has expired")
+	ErrorExhausted        = errors.New("the coupon has been fully redeemed")
+	ErrorCustomerLimit    = errors.New("the customer has already redeemed the coupon")
+	ErrorCustomerRequired = errors.New("the coupon can only be redeemed by a signed-in customer")
+	ErrorCurrency         = errors.New("the coupon is not valid in the order's currency")
+	ErrorNotApplicable    = errors.New("the coupon applies to none of the items")
+	ErrorRedeemed         = errors.New("the coupon has been redeemed; disable it instead")
+)
+
+func init() {
+	ErrorCodes.Register("COUPONS",
+		ErrorCodes.Def{N: 1, Slug: "NOT_FOUND", Err: ErrorNotFound},
+		ErrorCodes.Def{N: 2, Slug: "INVALID_PAYLOAD", Err: ErrorInvalidPayload},
+		ErrorCodes.Def{N: 3, Slug: "CODE_EXISTS", Err: ErrorCodeExists},
+		ErrorCodes.Def{N: 4, Slug: "UNKNOWN_TARGET", Err: ErrorUnknownTarget},
+		ErrorCodes.Def{N: 5, Slug: "INACTIVE", Err: ErrorInactive},
+		ErrorCodes.Def{N: 6, Slug: "NOT_STARTED", Err: ErrorNotStarted},
+		ErrorCodes.Def{N: 7, Slug: "EXPIRED", Err: ErrorExpired},
+		ErrorCodes.Def{N: 8, Slug: "EXHAUSTED", Err: ErrorExhausted},
+		ErrorCodes.Def{N: 9, Slug: "CUSTOMER_LIMIT", Err: ErrorCustomerLimit},
+		ErrorCodes.Def{N: 10, Slug: "CUSTOMER_REQUIRED", Err: ErrorCustomerRequired},
+		ErrorCodes.Def{N: 11, Slug: "WRONG_CURRENCY", Err: ErrorCurrency},
+		ErrorCodes.Def{N: 12, Slug: "NOT_APPLICABLE", Err: ErrorNotApplicable},
+		ErrorCodes.Def{N: 13, Slug: "REDEEMED", Err: ErrorRedeemed},
+	)
+}
+
+// Rejected reports whether err is why a coupon code can't be redeemed on an
+// order, which the checkout answers 422
+func Rejected(err error) bool {
+	for _, e := range []error{ErrorNotFound, ErrorInactive, ErrorNotStarted, ErrorExpired, ErrorExhausted, ErrorCustomerLimit, ErrorCustomerRequired, ErrorCurrency, ErrorNotApplicable} {
+		if errors.Is(err, e) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,183 @@
+package Coupons
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"savannah/src/ErrorCodes"
+	"savannah/src/Pagination"
+)
+
+type Handler struct {
+	svc Service
+	log *zap.Logger
+	v   *validator.Validate
+}
+
+func NewHandler(s Service, log *zap.Logger) *Handler {
+	return &Handler{svc: s, log: log, v: validator.New()}
+}
+
+// CreateCoupon godoc
+// @Summary      Issue a coupon code
+// @Description  PERCENT coupons take value percent off the items they apply to, FIXED coupons take value in currency off them. With product_ids or category_ids the coupon only applies to those items. Shoppers enter the code as coupon_code at checkout; codes are case-insensitive.
+// @Tags         coupons
+// @Accept       json
+// @Produce      json
+// @Param        payload  body      CreateCouponRequest  true  "Coupon"
+// @Success      201      {object}  Coupon
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      404      {object}  map[string]interface{}
+// @Failure      409      {object}  map[string]interface{}
+// @Router       /coupons [post]
+func (h *Handler) CreateCoupon(w http.ResponseWriter, r *http.Request) {
+	var dto CreateCouponRequest
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if err := h.v.Struct(dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	c, err := h.svc.Create(r.Context(), dto)
+	if err != nil {
+		h.couponError(w, "create coupon", err)
+		return
+	}
+	h.writeJSON(w, http.StatusCreated, c)
+}
+
+// ListCoupons godoc
+// @Summary      List coupons
+// @Tags         coupons
+// @Produce      json
+// @Param        limit   query     int  false  "Page size (default 50, max 200)"
+// @Param        offset  query     int  false  "Offset"
+// @Success      200     {array}   Coupon
+// @Header       200     {string}  Link  "first, prev, next and last pages (RFC 5988), with X-Total-Count, X-Page and X-Total-Pages"
+// @Router       /coupons [get]
+func (h *Handler) ListCoupons(w http.ResponseWriter, r *http.Request) {
+	limit, offset := Pagination.Params(r.URL.Query(), 50, 200)
+	coupons, err := h.svc.List(r.Context(), limit, offset)
+	var total int
+	if err == nil {
+		total, err = h.svc.Count(r.Context())
+	}
+	if err != nil {
+		h.log.Error("list coupons", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to list coupons")
+		return
+	}
+	Pagination.Write(w, r, limit, offset, total)
+	h.writeJSON(w, http.StatusOK, coupons)
+}
+
+// GetCoupon godoc
+// @Summary      Get a coupon
+// @Tags         coupons
+// @Produce      json
+// @Param        id   path      string  true  "Coupon ID"
+// @Success      200  {object}  Coupon
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /coupons/{id} [get]
+func (h *Handler) GetCoupon(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	c, err := h.svc.Get(r.Context(), id)
+	if err != nil {
+		h.couponError(w, "get coupon", err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, c)
+}
+
+// UpdateCoupon godoc
+// @Summary      Change a coupon's limits or validity
+// @Description  Sets the usage limits, validity window or whether the coupon can be redeemed (active). What it takes off and on what can't change once issued.
+// @Tags         coupons
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string               true  "Coupon ID"
+// @Param        payload  body      UpdateCouponRequest  true  "Changes"
+// @Success      200      {object}  Coupon
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      404      {object}  map[string]interface{}
+// @Router       /coupons/{id} [patch]
+func (h *Handler) UpdateCoupon(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var dto UpdateCouponRequest
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if err := h.v.Struct(dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	c, err := h.svc.Update(r.Context(), id, dto)
+	if err != nil {
+		h.couponError(w, "update coupon", err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, c)
+}
+
+// DeleteCoupon godoc
+// @Summary      Delete a coupon nobody redeemed
+// @Description  A redeemed coupon is kept with the orders it discounted; disable it with PATCH instead.
+// @Tags         coupons
+// @Param        id   path  string  true  "Coupon ID"
+// @Success      204
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      409  {object}  map[string]interface{}
+// @Router       /coupons/{id} [delete]
+func (h *Handler) DeleteCoupon(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	if err := h.svc.Delete(r.Context(), id); err != nil {
+		h.couponError(w, "delete coupon", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) couponError(w http.ResponseWriter, op string, err error) {
+	switch {
+	case errors.Is(err, ErrorNotFound), errors.Is(err, ErrorUnknownTarget):
+		h.writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrorInvalidPayload):
+		h.writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrorCodeExists), errors.Is(err, ErrorRedeemed):
+		h.writeError(w, http.StatusConflict, err.Error())
+	default:
+		h.log.Error(op, zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to "+op)
+	}
+}
+
+func (h *Handler) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (h *Handler) writeError(w http.ResponseWriter, status int, msg string) {
+	h.writeJSON(w, status, map[string]interface{}{"error": msg, "code": ErrorCodes.Resolve("COUPONS", status, msg), "timestamp": time.Now().UTC()})
+}
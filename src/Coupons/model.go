@@ -0,0 +1,61 @@
+package Coupons
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// Coupon is a code a shopper enters at checkout for money off: Value
+// percent of the items it applies to (PERCENT), or Value in Currency off
+// them (FIXED), never more than they cost. With products or categories in
+// scope it applies only to those items, otherwise to every item. Shipping
+// and tax are never discounted.
+type Coupon struct {
+	ID    uuid.UUID       `db:"id" json:"id"`
+	Code  string          `db:"code" json:"code"`
+	Type  string          `db:"type" json:"type"`
+	Value decimal.Decimal `db:"value" json:"value"`
+	// the currency of a FIXED coupon; it only applies to orders in it
+	Currency *string `db:"currency" json:"currency,omitempty"`
+	// redemptions allowed in all and per customer; unlimited when nil
+	MaxUses            *int        `db:"max_uses" json:"max_uses,omitempty"`
+	MaxUsesPerCustomer *int        `db:"max_uses_per_customer" json:"max_uses_per_customer,omitempty"`
+	Uses               int         `db:"uses" json:"uses"`
+	StartsAt           *time.Time  `db:"starts_at" json:"starts_at,omitempty"`
+	EndsAt             *time.Time  `db:"ends_at" json:"ends_at,omitempty"`
+	Active             bool        `db:"active" json:"active"`
+	ProductIDs         []uuid.UUID `db:"-" json:"product_ids"`
+	CategoryIDs        []uuid.UUID `db:"-" json:"category_ids"`
+	CreatedAt          time.Time   `db:"created_at" json:"created_at"`
+	UpdatedAt          time.Time   `db:"updated_at" json:"updated_at"`
+}
+
+const TableName = "coupons"
+
+// coupon types
+const (
+	TypePercent = "PERCENT"
+	TypeFixed   = "FIXED"
+)
+
+// scoped reports whether the coupon is limited to some products
+func (c *Coupon) scoped() bool {
+	return len(c.ProductIDs) > 0 || len(c.CategoryIDs) > 0
+}
+
+// Line is an order line a coupon may discount
+type Line struct {
+	ProductID uuid.UUID
+	Amount    decimal.Decimal
+}
+
+// Discount is what a coupon takes off an order; it is only counted against
+// the coupon's limits once redeemed
+type Discount struct {
+	CouponID    uuid.UUID       `json:"coupon_id"`
+	Code        string          `json:"code"`
+	Description string          `json:"description"`
+	Amount      decimal.Decimal `json:"amount"`
+}
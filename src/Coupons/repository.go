@@ -0,0 +1,266 @@
+package Coupons
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+type Repository interface {
+	Create(ctx context.Context, c *Coupon) error
+	Get(ctx context.Context, id uuid.UUID) (*Coupon, error)
+	GetByCode(ctx context.Context, code string) (*Coupon, error)
+	List(ctx context.Context, limit, offset int) ([]Coupon, error)
+	Count(ctx context.Context) (int, error)
+	Update(ctx context.Context, c *Coupon) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	InScope(ctx context.Context, couponID uuid.UUID, productIDs []uuid.UUID) ([]uuid.UUID, error)
+	CustomerUses(ctx context.Context, couponID, customerID uuid.UUID) (int, error)
+	Redeem(ctx context.Context, couponID, orderID uuid.UUID, customerID *uuid.UUID, amount decimal.Decimal, now time.Time) error
+	Release(ctx context.Context, orderID uuid.UUID) error
+}
+
+type repository struct {
+	db  *sqlx.DB
+	log *zap.Logger
+}
+
+func NewRepository(db *sqlx.DB, log *zap.Logger) Repository { return &repository{db: db, log: log} }
+
+const couponColumns = `id,code,type,value,currency,max_uses,max_uses_per_customer,uses,starts_at,ends_at,active,created_at,updated_at`
+
+// Create stores the coupon and its scope in one transaction; an unknown
+// product or category fails the whole coupon
+func (r *repository) Create(ctx context.Context, c *Coupon) (err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	if _, err = tx.NamedExecContext(ctx, fmt.Sprintf(`INSERT INTO %s (%s) VALUES (:id,:code,:type,:value,:currency,:max_uses,:max_uses_per_customer,:uses,:starts_at,:ends_at,:active,:created_at,:updated_at)`, TableName, couponColumns), c); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			err = ErrorCodeExists
+		}
+		return err
+	}
+	if err = insertScope(ctx, tx, `INSERT INTO coupon_products (coupon_id, product_id) SELECT ?, id FROM products WHERE id IN (?) AND deleted_at IS NULL`, c.ID, c.ProductIDs); err != nil {
+		return err
+	}
+	if err = insertScope(ctx, tx, `INSERT INTO coupon_categories (coupon_id, category_id) SELECT ?, id FROM categories WHERE id IN (?) AND deleted_at IS NULL`, c.ID, c.CategoryIDs); err != nil {
+		return err
+	}
+	err = tx.Commit()
+	return err
+}
+
+// insertScope links the ids that exist and fails when some do not
+func insertScope(ctx context.Context, tx *sqlx.Tx, query string, couponID uuid.UUID, ids []uuid.UUID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	query, args, err := sqlx.In(query, couponID, ids)
+	if err != nil {
+		return err
+	}
+	res, err := tx.ExecContext(ctx, tx.Rebind(query), args...)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if int(n) != len(ids) {
+		return ErrorUnknownTarget
+	}
+	return nil
+}
+
+func (r *repository) Get(ctx context.Context, id uuid.UUID) (*Coupon, error) {
+	return r.get(ctx, `id=$1`, id)
+}
+
+// GetByCode finds a coupon by its code, which is stored upper case
+func (r *repository) GetByCode(ctx context.Context, code string) (*Coupon, error) {
+	return r.get(ctx, `code=$1`, code)
+}
+
+func (r *repository) get(ctx context.Context, where string, arg interface{}) (*Coupon, error) {
+	var c Coupon
+	if err := r.db.GetContext(ctx, &c, fmt.Sprintf(`SELECT %s FROM %s WHERE %s`, couponColumns, TableName, where), arg); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrorNotFound
+		}
+		return nil, err
+	}
+	coupons := []Coupon{c}
+	if err := r.loadScope(ctx, coupons); err != nil {
+		return nil, err
+	}
+	return &coupons[0], nil
+}
+
+// List pages through the coupons, newest first
+func (r *repository) List(ctx context.Context, limit, offset int) ([]Coupon, error) {
+	coupons := []Coupon{}
+	if err := r.db.SelectContext(ctx, &coupons, fmt.Sprintf(`SELECT %s FROM %s ORDER BY created_at DESC, id LIMIT $1 OFFSET $2`, couponColumns, TableName), limit, offset); err != nil {
+		return nil, err
+	}
+	return coupons, r.loadScope(ctx, coupons)
+}
+
+func (r *repository) Count(ctx context.Context) (int, error) {
+	var n int
+	err := r.db.GetContext(ctx, &n, `SELECT COUNT(*) FROM `+TableName)
+	return n, err
+}
+
+func (r *repository) loadScope(ctx context.Context, coupons []Coupon) error {
+	if len(coupons) == 0 {
+		return nil
+	}
+	byID := make(map[uuid.UUID]*Coupon, len(coupons))
+	ids := make([]uuid.UUID, 0, len(coupons))
+	for i := range coupons {
+		coupons[i].ProductIDs = []uuid.UUID{}
+		coupons[i].CategoryIDs = []uuid.UUID{}
+		byID[coupons[i].ID] = &coupons[i]
+		ids = append(ids, coupons[i].ID)
+	}
+	var targets []struct {
+		CouponID uuid.UUID `db:"coupon_id"`
+		TargetID uuid.UUID `db:"target_id"`
+		Kind     string    `db:"kind"`
+	}
+	query, args, err := sqlx.In(`SELECT coupon_id, product_id AS target_id, 'product' AS kind FROM coupon_products WHERE coupon_id IN (?)
+		UNION ALL SELECT coupon_id, category_id, 'category' FROM coupon_categories WHERE coupon_id IN (?)`, ids, ids)
+	if err != nil {
+		return err
+	}
+	if err := r.db.SelectContext(ctx, &targets, r.db.Rebind(query), args...); err != nil {
+		return err
+	}
+	for _, t := range targets {
+		c := byID[t.CouponID]
+		if t.Kind == "product" {
+			c.ProductIDs = append(c.ProductIDs, t.TargetID)
+		} else {
+			c.CategoryIDs = append(c.CategoryIDs, t.TargetID)
+		}
+	}
+	return nil
+}
+
+// Update saves the coupon's limits, window and active flag
+func (r *repository) Update(ctx context.Context, c *Coupon) error {
+	c.UpdatedAt = time.Now().UTC()
+	res, err := r.db.NamedExecContext(ctx, fmt.Sprintf(`UPDATE %s SET active=:active, max_uses=:max_uses, max_uses_per_customer=:max_uses_per_customer,
+		starts_at=:starts_at, ends_at=:ends_at, updated_at=:updated_at WHERE id=:id`, TableName), c)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrorNotFound
+	}
+	return nil
+}
+
+// Delete removes a coupon nobody redeemed
+func (r *repository) Delete(ctx context.Context, id uuid.UUID) error {
+	res, err := r.db.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE id=$1 AND uses = 0`, TableName), id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		if _, err := r.Get(ctx, id); err != nil {
+			return err
+		}
+		return ErrorRedeemed
+	}
+	return nil
+}
+
+// InScope returns those of productIDs the coupon's scope covers: listed
+// themselves or in a listed category
+func (r *repository) InScope(ctx context.Context, couponID uuid.UUID, productIDs []uuid.UUID) ([]uuid.UUID, error) {
+	out := []uuid.UUID{}
+	if len(productIDs) == 0 {
+		return out, nil
+	}
+	query, args, err := sqlx.In(`SELECT p.id FROM products p WHERE p.id IN (?)
+		AND (EXISTS (SELECT 1 FROM coupon_products cp WHERE cp.coupon_id = ? AND cp.product_id = p.id)
+		  OR EXISTS (SELECT 1 FROM coupon_categories cc WHERE cc.coupon_id = ? AND cc.category_id = p.category_id))`, productIDs, couponID, couponID)
+	if err != nil {
+		return nil, err
+	}
+	err = r.db.SelectContext(ctx, &out, r.db.Rebind(query), args...)
+	return out, err
+}
+
+func (r *repository) CustomerUses(ctx context.Context, couponID, customerID uuid.UUID) (int, error) {
+	var n int
+	err := r.db.GetContext(ctx, &n, `SELECT COUNT(*) FROM coupon_redemptions WHERE coupon_id=$1 AND customer_id=$2`, couponID, customerID)
+	return n, err
+}
+
+// Redeem counts an order's use of the coupon. The coupon row is locked while
+// its limits are checked, so concurrent checkouts can't redeem it past them.
+func (r *repository) Redeem(ctx context.Context, couponID, orderID uuid.UUID, customerID *uuid.UUID, amount decimal.Decimal, now time.Time) (err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	var c Coupon
+	if err = tx.GetContext(ctx, &c, fmt.Sprintf(`SELECT %s FROM %s WHERE id=$1 FOR UPDATE`, couponColumns, TableName), couponID); err != nil {
+		if err == sql.ErrNoRows {
+			err = ErrorNotFound
+		}
+		return err
+	}
+	if c.MaxUses != nil && c.Uses >= *c.MaxUses {
+		err = ErrorExhausted
+		return err
+	}
+	if c.MaxUsesPerCustomer != nil && customerID != nil {
+		var n int
+		if err = tx.GetContext(ctx, &n, `SELECT COUNT(*) FROM coupon_redemptions WHERE coupon_id=$1 AND customer_id=$2`, couponID, *customerID); err != nil {
+			return err
+		}
+		if n >= *c.MaxUsesPerCustomer {
+			err = ErrorCustomerLimit
+			return err
+		}
+	}
+	if _, err = tx.ExecContext(ctx, `INSERT INTO coupon_redemptions (coupon_id,order_id,customer_id,amount,created_at) VALUES ($1,$2,$3,$4,$5)`, couponID, orderID, customerID, amount, now); err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET uses=uses+1, updated_at=$2 WHERE id=$1`, TableName), couponID, now); err != nil {
+		return err
+	}
+	err = tx.Commit()
+	return err
+}
+
+// Release gives back the coupon uses of an order that won't be paid
+func (r *repository) Release(ctx context.Context, orderID uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, fmt.Sprintf(`WITH released AS (DELETE FROM coupon_redemptions WHERE order_id=$1 RETURNING coupon_id)
+		UPDATE %s c SET uses=GREATEST(c.uses - n.count, 0), updated_at=NOW()
+		FROM (SELECT coupon_id, COUNT(*) FROM released GROUP BY coupon_id) n WHERE c.id = n.coupon_id`, TableName), orderID)
+	return err
+}
@@ -0,0 +1,225 @@
+package Coupons
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+// Service issues coupons and works out what they take off an order; Orders
+// applies and redeems them at checkout and releases them when an order is
+// cancelled or never placed
+type Service interface {
+	Create(ctx context.Context, req CreateCouponRequest) (*Coupon, error)
+	Get(ctx context.Context, id uuid.UUID) (*Coupon, error)
+	List(ctx context.Context, limit, offset int) ([]Coupon, error)
+	Count(ctx context.Context) (int, error)
+	Update(ctx context.Context, id uuid.UUID, req UpdateCouponRequest) (*Coupon, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+
+	Apply(ctx context.Context, code string, customerID *uuid.UUID, currency string, lines []Line) (*Discount, error)
+	Redeem(ctx context.Context, d Discount, orderID uuid.UUID, customerID *uuid.UUID) error
+	Release(ctx context.Context, orderID uuid.UUID) error
+}
+
+type service struct {
+	repo Repository
+	log  *zap.Logger
+}
+
+func NewService(r Repository, log *zap.Logger) Service {
+	return &service{repo: r, log: log}
+}
+
+// normalize is the form codes are stored and looked up in
+func normalize(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func (s *service) Create(ctx context.Context, req CreateCouponRequest) (*Coupon, error) {
+	code := normalize(req.Code)
+	if len(code) < 3 || strings.ContainsAny(code, " \t\n") {
+		return nil, fmt.Errorf("%w: code must be at least 3 characters without spaces", ErrorInvalidPayload)
+	}
+	if !req.Value.IsPositive() {
+		return nil, fmt.Errorf("%w: value must be above 0", ErrorInvalidPayload)
+	}
+	c := &Coupon{ID: uuid.New(), Code: code, Type: req.Type, Value: req.Value, MaxUses: req.MaxUses, MaxUsesPerCustomer: req.MaxUsesPerCustomer,
+		StartsAt: req.StartsAt, EndsAt: req.EndsAt, Active: true, ProductIDs: req.ProductIDs, CategoryIDs: req.CategoryIDs}
+	switch req.Type {
+	case TypePercent:
+		if req.Value.GreaterThan(decimal.NewFromInt(100)) {
+			return nil, fmt.Errorf("%w: a percentage can be at most 100", ErrorInvalidPayload)
+		}
+	case TypeFixed:
+		currency := strings.ToUpper(req.Currency)
+		c.Currency = &currency
+	}
+	if err := checkWindow(c.StartsAt, c.EndsAt); err != nil {
+		return nil, err
+	}
+	if c.ProductIDs == nil {
+		c.ProductIDs = []uuid.UUID{}
+	}
+	if c.CategoryIDs == nil {
+		c.CategoryIDs = []uuid.UUID{}
+	}
+	c.CreatedAt = time.Now().UTC()
+	c.UpdatedAt = c.CreatedAt
+	if err := s.repo.Create(ctx, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func checkWindow(starts, ends *time.Time) error {
+	if starts != nil && ends != nil && !ends.After(*starts) {
+		return fmt.Errorf("%w: ends_at must be after starts_at", ErrorInvalidPayload)
+	}
+	return nil
+}
+
+func (s *service) Get(ctx context.Context, id uuid.UUID) (*Coupon, error) {
+	return s.repo.Get(ctx, id)
+}
+
+func (s *service) List(ctx context.Context, limit, offset int) ([]Coupon, error) {
+	return s.repo.List(ctx, limit, offset)
+}
+
+func (s *service) Count(ctx context.Context) (int, error) {
+	return s.repo.Count(ctx)
+}
+
+func (s *service) Update(ctx context.Context, id uuid.UUID, req UpdateCouponRequest) (*Coupon, error) {
+	c, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if req.Active != nil {
+		c.Active = *req.Active
+	}
+	if req.MaxUses != nil {
+		c.MaxUses = req.MaxUses
+	}
+	if req.MaxUsesPerCustomer != nil {
+		c.MaxUsesPerCustomer = req.MaxUsesPerCustomer
+	}
+	if req.StartsAt != nil {
+		c.StartsAt = req.StartsAt
+	}
+	if req.EndsAt != nil {
+		c.EndsAt = req.EndsAt
+	}
+	if err := checkWindow(c.StartsAt, c.EndsAt); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(ctx, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Delete removes a coupon that was never redeemed; one that was is kept for
+// the orders it discounted and can only be disabled
+func (s *service) Delete(ctx context.Context, id uuid.UUID) error {
+	return s.repo.Delete(ctx, id)
+}
+
+// Apply checks that the coupon behind code can be redeemed on an order in
+// currency by customerID (nil for a guest) and works out what it takes off
+// lines. Nothing is counted against its limits until Redeem.
+func (s *service) Apply(ctx context.Context, code string, customerID *uuid.UUID, currency string, lines []Line) (*Discount, error) {
+	c, err := s.repo.GetByCode(ctx, normalize(code))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	switch {
+	case !c.Active:
+		return nil, ErrorInactive
+	case c.StartsAt != nil && now.Before(*c.StartsAt):
+		return nil, ErrorNotStarted
+	case c.EndsAt != nil && !now.Before(*c.EndsAt):
+		return nil, ErrorExpired
+	case c.MaxUses != nil && c.Uses >= *c.MaxUses:
+		return nil, ErrorExhausted
+	case c.Currency != nil && *c.Currency != currency:
+		return nil, ErrorCurrency
+	}
+	if c.MaxUsesPerCustomer != nil {
+		if customerID == nil {
+			return nil, ErrorCustomerRequired
+		}
+		n, err := s.repo.CustomerUses(ctx, c.ID, *customerID)
+		if err != nil {
+			return nil, err
+		}
+		if n >= *c.MaxUsesPerCustomer {
+			return nil, ErrorCustomerLimit
+		}
+	}
+	eligible, err := s.eligible(ctx, c, lines)
+	if err != nil {
+		return nil, err
+	}
+	if !eligible.IsPositive() {
+		return nil, ErrorNotApplicable
+	}
+	d := &Discount{CouponID: c.ID, Code: c.Code}
+	if c.Type == TypePercent {
+		d.Amount = eligible.Mul(c.Value).Div(decimal.NewFromInt(100)).Round(2)
+		d.Description = c.Value.String() + "% off"
+	} else {
+		d.Amount = decimal.Min(c.Value, eligible)
+		d.Description = c.Value.StringFixed(2) + " " + *c.Currency + " off"
+	}
+	if c.scoped() {
+		d.Description += " selected items"
+	}
+	return d, nil
+}
+
+// eligible sums the lines the coupon applies to
+func (s *service) eligible(ctx context.Context, c *Coupon, lines []Line) (decimal.Decimal, error) {
+	sum := decimal.Zero
+	if !c.scoped() {
+		for _, l := range lines {
+			sum = sum.Add(l.Amount)
+		}
+		return sum, nil
+	}
+	ids := make([]uuid.UUID, 0, len(lines))
+	for _, l := range lines {
+		ids = append(ids, l.ProductID)
+	}
+	covered, err := s.repo.InScope(ctx, c.ID, ids)
+	if err != nil {
+		return sum, err
+	}
+	in := make(map[uuid.UUID]bool, len(covered))
+	for _, id := range covered {
+		in[id] = true
+	}
+	for _, l := range lines {
+		if in[l.ProductID] {
+			sum = sum.Add(l.Amount)
+		}
+	}
+	return sum, nil
+}
+
+// Redeem counts the order's use of the coupon; the limits are checked again
+// since another checkout may have used it up after Apply
+func (s *service) Redeem(ctx context.Context, d Discount, orderID uuid.UUID, customerID *uuid.UUID) error {
+	return s.repo.Redeem(ctx, d.CouponID, orderID, customerID, d.Amount, time.Now().UTC())
+}
+
+func (s *service) Release(ctx context.Context, orderID uuid.UUID) error {
+	return s.repo.Release(ctx, orderID)
+}
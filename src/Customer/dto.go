@@ -1,6 +1,9 @@
 package Customer
 
-import "github.com/google/uuid"
+import (
+	"github.com/google/uuid"
+	"savannah/src/MergePatch"
+)
 
 // client requests to create
 type CreateCustomerRequest struct {
@@ -8,6 +11,13 @@ type CreateCustomerRequest struct {
 	LastName  string `json:"last_name" validate:"required,min=2,max=100"`
 	Email     string `json:"email" validate:"required,email"`
 	Phone     string `json:"phone" validate:"required,e164"`
+	// business customers
+	CompanyName *string `json:"company_name,omitempty" validate:"omitempty,max=200"`
+	TaxID       *string `json:"tax_id,omitempty" validate:"omitempty,max=30"`
+	TaxIDType   *string `json:"tax_id_type,omitempty" validate:"required_with=TaxID,omitempty,oneof=VAT PIN"`
+	TaxCountry  *string `json:"tax_country,omitempty" validate:"required_with=TaxID,omitempty,len=2"`
+	// IANA zone times are shown in, e.g. Africa/Nairobi
+	Timezone *string `json:"timezone,omitempty" validate:"omitempty,max=64"`
 }
 
 // update customer
@@ -16,7 +26,31 @@ type UpdateCustomerRequest struct {
 	LastName  *string `json:"last_name" validate:"omitempty,min=2,max=100"`
 	Email     *string `json:"email" validate:"omitempty,email"`
 	Phone     *string `json:"phone" validate:"omitempty,e164"`
-	Version   int     `json:"version" validate:"required"`
+	// an empty string clears the company or tax registration
+	CompanyName *string `json:"company_name,omitempty" validate:"omitempty,max=200"`
+	TaxID       *string `json:"tax_id,omitempty" validate:"omitempty,max=30"`
+	TaxIDType   *string `json:"tax_id_type,omitempty" validate:"omitempty,oneof=VAT PIN"`
+	TaxCountry  *string `json:"tax_country,omitempty" validate:"omitempty,len=2"`
+	// an empty string clears it
+	Timezone *string `json:"timezone,omitempty" validate:"omitempty,max=64"`
+	Version  int     `json:"version" validate:"required"`
+}
+
+// PatchCustomerRequest is a JSON merge patch of a customer. Members left out
+// are unchanged; null clears the company, tax registration or timezone and is refused
+// for the contact fields.
+type PatchCustomerRequest struct {
+	FirstName   MergePatch.Field[string] `json:"first_name"`
+	LastName    MergePatch.Field[string] `json:"last_name"`
+	Email       MergePatch.Field[string] `json:"email"`
+	Phone       MergePatch.Field[string] `json:"phone"`
+	CompanyName MergePatch.Field[string] `json:"company_name"`
+	TaxID       MergePatch.Field[string] `json:"tax_id"`
+	TaxIDType   MergePatch.Field[string] `json:"tax_id_type"`
+	TaxCountry  MergePatch.Field[string] `json:"tax_country"`
+	Timezone    MergePatch.Field[string] `json:"timezone"`
+	// optional; when sent it must match the stored version
+	Version MergePatch.Field[int] `json:"version"`
 }
 
 type CustomerResponse struct {
@@ -36,5 +70,6 @@ type ListCustomersQuery struct {
 	Limit  int    `schema:"limit"`
 	Offset int    `schema:"offset"`
 	Search string `schema:"search"`
+	Email  string `schema:"email"`
 	Status string `schema:"status"`
 }
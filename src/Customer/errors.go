@@ -1,9 +1,25 @@
 package Customer
 
-import "errors"
+import (
+	"errors"
+
+	"savannah/src/ErrorCodes"
+)
 
 var (
-	ErrorNotFound       = errors.New("customer not found")
-	ErrorConflict       = errors.New("customer already exist")
-	ErrorInvalidPayload = errors.New("invalid payload")
+	ErrorNotFound        = errors.New("customer not found")
+	ErrorConflict        = errors.New("customer already exist")
+	ErrorInvalidPayload  = errors.New("invalid payload")
+	ErrorInvalidTaxID    = errors.New("invalid tax id")
+	ErrorInvalidTimezone = errors.New("invalid timezone")
 )
+
+func init() {
+	ErrorCodes.Register("CUSTOMERS",
+		ErrorCodes.Def{N: 1, Slug: "NOT_FOUND", Err: ErrorNotFound},
+		ErrorCodes.Def{N: 2, Slug: "ALREADY_EXISTS", Err: ErrorConflict},
+		ErrorCodes.Def{N: 3, Slug: "INVALID_PAYLOAD", Err: ErrorInvalidPayload},
+		ErrorCodes.Def{N: 4, Slug: "INVALID_TAX_ID", Err: ErrorInvalidTaxID},
+		ErrorCodes.Def{N: 5, Slug: "INVALID_TIMEZONE", Err: ErrorInvalidTimezone},
+	)
+}
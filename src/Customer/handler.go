@@ -2,6 +2,8 @@ package Customer
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"time"
 
@@ -9,6 +11,9 @@ import (
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
+	"savannah/src/ErrorCodes"
+	"savannah/src/MergePatch"
+	"savannah/src/Pagination"
 )
 
 type Handler struct {
@@ -38,23 +43,28 @@ func NewHandler(s Service, log *zap.Logger) *Handler {
 	return &Handler{svc: s, log: log, v: v}
 }
 func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
-	q := ListCustomersQuery{Limit: 20}
-	// parse query params
-	if l := r.URL.Query().Get("limit"); l != "" {
-		// ignore parse errors for brevity; production: validate properly
-	}
+	q := ListCustomersQuery{}
+	q.Limit, q.Offset = Pagination.Params(r.URL.Query(), 20, 100)
 	if s := r.URL.Query().Get("search"); s != "" {
 		q.Search = s
 	}
+	if e := r.URL.Query().Get("email"); e != "" {
+		q.Email = e
+	}
 	if st := r.URL.Query().Get("status"); st != "" {
 		q.Status = st
 	}
 	customers, err := h.svc.List(r.Context(), q)
+	var total int
+	if err == nil {
+		total, err = h.svc.Count(r.Context(), q)
+	}
 	if err != nil {
 		h.log.Error("list customers", zap.Error(err))
 		h.writeError(w, http.StatusInternalServerError, "failed to list")
 		return
 	}
+	Pagination.Write(w, r, q.Limit, q.Offset, total)
 	h.writeJSON(w, http.StatusOK, customers)
 }
 
@@ -70,6 +80,10 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 	}
 	c, err := h.svc.Create(r.Context(), dto)
 	if err != nil {
+		if errors.Is(err, ErrorInvalidTaxID) || errors.Is(err, ErrorInvalidTimezone) {
+			h.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		h.log.Error("create", zap.Error(err))
 		h.writeError(w, http.StatusInternalServerError, "failed to create")
 		return
@@ -122,6 +136,10 @@ func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
 			h.writeError(w, http.StatusConflict, "version conflict")
 			return
 		}
+		if errors.Is(err, ErrorInvalidTaxID) || errors.Is(err, ErrorInvalidTimezone) {
+			h.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		if err == ErrorNotFound {
 			h.writeError(w, http.StatusNotFound, "not found")
 			return
@@ -131,6 +149,83 @@ func (h *Handler) Update(w http.ResponseWriter, r *http.Request) {
 	}
 	h.writeJSON(w, http.StatusOK, updated)
 }
+
+// Patch applies a JSON merge patch (application/merge-patch+json)
+func (h *Handler) Patch(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var patch PatchCustomerRequest
+	if err := MergePatch.Decode(r, &patch); err != nil {
+		if errors.Is(err, MergePatch.ErrorContentType) {
+			h.writeError(w, http.StatusUnsupportedMediaType, err.Error())
+			return
+		}
+		h.writeError(w, http.StatusBadRequest, "invalid json: "+err.Error())
+		return
+	}
+	if err := h.validatePatch(patch); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	updated, err := h.svc.Patch(r.Context(), id, patch)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrorConflict):
+			h.writeError(w, http.StatusConflict, "version conflict")
+		case errors.Is(err, ErrorInvalidTaxID), errors.Is(err, ErrorInvalidTimezone):
+			h.writeError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, ErrorNotFound):
+			h.writeError(w, http.StatusNotFound, "not found")
+		default:
+			h.log.Error("patch customer", zap.Error(err))
+			h.writeError(w, http.StatusInternalServerError, "failed to update")
+		}
+		return
+	}
+	h.writeJSON(w, http.StatusOK, updated)
+}
+
+// validatePatch applies the create rules to the members being set
+func (h *Handler) validatePatch(p PatchCustomerRequest) error {
+	fields := []struct {
+		name     string
+		field    MergePatch.Field[string]
+		rules    string
+		nullable bool
+	}{
+		{"first_name", p.FirstName, "min=2,max=100", false},
+		{"last_name", p.LastName, "min=2,max=100", false},
+		{"email", p.Email, "email", false},
+		{"phone", p.Phone, "e164", false},
+		{"company_name", p.CompanyName, "min=1,max=200", true},
+		{"tax_id", p.TaxID, "min=1,max=30", true},
+		{"tax_id_type", p.TaxIDType, "oneof=VAT PIN", true},
+		{"tax_country", p.TaxCountry, "len=2", true},
+		{"timezone", p.Timezone, "min=1,max=64", true},
+	}
+	for _, f := range fields {
+		if !f.field.Set {
+			continue
+		}
+		if f.field.Null {
+			if !f.nullable {
+				return fmt.Errorf("%w: %s cannot be null", ErrorInvalidPayload, f.name)
+			}
+			continue
+		}
+		if err := h.v.Var(f.field.Value, f.rules); err != nil {
+			return fmt.Errorf("%w: %s: %s", ErrorInvalidPayload, f.name, err.Error())
+		}
+	}
+	if p.Version.Set && p.Version.Null {
+		return fmt.Errorf("%w: version cannot be null", ErrorInvalidPayload)
+	}
+	return nil
+}
+
 func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := uuid.Parse(idStr)
@@ -151,5 +246,5 @@ func (h *Handler) writeJSON(w http.ResponseWriter, status int, v interface{}) {
 	_ = json.NewEncoder(w).Encode(v)
 }
 func (h *Handler) writeError(w http.ResponseWriter, status int, msg string) {
-	h.writeJSON(w, status, map[string]interface{}{"error": msg, "timestamp": time.Now().UTC()})
+	h.writeJSON(w, status, map[string]interface{}{"error": msg, "code": ErrorCodes.Resolve("CUSTOMERS", status, msg), "timestamp": time.Now().UTC()})
 }
@@ -19,6 +19,11 @@ LastName string `db:"last_name" json:"last_name"`
 Email string `db:"email" json:"email"`
 Phone string `db:"phone" json:"phone"`
 Status string `db:"status" json:"status"` // ACTIVE, SUSPENDED, DELETED
+CompanyName *string `db:"company_name" json:"company_name,omitempty"`
+TaxID *string `db:"tax_id" json:"tax_id,omitempty"`
+TaxIDType *string `db:"tax_id_type" json:"tax_id_type,omitempty"` // VAT, PIN
+TaxCountry *string `db:"tax_country" json:"tax_country,omitempty"`
+Timezone *string `db:"timezone" json:"timezone,omitempty"` // IANA name, e.g. Africa/Nairobi; the tenant default when unset
 CreatedAt time.Time `db:"created_at" json:"created_at"`
 UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
 Version int `db:"version" json:"version"` 
@@ -26,3 +31,6 @@ Version int `db:"version" json:"version"`
 }
 
 const TableName = "customers"
+
+// email and phone are read from the encrypted columns once a row is encrypted
+const columns = `id, first_name, last_name, COALESCE(email, '') AS email, COALESCE(phone, '') AS phone, email_encrypted, phone_encrypted, status, company_name, tax_id, tax_id_type, tax_country, timezone, created_at, updated_at, version`
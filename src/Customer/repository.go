@@ -3,9 +3,13 @@ package Customer
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"savannah/src/Storage"
+
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 	"go.uber.org/zap"
@@ -15,17 +19,77 @@ type Repository interface {
 	Create(ctx context.Context, c *Customer) error
 	GetByID(ctx context.Context, id uuid.UUID) (*Customer, error)
 	List(ctx context.Context, q ListCustomersQuery) ([]Customer, error)
+	Count(ctx context.Context, q ListCustomersQuery) (int, error)
 	Update(ctx context.Context, c *Customer) error
 	Delete(ctx context.Context, id uuid.UUID) error
+	EncryptPending(ctx context.Context, limit int) (int, error)
 }
 
+// repository encrypts email and phone with keys; without a keyring they are
+// stored in plaintext as before.
 type repository struct {
-	db  *sqlx.DB
-	log *zap.Logger
+	db   *sqlx.DB
+	keys *Storage.Keyring
+	log  *zap.Logger
+}
+
+func NewRepository(db *sqlx.DB, keys *Storage.Keyring, log *zap.Logger) Repository {
+	return &repository{db: db, keys: keys, log: log}
+}
+
+// row is a customer as stored, before decryption
+type row struct {
+	Customer
+	EmailEncrypted *string `db:"email_encrypted"`
+	PhoneEncrypted *string `db:"phone_encrypted"`
+}
+
+// sealed is what is stored for a customer's email and phone
+type sealed struct {
+	Email, Phone                   *string
+	EmailEncrypted, PhoneEncrypted *string
+	EmailIndex, PhoneIndex         *string
+	KeyID                          *string
+}
+
+func normalizeEmail(email string) string { return strings.ToLower(strings.TrimSpace(email)) }
+
+func (r *repository) seal(email, phone string) (*sealed, error) {
+	if r.keys == nil {
+		return &sealed{Email: &email, Phone: &phone}, nil
+	}
+	emailEnc, err := r.keys.Encrypt(email)
+	if err != nil {
+		return nil, err
+	}
+	phoneEnc, err := r.keys.Encrypt(phone)
+	if err != nil {
+		return nil, err
+	}
+	emailIdx, phoneIdx, keyID := r.keys.BlindIndex(normalizeEmail(email)), r.keys.BlindIndex(strings.TrimSpace(phone)), r.keys.ActiveKeyID()
+	return &sealed{EmailEncrypted: &emailEnc, PhoneEncrypted: &phoneEnc, EmailIndex: &emailIdx, PhoneIndex: &phoneIdx, KeyID: &keyID}, nil
 }
 
-func NewRepository(db *sqlx.DB, log *zap.Logger) Repository {
-	return &repository{db: db, log: log}
+// open decrypts the row's email and phone; rows not yet encrypted are read as is
+func (r *repository) open(rw *row) (*Customer, error) {
+	c := rw.Customer
+	for _, f := range []struct {
+		enc *string
+		dst *string
+	}{{rw.EmailEncrypted, &c.Email}, {rw.PhoneEncrypted, &c.Phone}} {
+		if f.enc == nil {
+			continue
+		}
+		if r.keys == nil {
+			return nil, errors.New("customer data is encrypted but no keyring is configured")
+		}
+		v, err := r.keys.Decrypt(*f.enc)
+		if err != nil {
+			return nil, err
+		}
+		*f.dst = v
+	}
+	return &c, nil
 }
 
 func (r *repository) Create(ctx context.Context, c *Customer) error {
@@ -35,45 +99,104 @@ func (r *repository) Create(ctx context.Context, c *Customer) error {
 	c.UpdatedAt = now
 	c.Version = 1
 
-	query := fmt.Sprintf(`INSERT INTO %s (id, first_name, last_name, email, phone, status, created_at, updated_at, version) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)`, TableName)
-	_, err := r.db.ExecContext(ctx, query, c.ID, c.FirstName, c.LastName, c.Email, c.Phone, c.Status, c.CreatedAt, c.UpdatedAt, c.Version)
+	p, err := r.seal(c.Email, c.Phone)
+	if err != nil {
+		return err
+	}
+	query := fmt.Sprintf(`INSERT INTO %s (id, first_name, last_name, email, phone, email_encrypted, phone_encrypted, email_index, phone_index, pii_key_id, status, company_name, tax_id, tax_id_type, tax_country, timezone, created_at, updated_at, version)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19)`, TableName)
+	_, err = r.db.ExecContext(ctx, query, c.ID, c.FirstName, c.LastName, p.Email, p.Phone, p.EmailEncrypted, p.PhoneEncrypted, p.EmailIndex, p.PhoneIndex, p.KeyID,
+		c.Status, c.CompanyName, c.TaxID, c.TaxIDType, c.TaxCountry, c.Timezone, c.CreatedAt, c.UpdatedAt, c.Version)
 	return err
 }
 
 func (r *repository) GetByID(ctx context.Context, id uuid.UUID) (*Customer, error) {
-	var c Customer
-	query := fmt.Sprintf(`SELECT id, first_name, last_name, email, phone, status, created_at, updated_at, version FROM %s WHERE id=$1 AND status <> 'DELETED'`, TableName)
-	err := r.db.GetContext(ctx, &c, query, id)
+	var rw row
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE id=$1 AND status <> 'DELETED'`, columns, TableName)
+	err := r.db.GetContext(ctx, &rw, query, id)
 	if err == sql.ErrNoRows {
 		return nil, ErrorNotFound
 	}
-	return &c, err
+	if err != nil {
+		return nil, err
+	}
+	return r.open(&rw)
 }
+
+// List searches names; with encryption on, an email (in Search or Email) only
+// matches exactly, through the blind index.
 func (r *repository) List(ctx context.Context, q ListCustomersQuery) ([]Customer, error) {
-	base := fmt.Sprintf(`SELECT id, first_name, last_name, email, phone, status, created_at, updated_at, version FROM %s WHERE status <> 'DELETED'`, TableName)
+	where, args := r.listFilter(q)
+	base := fmt.Sprintf(`SELECT %s FROM %s`, columns, TableName) + where
+	base += fmt.Sprintf(" ORDER BY created_at DESC LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+	args = append(args, q.Limit, q.Offset)
+
+	rows := []row{}
+	if err := r.db.SelectContext(ctx, &rows, base, args...); err != nil {
+		return nil, err
+	}
+	customers := make([]Customer, 0, len(rows))
+	for i := range rows {
+		c, err := r.open(&rows[i])
+		if err != nil {
+			return nil, err
+		}
+		customers = append(customers, *c)
+	}
+	return customers, nil
+}
+
+// Count counts the customers List pages through
+func (r *repository) Count(ctx context.Context, q ListCustomersQuery) (int, error) {
+	where, args := r.listFilter(q)
+	var n int
+	err := r.db.GetContext(ctx, &n, `SELECT COUNT(*) FROM `+TableName+where, args...)
+	return n, err
+}
+
+// listFilter is the WHERE clause of the customer list filters
+func (r *repository) listFilter(q ListCustomersQuery) (string, []interface{}) {
+	base := ` WHERE status <> 'DELETED'`
 	args := []interface{}{}
 	idx := 1
 	if q.Search != "" {
-		base += fmt.Sprintf(" AND (first_name ILIKE $%d OR last_name ILIKE $%d OR email ILIKE $%d)", idx, idx+1, idx+2)
-		args = append(args, "%"+q.Search+"%", "%"+q.Search+"%", "%"+q.Search+"%")
-		idx += 3
+		if r.keys == nil {
+			base += fmt.Sprintf(" AND (first_name ILIKE $%d OR last_name ILIKE $%d OR email ILIKE $%d)", idx, idx+1, idx+2)
+			args = append(args, "%"+q.Search+"%", "%"+q.Search+"%", "%"+q.Search+"%")
+			idx += 3
+		} else {
+			base += fmt.Sprintf(" AND (first_name ILIKE $%d OR last_name ILIKE $%d OR email_index = $%d)", idx, idx+1, idx+2)
+			args = append(args, "%"+q.Search+"%", "%"+q.Search+"%", r.keys.BlindIndex(normalizeEmail(q.Search)))
+			idx += 3
+		}
+	}
+	if q.Email != "" {
+		if r.keys == nil {
+			base += fmt.Sprintf(" AND LOWER(email) = $%d", idx)
+			args = append(args, normalizeEmail(q.Email))
+		} else {
+			base += fmt.Sprintf(" AND email_index = $%d", idx)
+			args = append(args, r.keys.BlindIndex(normalizeEmail(q.Email)))
+		}
+		idx++
 	}
 	if q.Status != "" {
 		base += fmt.Sprintf(" AND status = $%d", idx)
 		args = append(args, q.Status)
-		idx++
 	}
-	base += fmt.Sprintf(" ORDER BY created_at DESC LIMIT $%d OFFSET $%d", idx, idx+1)
-	args = append(args, q.Limit, q.Offset)
-
-	customers := []Customer{}
-	err := r.db.SelectContext(ctx, &customers, base, args...)
-	return customers, err
+	return base, args
 }
+
 func (r *repository) Update(ctx context.Context, c *Customer) error {
+	p, err := r.seal(c.Email, c.Phone)
+	if err != nil {
+		return err
+	}
 	// optimistic locking: check version
-	query := fmt.Sprintf(`UPDATE %s SET first_name=$1, last_name=$2, email=$3, phone=$4, status=$5, updated_at=$6, version=version+1 WHERE id=$7 AND version=$8`, TableName)
-	res, err := r.db.ExecContext(ctx, query, c.FirstName, c.LastName, c.Email, c.Phone, c.Status, c.UpdatedAt, c.ID, c.Version)
+	query := fmt.Sprintf(`UPDATE %s SET first_name=$1, last_name=$2, email=$3, phone=$4, email_encrypted=$5, phone_encrypted=$6, email_index=$7, phone_index=$8, pii_key_id=$9,
+		status=$10, company_name=$11, tax_id=$12, tax_id_type=$13, tax_country=$14, timezone=$15, updated_at=$16, version=version+1 WHERE id=$17 AND version=$18`, TableName)
+	res, err := r.db.ExecContext(ctx, query, c.FirstName, c.LastName, p.Email, p.Phone, p.EmailEncrypted, p.PhoneEncrypted, p.EmailIndex, p.PhoneIndex, p.KeyID,
+		c.Status, c.CompanyName, c.TaxID, c.TaxIDType, c.TaxCountry, c.Timezone, c.UpdatedAt, c.ID, c.Version)
 	if err != nil {
 		return err
 	}
@@ -89,3 +212,45 @@ func (r *repository) Delete(ctx context.Context, id uuid.UUID) error {
 	_, err := r.db.ExecContext(ctx, query, time.Now().UTC(), id)
 	return err
 }
+
+// EncryptPending (re-)encrypts up to limit customers that are in plaintext or
+// on an older key and returns how many it wrote. Deleted customers are
+// included; their data is still at rest. Version and updated_at are left
+// alone, this is not an edit.
+func (r *repository) EncryptPending(ctx context.Context, limit int) (n int, err error) {
+	if r.keys == nil {
+		return 0, nil
+	}
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	rows := []row{}
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE pii_key_id IS DISTINCT FROM $1 ORDER BY id LIMIT $2 FOR UPDATE SKIP LOCKED`, columns, TableName)
+	if err = tx.SelectContext(ctx, &rows, query, r.keys.ActiveKeyID(), limit); err != nil {
+		return 0, err
+	}
+	update := fmt.Sprintf(`UPDATE %s SET email=NULL, phone=NULL, email_encrypted=$1, phone_encrypted=$2, email_index=$3, phone_index=$4, pii_key_id=$5 WHERE id=$6`, TableName)
+	for i := range rows {
+		var c *Customer
+		if c, err = r.open(&rows[i]); err != nil {
+			return 0, fmt.Errorf("customer %s: %w", rows[i].ID, err)
+		}
+		var p *sealed
+		if p, err = r.seal(c.Email, c.Phone); err != nil {
+			return 0, err
+		}
+		if _, err = tx.ExecContext(ctx, update, p.EmailEncrypted, p.PhoneEncrypted, p.EmailIndex, p.PhoneIndex, p.KeyID, c.ID); err != nil {
+			return 0, err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(rows), nil
+}
@@ -12,8 +12,12 @@ type Service interface {
 	Create(ctx context.Context, dto CreateCustomerRequest) (*Customer, error)
 	Get(ctx context.Context, id uuid.UUID) (*Customer, error)
 	List(ctx context.Context, q ListCustomersQuery) ([]Customer, error)
+	Count(ctx context.Context, q ListCustomersQuery) (int, error)
 	Update(ctx context.Context, id uuid.UUID, dto UpdateCustomerRequest) (*Customer, error)
+	Patch(ctx context.Context, id uuid.UUID, patch PatchCustomerRequest) (*Customer, error)
 	Delete(ctx context.Context, id uuid.UUID) error
+	Email(ctx context.Context, id uuid.UUID) (string, error)
+	EncryptPending(ctx context.Context) error
 }
 
 type service struct {
@@ -33,6 +37,15 @@ func (s *service) Create(ctx context.Context, dto CreateCustomerRequest) (*Custo
 		Phone:     dto.Phone,
 		Status:    "ACTIVE",
 	}
+	if dto.CompanyName != nil && *dto.CompanyName != "" {
+		c.CompanyName = dto.CompanyName
+	}
+	if err := c.setTaxID(dto.TaxID, dto.TaxIDType, dto.TaxCountry); err != nil {
+		return nil, err
+	}
+	if err := c.setTimezone(dto.Timezone); err != nil {
+		return nil, err
+	}
 	if err := s.repo.Create(ctx, c); err != nil {
 		s.log.Error("create customer", zap.Error(err))
 		return nil, err
@@ -50,6 +63,12 @@ func (s *service) List(ctx context.Context, q ListCustomersQuery) ([]Customer, e
 	}
 	return s.repo.List(ctx, q)
 }
+
+// Count counts the customers matching the list filters, ignoring the page
+func (s *service) Count(ctx context.Context, q ListCustomersQuery) (int, error) {
+	return s.repo.Count(ctx, q)
+}
+
 func (s *service) Update(ctx context.Context, id uuid.UUID, dto UpdateCustomerRequest) (*Customer, error) {
 	c, err := s.repo.GetByID(ctx, id)
 	if err != nil {
@@ -71,6 +90,33 @@ func (s *service) Update(ctx context.Context, id uuid.UUID, dto UpdateCustomerRe
 	if dto.Phone != nil {
 		c.Phone = *dto.Phone
 	}
+	if dto.CompanyName != nil {
+		c.CompanyName = dto.CompanyName
+		if *dto.CompanyName == "" {
+			c.CompanyName = nil
+		}
+	}
+	if dto.TaxID != nil || dto.TaxIDType != nil || dto.TaxCountry != nil {
+		// fields left out keep their current value
+		id, idType, country := c.TaxID, c.TaxIDType, c.TaxCountry
+		if dto.TaxID != nil {
+			id = dto.TaxID
+		}
+		if dto.TaxIDType != nil {
+			idType = dto.TaxIDType
+		}
+		if dto.TaxCountry != nil {
+			country = dto.TaxCountry
+		}
+		if err := c.setTaxID(id, idType, country); err != nil {
+			return nil, err
+		}
+	}
+	if dto.Timezone != nil {
+		if err := c.setTimezone(dto.Timezone); err != nil {
+			return nil, err
+		}
+	}
 	c.UpdatedAt = time.Now().UTC()
 	if err := s.repo.Update(ctx, c); err != nil {
 		return nil, err
@@ -79,6 +125,89 @@ func (s *service) Update(ctx context.Context, id uuid.UUID, dto UpdateCustomerRe
 	return s.repo.GetByID(ctx, id)
 }
 
+// Patch applies a merge patch validated by the handler. Unlike Update, a
+// tax member or timezone sent as null clears it instead of keeping the stored value.
+func (s *service) Patch(ctx context.Context, id uuid.UUID, patch PatchCustomerRequest) (*Customer, error) {
+	c, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if patch.Version.Set && patch.Version.Value != c.Version {
+		return nil, ErrorConflict
+	}
+	if patch.FirstName.Set {
+		c.FirstName = patch.FirstName.Value
+	}
+	if patch.LastName.Set {
+		c.LastName = patch.LastName.Value
+	}
+	if patch.Email.Set {
+		c.Email = patch.Email.Value
+	}
+	if patch.Phone.Set {
+		c.Phone = patch.Phone.Value
+	}
+	if patch.CompanyName.Set {
+		c.CompanyName = patch.CompanyName.Ptr()
+	}
+	if patch.TaxID.Set || patch.TaxIDType.Set || patch.TaxCountry.Set {
+		id, idType, country := c.TaxID, c.TaxIDType, c.TaxCountry
+		if patch.TaxID.Set {
+			id = patch.TaxID.Ptr()
+		}
+		if patch.TaxIDType.Set {
+			idType = patch.TaxIDType.Ptr()
+		}
+		if patch.TaxCountry.Set {
+			country = patch.TaxCountry.Ptr()
+		}
+		if err := c.setTaxID(id, idType, country); err != nil {
+			return nil, err
+		}
+	}
+	if patch.Timezone.Set {
+		if err := c.setTimezone(patch.Timezone.Ptr()); err != nil {
+			return nil, err
+		}
+	}
+	c.UpdatedAt = time.Now().UTC()
+	if err := s.repo.Update(ctx, c); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(ctx, c.ID)
+}
+
 func (s *service) Delete(ctx context.Context, id uuid.UUID) error {
 	return s.repo.Delete(ctx, id)
 }
+
+// Email returns the customer's (decrypted) email address
+func (s *service) Email(ctx context.Context, id uuid.UUID) (string, error) {
+	c, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	return c.Email, nil
+}
+
+const encryptBatch = 200
+
+// EncryptPending encrypts customers still stored in plaintext and re-encrypts
+// those on a rotated-out key, batch by batch until none are left.
+func (s *service) EncryptPending(ctx context.Context) error {
+	total := 0
+	for {
+		n, err := s.repo.EncryptPending(ctx, encryptBatch)
+		total += n
+		if err != nil {
+			return err
+		}
+		if n < encryptBatch || ctx.Err() != nil {
+			break
+		}
+	}
+	if total > 0 {
+		s.log.Info("customer data encrypted", zap.Int("customers", total))
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package Customer
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// tax registration types
+const (
+	TaxIDVAT = "VAT" // EU-style VAT number, prefixed with the country code
+	TaxIDPIN = "PIN" // Kenya Revenue Authority PIN
+)
+
+var (
+	vatPattern = regexp.MustCompile(`^[A-Z]{2}[0-9A-Z]{2,13}$`)
+	pinPattern = regexp.MustCompile(`^[AP][0-9]{9}[A-Z]$`)
+)
+
+// NormalizeTaxID upper-cases the id and drops the spaces, dots and dashes people type
+func NormalizeTaxID(id string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '.', '-':
+			return -1
+		}
+		return r
+	}, strings.ToUpper(strings.TrimSpace(id)))
+}
+
+// ValidateTaxID checks the format of a normalised tax id for its type and country
+func ValidateTaxID(idType, country, id string) error {
+	switch idType {
+	case TaxIDVAT:
+		prefix := country
+		if country == "GR" {
+			prefix = "EL" // Greek VAT numbers use EL
+		}
+		if !vatPattern.MatchString(id) || id[:2] != prefix {
+			return fmt.Errorf("%w: VAT number must start with %s", ErrorInvalidTaxID, prefix)
+		}
+	case TaxIDPIN:
+		if country != "KE" {
+			return fmt.Errorf("%w: PIN is only issued in KE", ErrorInvalidTaxID)
+		}
+		if !pinPattern.MatchString(id) {
+			return fmt.Errorf("%w: PIN must look like A123456789B", ErrorInvalidTaxID)
+		}
+	default:
+		return fmt.Errorf("%w: unknown type %q", ErrorInvalidTaxID, idType)
+	}
+	return nil
+}
+
+// setTaxID normalises and validates the customer's tax registration; a nil
+// or empty id clears it.
+func (c *Customer) setTaxID(id, idType, country *string) error {
+	if id == nil || *id == "" {
+		c.TaxID, c.TaxIDType, c.TaxCountry = nil, nil, nil
+		return nil
+	}
+	if idType == nil || country == nil {
+		return fmt.Errorf("%w: tax_id_type and tax_country are required", ErrorInvalidTaxID)
+	}
+	normalized := NormalizeTaxID(*id)
+	cc := strings.ToUpper(*country)
+	if err := ValidateTaxID(*idType, cc, normalized); err != nil {
+		return err
+	}
+	c.TaxID, c.TaxIDType, c.TaxCountry = &normalized, idType, &cc
+	return nil
+}
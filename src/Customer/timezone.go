@@ -0,0 +1,32 @@
+package Customer
+
+import (
+	"fmt"
+	"time"
+)
+
+// setTimezone validates an IANA zone name for the customer; a nil or empty
+// name clears it, leaving the tenant default.
+func (c *Customer) setTimezone(name *string) error {
+	if name == nil || *name == "" {
+		c.Timezone = nil
+		return nil
+	}
+	if _, err := time.LoadLocation(*name); err != nil || *name == "Local" {
+		return fmt.Errorf("%w: unknown timezone %q", ErrorInvalidTimezone, *name)
+	}
+	c.Timezone = name
+	return nil
+}
+
+// Location returns the customer's timezone, or nil when they have none
+func (c *Customer) Location() *time.Location {
+	if c.Timezone == nil {
+		return nil
+	}
+	loc, err := time.LoadLocation(*c.Timezone)
+	if err != nil {
+		return nil
+	}
+	return loc
+}
@@ -0,0 +1,146 @@
+package ErrorCodes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Code is the stable, machine readable identity of an error, returned as
+// "code" in error responses so clients need not parse messages. Codes read
+// MODULE_NNN_SLUG; a number is never reused once published.
+type Code struct {
+	Code    string `json:"code"`
+	Module  string `json:"module"`
+	Message string `json:"message"`
+}
+
+// Def assigns a code to one error of a module. Err is matched with
+// errors.Is and its message is the catalog message; typed errors that carry
+// details set Match and Message instead.
+type Def struct {
+	N       int
+	Slug    string
+	Err     error
+	Match   func(error) bool
+	Message string
+}
+
+type entry struct {
+	Code
+	def Def
+}
+
+// errors every module's handlers answer with
+var (
+	ErrorInvalidJSON = errors.New("invalid json")
+	ErrorInvalidID   = errors.New("invalid id")
+)
+
+func init() {
+	Register("COMMON",
+		Def{N: 1, Slug: "INVALID_JSON", Err: ErrorInvalidJSON},
+		Def{N: 2, Slug: "INVALID_ID", Err: ErrorInvalidID},
+	)
+}
+
+var (
+	mu      sync.RWMutex
+	entries []entry
+	byCode  = map[string]bool{}
+)
+
+// Register adds the codes of a module from its package init. Codes are part
+// of the API: new errors get the next number and numbers are never reused.
+// A duplicate code panics so the clash fails at startup.
+func Register(module string, defs ...Def) {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, d := range defs {
+		c := Code{Code: fmt.Sprintf("%s_%03d_%s", module, d.N, d.Slug), Module: module, Message: d.Message}
+		if d.Err != nil && c.Message == "" {
+			c.Message = d.Err.Error()
+		}
+		if byCode[c.Code] {
+			panic("ErrorCodes: duplicate code " + c.Code)
+		}
+		byCode[c.Code] = true
+		entries = append(entries, entry{Code: c, def: d})
+	}
+}
+
+// For returns the code of err, or false when it has none
+func For(err error) (string, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, e := range entries {
+		if (e.def.Err != nil && errors.Is(err, e.def.Err)) || (e.def.Match != nil && e.def.Match(err)) {
+			return e.Code.Code, true
+		}
+	}
+	return "", false
+}
+
+// Resolve finds the code of an error response from its message, as the
+// handlers write it: the message of a registered error, possibly followed by
+// ": detail". The module's own errors are preferred; a message no registered
+// error produced gets the generic code of the status, e.g. COMMON_404_NOT_FOUND.
+func Resolve(module string, status int, msg string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+	var other string
+	for _, e := range entries {
+		if e.def.Err == nil || !matches(msg, e.def.Err.Error()) {
+			continue
+		}
+		if e.Module == module {
+			return e.Code.Code
+		}
+		if other == "" {
+			other = e.Code.Code
+		}
+	}
+	if other != "" {
+		return other
+	}
+	return Generic(status)
+}
+
+func matches(msg, errMsg string) bool {
+	return msg == errMsg || strings.HasPrefix(msg, errMsg+": ")
+}
+
+// Generic is the code of an error without a more specific one
+func Generic(status int) string {
+	slug := strings.ToUpper(strings.ReplaceAll(http.StatusText(status), " ", "_"))
+	if slug == "" {
+		slug = "ERROR"
+	}
+	return fmt.Sprintf("COMMON_%03d_%s", status, strings.NewReplacer("'", "", "-", "_").Replace(slug))
+}
+
+// genericStatuses are the statuses handlers answer with, listed in the catalog
+var genericStatuses = []int{
+	http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound,
+	http.StatusMethodNotAllowed, http.StatusConflict, http.StatusGone, http.StatusRequestEntityTooLarge,
+	http.StatusUnsupportedMediaType, http.StatusUnprocessableEntity, http.StatusTooManyRequests,
+	http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable,
+}
+
+// List returns every code, generic ones included, sorted by code
+func List() []Code {
+	mu.RLock()
+	out := make([]Code, 0, len(entries)+len(genericStatuses))
+	for _, e := range entries {
+		out = append(out, e.Code)
+	}
+	mu.RUnlock()
+	for _, status := range genericStatuses {
+		out = append(out, Code{Code: Generic(status), Module: "COMMON", Message: strings.ToLower(http.StatusText(status))})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Code < out[j].Code })
+	return out
+}
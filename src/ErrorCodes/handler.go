@@ -0,0 +1,19 @@
+package ErrorCodes
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ListCodes godoc
+// @Summary      Error code catalog
+// @Description  Every machine readable error code the API returns in the "code" field of error responses, with its module and message
+// @Tags         errors
+// @Produce      json
+// @Success      200  {array}  Code
+// @Router       /errors/catalog [get]
+func ListCodes(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(List())
+}
@@ -0,0 +1,15 @@
+package Exports
+
+import "time"
+
+type CreateFilterRequest struct {
+	Name     string  `json:"name" validate:"required,max=100"`
+	Resource string  `json:"resource" validate:"required,oneof=orders products customers"`
+	Filters  Filters `json:"filters"`
+}
+
+type CreateScheduleRequest struct {
+	Recipients    []string   `json:"recipients" validate:"required,min=1,max=20,dive,email"`
+	IntervalHours int        `json:"interval_hours" validate:"required,min=1,max=744"`
+	StartAt       *time.Time `json:"start_at,omitempty"` // first run, now when empty
+}
@@ -0,0 +1,21 @@
+package Exports
+
+import (
+	"errors"
+
+	"savannah/src/ErrorCodes"
+)
+
+var (
+	ErrorFilterNotFound   = errors.New("saved filter not found")
+	ErrorScheduleNotFound = errors.New("export schedule not found")
+	ErrorUnknownResource  = errors.New("unknown list resource")
+)
+
+func init() {
+	ErrorCodes.Register("EXPORTS",
+		ErrorCodes.Def{N: 1, Slug: "FILTER_NOT_FOUND", Err: ErrorFilterNotFound},
+		ErrorCodes.Def{N: 2, Slug: "SCHEDULE_NOT_FOUND", Err: ErrorScheduleNotFound},
+		ErrorCodes.Def{N: 3, Slug: "UNKNOWN_RESOURCE", Err: ErrorUnknownResource},
+	)
+}
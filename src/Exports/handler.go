@@ -0,0 +1,245 @@
+package Exports
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"savannah/src/ErrorCodes"
+)
+
+type Handler struct {
+	svc Service
+	log *zap.Logger
+	v   *validator.Validate
+}
+
+func NewHandler(s Service, log *zap.Logger) *Handler {
+	return &Handler{svc: s, log: log, v: validator.New()}
+}
+
+// ListFilters godoc
+// @Summary      List saved filters
+// @Tags         exports
+// @Produce      json
+// @Param        resource  query     string  false  "orders, products or customers"
+// @Success      200       {array}   SavedFilter
+// @Router       /saved-filters [get]
+func (h *Handler) ListFilters(w http.ResponseWriter, r *http.Request) {
+	filters, err := h.svc.ListFilters(r.Context(), r.URL.Query().Get("resource"))
+	if err != nil {
+		h.log.Error("list saved filters", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to list saved filters")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, filters)
+}
+
+// CreateFilter godoc
+// @Summary      Save a named filter set for an admin list
+// @Description  Filters are the list endpoint's query parameters, e.g. {"status": "CONFIRMED", "warehouse": "NBO"}
+// @Tags         exports
+// @Accept       json
+// @Produce      json
+// @Param        filter  body      CreateFilterRequest  true  "Filter"
+// @Success      201     {object}  SavedFilter
+// @Failure      400     {object}  map[string]interface{}
+// @Router       /saved-filters [post]
+func (h *Handler) CreateFilter(w http.ResponseWriter, r *http.Request) {
+	var dto CreateFilterRequest
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if err := h.v.Struct(dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	f, err := h.svc.CreateFilter(r.Context(), dto)
+	if err != nil {
+		if errors.Is(err, ErrorUnknownResource) {
+			h.writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		h.log.Error("create saved filter", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to save filter")
+		return
+	}
+	h.writeJSON(w, http.StatusCreated, f)
+}
+
+// GetFilter godoc
+// @Summary      Get a saved filter
+// @Tags         exports
+// @Produce      json
+// @Param        id   path      string  true  "Filter ID"
+// @Success      200  {object}  SavedFilter
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /saved-filters/{id} [get]
+func (h *Handler) GetFilter(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	f, err := h.svc.GetFilter(r.Context(), id)
+	if err != nil {
+		h.notFoundOr500(w, err, "get saved filter")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, f)
+}
+
+// DeleteFilter godoc
+// @Summary      Delete a saved filter and its export schedules
+// @Tags         exports
+// @Param        id   path  string  true  "Filter ID"
+// @Success      204
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /saved-filters/{id} [delete]
+func (h *Handler) DeleteFilter(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	if err := h.svc.DeleteFilter(r.Context(), id); err != nil {
+		h.notFoundOr500(w, err, "delete saved filter")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Export godoc
+// @Summary      Download a saved filter as CSV
+// @Tags         exports
+// @Produce      text/csv
+// @Param        id   path  string  true  "Filter ID"
+// @Success      200
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /saved-filters/{id}/export [get]
+func (h *Handler) Export(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var buf bytes.Buffer
+	f, err := h.svc.Export(r.Context(), id, &buf)
+	if err != nil {
+		h.notFoundOr500(w, err, "export saved filter")
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", f.Resource+"-"+time.Now().UTC().Format("2006-01-02")+".csv"))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(buf.Bytes())
+}
+
+// ListSchedules godoc
+// @Summary      List export schedules of a saved filter
+// @Tags         exports
+// @Produce      json
+// @Param        id   path      string  true  "Filter ID"
+// @Success      200  {array}   Schedule
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /saved-filters/{id}/schedules [get]
+func (h *Handler) ListSchedules(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	schedules, err := h.svc.ListSchedules(r.Context(), id)
+	if err != nil {
+		h.notFoundOr500(w, err, "list export schedules")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, schedules)
+}
+
+// CreateSchedule godoc
+// @Summary      Email a saved filter's CSV export on a schedule
+// @Tags         exports
+// @Accept       json
+// @Produce      json
+// @Param        id        path      string                 true  "Filter ID"
+// @Param        schedule  body      CreateScheduleRequest  true  "Schedule"
+// @Success      201       {object}  Schedule
+// @Failure      400       {object}  map[string]interface{}
+// @Failure      404       {object}  map[string]interface{}
+// @Router       /saved-filters/{id}/schedules [post]
+func (h *Handler) CreateSchedule(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var dto CreateScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if err := h.v.Struct(dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	sc, err := h.svc.CreateSchedule(r.Context(), id, dto)
+	if err != nil {
+		h.notFoundOr500(w, err, "create export schedule")
+		return
+	}
+	h.writeJSON(w, http.StatusCreated, sc)
+}
+
+// DeleteSchedule godoc
+// @Summary      Delete an export schedule
+// @Tags         exports
+// @Param        id   path  string  true  "Filter ID"
+// @Param        sid  path  string  true  "Schedule ID"
+// @Success      204
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /saved-filters/{id}/schedules/{sid} [delete]
+func (h *Handler) DeleteSchedule(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	sid, err := uuid.Parse(chi.URLParam(r, "sid"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid schedule id")
+		return
+	}
+	if err := h.svc.DeleteSchedule(r.Context(), id, sid); err != nil {
+		h.notFoundOr500(w, err, "delete export schedule")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) notFoundOr500(w http.ResponseWriter, err error, action string) {
+	if errors.Is(err, ErrorFilterNotFound) || errors.Is(err, ErrorScheduleNotFound) {
+		h.writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	h.log.Error(action, zap.Error(err))
+	h.writeError(w, http.StatusInternalServerError, "failed to "+action)
+}
+
+func (h *Handler) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (h *Handler) writeError(w http.ResponseWriter, status int, msg string) {
+	h.writeJSON(w, status, map[string]interface{}{"error": msg, "code": ErrorCodes.Resolve("EXPORTS", status, msg), "timestamp": time.Now().UTC()})
+}
@@ -0,0 +1,71 @@
+package Exports
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// admin lists a filter can be saved for
+const (
+	ResourceOrders    = "orders"
+	ResourceProducts  = "products"
+	ResourceCustomers = "customers"
+)
+
+// Filters are the list endpoint's query parameters, e.g. {"status": "CONFIRMED"}
+type Filters map[string]string
+
+func (f Filters) Value() (driver.Value, error) {
+	if f == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(f)
+}
+
+func (f *Filters) Scan(src interface{}) error {
+	var raw []byte
+	switch v := src.(type) {
+	case nil:
+		*f = nil
+		return nil
+	case []byte:
+		raw = v
+	case string:
+		raw = []byte(v)
+	default:
+		return fmt.Errorf("exports: cannot scan %T into Filters", src)
+	}
+	return json.Unmarshal(raw, f)
+}
+
+// SavedFilter is a named filter set for one admin list
+type SavedFilter struct {
+	ID        uuid.UUID `db:"id" json:"id"`
+	Name      string    `db:"name" json:"name"`
+	Resource  string    `db:"resource" json:"resource"`
+	Filters   Filters   `db:"filters" json:"filters"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// Schedule emails a CSV export of a saved filter every IntervalHours
+type Schedule struct {
+	ID            uuid.UUID      `db:"id" json:"id"`
+	FilterID      uuid.UUID      `db:"filter_id" json:"filter_id"`
+	Recipients    pq.StringArray `db:"recipients" json:"recipients"`
+	IntervalHours int            `db:"interval_hours" json:"interval_hours"`
+	NextRunAt     time.Time      `db:"next_run_at" json:"next_run_at"`
+	LastRunAt     *time.Time     `db:"last_run_at" json:"last_run_at,omitempty"`
+	LastError     *string        `db:"last_error" json:"last_error,omitempty"`
+	CreatedAt     time.Time      `db:"created_at" json:"created_at"`
+}
+
+const (
+	FilterTable   = "saved_filters"
+	ScheduleTable = "export_schedules"
+)
@@ -0,0 +1,110 @@
+package Exports
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+type Repository interface {
+	CreateFilter(ctx context.Context, f *SavedFilter) error
+	GetFilter(ctx context.Context, id uuid.UUID) (*SavedFilter, error)
+	ListFilters(ctx context.Context, resource string) ([]SavedFilter, error)
+	DeleteFilter(ctx context.Context, id uuid.UUID) error
+
+	CreateSchedule(ctx context.Context, s *Schedule) error
+	ListSchedules(ctx context.Context, filterID uuid.UUID) ([]Schedule, error)
+	DeleteSchedule(ctx context.Context, filterID, id uuid.UUID) error
+	ClaimDue(ctx context.Context, limit int) ([]Schedule, error)
+	MarkRun(ctx context.Context, id uuid.UUID, runAt time.Time, runErr *string) error
+}
+
+type repository struct {
+	db  *sqlx.DB
+	log *zap.Logger
+}
+
+func NewRepository(db *sqlx.DB, log *zap.Logger) Repository { return &repository{db: db, log: log} }
+
+const (
+	filterColumns   = `id,name,resource,filters,created_at,updated_at`
+	scheduleColumns = `id,filter_id,recipients,interval_hours,next_run_at,last_run_at,last_error,created_at`
+)
+
+func (r *repository) CreateFilter(ctx context.Context, f *SavedFilter) error {
+	query := fmt.Sprintf(`INSERT INTO %s (%s) VALUES ($1,$2,$3,$4,$5,$6)`, FilterTable, filterColumns)
+	_, err := r.db.ExecContext(ctx, query, f.ID, f.Name, f.Resource, f.Filters, f.CreatedAt, f.UpdatedAt)
+	return err
+}
+
+func (r *repository) GetFilter(ctx context.Context, id uuid.UUID) (*SavedFilter, error) {
+	var f SavedFilter
+	err := r.db.GetContext(ctx, &f, fmt.Sprintf(`SELECT %s FROM %s WHERE id=$1`, filterColumns, FilterTable), id)
+	if err == sql.ErrNoRows {
+		return nil, ErrorFilterNotFound
+	}
+	return &f, err
+}
+
+func (r *repository) ListFilters(ctx context.Context, resource string) ([]SavedFilter, error) {
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE ($1 = '' OR resource = $1) ORDER BY name`, filterColumns, FilterTable)
+	out := []SavedFilter{}
+	err := r.db.SelectContext(ctx, &out, query, resource)
+	return out, err
+}
+
+func (r *repository) DeleteFilter(ctx context.Context, id uuid.UUID) error {
+	res, err := r.db.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE id=$1`, FilterTable), id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrorFilterNotFound
+	}
+	return nil
+}
+
+func (r *repository) CreateSchedule(ctx context.Context, s *Schedule) error {
+	query := fmt.Sprintf(`INSERT INTO %s (%s) VALUES ($1,$2,$3,$4,$5,$6,$7,$8)`, ScheduleTable, scheduleColumns)
+	_, err := r.db.ExecContext(ctx, query, s.ID, s.FilterID, s.Recipients, s.IntervalHours, s.NextRunAt, s.LastRunAt, s.LastError, s.CreatedAt)
+	return err
+}
+
+func (r *repository) ListSchedules(ctx context.Context, filterID uuid.UUID) ([]Schedule, error) {
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE filter_id=$1 ORDER BY created_at`, scheduleColumns, ScheduleTable)
+	out := []Schedule{}
+	err := r.db.SelectContext(ctx, &out, query, filterID)
+	return out, err
+}
+
+func (r *repository) DeleteSchedule(ctx context.Context, filterID, id uuid.UUID) error {
+	res, err := r.db.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE id=$1 AND filter_id=$2`, ScheduleTable), id, filterID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrorScheduleNotFound
+	}
+	return nil
+}
+
+// ClaimDue advances next_run_at of due schedules by their interval so that
+// concurrent workers don't pick them up again, and returns them.
+func (r *repository) ClaimDue(ctx context.Context, limit int) ([]Schedule, error) {
+	query := fmt.Sprintf(`UPDATE %[1]s SET next_run_at = GREATEST(next_run_at, NOW()) + make_interval(hours => interval_hours) WHERE id IN (
+		SELECT id FROM %[1]s WHERE next_run_at <= NOW() ORDER BY next_run_at LIMIT $1 FOR UPDATE SKIP LOCKED
+	) RETURNING %[2]s`, ScheduleTable, scheduleColumns)
+	out := []Schedule{}
+	err := r.db.SelectContext(ctx, &out, query, limit)
+	return out, err
+}
+
+func (r *repository) MarkRun(ctx context.Context, id uuid.UUID, runAt time.Time, runErr *string) error {
+	_, err := r.db.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET last_run_at=$1, last_error=$2 WHERE id=$3`, ScheduleTable), runAt, runErr, id)
+	return err
+}
@@ -0,0 +1,180 @@
+package Exports
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"savannah/src/Notifications"
+)
+
+// Mailer delivers the scheduled exports
+type Mailer interface {
+	Send(ctx context.Context, m Notifications.Message) error
+}
+
+type Service interface {
+	CreateFilter(ctx context.Context, req CreateFilterRequest) (*SavedFilter, error)
+	GetFilter(ctx context.Context, id uuid.UUID) (*SavedFilter, error)
+	ListFilters(ctx context.Context, resource string) ([]SavedFilter, error)
+	DeleteFilter(ctx context.Context, id uuid.UUID) error
+	Export(ctx context.Context, filterID uuid.UUID, w io.Writer) (*SavedFilter, error)
+
+	CreateSchedule(ctx context.Context, filterID uuid.UUID, req CreateScheduleRequest) (*Schedule, error)
+	ListSchedules(ctx context.Context, filterID uuid.UUID) ([]Schedule, error)
+	DeleteSchedule(ctx context.Context, filterID, id uuid.UUID) error
+	ProcessDue(ctx context.Context) error
+}
+
+type service struct {
+	repo    Repository
+	sources map[string]Source
+	mailer  Mailer
+	log     *zap.Logger
+}
+
+func NewService(r Repository, sources map[string]Source, mailer Mailer, log *zap.Logger) Service {
+	return &service{repo: r, sources: sources, mailer: mailer, log: log}
+}
+
+const (
+	maxExportRows = 10000
+	batchSize     = 20
+)
+
+func (s *service) CreateFilter(ctx context.Context, req CreateFilterRequest) (*SavedFilter, error) {
+	if _, ok := s.sources[req.Resource]; !ok {
+		return nil, ErrorUnknownResource
+	}
+	now := time.Now().UTC()
+	f := &SavedFilter{ID: uuid.New(), Name: req.Name, Resource: req.Resource, Filters: req.Filters, CreatedAt: now, UpdatedAt: now}
+	if f.Filters == nil {
+		f.Filters = Filters{}
+	}
+	if err := s.repo.CreateFilter(ctx, f); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (s *service) GetFilter(ctx context.Context, id uuid.UUID) (*SavedFilter, error) {
+	return s.repo.GetFilter(ctx, id)
+}
+
+func (s *service) ListFilters(ctx context.Context, resource string) ([]SavedFilter, error) {
+	return s.repo.ListFilters(ctx, resource)
+}
+
+func (s *service) DeleteFilter(ctx context.Context, id uuid.UUID) error {
+	return s.repo.DeleteFilter(ctx, id)
+}
+
+// Export writes the saved filter's rows (up to maxExportRows) to w as CSV
+func (s *service) Export(ctx context.Context, filterID uuid.UUID, w io.Writer) (*SavedFilter, error) {
+	f, err := s.repo.GetFilter(ctx, filterID)
+	if err != nil {
+		return nil, err
+	}
+	return f, s.writeCSV(ctx, f, w)
+}
+
+func (s *service) writeCSV(ctx context.Context, f *SavedFilter, w io.Writer) error {
+	src, ok := s.sources[f.Resource]
+	if !ok {
+		return ErrorUnknownResource
+	}
+	header, rows, err := src.Rows(ctx, f.Filters, maxExportRows)
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	if err := cw.WriteAll(rows); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+func (s *service) CreateSchedule(ctx context.Context, filterID uuid.UUID, req CreateScheduleRequest) (*Schedule, error) {
+	if _, err := s.repo.GetFilter(ctx, filterID); err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	sc := &Schedule{ID: uuid.New(), FilterID: filterID, Recipients: req.Recipients, IntervalHours: req.IntervalHours, NextRunAt: now, CreatedAt: now}
+	if req.StartAt != nil {
+		sc.NextRunAt = req.StartAt.UTC()
+	}
+	if err := s.repo.CreateSchedule(ctx, sc); err != nil {
+		return nil, err
+	}
+	return sc, nil
+}
+
+func (s *service) ListSchedules(ctx context.Context, filterID uuid.UUID) ([]Schedule, error) {
+	if _, err := s.repo.GetFilter(ctx, filterID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListSchedules(ctx, filterID)
+}
+
+func (s *service) DeleteSchedule(ctx context.Context, filterID, id uuid.UUID) error {
+	return s.repo.DeleteSchedule(ctx, filterID, id)
+}
+
+// ProcessDue emails the CSV export of every due schedule to its recipients.
+// A failed run is recorded on the schedule and retried at the next interval.
+func (s *service) ProcessDue(ctx context.Context) error {
+	due, err := s.repo.ClaimDue(ctx, batchSize)
+	if err != nil {
+		return err
+	}
+	for i := range due {
+		sc := &due[i]
+		var runErr *string
+		if err := s.run(ctx, sc); err != nil {
+			s.log.Warn("scheduled export failed", zap.String("schedule_id", sc.ID.String()), zap.Error(err))
+			msg := err.Error()
+			runErr = &msg
+		}
+		if err := s.repo.MarkRun(ctx, sc.ID, time.Now().UTC(), runErr); err != nil {
+			s.log.Error("mark export run", zap.String("schedule_id", sc.ID.String()), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+func (s *service) run(ctx context.Context, sc *Schedule) error {
+	f, err := s.repo.GetFilter(ctx, sc.FilterID)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := s.writeCSV(ctx, f, &buf); err != nil {
+		return err
+	}
+	today := time.Now().UTC().Format("2006-01-02")
+	attachment := Notifications.Attachment{Name: fmt.Sprintf("%s-%s.csv", f.Resource, today), ContentType: "text/csv", Data: buf.Bytes()}
+	var failed error
+	for _, to := range sc.Recipients {
+		err := s.mailer.Send(ctx, Notifications.Message{
+			Channel:     Notifications.ChannelEmail,
+			To:          to,
+			Subject:     fmt.Sprintf("Export: %s (%s)", f.Name, today),
+			Body:        fmt.Sprintf("The scheduled export of \"%s\" is attached.", f.Name),
+			Template:    "scheduled_export",
+			Data:        map[string]interface{}{"name": f.Name, "resource": f.Resource},
+			Attachments: []Notifications.Attachment{attachment},
+		})
+		if err != nil {
+			failed = err
+		}
+	}
+	return failed
+}
@@ -0,0 +1,115 @@
+package Exports
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"savannah/src/Catalog"
+	"savannah/src/Customer"
+	"savannah/src/Orders"
+)
+
+// Source turns a saved filter into CSV rows for one admin list
+type Source interface {
+	Rows(ctx context.Context, f Filters, limit int) (header []string, rows [][]string, err error)
+}
+
+// NewSources wires the order, product and customer lists as export sources
+func NewSources(orders Orders.Service, products Catalog.Service, customers Customer.Service) map[string]Source {
+	return map[string]Source{
+		ResourceOrders:    orderSource{orders},
+		ResourceProducts:  productSource{products},
+		ResourceCustomers: customerSource{customers},
+	}
+}
+
+// the list services cap their page size at 100
+const pageSize = 100
+
+// paginate calls fetch page by page until it returns a short page or limit rows were read
+func paginate(limit int, fetch func(offset, size int) (int, error)) error {
+	for offset := 0; offset < limit; offset += pageSize {
+		size := pageSize
+		if limit-offset < size {
+			size = limit - offset
+		}
+		n, err := fetch(offset, size)
+		if err != nil {
+			return err
+		}
+		if n < size {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (f Filters) values() url.Values {
+	v := url.Values{}
+	for k, val := range f {
+		v.Set(k, val)
+	}
+	return v
+}
+
+type orderSource struct{ svc Orders.Service }
+
+func (s orderSource) Rows(ctx context.Context, f Filters, limit int) ([]string, [][]string, error) {
+	q, err := Orders.ParseListOrdersQuery(f.values())
+	if err != nil {
+		return nil, nil, err
+	}
+	header := []string{"id", "created_at", "status", "priority", "customer_id", "warehouse", "payment_method", "source", "channel", "external_reference", "currency", "subtotal", "tax", "shipping", "discount", "total"}
+	rows := [][]string{}
+	err = paginate(limit, func(offset, size int) (int, error) {
+		q.Offset, q.Limit = offset, size
+		orders, err := s.svc.List(ctx, q)
+		for _, o := range orders {
+			customer := ""
+			if o.CustomerID != nil {
+				customer = o.CustomerID.String()
+			}
+			rows = append(rows, []string{o.ID.String(), o.CreatedAt.Format(time.RFC3339), o.Status, o.Priority, customer, str(o.Warehouse), str(o.PaymentMethod), str(o.Source), o.Channel, str(o.ExternalReference), o.Currency, o.Subtotal.StringFixed(2), o.Tax.StringFixed(2), o.Shipping.StringFixed(2), o.Discount.StringFixed(2), o.Total.StringFixed(2)})
+		}
+		return len(orders), err
+	})
+	return header, rows, err
+}
+
+type productSource struct{ svc Catalog.Service }
+
+func (s productSource) Rows(ctx context.Context, f Filters, limit int) ([]string, [][]string, error) {
+	header := []string{"id", "sku", "name", "product_type", "price", "currency", "created_at"}
+	rows := [][]string{}
+	err := paginate(limit, func(offset, size int) (int, error) {
+		products, err := s.svc.ListProducts(ctx, Catalog.ListProductsQuery{Search: f["search"], Limit: size, Offset: offset})
+		for _, p := range products {
+			rows = append(rows, []string{p.ID.String(), p.SKU, p.Name, p.ProductType, p.Price.StringFixed(2), p.Currency, p.CreatedAt.Format(time.RFC3339)})
+		}
+		return len(products), err
+	})
+	return header, rows, err
+}
+
+type customerSource struct{ svc Customer.Service }
+
+func (s customerSource) Rows(ctx context.Context, f Filters, limit int) ([]string, [][]string, error) {
+	header := []string{"id", "first_name", "last_name", "email", "phone", "status", "created_at"}
+	rows := [][]string{}
+	err := paginate(limit, func(offset, size int) (int, error) {
+		customers, err := s.svc.List(ctx, Customer.ListCustomersQuery{Search: f["search"], Status: f["status"], Limit: size, Offset: offset})
+		for _, c := range customers {
+			rows = append(rows, []string{c.ID.String(), c.FirstName, c.LastName, c.Email, c.Phone, c.Status, c.CreatedAt.Format(time.RFC3339)})
+		}
+		return len(customers), err
+	})
+	return header, rows, err
+}
+
+func str(v *string) string {
+	if v == nil {
+		return ""
+	}
+	return *v
+}
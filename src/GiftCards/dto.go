@@ -0,0 +1,43 @@
+package GiftCards
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// IssueRequest issues a card loaded with Amount
+type IssueRequest struct {
+	Amount    decimal.Decimal `json:"amount"`
+	Currency  string          `json:"currency" validate:"required,len=3"`
+	ExpiresAt *time.Time      `json:"expires_at,omitempty"`
+	Note      string          `json:"note,omitempty" validate:"max=255"`
+}
+
+// UpdateRequest disables or re-enables a card or changes its expiry; absent
+// fields are kept
+type UpdateRequest struct {
+	Status    *string    `json:"status,omitempty" validate:"omitempty,oneof=ACTIVE DISABLED"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// AdjustRequest credits (positive Amount) or debits (negative) a card by hand
+type AdjustRequest struct {
+	Amount decimal.Decimal `json:"amount"`
+	Reason string          `json:"reason" validate:"required,max=255"`
+}
+
+type BalanceRequest struct {
+	Code string `json:"code" validate:"required,max=64"`
+}
+
+// RedeemRequest takes up to Amount off a card, such as for a sale at a till;
+// orders redeem cards at checkout through gift_card_code instead
+type RedeemRequest struct {
+	Code      string          `json:"code" validate:"required,max=64"`
+	Amount    decimal.Decimal `json:"amount"`
+	Currency  string          `json:"currency" validate:"required,len=3"`
+	OrderID   *uuid.UUID      `json:"order_id,omitempty"`
+	Reference string          `json:"reference,omitempty" validate:"max=255"`
+}
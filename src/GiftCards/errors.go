@@ -0,0 +1,40 @@
+package GiftCards
+
+import (
+	"errors"
+
+	"savannah/src/ErrorCodes"
+)
+
+var (
+	ErrorNotFound       = errors.New("gift card not found")
+	ErrorInvalidPayload = errors.New("invalid gift card payload")
+	ErrorDisabled       = errors.New("the gift card is disabled")
+	ErrorExpired        = errors.New("the gift card has expired")
+	ErrorCurrency       = errors.New("the gift card is in another currency")
+	ErrorEmpty          = errors.New("the gift card has no balance left")
+	ErrorInsufficient   = errors.New("the adjustment would take the balance below zero")
+)
+
+func init() {
+	ErrorCodes.Register("GIFTCARDS",
+		ErrorCodes.Def{N: 1, Slug: "NOT_FOUND", Err: ErrorNotFound},
+		ErrorCodes.Def{N: 2, Slug: "INVALID_PAYLOAD", Err: ErrorInvalidPayload},
+		ErrorCodes.Def{N: 3, Slug: "DISABLED", Err: ErrorDisabled},
+		ErrorCodes.Def{N: 4, Slug: "EXPIRED", Err: ErrorExpired},
+		ErrorCodes.Def{N: 5, Slug: "WRONG_CURRENCY", Err: ErrorCurrency},
+		ErrorCodes.Def{N: 6, Slug: "EMPTY", Err: ErrorEmpty},
+		ErrorCodes.Def{N: 7, Slug: "INSUFFICIENT_BALANCE", Err: ErrorInsufficient},
+	)
+}
+
+// Rejected reports whether err is why a gift card can't pay for an order,
+// which the checkout answers 422
+func Rejected(err error) bool {
+	for _, e := range []error{ErrorNotFound, ErrorDisabled, ErrorExpired, ErrorCurrency, ErrorEmpty} {
+		if errors.Is(err, e) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,278 @@
+package GiftCards
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"savannah/src/ErrorCodes"
+	"savannah/src/Pagination"
+)
+
+type Handler struct {
+	svc Service
+	log *zap.Logger
+	v   *validator.Validate
+}
+
+func NewHandler(s Service, log *zap.Logger) *Handler {
+	return &Handler{svc: s, log: log, v: validator.New()}
+}
+
+// IssueGiftCard godoc
+// @Summary      Issue a gift card
+// @Description  Loads a new card with amount. The response carries the card's code, which is shown only this once; later reads show its last four characters.
+// @Tags         gift-cards
+// @Accept       json
+// @Produce      json
+// @Param        payload  body      IssueRequest  true  "Card"
+// @Success      201      {object}  GiftCard
+// @Failure      400      {object}  map[string]interface{}
+// @Router       /gift-cards [post]
+func (h *Handler) IssueGiftCard(w http.ResponseWriter, r *http.Request) {
+	var dto IssueRequest
+	if !h.decode(w, r, &dto) {
+		return
+	}
+	c, err := h.svc.Issue(r.Context(), dto)
+	if err != nil {
+		h.cardError(w, "issue gift card", err)
+		return
+	}
+	h.writeJSON(w, http.StatusCreated, c)
+}
+
+// ListGiftCards godoc
+// @Summary      List gift cards
+// @Tags         gift-cards
+// @Produce      json
+// @Param        limit   query     int  false  "Page size (default 50, max 200)"
+// @Param        offset  query     int  false  "Offset"
+// @Success      200     {array}   GiftCard
+// @Header       200     {string}  Link  "first, prev, next and last pages (RFC 5988), with X-Total-Count, X-Page and X-Total-Pages"
+// @Router       /gift-cards [get]
+func (h *Handler) ListGiftCards(w http.ResponseWriter, r *http.Request) {
+	limit, offset := Pagination.Params(r.URL.Query(), 50, 200)
+	cards, err := h.svc.List(r.Context(), limit, offset)
+	var total int
+	if err == nil {
+		total, err = h.svc.Count(r.Context())
+	}
+	if err != nil {
+		h.log.Error("list gift cards", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to list gift cards")
+		return
+	}
+	Pagination.Write(w, r, limit, offset, total)
+	h.writeJSON(w, http.StatusOK, cards)
+}
+
+// GetGiftCard godoc
+// @Summary      Get a gift card
+// @Tags         gift-cards
+// @Produce      json
+// @Param        id   path      string  true  "Gift card ID"
+// @Success      200  {object}  GiftCard
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /gift-cards/{id} [get]
+func (h *Handler) GetGiftCard(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.id(w, r)
+	if !ok {
+		return
+	}
+	c, err := h.svc.Get(r.Context(), id)
+	if err != nil {
+		h.cardError(w, "get gift card", err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, c)
+}
+
+// UpdateGiftCard godoc
+// @Summary      Disable, re-enable or change the expiry of a gift card
+// @Tags         gift-cards
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string         true  "Gift card ID"
+// @Param        payload  body      UpdateRequest  true  "Changes"
+// @Success      200      {object}  GiftCard
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      404      {object}  map[string]interface{}
+// @Router       /gift-cards/{id} [patch]
+func (h *Handler) UpdateGiftCard(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.id(w, r)
+	if !ok {
+		return
+	}
+	var dto UpdateRequest
+	if !h.decode(w, r, &dto) {
+		return
+	}
+	c, err := h.svc.Update(r.Context(), id, dto)
+	if err != nil {
+		h.cardError(w, "update gift card", err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, c)
+}
+
+// AdjustGiftCard godoc
+// @Summary      Credit or debit a gift card by hand
+// @Description  A positive amount credits the card, a negative one debits it; the balance can't go below zero. The reason is kept on the transaction.
+// @Tags         gift-cards
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string         true  "Gift card ID"
+// @Param        payload  body      AdjustRequest  true  "Adjustment"
+// @Success      201      {object}  Transaction
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      404      {object}  map[string]interface{}
+// @Failure      409      {object}  map[string]interface{}
+// @Router       /gift-cards/{id}/adjustments [post]
+func (h *Handler) AdjustGiftCard(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.id(w, r)
+	if !ok {
+		return
+	}
+	var dto AdjustRequest
+	if !h.decode(w, r, &dto) {
+		return
+	}
+	t, err := h.svc.Adjust(r.Context(), id, dto)
+	if err != nil {
+		h.cardError(w, "adjust gift card", err)
+		return
+	}
+	h.writeJSON(w, http.StatusCreated, t)
+}
+
+// ListTransactions godoc
+// @Summary      A gift card's balance history
+// @Description  Issue, redemptions, releases of cancelled orders' redemptions and adjustments, latest first.
+// @Tags         gift-cards
+// @Produce      json
+// @Param        id      path      string  true   "Gift card ID"
+// @Param        limit   query     int     false  "Page size (default 50, max 200)"
+// @Param        offset  query     int     false  "Offset"
+// @Success      200     {array}   Transaction
+// @Header       200     {string}  Link  "first, prev, next and last pages (RFC 5988), with X-Total-Count, X-Page and X-Total-Pages"
+// @Failure      404     {object}  map[string]interface{}
+// @Router       /gift-cards/{id}/transactions [get]
+func (h *Handler) ListTransactions(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.id(w, r)
+	if !ok {
+		return
+	}
+	limit, offset := Pagination.Params(r.URL.Query(), 50, 200)
+	txs, err := h.svc.Transactions(r.Context(), id, limit, offset)
+	var total int
+	if err == nil {
+		total, err = h.svc.CountTransactions(r.Context(), id)
+	}
+	if err != nil {
+		h.cardError(w, "list gift card transactions", err)
+		return
+	}
+	Pagination.Write(w, r, limit, offset, total)
+	h.writeJSON(w, http.StatusOK, txs)
+}
+
+// CheckBalance godoc
+// @Summary      Check a gift card's balance by its code
+// @Description  The code is sent in the body so it stays out of URLs and access logs.
+// @Tags         gift-cards
+// @Accept       json
+// @Produce      json
+// @Param        payload  body      BalanceRequest  true  "Code"
+// @Success      200      {object}  Balance
+// @Failure      404      {object}  map[string]interface{}
+// @Router       /gift-cards/balance [post]
+func (h *Handler) CheckBalance(w http.ResponseWriter, r *http.Request) {
+	var dto BalanceRequest
+	if !h.decode(w, r, &dto) {
+		return
+	}
+	b, err := h.svc.Balance(r.Context(), dto.Code)
+	if err != nil {
+		h.cardError(w, "check gift card balance", err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, b)
+}
+
+// RedeemGiftCard godoc
+// @Summary      Spend a gift card's balance
+// @Description  Takes up to amount off the card, as much as its balance covers, and returns what was taken and what is left; charge the rest another way. For sales outside checkout, such as at a till; orders redeem cards with gift_card_code.
+// @Tags         gift-cards
+// @Accept       json
+// @Produce      json
+// @Param        payload  body      RedeemRequest  true  "Redemption"
+// @Success      201      {object}  Redemption
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      404      {object}  map[string]interface{}
+// @Failure      422      {object}  map[string]interface{}
+// @Router       /gift-cards/redemptions [post]
+func (h *Handler) RedeemGiftCard(w http.ResponseWriter, r *http.Request) {
+	var dto RedeemRequest
+	if !h.decode(w, r, &dto) {
+		return
+	}
+	red, err := h.svc.Redeem(r.Context(), dto.Code, dto.Amount, dto.Currency, dto.OrderID, dto.Reference)
+	if err != nil {
+		h.cardError(w, "redeem gift card", err)
+		return
+	}
+	h.writeJSON(w, http.StatusCreated, red)
+}
+
+func (h *Handler) id(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func (h *Handler) decode(w http.ResponseWriter, r *http.Request, dto interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return false
+	}
+	if err := h.v.Struct(dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return false
+	}
+	return true
+}
+
+func (h *Handler) cardError(w http.ResponseWriter, op string, err error) {
+	switch {
+	case errors.Is(err, ErrorNotFound):
+		h.writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrorInvalidPayload):
+		h.writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrorInsufficient):
+		h.writeError(w, http.StatusConflict, err.Error())
+	case Rejected(err):
+		h.writeError(w, http.StatusUnprocessableEntity, err.Error())
+	default:
+		h.log.Error(op, zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to "+op)
+	}
+}
+
+func (h *Handler) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (h *Handler) writeError(w http.ResponseWriter, status int, msg string) {
+	h.writeJSON(w, status, map[string]interface{}{"error": msg, "code": ErrorCodes.Resolve("GIFTCARDS", status, msg), "timestamp": time.Now().UTC()})
+}
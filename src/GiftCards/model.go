@@ -0,0 +1,89 @@
+package GiftCards
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// GiftCard is a prepaid balance in Currency that pays for orders until it
+// runs out, is disabled or expires. Its code is a bearer secret: only its
+// hash and last four characters are stored, and it is shown once, when the
+// card is issued.
+type GiftCard struct {
+	ID             uuid.UUID       `db:"id" json:"id"`
+	CodeHash       string          `db:"code_hash" json:"-"`
+	Last4          string          `db:"last4" json:"last4"`
+	Currency       string          `db:"currency" json:"currency"`
+	InitialBalance decimal.Decimal `db:"initial_balance" json:"initial_balance"`
+	Balance        decimal.Decimal `db:"balance" json:"balance"`
+	Status         string          `db:"status" json:"status"`
+	ExpiresAt      *time.Time      `db:"expires_at" json:"expires_at,omitempty"`
+	Note           *string         `db:"note" json:"note,omitempty"`
+	CreatedAt      time.Time       `db:"created_at" json:"created_at"`
+	UpdatedAt      time.Time       `db:"updated_at" json:"updated_at"`
+	// only set when the card is issued
+	Code string `db:"-" json:"code,omitempty"`
+}
+
+const TableName = "gift_cards"
+
+// gift card statuses
+const (
+	StatusActive   = "ACTIVE"
+	StatusDisabled = "DISABLED"
+	StatusExpired  = "EXPIRED" // shown for an active card past its expiry; never stored
+)
+
+// usable returns why the card can't pay for anything now, if it can't
+func (c *GiftCard) usable(now time.Time) error {
+	switch {
+	case c.Status != StatusActive:
+		return ErrorDisabled
+	case c.ExpiresAt != nil && !now.Before(*c.ExpiresAt):
+		return ErrorExpired
+	}
+	return nil
+}
+
+// Transaction is a change to a card's balance: Amount is positive for
+// credits and negative for debits
+type Transaction struct {
+	ID           uuid.UUID       `db:"id" json:"id"`
+	GiftCardID   uuid.UUID       `db:"gift_card_id" json:"gift_card_id"`
+	Type         string          `db:"type" json:"type"`
+	Amount       decimal.Decimal `db:"amount" json:"amount"`
+	BalanceAfter decimal.Decimal `db:"balance_after" json:"balance_after"`
+	OrderID      *uuid.UUID      `db:"order_id" json:"order_id,omitempty"`
+	Reference    *string         `db:"reference" json:"reference,omitempty"`
+	CreatedAt    time.Time       `db:"created_at" json:"created_at"`
+}
+
+const TransactionTableName = "gift_card_transactions"
+
+// transaction types
+const (
+	TxIssue   = "ISSUE"
+	TxRedeem  = "REDEEM"
+	TxRelease = "RELEASE" // a redemption given back, as when its order is cancelled
+	TxAdjust  = "ADJUST"
+)
+
+// Balance is what a balance check shows of a card
+type Balance struct {
+	Last4     string          `json:"last4"`
+	Currency  string          `json:"currency"`
+	Balance   decimal.Decimal `json:"balance"`
+	Status    string          `json:"status"`
+	ExpiresAt *time.Time      `json:"expires_at,omitempty"`
+}
+
+// Redemption is what a card paid towards an order or a sale
+type Redemption struct {
+	GiftCardID  uuid.UUID       `json:"gift_card_id"`
+	Last4       string          `json:"last4"`
+	Amount      decimal.Decimal `json:"amount"`
+	Balance     decimal.Decimal `json:"balance"`
+	Transaction uuid.UUID       `json:"transaction_id"`
+}
@@ -0,0 +1,243 @@
+package GiftCards
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+type Repository interface {
+	Create(ctx context.Context, c *GiftCard) error
+	Get(ctx context.Context, id uuid.UUID) (*GiftCard, error)
+	GetByCodeHash(ctx context.Context, hash string) (*GiftCard, error)
+	List(ctx context.Context, limit, offset int) ([]GiftCard, error)
+	Count(ctx context.Context) (int, error)
+	Update(ctx context.Context, c *GiftCard) error
+	Transactions(ctx context.Context, id uuid.UUID, limit, offset int) ([]Transaction, error)
+	CountTransactions(ctx context.Context, id uuid.UUID) (int, error)
+	Debit(ctx context.Context, id uuid.UUID, max decimal.Decimal, orderID *uuid.UUID, reference *string, now time.Time) (*GiftCard, *Transaction, error)
+	Adjust(ctx context.Context, id uuid.UUID, amount decimal.Decimal, reference string, now time.Time) (*Transaction, error)
+	Release(ctx context.Context, orderID uuid.UUID, now time.Time) error
+}
+
+type repository struct {
+	db  *sqlx.DB
+	log *zap.Logger
+}
+
+func NewRepository(db *sqlx.DB, log *zap.Logger) Repository { return &repository{db: db, log: log} }
+
+const cardColumns = `id,code_hash,last4,currency,initial_balance,balance,status,expires_at,note,created_at,updated_at`
+
+const transactionColumns = `id,gift_card_id,type,amount,balance_after,order_id,reference,created_at`
+
+// Create stores the card with the transaction loading it
+func (r *repository) Create(ctx context.Context, c *GiftCard) (err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	if _, err = tx.NamedExecContext(ctx, fmt.Sprintf(`INSERT INTO %s (%s) VALUES (:id,:code_hash,:last4,:currency,:initial_balance,:balance,:status,:expires_at,:note,:created_at,:updated_at)`, TableName, cardColumns), c); err != nil {
+		return err
+	}
+	if err = addTransaction(ctx, tx, &Transaction{GiftCardID: c.ID, Type: TxIssue, Amount: c.Balance, BalanceAfter: c.Balance, CreatedAt: c.CreatedAt}); err != nil {
+		return err
+	}
+	err = tx.Commit()
+	return err
+}
+
+func addTransaction(ctx context.Context, tx *sqlx.Tx, t *Transaction) error {
+	t.ID = uuid.New()
+	_, err := tx.NamedExecContext(ctx, fmt.Sprintf(`INSERT INTO %s (%s) VALUES (:id,:gift_card_id,:type,:amount,:balance_after,:order_id,:reference,:created_at)`, TransactionTableName, transactionColumns), t)
+	return err
+}
+
+func (r *repository) Get(ctx context.Context, id uuid.UUID) (*GiftCard, error) {
+	return r.get(ctx, r.db, `id=$1`, id)
+}
+
+func (r *repository) GetByCodeHash(ctx context.Context, hash string) (*GiftCard, error) {
+	return r.get(ctx, r.db, `code_hash=$1`, hash)
+}
+
+// lock reads the card for update within tx
+func (r *repository) lock(ctx context.Context, tx *sqlx.Tx, id uuid.UUID) (*GiftCard, error) {
+	return r.get(ctx, tx, `id=$1 FOR UPDATE`, id)
+}
+
+func (r *repository) get(ctx context.Context, q sqlx.QueryerContext, where string, arg interface{}) (*GiftCard, error) {
+	var c GiftCard
+	if err := sqlx.GetContext(ctx, q, &c, fmt.Sprintf(`SELECT %s FROM %s WHERE %s`, cardColumns, TableName, where), arg); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrorNotFound
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+// List pages through the cards, newest first
+func (r *repository) List(ctx context.Context, limit, offset int) ([]GiftCard, error) {
+	out := []GiftCard{}
+	err := r.db.SelectContext(ctx, &out, fmt.Sprintf(`SELECT %s FROM %s ORDER BY created_at DESC, id LIMIT $1 OFFSET $2`, cardColumns, TableName), limit, offset)
+	return out, err
+}
+
+func (r *repository) Count(ctx context.Context) (int, error) {
+	var n int
+	err := r.db.GetContext(ctx, &n, `SELECT COUNT(*) FROM `+TableName)
+	return n, err
+}
+
+// Update saves the card's status and expiry
+func (r *repository) Update(ctx context.Context, c *GiftCard) error {
+	c.UpdatedAt = time.Now().UTC()
+	res, err := r.db.NamedExecContext(ctx, fmt.Sprintf(`UPDATE %s SET status=:status, expires_at=:expires_at, updated_at=:updated_at WHERE id=:id`, TableName), c)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrorNotFound
+	}
+	return nil
+}
+
+// Transactions pages through the card's transactions, latest first
+func (r *repository) Transactions(ctx context.Context, id uuid.UUID, limit, offset int) ([]Transaction, error) {
+	out := []Transaction{}
+	err := r.db.SelectContext(ctx, &out, fmt.Sprintf(`SELECT %s FROM %s WHERE gift_card_id=$1 ORDER BY created_at DESC, id LIMIT $2 OFFSET $3`, transactionColumns, TransactionTableName), id, limit, offset)
+	return out, err
+}
+
+func (r *repository) CountTransactions(ctx context.Context, id uuid.UUID) (int, error) {
+	var n int
+	err := r.db.GetContext(ctx, &n, fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE gift_card_id=$1`, TransactionTableName), id)
+	return n, err
+}
+
+// Debit takes up to max off the card, as much as its balance covers. The
+// card is locked and checked again, so concurrent redemptions can't spend
+// the same balance twice.
+func (r *repository) Debit(ctx context.Context, id uuid.UUID, max decimal.Decimal, orderID *uuid.UUID, reference *string, now time.Time) (c *GiftCard, t *Transaction, err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	if c, err = r.lock(ctx, tx, id); err != nil {
+		return nil, nil, err
+	}
+	if err = c.usable(now); err != nil {
+		return nil, nil, err
+	}
+	take := decimal.Min(c.Balance, max)
+	if !take.IsPositive() {
+		err = ErrorEmpty
+		return nil, nil, err
+	}
+	if c, t, err = r.apply(ctx, tx, c, take.Neg(), TxRedeem, orderID, reference, now); err != nil {
+		return nil, nil, err
+	}
+	err = tx.Commit()
+	return c, t, err
+}
+
+// Adjust credits or debits the card by hand; it can't go below zero
+func (r *repository) Adjust(ctx context.Context, id uuid.UUID, amount decimal.Decimal, reference string, now time.Time) (t *Transaction, err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	c, err := r.lock(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if c.Balance.Add(amount).IsNegative() {
+		err = ErrorInsufficient
+		return nil, err
+	}
+	if _, t, err = r.apply(ctx, tx, c, amount, TxAdjust, nil, &reference, now); err != nil {
+		return nil, err
+	}
+	err = tx.Commit()
+	return t, err
+}
+
+// Release credits back what the order's redemptions took and hasn't been
+// given back yet, so releasing twice is harmless. The order's cards are
+// locked before what is owed is summed, so concurrent releases of the same
+// order wait for each other instead of both crediting the card.
+func (r *repository) Release(ctx context.Context, orderID uuid.UUID, now time.Time) (err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	var cards []GiftCard
+	if err = tx.SelectContext(ctx, &cards, fmt.Sprintf(`SELECT %s FROM %s
+		WHERE id IN (SELECT gift_card_id FROM %s WHERE order_id=$1) ORDER BY id FOR UPDATE`, cardColumns, TableName, TransactionTableName), orderID); err != nil {
+		return err
+	}
+	locked := make(map[uuid.UUID]*GiftCard, len(cards))
+	for i := range cards {
+		locked[cards[i].ID] = &cards[i]
+	}
+	var owed []struct {
+		GiftCardID uuid.UUID       `db:"gift_card_id"`
+		Net        decimal.Decimal `db:"net"`
+	}
+	if err = tx.SelectContext(ctx, &owed, fmt.Sprintf(`SELECT gift_card_id, SUM(amount) AS net FROM %s
+		WHERE order_id=$1 AND type IN ('%s','%s') GROUP BY gift_card_id ORDER BY gift_card_id`, TransactionTableName, TxRedeem, TxRelease), orderID); err != nil {
+		return err
+	}
+	for _, o := range owed {
+		c, ok := locked[o.GiftCardID]
+		if !ok || !o.Net.IsNegative() {
+			continue
+		}
+		if _, _, err = r.apply(ctx, tx, c, o.Net.Neg(), TxRelease, &orderID, nil, now); err != nil {
+			return err
+		}
+	}
+	err = tx.Commit()
+	return err
+}
+
+// apply changes the locked card's balance by amount and records why
+func (r *repository) apply(ctx context.Context, tx *sqlx.Tx, c *GiftCard, amount decimal.Decimal, typ string, orderID *uuid.UUID, reference *string, now time.Time) (*GiftCard, *Transaction, error) {
+	c.Balance = c.Balance.Add(amount)
+	c.UpdatedAt = now
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET balance=$2, updated_at=$3 WHERE id=$1`, TableName), c.ID, c.Balance, now); err != nil {
+		return nil, nil, err
+	}
+	t := &Transaction{GiftCardID: c.ID, Type: typ, Amount: amount, BalanceAfter: c.Balance, OrderID: orderID, Reference: reference, CreatedAt: now}
+	if err := addTransaction(ctx, tx, t); err != nil {
+		return nil, nil, err
+	}
+	return c, t, nil
+}
@@ -0,0 +1,185 @@
+package GiftCards
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+// Service issues gift cards and spends their balances; Orders redeems a
+// card at checkout and releases what it took when the order is cancelled
+// or never placed
+type Service interface {
+	Issue(ctx context.Context, req IssueRequest) (*GiftCard, error)
+	Get(ctx context.Context, id uuid.UUID) (*GiftCard, error)
+	List(ctx context.Context, limit, offset int) ([]GiftCard, error)
+	Count(ctx context.Context) (int, error)
+	Update(ctx context.Context, id uuid.UUID, req UpdateRequest) (*GiftCard, error)
+	Adjust(ctx context.Context, id uuid.UUID, req AdjustRequest) (*Transaction, error)
+	Transactions(ctx context.Context, id uuid.UUID, limit, offset int) ([]Transaction, error)
+	CountTransactions(ctx context.Context, id uuid.UUID) (int, error)
+
+	Balance(ctx context.Context, code string) (*Balance, error)
+	Redeem(ctx context.Context, code string, amount decimal.Decimal, currency string, orderID *uuid.UUID, reference string) (*Redemption, error)
+	Release(ctx context.Context, orderID uuid.UUID) error
+}
+
+type service struct {
+	repo Repository
+	log  *zap.Logger
+}
+
+func NewService(r Repository, log *zap.Logger) Service {
+	return &service{repo: r, log: log}
+}
+
+// codeAlphabet leaves out characters easily misread on a printed card
+const codeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// newCode returns a code such as "ABCD-EFGH-JKLM-NPQR" and the hash it is
+// stored as
+func newCode() (string, string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	var sb strings.Builder
+	for i, v := range b {
+		if i > 0 && i%4 == 0 {
+			sb.WriteByte('-')
+		}
+		sb.WriteByte(codeAlphabet[int(v)%len(codeAlphabet)])
+	}
+	code := sb.String()
+	return code, hashCode(code), nil
+}
+
+// hashCode hashes a code as entered, ignoring case, spaces and dashes
+func hashCode(code string) string {
+	code = strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *service) Issue(ctx context.Context, req IssueRequest) (*GiftCard, error) {
+	if !req.Amount.IsPositive() {
+		return nil, fmt.Errorf("%w: amount must be above 0", ErrorInvalidPayload)
+	}
+	now := time.Now().UTC()
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(now) {
+		return nil, fmt.Errorf("%w: expires_at must be in the future", ErrorInvalidPayload)
+	}
+	code, hash, err := newCode()
+	if err != nil {
+		return nil, err
+	}
+	amount := req.Amount.Round(2)
+	c := &GiftCard{ID: uuid.New(), CodeHash: hash, Last4: code[len(code)-4:], Currency: strings.ToUpper(req.Currency), InitialBalance: amount, Balance: amount,
+		Status: StatusActive, ExpiresAt: req.ExpiresAt, CreatedAt: now, UpdatedAt: now}
+	if req.Note != "" {
+		c.Note = &req.Note
+	}
+	if err := s.repo.Create(ctx, c); err != nil {
+		return nil, err
+	}
+	c.Code = code
+	return c, nil
+}
+
+func (s *service) Get(ctx context.Context, id uuid.UUID) (*GiftCard, error) {
+	return s.repo.Get(ctx, id)
+}
+
+func (s *service) List(ctx context.Context, limit, offset int) ([]GiftCard, error) {
+	return s.repo.List(ctx, limit, offset)
+}
+
+func (s *service) Count(ctx context.Context) (int, error) {
+	return s.repo.Count(ctx)
+}
+
+func (s *service) Update(ctx context.Context, id uuid.UUID, req UpdateRequest) (*GiftCard, error) {
+	c, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if req.Status != nil {
+		c.Status = *req.Status
+	}
+	if req.ExpiresAt != nil {
+		c.ExpiresAt = req.ExpiresAt
+	}
+	if err := s.repo.Update(ctx, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *service) Adjust(ctx context.Context, id uuid.UUID, req AdjustRequest) (*Transaction, error) {
+	amount := req.Amount.Round(2)
+	if amount.IsZero() {
+		return nil, fmt.Errorf("%w: amount must not be 0", ErrorInvalidPayload)
+	}
+	return s.repo.Adjust(ctx, id, amount, req.Reason, time.Now().UTC())
+}
+
+func (s *service) Transactions(ctx context.Context, id uuid.UUID, limit, offset int) ([]Transaction, error) {
+	if _, err := s.repo.Get(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.repo.Transactions(ctx, id, limit, offset)
+}
+
+func (s *service) CountTransactions(ctx context.Context, id uuid.UUID) (int, error) {
+	return s.repo.CountTransactions(ctx, id)
+}
+
+// Balance looks a card up by its code; a disabled or expired card shows its
+// status and balance all the same
+func (s *service) Balance(ctx context.Context, code string) (*Balance, error) {
+	c, err := s.repo.GetByCodeHash(ctx, hashCode(code))
+	if err != nil {
+		return nil, err
+	}
+	b := &Balance{Last4: c.Last4, Currency: c.Currency, Balance: c.Balance, Status: c.Status, ExpiresAt: c.ExpiresAt}
+	if c.Status == StatusActive && c.usable(time.Now().UTC()) != nil {
+		b.Status = StatusExpired
+	}
+	return b, nil
+}
+
+// Redeem takes up to amount in currency off the card behind code, as much
+// as its balance covers, for orderID when given
+func (s *service) Redeem(ctx context.Context, code string, amount decimal.Decimal, currency string, orderID *uuid.UUID, reference string) (*Redemption, error) {
+	if !amount.IsPositive() {
+		return nil, fmt.Errorf("%w: amount must be above 0", ErrorInvalidPayload)
+	}
+	c, err := s.repo.GetByCodeHash(ctx, hashCode(code))
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(c.Currency, currency) {
+		return nil, ErrorCurrency
+	}
+	var ref *string
+	if reference != "" {
+		ref = &reference
+	}
+	c, t, err := s.repo.Debit(ctx, c.ID, amount.Round(2), orderID, ref, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	return &Redemption{GiftCardID: c.ID, Last4: c.Last4, Amount: t.Amount.Neg(), Balance: c.Balance, Transaction: t.ID}, nil
+}
+
+func (s *service) Release(ctx context.Context, orderID uuid.UUID) error {
+	return s.repo.Release(ctx, orderID, time.Now().UTC())
+}
@@ -0,0 +1,124 @@
+package Inventory
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+// maxAlternatives caps the suggestions returned for one short line
+const maxAlternatives = 3
+
+// CheckAvailability previews whether the basket could be reserved, without
+// reserving anything. Lines asking for the same stock item are added up, as
+// checkout reserves them together. Short lines get alternatives: the same
+// item from warehouses holding enough of it, then other variants of the
+// product with enough stock in the checked warehouse.
+func (s *service) CheckAvailability(ctx context.Context, req CheckAvailabilityRequest) (*AvailabilityCheck, error) {
+	quantities := map[uuid.UUID]int{}
+	var productIDs []uuid.UUID
+	seen := map[uuid.UUID]bool{}
+	for _, it := range req.Items {
+		quantities[it.stockID()] += it.Quantity
+		if !seen[it.ProductID] {
+			seen[it.ProductID] = true
+			productIDs = append(productIDs, it.ProductID)
+		}
+	}
+	rows, err := s.repo.ProductStock(ctx, productIDs)
+	if err != nil {
+		return nil, err
+	}
+	// warehouse -> stock item -> available
+	stock := map[string]map[uuid.UUID]int{}
+	for _, row := range rows {
+		if stock[row.Warehouse] == nil {
+			stock[row.Warehouse] = map[uuid.UUID]int{}
+		}
+		id := row.ProductID
+		if row.VariantID != nil {
+			id = *row.VariantID
+		}
+		stock[row.Warehouse][id] = row.Available
+	}
+
+	warehouse := req.Warehouse
+	if warehouse == "" {
+		warehouse, err = s.SelectWarehouse(ctx, req.Country, req.Region, quantities)
+		if errors.Is(err, ErrorNoWarehouse) {
+			warehouse = closestWarehouse(stock, quantities)
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	check := &AvailabilityCheck{Warehouse: warehouse, Available: true, Lines: make([]LineAvailability, 0, len(req.Items))}
+	for _, it := range req.Items {
+		id := it.stockID()
+		line := LineAvailability{ProductID: it.ProductID, VariantID: it.VariantID, Requested: it.Quantity, Available: max(stock[warehouse][id], 0)}
+		line.InStock = line.Available >= quantities[id]
+		if !line.InStock {
+			check.Available = false
+			line.Alternatives = alternatives(rows, stock, warehouse, it, quantities[id])
+		}
+		check.Lines = append(check.Lines, line)
+	}
+	return check, nil
+}
+
+// closestWarehouse is the warehouse able to fill the most lines, preferring
+// the larger stock of the basket's items and then the name
+func closestWarehouse(stock map[string]map[uuid.UUID]int, quantities map[uuid.UUID]int) string {
+	best, bestLines, bestTotal := "", -1, -1
+	for warehouse, items := range stock {
+		lines, total := 0, 0
+		for id, qty := range quantities {
+			if items[id] >= qty {
+				lines++
+			}
+			total += max(items[id], 0)
+		}
+		if lines > bestLines || (lines == bestLines && (total > bestTotal || (total == bestTotal && warehouse < best))) {
+			best, bestLines, bestTotal = warehouse, lines, total
+		}
+	}
+	return best
+}
+
+func alternatives(rows []ProductStock, stock map[string]map[uuid.UUID]int, warehouse string, it CheckItem, qty int) []Alternative {
+	id := it.stockID()
+	var elsewhere []Alternative
+	for w, items := range stock {
+		if w != warehouse && items[id] >= qty {
+			elsewhere = append(elsewhere, Alternative{Warehouse: w, Available: items[id]})
+		}
+	}
+	var variants []Alternative
+	if it.VariantID != nil {
+		for _, row := range rows {
+			if row.Warehouse == warehouse && row.ProductID == it.ProductID && row.VariantID != nil && *row.VariantID != id && row.Available >= qty {
+				variants = append(variants, Alternative{Warehouse: warehouse, VariantID: row.VariantID, Available: row.Available})
+			}
+		}
+	}
+	byStock := func(list []Alternative) {
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].Available != list[j].Available {
+				return list[i].Available > list[j].Available
+			}
+			if list[i].Warehouse != list[j].Warehouse {
+				return list[i].Warehouse < list[j].Warehouse
+			}
+			return list[i].VariantID != nil && list[j].VariantID != nil && list[i].VariantID.String() < list[j].VariantID.String()
+		})
+	}
+	byStock(elsewhere)
+	byStock(variants)
+	out := append(elsewhere, variants...)
+	if len(out) > maxAlternatives {
+		out = out[:maxAlternatives]
+	}
+	return out
+}
@@ -0,0 +1,44 @@
+package Inventory
+
+import "github.com/google/uuid"
+
+type CreateRoutingRuleRequest struct {
+	Country   *string `json:"country,omitempty" validate:"omitempty,len=2"`
+	Region    *string `json:"region,omitempty" validate:"omitempty,max=100"`
+	Warehouse string  `json:"warehouse" validate:"required,max=50"`
+	Priority  int     `json:"priority" validate:"min=0"`
+}
+
+// CheckAvailabilityRequest is a prospective basket. Without a Warehouse one
+// is chosen from Country/Region the way checkout routes orders.
+type CheckAvailabilityRequest struct {
+	Warehouse string      `json:"warehouse,omitempty" validate:"omitempty,max=50"`
+	Country   string      `json:"country,omitempty" validate:"omitempty,len=2"`
+	Region    string      `json:"region,omitempty" validate:"omitempty,max=100"`
+	Items     []CheckItem `json:"items" validate:"required,min=1,max=100,dive"`
+}
+
+// CreateHoldRequest reserves a basket for TTLSeconds (default 600, at most
+// 1800). The warehouse is chosen like CheckAvailabilityRequest's.
+type CreateHoldRequest struct {
+	Reference  string      `json:"reference,omitempty" validate:"omitempty,max=100"`
+	Warehouse  string      `json:"warehouse,omitempty" validate:"omitempty,max=50"`
+	Country    string      `json:"country,omitempty" validate:"omitempty,len=2"`
+	Region     string      `json:"region,omitempty" validate:"omitempty,max=100"`
+	Items      []CheckItem `json:"items" validate:"required,min=1,max=100,dive"`
+	TTLSeconds int         `json:"ttl_seconds,omitempty" validate:"omitempty,min=30,max=1800"`
+}
+
+type CheckItem struct {
+	ProductID uuid.UUID  `json:"product_id" validate:"required"`
+	VariantID *uuid.UUID `json:"variant_id,omitempty"`
+	Quantity  int        `json:"quantity" validate:"required,min=1"`
+}
+
+// stockID is the inventory row the item reserves from
+func (it CheckItem) stockID() uuid.UUID {
+	if it.VariantID != nil {
+		return *it.VariantID
+	}
+	return it.ProductID
+}
@@ -0,0 +1,29 @@
+package Inventory
+
+import (
+	"errors"
+
+	"savannah/src/ErrorCodes"
+)
+
+var (
+	ErrorNoWarehouse       = errors.New("no warehouse can fulfill the order")
+	ErrorInsufficientStock = errors.New("insufficient stock")
+
+	ErrorHoldNotFound  = errors.New("stock hold not found")
+	ErrorHoldNotActive = errors.New("stock hold is no longer active")
+
+	ErrorFlashSaleUnavailable = errors.New("flash-sale mode needs REDIS_URL to be configured")
+	ErrorFlashSaleDegraded    = errors.New("redis is failing its health checks; try enabling flash-sale mode later")
+)
+
+func init() {
+	ErrorCodes.Register("INVENTORY",
+		ErrorCodes.Def{N: 1, Slug: "NO_WAREHOUSE", Err: ErrorNoWarehouse},
+		ErrorCodes.Def{N: 2, Slug: "INSUFFICIENT_STOCK", Err: ErrorInsufficientStock},
+		ErrorCodes.Def{N: 3, Slug: "FLASH_SALE_UNAVAILABLE", Err: ErrorFlashSaleUnavailable},
+		ErrorCodes.Def{N: 4, Slug: "FLASH_SALE_DEGRADED", Err: ErrorFlashSaleDegraded},
+		ErrorCodes.Def{N: 5, Slug: "HOLD_NOT_FOUND", Err: ErrorHoldNotFound},
+		ErrorCodes.Def{N: 6, Slug: "HOLD_NOT_ACTIVE", Err: ErrorHoldNotActive},
+	)
+}
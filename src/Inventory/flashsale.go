@@ -0,0 +1,357 @@
+package Inventory
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+	"savannah/src/Server"
+)
+
+// Flash-sale mode moves the available count of a stock item in one
+// warehouse into Redis, where reserving and releasing are atomic script
+// calls instead of row locks. Every reservation is also added to a pending
+// counter that FlushFlashSales writes back to the reserved column, so
+// Postgres (and the routing and availability checks reading it) lags by at
+// most one flush. An item is in flash-sale mode while its count key exists:
+// other items, items whose key is gone and every item when no Redis is
+// configured take the transactional path.
+
+// Redis runs commands on the server holding the flash-sale counts
+type Redis interface {
+	Do(ctx context.Context, args ...string) (interface{}, error)
+}
+
+// Health reports which dependencies fail their checks (see Server.Health)
+type Health interface {
+	Degraded(name string) bool
+}
+
+// the hash tag keeps both keys of an item on one cluster slot
+func flashKeys(stockID uuid.UUID, warehouse string) (available, pending string) {
+	tag := "flash:{" + warehouse + ":" + stockID.String() + "}"
+	return tag + ":available", tag + ":pending"
+}
+
+// flashTake reserves ARGV[1] units: {0} when the item is not in flash-sale
+// mode, {2, available} when short, otherwise {1, available before}
+const flashTake = `local v = redis.call('GET', KEYS[1])
+if not v then return {0} end
+v = tonumber(v)
+local n = tonumber(ARGV[1])
+if v < n then return {2, v} end
+redis.call('DECRBY', KEYS[1], n)
+redis.call('INCRBY', KEYS[2], n)
+return {1, v}`
+
+// flashAdd adds ARGV[1] to the available count and ARGV[2] to the pending
+// reservations: {0} when the item is not in flash-sale mode, otherwise
+// {1, available before}
+const flashAdd = `local v = redis.call('GET', KEYS[1])
+if not v then return {0} end
+redis.call('INCRBY', KEYS[1], ARGV[1])
+redis.call('INCRBY', KEYS[2], ARGV[2])
+return {1, tonumber(v)}`
+
+// flashDrain takes the pending reservations, leaving any added meanwhile
+const flashDrain = `local n = tonumber(redis.call('GET', KEYS[1]) or '0')
+if n ~= 0 then redis.call('DECRBY', KEYS[1], n) end
+return n`
+
+func (s *service) eval(ctx context.Context, script string, keys []string, args ...int) (interface{}, error) {
+	cmd := []string{"EVAL", script, strconv.Itoa(len(keys))}
+	cmd = append(cmd, keys...)
+	for _, a := range args {
+		cmd = append(cmd, strconv.Itoa(a))
+	}
+	return s.redis.Do(ctx, cmd...)
+}
+
+// flashResult reads a {code, available} script reply
+func flashResult(reply interface{}) (code, available int, err error) {
+	values, ok := reply.([]interface{})
+	if !ok || len(values) == 0 {
+		return 0, 0, fmt.Errorf("flash sale: unexpected reply %v", reply)
+	}
+	c, _ := values[0].(int64)
+	if len(values) > 1 {
+		v, _ := values[1].(int64)
+		available = int(v)
+	}
+	return int(c), available, nil
+}
+
+// reserveFlash reserves from Redis; handled is false when the item is not in
+// flash-sale mode
+func (s *service) reserveFlash(ctx context.Context, stockID uuid.UUID, qty int, warehouse string) (handled bool, err error) {
+	if s.redis == nil {
+		return false, nil
+	}
+	available, pending := flashKeys(stockID, warehouse)
+	reply, err := s.eval(ctx, flashTake, []string{available, pending}, qty)
+	if err != nil {
+		return true, err
+	}
+	code, before, err := flashResult(reply)
+	switch {
+	case err != nil:
+		return true, err
+	case code == 0:
+		return false, nil
+	case code == 2:
+		return true, ErrorInsufficientStock
+	}
+	s.changed(ctx, stockID, warehouse, before, before-qty)
+	return true, nil
+}
+
+// addFlash changes the Redis count of an item in flash-sale mode by delta,
+// and its pending reservations by reserved; handled is false when the item
+// is not in flash-sale mode
+func (s *service) addFlash(ctx context.Context, stockID uuid.UUID, delta, reserved int, warehouse string) (handled bool, err error) {
+	if s.redis == nil {
+		return false, nil
+	}
+	available, pending := flashKeys(stockID, warehouse)
+	reply, err := s.eval(ctx, flashAdd, []string{available, pending}, delta, reserved)
+	if err != nil {
+		return true, err
+	}
+	code, before, err := flashResult(reply)
+	if err != nil || code == 0 {
+		return err != nil, err
+	}
+	s.changed(ctx, stockID, warehouse, before, before+delta)
+	return true, nil
+}
+
+// flashAvailable is the live count of an item in flash-sale mode
+func (s *service) flashAvailable(ctx context.Context, stockID uuid.UUID, warehouse string) (*int, error) {
+	if s.redis == nil {
+		return nil, nil
+	}
+	key, _ := flashKeys(stockID, warehouse)
+	return s.getCount(ctx, key)
+}
+
+func (s *service) getCount(ctx context.Context, key string) (*int, error) {
+	reply, err := s.redis.Do(ctx, "GET", key)
+	if err != nil || reply == nil {
+		return nil, err
+	}
+	str, _ := reply.(string)
+	n, err := strconv.Atoi(str)
+	if err != nil {
+		return nil, fmt.Errorf("flash sale: %s holds %q", key, str)
+	}
+	return &n, nil
+}
+
+// FlashSales lists the stock items in flash-sale mode with their live counts
+func (s *service) FlashSales(ctx context.Context) ([]FlashSale, error) {
+	sales, err := s.repo.FlashSaleItems(ctx)
+	if err != nil || s.redis == nil {
+		return sales, err
+	}
+	for i := range sales {
+		available, pending := flashKeys(sales[i].ProductID, sales[i].Warehouse)
+		if sales[i].Available, err = s.getCount(ctx, available); err != nil {
+			return nil, err
+		}
+		p, err := s.getCount(ctx, pending)
+		if err != nil {
+			return nil, err
+		}
+		if p != nil {
+			sales[i].Pending = *p
+		}
+	}
+	return sales, nil
+}
+
+// EnableFlashSale moves the available count of a stock item into Redis. The
+// row stays locked until the count is there, so no transactional
+// reservation slips in between. Enabling an item already in flash-sale mode
+// keeps its live count.
+func (s *service) EnableFlashSale(ctx context.Context, stockID uuid.UUID, warehouse string) (*FlashSale, error) {
+	if s.redis == nil {
+		return nil, ErrorFlashSaleUnavailable
+	}
+	// moving stock onto a failing Redis would strand it there
+	if s.health != nil && s.health.Degraded(Server.DependencyRedis) {
+		return nil, ErrorFlashSaleDegraded
+	}
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	var inv Inventory
+	if err = tx.GetContext(ctx, &inv, `SELECT id,product_id,variant_id,warehouse,quantity,reserved FROM inventory WHERE `+stockItem+` AND warehouse=$2 FOR UPDATE`, stockID, warehouse); err != nil {
+		return nil, err
+	}
+	if _, err = tx.ExecContext(ctx, `UPDATE inventory SET flash_sale=TRUE, updated_at=NOW() WHERE id=$1`, inv.ID); err != nil {
+		return nil, err
+	}
+	// reservations left from an earlier sale whose count was lost have to be
+	// in the seed
+	available, pending := flashKeys(stockID, warehouse)
+	n, err := s.drain(ctx, pending)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.writeBack(ctx, tx, inv.ID, n); err != nil {
+		s.restore(ctx, pending, n)
+		return nil, err
+	}
+	seeded, err := s.redis.Do(ctx, "SET", available, strconv.Itoa(inv.Quantity-max(inv.Reserved+n, 0)), "NX")
+	if err != nil {
+		s.restore(ctx, pending, n)
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		s.restore(ctx, pending, n)
+		if seeded != nil {
+			if _, derr := s.redis.Do(ctx, "DEL", available); derr != nil {
+				s.log.Error("remove flash-sale count", zap.String("key", available), zap.Error(derr))
+			}
+		}
+		return nil, err
+	}
+	count, err := s.getCount(ctx, available)
+	if err != nil {
+		return nil, err
+	}
+	return &FlashSale{ProductID: stockID, Warehouse: warehouse, Available: count}, nil
+}
+
+// DisableFlashSale returns a stock item to the transactional path: the
+// count is removed from Redis and the pending reservations written back
+// while the row is locked.
+func (s *service) DisableFlashSale(ctx context.Context, stockID uuid.UUID, warehouse string) error {
+	if s.redis == nil {
+		return ErrorFlashSaleUnavailable
+	}
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	var id uuid.UUID
+	if err = tx.GetContext(ctx, &id, `SELECT id FROM inventory WHERE `+stockItem+` AND warehouse=$2 AND flash_sale FOR UPDATE`, stockID, warehouse); err != nil {
+		return err
+	}
+	available, pending := flashKeys(stockID, warehouse)
+	if _, err = s.redis.Do(ctx, "DEL", available); err != nil {
+		return err
+	}
+	n, err := s.drain(ctx, pending)
+	if err != nil {
+		return err
+	}
+	if err = s.writeBack(ctx, tx, id, n); err != nil {
+		s.restore(ctx, pending, n)
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, `UPDATE inventory SET flash_sale=FALSE, updated_at=NOW() WHERE id=$1`, id); err != nil {
+		s.restore(ctx, pending, n)
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		// the flag is still set, so the next flush writes these back
+		s.restore(ctx, pending, n)
+		return err
+	}
+	return nil
+}
+
+// FlushFlashSales writes the pending flash-sale reservations back to
+// Postgres; run by the scheduler every few seconds. Reservations that fail
+// to write are put back for the next run.
+func (s *service) FlushFlashSales(ctx context.Context) error {
+	if s.redis == nil {
+		return nil
+	}
+	sales, err := s.repo.FlashSaleItems(ctx)
+	if err != nil {
+		return err
+	}
+	for _, sale := range sales {
+		if err := s.flush(ctx, sale); err != nil {
+			s.log.Error("flush flash sale", zap.String("product_id", sale.ProductID.String()), zap.String("warehouse", sale.Warehouse), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+func (s *service) flush(ctx context.Context, sale FlashSale) (err error) {
+	_, pending := flashKeys(sale.ProductID, sale.Warehouse)
+	n, err := s.drain(ctx, pending)
+	if err != nil || n == 0 {
+		return err
+	}
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		s.restore(ctx, pending, n)
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			s.restore(ctx, pending, n)
+		}
+	}()
+	if err = s.writeBack(ctx, tx, sale.InventoryID, n); err != nil {
+		return err
+	}
+	err = tx.Commit()
+	return err
+}
+
+func (s *service) drain(ctx context.Context, pending string) (int, error) {
+	reply, err := s.eval(ctx, flashDrain, []string{pending})
+	if err != nil {
+		return 0, err
+	}
+	n, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("flash sale: unexpected reply %v", reply)
+	}
+	return int(n), nil
+}
+
+// restore puts drained reservations back when writing them failed
+func (s *service) restore(ctx context.Context, pending string, n int) {
+	if n == 0 {
+		return
+	}
+	if _, err := s.redis.Do(context.WithoutCancel(ctx), "INCRBY", pending, strconv.Itoa(n)); err != nil {
+		s.log.Error("restore pending flash-sale reservations", zap.String("key", pending), zap.Int("reserved", n), zap.Error(err))
+	}
+}
+
+// writeBack adds n flash-sale reservations (negative when more were
+// released) to the reserved column
+func (s *service) writeBack(ctx context.Context, tx sqlx.ExecerContext, inventoryID uuid.UUID, n int) error {
+	if n == 0 {
+		return nil
+	}
+	now := time.Now().UTC()
+	if _, err := tx.ExecContext(ctx, `UPDATE inventory SET reserved=GREATEST(reserved+$1, 0), updated_at=$2 WHERE id=$3`, n, now, inventoryID); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, `INSERT INTO stock_transactions (id,inventory_id,change,reason,created_at) VALUES ($1,$2,$3,$4,$5)`, uuid.New(), inventoryID, -n, "flash_sale", now)
+	return err
+}
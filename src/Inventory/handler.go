@@ -0,0 +1,296 @@
+package Inventory
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"savannah/src/ErrorCodes"
+)
+
+type Handler struct {
+	svc Service
+	log *zap.Logger
+	v   *validator.Validate
+}
+
+func NewHandler(s Service, log *zap.Logger) *Handler {
+	return &Handler{svc: s, log: log, v: validator.New()}
+}
+
+// ListRoutingRules godoc
+// @Summary      List order routing rules
+// @Tags         inventory
+// @Produce      json
+// @Success      200  {array}  RoutingRule
+// @Router       /routing-rules [get]
+func (h *Handler) ListRoutingRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.svc.ListRoutingRules(r.Context())
+	if err != nil {
+		h.log.Error("list routing rules", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to list routing rules")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, rules)
+}
+
+// CreateRoutingRule godoc
+// @Summary      Add an order routing rule
+// @Description  Makes a warehouse a fulfillment candidate for a country and/or region
+// @Tags         inventory
+// @Accept       json
+// @Produce      json
+// @Param        payload  body      CreateRoutingRuleRequest  true  "Rule"
+// @Success      201      {object}  RoutingRule
+// @Failure      400      {object}  map[string]interface{}
+// @Router       /routing-rules [post]
+func (h *Handler) CreateRoutingRule(w http.ResponseWriter, r *http.Request) {
+	var dto CreateRoutingRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if err := h.v.Struct(dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	rule, err := h.svc.CreateRoutingRule(r.Context(), dto)
+	if err != nil {
+		h.log.Error("create routing rule", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to create routing rule")
+		return
+	}
+	h.writeJSON(w, http.StatusCreated, rule)
+}
+
+// DeleteRoutingRule godoc
+// @Summary      Delete an order routing rule
+// @Tags         inventory
+// @Param        id   path  string  true  "Rule ID"
+// @Success      204
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /routing-rules/{id} [delete]
+func (h *Handler) DeleteRoutingRule(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	if err := h.svc.DeleteRoutingRule(r.Context(), id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			h.writeError(w, http.StatusNotFound, "routing rule not found")
+			return
+		}
+		h.log.Error("delete routing rule", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to delete routing rule")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CheckAvailability godoc
+// @Summary      Preview basket availability
+// @Description  Reports per line whether the basket could be reserved now, with alternatives for short lines (other warehouses, other variants). Nothing is reserved. Without a warehouse one is routed from country/region as at checkout.
+// @Tags         inventory
+// @Accept       json
+// @Produce      json
+// @Param        payload  body      CheckAvailabilityRequest  true  "Basket"
+// @Success      200      {object}  AvailabilityCheck
+// @Failure      400      {object}  map[string]interface{}
+// @Router       /inventory/check [post]
+func (h *Handler) CheckAvailability(w http.ResponseWriter, r *http.Request) {
+	var dto CheckAvailabilityRequest
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if err := h.v.Struct(dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	check, err := h.svc.CheckAvailability(r.Context(), dto)
+	if err != nil {
+		h.log.Error("check availability", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to check availability")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, check)
+}
+
+// CreateHold godoc
+// @Summary      Hold a basket's stock for checkout
+// @Description  Reserves every line in one warehouse for ttl_seconds (default 600) without placing an order. Placing the order with the hold_id converts the hold; otherwise it is released when deleted or once it expires.
+// @Tags         inventory
+// @Accept       json
+// @Produce      json
+// @Param        payload  body      CreateHoldRequest  true  "Basket"
+// @Success      201      {object}  Hold
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      409      {object}  map[string]interface{}
+// @Router       /inventory/holds [post]
+func (h *Handler) CreateHold(w http.ResponseWriter, r *http.Request) {
+	var dto CreateHoldRequest
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if err := h.v.Struct(dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	hold, err := h.svc.CreateHold(r.Context(), dto)
+	if err != nil {
+		h.holdError(w, "create stock hold", err)
+		return
+	}
+	h.writeJSON(w, http.StatusCreated, hold)
+}
+
+// GetHold godoc
+// @Summary      Get a stock hold
+// @Tags         inventory
+// @Produce      json
+// @Param        id   path      string  true  "Hold ID"
+// @Success      200  {object}  Hold
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /inventory/holds/{id} [get]
+func (h *Handler) GetHold(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	hold, err := h.svc.GetHold(r.Context(), id)
+	if err != nil {
+		h.holdError(w, "get stock hold", err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, hold)
+}
+
+// ReleaseHold godoc
+// @Summary      Release a stock hold
+// @Tags         inventory
+// @Param        id  path  string  true  "Hold ID"
+// @Success      204
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      409  {object}  map[string]interface{}
+// @Router       /inventory/holds/{id} [delete]
+func (h *Handler) ReleaseHold(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	if err := h.svc.ReleaseHold(r.Context(), id); err != nil {
+		h.holdError(w, "release stock hold", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) holdError(w http.ResponseWriter, op string, err error) {
+	switch {
+	case errors.Is(err, ErrorHoldNotFound):
+		h.writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrorHoldNotActive), errors.Is(err, ErrorInsufficientStock), errors.Is(err, ErrorNoWarehouse):
+		h.writeError(w, http.StatusConflict, err.Error())
+	default:
+		h.log.Error(op, zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to "+op)
+	}
+}
+
+// ListFlashSales godoc
+// @Summary      List stock items in flash-sale mode
+// @Description  With their live available count in Redis and the reservations not yet written back to the database
+// @Tags         inventory
+// @Produce      json
+// @Success      200  {array}  FlashSale
+// @Router       /flash-sales [get]
+func (h *Handler) ListFlashSales(w http.ResponseWriter, r *http.Request) {
+	sales, err := h.svc.FlashSales(r.Context())
+	if err != nil {
+		h.log.Error("list flash sales", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to list flash sales")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, sales)
+}
+
+// EnableFlashSale godoc
+// @Summary      Put a stock item in flash-sale mode
+// @Description  Moves the available count of the stock item (variant id for products sold in variants) in the warehouse to Redis, where it is reserved with atomic decrements and written back to the database asynchronously. Needs REDIS_URL.
+// @Tags         inventory
+// @Produce      json
+// @Param        warehouse  path      string  true  "Warehouse code"
+// @Param        id         path      string  true  "Product or variant ID"
+// @Success      200        {object}  FlashSale
+// @Failure      404        {object}  map[string]interface{}
+// @Failure      409        {object}  map[string]interface{}
+// @Router       /flash-sales/{warehouse}/{id} [put]
+func (h *Handler) EnableFlashSale(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	sale, err := h.svc.EnableFlashSale(r.Context(), id, chi.URLParam(r, "warehouse"))
+	if err != nil {
+		h.flashSaleError(w, "enable flash sale", err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, sale)
+}
+
+// DisableFlashSale godoc
+// @Summary      End flash-sale mode for a stock item
+// @Description  Writes the pending reservations back and returns the stock item to transactional reservations
+// @Tags         inventory
+// @Param        warehouse  path  string  true  "Warehouse code"
+// @Param        id         path  string  true  "Product or variant ID"
+// @Success      204
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /flash-sales/{warehouse}/{id} [delete]
+func (h *Handler) DisableFlashSale(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	if err := h.svc.DisableFlashSale(r.Context(), id, chi.URLParam(r, "warehouse")); err != nil {
+		h.flashSaleError(w, "disable flash sale", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) flashSaleError(w http.ResponseWriter, op string, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		h.writeError(w, http.StatusNotFound, "stock item not in flash-sale mode or not stocked in warehouse")
+	case errors.Is(err, ErrorFlashSaleUnavailable):
+		h.writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, ErrorFlashSaleDegraded):
+		h.writeError(w, http.StatusServiceUnavailable, err.Error())
+	default:
+		h.log.Error(op, zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to "+op)
+	}
+}
+
+func (h *Handler) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (h *Handler) writeError(w http.ResponseWriter, status int, msg string) {
+	h.writeJSON(w, status, map[string]interface{}{"error": msg, "code": ErrorCodes.Resolve("INVENTORY", status, msg), "timestamp": time.Now().UTC()})
+}
@@ -0,0 +1,146 @@
+package Inventory
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultHoldTTL = 10 * time.Minute
+	// expiredHoldBatch caps the holds one ExpireHolds run releases
+	expiredHoldBatch = 500
+)
+
+// CreateHold reserves the basket in one warehouse for a checkout that has
+// not placed its order yet. Lines asking for the same stock item are added
+// up. The hold is recorded before any of its stock is reserved, so no
+// reservation outlives a crash without a hold to expire it. Either every
+// line is reserved or none is: a short line releases the hold and what was
+// reserved before it, and fails with ErrorInsufficientStock.
+func (s *service) CreateHold(ctx context.Context, req CreateHoldRequest) (*Hold, error) {
+	now := time.Now().UTC()
+	ttl := defaultHoldTTL
+	if req.TTLSeconds > 0 {
+		ttl = time.Duration(req.TTLSeconds) * time.Second
+	}
+	h := &Hold{ID: uuid.New(), Warehouse: req.Warehouse, Status: HoldActive, ExpiresAt: now.Add(ttl), CreatedAt: now, UpdatedAt: now}
+	if req.Reference != "" {
+		h.Reference = &req.Reference
+	}
+	quantities := map[uuid.UUID]int{}
+	index := map[uuid.UUID]int{}
+	for _, it := range req.Items {
+		id := it.stockID()
+		quantities[id] += it.Quantity
+		if i, ok := index[id]; ok {
+			h.Items[i].Quantity += it.Quantity
+			continue
+		}
+		index[id] = len(h.Items)
+		h.Items = append(h.Items, HoldItem{ProductID: it.ProductID, VariantID: it.VariantID, Quantity: it.Quantity})
+	}
+	if h.Warehouse == "" {
+		warehouse, err := s.SelectWarehouse(ctx, req.Country, req.Region, quantities)
+		if err != nil {
+			return nil, err
+		}
+		h.Warehouse = warehouse
+	}
+	if err := s.repo.CreateHold(ctx, h); err != nil {
+		return nil, err
+	}
+	for i, it := range h.Items {
+		if err := s.Reserve(ctx, it.stockID(), it.Quantity, h.Warehouse); err != nil {
+			if _, endErr := s.repo.EndHold(ctx, h.ID, HoldReleased, "", time.Now().UTC()); endErr != nil {
+				s.log.Error("release short hold", zap.String("hold_id", h.ID.String()), zap.Error(endErr))
+			}
+			s.releaseHeld(ctx, h.ID, h.Items[:i], h.Warehouse)
+			if errors.Is(err, sql.ErrNoRows) {
+				err = ErrorInsufficientStock
+			}
+			return nil, err
+		}
+	}
+	return h, nil
+}
+
+func (s *service) GetHold(ctx context.Context, id uuid.UUID) (*Hold, error) {
+	return s.repo.GetHold(ctx, id)
+}
+
+// ReleaseHold gives the stock of an active hold back, for a checkout that
+// was abandoned before its hold expired
+func (s *service) ReleaseHold(ctx context.Context, id uuid.UUID) error {
+	h, err := s.repo.EndHold(ctx, id, HoldReleased, "", time.Now().UTC())
+	if err != nil {
+		return s.holdError(ctx, id, err)
+	}
+	s.releaseHeld(ctx, h.ID, h.Items, h.Warehouse)
+	return nil
+}
+
+// ConvertHold hands the stock of an unexpired hold in warehouse over to the
+// order being placed and returns the quantities held per stock item. The
+// stock stays reserved: the order takes what it needs and releases the rest.
+func (s *service) ConvertHold(ctx context.Context, id uuid.UUID, warehouse string) (map[uuid.UUID]int, error) {
+	h, err := s.repo.EndHold(ctx, id, HoldConverted, warehouse, time.Now().UTC())
+	if err != nil {
+		return nil, s.holdError(ctx, id, err)
+	}
+	held := make(map[uuid.UUID]int, len(h.Items))
+	for _, it := range h.Items {
+		held[it.stockID()] += it.Quantity
+	}
+	return held, nil
+}
+
+// ExpireHolds releases the stock of the holds past their expiry. Each hold
+// is marked expired before its stock is released, so a run racing a
+// conversion or another run releases nothing twice.
+func (s *service) ExpireHolds(ctx context.Context) error {
+	now := time.Now().UTC()
+	ids, err := s.repo.ExpiredHolds(ctx, now, expiredHoldBatch)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		h, err := s.repo.EndHold(ctx, id, HoldExpired, "", now)
+		if errors.Is(err, ErrorHoldNotActive) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		s.releaseHeld(ctx, h.ID, h.Items, h.Warehouse)
+	}
+	if len(ids) > 0 {
+		s.log.Info("expired stock holds", zap.Int("count", len(ids)))
+	}
+	return nil
+}
+
+// releaseHeld returns held stock; a failure is logged, as the hold has
+// already ended and retrying would not find it
+func (s *service) releaseHeld(ctx context.Context, holdID uuid.UUID, items []HoldItem, warehouse string) {
+	for _, it := range items {
+		if err := s.Release(ctx, it.stockID(), it.Quantity, warehouse); err != nil {
+			s.log.Error("release held stock", zap.String("hold_id", holdID.String()), zap.String("stock_id", it.stockID().String()), zap.Int("quantity", it.Quantity), zap.Error(err))
+		}
+	}
+}
+
+// holdError tells a missing hold from one that can no longer be ended
+func (s *service) holdError(ctx context.Context, id uuid.UUID, err error) error {
+	if !errors.Is(err, ErrorHoldNotActive) {
+		return err
+	}
+	if _, getErr := s.repo.GetHold(ctx, id); errors.Is(getErr, ErrorHoldNotFound) {
+		return ErrorHoldNotFound
+	}
+	return err
+}
@@ -7,13 +7,15 @@ import (
 )
 
 type Inventory struct {
-	ID        uuid.UUID `db:"id" json:"id"`
-	ProductID uuid.UUID `db:"product_id" json:"product_id"`
-	Warehouse string    `db:"warehouse" json:"warehouse"`
-	Quantity  int       `db:"quantity" json:"quantity"`
-	Reserved  int       `db:"reserved" json:"reserved"`
-	CreatedAt time.Time `db:"created_at" json:"created_at"`
-	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+	ID          uuid.UUID  `db:"id" json:"id"`
+	ProductID   uuid.UUID  `db:"product_id" json:"product_id"`
+	VariantID   *uuid.UUID `db:"variant_id" json:"variant_id,omitempty"`
+	Warehouse   string     `db:"warehouse" json:"warehouse"`
+	Quantity    int        `db:"quantity" json:"quantity"`
+	Reserved    int        `db:"reserved" json:"reserved"`
+	BinLocation *string    `db:"bin_location" json:"bin_location,omitempty"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt   time.Time  `db:"updated_at" json:"updated_at"`
 }
 
 type StockTransaction struct {
@@ -24,3 +26,107 @@ type StockTransaction struct {
 	Reference   *string   `db:"reference" json:"reference,omitempty"`
 	CreatedAt   time.Time `db:"created_at" json:"created_at"`
 }
+
+// FlashSale is a stock item in flash-sale mode. Available is its live count
+// in Redis (absent when the count is gone and the item has fallen back to
+// the transactional path); Pending is what is not yet written back.
+type FlashSale struct {
+	InventoryID uuid.UUID `db:"id" json:"-"`
+	ProductID   uuid.UUID `db:"product_id" json:"product_id"`
+	Warehouse   string    `db:"warehouse" json:"warehouse"`
+	Available   *int      `json:"available,omitempty"`
+	Pending     int       `json:"pending"`
+}
+
+// RoutingRule makes warehouse a fulfillment candidate for shipping addresses
+// in country/region; an empty country or region matches any.
+type RoutingRule struct {
+	ID        uuid.UUID `db:"id" json:"id"`
+	Country   *string   `db:"country" json:"country,omitempty"`
+	Region    *string   `db:"region" json:"region,omitempty"`
+	Warehouse string    `db:"warehouse" json:"warehouse"`
+	Priority  int       `db:"priority" json:"priority"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+// StockLevel is the unreserved quantity of a stock item (product, or variant
+// for products sold in variants) in one warehouse
+type StockLevel struct {
+	Warehouse string    `db:"warehouse"`
+	ProductID uuid.UUID `db:"product_id"`
+	Available int       `db:"available"`
+}
+
+// ProductStock is the unreserved quantity of one inventory row, keeping the
+// product a variant belongs to
+type ProductStock struct {
+	Warehouse string     `db:"warehouse"`
+	ProductID uuid.UUID  `db:"product_id"`
+	VariantID *uuid.UUID `db:"variant_id"`
+	Available int        `db:"available"`
+}
+
+// AvailabilityCheck answers whether a basket could be reserved now. Nothing
+// is reserved, so the answer can change before the order is placed.
+type AvailabilityCheck struct {
+	// the warehouse the lines were checked against; when none can fulfill
+	// the whole basket, the one that fulfills the most lines
+	Warehouse string             `json:"warehouse"`
+	Available bool               `json:"available"`
+	Lines     []LineAvailability `json:"lines"`
+}
+
+type LineAvailability struct {
+	ProductID    uuid.UUID     `json:"product_id"`
+	VariantID    *uuid.UUID    `json:"variant_id,omitempty"`
+	Requested    int           `json:"requested"`
+	Available    int           `json:"available"`
+	InStock      bool          `json:"in_stock"`
+	Alternatives []Alternative `json:"alternatives,omitempty"`
+}
+
+// Alternative can fill a line that is short: the same item from another
+// warehouse, or another variant of the product from the checked one.
+type Alternative struct {
+	Warehouse string     `json:"warehouse"`
+	VariantID *uuid.UUID `json:"variant_id,omitempty"`
+	Available int        `json:"available"`
+}
+
+// hold statuses
+const (
+	HoldActive    = "ACTIVE"
+	HoldConverted = "CONVERTED" // its stock went to an order
+	HoldReleased  = "RELEASED"
+	HoldExpired   = "EXPIRED"
+)
+
+// Hold keeps stock reserved for a checkout in progress, such as a cart on
+// the payment page, for a few minutes without placing an order. The stock
+// is reserved like an order's until the hold is converted into the order,
+// released, or expires.
+type Hold struct {
+	ID        uuid.UUID  `db:"id" json:"id"`
+	Reference *string    `db:"reference" json:"reference,omitempty"` // e.g. the cart id
+	Warehouse string     `db:"warehouse" json:"warehouse"`
+	Status    string     `db:"status" json:"status"`
+	ExpiresAt time.Time  `db:"expires_at" json:"expires_at"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time  `db:"updated_at" json:"updated_at"`
+	Items     []HoldItem `db:"-" json:"items"`
+}
+
+// HoldItem is the quantity of one stock item a hold keeps
+type HoldItem struct {
+	HoldID    uuid.UUID  `db:"hold_id" json:"-"`
+	ProductID uuid.UUID  `db:"product_id" json:"product_id"`
+	VariantID *uuid.UUID `db:"variant_id" json:"variant_id,omitempty"`
+	Quantity  int        `db:"quantity" json:"quantity"`
+}
+
+func (it HoldItem) stockID() uuid.UUID {
+	if it.VariantID != nil {
+		return *it.VariantID
+	}
+	return it.ProductID
+}
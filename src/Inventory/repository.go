@@ -3,6 +3,7 @@ package Inventory
 import (
 	"context"
 	"database/sql"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,6 +15,19 @@ type Repository interface {
 	GetByProductAndWarehouse(ctx context.Context, productID uuid.UUID, warehouse string) (*Inventory, error)
 	UpsertInventory(ctx context.Context, inv *Inventory) error
 	AdjustInventory(ctx context.Context, inventoryID uuid.UUID, change int, reason, reference string) error
+
+	MatchRoutingRules(ctx context.Context, country, region string) ([]RoutingRule, error)
+	ListRoutingRules(ctx context.Context) ([]RoutingRule, error)
+	CreateRoutingRule(ctx context.Context, rule *RoutingRule) error
+	DeleteRoutingRule(ctx context.Context, id uuid.UUID) error
+	StockLevels(ctx context.Context, productIDs []uuid.UUID) ([]StockLevel, error)
+	ProductStock(ctx context.Context, productIDs []uuid.UUID) ([]ProductStock, error)
+	FlashSaleItems(ctx context.Context) ([]FlashSale, error)
+
+	CreateHold(ctx context.Context, h *Hold) error
+	GetHold(ctx context.Context, id uuid.UUID) (*Hold, error)
+	EndHold(ctx context.Context, id uuid.UUID, status, warehouse string, now time.Time) (*Hold, error)
+	ExpiredHolds(ctx context.Context, now time.Time, limit int) ([]uuid.UUID, error)
 }
 
 type repository struct {
@@ -21,11 +35,15 @@ type repository struct {
 	log *zap.Logger
 }
 
+// stockItem matches the inventory row of a stock item id ($1): a variant id
+// for products sold in variants, otherwise the product id.
+const stockItem = `(variant_id=$1 OR (variant_id IS NULL AND product_id=$1))`
+
 func NewRepository(db *sqlx.DB, log *zap.Logger) Repository { return &repository{db: db, log: log} }
 
 func (r *repository) GetByProductAndWarehouse(ctx context.Context, productID uuid.UUID, warehouse string) (*Inventory, error) {
 	var inv Inventory
-	if err := r.db.GetContext(ctx, &inv, `SELECT id,product_id,warehouse,quantity,reserved,created_at,updated_at FROM inventory WHERE product_id=$1 AND warehouse=$2`, productID, warehouse); err != nil {
+	if err := r.db.GetContext(ctx, &inv, `SELECT id,product_id,variant_id,warehouse,quantity,reserved,bin_location,created_at,updated_at FROM inventory WHERE `+stockItem+` AND warehouse=$2`, productID, warehouse); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, sql.ErrNoRows
 		}
@@ -40,7 +58,11 @@ func (r *repository) UpsertInventory(ctx context.Context, inv *Inventory) error
 		inv.CreatedAt = time.Now().UTC()
 	}
 	inv.UpdatedAt = time.Now().UTC()
-	_, err := r.db.ExecContext(ctx, `INSERT INTO inventory (id,product_id,warehouse,quantity,reserved,created_at,updated_at) VALUES ($1,$2,$3,$4,$5,$6,$7) ON CONFLICT (product_id,warehouse) DO UPDATE SET quantity=EXCLUDED.quantity, reserved=EXCLUDED.reserved, updated_at=EXCLUDED.updated_at`, inv.ID, inv.ProductID, inv.Warehouse, inv.Quantity, inv.Reserved, inv.CreatedAt, inv.UpdatedAt)
+	conflict := `(product_id,warehouse) WHERE variant_id IS NULL`
+	if inv.VariantID != nil {
+		conflict = `(variant_id,warehouse) WHERE variant_id IS NOT NULL`
+	}
+	_, err := r.db.ExecContext(ctx, `INSERT INTO inventory (id,product_id,variant_id,warehouse,quantity,reserved,bin_location,created_at,updated_at) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9) ON CONFLICT `+conflict+` DO UPDATE SET quantity=EXCLUDED.quantity, reserved=EXCLUDED.reserved, bin_location=EXCLUDED.bin_location, updated_at=EXCLUDED.updated_at`, inv.ID, inv.ProductID, inv.VariantID, inv.Warehouse, inv.Quantity, inv.Reserved, inv.BinLocation, inv.CreatedAt, inv.UpdatedAt)
 	return err
 }
 
@@ -53,3 +75,133 @@ func (r *repository) AdjustInventory(ctx context.Context, inventoryID uuid.UUID,
 	_, err = r.db.NamedExecContext(ctx, `INSERT INTO stock_transactions (id,inventory_id,change,reason,reference,created_at) VALUES (:id,:inventory_id,:change,:reason,:reference,:created_at)`, st)
 	return err
 }
+
+const routingRuleColumns = `id,country,region,warehouse,priority,created_at`
+
+// MatchRoutingRules returns the rules covering the address, most specific first
+func (r *repository) MatchRoutingRules(ctx context.Context, country, region string) ([]RoutingRule, error) {
+	var rules []RoutingRule
+	err := r.db.SelectContext(ctx, &rules, `SELECT `+routingRuleColumns+` FROM routing_rules
+		WHERE (country IS NULL OR country=$1) AND (region IS NULL OR LOWER(region)=LOWER($2))
+		ORDER BY (country IS NOT NULL)::int + (region IS NOT NULL)::int DESC, priority, created_at`, strings.ToUpper(country), region)
+	return rules, err
+}
+
+func (r *repository) ListRoutingRules(ctx context.Context) ([]RoutingRule, error) {
+	rules := []RoutingRule{}
+	err := r.db.SelectContext(ctx, &rules, `SELECT `+routingRuleColumns+` FROM routing_rules ORDER BY country NULLS LAST, region NULLS LAST, priority`)
+	return rules, err
+}
+
+func (r *repository) CreateRoutingRule(ctx context.Context, rule *RoutingRule) error {
+	rule.ID = uuid.New()
+	rule.CreatedAt = time.Now().UTC()
+	_, err := r.db.ExecContext(ctx, `INSERT INTO routing_rules (id,country,region,warehouse,priority,created_at) VALUES ($1,$2,$3,$4,$5,$6)`, rule.ID, rule.Country, rule.Region, rule.Warehouse, rule.Priority, rule.CreatedAt)
+	return err
+}
+
+func (r *repository) DeleteRoutingRule(ctx context.Context, id uuid.UUID) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM routing_rules WHERE id=$1`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *repository) StockLevels(ctx context.Context, productIDs []uuid.UUID) ([]StockLevel, error) {
+	query, args, err := sqlx.In(`SELECT warehouse, COALESCE(variant_id, product_id) AS product_id, quantity - reserved AS available FROM inventory
+		WHERE variant_id IN (?) OR (variant_id IS NULL AND product_id IN (?))`, productIDs, productIDs)
+	if err != nil {
+		return nil, err
+	}
+	var levels []StockLevel
+	err = r.db.SelectContext(ctx, &levels, r.db.Rebind(query), args...)
+	return levels, err
+}
+
+// ProductStock returns every inventory row of the products, variants included
+func (r *repository) ProductStock(ctx context.Context, productIDs []uuid.UUID) ([]ProductStock, error) {
+	query, args, err := sqlx.In(`SELECT warehouse, product_id, variant_id, quantity - reserved AS available FROM inventory WHERE product_id IN (?)`, productIDs)
+	if err != nil {
+		return nil, err
+	}
+	var stock []ProductStock
+	err = r.db.SelectContext(ctx, &stock, r.db.Rebind(query), args...)
+	return stock, err
+}
+
+// FlashSaleItems lists the inventory rows in flash-sale mode by stock item
+func (r *repository) FlashSaleItems(ctx context.Context) ([]FlashSale, error) {
+	sales := []FlashSale{}
+	err := r.db.SelectContext(ctx, &sales, `SELECT id, COALESCE(variant_id, product_id) AS product_id, warehouse FROM inventory WHERE flash_sale ORDER BY warehouse, product_id`)
+	return sales, err
+}
+
+const holdColumns = `id,reference,warehouse,status,expires_at,created_at,updated_at`
+
+func (r *repository) CreateHold(ctx context.Context, h *Hold) (err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	if _, err = tx.NamedExecContext(ctx, `INSERT INTO stock_holds (`+holdColumns+`) VALUES (:id,:reference,:warehouse,:status,:expires_at,:created_at,:updated_at)`, h); err != nil {
+		return err
+	}
+	for i := range h.Items {
+		h.Items[i].HoldID = h.ID
+		if _, err = tx.NamedExecContext(ctx, `INSERT INTO stock_hold_items (hold_id,product_id,variant_id,quantity) VALUES (:hold_id,:product_id,:variant_id,:quantity)`, h.Items[i]); err != nil {
+			return err
+		}
+	}
+	err = tx.Commit()
+	return err
+}
+
+func (r *repository) GetHold(ctx context.Context, id uuid.UUID) (*Hold, error) {
+	var h Hold
+	if err := r.db.GetContext(ctx, &h, `SELECT `+holdColumns+` FROM stock_holds WHERE id=$1`, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrorHoldNotFound
+		}
+		return nil, err
+	}
+	return &h, r.holdItems(ctx, &h)
+}
+
+func (r *repository) holdItems(ctx context.Context, h *Hold) error {
+	h.Items = []HoldItem{}
+	return r.db.SelectContext(ctx, &h.Items, `SELECT hold_id,product_id,variant_id,quantity FROM stock_hold_items WHERE hold_id=$1 ORDER BY product_id, variant_id`, h.ID)
+}
+
+// EndHold moves an active hold to status and returns it. Only an unexpired
+// hold converts, only an expired one expires, and when warehouse is given
+// the hold must keep its stock there. Only one caller can end a hold, so only one
+// gets to release or convert its stock.
+func (r *repository) EndHold(ctx context.Context, id uuid.UUID, status, warehouse string, now time.Time) (*Hold, error) {
+	var h Hold
+	err := r.db.GetContext(ctx, &h, `UPDATE stock_holds SET status=$2, updated_at=$4
+		WHERE id=$1 AND status='`+HoldActive+`' AND ($3 = '' OR warehouse=$3) AND CASE $2 WHEN '`+HoldConverted+`' THEN expires_at > $4 WHEN '`+HoldExpired+`' THEN expires_at <= $4 ELSE TRUE END
+		RETURNING `+holdColumns, id, status, warehouse, now)
+	if err == sql.ErrNoRows {
+		return nil, ErrorHoldNotActive
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &h, r.holdItems(ctx, &h)
+}
+
+// ExpiredHolds lists active holds past their expiry, oldest first
+func (r *repository) ExpiredHolds(ctx context.Context, now time.Time, limit int) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := r.db.SelectContext(ctx, &ids, `SELECT id FROM stock_holds WHERE status='`+HoldActive+`' AND expires_at <= $1 ORDER BY expires_at LIMIT $2`, now, limit)
+	return ids, err
+}
@@ -0,0 +1,101 @@
+package Inventory
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// SelectWarehouse picks the fulfillment warehouse for an order shipping to
+// country/region. Warehouses named by matching routing rules are tried in
+// rule order and the first with stock for every line wins; when no rule
+// matches, any warehouse holding all lines is used, largest stock first.
+func (s *service) SelectWarehouse(ctx context.Context, country, region string, quantities map[uuid.UUID]int) (string, error) {
+	if len(quantities) == 0 {
+		return "", ErrorNoWarehouse
+	}
+	productIDs := make([]uuid.UUID, 0, len(quantities))
+	for id := range quantities {
+		productIDs = append(productIDs, id)
+	}
+	levels, err := s.repo.StockLevels(ctx, productIDs)
+	if err != nil {
+		return "", err
+	}
+	// warehouse -> product -> available
+	stock := map[string]map[uuid.UUID]int{}
+	for _, l := range levels {
+		if stock[l.Warehouse] == nil {
+			stock[l.Warehouse] = map[uuid.UUID]int{}
+		}
+		stock[l.Warehouse][l.ProductID] = l.Available
+	}
+	canFulfill := func(warehouse string) bool {
+		for id, qty := range quantities {
+			if stock[warehouse][id] < qty {
+				return false
+			}
+		}
+		return true
+	}
+
+	rules, err := s.repo.MatchRoutingRules(ctx, country, region)
+	if err != nil {
+		return "", err
+	}
+	if len(rules) > 0 {
+		for _, rule := range rules {
+			if canFulfill(rule.Warehouse) {
+				return rule.Warehouse, nil
+			}
+		}
+		return "", ErrorNoWarehouse
+	}
+
+	candidates := make([]string, 0, len(stock))
+	totals := map[string]int{}
+	for warehouse, products := range stock {
+		if !canFulfill(warehouse) {
+			continue
+		}
+		candidates = append(candidates, warehouse)
+		for _, available := range products {
+			totals[warehouse] += available
+		}
+	}
+	if len(candidates) == 0 {
+		return "", ErrorNoWarehouse
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if totals[candidates[i]] != totals[candidates[j]] {
+			return totals[candidates[i]] > totals[candidates[j]]
+		}
+		return candidates[i] < candidates[j]
+	})
+	return candidates[0], nil
+}
+
+func (s *service) ListRoutingRules(ctx context.Context) ([]RoutingRule, error) {
+	return s.repo.ListRoutingRules(ctx)
+}
+
+func (s *service) CreateRoutingRule(ctx context.Context, req CreateRoutingRuleRequest) (*RoutingRule, error) {
+	rule := &RoutingRule{Region: req.Region, Warehouse: req.Warehouse, Priority: req.Priority}
+	if req.Country != nil {
+		country := strings.ToUpper(*req.Country)
+		rule.Country = &country
+	}
+	if rule.Priority == 0 {
+		rule.Priority = 100
+	}
+	if err := s.repo.CreateRoutingRule(ctx, rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (s *service) DeleteRoutingRule(ctx context.Context, id uuid.UUID) error {
+	return s.repo.DeleteRoutingRule(ctx, id)
+}
@@ -2,6 +2,7 @@ package Inventory
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"time"
 
@@ -10,23 +11,65 @@ import (
 	"go.uber.org/zap"
 )
 
+// Service tracks stock per stock item: the methods taking a productID expect
+// the variant id for products sold in variants. Items in flash-sale mode
+// are reserved from Redis instead (see flashsale.go).
 type Service interface {
 	Reserve(ctx context.Context, productID uuid.UUID, qty int, warehouse string) error
 	Release(ctx context.Context, productID uuid.UUID, qty int, warehouse string) error
 	GetAvailable(ctx context.Context, productID uuid.UUID, warehouse string) (int, error)
+	Sell(ctx context.Context, productID uuid.UUID, qty int, warehouse, reference string) (int, error)
+
+	SelectWarehouse(ctx context.Context, country, region string, quantities map[uuid.UUID]int) (string, error)
+	ListRoutingRules(ctx context.Context) ([]RoutingRule, error)
+	CreateRoutingRule(ctx context.Context, req CreateRoutingRuleRequest) (*RoutingRule, error)
+	DeleteRoutingRule(ctx context.Context, id uuid.UUID) error
+
+	CheckAvailability(ctx context.Context, req CheckAvailabilityRequest) (*AvailabilityCheck, error)
+
+	FlashSales(ctx context.Context) ([]FlashSale, error)
+	EnableFlashSale(ctx context.Context, productID uuid.UUID, warehouse string) (*FlashSale, error)
+	DisableFlashSale(ctx context.Context, productID uuid.UUID, warehouse string) error
+	FlushFlashSales(ctx context.Context) error
+
+	CreateHold(ctx context.Context, req CreateHoldRequest) (*Hold, error)
+	GetHold(ctx context.Context, id uuid.UUID) (*Hold, error)
+	ReleaseHold(ctx context.Context, id uuid.UUID) error
+	ConvertHold(ctx context.Context, id uuid.UUID, warehouse string) (map[uuid.UUID]int, error)
+	ExpireHolds(ctx context.Context) error
+}
+
+// StockListener is told how many units of a stock item were available in a
+// warehouse before and after each change, once the change is committed
+type StockListener interface {
+	StockChanged(ctx context.Context, productID uuid.UUID, warehouse string, before, after int)
 }
 
 type service struct {
-	repo Repository
-	db   *sqlx.DB
-	log  *zap.Logger
+	repo     Repository
+	db       *sqlx.DB
+	redis    Redis
+	health   Health
+	listener StockListener
+	log      *zap.Logger
 }
 
-func NewService(r Repository, db *sqlx.DB, log *zap.Logger) Service {
-	return &service{repo: r, db: db, log: log}
+// NewService takes a nil redis when flash-sale mode is not used, and a nil
+// health when dependencies are not checked
+func NewService(r Repository, db *sqlx.DB, redis Redis, health Health, listener StockListener, log *zap.Logger) Service {
+	return &service{repo: r, db: db, redis: redis, health: health, listener: listener, log: log}
 }
 
-func (s *service) Reserve(ctx context.Context, productID uuid.UUID, qty int, warehouse string) error {
+func (s *service) changed(ctx context.Context, productID uuid.UUID, warehouse string, before, after int) {
+	if s.listener != nil && before != after {
+		s.listener.StockChanged(ctx, productID, warehouse, before, after)
+	}
+}
+
+func (s *service) Reserve(ctx context.Context, productID uuid.UUID, qty int, warehouse string) (err error) {
+	if handled, err := s.reserveFlash(ctx, productID, qty, warehouse); handled {
+		return err
+	}
 	// simple strategy: single inventory row per product+warehouse; use transaction + row lock
 	tx, err := s.db.BeginTxx(ctx, nil)
 	if err != nil {
@@ -38,12 +81,12 @@ func (s *service) Reserve(ctx context.Context, productID uuid.UUID, qty int, war
 		}
 	}()
 	var inv Inventory
-	if err := tx.GetContext(ctx, &inv, `SELECT id,product_id,warehouse,quantity,reserved FROM inventory WHERE product_id=$1 AND warehouse=$2 FOR UPDATE`, productID, warehouse); err != nil {
+	if err = tx.GetContext(ctx, &inv, `SELECT id,product_id,variant_id,warehouse,quantity,reserved FROM inventory WHERE `+stockItem+` AND warehouse=$2 FOR UPDATE`, productID, warehouse); err != nil {
 		return err
 	}
 	available := inv.Quantity - inv.Reserved
 	if available < qty {
-		return errors.New("insufficient stock")
+		return ErrorInsufficientStock
 	}
 	inv.Reserved += qty
 	inv.UpdatedAt = time.Now().UTC()
@@ -56,10 +99,14 @@ func (s *service) Reserve(ctx context.Context, productID uuid.UUID, qty int, war
 	if err = tx.Commit(); err != nil {
 		return err
 	}
+	s.changed(ctx, productID, warehouse, available, available-qty)
 	return nil
 }
 
-func (s *service) Release(ctx context.Context, productID uuid.UUID, qty int, warehouse string) error {
+func (s *service) Release(ctx context.Context, productID uuid.UUID, qty int, warehouse string) (err error) {
+	if handled, err := s.addFlash(ctx, productID, qty, -qty, warehouse); handled {
+		return err
+	}
 	tx, err := s.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return err
@@ -70,12 +117,13 @@ func (s *service) Release(ctx context.Context, productID uuid.UUID, qty int, war
 		}
 	}()
 	var inv Inventory
-	if err := tx.GetContext(ctx, &inv, `SELECT id,product_id,warehouse,quantity,reserved FROM inventory WHERE product_id=$1 AND warehouse=$2 FOR UPDATE`, productID, warehouse); err != nil {
+	if err = tx.GetContext(ctx, &inv, `SELECT id,product_id,variant_id,warehouse,quantity,reserved FROM inventory WHERE `+stockItem+` AND warehouse=$2 FOR UPDATE`, productID, warehouse); err != nil {
 		return err
 	}
 	if inv.Reserved < qty {
 		return errors.New("release quantity exceeds reserved")
 	}
+	available := inv.Quantity - inv.Reserved
 	inv.Reserved -= qty
 	inv.UpdatedAt = time.Now().UTC()
 	if _, err = tx.ExecContext(ctx, `UPDATE inventory SET reserved=$1, updated_at=$2 WHERE id=$3`, inv.Reserved, inv.UpdatedAt, inv.ID); err != nil {
@@ -87,10 +135,62 @@ func (s *service) Release(ctx context.Context, productID uuid.UUID, qty int, war
 	if err = tx.Commit(); err != nil {
 		return err
 	}
+	s.changed(ctx, productID, warehouse, available, available+qty)
 	return nil
 }
 
+// Sell deducts stock that already left the warehouse over the counter, so it
+// never refuses: on-hand quantity is reduced by what is there and the
+// unreserved quantity before the sale is returned for the caller to report
+// any shortfall. A product the warehouse does not stock has 0 available.
+func (s *service) Sell(ctx context.Context, productID uuid.UUID, qty int, warehouse, reference string) (available int, err error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	var inv Inventory
+	if err = tx.GetContext(ctx, &inv, `SELECT id,product_id,variant_id,warehouse,quantity,reserved FROM inventory WHERE `+stockItem+` AND warehouse=$2 FOR UPDATE`, productID, warehouse); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			_ = tx.Rollback()
+			return 0, nil
+		}
+		return 0, err
+	}
+	available = inv.Quantity - inv.Reserved
+	deducted := min(qty, inv.Quantity)
+	if deducted <= 0 {
+		return available, tx.Commit()
+	}
+	now := time.Now().UTC()
+	if _, err = tx.ExecContext(ctx, `UPDATE inventory SET quantity=quantity-$1, updated_at=$2 WHERE id=$3`, deducted, now, inv.ID); err != nil {
+		return 0, err
+	}
+	if _, err = tx.ExecContext(ctx, `INSERT INTO stock_transactions (id,inventory_id,change,reason,reference,created_at) VALUES ($1,$2,$3,$4,$5,$6)`, uuid.New(), inv.ID, -deducted, "pos_sale", reference, now); err != nil {
+		return 0, err
+	}
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+	if _, ferr := s.addFlash(ctx, productID, -deducted, 0, warehouse); ferr != nil {
+		s.log.Error("deduct flash-sale count", zap.String("product_id", productID.String()), zap.Error(ferr))
+	} else {
+		s.changed(ctx, productID, warehouse, available, available-deducted)
+	}
+	return available, nil
+}
+
 func (s *service) GetAvailable(ctx context.Context, productID uuid.UUID, warehouse string) (int, error) {
+	if n, err := s.flashAvailable(ctx, productID, warehouse); err != nil || n != nil {
+		if err != nil {
+			return 0, err
+		}
+		return *n, nil
+	}
 	inv, err := s.repo.GetByProductAndWarehouse(ctx, productID, warehouse)
 	if err != nil {
 		return 0, err
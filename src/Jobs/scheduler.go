@@ -0,0 +1,108 @@
+package Jobs
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Job is a unit of background work run periodically by the Scheduler
+type Job func(ctx context.Context) error
+
+// Locker takes locks shared by every replica (see the Locking package)
+type Locker interface {
+	TryLock(ctx context.Context, key string) (unlock func(), ok bool, err error)
+}
+
+type entry struct {
+	name      string
+	every     time.Duration
+	job       Job
+	exclusive bool
+}
+
+// Scheduler runs registered jobs on fixed intervals. A job never overlaps
+// with itself: the next tick is skipped while a run is still in progress.
+type Scheduler struct {
+	log    *zap.Logger
+	locker Locker
+	jobs   []entry
+	wg     sync.WaitGroup
+	cancel context.CancelFunc
+}
+
+// NewScheduler takes the locker exclusive jobs run under; with a nil locker
+// they run on every instance like other jobs.
+func NewScheduler(locker Locker, log *zap.Logger) *Scheduler {
+	return &Scheduler{locker: locker, log: log}
+}
+
+// Register adds a job run by every instance; it must be called before Start.
+func (s *Scheduler) Register(name string, every time.Duration, job Job) {
+	s.jobs = append(s.jobs, entry{name: name, every: every, job: job})
+}
+
+// RegisterExclusive adds a job that runs on one instance at a time: a tick
+// is skipped while another instance holds the job's lock.
+func (s *Scheduler) RegisterExclusive(name string, every time.Duration, job Job) {
+	s.jobs = append(s.jobs, entry{name: name, every: every, job: job, exclusive: true})
+}
+
+func (s *Scheduler) Start(ctx context.Context) {
+	ctx, s.cancel = context.WithCancel(ctx)
+	for _, e := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(ctx, e)
+	}
+	s.log.Info("job scheduler started", zap.Int("jobs", len(s.jobs)))
+}
+
+// Stop cancels running jobs and waits for them to return.
+func (s *Scheduler) Stop() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
+}
+
+func (s *Scheduler) loop(ctx context.Context, e entry) {
+	defer s.wg.Done()
+	ticker := time.NewTicker(e.every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.run(ctx, e)
+		}
+	}
+}
+
+func (s *Scheduler) run(ctx context.Context, e entry) {
+	defer func() {
+		if v := recover(); v != nil {
+			s.log.Error("job panic", zap.String("job", e.name), zap.Any("value", v))
+		}
+	}()
+	if e.exclusive && s.locker != nil {
+		unlock, ok, err := s.locker.TryLock(ctx, "jobs:"+e.name)
+		if err != nil {
+			s.log.Error("job lock failed", zap.String("job", e.name), zap.Error(err))
+			return
+		}
+		if !ok {
+			s.log.Debug("job running elsewhere", zap.String("job", e.name))
+			return
+		}
+		defer unlock()
+	}
+	start := time.Now()
+	if err := e.job(ctx); err != nil {
+		s.log.Error("job failed", zap.String("job", e.name), zap.Duration("elapsed", time.Since(start)), zap.Error(err))
+		return
+	}
+	s.log.Debug("job finished", zap.String("job", e.name), zap.Duration("elapsed", time.Since(start)))
+}
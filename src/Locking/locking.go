@@ -0,0 +1,32 @@
+package Locking
+
+import (
+	"context"
+	"hash/fnv"
+)
+
+// Locker takes named locks shared by every replica, so work that must not
+// run twice at once (sweeps, dunning, cache warmers) runs on one replica at
+// a time.
+type Locker interface {
+	// TryLock takes the lock without waiting. ok is false when another
+	// holder has it; otherwise unlock must be called once the work is done.
+	TryLock(ctx context.Context, key string) (unlock func(), ok bool, err error)
+}
+
+// Do runs fn while holding key; ran is false when another holder has it.
+func Do(ctx context.Context, l Locker, key string, fn func(ctx context.Context) error) (ran bool, err error) {
+	unlock, ok, err := l.TryLock(ctx, key)
+	if err != nil || !ok {
+		return false, err
+	}
+	defer unlock()
+	return true, fn(ctx)
+}
+
+// lockID maps a key to the 64-bit id advisory locks are taken on
+func lockID(key string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	return int64(h.Sum64())
+}
@@ -0,0 +1,44 @@
+package Locking
+
+import (
+	"context"
+	"database/sql/driver"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// PostgresLocker takes session advisory locks. Each held lock pins one
+// connection of the pool; if that connection drops, Postgres releases the
+// lock with it.
+type PostgresLocker struct {
+	db  *sqlx.DB
+	log *zap.Logger
+}
+
+func NewPostgresLocker(db *sqlx.DB, log *zap.Logger) *PostgresLocker {
+	return &PostgresLocker{db: db, log: log}
+}
+
+// TryLock implements Locker.
+func (l *PostgresLocker) TryLock(ctx context.Context, key string) (func(), bool, error) {
+	conn, err := l.db.Connx(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	id := lockID(key)
+	var ok bool
+	if err := conn.GetContext(ctx, &ok, `SELECT pg_try_advisory_lock($1)`, id); err != nil || !ok {
+		conn.Close()
+		return nil, false, err
+	}
+	unlock := func() {
+		if _, err := conn.ExecContext(context.WithoutCancel(ctx), `SELECT pg_advisory_unlock($1)`, id); err != nil {
+			l.log.Error("release advisory lock", zap.String("key", key), zap.Error(err))
+			// a pooled session would keep the lock; drop the connection instead
+			_ = conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+		}
+		conn.Close()
+	}
+	return unlock, true, nil
+}
@@ -0,0 +1,87 @@
+package Locking
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Redis runs commands on the server holding the locks
+type Redis interface {
+	Do(ctx context.Context, args ...string) (interface{}, error)
+}
+
+// renew extends the lock only while this holder still owns it
+const renew = `if redis.call('GET', KEYS[1]) == ARGV[1] then
+return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0`
+
+// release deletes the lock only while this holder still owns it
+const release = `if redis.call('GET', KEYS[1]) == ARGV[1] then
+return redis.call('DEL', KEYS[1])
+end
+return 0`
+
+// RedisLocker takes locks as keys with an expiry, renewed while held, so a
+// replica that dies keeps its lock for at most ttl. A holder that can't
+// renew in time (a long pause or a partition) may lose the lock while still
+// working; use PostgresLocker where that matters more than connection use.
+type RedisLocker struct {
+	redis Redis
+	ttl   time.Duration
+	log   *zap.Logger
+}
+
+func NewRedisLocker(redis Redis, ttl time.Duration, log *zap.Logger) *RedisLocker {
+	return &RedisLocker{redis: redis, ttl: ttl, log: log}
+}
+
+// TryLock implements Locker.
+func (l *RedisLocker) TryLock(ctx context.Context, key string) (func(), bool, error) {
+	key = "lock:" + key
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	token := hex.EncodeToString(b)
+	ttl := strconv.FormatInt(l.ttl.Milliseconds(), 10)
+	reply, err := l.redis.Do(ctx, "SET", key, token, "NX", "PX", ttl)
+	if err != nil || reply == nil {
+		return nil, false, err
+	}
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(l.ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				held, err := l.redis.Do(context.WithoutCancel(ctx), "EVAL", renew, "1", key, token, ttl)
+				if err != nil {
+					l.log.Error("renew lock", zap.String("key", key), zap.Error(err))
+				} else if held == int64(0) {
+					l.log.Error("lock lost", zap.String("key", key))
+					return
+				}
+			}
+		}
+	}()
+	unlock := func() {
+		close(stop)
+		wg.Wait()
+		if _, err := l.redis.Do(context.WithoutCancel(ctx), "EVAL", release, "1", key, token); err != nil {
+			l.log.Error("release lock", zap.String("key", key), zap.Error(err))
+		}
+	}
+	return unlock, true, nil
+}
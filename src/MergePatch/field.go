@@ -0,0 +1,65 @@
+package MergePatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+
+	"savannah/src/ErrorCodes"
+)
+
+// ContentType is the media type of JSON Merge Patch (RFC 7396) documents
+const ContentType = "application/merge-patch+json"
+
+var ErrorContentType = errors.New("content type must be " + ContentType)
+
+// Field is one member of a merge patch document. A member left out of the
+// document keeps the stored value (Set is false); a member sent as null
+// clears it (Set and Null are true); anything else replaces it with Value.
+type Field[T any] struct {
+	Set   bool
+	Null  bool
+	Value T
+}
+
+// UnmarshalJSON is only called for members present in the document,
+// including those sent as null.
+func (f *Field[T]) UnmarshalJSON(b []byte) error {
+	f.Set = true
+	if bytes.Equal(bytes.TrimSpace(b), []byte("null")) {
+		var zero T
+		f.Null, f.Value = true, zero
+		return nil
+	}
+	f.Null = false
+	return json.Unmarshal(b, &f.Value)
+}
+
+// Ptr is the new value of a nullable column: nil when the member was null
+func (f Field[T]) Ptr() *T {
+	if f.Null {
+		return nil
+	}
+	v := f.Value
+	return &v
+}
+
+// Decode reads a merge patch request body into dst. Members dst does not
+// declare are rejected, so a misspelt field is an error rather than a no-op.
+func Decode(r *http.Request, dst interface{}) error {
+	mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mt != ContentType {
+		return ErrorContentType
+	}
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	return dec.Decode(dst)
+}
+
+func init() {
+	ErrorCodes.Register("MERGE_PATCH",
+		ErrorCodes.Def{N: 1, Slug: "UNSUPPORTED_CONTENT_TYPE", Err: ErrorContentType},
+	)
+}
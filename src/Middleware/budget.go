@@ -0,0 +1,112 @@
+package Middleware
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// QueryBudget bounds how long a request may keep the database busy: once
+// budget has passed the request context is cancelled, which makes the driver
+// cancel the running statement on the server. A request that fails after
+// running out of budget gets 503 with Retry-After instead of the handler's
+// error, so a pathological filter is retried later rather than holding a
+// connection for minutes.
+//
+// Budgets nest by request class: an inner QueryBudget (say on a report
+// route) replaces the budget of an outer one (say for every read), counted
+// from the start of the request. A zero budget is unlimited.
+func QueryBudget(budget time.Duration) func(next http.Handler) http.Handler {
+	retry := strconv.Itoa(int(math.Max(1, math.Ceil(budget.Seconds()))))
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if b, ok := r.Context().Value(budgetKey{}).(*queryBudget); ok {
+				b.reset(budget, retry)
+				next.ServeHTTP(w, r)
+				return
+			}
+			ctx, cancel := context.WithCancelCause(r.Context())
+			defer cancel(nil)
+			b := &queryBudget{start: time.Now(), cancel: cancel}
+			b.reset(budget, retry)
+			defer b.stop()
+			bw := &budgetWriter{ResponseWriter: w, ctx: ctx, budget: b}
+			next.ServeHTTP(bw, r.WithContext(context.WithValue(ctx, budgetKey{}, b)))
+			if !bw.wrote && context.Cause(ctx) == ErrorQueryBudget {
+				bw.exceeded()
+			}
+		})
+	}
+}
+
+type budgetKey struct{}
+
+type queryBudget struct {
+	start  time.Time
+	cancel context.CancelCauseFunc
+	timer  *time.Timer
+	retry  string
+}
+
+// reset replaces the budget; it only runs on the request's goroutine
+func (b *queryBudget) reset(budget time.Duration, retry string) {
+	b.stop()
+	b.retry = retry
+	if budget <= 0 {
+		return
+	}
+	b.timer = time.AfterFunc(budget-time.Since(b.start), func() { b.cancel(ErrorQueryBudget) })
+}
+
+func (b *queryBudget) stop() {
+	if b.timer != nil {
+		b.timer.Stop()
+	}
+}
+
+// budgetWriter swaps a server error written after the budget ran out for 503
+type budgetWriter struct {
+	http.ResponseWriter
+	ctx     context.Context
+	budget  *queryBudget
+	wrote   bool
+	dropped bool
+}
+
+func (w *budgetWriter) WriteHeader(status int) {
+	if w.wrote {
+		return
+	}
+	w.wrote = true
+	if status >= http.StatusInternalServerError && context.Cause(w.ctx) == ErrorQueryBudget {
+		w.dropped = true
+		w.exceeded()
+		return
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *budgetWriter) Write(b []byte) (int, error) {
+	if !w.wrote {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.dropped {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *budgetWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok && !w.dropped {
+		f.Flush()
+	}
+}
+
+func (w *budgetWriter) exceeded() {
+	w.wrote = true
+	w.Header().Del("ETag")
+	w.Header().Set("Retry-After", w.budget.retry)
+	writeError(w.ResponseWriter, http.StatusServiceUnavailable, ErrorQueryBudget)
+}
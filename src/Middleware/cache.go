@@ -0,0 +1,62 @@
+package Middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Cacheable marks successful GET responses as publicly cacheable for maxAge
+// and tags them with an ETag of the body, answering 304 when the client
+// already has it. A handler that tags its own responses keeps its ETag and
+// answers conditional requests itself.
+func Cacheable(maxAge time.Duration) func(next http.Handler) http.Handler {
+	control := "public, max-age=" + strconv.Itoa(int(maxAge.Seconds()))
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+			rec := &recorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+			if rec.status == http.StatusNotModified && w.Header().Get("Cache-Control") == "" {
+				w.Header().Set("Cache-Control", control)
+			}
+			if rec.status != http.StatusOK {
+				w.WriteHeader(rec.status)
+				_, _ = w.Write(rec.body.Bytes())
+				return
+			}
+			etag := w.Header().Get("ETag")
+			if etag == "" {
+				sum := sha256.Sum256(rec.body.Bytes())
+				etag = `"` + hex.EncodeToString(sum[:16]) + `"`
+				w.Header().Set("ETag", etag)
+			}
+			if w.Header().Get("Cache-Control") == "" {
+				w.Header().Set("Cache-Control", control)
+			}
+			if r.Header.Get("If-None-Match") == etag {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write(rec.body.Bytes())
+		})
+	}
+}
+
+// recorder buffers the response so its ETag can be computed before sending
+type recorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recorder) WriteHeader(status int) { r.status = status }
+
+func (r *recorder) Write(b []byte) (int, error) { return r.body.Write(b) }
@@ -0,0 +1,91 @@
+package Middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Concurrency caps how many requests run at once: total across the instance
+// and perClient for one client IP. A client over its own cap gets 429 at once;
+// otherwise the request waits up to queue for a free slot and then gets 503,
+// so slow reports back off instead of piling up. Zero caps are unlimited and,
+// like RateLimit, the counts are per instance.
+func Concurrency(total, perClient int, queue time.Duration) func(next http.Handler) http.Handler {
+	var slots chan struct{}
+	if total > 0 {
+		slots = make(chan struct{}, total)
+	}
+	c := &clientSlots{max: perClient, running: map[string]int{}}
+	retry := strconv.Itoa(int(math.Max(1, math.Ceil(queue.Seconds()))))
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			client := clientIP(r)
+			if !c.acquire(client) {
+				w.Header().Set("Retry-After", retry)
+				writeError(w, http.StatusTooManyRequests, ErrorTooManyRunning)
+				return
+			}
+			defer c.release(client)
+			if slots != nil {
+				timer := time.NewTimer(queue)
+				select {
+				case slots <- struct{}{}:
+					timer.Stop()
+					defer func() { <-slots }()
+				case <-timer.C:
+					w.Header().Set("Retry-After", retry)
+					writeError(w, http.StatusServiceUnavailable, ErrorBusy)
+					return
+				case <-r.Context().Done():
+					timer.Stop()
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Matching applies mws, outermost first, only to the requests match selects
+func Matching(match func(r *http.Request) bool, mws ...func(next http.Handler) http.Handler) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		wrapped := next
+		for i := len(mws) - 1; i >= 0; i-- {
+			wrapped = mws[i](wrapped)
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if match(r) {
+				wrapped.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+type clientSlots struct {
+	mu      sync.Mutex
+	max     int
+	running map[string]int
+}
+
+func (c *clientSlots) acquire(client string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.max > 0 && c.running[client] >= c.max {
+		return false
+	}
+	c.running[client]++
+	return true
+}
+
+func (c *clientSlots) release(client string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.running[client]--; c.running[client] <= 0 {
+		delete(c.running, client)
+	}
+}
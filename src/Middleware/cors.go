@@ -0,0 +1,48 @@
+package Middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReadOnlyCORS lets browsers on origins call read-only endpoints directly;
+// "*" allows any origin. Only GET and HEAD are allowed cross-origin and no
+// credentials are shared. Preflight requests are answered here.
+func ReadOnlyCORS(origins []string, maxAge time.Duration) func(next http.Handler) http.Handler {
+	anyOrigin := false
+	allowed := map[string]bool{}
+	for _, o := range origins {
+		if o == "*" {
+			anyOrigin = true
+		}
+		allowed[strings.TrimRight(o, "/")] = true
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			w.Header().Add("Vary", "Origin")
+			if origin != "" && (anyOrigin || allowed[origin]) {
+				if anyOrigin {
+					w.Header().Set("Access-Control-Allow-Origin", "*")
+				} else {
+					w.Header().Set("Access-Control-Allow-Origin", origin)
+				}
+				w.Header().Set("Access-Control-Expose-Headers", "ETag, Retry-After, Link, X-Total-Count, X-Page, X-Total-Pages")
+			}
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				w.Header().Set("Access-Control-Allow-Methods", "GET, HEAD")
+				w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, If-None-Match, X-Anonymous-ID")
+				w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(maxAge.Seconds())))
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				writeError(w, http.StatusMethodNotAllowed, ErrorReadOnly)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
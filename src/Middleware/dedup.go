@@ -0,0 +1,180 @@
+package Middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	maxDedupBody    = 1 << 20 // larger requests are not deduplicated
+	maxDedupReplay  = 1 << 16 // larger responses are not kept for replay
+	maxDedupEntries = 10000
+)
+
+// Dedup collapses repeats of a POST (same client, URL and body) sent within
+// window of the first, such as a double-clicked form: the repeat waits for
+// the first to finish and gets its response with 200 and X-Deduplicated
+// instead of creating the resource again. Only 201 responses are replayed,
+// so a failed create can be retried at once. Like RateLimit the requests
+// seen are per instance.
+func Dedup(window time.Duration) func(next http.Handler) http.Handler {
+	d := &dedup{window: window, entries: map[string]*dedupEntry{}}
+	return func(next http.Handler) http.Handler {
+		if window <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				next.ServeHTTP(w, r)
+				return
+			}
+			body, err := io.ReadAll(io.LimitReader(r.Body, maxDedupBody+1))
+			if err != nil {
+				writeError(w, http.StatusBadRequest, err)
+				return
+			}
+			if len(body) > maxDedupBody {
+				r.Body = struct {
+					io.Reader
+					io.Closer
+				}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+				next.ServeHTTP(w, r)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			key := dedupKey(r, body)
+			e, first := d.enter(key)
+			if !first {
+				select {
+				case <-e.done:
+				case <-r.Context().Done():
+					return
+				}
+				if e.status == http.StatusCreated {
+					e.replay(w)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+			rec := &teeRecorder{ResponseWriter: w, status: http.StatusOK}
+			defer func() {
+				e.status, e.header, e.body = rec.status, w.Header().Clone(), rec.body.Bytes()
+				if rec.status != http.StatusCreated || rec.overflow {
+					d.forget(key, e)
+				}
+				close(e.done)
+			}()
+			next.ServeHTTP(rec, r)
+		})
+	}
+}
+
+type dedup struct {
+	window time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*dedupEntry
+	order   []string // keys, oldest first
+}
+
+type dedupEntry struct {
+	at   time.Time
+	done chan struct{}
+	// set before done is closed
+	status int
+	header http.Header
+	body   []byte
+}
+
+// enter returns the entry of a request seen within the window, or records
+// this one as the first
+func (d *dedup) enter(key string) (*dedupEntry, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := time.Now()
+	for len(d.order) > 0 {
+		old, ok := d.entries[d.order[0]]
+		if ok && now.Sub(old.at) < d.window && len(d.order) < maxDedupEntries {
+			break
+		}
+		if ok {
+			delete(d.entries, d.order[0])
+		}
+		d.order = d.order[1:]
+	}
+	if e, ok := d.entries[key]; ok {
+		return e, false
+	}
+	e := &dedupEntry{at: now, done: make(chan struct{})}
+	d.entries[key] = e
+	d.order = append(d.order, key)
+	return e, true
+}
+
+// forget drops an entry whose response is not replayed; the key stays in
+// order until it expires
+func (d *dedup) forget(key string, e *dedupEntry) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.entries[key] == e {
+		delete(d.entries, key)
+	}
+}
+
+func (e *dedupEntry) replay(w http.ResponseWriter) {
+	for _, h := range []string{"Content-Type", "Location", "ETag"} {
+		if v := e.header.Get(h); v != "" {
+			w.Header().Set(h, v)
+		}
+	}
+	w.Header().Set("X-Deduplicated", "true")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(e.body)
+}
+
+// dedupKey identifies a request by the client (its API key, else its IP),
+// URL and body
+func dedupKey(r *http.Request, body []byte) string {
+	h := sha256.New()
+	client := presentedKey(r)
+	if client == "" {
+		client = clientIP(r)
+	}
+	for _, part := range []string{client, r.URL.RequestURI()} {
+		_, _ = io.WriteString(h, part)
+		_, _ = h.Write([]byte{0})
+	}
+	_, _ = h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// teeRecorder passes the response through while keeping a copy for replay
+type teeRecorder struct {
+	http.ResponseWriter
+	status   int
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (t *teeRecorder) WriteHeader(status int) {
+	t.status = status
+	t.ResponseWriter.WriteHeader(status)
+}
+
+func (t *teeRecorder) Write(b []byte) (int, error) {
+	if !t.overflow {
+		if t.body.Len()+len(b) > maxDedupReplay {
+			t.overflow = true
+		} else {
+			t.body.Write(b)
+		}
+	}
+	return t.ResponseWriter.Write(b)
+}
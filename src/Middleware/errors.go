@@ -0,0 +1,42 @@
+package Middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"savannah/src/ErrorCodes"
+)
+
+var (
+	ErrorUnauthorized   = errors.New("missing or invalid api key")
+	ErrorRateLimited    = errors.New("rate limit exceeded")
+	ErrorTooManyRunning = errors.New("too many concurrent requests")
+	ErrorBusy           = errors.New("server busy, retry later")
+	ErrorReadOnly       = errors.New("read-only endpoint")
+	ErrorQueryBudget    = errors.New("query took too long, retry later or narrow the filter")
+	ErrorTimezone       = errors.New("unknown timezone, expected an IANA name such as Africa/Nairobi or local")
+)
+
+func init() {
+	ErrorCodes.Register("HTTP",
+		ErrorCodes.Def{N: 1, Slug: "UNAUTHORIZED", Err: ErrorUnauthorized},
+		ErrorCodes.Def{N: 2, Slug: "RATE_LIMITED", Err: ErrorRateLimited},
+		ErrorCodes.Def{N: 3, Slug: "TOO_MANY_CONCURRENT", Err: ErrorTooManyRunning},
+		ErrorCodes.Def{N: 4, Slug: "BUSY", Err: ErrorBusy},
+		ErrorCodes.Def{N: 5, Slug: "READ_ONLY", Err: ErrorReadOnly},
+		ErrorCodes.Def{N: 6, Slug: "QUERY_TIMEOUT", Err: ErrorQueryBudget},
+		ErrorCodes.Def{N: 7, Slug: "INVALID_TIMEZONE", Err: ErrorTimezone},
+	)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	code, ok := ErrorCodes.For(err)
+	if !ok {
+		code = ErrorCodes.Generic(status)
+	}
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"error": err.Error(), "code": code, "timestamp": time.Now().UTC()})
+}
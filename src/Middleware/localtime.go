@@ -0,0 +1,223 @@
+package Middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimezoneLocal asks LocalTimes for the zone of the customer the request is
+// about, else the tenant's
+const TimezoneLocal = "local"
+
+type zoneKey struct{}
+
+// LocalTimes renders the timestamps of JSON responses in the zone a client
+// asks for with the tz query parameter or the X-Timezone header: an IANA
+// name, or "local" for the zone zoneOf finds for the request (a customer's
+// own setting) falling back to tenant. Every member "x" holding an RFC 3339
+// time gains an "x_local" beside it with the same instant at the zone's
+// offset; the UTC members are left as they are, so a client that doesn't
+// ask sees no difference.
+//
+// The zone, or tenant when none is asked for, is kept in the request
+// context for handlers that cut reports into local days or weeks.
+func LocalTimes(tenant *time.Location, zoneOf func(*http.Request) *time.Location) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "X-Timezone")
+			name := r.URL.Query().Get("tz")
+			if name == "" {
+				name = r.Header.Get("X-Timezone")
+			}
+			if name == "" {
+				next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), zoneKey{}, tenant)))
+				return
+			}
+			loc := tenant
+			if strings.EqualFold(name, TimezoneLocal) {
+				if zoneOf != nil {
+					if own := zoneOf(r); own != nil {
+						loc = own
+					}
+				}
+			} else {
+				var err error
+				if loc, err = time.LoadLocation(name); err != nil {
+					writeError(w, http.StatusBadRequest, ErrorTimezone)
+					return
+				}
+			}
+			lw := &localWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(lw, r.WithContext(context.WithValue(r.Context(), zoneKey{}, loc)))
+			lw.finish(loc)
+		})
+	}
+}
+
+// Timezone returns the zone LocalTimes settled on for the request; UTC
+// outside of it
+func Timezone(ctx context.Context) *time.Location {
+	if loc, ok := ctx.Value(zoneKey{}).(*time.Location); ok && loc != nil {
+		return loc
+	}
+	return time.UTC
+}
+
+// localWriter holds back JSON responses to add their local times; anything
+// else, such as a CSV export, streams through untouched
+type localWriter struct {
+	http.ResponseWriter
+	status int
+	wrote  bool
+	held   bool
+	body   bytes.Buffer
+}
+
+func (w *localWriter) WriteHeader(status int) {
+	if w.wrote {
+		return
+	}
+	w.wrote = true
+	w.status = status
+	w.held = strings.HasPrefix(w.Header().Get("Content-Type"), "application/json")
+	if !w.held {
+		w.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (w *localWriter) Write(b []byte) (int, error) {
+	if !w.wrote {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.held {
+		return w.body.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *localWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok && !w.held {
+		f.Flush()
+	}
+}
+
+func (w *localWriter) finish(loc *time.Location) {
+	if !w.held {
+		return
+	}
+	body := w.body.Bytes()
+	if local, err := localize(body, loc); err == nil {
+		body = local
+		// the tag was computed over the UTC-only body
+		w.Header().Del("ETag")
+		w.Header().Set("X-Timezone", loc.String())
+	}
+	w.Header().Del("Content-Length")
+	w.ResponseWriter.WriteHeader(w.status)
+	_, _ = w.ResponseWriter.Write(body)
+}
+
+// localize copies a JSON document member by member, in order, adding the
+// local rendering of each timestamp after it
+func localize(doc []byte, loc *time.Location) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.UseNumber()
+	var out bytes.Buffer
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return out.Bytes(), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := localizeValue(dec, tok, &out, loc); err != nil {
+			return nil, err
+		}
+		out.WriteByte('\n')
+	}
+}
+
+func localizeValue(dec *json.Decoder, tok json.Token, out *bytes.Buffer, loc *time.Location) error {
+	switch t := tok.(type) {
+	case json.Delim:
+		if t == '{' {
+			out.WriteByte('{')
+			for n := 0; dec.More(); n++ {
+				key, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				val, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				if n > 0 {
+					out.WriteByte(',')
+				}
+				writeJSONString(out, key.(string))
+				out.WriteByte(':')
+				if err := localizeValue(dec, val, out, loc); err != nil {
+					return err
+				}
+				if s, ok := val.(string); ok {
+					if at, ok := timestamp(s); ok {
+						out.WriteByte(',')
+						writeJSONString(out, key.(string)+"_local")
+						out.WriteByte(':')
+						writeJSONString(out, at.In(loc).Format(time.RFC3339))
+					}
+				}
+			}
+			out.WriteByte('}')
+		} else {
+			out.WriteByte('[')
+			for n := 0; dec.More(); n++ {
+				val, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				if n > 0 {
+					out.WriteByte(',')
+				}
+				if err := localizeValue(dec, val, out, loc); err != nil {
+					return err
+				}
+			}
+			out.WriteByte(']')
+		}
+		// the closing delimiter
+		_, err := dec.Token()
+		return err
+	case string:
+		writeJSONString(out, t)
+	case json.Number:
+		out.WriteString(t.String())
+	case bool:
+		out.WriteString(strconv.FormatBool(t))
+	case nil:
+		out.WriteString("null")
+	}
+	return nil
+}
+
+// timestamp parses s when it is an RFC 3339 date and time; plain dates and
+// other strings are left alone
+func timestamp(s string) (time.Time, bool) {
+	if len(s) < len("2006-01-02T15:04:05Z") || s[10] != 'T' {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	return t, err == nil
+}
+
+func writeJSONString(out *bytes.Buffer, s string) {
+	b, _ := json.Marshal(s)
+	out.Write(b)
+}
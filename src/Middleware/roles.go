@@ -0,0 +1,40 @@
+package Middleware
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+)
+
+type roleKey struct{}
+
+// KeyRoles tags a request made with one of the keys in roles (key to role)
+// with that key's role, for policies that depend on who is calling, such
+// as access to order attachments and refund approval thresholds. It does
+// not authenticate; APIKeys does.
+func KeyRoles(roles map[string]string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if len(roles) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			presented := presentedKey(r)
+			role := ""
+			for key, name := range roles {
+				if presented != "" && subtle.ConstantTimeCompare([]byte(key), []byte(presented)) == 1 {
+					role = name
+				}
+			}
+			if role != "" {
+				r = r.WithContext(context.WithValue(r.Context(), roleKey{}, role))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Role returns the role KeyRoles found for the request, "" when its key has none
+func Role(ctx context.Context) string {
+	role, _ := ctx.Value(roleKey{}).(string)
+	return role
+}
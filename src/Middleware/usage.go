@@ -0,0 +1,58 @@
+package Middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Usage hands every request to record with its client, response status and
+// duration. Put it in front of APIKeys so rejected requests count too.
+func Usage(record func(clientID string, status int, elapsed time.Duration)) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			defer func() {
+				status := ww.Status()
+				if status == 0 {
+					status = http.StatusOK
+				}
+				record(ClientID(r), status, time.Since(start))
+			}()
+			next.ServeHTTP(ww, r)
+		})
+	}
+}
+
+// ClientID names the caller for usage accounting: a fingerprint of the API
+// key it presented (never the key itself), else the X-Client-ID it sent,
+// else its IP.
+func ClientID(r *http.Request) string {
+	if key := presentedKey(r); key != "" {
+		sum := sha256.Sum256([]byte(key))
+		return "key:" + hex.EncodeToString(sum[:6])
+	}
+	if id := cleanClientID(r.Header.Get("X-Client-ID")); id != "" {
+		return "client:" + id
+	}
+	return "ip:" + clientIP(r)
+}
+
+// cleanClientID keeps a self-declared client id short and printable
+func cleanClientID(id string) string {
+	id = strings.Map(func(c rune) rune {
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.' {
+			return c
+		}
+		return -1
+	}, id)
+	if len(id) > 64 {
+		id = id[:64]
+	}
+	return id
+}
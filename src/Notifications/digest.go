@@ -0,0 +1,148 @@
+package Notifications
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// DigestPolicy maps a message template to how long its messages may be held
+// back and coalesced with later ones for the same recipient. Templates not
+// listed are sent immediately.
+type DigestPolicy map[string]time.Duration
+
+// ParseDigestPolicy reads "template=duration" pairs separated by commas,
+// e.g. "order_status_changed=4h,back_in_stock=24h".
+func ParseDigestPolicy(s string) (DigestPolicy, error) {
+	p := DigestPolicy{}
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		template, window, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("notifications: invalid digest entry %q", pair)
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(window))
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("notifications: invalid digest window for %q", template)
+		}
+		p[strings.TrimSpace(template)] = d
+	}
+	return p, nil
+}
+
+// recipients flushed per FlushDigests run
+const digestBatch = 100
+
+// hold queues m instead of sending it now: for its recipient's digest when
+// its template is digested, until the rate limit's window has passed when
+// the recipient already had their share, and to the end of the quiet hours
+// when it would go out in them
+func (d *Dispatcher) hold(ctx context.Context, m Message) (bool, error) {
+	if m.Critical || d.repo == nil {
+		return false, nil
+	}
+	now := time.Now().UTC()
+	var sendAfter time.Time
+	if window, ok := d.digests[m.Template]; ok {
+		sendAfter = now.Add(window)
+	} else if limit := d.throttle.Limit; limit.Max > 0 {
+		// when the count fails the message is sent rather than lost
+		sent, err := d.repo.CountSent(ctx, m.Channel, m.To, now.Add(-limit.Per))
+		if err != nil {
+			d.log.Warn("count notifications sent", zap.String("channel", m.Channel), zap.Error(err))
+		}
+		if err == nil && sent >= limit.Max {
+			sendAfter = now.Add(limit.Per)
+		}
+	}
+	at := now
+	if !sendAfter.IsZero() {
+		at = sendAfter
+	}
+	if end, quiet := d.throttle.Quiet.ends(m.Channel, at); quiet {
+		sendAfter = end.UTC()
+	}
+	if sendAfter.IsZero() {
+		return false, nil
+	}
+	e := &DigestEntry{ID: uuid.New(), Channel: m.Channel, Recipient: m.To, Template: m.Template, Body: m.Body, OrderID: m.OrderID, SendAfter: sendAfter, CreatedAt: now}
+	if m.Subject != "" {
+		e.Subject = &m.Subject
+	}
+	return true, d.repo.QueueDigest(ctx, e)
+}
+
+// FlushDigests sends one summary message per recipient whose digest is due;
+// run periodically by the scheduler.
+func (d *Dispatcher) FlushDigests(ctx context.Context) error {
+	entries, err := d.repo.ClaimDueDigests(ctx, digestBatch)
+	if err != nil {
+		return err
+	}
+	type key struct{ channel, recipient string }
+	groups := map[key][]DigestEntry{}
+	order := []key{}
+	for _, e := range entries {
+		k := key{e.Channel, e.Recipient}
+		if _, ok := groups[k]; !ok {
+			order = append(order, k)
+		}
+		groups[k] = append(groups[k], e)
+	}
+	for _, k := range order {
+		if err := d.Send(ctx, digestMessage(k.channel, k.recipient, groups[k])); err != nil {
+			d.log.Warn("send digest", zap.String("channel", k.channel), zap.Int("updates", len(groups[k])), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// digestMessage summarises entries (oldest first) in a single critical message
+func digestMessage(channel, recipient string, entries []DigestEntry) Message {
+	m := Message{Channel: channel, To: recipient, Template: "digest", Critical: true, OrderID: entries[0].OrderID}
+	var body strings.Builder
+	if len(entries) == 1 {
+		// a single message held for quiet hours or the rate limit goes out as it was
+		m.Subject, m.Body = entries[0].Template, entries[0].Body
+		if entries[0].Subject != nil {
+			m.Subject = *entries[0].Subject
+		}
+		if channel == ChannelSMS {
+			m.Subject = ""
+		}
+		return m
+	}
+	if channel == ChannelSMS {
+		fmt.Fprintf(&body, "%d updates: ", len(entries))
+		for i, e := range entries {
+			if i > 0 {
+				body.WriteString("; ")
+			}
+			body.WriteString(e.Body)
+		}
+	} else {
+		m.Subject = fmt.Sprintf("%d updates on your orders", len(entries))
+		for _, e := range entries {
+			title := e.Template
+			if e.Subject != nil {
+				title = *e.Subject
+			}
+			fmt.Fprintf(&body, "%s - %s\n%s\n\n", e.CreatedAt.Format("Jan 2 15:04"), title, e.Body)
+		}
+	}
+	m.Body = body.String()
+	for _, e := range entries[1:] {
+		if e.OrderID == nil || m.OrderID == nil || *e.OrderID != *m.OrderID {
+			m.OrderID = nil // only link the digest to a timeline when it covers one order
+			break
+		}
+	}
+	return m
+}
@@ -0,0 +1,163 @@
+package Notifications
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"savannah/src/ErrorCodes"
+)
+
+var (
+	ErrorNoSender         = errors.New("no sender configured for channel")
+	ErrorLogEntryNotFound = errors.New("notification not found")
+	ErrorTemplateNotFound = errors.New("notification template not found")
+	ErrorInvalidTemplate  = errors.New("invalid notification template")
+	ErrorChannelDegraded  = errors.New("the channel's gateway is failing its health checks")
+)
+
+func init() {
+	ErrorCodes.Register("NOTIFICATIONS",
+		ErrorCodes.Def{N: 1, Slug: "NO_SENDER", Err: ErrorNoSender},
+		ErrorCodes.Def{N: 2, Slug: "NOT_FOUND", Err: ErrorLogEntryNotFound},
+		ErrorCodes.Def{N: 3, Slug: "TEMPLATE_NOT_FOUND", Err: ErrorTemplateNotFound},
+		ErrorCodes.Def{N: 4, Slug: "INVALID_TEMPLATE", Err: ErrorInvalidTemplate},
+		ErrorCodes.Def{N: 5, Slug: "CHANNEL_DEGRADED", Err: ErrorChannelDegraded},
+	)
+}
+
+// Health reports which dependencies fail their checks (see Server.Health);
+// a channel's gateway is checked under the channel's name.
+type Health interface {
+	Degraded(name string) bool
+}
+
+// Dispatcher is the single entry point other modules use to notify
+// customers; it routes each message to the sender for its channel.
+// Every attempt is written to the notification log. Non-critical messages
+// whose template has a digest window are queued and sent as a summary, and
+// so are those the throttle holds back.
+// While a channel's gateway is degraded its messages are logged as failed
+// without waiting on the gateway, and can be resent later.
+type Dispatcher struct {
+	senders  map[string]Sender
+	repo     Repository
+	digests  DigestPolicy
+	throttle Throttle
+	health   Health
+	log      *zap.Logger
+}
+
+// NewDispatcher takes a nil health when gateways are not checked
+func NewDispatcher(senders map[string]Sender, repo Repository, digests DigestPolicy, throttle Throttle, health Health, log *zap.Logger) *Dispatcher {
+	return &Dispatcher{senders: senders, repo: repo, digests: digests, throttle: throttle, health: health, log: log}
+}
+
+func (d *Dispatcher) Send(ctx context.Context, m Message) error {
+	_, err := d.send(ctx, m, nil)
+	return err
+}
+
+func (d *Dispatcher) send(ctx context.Context, m Message, resentFrom *uuid.UUID) (*LogEntry, error) {
+	sender, ok := d.senders[m.Channel]
+	if !ok {
+		return nil, ErrorNoSender
+	}
+	if resentFrom == nil {
+		d.applyTemplate(ctx, &m)
+	}
+	if held, err := d.hold(ctx, m); held {
+		if err != nil {
+			d.log.Error("hold notification", zap.String("template", m.Template), zap.Error(err))
+			return nil, err
+		}
+		return d.record(ctx, m, StatusQueued, "", nil, resentFrom), nil
+	}
+	if d.health != nil && d.health.Degraded(m.Channel) {
+		d.log.Warn("notification channel degraded", zap.String("channel", m.Channel), zap.String("template", m.Template))
+		return d.record(ctx, m, StatusFailed, "", ErrorChannelDegraded, resentFrom), ErrorChannelDegraded
+	}
+	ref, err := sender.Send(ctx, m)
+	status := StatusSent
+	if err != nil {
+		status = StatusFailed
+	}
+	e := d.record(ctx, m, status, ref, err, resentFrom)
+	if err != nil {
+		d.log.Error("send notification", zap.String("channel", m.Channel), zap.String("template", m.Template), zap.Error(err))
+		return e, err
+	}
+	return e, nil
+}
+
+// ListForOrder returns every notification attempt linked to the order, oldest first
+func (d *Dispatcher) ListForOrder(ctx context.Context, orderID uuid.UUID) ([]LogEntry, error) {
+	return d.repo.ListByOrder(ctx, orderID)
+}
+
+// Resend sends a logged notification again, right away and to the same
+// recipient, and returns the new attempt. Attachments are not kept in the log
+// and are not resent.
+func (d *Dispatcher) Resend(ctx context.Context, id uuid.UUID) (*LogEntry, error) {
+	prev, err := d.repo.GetLogEntry(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	m := Message{Channel: prev.Channel, To: prev.Recipient, OrderID: prev.OrderID, Critical: true}
+	if prev.Template != nil {
+		m.Template = *prev.Template
+	}
+	if prev.Subject != nil {
+		m.Subject = *prev.Subject
+	}
+	if prev.Body != nil {
+		m.Body = *prev.Body
+	}
+	if len(prev.Data) > 0 {
+		if err := json.Unmarshal(prev.Data, &m.Data); err != nil {
+			return nil, err
+		}
+	}
+	e, err := d.send(ctx, m, &prev.ID)
+	if e != nil {
+		// the failed attempt is logged; the caller reads the outcome from it
+		return e, nil
+	}
+	return nil, err
+}
+
+// record logs one attempt; it returns the entry even when it could not be stored
+func (d *Dispatcher) record(ctx context.Context, m Message, status, ref string, sendErr error, resentFrom *uuid.UUID) *LogEntry {
+	e := &LogEntry{ID: uuid.New(), OrderID: m.OrderID, Channel: m.Channel, Recipient: m.To, Status: status, ResentFrom: resentFrom, CreatedAt: time.Now().UTC()}
+	if m.Template != "" {
+		e.Template = &m.Template
+	}
+	if m.Subject != "" {
+		e.Subject = &m.Subject
+	}
+	if m.Body != "" {
+		e.Body = &m.Body
+	}
+	if len(m.Data) > 0 {
+		if data, err := json.Marshal(m.Data); err == nil {
+			e.Data = data
+		}
+	}
+	if ref != "" {
+		e.ProviderReference = &ref
+	}
+	if sendErr != nil {
+		msg := sendErr.Error()
+		e.Error = &msg
+	}
+	if d.repo == nil {
+		return e
+	}
+	if err := d.repo.Record(ctx, e); err != nil {
+		d.log.Error("record notification", zap.String("channel", m.Channel), zap.Error(err))
+	}
+	return e
+}
@@ -0,0 +1,27 @@
+package Notifications
+
+import "github.com/google/uuid"
+
+type CreateTemplateRequest struct {
+	Name    string `json:"name" validate:"required,max=100"`
+	Tenant  string `json:"tenant,omitempty" validate:"max=50"`
+	Locale  string `json:"locale,omitempty" validate:"max=10"`
+	Subject string `json:"subject" validate:"max=500"`
+	Body    string `json:"body" validate:"required,max=20000"`
+}
+
+// PreviewRequest renders a stored template version (TemplateID) or a draft
+// (Name, Subject, Body). Without Data the template's sample data is used.
+type PreviewRequest struct {
+	TemplateID *uuid.UUID             `json:"template_id,omitempty"`
+	Name       string                 `json:"name,omitempty" validate:"max=100"`
+	Subject    string                 `json:"subject,omitempty" validate:"max=500"`
+	Body       string                 `json:"body,omitempty" validate:"max=20000"`
+	Data       map[string]interface{} `json:"data,omitempty"`
+}
+
+type PreviewResponse struct {
+	Subject string                 `json:"subject"`
+	Body    string                 `json:"body"`
+	Data    map[string]interface{} `json:"data"`
+}
@@ -0,0 +1,218 @@
+package Notifications
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"savannah/src/ErrorCodes"
+)
+
+type Handler struct {
+	d   *Dispatcher
+	log *zap.Logger
+	v   *validator.Validate
+}
+
+func NewHandler(d *Dispatcher, log *zap.Logger) *Handler {
+	return &Handler{d: d, log: log, v: validator.New()}
+}
+
+// ListForOrder godoc
+// @Summary      Order notifications
+// @Description  Every notification attempt for the order with channel, template, recipient and provider response
+// @Tags         notifications
+// @Produce      json
+// @Param        id   path      string  true  "Order ID"
+// @Success      200  {array}   LogEntry
+// @Router       /orders/{id}/notifications [get]
+func (h *Handler) ListForOrder(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	entries, err := h.d.ListForOrder(r.Context(), id)
+	if err != nil {
+		h.log.Error("list order notifications", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to list notifications")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, entries)
+}
+
+// Resend godoc
+// @Summary      Resend a notification
+// @Description  Sends the logged message again to the same recipient, bypassing digests. The new attempt is returned; check its status.
+// @Tags         notifications
+// @Produce      json
+// @Param        id   path      string  true  "Notification ID"
+// @Success      201  {object}  LogEntry
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /notifications/{id}/resend [post]
+func (h *Handler) Resend(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	e, err := h.d.Resend(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrorLogEntryNotFound):
+			h.writeError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, ErrorNoSender):
+			h.writeError(w, http.StatusUnprocessableEntity, err.Error())
+		default:
+			h.log.Error("resend notification", zap.Error(err))
+			h.writeError(w, http.StatusInternalServerError, "failed to resend notification")
+		}
+		return
+	}
+	h.writeJSON(w, http.StatusCreated, e)
+}
+
+// ListTemplates godoc
+// @Summary      List notification template versions
+// @Tags         notifications
+// @Produce      json
+// @Param        name  query     string  false  "Template name"
+// @Success      200   {array}   Template
+// @Router       /notification-templates [get]
+func (h *Handler) ListTemplates(w http.ResponseWriter, r *http.Request) {
+	list, err := h.d.ListTemplates(r.Context(), r.URL.Query().Get("name"))
+	if err != nil {
+		h.log.Error("list notification templates", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to list templates")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, list)
+}
+
+// CreateTemplate godoc
+// @Summary      Publish a new template version
+// @Description  Subject and body are Go text/templates over the message data, e.g. "Order {{.reference}} has shipped". The new version becomes active for its tenant and locale.
+// @Tags         notifications
+// @Accept       json
+// @Produce      json
+// @Param        template  body      CreateTemplateRequest  true  "Template"
+// @Success      201       {object}  Template
+// @Failure      400       {object}  map[string]interface{}
+// @Router       /notification-templates [post]
+func (h *Handler) CreateTemplate(w http.ResponseWriter, r *http.Request) {
+	var dto CreateTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if err := h.v.Struct(dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	t, err := h.d.CreateTemplate(r.Context(), dto)
+	if err != nil {
+		h.templateError(w, err, "create notification template")
+		return
+	}
+	h.writeJSON(w, http.StatusCreated, t)
+}
+
+// GetTemplate godoc
+// @Summary      Get a template version
+// @Tags         notifications
+// @Produce      json
+// @Param        id   path      string  true  "Template version ID"
+// @Success      200  {object}  Template
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /notification-templates/{id} [get]
+func (h *Handler) GetTemplate(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	t, err := h.d.GetTemplate(r.Context(), id)
+	if err != nil {
+		h.templateError(w, err, "get notification template")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, t)
+}
+
+// ActivateTemplate godoc
+// @Summary      Activate a template version
+// @Description  Makes this version the one in use for its tenant and locale, e.g. to roll back
+// @Tags         notifications
+// @Produce      json
+// @Param        id   path      string  true  "Template version ID"
+// @Success      200  {object}  Template
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /notification-templates/{id}/activate [post]
+func (h *Handler) ActivateTemplate(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	t, err := h.d.ActivateTemplate(r.Context(), id)
+	if err != nil {
+		h.templateError(w, err, "activate notification template")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, t)
+}
+
+// PreviewTemplate godoc
+// @Summary      Render a template with sample data
+// @Description  Renders a stored version (template_id) or a draft (name, subject, body) with the given data, or the template's sample data when none is given
+// @Tags         notifications
+// @Accept       json
+// @Produce      json
+// @Param        preview  body      PreviewRequest  true  "Template and data"
+// @Success      200      {object}  PreviewResponse
+// @Failure      400      {object}  map[string]interface{}
+// @Router       /notification-templates/preview [post]
+func (h *Handler) PreviewTemplate(w http.ResponseWriter, r *http.Request) {
+	var dto PreviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if err := h.v.Struct(dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	out, err := h.d.Preview(r.Context(), dto)
+	if err != nil {
+		h.templateError(w, err, "preview notification template")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, out)
+}
+
+func (h *Handler) templateError(w http.ResponseWriter, err error, action string) {
+	switch {
+	case errors.Is(err, ErrorTemplateNotFound):
+		h.writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrorInvalidTemplate):
+		h.writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		h.log.Error(action, zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to "+action)
+	}
+}
+
+func (h *Handler) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (h *Handler) writeError(w http.ResponseWriter, status int, msg string) {
+	h.writeJSON(w, status, map[string]interface{}{"error": msg, "code": ErrorCodes.Resolve("NOTIFICATIONS", status, msg), "timestamp": time.Now().UTC()})
+}
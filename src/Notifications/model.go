@@ -0,0 +1,94 @@
+package Notifications
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx/types"
+)
+
+// channels
+const (
+	ChannelEmail = "email"
+	ChannelSMS   = "sms"
+)
+
+// delivery statuses
+const (
+	StatusSent   = "SENT"
+	StatusFailed = "FAILED"
+	StatusQueued = "QUEUED" // held for a digest
+)
+
+// Message is a single notification addressed to one recipient
+type Message struct {
+	Channel  string                 `json:"channel"`
+	To       string                 `json:"to"`
+	Subject  string                 `json:"subject,omitempty"`
+	Body     string                 `json:"body"`
+	Template string                 `json:"template,omitempty"`
+	Data     map[string]interface{} `json:"data,omitempty"`
+	OrderID  *uuid.UUID             `json:"order_id,omitempty"` // links the message to an order's timeline
+	Critical bool                   `json:"critical,omitempty"` // never held back for a digest
+	Tenant   string                 `json:"tenant,omitempty"`   // selects template overrides
+	Locale   string                 `json:"locale,omitempty"`   // e.g. "sw" or "en-KE"
+
+	Attachments []Attachment `json:"-"` // email only
+}
+
+// Attachment is a file sent along with an email
+type Attachment struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// LogEntry records one delivery attempt
+type LogEntry struct {
+	ID                uuid.UUID      `db:"id" json:"id"`
+	OrderID           *uuid.UUID     `db:"order_id" json:"order_id,omitempty"`
+	Channel           string         `db:"channel" json:"channel"`
+	Recipient         string         `db:"recipient" json:"recipient"`
+	Template          *string        `db:"template" json:"template,omitempty"`
+	Subject           *string        `db:"subject" json:"subject,omitempty"`
+	Status            string         `db:"status" json:"status"`
+	ProviderReference *string        `db:"provider_reference" json:"provider_reference,omitempty"`
+	Error             *string        `db:"error" json:"error,omitempty"`
+	Body              *string        `db:"body" json:"body,omitempty"`
+	Data              types.JSONText `db:"data" json:"data,omitempty"`
+	ResentFrom        *uuid.UUID     `db:"resent_from" json:"resent_from,omitempty"`
+	CreatedAt         time.Time      `db:"created_at" json:"created_at"`
+}
+
+const LogTable = "notification_log"
+
+// DigestEntry is a message waiting to go out in its recipient's next digest
+type DigestEntry struct {
+	ID        uuid.UUID  `db:"id" json:"id"`
+	Channel   string     `db:"channel" json:"channel"`
+	Recipient string     `db:"recipient" json:"recipient"`
+	Template  string     `db:"template" json:"template"`
+	Subject   *string    `db:"subject" json:"subject,omitempty"`
+	Body      string     `db:"body" json:"body"`
+	OrderID   *uuid.UUID `db:"order_id" json:"order_id,omitempty"`
+	SendAfter time.Time  `db:"send_after" json:"send_after"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+}
+
+const DigestTable = "notification_digest_queue"
+
+// Template is one version of a template's copy for a tenant and locale (empty
+// means any). Subject and Body are Go text/templates over the message Data.
+type Template struct {
+	ID        uuid.UUID `db:"id" json:"id"`
+	Name      string    `db:"name" json:"name"`
+	Tenant    string    `db:"tenant" json:"tenant"`
+	Locale    string    `db:"locale" json:"locale"`
+	Version   int       `db:"version" json:"version"`
+	Subject   string    `db:"subject" json:"subject"`
+	Body      string    `db:"body" json:"body"`
+	Active    bool      `db:"active" json:"active"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+const TemplateTable = "notification_templates"
@@ -0,0 +1,181 @@
+package Notifications
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+type Repository interface {
+	Record(ctx context.Context, e *LogEntry) error
+	GetLogEntry(ctx context.Context, id uuid.UUID) (*LogEntry, error)
+	ListByOrder(ctx context.Context, orderID uuid.UUID) ([]LogEntry, error)
+
+	CreateTemplateVersion(ctx context.Context, t *Template) error
+	ActivateTemplate(ctx context.Context, id uuid.UUID) (*Template, error)
+	GetTemplate(ctx context.Context, id uuid.UUID) (*Template, error)
+	ListTemplates(ctx context.Context, name string) ([]Template, error)
+	ActiveTemplates(ctx context.Context, name, tenant string, locales []string) ([]Template, error)
+	QueueDigest(ctx context.Context, e *DigestEntry) error
+	ClaimDueDigests(ctx context.Context, recipients int) ([]DigestEntry, error)
+	CountSent(ctx context.Context, channel, recipient string, since time.Time) (int, error)
+}
+
+type repository struct {
+	db  *sqlx.DB
+	log *zap.Logger
+}
+
+func NewRepository(db *sqlx.DB, log *zap.Logger) Repository { return &repository{db: db, log: log} }
+
+func (r *repository) Record(ctx context.Context, e *LogEntry) error {
+	query := fmt.Sprintf(`INSERT INTO %s (%s)
+		VALUES (:id,:order_id,:channel,:recipient,:template,:subject,:status,:provider_reference,:error,:body,:data,:resent_from,:created_at)`, LogTable, logColumns)
+	_, err := r.db.NamedExecContext(ctx, query, e)
+	return err
+}
+
+const logColumns = `id,order_id,channel,recipient,template,subject,status,provider_reference,error,body,data,resent_from,created_at`
+
+func (r *repository) GetLogEntry(ctx context.Context, id uuid.UUID) (*LogEntry, error) {
+	var e LogEntry
+	err := r.db.GetContext(ctx, &e, fmt.Sprintf(`SELECT %s FROM %s WHERE id=$1`, logColumns, LogTable), id)
+	if err == sql.ErrNoRows {
+		return nil, ErrorLogEntryNotFound
+	}
+	return &e, err
+}
+
+func (r *repository) ListByOrder(ctx context.Context, orderID uuid.UUID) ([]LogEntry, error) {
+	out := []LogEntry{}
+	err := r.db.SelectContext(ctx, &out, fmt.Sprintf(`SELECT %s FROM %s WHERE order_id=$1 ORDER BY created_at`, logColumns, LogTable), orderID)
+	return out, err
+}
+
+const digestColumns = `id,channel,recipient,template,subject,body,order_id,send_after,created_at`
+
+// QueueDigest adds the entry to its recipient's pending digest. A digest that
+// is already pending keeps its send time, so updates coalesce into it.
+func (r *repository) QueueDigest(ctx context.Context, e *DigestEntry) error {
+	query := fmt.Sprintf(`INSERT INTO %[1]s (%[2]s)
+		SELECT $1,$2,$3,$4,$5,$6,$7,COALESCE((SELECT MIN(send_after) FROM %[1]s WHERE channel=$2 AND recipient=$3), $8),$9`, DigestTable, digestColumns)
+	_, err := r.db.ExecContext(ctx, query, e.ID, e.Channel, e.Recipient, e.Template, e.Subject, e.Body, e.OrderID, e.SendAfter, e.CreatedAt)
+	return err
+}
+
+// ClaimDueDigests removes and returns every pending entry of up to the given
+// number of recipients whose digest is due, oldest first.
+func (r *repository) ClaimDueDigests(ctx context.Context, recipients int) ([]DigestEntry, error) {
+	query := fmt.Sprintf(`DELETE FROM %[1]s WHERE (channel, recipient) IN (
+		SELECT channel, recipient FROM %[1]s GROUP BY channel, recipient HAVING MIN(send_after) <= $1 LIMIT $2
+	) RETURNING %[2]s`, DigestTable, digestColumns)
+	out := []DigestEntry{}
+	if err := r.db.SelectContext(ctx, &out, query, time.Now().UTC(), recipients); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CountSent counts the messages sent to recipient on channel since then
+func (r *repository) CountSent(ctx context.Context, channel, recipient string, since time.Time) (int, error) {
+	var n int
+	err := r.db.GetContext(ctx, &n, fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE channel=$1 AND recipient=$2 AND status=$3 AND created_at >= $4`, LogTable), channel, recipient, StatusSent, since)
+	return n, err
+}
+
+const templateColumns = `id,name,tenant,locale,version,subject,body,active,created_at`
+
+// CreateTemplateVersion stores t as the next version of its (name, tenant,
+// locale) and makes it the active one.
+func (r *repository) CreateTemplateVersion(ctx context.Context, t *Template) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	// serialise version numbering per template key
+	if _, err = tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1 || '/' || $2 || '/' || $3))`, t.Name, t.Tenant, t.Locale); err != nil {
+		return err
+	}
+	if err = tx.GetContext(ctx, &t.Version, fmt.Sprintf(`SELECT COALESCE(MAX(version), 0) + 1 FROM %s WHERE name=$1 AND tenant=$2 AND locale=$3`, TemplateTable), t.Name, t.Tenant, t.Locale); err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET active=FALSE WHERE name=$1 AND tenant=$2 AND locale=$3 AND active`, TemplateTable), t.Name, t.Tenant, t.Locale); err != nil {
+		return err
+	}
+	t.Active = true
+	if _, err = tx.ExecContext(ctx, fmt.Sprintf(`INSERT INTO %s (%s) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)`, TemplateTable, templateColumns),
+		t.ID, t.Name, t.Tenant, t.Locale, t.Version, t.Subject, t.Body, t.Active, t.CreatedAt); err != nil {
+		return err
+	}
+	err = tx.Commit()
+	return err
+}
+
+// ActivateTemplate makes the version the active one of its key, e.g. to roll back
+func (r *repository) ActivateTemplate(ctx context.Context, id uuid.UUID) (*Template, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	var t Template
+	if err = tx.GetContext(ctx, &t, fmt.Sprintf(`SELECT %s FROM %s WHERE id=$1 FOR UPDATE`, templateColumns, TemplateTable), id); err != nil {
+		if err == sql.ErrNoRows {
+			err = ErrorTemplateNotFound
+		}
+		return nil, err
+	}
+	if _, err = tx.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET active=FALSE WHERE name=$1 AND tenant=$2 AND locale=$3 AND active`, TemplateTable), t.Name, t.Tenant, t.Locale); err != nil {
+		return nil, err
+	}
+	if _, err = tx.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET active=TRUE WHERE id=$1`, TemplateTable), id); err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	t.Active = true
+	return &t, nil
+}
+
+func (r *repository) GetTemplate(ctx context.Context, id uuid.UUID) (*Template, error) {
+	var t Template
+	err := r.db.GetContext(ctx, &t, fmt.Sprintf(`SELECT %s FROM %s WHERE id=$1`, templateColumns, TemplateTable), id)
+	if err == sql.ErrNoRows {
+		return nil, ErrorTemplateNotFound
+	}
+	return &t, err
+}
+
+func (r *repository) ListTemplates(ctx context.Context, name string) ([]Template, error) {
+	out := []Template{}
+	err := r.db.SelectContext(ctx, &out, fmt.Sprintf(`SELECT %s FROM %s WHERE ($1 = '' OR name = $1) ORDER BY name, tenant, locale, version DESC`, templateColumns, TemplateTable), name)
+	return out, err
+}
+
+// ActiveTemplates returns the active versions of name for the tenant (or any
+// tenant) in any of the locales.
+func (r *repository) ActiveTemplates(ctx context.Context, name, tenant string, locales []string) ([]Template, error) {
+	query, args, err := sqlx.In(fmt.Sprintf(`SELECT %s FROM %s WHERE name=? AND active AND tenant IN ('', ?) AND locale IN (?)`, templateColumns, TemplateTable), name, tenant, locales)
+	if err != nil {
+		return nil, err
+	}
+	out := []Template{}
+	err = r.db.SelectContext(ctx, &out, r.db.Rebind(query), args...)
+	return out, err
+}
@@ -0,0 +1,106 @@
+package Notifications
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// Sender delivers messages over one channel and returns the provider's
+// message id (or response) when there is one.
+type Sender interface {
+	Send(ctx context.Context, m Message) (string, error)
+}
+
+// SMTPSender sends plain-text email through an SMTP relay.
+type SMTPSender struct {
+	Addr     string
+	From     string
+	Username string
+	Password string
+}
+
+func NewSMTPSender(addr, from, username, password string) *SMTPSender {
+	return &SMTPSender{Addr: addr, From: from, Username: username, Password: password}
+}
+
+func (s *SMTPSender) Send(ctx context.Context, m Message) (string, error) {
+	var auth smtp.Auth
+	if s.Username != "" {
+		host := s.Addr
+		if i := strings.LastIndex(host, ":"); i >= 0 {
+			host = host[:i]
+		}
+		auth = smtp.PlainAuth("", s.Username, s.Password, host)
+	}
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s", s.From, m.To, m.Subject, m.Body)
+	if len(m.Attachments) > 0 {
+		var err error
+		if msg, err = s.multipart(m); err != nil {
+			return "", err
+		}
+	}
+	if err := smtp.SendMail(s.Addr, auth, s.From, []string{m.To}, []byte(msg)); err != nil {
+		return "", err
+	}
+	return "smtp:accepted", nil
+}
+
+// multipart builds a multipart/mixed message with the body and base64 encoded attachments
+func (s *SMTPSender) multipart(m Message) (string, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"text/plain; charset=UTF-8"}})
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.WriteString(part, m.Body); err != nil {
+		return "", err
+	}
+	for _, a := range m.Attachments {
+		part, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {a.ContentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": a.Name})},
+		})
+		if err != nil {
+			return "", err
+		}
+		enc := base64.StdEncoding.EncodeToString(a.Data)
+		for len(enc) > 76 {
+			if _, err := io.WriteString(part, enc[:76]+"\r\n"); err != nil {
+				return "", err
+			}
+			enc = enc[76:]
+		}
+		if _, err := io.WriteString(part, enc); err != nil {
+			return "", err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return "", err
+	}
+	header := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\nContent-Type: multipart/mixed; boundary=%q\r\n\r\n", s.From, m.To, m.Subject, mw.Boundary())
+	return header + body.String(), nil
+}
+
+// LogSender only logs messages; used when no provider is configured.
+type LogSender struct {
+	log *zap.Logger
+}
+
+func NewLogSender(log *zap.Logger) *LogSender { return &LogSender{log: log} }
+
+func (s *LogSender) Send(ctx context.Context, m Message) (string, error) {
+	s.log.Info("notification", zap.String("channel", m.Channel), zap.String("to", m.To), zap.String("subject", m.Subject))
+	return "log", nil
+}
@@ -0,0 +1,149 @@
+package Notifications
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// SampleData is the preview data for the templates the application sends
+var SampleData = map[string]map[string]interface{}{
+	"order_status_changed": {"order_id": "2f1c6a0e-6b1d-4a53-9a57-0c1f3e5d9b21", "reference": "2F1C6A0E", "status": "SHIPPED"},
+	"order_downloads":      {"order_id": "2f1c6a0e-6b1d-4a53-9a57-0c1f3e5d9b21"},
+	"scheduled_export":     {"name": "Open orders", "resource": "orders"},
+}
+
+// locales to try for a message, most specific first: "en-KE", "en", ""
+func candidateLocales(locale string) []string {
+	out := []string{}
+	if locale != "" {
+		out = append(out, locale)
+		if lang, _, ok := strings.Cut(locale, "-"); ok {
+			out = append(out, lang)
+		}
+	}
+	return append(out, "")
+}
+
+// pick returns the active template matching tenant and the most specific
+// locale; a tenant match beats a locale match.
+func pick(candidates []Template, tenant string, locales []string) *Template {
+	var best *Template
+	bestScore := -1
+	for i := range candidates {
+		t := &candidates[i]
+		score := 0
+		if tenant != "" && t.Tenant == tenant {
+			score += len(locales) + 1
+		}
+		for rank, l := range locales {
+			if t.Locale == l {
+				score += len(locales) - rank
+				break
+			}
+		}
+		if score > bestScore {
+			best, bestScore = t, score
+		}
+	}
+	return best
+}
+
+// applyTemplate replaces the message's subject and body with the active DB
+// template, if there is one. On any error the built-in text is kept.
+func (d *Dispatcher) applyTemplate(ctx context.Context, m *Message) {
+	if d.repo == nil || m.Template == "" {
+		return
+	}
+	locales := candidateLocales(m.Locale)
+	candidates, err := d.repo.ActiveTemplates(ctx, m.Template, m.Tenant, locales)
+	if err != nil {
+		d.log.Error("load notification template", zap.String("template", m.Template), zap.Error(err))
+		return
+	}
+	t := pick(candidates, m.Tenant, locales)
+	if t == nil {
+		return
+	}
+	subject, body, err := render(t.Subject, t.Body, m.Data)
+	if err != nil {
+		d.log.Error("render notification template", zap.String("template", m.Template), zap.Int("version", t.Version), zap.Error(err))
+		return
+	}
+	if subject != "" {
+		m.Subject = subject
+	}
+	m.Body = body
+}
+
+func render(subject, body string, data map[string]interface{}) (string, string, error) {
+	s, err := execute("subject", subject, data)
+	if err != nil {
+		return "", "", err
+	}
+	b, err := execute("body", body, data)
+	return s, b, err
+}
+
+func execute(name, text string, data map[string]interface{}) (string, error) {
+	t, err := template.New(name).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// CreateTemplate validates the copy and stores it as the new active version
+func (d *Dispatcher) CreateTemplate(ctx context.Context, req CreateTemplateRequest) (*Template, error) {
+	if _, _, err := render(req.Subject, req.Body, SampleData[req.Name]); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrorInvalidTemplate, err)
+	}
+	t := &Template{ID: uuid.New(), Name: req.Name, Tenant: req.Tenant, Locale: req.Locale, Subject: req.Subject, Body: req.Body, CreatedAt: time.Now().UTC()}
+	if err := d.repo.CreateTemplateVersion(ctx, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (d *Dispatcher) ActivateTemplate(ctx context.Context, id uuid.UUID) (*Template, error) {
+	return d.repo.ActivateTemplate(ctx, id)
+}
+
+func (d *Dispatcher) GetTemplate(ctx context.Context, id uuid.UUID) (*Template, error) {
+	return d.repo.GetTemplate(ctx, id)
+}
+
+func (d *Dispatcher) ListTemplates(ctx context.Context, name string) ([]Template, error) {
+	return d.repo.ListTemplates(ctx, name)
+}
+
+// Preview renders a stored version or a draft with the given or sample data
+func (d *Dispatcher) Preview(ctx context.Context, req PreviewRequest) (*PreviewResponse, error) {
+	name, subject, body := req.Name, req.Subject, req.Body
+	if req.TemplateID != nil {
+		t, err := d.repo.GetTemplate(ctx, *req.TemplateID)
+		if err != nil {
+			return nil, err
+		}
+		name, subject, body = t.Name, t.Subject, t.Body
+	}
+	data := req.Data
+	if data == nil {
+		data = SampleData[name]
+	}
+	s, b, err := render(subject, body, data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrorInvalidTemplate, err)
+	}
+	return &PreviewResponse{Subject: s, Body: b, Data: data}, nil
+}
@@ -0,0 +1,106 @@
+package Notifications
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Throttle spares customers a flood of messages: at most Limit messages per
+// recipient and channel, and none on the QuietHours channels at night.
+// Throttled messages are not dropped; they are queued like a digest and go
+// out as one summary once the limit's window has passed or the quiet hours
+// end. Critical messages are never throttled.
+type Throttle struct {
+	Limit RateLimit
+	Quiet QuietHours
+}
+
+// RateLimit allows Max messages in any Per; a zero Max is unlimited
+type RateLimit struct {
+	Max int
+	Per time.Duration
+}
+
+// ParseRateLimit reads "count/duration", e.g. "5/1h"; empty is unlimited
+func ParseRateLimit(s string) (RateLimit, error) {
+	if s = strings.TrimSpace(s); s == "" {
+		return RateLimit{}, nil
+	}
+	count, window, ok := strings.Cut(s, "/")
+	max, err := strconv.Atoi(strings.TrimSpace(count))
+	if !ok || err != nil || max < 1 {
+		return RateLimit{}, fmt.Errorf("notifications: invalid rate limit %q", s)
+	}
+	per, err := time.ParseDuration(strings.TrimSpace(window))
+	if err != nil || per <= 0 {
+		return RateLimit{}, fmt.Errorf("notifications: invalid rate limit window %q", s)
+	}
+	return RateLimit{Max: max, Per: per}, nil
+}
+
+// QuietHours is a daily window of local time in Location, from Start to End
+// after midnight, when messages on Channels wait. A Start after End spans
+// midnight, as in 21:00 to 07:00.
+type QuietHours struct {
+	Start, End time.Duration
+	Location   *time.Location
+	Channels   []string
+}
+
+// ParseQuietHours reads "HH:MM-HH:MM" in the IANA zone tz for channels;
+// an empty window has no quiet hours
+func ParseQuietHours(window, tz string, channels []string) (QuietHours, error) {
+	if window = strings.TrimSpace(window); window == "" {
+		return QuietHours{}, nil
+	}
+	from, to, ok := strings.Cut(window, "-")
+	start, err1 := clock(from)
+	end, err2 := clock(to)
+	if !ok || err1 != nil || err2 != nil || start == end {
+		return QuietHours{}, fmt.Errorf("notifications: invalid quiet hours %q", window)
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return QuietHours{}, fmt.Errorf("notifications: quiet hours time zone: %w", err)
+	}
+	return QuietHours{Start: start, End: end, Location: loc, Channels: channels}, nil
+}
+
+func clock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// ends returns when the quiet hours around t end for channel, and false
+// when t is outside them
+func (q QuietHours) ends(channel string, t time.Time) (time.Time, bool) {
+	if q.Location == nil || !q.covers(channel) {
+		return time.Time{}, false
+	}
+	local := t.In(q.Location)
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, q.Location)
+	since := local.Sub(midnight)
+	switch {
+	case q.Start < q.End && since >= q.Start && since < q.End:
+		return midnight.Add(q.End), true
+	case q.Start > q.End && since >= q.Start:
+		return time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, q.Location).Add(q.End), true
+	case q.Start > q.End && since < q.End:
+		return midnight.Add(q.End), true
+	}
+	return time.Time{}, false
+}
+
+func (q QuietHours) covers(channel string) bool {
+	for _, c := range q.Channels {
+		if c == channel {
+			return true
+		}
+	}
+	return false
+}
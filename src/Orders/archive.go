@@ -0,0 +1,37 @@
+package Orders
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// archiveBatch caps how many orders one archive transaction moves
+const archiveBatch = 500
+
+// archivableStatuses are the final statuses; orders in any other status
+// stay in the live table however old they are
+var archivableStatuses = []string{"COMPLETED", "CANCELLED", "PAYMENT_FAILED"}
+
+// ArchiveOrders moves finished orders that have not changed since before,
+// with their items, to the archive tables in batches; run by the scheduler.
+// Frozen orders stay until their dispute is resolved. Get still finds
+// archived orders, marked Archived; lists, statistics and margins no longer
+// include them.
+func (s *service) ArchiveOrders(ctx context.Context, before time.Time) error {
+	total := 0
+	defer func() {
+		if total > 0 {
+			s.log.Info("orders archived", zap.Int("orders", total), zap.Time("before", before))
+		}
+	}()
+	for ctx.Err() == nil {
+		n, err := s.repo.ArchiveOrders(ctx, before, archivableStatuses, archiveBatch)
+		total += n
+		if err != nil || n < archiveBatch {
+			return err
+		}
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,172 @@
+package Orders
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// Kinds of order attachments
+const (
+	AttachmentCustomsForm   = "CUSTOMS_FORM"
+	AttachmentDeliveryProof = "DELIVERY_PROOF"
+	AttachmentPurchaseOrder = "PURCHASE_ORDER"
+	AttachmentOther         = "OTHER"
+)
+
+// OrderAttachment is a document kept with an order, such as a customs form,
+// a signed proof of delivery or the buyer's purchase order. Roles, when
+// set, narrows who may read it to those roles and the writers of
+// AttachmentAccess.
+type OrderAttachment struct {
+	ID          uuid.UUID      `db:"id" json:"id"`
+	OrderID     uuid.UUID      `db:"order_id" json:"order_id"`
+	Kind        string         `db:"kind" json:"kind"`
+	FileName    string         `db:"file_name" json:"file_name"`
+	FileKey     string         `db:"file_key" json:"-"`
+	ContentType string         `db:"content_type" json:"content_type"`
+	SizeBytes   int64          `db:"size_bytes" json:"size_bytes"`
+	SHA256      string         `db:"sha256" json:"sha256"`
+	Description *string        `db:"description" json:"description,omitempty"`
+	Roles       pq.StringArray `db:"roles" json:"roles"`
+	UploadedBy  *string        `db:"uploaded_by" json:"uploaded_by,omitempty"` // the uploader's role
+	CreatedAt   time.Time      `db:"created_at" json:"created_at"`
+}
+
+// AttachmentStore keeps the files of order attachments
+type AttachmentStore interface {
+	Put(ctx context.Context, key string, r io.Reader, contentType string) error
+	Delete(ctx context.Context, key string) error
+}
+
+// AttachmentAccess decides who works with order attachments: callers whose
+// role, as Role finds it, is one of Read list and download them, and those
+// with one of Write also attach and delete them. Callers without a role,
+// such as the plain ADMIN_API_KEYS, are full admins and do both.
+type AttachmentAccess struct {
+	Read  []string
+	Write []string
+	Role  func(ctx context.Context) string
+}
+
+func (a AttachmentAccess) role(ctx context.Context) string {
+	if a.Role == nil {
+		return ""
+	}
+	return a.Role(ctx)
+}
+
+func hasRole(roles []string, role string) bool {
+	for _, r := range roles {
+		if role != "" && r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func (a AttachmentAccess) canWrite(ctx context.Context) bool {
+	role := a.role(ctx)
+	return role == "" || hasRole(a.Write, role)
+}
+
+func (a AttachmentAccess) canList(ctx context.Context) bool {
+	return a.canWrite(ctx) || hasRole(a.Read, a.role(ctx))
+}
+
+// canRead reports whether the caller may see att, which its roles may
+// restrict further
+func (a AttachmentAccess) canRead(ctx context.Context, att OrderAttachment) bool {
+	if a.canWrite(ctx) {
+		return true
+	}
+	role := a.role(ctx)
+	return hasRole(a.Read, role) && (len(att.Roles) == 0 || hasRole(att.Roles, role))
+}
+
+// hashingCounter sums and counts what is written to it
+type hashingCounter struct {
+	hash.Hash
+	n int64
+}
+
+func (h *hashingCounter) Write(p []byte) (int, error) {
+	h.n += int64(len(p))
+	return h.Hash.Write(p)
+}
+
+// Attach stores body as a new attachment of the order; the file's size and
+// SHA-256 are taken as it is stored. uploadedBy is the uploader's role.
+func (s *service) Attach(ctx context.Context, orderID uuid.UUID, req AttachRequest, body io.Reader, uploadedBy string) (*OrderAttachment, error) {
+	if s.attachments == nil {
+		return nil, fmt.Errorf("attachment storage is not configured")
+	}
+	if _, _, err := s.repo.GetOrder(ctx, orderID); err != nil {
+		return nil, err
+	}
+	a := &OrderAttachment{
+		ID:          uuid.New(),
+		OrderID:     orderID,
+		Kind:        req.Kind,
+		FileName:    path.Base(req.FileName),
+		ContentType: req.ContentType,
+		Description: optional(strings.TrimSpace(req.Description)),
+		Roles:       pq.StringArray(req.Roles),
+		UploadedBy:  optional(uploadedBy),
+		CreatedAt:   time.Now().UTC(),
+	}
+	if a.Roles == nil {
+		a.Roles = pq.StringArray{}
+	}
+	a.FileKey = fmt.Sprintf("orders/%s/attachments/%s/%s", orderID, a.ID, a.FileName)
+	sum := &hashingCounter{Hash: sha256.New()}
+	if err := s.attachments.Put(ctx, a.FileKey, io.TeeReader(body, sum), a.ContentType); err != nil {
+		return nil, err
+	}
+	a.SizeBytes, a.SHA256 = sum.n, hex.EncodeToString(sum.Sum(nil))
+	if err := s.repo.CreateAttachment(ctx, a); err != nil {
+		_ = s.attachments.Delete(ctx, a.FileKey)
+		return nil, err
+	}
+	return a, nil
+}
+
+// Attachments lists the order's attachments, oldest first
+func (s *service) Attachments(ctx context.Context, orderID uuid.UUID) ([]OrderAttachment, error) {
+	if _, _, err := s.repo.GetOrder(ctx, orderID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListAttachments(ctx, orderID)
+}
+
+func (s *service) Attachment(ctx context.Context, orderID, id uuid.UUID) (*OrderAttachment, error) {
+	return s.repo.GetAttachment(ctx, orderID, id)
+}
+
+// DeleteAttachment removes the attachment and then its file; a file that
+// can't be removed is only logged, as nothing refers to it any more
+func (s *service) DeleteAttachment(ctx context.Context, orderID, id uuid.UUID) error {
+	a, err := s.repo.GetAttachment(ctx, orderID, id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.DeleteAttachment(ctx, orderID, id); err != nil {
+		return err
+	}
+	if s.attachments != nil {
+		if err := s.attachments.Delete(ctx, a.FileKey); err != nil {
+			s.log.Error("delete attachment file", zap.String("key", a.FileKey), zap.Error(err))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package Orders_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"savannah/src/Approvals"
+	"savannah/src/Orders"
+)
+
+func TestBulkCancelReleasesReservedStock(t *testing.T) {
+	orders := Orders.NewTestOrders(t)
+	ids := []uuid.UUID{}
+	products := []uuid.UUID{}
+	for _, status := range []string{"CREATED", "CONFIRMED", "PROCESSING"} {
+		id, productID := orders.Place(t, status, "NBO", 2)
+		ids, products = append(ids, id), append(products, productID)
+	}
+	payload, err := json.Marshal(Approvals.BulkCancelPayload{OrderIDs: ids})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cancel := Approvals.NewActions(nil, nil, orders)[Approvals.ActionBulkCancel]
+	if _, err := cancel.Execute(context.Background(), payload); err != nil {
+		t.Fatalf("bulk cancel: %v", err)
+	}
+	for i, productID := range products {
+		if got := orders.Reserved("NBO", productID); got != 0 {
+			t.Errorf("order %d: reserved after bulk cancel = %d, want 0", i, got)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package Orders
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+	"savannah/src/Analytics"
+	"savannah/src/Billing"
+)
+
+// customerCancellable lists the statuses a customer may cancel from; once
+// PROCESSING the warehouse has started on the order and only staff can
+// cancel it.
+var customerCancellable = map[string]bool{StatusPreorder: true, "CREATED": true, "CONFIRMED": true}
+
+// CustomerCancel cancels the order on behalf of its customer. Unlike the
+// admin status change it checks ownership, the cancellation window and the
+// status, refunds what was already captured, and records a
+// CUSTOMER_CANCELLED event with the reason and refund on the timeline.
+// Another customer's order is reported as not found.
+func (s *service) CustomerCancel(ctx context.Context, id, customerID uuid.UUID, reason string) (*Cancellation, error) {
+	order, _, err := s.repo.GetOrder(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if order.CustomerID == nil || *order.CustomerID != customerID {
+		return nil, sql.ErrNoRows
+	}
+	if !customerCancellable[order.Status] {
+		return nil, fmt.Errorf("%w: order is %s", ErrorNotCancellable, strings.ToLower(order.Status))
+	}
+	// nothing is reserved or charged for a pre-order, so it stays cancellable
+	// until it is allocated
+	if w := s.limits.CancelWindow; w > 0 && order.Status != StatusPreorder && time.Since(order.CreatedAt) > w {
+		return nil, fmt.Errorf("%w: orders can be cancelled within %s of being placed", ErrorNotCancellable, w)
+	}
+	if err := s.applyStatus(ctx, order, "CANCELLED"); err != nil {
+		return nil, err
+	}
+
+	c := &Cancellation{OrderID: order.ID, Status: "CANCELLED", Refunds: []Billing.Refund{}}
+	refundReason := reason
+	if refundReason == "" {
+		refundReason = "cancelled by customer"
+	}
+	// authorizations were voided with the status change; only captured
+	// payments are left to refund
+	if s.payments != nil {
+		refunds, err := s.payments.RefundOrder(ctx, order.ID, nil, &refundReason)
+		switch {
+		case err == nil:
+			c.Refunds = refunds
+		case errors.Is(err, Billing.ErrorInvoiceNotFound), errors.Is(err, Billing.ErrorNotRefundable), errors.Is(err, Billing.ErrorNoPaymentToRefund):
+		default:
+			s.log.Error("refund customer cancellation", zap.String("order_id", order.ID.String()), zap.Error(err))
+			c.Refunds = append(c.Refunds, refunds...)
+			c.RefundFailed = true
+		}
+	}
+	refunded := decimal.Zero
+	for _, rf := range c.Refunds {
+		refunded = refunded.Add(rf.Amount)
+	}
+	data := map[string]interface{}{"customer_id": customerID, "reason": reason, "refunded": refunded, "refund_failed": c.RefundFailed}
+	if err := s.repo.AddEvent(ctx, order.ID, EventCustomerCancelled, data); err != nil {
+		s.log.Error("record customer cancellation", zap.String("order_id", order.ID.String()), zap.Error(err))
+	}
+	s.track(ctx, Analytics.EventOrderCancelled, order.CustomerID, map[string]interface{}{"order_id": order.ID, "refunded": refunded})
+	return c, nil
+}
@@ -0,0 +1,107 @@
+package Orders
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// DeviceIDHeader carries the storefront's identifier of the shopper's
+// device, such as an app install id or a fingerprint
+const DeviceIDHeader = "X-Device-ID"
+
+// ClientContext is where an order was placed from, kept for fraud analysis
+// and support. It is personal data, shown only to the roles ClientAccess
+// lets see it.
+type ClientContext struct {
+	OrderID   uuid.UUID `db:"order_id" json:"-"`
+	IP        *string   `db:"ip" json:"ip,omitempty"`
+	UserAgent *string   `db:"user_agent" json:"user_agent,omitempty"`
+	DeviceID  *string   `db:"device_id" json:"device_id,omitempty"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+const (
+	maxUserAgent = 512
+	maxDeviceID  = 128
+)
+
+// ClientFrom reads the client context of a checkout request: the remote
+// address (put chi's RealIP in front when behind a trusted proxy), the
+// User-Agent and the DeviceIDHeader. It is nil when the request has none.
+func ClientFrom(r *http.Request) *ClientContext {
+	ip := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	c := &ClientContext{
+		IP:        optional(ip),
+		UserAgent: optional(truncate(r.UserAgent(), maxUserAgent)),
+		DeviceID:  optional(truncate(strings.TrimSpace(r.Header.Get(DeviceIDHeader)), maxDeviceID)),
+	}
+	if c.IP == nil && c.UserAgent == nil && c.DeviceID == nil {
+		return nil
+	}
+	return c
+}
+
+// truncate cuts s to at most n bytes without splitting a character
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	s = s[:n]
+	for !utf8.ValidString(s) {
+		s = s[:len(s)-1]
+	}
+	return s
+}
+
+// ClientAccess decides who sees an order's client context: callers whose
+// role, as Role finds it, is one of Roles
+type ClientAccess struct {
+	Roles []string
+	Role  func(ctx context.Context) string
+}
+
+func (a ClientAccess) allowed(ctx context.Context) bool {
+	return a.Role != nil && hasRole(a.Roles, a.Role(ctx))
+}
+
+// FraudChecker screens a checkout before its stock is reserved and it is
+// paid for; an error wrapping ErrorFraudRejected declines the order, any
+// other fails it. client is nil when the order wasn't placed over HTTP.
+type FraudChecker interface {
+	Check(ctx context.Context, order *Order, items []OrderItem, client *ClientContext) error
+}
+
+func (s *service) screen(ctx context.Context, order *Order, items []OrderItem, client *ClientContext) error {
+	if s.fraud == nil {
+		return nil
+	}
+	return s.fraud.Check(ctx, order, items, client)
+}
+
+// recordClient keeps the client context of a placed order; losing it doesn't
+// fail the order
+func (s *service) recordClient(ctx context.Context, order *Order, client *ClientContext) {
+	if client == nil {
+		return
+	}
+	c := *client
+	c.OrderID, c.CreatedAt = order.ID, order.CreatedAt
+	if err := s.repo.SaveClient(ctx, &c); err != nil {
+		s.log.Error("record order client context", zap.String("order_id", order.ID.String()), zap.Error(err))
+	}
+}
+
+// Client returns where the order was placed from, nil when unknown
+func (s *service) Client(ctx context.Context, orderID uuid.UUID) (*ClientContext, error) {
+	return s.repo.GetClient(ctx, orderID)
+}
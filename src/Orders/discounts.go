@@ -0,0 +1,105 @@
+package Orders
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+	"savannah/src/Coupons"
+	"savannah/src/GiftCards"
+)
+
+// CouponRedeemer applies the coupon code a shopper entered at checkout and
+// counts its use against the coupon's limits
+type CouponRedeemer interface {
+	Apply(ctx context.Context, code string, customerID *uuid.UUID, currency string, lines []Coupons.Line) (*Coupons.Discount, error)
+	Redeem(ctx context.Context, d Coupons.Discount, orderID uuid.UUID, customerID *uuid.UUID) error
+	Release(ctx context.Context, orderID uuid.UUID) error
+}
+
+// GiftCardRedeemer spends the balance of the gift card a shopper entered at
+// checkout and gives it back when the order isn't kept
+type GiftCardRedeemer interface {
+	Redeem(ctx context.Context, code string, amount decimal.Decimal, currency string, orderID *uuid.UUID, reference string) (*GiftCards.Redemption, error)
+	Release(ctx context.Context, orderID uuid.UUID) error
+}
+
+// applyCoupon takes what the coupon behind code is worth off the order's
+// items. The order is given its id here, since the coupon is redeemed for
+// it before it is stored.
+func (s *service) applyCoupon(ctx context.Context, order *Order, items []OrderItem, code string) error {
+	if code == "" {
+		return nil
+	}
+	if s.coupons == nil {
+		return Coupons.ErrorNotFound
+	}
+	lines := make([]Coupons.Line, 0, len(items))
+	for _, it := range items {
+		if it.ProductID != nil {
+			lines = append(lines, Coupons.Line{ProductID: *it.ProductID, Amount: it.LineTotal})
+		}
+	}
+	d, err := s.coupons.Apply(ctx, code, order.CustomerID, order.Currency, lines)
+	if err != nil {
+		return err
+	}
+	if order.ID == uuid.Nil {
+		order.ID = uuid.New()
+	}
+	if err := s.coupons.Redeem(ctx, *d, order.ID, order.CustomerID); err != nil {
+		return err
+	}
+	couponID := d.CouponID
+	order.addDiscount(OrderDiscount{CouponID: &couponID, Code: d.Code, Description: d.Description, Amount: d.Amount})
+	return nil
+}
+
+// applyGiftCard pays what it can of the order's total, after any coupon,
+// from the gift card behind code; the payment method covers the rest. Like
+// a coupon, the card is redeemed before the order is stored.
+func (s *service) applyGiftCard(ctx context.Context, order *Order, code string) error {
+	if code == "" || !order.Total.IsPositive() {
+		return nil
+	}
+	if s.giftCards == nil {
+		return GiftCards.ErrorNotFound
+	}
+	if order.ID == uuid.Nil {
+		order.ID = uuid.New()
+	}
+	red, err := s.giftCards.Redeem(ctx, code, order.Total, order.Currency, &order.ID, "")
+	if err != nil {
+		return err
+	}
+	cardID := red.GiftCardID
+	order.addDiscount(OrderDiscount{GiftCardID: &cardID, Code: "GIFT CARD " + red.Last4, Description: "Gift card ending " + red.Last4, Amount: red.Amount})
+	return nil
+}
+
+func (o *Order) addDiscount(d OrderDiscount) {
+	o.Discounts = append(o.Discounts, d)
+	o.Discount = o.Discount.Add(d.Amount)
+	o.Total = o.Total.Sub(d.Amount)
+}
+
+// releaseDiscounts gives back the coupon uses and gift card balance of an
+// order that was never placed or was cancelled
+func (s *service) releaseDiscounts(ctx context.Context, order *Order) {
+	var coupon, giftCard bool
+	for _, d := range order.Discounts {
+		coupon = coupon || d.CouponID != nil
+		giftCard = giftCard || d.GiftCardID != nil
+	}
+	if coupon && s.coupons != nil {
+		if err := s.coupons.Release(ctx, order.ID); err != nil {
+			s.log.Error("release coupon", zap.String("order_id", order.ID.String()), zap.Error(err))
+		}
+	}
+	if giftCard && s.giftCards != nil {
+		if err := s.giftCards.Release(ctx, order.ID); err != nil {
+			s.log.Error("release gift card", zap.String("order_id", order.ID.String()), zap.Error(err))
+		}
+	}
+}
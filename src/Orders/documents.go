@@ -0,0 +1,119 @@
+package Orders
+
+import (
+	"context"
+	"html/template"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+	"savannah/src/PDF"
+)
+
+// statuses whose items still have to be picked
+var openStatuses = []string{"CREATED", "CONFIRMED", "PROCESSING"}
+
+// PackingSlip is the printable content that goes in the parcel
+type PackingSlip struct {
+	Order        Order
+	Items        []OrderItem
+	CustomerName string
+	PrintedAt    time.Time
+}
+
+func (s *service) PackingSlip(ctx context.Context, id uuid.UUID) (*PackingSlip, error) {
+	o, items, err := s.repo.GetOrder(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	slip := &PackingSlip{Order: *o, Items: items, PrintedAt: time.Now().UTC()}
+	if o.CustomerID != nil {
+		if name, err := s.repo.GetCustomerName(ctx, *o.CustomerID); err == nil {
+			slip.CustomerName = name
+		}
+	}
+	return slip, nil
+}
+
+func (s *service) PickList(ctx context.Context, warehouse string) ([]PickListLine, error) {
+	return s.repo.PickList(ctx, warehouse, openStatuses)
+}
+
+var packingSlipHTML = template.Must(template.New("packing-slip").Funcs(template.FuncMap{"deref": deref}).Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Packing slip {{.Order.ID}}</title>
+<style>body{font-family:sans-serif;font-size:12px}table{border-collapse:collapse;width:100%}td,th{border:1px solid #999;padding:4px;text-align:left}.gift{font-style:italic}</style>
+</head><body>
+<h1>Packing slip</h1>
+<p>Order: {{.Order.ID}}<br>Date: {{.Order.CreatedAt.Format "2006-01-02"}}{{if .CustomerName}}<br>Customer: {{.CustomerName}}{{end}}{{if .Order.Warehouse}}<br>Warehouse: {{deref .Order.Warehouse}}{{end}}</p>
+<table><tr><th>SKU</th><th>Item</th><th>Qty</th><th>Gift wrap</th><th>Notes</th></tr>
+{{range .Items}}<tr><td>{{deref .SKU}}</td><td>{{deref .Name}}</td><td>{{.Quantity}}</td><td>{{if .GiftWrap}}yes{{end}}</td>
+<td>{{if .GiftMessage}}<div class="gift">Gift message: {{deref .GiftMessage}}</div>{{end}}{{if .CustomerNote}}<div>Note: {{deref .CustomerNote}}</div>{{end}}</td></tr>
+{{end}}</table>
+</body></html>`))
+
+func (p *PackingSlip) WriteHTML(w io.Writer) error {
+	return packingSlipHTML.Execute(w, p)
+}
+
+func (p *PackingSlip) PDF() []byte {
+	var doc PDF.Text
+	doc.Line("PACKING SLIP")
+	doc.Line("")
+	doc.Line("Order: %s", p.Order.ID)
+	doc.Line("Date: %s", p.Order.CreatedAt.Format("2006-01-02"))
+	if p.CustomerName != "" {
+		doc.Line("Customer: %s", p.CustomerName)
+	}
+	if p.Order.Warehouse != nil {
+		doc.Line("Warehouse: %s", *p.Order.Warehouse)
+	}
+	doc.Line("")
+	doc.Line("%-20s %-40s %5s", "SKU", "ITEM", "QTY")
+	for _, it := range p.Items {
+		doc.Line("%-20s %-40s %5d", deref(it.SKU), deref(it.Name), it.Quantity)
+		if it.GiftWrap {
+			doc.Line("    * gift wrap")
+		}
+		if it.GiftMessage != nil {
+			doc.Line("    Gift message: %s", *it.GiftMessage)
+		}
+		if it.CustomerNote != nil {
+			doc.Line("    Note: %s", *it.CustomerNote)
+		}
+	}
+	return doc.Bytes()
+}
+
+var pickListHTML = template.Must(template.New("pick-list").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Pick list {{.Warehouse}}</title>
+<style>body{font-family:sans-serif;font-size:12px}table{border-collapse:collapse;width:100%}td,th{border:1px solid #999;padding:4px;text-align:left}</style>
+</head><body>
+<h1>Pick list – {{.Warehouse}}</h1>
+<p>Generated: {{.GeneratedAt.Format "2006-01-02 15:04"}} UTC</p>
+<table><tr><th>Bin</th><th>SKU</th><th>Item</th><th>Qty</th><th>Gift wrap</th><th>Orders</th></tr>
+{{range .Lines}}<tr><td>{{.BinLocation}}</td><td>{{.SKU}}</td><td>{{.Name}}</td><td>{{.Quantity}}</td><td>{{if .GiftWrap}}{{.GiftWrap}}{{end}}</td><td>{{.Orders}}</td></tr>
+{{end}}</table>
+</body></html>`))
+
+func writePickListHTML(w io.Writer, warehouse string, lines []PickListLine) error {
+	return pickListHTML.Execute(w, map[string]interface{}{"Warehouse": warehouse, "Lines": lines, "GeneratedAt": time.Now().UTC()})
+}
+
+func pickListPDF(warehouse string, lines []PickListLine) []byte {
+	var doc PDF.Text
+	doc.Line("PICK LIST - %s", warehouse)
+	doc.Line("Generated: %s UTC", time.Now().UTC().Format("2006-01-02 15:04"))
+	doc.Line("")
+	doc.Line("%-10s %-20s %-35s %5s %5s", "BIN", "SKU", "ITEM", "QTY", "GIFT")
+	for _, l := range lines {
+		doc.Line("%-10s %-20s %-35s %5d %5d", l.BinLocation, l.SKU, l.Name, l.Quantity, l.GiftWrap)
+	}
+	return doc.Bytes()
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,99 @@
+package Orders
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"savannah/src/Notifications"
+)
+
+// DownloadConfig controls how digital product links are issued
+type DownloadConfig struct {
+	BaseURL      string
+	SigningKey   []byte
+	TTL          time.Duration
+	MaxDownloads int
+}
+
+// Notifier sends customer notifications
+type Notifier interface {
+	Send(ctx context.Context, m Notifications.Message) error
+}
+
+// Customers looks up where to notify a customer; their contact details are
+// encrypted at rest and only the Customer module can read them.
+type Customers interface {
+	Email(ctx context.Context, id uuid.UUID) (string, error)
+}
+
+// InvoicePaid is called by Billing once an order's invoice is settled. It
+// issues download links for digital items and emails them to the customer;
+// an order with nothing to ship is then complete.
+func (s *service) InvoicePaid(ctx context.Context, orderID uuid.UUID) error {
+	order, items, err := s.repo.GetOrder(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.CreateDownloadLinks(ctx, orderID, time.Now().UTC().Add(s.downloads.TTL), s.downloads.MaxDownloads); err != nil {
+		return err
+	}
+	if err := s.fulfilled(ctx, order, items); err != nil {
+		s.log.Error("complete unshipped order", zap.String("order_id", orderID.String()), zap.Error(err))
+	}
+	links, err := s.DownloadLinks(ctx, orderID)
+	if err != nil || len(links) == 0 || order.CustomerID == nil || s.notifier == nil || s.customers == nil {
+		return err
+	}
+	email, err := s.customers.Email(ctx, *order.CustomerID)
+	if err != nil {
+		return err
+	}
+	var body strings.Builder
+	body.WriteString("Thank you for your order. Your downloads are ready:\n\n")
+	for _, l := range links {
+		fmt.Fprintf(&body, "%s\n%s\n(valid until %s, %d downloads)\n\n", l.FileName, l.URL, l.ExpiresAt.Format(time.RFC1123), l.MaxDownloads)
+	}
+	msg := Notifications.Message{Channel: Notifications.ChannelEmail, To: email, Subject: "Your downloads are ready", Body: body.String(), Template: "order_downloads", Data: map[string]interface{}{"order_id": orderID}, OrderID: &orderID}
+	if err := s.notifier.Send(ctx, msg); err != nil {
+		// links are still available on the order detail
+		s.log.Error("send download email", zap.String("order_id", orderID.String()), zap.Error(err))
+	}
+	return nil
+}
+
+// DownloadLinks returns the order's links with signed URLs.
+func (s *service) DownloadLinks(ctx context.Context, orderID uuid.UUID) ([]DownloadLink, error) {
+	links, err := s.repo.ListDownloadLinks(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range links {
+		expires := links[i].ExpiresAt.Unix()
+		links[i].URL = fmt.Sprintf("%s/api/v1/downloads/%s?expires=%d&signature=%s", s.downloads.BaseURL, links[i].ID, expires, s.signDownload(links[i].ID, expires))
+	}
+	return links, nil
+}
+
+// ConsumeDownload verifies the link signature and counts one download.
+func (s *service) ConsumeDownload(ctx context.Context, id uuid.UUID, expires int64, signature string) (*DownloadLink, error) {
+	if !hmac.Equal([]byte(signature), []byte(s.signDownload(id, expires))) {
+		return nil, ErrorDownloadNotFound
+	}
+	if time.Now().UTC().Unix() > expires {
+		return nil, ErrorDownloadExpired
+	}
+	return s.repo.ConsumeDownloadLink(ctx, id)
+}
+
+func (s *service) signDownload(id uuid.UUID, expires int64) string {
+	mac := hmac.New(sha256.New, s.downloads.SigningKey)
+	fmt.Fprintf(mac, "%s:%d", id, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,247 @@
+package Orders
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"savannah/src/Pagination"
+)
+
+// Checkout says where an order ships from/to and how it is paid. Without a
+// Warehouse one is chosen by the routing rules from Country/Region and stock.
+// Offline methods (COD, BANK_TRANSFER) skip authorization and ship with an
+// unpaid invoice.
+type Checkout struct {
+	Warehouse     string
+	Country       string
+	Region        string
+	PaymentMethod string
+	Priority      string // standard when empty
+	Channel       string // web when empty
+	Currency      string // USD when empty
+	PONumber      string
+	PaymentTerms  string         // e.g. NET30: invoiced on the customer's credit account instead of paid upfront
+	HoldID        *uuid.UUID     // stock held for this checkout, see Inventory.Hold
+	CouponCode    string         // redeemed for money off the items, see Coupons.Coupon
+	GiftCardCode  string         // pays what it can of the total, see GiftCards.GiftCard
+	Client        *ClientContext // where the checkout came from; nil when not placed over HTTP
+}
+
+// CreateOrderRequest is a storefront checkout; prices come from the catalog
+type CreateOrderRequest struct {
+	CustomerID    *uuid.UUID        `json:"customer_id,omitempty"`
+	Warehouse     string            `json:"warehouse,omitempty"`
+	Country       string            `json:"country,omitempty" validate:"omitempty,len=2"`
+	Region        string            `json:"region,omitempty" validate:"omitempty,max=100"`
+	PaymentMethod string            `json:"payment_method" validate:"required_without=PaymentTerms,max=50"`
+	Priority      string            `json:"priority,omitempty" validate:"omitempty,oneof=standard express"`
+	Channel       string            `json:"channel,omitempty" validate:"omitempty,oneof=web mobile"`
+	Currency      string            `json:"currency,omitempty" validate:"omitempty,len=3"` // the items' when empty; they must all be priced in it
+	Items         []CreateOrderItem `json:"items" validate:"required,min=1,max=100,dive"`
+	PONumber      string            `json:"po_number,omitempty" validate:"omitempty,max=64"`
+	// net terms such as NET30 for customers approved to pay on terms; the
+	// payment method is then TERMS
+	PaymentTerms string `json:"payment_terms,omitempty" validate:"omitempty,max=10"`
+	// a stock hold taken for this checkout; its stock becomes the order's
+	HoldID *uuid.UUID `json:"hold_id,omitempty"`
+	// a coupon taking money off the items; its discount shows in the
+	// order's discount lines and is netted from the total
+	CouponCode string `json:"coupon_code,omitempty" validate:"omitempty,max=40"`
+	// a gift card paying what its balance covers of the total after any
+	// coupon; payment_method pays the rest
+	GiftCardCode string `json:"gift_card_code,omitempty" validate:"omitempty,max=64"`
+	// where the checkout came from, read off the request by ClientFrom
+	Client *ClientContext `json:"-"`
+}
+
+type SetPurchaseLimitRequest struct {
+	MaxQuantity int `json:"max_quantity" validate:"required,min=1"`
+	PeriodHours int `json:"period_hours" validate:"required,min=1,max=8760"`
+}
+
+// ListOrdersQuery filters the admin order list; zero values match everything
+type ListOrdersQuery struct {
+	Status     string
+	Warehouse  string
+	Priority   string
+	Source     string
+	Channel    string
+	CustomerID *uuid.UUID
+	// orders with at least one item of this SKU or product
+	SKU       string
+	ProductID *uuid.UUID
+	Tag       string
+	From      *time.Time
+	To        *time.Time
+	Limit     int
+	Offset    int
+}
+
+// ParseListOrdersQuery reads the order list filters from query parameters:
+// status, warehouse, priority, source, channel, customer_id, sku, product_id,
+// tag, from and to (RFC3339), and the page: limit (default 20, max 100) and
+// offset or page.
+func ParseListOrdersQuery(v url.Values) (ListOrdersQuery, error) {
+	q := ListOrdersQuery{Status: v.Get("status"), Warehouse: v.Get("warehouse"), Priority: v.Get("priority"), Source: v.Get("source"), Channel: v.Get("channel"), SKU: strings.TrimSpace(v.Get("sku")), Tag: v.Get("tag")}
+	for name, dst := range map[string]**uuid.UUID{"customer_id": &q.CustomerID, "product_id": &q.ProductID} {
+		if s := v.Get(name); s != "" {
+			id, err := uuid.Parse(s)
+			if err != nil {
+				return q, fmt.Errorf("invalid %s", name)
+			}
+			*dst = &id
+		}
+	}
+	for name, dst := range map[string]**time.Time{"from": &q.From, "to": &q.To} {
+		if s := v.Get(name); s != "" {
+			t, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				return q, fmt.Errorf("invalid %s", name)
+			}
+			*dst = &t
+		}
+	}
+	q.Limit, q.Offset = Pagination.Params(v, 20, 100)
+	return q, nil
+}
+
+// RecallRequest finds the orders of SKU placed from From up to To, narrowed
+// to one Warehouse when set. Order items don't record their lot, so Lot only
+// labels the recall; the date range (and warehouse) should bound the lot's
+// sales. Tag tags the orders; Notify emails each customer Message once.
+type RecallRequest struct {
+	SKU       string    `json:"sku" validate:"required,max=64"`
+	Lot       string    `json:"lot,omitempty" validate:"omitempty,max=64"`
+	Warehouse string    `json:"warehouse,omitempty" validate:"omitempty,max=50"`
+	From      time.Time `json:"from" validate:"required"`
+	To        time.Time `json:"to" validate:"required"`
+	Tag       string    `json:"tag,omitempty" validate:"omitempty,max=64"`
+	Notify    bool      `json:"notify,omitempty"`
+	Subject   string    `json:"subject,omitempty" validate:"omitempty,max=200"`
+	Message   string    `json:"message,omitempty" validate:"required_if=Notify true,max=5000"`
+}
+
+// UpdateStatusRequest changes the order status; Version is the order version the client last read
+type UpdateStatusRequest struct {
+	Status  string `json:"status" validate:"required,oneof=CONFIRMED PROCESSING SHIPPED COMPLETED CANCELLED"`
+	Version int    `json:"version" validate:"required,min=1"`
+}
+
+type CreateNoteRequest struct {
+	Author string `json:"author" validate:"required,max=200"`
+	Body   string `json:"body" validate:"required,max=5000"`
+}
+
+// AttachRequest describes a file attached to an order, sent as the form
+// fields of the upload
+type AttachRequest struct {
+	Kind        string   `validate:"required,oneof=CUSTOMS_FORM DELIVERY_PROOF PURCHASE_ORDER OTHER"`
+	FileName    string   `validate:"required,max=255"`
+	ContentType string   `validate:"required,max=100"`
+	Description string   `validate:"max=500"`
+	Roles       []string `validate:"max=10,dive,required,max=50"`
+}
+
+// RetryPaymentRequest pays a PAYMENT_FAILED order; the order's own method is
+// used when PaymentMethod is empty
+type RetryPaymentRequest struct {
+	PaymentMethod string `json:"payment_method,omitempty" validate:"omitempty,max=50"`
+}
+
+// CustomerCancelRequest is sent by the storefront on the customer's behalf
+type CustomerCancelRequest struct {
+	Reason string `json:"reason,omitempty" validate:"omitempty,max=500"`
+}
+
+type CreateOrderItem struct {
+	ProductID    *uuid.UUID      `json:"product_id" validate:"required"`
+	VariantID    *uuid.UUID      `json:"variant_id,omitempty"`
+	SKU          *string         `json:"sku,omitempty"`
+	Name         *string         `json:"name,omitempty"`
+	UnitPrice    decimal.Decimal `json:"unit_price"`
+	Quantity     int             `json:"quantity" validate:"required,min=1"`
+	GiftWrap     bool            `json:"gift_wrap,omitempty"`
+	GiftMessage  *string         `json:"gift_message,omitempty" validate:"omitempty,max=500"`
+	CustomerNote *string         `json:"customer_note,omitempty" validate:"omitempty,max=1000"`
+	// SlotID is required for bookable products, see Booking
+	SlotID *uuid.UUID `json:"slot_id,omitempty"`
+}
+
+// ImportOrderRequest is an order captured outside our checkout (marketplace, POS, ...)
+type ImportOrderRequest struct {
+	ExternalReference string            `json:"external_reference" validate:"required,max=128"`
+	Source            string            `json:"source" validate:"required,max=50"`
+	CustomerID        *uuid.UUID        `json:"customer_id,omitempty"`
+	Currency          string            `json:"currency" validate:"required,len=3"`
+	Warehouse         string            `json:"warehouse,omitempty"` // routed from country/region when empty
+	Country           string            `json:"country,omitempty" validate:"omitempty,len=2"`
+	Region            string            `json:"region,omitempty" validate:"omitempty,max=100"`
+	Priority          string            `json:"priority,omitempty" validate:"omitempty,oneof=standard express"`
+	Items             []CreateOrderItem `json:"items" validate:"required,min=1,dive"`
+}
+
+type ImportOrdersRequest struct {
+	Orders []ImportOrderRequest `json:"orders" validate:"required,min=1,max=500"`
+}
+
+// per-order import outcome
+const (
+	ImportStatusCreated   = "created"
+	ImportStatusDuplicate = "duplicate"
+	ImportStatusFailed    = "failed"
+)
+
+type ImportOrderResult struct {
+	ExternalReference string     `json:"external_reference"`
+	OrderID           *uuid.UUID `json:"order_id,omitempty"`
+	Status            string     `json:"status"`
+	Error             string     `json:"error,omitempty"`
+}
+
+// POSOrderRequest is a sale rung up at a store till, possibly while offline.
+// ID is generated by the till and becomes the order id, so resending a batch
+// is harmless; CreatedAt is the till's local clock.
+type POSOrderRequest struct {
+	ID         uuid.UUID         `json:"id" validate:"required"`
+	CreatedAt  time.Time         `json:"created_at" validate:"required"`
+	CustomerID *uuid.UUID        `json:"customer_id,omitempty"`
+	Currency   string            `json:"currency" validate:"required,len=3"`
+	Items      []CreateOrderItem `json:"items" validate:"required,min=1,dive"`
+}
+
+// POSBatchRequest is what a store uploads when it syncs; every order is
+// deducted from the store's own warehouse.
+type POSBatchRequest struct {
+	Warehouse string            `json:"warehouse" validate:"required,max=50"`
+	Orders    []POSOrderRequest `json:"orders" validate:"required,min=1,max=500,dive"`
+}
+
+// POSConflict is an item sold offline beyond the stock the warehouse had
+// unreserved when the sale was synced.
+type POSConflict struct {
+	ProductID uuid.UUID  `json:"product_id"`
+	VariantID *uuid.UUID `json:"variant_id,omitempty"`
+	SKU       *string    `json:"sku,omitempty"`
+	Requested int        `json:"requested"`
+	Available int        `json:"available"`
+	Oversold  int        `json:"oversold"`
+}
+
+type POSOrderResult struct {
+	ID        uuid.UUID     `json:"id"`
+	Status    string        `json:"status"`
+	Error     string        `json:"error,omitempty"`
+	Conflicts []POSConflict `json:"conflicts,omitempty"`
+}
+
+type OrderResponse struct {
+	Order
+	Items     []OrderItem    `json:"items"`
+	Downloads []DownloadLink `json:"downloads,omitempty"`
+	// only for the roles allowed to see it
+	Client *ClientContext `json:"client,omitempty"`
+}
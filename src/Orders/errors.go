@@ -0,0 +1,142 @@
+package Orders
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"savannah/src/ErrorCodes"
+)
+
+var (
+	ErrorNotFound            = errors.New("order not found")
+	ErrorVersionConflict     = errors.New("version conflict")
+	ErrorDuplicateExternal   = errors.New("order with external reference already exists")
+	ErrorInvalidPayload      = errors.New("invalid order payload")
+	ErrorDownloadNotFound    = errors.New("download link not found")
+	ErrorDownloadExpired     = errors.New("download link expired or download limit reached")
+	ErrorOrderFrozen         = errors.New("order is frozen pending dispute resolution")
+	ErrorUnknownProduct      = errors.New("unknown product")
+	ErrorUnknownVariant      = errors.New("unknown variant for product")
+	ErrorInvalidRange        = errors.New("from must be before to")
+	ErrorInvalidTransition   = errors.New("status transition not allowed")
+	ErrorRangeRequired       = errors.New("from and to are required")
+	ErrorNotCancellable      = errors.New("order can no longer be cancelled by the customer")
+	ErrorNotAwaitingPay      = errors.New("order is not awaiting payment")
+	ErrorAttachmentNotFound  = errors.New("order attachment not found")
+	ErrorAttachmentForbidden = errors.New("role may not access order attachments")
+	ErrorTermsNeedCustomer   = errors.New("payment terms need a customer and the TERMS payment method")
+	ErrorMixedPreorder       = errors.New("pre-order products must be ordered separately from products in stock")
+	ErrorArchived            = errors.New("order is archived and can no longer change")
+	ErrorFraudRejected       = errors.New("order declined by fraud screening")
+)
+
+// StatsRangeError is returned when a statistics window is wider than allowed
+type StatsRangeError struct {
+	MaxDays       int `json:"max_days"`
+	RequestedDays int `json:"requested_days"`
+}
+
+func (e *StatsRangeError) Error() string {
+	return fmt.Sprintf("statistics range of %d days exceeds the maximum of %d days; split the request into windows of at most %d days", e.RequestedDays, e.MaxDays, e.MaxDays)
+}
+
+// limit codes
+const (
+	LimitProductQuantity = "product_quantity_limit"
+	LimitOpenOrders      = "open_orders_limit"
+)
+
+// LimitError is returned when an order would exceed a customer purchase limit
+type LimitError struct {
+	Code      string     `json:"code"`
+	ProductID *uuid.UUID `json:"product_id,omitempty"`
+	Limit     int        `json:"limit"`
+	Used      int        `json:"used"`
+	Requested int        `json:"requested"`
+	Hours     int        `json:"period_hours,omitempty"`
+}
+
+func (e *LimitError) Error() string {
+	if e.Code == LimitOpenOrders {
+		return fmt.Sprintf("customer already has %d open orders (limit %d)", e.Used, e.Limit)
+	}
+	return fmt.Sprintf("product %s is limited to %d units per customer every %d hours; %d already purchased, %d requested", e.ProductID, e.Limit, e.Hours, e.Used, e.Requested)
+}
+
+// CurrencyError is returned when order lines are priced in another currency
+// than the order: the one asked for or, when none is, the first line's
+type CurrencyError struct {
+	Currency string             `json:"currency"`
+	Lines    []CurrencyMismatch `json:"lines"`
+}
+
+// CurrencyMismatch is an order line priced in another currency; Line is its
+// index in the request's items
+type CurrencyMismatch struct {
+	Line      int        `json:"line"`
+	ProductID uuid.UUID  `json:"product_id"`
+	VariantID *uuid.UUID `json:"variant_id,omitempty"`
+	Currency  string     `json:"currency"`
+}
+
+func (e *CurrencyError) Error() string {
+	if len(e.Lines) == 1 {
+		return fmt.Sprintf("order line %d is priced in %s, not the order currency %s", e.Lines[0].Line, e.Lines[0].Currency, e.Currency)
+	}
+	return fmt.Sprintf("%d order lines are not priced in the order currency %s", len(e.Lines), e.Currency)
+}
+
+// PaymentError is returned when the order was placed but its payment failed;
+// the order is kept as PAYMENT_FAILED so the payment can be retried.
+type PaymentError struct {
+	Order *Order
+	Err   error
+}
+
+func (e *PaymentError) Error() string {
+	return "payment failed: " + e.Err.Error()
+}
+
+func (e *PaymentError) Unwrap() error { return e.Err }
+
+func init() {
+	ErrorCodes.Register("ORDERS",
+		ErrorCodes.Def{N: 1, Slug: "NOT_FOUND", Err: ErrorNotFound},
+		ErrorCodes.Def{N: 2, Slug: "VERSION_CONFLICT", Err: ErrorVersionConflict},
+		ErrorCodes.Def{N: 3, Slug: "DUPLICATE_EXTERNAL_REFERENCE", Err: ErrorDuplicateExternal},
+		ErrorCodes.Def{N: 4, Slug: "INVALID_PAYLOAD", Err: ErrorInvalidPayload},
+		ErrorCodes.Def{N: 5, Slug: "DOWNLOAD_NOT_FOUND", Err: ErrorDownloadNotFound},
+		ErrorCodes.Def{N: 6, Slug: "DOWNLOAD_EXPIRED", Err: ErrorDownloadExpired},
+		ErrorCodes.Def{N: 7, Slug: "ORDER_FROZEN", Err: ErrorOrderFrozen},
+		ErrorCodes.Def{N: 8, Slug: "UNKNOWN_PRODUCT", Err: ErrorUnknownProduct},
+		ErrorCodes.Def{N: 9, Slug: "UNKNOWN_VARIANT", Err: ErrorUnknownVariant},
+		ErrorCodes.Def{N: 10, Slug: "INVALID_RANGE", Err: ErrorInvalidRange},
+		ErrorCodes.Def{N: 11, Slug: "INVALID_TRANSITION", Err: ErrorInvalidTransition},
+		ErrorCodes.Def{N: 12, Slug: "RANGE_REQUIRED", Err: ErrorRangeRequired},
+		ErrorCodes.Def{N: 13, Slug: "STATS_RANGE_TOO_LARGE", Message: "statistics range too large", Match: func(err error) bool {
+			var e *StatsRangeError
+			return errors.As(err, &e)
+		}},
+		ErrorCodes.Def{N: 14, Slug: "PURCHASE_LIMIT_EXCEEDED", Message: "order exceeds a customer purchase limit", Match: func(err error) bool {
+			var e *LimitError
+			return errors.As(err, &e)
+		}},
+		ErrorCodes.Def{N: 15, Slug: "NOT_CANCELLABLE", Err: ErrorNotCancellable},
+		ErrorCodes.Def{N: 16, Slug: "PAYMENT_FAILED", Message: "payment failed", Match: func(err error) bool {
+			var e *PaymentError
+			return errors.As(err, &e)
+		}},
+		ErrorCodes.Def{N: 17, Slug: "NOT_AWAITING_PAYMENT", Err: ErrorNotAwaitingPay},
+		ErrorCodes.Def{N: 18, Slug: "ATTACHMENT_NOT_FOUND", Err: ErrorAttachmentNotFound},
+		ErrorCodes.Def{N: 19, Slug: "ATTACHMENT_FORBIDDEN", Err: ErrorAttachmentForbidden},
+		ErrorCodes.Def{N: 20, Slug: "TERMS_NEED_CUSTOMER", Err: ErrorTermsNeedCustomer},
+		ErrorCodes.Def{N: 21, Slug: "MIXED_PREORDER", Err: ErrorMixedPreorder},
+		ErrorCodes.Def{N: 22, Slug: "ORDER_ARCHIVED", Err: ErrorArchived},
+		ErrorCodes.Def{N: 23, Slug: "CURRENCY_MISMATCH", Message: "order lines priced in another currency", Match: func(err error) bool {
+			var e *CurrencyError
+			return errors.As(err, &e)
+		}},
+		ErrorCodes.Def{N: 24, Slug: "FRAUD_REJECTED", Err: ErrorFraudRejected},
+	)
+}
@@ -0,0 +1,31 @@
+package Orders
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// TestOrders is the service over the in-memory repository and inventory,
+// for the external tests driving it the way other packages do
+type TestOrders struct {
+	Service
+	s    *service
+	repo *fakeRepository
+	inv  *fakeInventory
+}
+
+func NewTestOrders(t *testing.T) *TestOrders {
+	s, repo, inv := newTestService(t)
+	return &TestOrders{Service: s, s: s, repo: repo, inv: inv}
+}
+
+// Place stores an order in status with qty units of a new product reserved
+func (o *TestOrders) Place(t *testing.T, status, warehouse string, qty int) (orderID, productID uuid.UUID) {
+	order, productID := placeTestOrder(t, o.s, o.repo, status, warehouse, qty)
+	return order.ID, productID
+}
+
+func (o *TestOrders) Reserved(warehouse string, productID uuid.UUID) int {
+	return o.inv.Reserved(warehouse, productID)
+}
@@ -0,0 +1,106 @@
+package Orders
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"savannah/src/Billing"
+)
+
+// stocked returns the items reserved from a warehouse and shipped: those of
+// physical products. Digital goods are delivered as download links and
+// services need no delivery, so neither holds stock.
+func (s *service) stocked(ctx context.Context, items []OrderItem) ([]OrderItem, error) {
+	ids := []uuid.UUID{}
+	for _, it := range items {
+		if it.ProductID != nil {
+			ids = append(ids, *it.ProductID)
+		}
+	}
+	if len(ids) == 0 {
+		return items, nil
+	}
+	unstocked, err := s.repo.UnstockedProducts(ctx, ids)
+	if err != nil || len(unstocked) == 0 {
+		return items, err
+	}
+	skip := make(map[uuid.UUID]bool, len(unstocked))
+	for _, id := range unstocked {
+		skip[id] = true
+	}
+	out := make([]OrderItem, 0, len(items))
+	for _, it := range items {
+		if it.ProductID == nil || !skip[*it.ProductID] {
+			out = append(out, it)
+		}
+	}
+	return out, nil
+}
+
+// shipsNothing reports whether every item of the order is digital or a
+// service
+func (s *service) shipsNothing(ctx context.Context, items []OrderItem) (bool, error) {
+	stock, err := s.stocked(ctx, items)
+	return err == nil && len(stock) == 0, err
+}
+
+// captureUnshipped captures the authorization of an order that ships
+// nothing, which would otherwise wait for a shipment that never comes. The
+// settled invoice then issues the download links and completes the order
+// through InvoicePaid. Offline and terms invoices get there when they are
+// paid. A failed capture is left to Billing's auto-capture.
+func (s *service) captureUnshipped(ctx context.Context, order *Order, items []OrderItem, method string) {
+	if s.payments == nil || method == Billing.MethodTerms || Billing.IsOfflineMethod(method) {
+		return
+	}
+	if none, err := s.shipsNothing(ctx, items); err != nil || !none {
+		if err != nil {
+			s.log.Error("check order fulfillment", zap.String("order_id", order.ID.String()), zap.Error(err))
+		}
+		return
+	}
+	if _, err := s.payments.CaptureOrder(ctx, order.ID); err != nil {
+		s.log.Error("capture unshipped order", zap.String("order_id", order.ID.String()), zap.Error(err))
+		return
+	}
+	if stored, _, err := s.repo.GetOrder(ctx, order.ID); err == nil {
+		*order = *stored
+	}
+}
+
+// fulfilled completes a paid order that ships nothing: its downloads are
+// issued and services need no delivery
+func (s *service) fulfilled(ctx context.Context, order *Order, items []OrderItem) error {
+	if !canTransition(order.Status, "COMPLETED") || order.Status == "SHIPPED" {
+		return nil
+	}
+	if none, err := s.shipsNothing(ctx, items); err != nil || !none {
+		return err
+	}
+	return s.Transition(ctx, order.ID, "COMPLETED")
+}
+
+// OrderDelivered is called by Shipping once every parcel of the order is
+// delivered, which completes it. The statuses nobody moved the order
+// through on the way, such as SHIPPED, are passed through first.
+func (s *service) OrderDelivered(ctx context.Context, orderID uuid.UUID) error {
+	order, _, err := s.repo.GetOrder(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	status := order.Status
+	for _, next := range []string{"PROCESSING", "SHIPPED", "COMPLETED"} {
+		if !canTransition(status, next) {
+			continue
+		}
+		if err := s.Transition(ctx, orderID, next); err != nil {
+			return err
+		}
+		status = next
+	}
+	if status != "COMPLETED" {
+		return ErrorInvalidTransition
+	}
+	return nil
+}
@@ -0,0 +1,1305 @@
+package Orders
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"savannah/src/Billing"
+	"savannah/src/Booking"
+	"savannah/src/Coupons"
+	"savannah/src/ErrorCodes"
+	"savannah/src/GiftCards"
+	"savannah/src/Inventory"
+	"savannah/src/Pagination"
+)
+
+// FileStore serves digital product files
+type FileStore interface {
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+}
+
+// presigner is implemented by stores that can hand out direct, short-lived URLs (S3)
+type presigner interface {
+	PresignGet(key string, ttl time.Duration) (string, error)
+}
+
+type Handler struct {
+	svc      Service
+	files    FileStore
+	room     *WaitingRoom
+	calendar Calendar
+	access   ClientAccess
+	attach   AttachmentAccess
+	log      *zap.Logger
+	v        *validator.Validate
+}
+
+// Calendar is how the tenant's reports cut time into weeks
+type Calendar struct {
+	// Zone returns the zone of the request, the tenant's unless the client
+	// asked for another; UTC when nil
+	Zone      func(ctx context.Context) *time.Location
+	WeekStart time.Weekday
+}
+
+func (c Calendar) zone(ctx context.Context) *time.Location {
+	if c.Zone == nil {
+		return time.UTC
+	}
+	return c.Zone(ctx)
+}
+
+func NewHandler(s Service, files FileStore, room *WaitingRoom, calendar Calendar, access ClientAccess, attach AttachmentAccess, log *zap.Logger) *Handler {
+	return &Handler{svc: s, files: files, room: room, calendar: calendar, access: access, attach: attach, log: log, v: validator.New()}
+}
+
+// CreateOrder godoc
+// @Summary      Place an order
+// @Description  Prices the items from the catalog, enforces customer purchase limits, reserves stock and authorizes payment. The order is in the items' currency; items priced in different currencies, or in another currency than the one asked for, are rejected with a 422 listing the lines. Items of bookable products name the slot they book (slot_id); a slot that is full or has started is a 409. A coupon_code takes the coupon's discount off the items; it shows as a discount line and is netted from the total, and a code that is unknown, expired, used up or doesn't apply to the items is a 422. A gift_card_code pays what the card's balance covers of the rest, also as a discount line, and payment_method pays the remainder. When the payment fails the order is kept as PAYMENT_FAILED and returned with a 402; retry with POST /orders/{id}/pay. Checkouts of waiting room products over its capacity are answered 202 with a queue ticket; poll GET /waiting-room/tickets/{token} and, once admitted, send the checkout again with the token in X-Queue-Token.
+// @Tags         orders
+// @Accept       json
+// @Produce      json
+// @Param        order          body      CreateOrderRequest  true   "Order"
+// @Param        X-Queue-Token  header    string              false  "Admitted waiting room ticket"
+// @Param        X-Device-ID    header    string              false  "Shopper's device id, kept with the order for fraud analysis"
+// @Success      201    {object}  Order
+// @Success      202    {object}  QueueTicket
+// @Failure      400    {object}  map[string]interface{}
+// @Failure      402    {object}  map[string]interface{}
+// @Failure      422    {object}  map[string]interface{}
+// @Router       /orders [post]
+func (h *Handler) CreateOrder(w http.ResponseWriter, r *http.Request) {
+	var dto CreateOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if err := h.v.Struct(dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	ids := make([]uuid.UUID, 0, len(dto.Items))
+	for _, it := range dto.Items {
+		ids = append(ids, *it.ProductID)
+	}
+	leave, ticket, err := h.room.Enter(r.Context(), ids, r.Header.Get("X-Queue-Token"))
+	if err != nil {
+		h.log.Error("waiting room", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to create order")
+		return
+	}
+	if ticket != nil {
+		h.writeTicket(w, ticket, http.StatusAccepted)
+		return
+	}
+	defer leave()
+	dto.Client = ClientFrom(r)
+	o, err := h.svc.Checkout(r.Context(), dto)
+	if err != nil {
+		var limit *LimitError
+		var payment *PaymentError
+		var mismatch *CurrencyError
+		switch {
+		case errors.As(err, &payment):
+			h.writePaymentError(w, payment)
+		case errors.As(err, &limit):
+			code, _ := ErrorCodes.For(err)
+			h.writeJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{"error": limit.Error(), "code": code, "limit": limit, "timestamp": time.Now().UTC()})
+		case errors.As(err, &mismatch):
+			code, _ := ErrorCodes.For(err)
+			h.writeJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{"error": mismatch.Error(), "code": code, "currency": mismatch.Currency, "lines": mismatch.Lines, "timestamp": time.Now().UTC()})
+		case errors.Is(err, Billing.ErrorInvalidTerms):
+			h.writeError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, ErrorUnknownProduct), errors.Is(err, ErrorUnknownVariant), errors.Is(err, Inventory.ErrorNoWarehouse), errors.Is(err, ErrorTermsNeedCustomer), errors.Is(err, ErrorMixedPreorder),
+			errors.Is(err, Booking.ErrorSlotRequired), errors.Is(err, Booking.ErrorWrongProduct), errors.Is(err, Booking.ErrorNotFound), Coupons.Rejected(err), GiftCards.Rejected(err):
+			h.writeError(w, http.StatusUnprocessableEntity, err.Error())
+		case errors.Is(err, Inventory.ErrorInsufficientStock), errors.Is(err, Booking.ErrorFull), errors.Is(err, Booking.ErrorStarted):
+			h.writeError(w, http.StatusConflict, err.Error())
+		case errors.Is(err, ErrorFraudRejected):
+			// the screening's reasons stay out of the response
+			h.log.Info("order declined by fraud screening", zap.Error(err))
+			h.writeError(w, http.StatusUnprocessableEntity, ErrorFraudRejected.Error())
+		default:
+			h.log.Error("create order", zap.Error(err))
+			h.writeError(w, http.StatusInternalServerError, "failed to create order")
+		}
+		return
+	}
+	h.writeJSON(w, http.StatusCreated, o)
+}
+
+// ListOrders godoc
+// @Summary      List orders
+// @Description  Newest first, filtered by status, warehouse, priority, source, channel, customer_id, items (sku, product_id), tag and created_at range
+// @Tags         orders
+// @Produce      json
+// @Param        status       query     string  false  "Order status"
+// @Param        warehouse    query     string  false  "Warehouse code"
+// @Param        priority     query     string  false  "standard or express"
+// @Param        source       query     string  false  "Import source"
+// @Param        channel      query     string  false  "Sales channel: web, mobile, pos, quote or marketplace-<source>"
+// @Param        customer_id  query     string  false  "Customer ID"
+// @Param        sku          query     string  false  "Only orders with an item of this SKU"
+// @Param        product_id   query     string  false  "Only orders with an item of this product"
+// @Param        tag          query     string  false  "Only orders with this tag"
+// @Param        from         query     string  false  "RFC3339 created_at lower bound"
+// @Param        to           query     string  false  "RFC3339 created_at upper bound"
+// @Param        limit        query     int     false  "Page size (max 100)"
+// @Param        offset       query     int     false  "Offset"
+// @Param        page         query     int     false  "Page of limit orders, 1-based; instead of offset"
+// @Success      200          {array}   Order
+// @Header       200          {string}  Link     "first, prev, next and last pages (RFC 5988), with X-Total-Count, X-Page and X-Total-Pages"
+// @Failure      400          {object}  map[string]interface{}
+// @Router       /orders [get]
+func (h *Handler) ListOrders(w http.ResponseWriter, r *http.Request) {
+	q, err := ParseListOrdersQuery(r.URL.Query())
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	orders, err := h.svc.List(r.Context(), q)
+	var total int
+	if err == nil {
+		total, err = h.svc.Count(r.Context(), q)
+	}
+	if err != nil {
+		h.log.Error("list orders", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to list orders")
+		return
+	}
+	Pagination.Write(w, r, q.Limit, q.Offset, total)
+	h.writeJSON(w, http.StatusOK, orders)
+}
+
+// GetOrder godoc
+// @Summary      Get order by ID
+// @Description  Returns the order with its items, its discount lines and, for paid digital orders, download links. Callers with one of the ORDER_CLIENT_ROLES also get the client context the order was placed from (IP, user agent, device id).
+// @Tags         orders
+// @Produce      json
+// @Param        id   path      string  true  "Order ID"
+// @Success      200  {object}  OrderResponse
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /orders/{id} [get]
+func (h *Handler) GetOrder(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	o, items, err := h.svc.Get(r.Context(), id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			h.writeError(w, http.StatusNotFound, "order not found")
+			return
+		}
+		h.log.Error("get order", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to get order")
+		return
+	}
+	links, err := h.svc.DownloadLinks(r.Context(), id)
+	if err != nil {
+		h.log.Error("list download links", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to get order")
+		return
+	}
+	resp := OrderResponse{Order: *o, Items: items, Downloads: links}
+	if h.access.allowed(r.Context()) {
+		if resp.Client, err = h.svc.Client(r.Context(), id); err != nil {
+			h.log.Error("get order client context", zap.Error(err))
+			h.writeError(w, http.StatusInternalServerError, "failed to get order")
+			return
+		}
+	}
+	h.writeJSON(w, http.StatusOK, resp)
+}
+
+// UpdateStatus godoc
+// @Summary      Change an order's status
+// @Description  Optimistically locked: version must match the order's current version, otherwise 409 and the client should reload
+// @Tags         orders
+// @Accept       json
+// @Param        id       path  string               true  "Order ID"
+// @Param        payload  body  UpdateStatusRequest  true  "New status and the version it applies to"
+// @Success      204
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      409  {object}  map[string]interface{}
+// @Failure      422  {object}  map[string]interface{}
+// @Router       /orders/{id}/status [post]
+func (h *Handler) UpdateStatus(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var dto UpdateStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if err := h.v.Struct(dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := h.svc.UpdateStatus(r.Context(), id, dto.Status, dto.Version); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			h.writeError(w, http.StatusNotFound, "order not found")
+		case errors.Is(err, ErrorVersionConflict):
+			h.writeError(w, http.StatusConflict, err.Error())
+		case errors.Is(err, ErrorInvalidTransition), errors.Is(err, ErrorOrderFrozen), errors.Is(err, ErrorArchived):
+			h.writeError(w, http.StatusUnprocessableEntity, err.Error())
+		default:
+			h.log.Error("update order status", zap.Error(err))
+			h.writeError(w, http.StatusInternalServerError, "failed to update status")
+		}
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CustomerCancel godoc
+// @Summary      Cancel an order on the customer's behalf
+// @Description  Allowed before PROCESSING and within the cancellation window (CUSTOMER_CANCEL_WINDOW). Captured payments are refunded; the cancellation is recorded on the timeline as CUSTOMER_CANCELLED. Use POST /orders/{id}/status for staff cancellations.
+// @Tags         orders
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                 true   "Customer ID"
+// @Param        orderId  path      string                 true   "Order ID"
+// @Param        payload  body      CustomerCancelRequest  false  "Reason"
+// @Success      200      {object}  Cancellation
+// @Failure      404      {object}  map[string]interface{}
+// @Failure      409      {object}  map[string]interface{}
+// @Failure      422      {object}  map[string]interface{}
+// @Router       /customers/{id}/orders/{orderId}/cancel [post]
+func (h *Handler) CustomerCancel(w http.ResponseWriter, r *http.Request) {
+	customerID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid customer id")
+		return
+	}
+	id, err := uuid.Parse(chi.URLParam(r, "orderId"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var dto CustomerCancelRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+			h.writeError(w, http.StatusBadRequest, "invalid json")
+			return
+		}
+	}
+	if err := h.v.Struct(dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	c, err := h.svc.CustomerCancel(r.Context(), id, customerID, dto.Reason)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			h.writeError(w, http.StatusNotFound, "order not found")
+		case errors.Is(err, ErrorVersionConflict):
+			h.writeError(w, http.StatusConflict, err.Error())
+		case errors.Is(err, ErrorNotCancellable), errors.Is(err, ErrorInvalidTransition), errors.Is(err, ErrorOrderFrozen), errors.Is(err, ErrorArchived):
+			h.writeError(w, http.StatusUnprocessableEntity, err.Error())
+		default:
+			h.log.Error("customer cancel order", zap.Error(err))
+			h.writeError(w, http.StatusInternalServerError, "failed to cancel order")
+		}
+		return
+	}
+	h.writeJSON(w, http.StatusOK, c)
+}
+
+// RetryPayment godoc
+// @Summary      Retry the payment of an order
+// @Description  Pays an order left PAYMENT_FAILED at checkout, optionally with a different payment method. Stock is reserved again and, once paid, the order continues as CREATED (CONFIRMED for offline methods). A failed retry answers 402 and leaves the order PAYMENT_FAILED.
+// @Tags         orders
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string               true   "Order ID"
+// @Param        payload  body      RetryPaymentRequest  false  "Payment method"
+// @Success      200      {object}  Order
+// @Failure      402      {object}  map[string]interface{}
+// @Failure      404      {object}  map[string]interface{}
+// @Failure      409      {object}  map[string]interface{}
+// @Failure      422      {object}  map[string]interface{}
+// @Router       /orders/{id}/pay [post]
+func (h *Handler) RetryPayment(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var dto RetryPaymentRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+			h.writeError(w, http.StatusBadRequest, "invalid json")
+			return
+		}
+	}
+	if err := h.v.Struct(dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	o, err := h.svc.RetryPayment(r.Context(), id, dto.PaymentMethod)
+	if err != nil {
+		var limit *LimitError
+		var payment *PaymentError
+		switch {
+		case errors.As(err, &payment):
+			h.writePaymentError(w, payment)
+		case errors.Is(err, sql.ErrNoRows):
+			h.writeError(w, http.StatusNotFound, "order not found")
+		case errors.As(err, &limit):
+			code, _ := ErrorCodes.For(err)
+			h.writeJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{"error": limit.Error(), "code": code, "limit": limit, "timestamp": time.Now().UTC()})
+		case errors.Is(err, ErrorNotAwaitingPay), errors.Is(err, ErrorVersionConflict), errors.Is(err, Inventory.ErrorInsufficientStock), errors.Is(err, Booking.ErrorFull), errors.Is(err, Booking.ErrorStarted):
+			h.writeError(w, http.StatusConflict, err.Error())
+		case errors.Is(err, ErrorOrderFrozen), errors.Is(err, ErrorArchived):
+			h.writeError(w, http.StatusUnprocessableEntity, err.Error())
+		default:
+			h.log.Error("retry order payment", zap.Error(err))
+			h.writeError(w, http.StatusInternalServerError, "failed to pay order")
+		}
+		return
+	}
+	h.writeJSON(w, http.StatusOK, o)
+}
+
+// writePaymentError answers 402 with the order kept as PAYMENT_FAILED, so
+// the client has its id for the retry
+func (h *Handler) writePaymentError(w http.ResponseWriter, e *PaymentError) {
+	code, _ := ErrorCodes.For(e)
+	h.writeJSON(w, http.StatusPaymentRequired, map[string]interface{}{"error": e.Error(), "code": code, "order": e.Order, "timestamp": time.Now().UTC()})
+}
+
+// Timeline godoc
+// @Summary      Order timeline
+// @Description  Events, payments, shipments, notes and notifications for the order in one chronological feed
+// @Tags         orders
+// @Produce      json
+// @Param        id   path      string  true  "Order ID"
+// @Success      200  {array}   TimelineEntry
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /orders/{id}/timeline [get]
+func (h *Handler) Timeline(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	entries, err := h.svc.Timeline(r.Context(), id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			h.writeError(w, http.StatusNotFound, "order not found")
+			return
+		}
+		h.log.Error("order timeline", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to get timeline")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, entries)
+}
+
+// AddNote godoc
+// @Summary      Add a note to an order
+// @Tags         orders
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string             true  "Order ID"
+// @Param        payload  body      CreateNoteRequest  true  "Note"
+// @Success      201      {object}  OrderNote
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      404      {object}  map[string]interface{}
+// @Router       /orders/{id}/notes [post]
+func (h *Handler) AddNote(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var dto CreateNoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if err := h.v.Struct(dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	note, err := h.svc.AddNote(r.Context(), id, dto)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			h.writeError(w, http.StatusNotFound, "order not found")
+			return
+		}
+		h.log.Error("add order note", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to add note")
+		return
+	}
+	h.writeJSON(w, http.StatusCreated, note)
+}
+
+// maxAttachment caps the size of an order attachment upload
+const maxAttachment = 25 << 20
+
+// AttachDocument godoc
+// @Summary      Attach a document to an order
+// @Description  Stores a customs form, proof of delivery, purchase order or other document with the order. Only callers with one of the ORDER_ATTACHMENT_WRITE_ROLES, or a plain ADMIN_API_KEYS key without a role, may attach; roles, comma-separated, limits who of the ORDER_ATTACHMENT_READ_ROLES may read it.
+// @Tags         orders
+// @Accept       mpfd
+// @Produce      json
+// @Param        id           path      string  true   "Order ID"
+// @Param        file         formData  file    true   "Document, at most 25 MB"
+// @Param        kind         formData  string  true   "CUSTOMS_FORM, DELIVERY_PROOF, PURCHASE_ORDER or OTHER"
+// @Param        description  formData  string  false  "Description"
+// @Param        roles        formData  string  false  "Roles that may read it"
+// @Success      201          {object}  OrderAttachment
+// @Failure      400          {object}  map[string]interface{}
+// @Failure      403          {object}  map[string]interface{}
+// @Failure      404          {object}  map[string]interface{}
+// @Failure      413          {object}  map[string]interface{}
+// @Router       /orders/{id}/attachments [post]
+func (h *Handler) AttachDocument(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	if !h.attach.canWrite(r.Context()) {
+		h.writeError(w, http.StatusForbidden, ErrorAttachmentForbidden.Error())
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxAttachment)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			h.writeError(w, http.StatusRequestEntityTooLarge, "attachment exceeds 25 MB")
+			return
+		}
+		h.writeError(w, http.StatusBadRequest, "file is required")
+		return
+	}
+	defer file.Close()
+	dto := AttachRequest{
+		Kind:        strings.ToUpper(strings.TrimSpace(r.FormValue("kind"))),
+		FileName:    header.Filename,
+		ContentType: header.Header.Get("Content-Type"),
+		Description: r.FormValue("description"),
+	}
+	if dto.ContentType == "" {
+		dto.ContentType = "application/octet-stream"
+	}
+	for _, role := range strings.Split(r.FormValue("roles"), ",") {
+		if role = strings.TrimSpace(role); role != "" {
+			dto.Roles = append(dto.Roles, role)
+		}
+	}
+	if err := h.v.Struct(dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	a, err := h.svc.Attach(r.Context(), id, dto, file, h.attach.role(r.Context()))
+	if err != nil {
+		h.attachmentError(w, "attach order document", err)
+		return
+	}
+	h.writeJSON(w, http.StatusCreated, a)
+}
+
+// ListAttachments godoc
+// @Summary      List an order's attachments
+// @Description  Lists the documents attached to the order that the caller's role may read, oldest first. Plain ADMIN_API_KEYS keys, which carry no role, see all of them.
+// @Tags         orders
+// @Produce      json
+// @Param        id   path      string  true  "Order ID"
+// @Success      200  {array}   OrderAttachment
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /orders/{id}/attachments [get]
+func (h *Handler) ListAttachments(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	if !h.attach.canList(r.Context()) {
+		h.writeError(w, http.StatusForbidden, ErrorAttachmentForbidden.Error())
+		return
+	}
+	all, err := h.svc.Attachments(r.Context(), id)
+	if err != nil {
+		h.attachmentError(w, "list order attachments", err)
+		return
+	}
+	out := make([]OrderAttachment, 0, len(all))
+	for _, a := range all {
+		if h.attach.canRead(r.Context(), a) {
+			out = append(out, a)
+		}
+	}
+	h.writeJSON(w, http.StatusOK, out)
+}
+
+// DownloadAttachment godoc
+// @Summary      Download an order attachment
+// @Description  Redirects to (or streams) the attached document
+// @Tags         orders
+// @Param        id   path  string  true  "Order ID"
+// @Param        aid  path  string  true  "Attachment ID"
+// @Success      200
+// @Success      302
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /orders/{id}/attachments/{aid} [get]
+func (h *Handler) DownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	a, ok := h.attachment(w, r)
+	if !ok {
+		return
+	}
+	if p, ok := h.files.(presigner); ok {
+		url, err := p.PresignGet(a.FileKey, 5*time.Minute)
+		if err == nil {
+			http.Redirect(w, r, url, http.StatusFound)
+			return
+		}
+		h.log.Error("presign attachment", zap.Error(err))
+	}
+	rc, err := h.files.Open(r.Context(), a.FileKey)
+	if err != nil {
+		h.log.Error("open attachment", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "download failed")
+		return
+	}
+	defer rc.Close()
+	w.Header().Set("Content-Type", a.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", a.FileName))
+	w.WriteHeader(http.StatusOK)
+	_, _ = io.Copy(w, rc)
+}
+
+// DeleteAttachment godoc
+// @Summary      Delete an order attachment
+// @Tags         orders
+// @Param        id   path  string  true  "Order ID"
+// @Param        aid  path  string  true  "Attachment ID"
+// @Success      204
+// @Failure      403  {object}  map[string]interface{}
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /orders/{id}/attachments/{aid} [delete]
+func (h *Handler) DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	if !h.attach.canWrite(r.Context()) {
+		h.writeError(w, http.StatusForbidden, ErrorAttachmentForbidden.Error())
+		return
+	}
+	a, ok := h.attachment(w, r)
+	if !ok {
+		return
+	}
+	if err := h.svc.DeleteAttachment(r.Context(), a.OrderID, a.ID); err != nil {
+		h.attachmentError(w, "delete order attachment", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// attachment loads the attachment of the path, answering 404 as well when
+// the caller's role may not read it
+func (h *Handler) attachment(w http.ResponseWriter, r *http.Request) (*OrderAttachment, bool) {
+	orderID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return nil, false
+	}
+	id, err := uuid.Parse(chi.URLParam(r, "aid"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid attachment id")
+		return nil, false
+	}
+	if !h.attach.canList(r.Context()) {
+		h.writeError(w, http.StatusForbidden, ErrorAttachmentForbidden.Error())
+		return nil, false
+	}
+	a, err := h.svc.Attachment(r.Context(), orderID, id)
+	if err == nil && !h.attach.canRead(r.Context(), *a) {
+		err = ErrorAttachmentNotFound
+	}
+	if err != nil {
+		h.attachmentError(w, "get order attachment", err)
+		return nil, false
+	}
+	return a, true
+}
+
+func (h *Handler) attachmentError(w http.ResponseWriter, op string, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		h.writeError(w, http.StatusNotFound, "order not found")
+	case errors.Is(err, ErrorAttachmentNotFound):
+		h.writeError(w, http.StatusNotFound, err.Error())
+	default:
+		h.log.Error(op, zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to "+op)
+	}
+}
+
+// Download godoc
+// @Summary      Download a purchased digital file
+// @Description  Validates the signed link, counts the download and redirects to (or streams) the file
+// @Tags         orders
+// @Param        id         path   string  true  "Download link ID"
+// @Param        expires    query  int     true  "Expiry (unix seconds)"
+// @Param        signature  query  string  true  "Link signature"
+// @Success      302
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      410  {object}  map[string]interface{}
+// @Router       /downloads/{id} [get]
+func (h *Handler) Download(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	expires, _ := strconv.ParseInt(r.URL.Query().Get("expires"), 10, 64)
+	link, err := h.svc.ConsumeDownload(r.Context(), id, expires, r.URL.Query().Get("signature"))
+	if err != nil {
+		switch err {
+		case ErrorDownloadNotFound:
+			h.writeError(w, http.StatusNotFound, err.Error())
+		case ErrorDownloadExpired:
+			h.writeError(w, http.StatusGone, err.Error())
+		default:
+			h.log.Error("consume download", zap.Error(err))
+			h.writeError(w, http.StatusInternalServerError, "download failed")
+		}
+		return
+	}
+	if p, ok := h.files.(presigner); ok {
+		url, err := p.PresignGet(link.FileKey, 5*time.Minute)
+		if err == nil {
+			http.Redirect(w, r, url, http.StatusFound)
+			return
+		}
+		h.log.Error("presign download", zap.Error(err))
+	}
+	rc, err := h.files.Open(r.Context(), link.FileKey)
+	if err != nil {
+		h.log.Error("open download", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "download failed")
+		return
+	}
+	defer rc.Close()
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", link.FileName))
+	w.WriteHeader(http.StatusOK)
+	_, _ = io.Copy(w, rc)
+}
+
+// ImportOrders godoc
+// @Summary      Bulk import externally captured orders
+// @Description  Creates orders pushed by marketplace integrations. Idempotent by source + external_reference; no payment is processed.
+// @Tags         orders
+// @Accept       json
+// @Produce      json
+// @Param        orders  body      ImportOrdersRequest  true  "Orders batch"
+// @Success      200     {array}   ImportOrderResult
+// @Failure      400     {object}  map[string]interface{}
+// @Router       /orders/import [post]
+func (h *Handler) ImportOrders(w http.ResponseWriter, r *http.Request) {
+	var dto ImportOrdersRequest
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if err := h.v.Struct(dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	results := h.svc.Import(r.Context(), dto.Orders)
+	h.writeJSON(w, http.StatusOK, map[string]interface{}{"results": results})
+}
+
+// SyncPOSOrders godoc
+// @Summary      Sync store till sales
+// @Description  Records orders rung up at a store, possibly offline. Each order keeps the till-generated id and local created_at, so resending a batch reports duplicates. Stock is deducted from the store warehouse; items sold beyond the available stock are listed as conflicts.
+// @Tags         orders
+// @Accept       json
+// @Produce      json
+// @Param        batch  body      POSBatchRequest  true  "Store sales batch"
+// @Success      200    {array}   POSOrderResult
+// @Failure      400    {object}  map[string]interface{}
+// @Router       /orders/pos [post]
+func (h *Handler) SyncPOSOrders(w http.ResponseWriter, r *http.Request) {
+	var dto POSBatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if err := h.v.Struct(dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	results := h.svc.POS(r.Context(), dto.Warehouse, dto.Orders)
+	h.writeJSON(w, http.StatusOK, map[string]interface{}{"results": results})
+}
+
+// PackingSlip godoc
+// @Summary      Packing slip
+// @Description  Printable packing slip with gift options and item notes
+// @Tags         orders
+// @Produce      html
+// @Produce      application/pdf
+// @Param        id      path   string  true   "Order ID"
+// @Param        format  query  string  false  "html (default) or pdf"
+// @Success      200
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /orders/{id}/packing-slip [get]
+func (h *Handler) PackingSlip(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	slip, err := h.svc.PackingSlip(r.Context(), id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			h.writeError(w, http.StatusNotFound, "order not found")
+			return
+		}
+		h.log.Error("packing slip", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to render packing slip")
+		return
+	}
+	if r.URL.Query().Get("format") == "pdf" {
+		h.writePDF(w, "packing-slip-"+id.String()+".pdf", slip.PDF())
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := slip.WriteHTML(w); err != nil {
+		h.log.Error("render packing slip", zap.Error(err))
+	}
+}
+
+// PickList godoc
+// @Summary      Warehouse pick list
+// @Description  Items of open orders in the warehouse aggregated by bin and product
+// @Tags         orders
+// @Produce      json
+// @Produce      html
+// @Produce      application/pdf
+// @Param        code    path   string  true   "Warehouse code"
+// @Param        format  query  string  false  "json (default), html or pdf"
+// @Success      200     {array}  PickListLine
+// @Router       /warehouses/{code}/pick-list [get]
+func (h *Handler) PickList(w http.ResponseWriter, r *http.Request) {
+	warehouse := chi.URLParam(r, "code")
+	lines, err := h.svc.PickList(r.Context(), warehouse)
+	if err != nil {
+		h.log.Error("pick list", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to build pick list")
+		return
+	}
+	switch r.URL.Query().Get("format") {
+	case "pdf":
+		h.writePDF(w, "pick-list-"+warehouse+".pdf", pickListPDF(warehouse, lines))
+	case "html":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := writePickListHTML(w, warehouse, lines); err != nil {
+			h.log.Error("render pick list", zap.Error(err))
+		}
+	default:
+		h.writeJSON(w, http.StatusOK, lines)
+	}
+}
+
+// Recall godoc
+// @Summary      Find the orders and customers hit by a product recall
+// @Description  Lists the orders with items of the SKU placed in [from, to), optionally in one warehouse, leaving out cancelled and unpaid ones, and their customers. Order items don't record their lot, so lot only labels the recall. With tag the orders are tagged (see the tag filter of the order list); with notify each customer gets message once by email, listing their orders.
+// @Tags         orders
+// @Accept       json
+// @Produce      json
+// @Param        recall  body      RecallRequest  true  "Recall"
+// @Success      200     {object}  Recall
+// @Failure      400     {object}  map[string]interface{}
+// @Router       /admin/recalls [post]
+func (h *Handler) Recall(w http.ResponseWriter, r *http.Request) {
+	var dto RecallRequest
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if err := h.v.Struct(dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	recall, err := h.svc.Recall(r.Context(), dto)
+	switch {
+	case err == nil:
+		h.writeJSON(w, http.StatusOK, recall)
+	case errors.Is(err, ErrorInvalidRange):
+		h.writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		h.log.Error("recall", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to run recall")
+	}
+}
+
+// Preorders godoc
+// @Summary      Pre-order queue
+// @Description  Pre-orders waiting for stock or their release date, grouped per product, soonest release first. They are allocated, charged and moved to PROCESSING automatically, oldest first.
+// @Tags         orders
+// @Produce      json
+// @Success      200  {array}  PreorderGroup
+// @Router       /preorders [get]
+func (h *Handler) Preorders(w http.ResponseWriter, r *http.Request) {
+	groups, err := h.svc.Preorders(r.Context())
+	if err != nil {
+		h.log.Error("pre-orders", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to list pre-orders")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, groups)
+}
+
+// WaitingRoom godoc
+// @Summary      Waiting room status
+// @Description  Capacity, checkouts running and admitted, queue length and the designated products and campaigns
+// @Tags         orders
+// @Produce      json
+// @Success      200  {object}  WaitingRoomStatus
+// @Router       /waiting-room [get]
+func (h *Handler) WaitingRoom(w http.ResponseWriter, r *http.Request) {
+	status, err := h.room.Status(r.Context())
+	if err != nil {
+		h.log.Error("waiting room status", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to get waiting room status")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, status)
+}
+
+// AddWaitingRoomTarget godoc
+// @Summary      Send checkouts of a product or campaign through the waiting room
+// @Description  A campaign covers its products and categories while it runs
+// @Tags         orders
+// @Produce      json
+// @Param        type  path      string  true  "products or campaigns"
+// @Param        id    path      string  true  "Product or campaign ID"
+// @Success      200   {object}  WaitingRoomTarget
+// @Failure      404   {object}  map[string]interface{}
+// @Router       /waiting-room/{type}/{id} [put]
+func (h *Handler) AddWaitingRoomTarget(w http.ResponseWriter, r *http.Request) {
+	targetType, id, ok := h.waitingRoomTarget(w, r)
+	if !ok {
+		return
+	}
+	t, err := h.room.AddTarget(r.Context(), targetType, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			h.writeError(w, http.StatusNotFound, targetType+" not found")
+			return
+		}
+		h.log.Error("add waiting room target", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to add waiting room target")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, t)
+}
+
+// RemoveWaitingRoomTarget godoc
+// @Summary      Stop sending checkouts of a product or campaign through the waiting room
+// @Tags         orders
+// @Param        type  path  string  true  "products or campaigns"
+// @Param        id    path  string  true  "Product or campaign ID"
+// @Success      204
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /waiting-room/{type}/{id} [delete]
+func (h *Handler) RemoveWaitingRoomTarget(w http.ResponseWriter, r *http.Request) {
+	targetType, id, ok := h.waitingRoomTarget(w, r)
+	if !ok {
+		return
+	}
+	if err := h.room.RemoveTarget(r.Context(), targetType, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			h.writeError(w, http.StatusNotFound, "waiting room target not found")
+			return
+		}
+		h.log.Error("remove waiting room target", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to remove waiting room target")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) waitingRoomTarget(w http.ResponseWriter, r *http.Request) (string, uuid.UUID, bool) {
+	types := map[string]string{"products": TargetProduct, "campaigns": TargetCampaign}
+	targetType, ok := types[chi.URLParam(r, "type")]
+	if !ok {
+		h.writeError(w, http.StatusBadRequest, "type must be products or campaigns")
+		return "", uuid.Nil, false
+	}
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return "", uuid.Nil, false
+	}
+	return targetType, id, true
+}
+
+// QueueTicket godoc
+// @Summary      Waiting room ticket
+// @Description  The position of a queued checkout; poll every retry_after seconds to keep the ticket. Once admitted, send the checkout again with the token in X-Queue-Token before expires_at.
+// @Tags         orders
+// @Produce      json
+// @Param        token  path      string  true  "Ticket token"
+// @Success      200    {object}  QueueTicket
+// @Failure      404    {object}  map[string]interface{}
+// @Router       /waiting-room/tickets/{token} [get]
+func (h *Handler) QueueTicket(w http.ResponseWriter, r *http.Request) {
+	ticket, ok := h.room.Ticket(chi.URLParam(r, "token"))
+	if !ok {
+		h.writeError(w, http.StatusNotFound, "ticket not found or expired")
+		return
+	}
+	h.writeTicket(w, ticket, http.StatusOK)
+}
+
+func (h *Handler) writeTicket(w http.ResponseWriter, t *QueueTicket, status int) {
+	w.Header().Set("Location", "/api/v1/waiting-room/tickets/"+t.Token)
+	if t.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(t.RetryAfter))
+	}
+	h.writeJSON(w, status, t)
+}
+
+// ListPurchaseLimits godoc
+// @Summary      List purchase limits
+// @Tags         orders
+// @Produce      json
+// @Success      200  {array}  PurchaseLimit
+// @Router       /purchase-limits [get]
+func (h *Handler) ListPurchaseLimits(w http.ResponseWriter, r *http.Request) {
+	limits, err := h.svc.ListPurchaseLimits(r.Context())
+	if err != nil {
+		h.log.Error("list purchase limits", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to list purchase limits")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, limits)
+}
+
+// SetPurchaseLimit godoc
+// @Summary      Limit how many units of a product one customer may buy per period
+// @Tags         orders
+// @Accept       json
+// @Produce      json
+// @Param        productId  path      string                   true  "Product ID"
+// @Param        limit      body      SetPurchaseLimitRequest  true  "Limit"
+// @Success      200        {object}  PurchaseLimit
+// @Failure      400        {object}  map[string]interface{}
+// @Router       /purchase-limits/{productId} [put]
+func (h *Handler) SetPurchaseLimit(w http.ResponseWriter, r *http.Request) {
+	productID, err := uuid.Parse(chi.URLParam(r, "productId"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid product id")
+		return
+	}
+	var dto SetPurchaseLimitRequest
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if err := h.v.Struct(dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	limit, err := h.svc.SetPurchaseLimit(r.Context(), productID, dto)
+	if err != nil {
+		h.log.Error("set purchase limit", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to set purchase limit")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, limit)
+}
+
+// DeletePurchaseLimit godoc
+// @Summary      Remove a product purchase limit
+// @Tags         orders
+// @Param        productId  path  string  true  "Product ID"
+// @Success      204
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /purchase-limits/{productId} [delete]
+func (h *Handler) DeletePurchaseLimit(w http.ResponseWriter, r *http.Request) {
+	productID, err := uuid.Parse(chi.URLParam(r, "productId"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid product id")
+		return
+	}
+	if err := h.svc.DeletePurchaseLimit(r.Context(), productID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			h.writeError(w, http.StatusNotFound, "purchase limit not found")
+			return
+		}
+		h.log.Error("delete purchase limit", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to delete purchase limit")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SLAOrders godoc
+// @Summary      Orders breaching or nearing their SLA
+// @Description  Open orders past their SLA deadline or due within the window, most overdue first
+// @Tags         orders
+// @Produce      json
+// @Param        within  query     string  false  "Look-ahead window, e.g. 2h (default 4h)"
+// @Success      200     {array}   SLAOrder
+// @Failure      400     {object}  map[string]interface{}
+// @Router       /orders/sla [get]
+func (h *Handler) SLAOrders(w http.ResponseWriter, r *http.Request) {
+	within := 4 * time.Hour
+	if v := r.URL.Query().Get("within"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			h.writeError(w, http.StatusBadRequest, "invalid within")
+			return
+		}
+		within = d
+	}
+	orders, err := h.svc.SLAOrders(r.Context(), within)
+	if err != nil {
+		h.log.Error("list sla orders", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to list orders")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, orders)
+}
+
+// SLAMetrics godoc
+// @Summary      SLA attainment
+// @Description  Share of orders that left each status within its SLA, per priority
+// @Tags         orders
+// @Produce      json
+// @Param        from  query     string  false  "RFC3339 start (default 30 days ago)"
+// @Param        to    query     string  false  "RFC3339 end (default now)"
+// @Success      200   {array}   SLAAttainment
+// @Failure      400   {object}  map[string]interface{}
+// @Router       /orders/sla/metrics [get]
+func (h *Handler) SLAMetrics(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	to := time.Now().UTC()
+	from := to.AddDate(0, 0, -30)
+	var err error
+	if v := q.Get("from"); v != "" {
+		if from, err = time.Parse(time.RFC3339, v); err != nil {
+			h.writeError(w, http.StatusBadRequest, "invalid from")
+			return
+		}
+	}
+	if v := q.Get("to"); v != "" {
+		if to, err = time.Parse(time.RFC3339, v); err != nil {
+			h.writeError(w, http.StatusBadRequest, "invalid to")
+			return
+		}
+	}
+	metrics, err := h.svc.SLAMetrics(r.Context(), from, to)
+	if err != nil {
+		h.log.Error("sla metrics", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to compute sla metrics")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, metrics)
+}
+
+// OrderStatistics godoc
+// @Summary      Order statistics
+// @Description  Order counts, revenue and gross margin per currency, sales channel, status and UTC day for [from, to). Both bounds are required and the window may span at most 366 days. With weekly=true orders and revenue are also summed per week of the request's timezone (tz, else the tenant's), beginning on week_start (default the tenant's).
+// @Tags         orders
+// @Produce      json
+// @Param        from        query     string  true   "Start day (YYYY-MM-DD)"
+// @Param        to          query     string  true   "End day, exclusive (YYYY-MM-DD)"
+// @Param        weekly      query     bool    false  "Add weekly totals"
+// @Param        week_start  query     string  false  "Day weeks begin on, e.g. monday or sunday"
+// @Param        tz          query     string  false  "IANA timezone, or local"
+// @Success      200   {object}  OrderStatistics
+// @Failure      400   {object}  map[string]interface{}
+// @Failure      422   {object}  map[string]interface{}
+// @Router       /orders/statistics [get]
+func (h *Handler) OrderStatistics(w http.ResponseWriter, r *http.Request) {
+	from, to, ok := h.statsRange(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	weekly := q.Get("weekly") == "true" || q.Get("week_start") != ""
+	weekStart := h.calendar.WeekStart
+	if v := q.Get("week_start"); v != "" {
+		d, err := ParseWeekday(v)
+		if err != nil {
+			h.writeError(w, http.StatusBadRequest, "invalid week_start, expected a day such as monday")
+			return
+		}
+		weekStart = d
+	}
+	stats, err := h.svc.GetOrderStatistics(r.Context(), from, to)
+	if err != nil {
+		h.writeStatsError(w, err, "order statistics")
+		return
+	}
+	if weekly {
+		loc := h.calendar.zone(r.Context())
+		if stats.Weekly, err = h.svc.WeeklyStatistics(r.Context(), from, to, loc, weekStart); err != nil {
+			h.writeStatsError(w, err, "weekly order statistics")
+			return
+		}
+		stats.Timezone, stats.WeekStart = loc.String(), strings.ToLower(weekStart.String())
+	}
+	h.writeJSON(w, http.StatusOK, stats)
+}
+
+// Margins godoc
+// @Summary      Gross margin report
+// @Description  Gross margin per order or per product (and currency) for orders placed in [from, to), excluding cancelled and unpaid orders. Margins use the cost captured on each item when the order was placed; items without a cost are counted in uncosted_items and left out of the margin. The window may span at most 366 days.
+// @Tags         orders
+// @Produce      json
+// @Param        from      query     string  true   "Start day (YYYY-MM-DD)"
+// @Param        to        query     string  true   "End day, exclusive (YYYY-MM-DD)"
+// @Param        group_by  query     string  false  "order (default) or product"
+// @Param        limit     query     int     false  "Page size (max 500)"
+// @Param        offset    query     int     false  "Offset"
+// @Success      200       {array}   OrderMargin
+// @Failure      400       {object}  map[string]interface{}
+// @Failure      422       {object}  map[string]interface{}
+// @Router       /orders/margins [get]
+func (h *Handler) Margins(w http.ResponseWriter, r *http.Request) {
+	from, to, ok := h.statsRange(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+	offset, _ := strconv.Atoi(q.Get("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+	var (
+		rows interface{}
+		err  error
+	)
+	switch q.Get("group_by") {
+	case "", "order":
+		rows, err = h.svc.OrderMargins(r.Context(), from, to, limit, offset)
+	case "product":
+		rows, err = h.svc.ProductMargins(r.Context(), from, to, limit, offset)
+	default:
+		h.writeError(w, http.StatusBadRequest, "group_by must be order or product")
+		return
+	}
+	if err != nil {
+		h.writeStatsError(w, err, "margin report")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, rows)
+}
+
+// BackfillStatistics godoc
+// @Summary      Rebuild order statistics rollups
+// @Description  Re-aggregates the daily rollups for [from, to); at most 366 days per call
+// @Tags         orders
+// @Param        from  query  string  true  "Start day (YYYY-MM-DD)"
+// @Param        to    query  string  true  "End day, exclusive (YYYY-MM-DD)"
+// @Success      204
+// @Failure      422   {object}  map[string]interface{}
+// @Router       /orders/statistics/rollup [post]
+func (h *Handler) BackfillStatistics(w http.ResponseWriter, r *http.Request) {
+	from, to, ok := h.statsRange(w, r)
+	if !ok {
+		return
+	}
+	if err := h.svc.BackfillStatistics(r.Context(), from, to); err != nil {
+		h.writeStatsError(w, err, "backfill order statistics")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// statsRange parses the required from/to days; an open-ended range is rejected with 422
+func (h *Handler) statsRange(w http.ResponseWriter, r *http.Request) (time.Time, time.Time, bool) {
+	q := r.URL.Query()
+	if q.Get("from") == "" || q.Get("to") == "" {
+		h.writeJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error":     ErrorRangeRequired.Error(),
+			"code":      ErrorCodes.Resolve("ORDERS", http.StatusUnprocessableEntity, ErrorRangeRequired.Error()),
+			"guidance":  fmt.Sprintf("query at most %d days at a time, e.g. ?from=2024-01-01&to=2024-02-01", MaxStatsDays),
+			"max_days":  MaxStatsDays,
+			"timestamp": time.Now().UTC(),
+		})
+		return time.Time{}, time.Time{}, false
+	}
+	from, err := time.Parse("2006-01-02", q.Get("from"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid from, expected YYYY-MM-DD")
+		return time.Time{}, time.Time{}, false
+	}
+	to, err := time.Parse("2006-01-02", q.Get("to"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid to, expected YYYY-MM-DD")
+		return time.Time{}, time.Time{}, false
+	}
+	return from, to, true
+}
+
+func (h *Handler) writeStatsError(w http.ResponseWriter, err error, action string) {
+	var rangeErr *StatsRangeError
+	switch {
+	case errors.As(err, &rangeErr):
+		code, _ := ErrorCodes.For(err)
+		h.writeJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error":          "statistics range too large",
+			"code":           code,
+			"guidance":       rangeErr.Error(),
+			"max_days":       rangeErr.MaxDays,
+			"requested_days": rangeErr.RequestedDays,
+			"timestamp":      time.Now().UTC(),
+		})
+	case errors.Is(err, ErrorInvalidRange):
+		h.writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		h.log.Error(action, zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to "+action)
+	}
+}
+
+func (h *Handler) writePDF(w http.ResponseWriter, filename string, body []byte) {
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(body)
+}
+
+func (h *Handler) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (h *Handler) writeError(w http.ResponseWriter, status int, msg string) {
+	h.writeJSON(w, status, map[string]interface{}{"error": msg, "code": ErrorCodes.Resolve("ORDERS", status, msg), "timestamp": time.Now().UTC()})
+}
@@ -0,0 +1,160 @@
+package Orders
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// Limits holds the customer order limits that are not per product
+type Limits struct {
+	MaxOpenOrders int // 0 disables the check
+	// how long after placing an order the customer may cancel it; 0 only
+	// keeps the status rule (before PROCESSING)
+	CancelWindow time.Duration
+}
+
+// statuses counted against MaxOpenOrders: open orders and pre-orders
+var limitedStatuses = append([]string{StatusPreorder}, openStatuses...)
+
+// statuses whose orders don't count towards purchase limits
+var voidStatuses = []string{"CANCELLED", "PAYMENT_FAILED"}
+
+// checkLimits enforces the open-orders limit and per-product quantity limits
+// for a customer checkout.
+func (s *service) checkLimits(ctx context.Context, customerID uuid.UUID, items []OrderItem) error {
+	if s.limits.MaxOpenOrders > 0 {
+		open, err := s.repo.CountOrders(ctx, customerID, limitedStatuses)
+		if err != nil {
+			return err
+		}
+		if open >= s.limits.MaxOpenOrders {
+			return &LimitError{Code: LimitOpenOrders, Limit: s.limits.MaxOpenOrders, Used: open, Requested: 1}
+		}
+	}
+
+	requested := map[uuid.UUID]int{}
+	ids := []uuid.UUID{}
+	for _, it := range items {
+		if it.ProductID == nil {
+			continue
+		}
+		if _, seen := requested[*it.ProductID]; !seen {
+			ids = append(ids, *it.ProductID)
+		}
+		requested[*it.ProductID] += it.Quantity
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	limits, err := s.repo.PurchaseLimits(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, l := range limits {
+		since := time.Now().UTC().Add(-time.Duration(l.PeriodHours) * time.Hour)
+		used, err := s.repo.PurchasedQuantity(ctx, customerID, l.ProductID, since, voidStatuses)
+		if err != nil {
+			return err
+		}
+		if used+requested[l.ProductID] > l.MaxQuantity {
+			productID := l.ProductID
+			return &LimitError{Code: LimitProductQuantity, ProductID: &productID, Limit: l.MaxQuantity, Used: used, Requested: requested[l.ProductID], Hours: l.PeriodHours}
+		}
+	}
+	return nil
+}
+
+// Checkout prices the items from the catalog and creates the order in
+// their currency. The catalog prices each product in one currency, so a
+// basket mixing currencies, or priced in another currency than the one
+// asked for, is rejected with a CurrencyError listing the offending lines.
+// Lines of bookable products must name the slot they book.
+func (s *service) Checkout(ctx context.Context, req CreateOrderRequest) (*Order, error) {
+	ids := make([]uuid.UUID, 0, len(req.Items))
+	var variantIDs []uuid.UUID
+	for _, it := range req.Items {
+		ids = append(ids, *it.ProductID)
+		if it.VariantID != nil {
+			variantIDs = append(variantIDs, *it.VariantID)
+		}
+	}
+	prices, err := s.repo.CatalogPrices(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]CatalogPrice, len(prices))
+	for _, p := range prices {
+		byID[p.ProductID] = p
+	}
+	byVariant := make(map[uuid.UUID]CatalogPrice, len(variantIDs))
+	if len(variantIDs) > 0 {
+		variants, err := s.repo.VariantPrices(ctx, variantIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range variants {
+			byVariant[*v.VariantID] = v
+		}
+	}
+	currency := strings.ToUpper(req.Currency)
+	var mismatched []CurrencyMismatch
+	items := make([]OrderItem, 0, len(req.Items))
+	for i, it := range req.Items {
+		p, ok := byID[*it.ProductID]
+		if !ok {
+			return nil, ErrorUnknownProduct
+		}
+		if it.VariantID != nil {
+			if p, ok = byVariant[*it.VariantID]; !ok || p.ProductID != *it.ProductID {
+				return nil, ErrorUnknownVariant
+			}
+		}
+		if currency == "" {
+			currency = p.Currency
+		}
+		if p.Currency != currency {
+			mismatched = append(mismatched, CurrencyMismatch{Line: i, ProductID: *it.ProductID, VariantID: it.VariantID, Currency: p.Currency})
+		}
+		sku, name := p.SKU, p.Name
+		items = append(items, OrderItem{
+			ProductID:    it.ProductID,
+			VariantID:    it.VariantID,
+			SKU:          &sku,
+			Name:         &name,
+			UnitPrice:    p.Price,
+			Quantity:     it.Quantity,
+			LineTotal:    p.Price.Mul(decimal.NewFromInt(int64(it.Quantity))),
+			GiftWrap:     it.GiftWrap,
+			GiftMessage:  it.GiftMessage,
+			CustomerNote: it.CustomerNote,
+			SlotID:       it.SlotID,
+		})
+	}
+	if len(mismatched) > 0 {
+		return nil, &CurrencyError{Currency: currency, Lines: mismatched}
+	}
+	if err := s.requireSlots(ctx, items); err != nil {
+		return nil, err
+	}
+	return s.Create(ctx, req.CustomerID, items, Checkout{Warehouse: req.Warehouse, Country: req.Country, Region: req.Region, PaymentMethod: req.PaymentMethod, Priority: req.Priority, Channel: req.Channel, Currency: currency, PONumber: req.PONumber, PaymentTerms: req.PaymentTerms, HoldID: req.HoldID, CouponCode: req.CouponCode, GiftCardCode: req.GiftCardCode, Client: req.Client})
+}
+
+func (s *service) ListPurchaseLimits(ctx context.Context) ([]PurchaseLimit, error) {
+	return s.repo.ListPurchaseLimits(ctx)
+}
+
+func (s *service) SetPurchaseLimit(ctx context.Context, productID uuid.UUID, req SetPurchaseLimitRequest) (*PurchaseLimit, error) {
+	l := &PurchaseLimit{ProductID: productID, MaxQuantity: req.MaxQuantity, PeriodHours: req.PeriodHours}
+	if err := s.repo.UpsertPurchaseLimit(ctx, l); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (s *service) DeletePurchaseLimit(ctx context.Context, productID uuid.UUID) error {
+	return s.repo.DeletePurchaseLimit(ctx, productID)
+}
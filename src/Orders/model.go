@@ -4,30 +4,332 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx/types"
 	"github.com/shopspring/decimal"
+	"savannah/src/Billing"
 )
 
 type Order struct {
-	ID         uuid.UUID       `db:"id" json:"id"`
-	CustomerID *uuid.UUID      `db:"customer_id" json:"customer_id,omitempty"`
-	Status     string          `db:"status" json:"status"`
-	Subtotal   decimal.Decimal `db:"subtotal" json:"subtotal"`
-	Tax        decimal.Decimal `db:"tax" json:"tax"`
-	Shipping   decimal.Decimal `db:"shipping" json:"shipping"`
-	Total      decimal.Decimal `db:"total" json:"total"`
-	Currency   string          `db:"currency" json:"currency"`
-	CreatedAt  time.Time       `db:"created_at" json:"created_at"`
-	UpdatedAt  time.Time       `db:"updated_at" json:"updated_at"`
-	Version    int             `db:"version" json:"version"`
+	ID                uuid.UUID       `db:"id" json:"id"`
+	CustomerID        *uuid.UUID      `db:"customer_id" json:"customer_id,omitempty"`
+	Status            string          `db:"status" json:"status"`
+	Subtotal          decimal.Decimal `db:"subtotal" json:"subtotal"`
+	Tax               decimal.Decimal `db:"tax" json:"tax"`
+	Shipping          decimal.Decimal `db:"shipping" json:"shipping"`
+	Discount          decimal.Decimal `db:"discount" json:"discount"` // taken off by coupons and gift cards, see Discounts
+	Total             decimal.Decimal `db:"total" json:"total"`
+	Currency          string          `db:"currency" json:"currency"`
+	ExternalReference *string         `db:"external_reference" json:"external_reference,omitempty"`
+	Source            *string         `db:"source" json:"source,omitempty"`
+	Channel           string          `db:"channel" json:"channel"`
+	Warehouse         *string         `db:"warehouse" json:"warehouse,omitempty"`
+	Country           *string         `db:"country" json:"country,omitempty"`
+	Region            *string         `db:"region" json:"region,omitempty"`
+	PaymentMethod     *string         `db:"payment_method" json:"payment_method,omitempty"`
+	PONumber          *string         `db:"po_number" json:"po_number,omitempty"`         // the B2B buyer's purchase order
+	PaymentTerms      *string         `db:"payment_terms" json:"payment_terms,omitempty"` // net terms such as NET30
+	Priority          string          `db:"priority" json:"priority"`
+	SLADueAt          *time.Time      `db:"sla_due_at" json:"sla_due_at,omitempty"`
+	FrozenAt          *time.Time      `db:"frozen_at" json:"frozen_at,omitempty"`
+	FrozenReason      *string         `db:"frozen_reason" json:"frozen_reason,omitempty"`
+	CreatedAt         time.Time       `db:"created_at" json:"created_at"`
+	UpdatedAt         time.Time       `db:"updated_at" json:"updated_at"`
+	Version           int             `db:"version" json:"version"`
+	// read from cold storage; an archived order can no longer change
+	Archived bool `db:"-" json:"archived,omitempty"`
+	// the discount lines making up Discount
+	Discounts []OrderDiscount `db:"-" json:"discounts,omitempty"`
+}
+
+// OrderDiscount is a discount line of an order, a coupon or a gift card
+// paying part of it, as it was applied at checkout
+type OrderDiscount struct {
+	ID          uuid.UUID       `db:"id" json:"id"`
+	OrderID     uuid.UUID       `db:"order_id" json:"order_id"`
+	CouponID    *uuid.UUID      `db:"coupon_id" json:"coupon_id,omitempty"`
+	GiftCardID  *uuid.UUID      `db:"gift_card_id" json:"gift_card_id,omitempty"`
+	Code        string          `db:"code" json:"code"`
+	Description string          `db:"description" json:"description"`
+	Amount      decimal.Decimal `db:"amount" json:"amount"`
+	CreatedAt   time.Time       `db:"created_at" json:"created_at"`
+}
+
+// Sales channels an order can be placed through; imported orders use MarketplaceChannel
+const (
+	ChannelWeb    = "web"
+	ChannelMobile = "mobile"
+	ChannelPOS    = "pos"
+	ChannelQuote  = "quote"
+)
+
+// MarketplaceChannel is the channel of orders imported from a marketplace source
+func MarketplaceChannel(source string) string {
+	return "marketplace-" + source
 }
 
 type OrderItem struct {
 	ID        uuid.UUID       `db:"id" json:"id"`
 	OrderID   uuid.UUID       `db:"order_id" json:"order_id"`
 	ProductID *uuid.UUID      `db:"product_id" json:"product_id,omitempty"`
+	VariantID *uuid.UUID      `db:"variant_id" json:"variant_id,omitempty"`
 	SKU       *string         `db:"sku" json:"sku,omitempty"`
 	Name      *string         `db:"name" json:"name,omitempty"`
 	UnitPrice decimal.Decimal `db:"unit_price" json:"unit_price"`
 	Quantity  int             `db:"quantity" json:"quantity"`
 	LineTotal decimal.Decimal `db:"line_total" json:"line_total"`
+	// product cost when the order was placed; nil when the product had none
+	UnitCost     *decimal.Decimal `db:"unit_cost" json:"unit_cost,omitempty"`
+	GiftWrap     bool             `db:"gift_wrap" json:"gift_wrap"`
+	GiftMessage  *string          `db:"gift_message" json:"gift_message,omitempty"`
+	CustomerNote *string          `db:"customer_note" json:"customer_note,omitempty"`
+	// the booking slot of a bookable product
+	SlotID *uuid.UUID `db:"slot_id" json:"slot_id,omitempty"`
+}
+
+// stockID is what inventory tracks the item under: its variant when it has one
+func (it OrderItem) stockID() uuid.UUID {
+	if it.VariantID != nil {
+		return *it.VariantID
+	}
+	return *it.ProductID
+}
+
+// DownloadLink grants time- and count-limited access to a digital product file
+type DownloadLink struct {
+	ID            uuid.UUID `db:"id" json:"id"`
+	OrderID       uuid.UUID `db:"order_id" json:"order_id"`
+	FileID        uuid.UUID `db:"file_id" json:"file_id"`
+	FileName      string    `db:"file_name" json:"file_name"`
+	FileKey       string    `db:"file_key" json:"-"`
+	ExpiresAt     time.Time `db:"expires_at" json:"expires_at"`
+	MaxDownloads  int       `db:"max_downloads" json:"max_downloads"`
+	DownloadCount int       `db:"download_count" json:"download_count"`
+	CreatedAt     time.Time `db:"created_at" json:"created_at"`
+	URL           string    `db:"-" json:"url"`
+}
+
+// PickListLine is the quantity of one product to pick from a bin for open orders
+type PickListLine struct {
+	ProductID   uuid.UUID `db:"product_id" json:"product_id"`
+	SKU         string    `db:"sku" json:"sku"`
+	Name        string    `db:"name" json:"name"`
+	BinLocation string    `db:"bin_location" json:"bin_location"`
+	Quantity    int       `db:"quantity" json:"quantity"`
+	GiftWrap    int       `db:"gift_wrap" json:"gift_wrap"`
+	Orders      int       `db:"orders" json:"orders"`
+}
+
+// order event types
+const (
+	EventOrderCreated  = "ORDER_CREATED"
+	EventOrderImported = "ORDER_IMPORTED"
+	EventStatusChanged = "STATUS_CHANGED"
+	EventFrozen        = "FROZEN"
+	EventUnfrozen      = "UNFROZEN"
+	// the customer cancelled; data has who, why and what was refunded
+	EventCustomerCancelled = "CUSTOMER_CANCELLED"
+	// a payment retry on a PAYMENT_FAILED order; data has the method and outcome
+	EventPaymentRetried = "PAYMENT_RETRIED"
+	// tagged or untagged in bulk, e.g. by a recall; data has the tag and why
+	EventTagged   = "TAGGED"
+	EventUntagged = "UNTAGGED"
+)
+
+// RecallOrder is an order holding units of a recalled SKU
+type RecallOrder struct {
+	OrderID    uuid.UUID  `db:"order_id" json:"order_id"`
+	CustomerID *uuid.UUID `db:"customer_id" json:"customer_id,omitempty"`
+	Status     string     `db:"status" json:"status"`
+	Warehouse  *string    `db:"warehouse" json:"warehouse,omitempty"`
+	Quantity   int        `db:"quantity" json:"quantity"`
+	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+}
+
+// Recall lists the orders and customers a recall reaches and what was done
+// about them
+type Recall struct {
+	SKU       string        `json:"sku"`
+	Lot       string        `json:"lot,omitempty"`
+	From      time.Time     `json:"from"`
+	To        time.Time     `json:"to"`
+	Orders    []RecallOrder `json:"orders"`
+	Customers []uuid.UUID   `json:"customers"`
+	// orders without a customer; there is no one to notify
+	GuestOrders int    `json:"guest_orders"`
+	Tag         string `json:"tag,omitempty"`
+	Tagged      int    `json:"tagged"`
+	Notified    int    `json:"notified"`
+	// customers the notification could not be sent to; see the notification log
+	NotifyFailed []uuid.UUID `json:"notify_failed,omitempty"`
+}
+
+// timeline entry types
+const (
+	TimelineEvent        = "event"
+	TimelinePayment      = "payment"
+	TimelineRefund       = "refund"
+	TimelineShipment     = "shipment"
+	TimelineNote         = "note"
+	TimelineNotification = "notification"
+)
+
+type OrderNote struct {
+	ID        uuid.UUID `db:"id" json:"id"`
+	OrderID   uuid.UUID `db:"order_id" json:"order_id"`
+	Author    string    `db:"author" json:"author"`
+	Body      string    `db:"body" json:"body"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+// TimelineEntry is one item of an order's history; Type says which source it
+// came from and Data carries the source-specific fields.
+type TimelineEntry struct {
+	Type    string         `db:"type" json:"type"`
+	ID      uuid.UUID      `db:"id" json:"id"`
+	At      time.Time      `db:"at" json:"at"`
+	Summary string         `db:"summary" json:"summary"`
+	Data    types.JSONText `db:"data" json:"data"`
+}
+
+// PurchaseLimit caps how many units of a product one customer may buy
+// within a rolling period.
+type PurchaseLimit struct {
+	ProductID   uuid.UUID `db:"product_id" json:"product_id"`
+	MaxQuantity int       `db:"max_quantity" json:"max_quantity"`
+	PeriodHours int       `db:"period_hours" json:"period_hours"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt   time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// CatalogPrice is the price a product or variant sells at now: the catalog
+// price (a variant's own price when it overrides the product's), or the sale
+// price while a campaign discounts the product
+type CatalogPrice struct {
+	ProductID uuid.UUID       `db:"id"`
+	VariantID *uuid.UUID      `db:"variant_id"`
+	SKU       string          `db:"sku"`
+	Name      string          `db:"name"`
+	Price     decimal.Decimal `db:"price"`
+	Currency  string          `db:"currency"`
+}
+
+// SLAOrder is an open order whose SLA deadline has passed or is close
+type SLAOrder struct {
+	ID        uuid.UUID `db:"id" json:"id"`
+	Status    string    `db:"status" json:"status"`
+	Priority  string    `db:"priority" json:"priority"`
+	Warehouse *string   `db:"warehouse" json:"warehouse,omitempty"`
+	SLADueAt  time.Time `db:"sla_due_at" json:"sla_due_at"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	Breached  bool      `db:"-" json:"breached"`
+}
+
+// DailyOrderStats is one row of the order_daily_stats rollup. Cost and
+// CostedSales only cover items placed with a known cost; the others are
+// counted in UncostedItems.
+type DailyOrderStats struct {
+	Day           time.Time       `db:"day" json:"day"`
+	Currency      string          `db:"currency" json:"currency"`
+	Status        string          `db:"status" json:"status"`
+	Channel       string          `db:"channel" json:"channel"`
+	Orders        int             `db:"orders" json:"orders"`
+	Revenue       decimal.Decimal `db:"revenue" json:"revenue"`
+	Cost          decimal.Decimal `db:"cost" json:"cost"`
+	CostedSales   decimal.Decimal `db:"costed_sales" json:"costed_sales"`
+	UncostedItems int             `db:"uncosted_items" json:"uncosted_items"`
+}
+
+// Margin is the gross margin on the sales of items with a known cost
+type Margin struct {
+	CostedSales   decimal.Decimal `db:"costed_sales" json:"costed_sales"`
+	Cost          decimal.Decimal `db:"cost" json:"cost"`
+	GrossMargin   decimal.Decimal `db:"-" json:"gross_margin"`
+	MarginPercent *float64        `db:"-" json:"margin_percent,omitempty"`
+	UncostedItems int             `db:"uncosted_items" json:"uncosted_items"`
+}
+
+// OrderMargin is the gross margin of one order; Revenue is the order total
+type OrderMargin struct {
+	OrderID   uuid.UUID       `db:"order_id" json:"order_id"`
+	CreatedAt time.Time       `db:"created_at" json:"created_at"`
+	Status    string          `db:"status" json:"status"`
+	Channel   string          `db:"channel" json:"channel"`
+	Currency  string          `db:"currency" json:"currency"`
+	Revenue   decimal.Decimal `db:"revenue" json:"revenue"`
+	Margin
+}
+
+// ProductMargin is the gross margin of a product over a period, per currency
+type ProductMargin struct {
+	ProductID uuid.UUID       `db:"product_id" json:"product_id"`
+	SKU       *string         `db:"sku" json:"sku,omitempty"`
+	Name      *string         `db:"name" json:"name,omitempty"`
+	Currency  string          `db:"currency" json:"currency"`
+	Quantity  int             `db:"quantity" json:"quantity"`
+	Sales     decimal.Decimal `db:"sales" json:"sales"`
+	Margin
+}
+
+// CurrencyTotals sums the statistics window for one currency
+type CurrencyTotals struct {
+	Currency          string          `json:"currency"`
+	Orders            int             `json:"orders"`
+	Revenue           decimal.Decimal `json:"revenue"`
+	AverageOrderValue decimal.Decimal `json:"average_order_value"`
+	Margin
+}
+
+// ChannelTotals sums the statistics window for one sales channel and currency
+type ChannelTotals struct {
+	Channel           string          `json:"channel"`
+	Currency          string          `json:"currency"`
+	Orders            int             `json:"orders"`
+	Revenue           decimal.Decimal `json:"revenue"`
+	AverageOrderValue decimal.Decimal `json:"average_order_value"`
+	Margin
+}
+
+// OrderStatistics summarises orders placed in [From, To)
+type OrderStatistics struct {
+	From      time.Time         `json:"from"`
+	To        time.Time         `json:"to"`
+	Totals    []CurrencyTotals  `json:"totals"`
+	ByChannel []ChannelTotals   `json:"by_channel"`
+	ByStatus  map[string]int    `json:"by_status"`
+	Daily     []DailyOrderStats `json:"daily"`
+	// asked for with weekly=true: weeks of Timezone starting on WeekStart
+	Timezone  string              `json:"timezone,omitempty"`
+	WeekStart string              `json:"week_start,omitempty"`
+	Weekly    []WeeklyOrderTotals `json:"weekly,omitempty"`
+}
+
+// WeeklyOrderTotals sums the orders of one currency placed in a local week,
+// leaving out cancelled and unpaid ones. Start and End are the instants the
+// week begins and ends, cut to the statistics window.
+type WeeklyOrderTotals struct {
+	Week     time.Time       `db:"week" json:"-"` // local date the week begins
+	Start    time.Time       `db:"-" json:"start"`
+	End      time.Time       `db:"-" json:"end"`
+	Currency string          `db:"currency" json:"currency"`
+	Orders   int             `db:"orders" json:"orders"`
+	Revenue  decimal.Decimal `db:"revenue" json:"revenue"`
+}
+
+// SLAAttainment is the share of orders that left a status within its SLA
+type SLAAttainment struct {
+	Priority   string  `db:"priority" json:"priority"`
+	Status     string  `db:"status" json:"status"`
+	Total      int     `db:"total" json:"total"`
+	Met        int     `db:"met" json:"met"`
+	Attainment float64 `db:"-" json:"attainment"`
+}
+
+// Cancellation is the outcome of a customer cancelling their order
+type Cancellation struct {
+	OrderID uuid.UUID        `json:"order_id"`
+	Status  string           `json:"status"`
+	Refunds []Billing.Refund `json:"refunds"`
+	// the order is cancelled but refunding its payment failed; staff
+	// refund it with POST /orders/{id}/refunds
+	RefundFailed bool `json:"refund_failed,omitempty"`
 }
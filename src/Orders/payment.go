@@ -0,0 +1,130 @@
+package Orders
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"savannah/src/Analytics"
+	"savannah/src/Billing"
+)
+
+// pay authorizes the order's payment with method, or opens an unpaid invoice
+// for offline methods
+func (s *service) pay(ctx context.Context, order *Order, method string) error {
+	if s.payments == nil {
+		return nil
+	}
+	if method == Billing.MethodTerms {
+		if order.CustomerID == nil || order.PaymentTerms == nil {
+			return ErrorTermsNeedCustomer
+		}
+		_, err := s.payments.OpenTermsInvoice(ctx, order.ID, *order.CustomerID, order.Total, order.Currency, *order.PaymentTerms)
+		return err
+	}
+	if Billing.IsOfflineMethod(method) {
+		var warehouse, region string
+		if order.Warehouse != nil {
+			warehouse = *order.Warehouse
+		}
+		if order.Region != nil {
+			region = *order.Region
+		}
+		_, err := s.payments.OpenOfflineInvoice(ctx, order.ID, order.Total, order.Currency, method, warehouse, region)
+		return err
+	}
+	_, err := s.payments.AuthorizeOrder(ctx, order.ID, order.Total, order.Currency, method)
+	return err
+}
+
+// RetryPayment pays for an order left PAYMENT_FAILED by checkout, with
+// paymentMethod or, when empty, the method the order was placed with. The
+// stock and slots released by the failed attempt are taken again first; on success
+// the order resumes the checkout flow (CREATED, or CONFIRMED when paid
+// offline), on failure it stays PAYMENT_FAILED and a PaymentError is
+// returned. Every attempt is recorded on the timeline.
+func (s *service) RetryPayment(ctx context.Context, id uuid.UUID, paymentMethod string) (*Order, error) {
+	order, items, err := s.repo.GetOrder(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if order.Archived {
+		return nil, ErrorArchived
+	}
+	if order.FrozenAt != nil {
+		return nil, ErrorOrderFrozen
+	}
+	if order.Status != "PAYMENT_FAILED" {
+		return nil, ErrorNotAwaitingPay
+	}
+	if paymentMethod == "" && order.PaymentMethod != nil {
+		paymentMethod = *order.PaymentMethod
+	}
+	if order.CustomerID != nil {
+		if err := s.checkLimits(ctx, *order.CustomerID, items); err != nil {
+			return nil, err
+		}
+	}
+	var warehouse string
+	if order.Warehouse != nil {
+		warehouse = *order.Warehouse
+	}
+	if err := s.reserve(ctx, order.ID, items, warehouse); err != nil {
+		return nil, err
+	}
+	if err := s.bookSlots(ctx, order.ID, items); err != nil {
+		s.release(ctx, order.ID, items, warehouse)
+		return nil, err
+	}
+
+	perr := s.pay(ctx, order, paymentMethod)
+	if eerr := s.repo.AddEvent(ctx, order.ID, EventPaymentRetried, map[string]interface{}{"payment_method": paymentMethod, "succeeded": perr == nil}); eerr != nil {
+		s.log.Error("record payment retry", zap.String("order_id", order.ID.String()), zap.Error(eerr))
+	}
+	if perr != nil {
+		s.release(ctx, order.ID, items, warehouse)
+		s.freeSlots(ctx, order.ID, items)
+		return nil, &PaymentError{Order: order, Err: perr}
+	}
+
+	if order.PaymentMethod == nil || *order.PaymentMethod != paymentMethod {
+		if err := s.repo.SetPaymentMethod(ctx, order.ID, paymentMethod); err != nil {
+			return nil, err
+		}
+		order.PaymentMethod = &paymentMethod
+	}
+	status := "CREATED"
+	if Billing.IsOfflineMethod(paymentMethod) {
+		status = "CONFIRMED"
+	}
+	if err := s.Transition(ctx, order.ID, status); err != nil {
+		s.log.Error("resume order after payment", zap.String("order_id", order.ID.String()), zap.Error(err))
+		return nil, err
+	}
+	s.captureUnshipped(ctx, order, items, paymentMethod)
+	if order, _, err = s.repo.GetOrder(ctx, order.ID); err != nil {
+		return nil, err
+	}
+	s.track(ctx, Analytics.EventOrderCompleted, order.CustomerID, map[string]interface{}{"order_id": order.ID, "total": order.Total, "currency": order.Currency, "items": len(items), "retry": true})
+	s.placed(ctx, order)
+	return order, nil
+}
+
+// reserve takes the order's stock again, giving back what it already took
+// when one of the items is short
+func (s *service) reserve(ctx context.Context, orderID uuid.UUID, items []OrderItem, warehouse string) error {
+	stock, err := s.stocked(ctx, items)
+	if err != nil {
+		return err
+	}
+	for i, it := range stock {
+		if it.ProductID == nil {
+			continue
+		}
+		if err := s.inv.Reserve(ctx, it.stockID(), it.Quantity, warehouse); err != nil {
+			s.release(ctx, orderID, stock[:i], warehouse)
+			return err
+		}
+	}
+	return nil
+}
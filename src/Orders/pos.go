@@ -0,0 +1,136 @@
+package Orders
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+// SourcePOS marks orders synced from store tills
+const SourcePOS = "pos"
+
+// POS records sales made at a store, in the order the till sent them. The
+// goods have already been handed over, so an order is never refused for lack
+// of stock: it is recorded as COMPLETED, stock is deducted from the store
+// warehouse and any item sold beyond what was available is reported as a
+// conflict for the store to reconcile.
+func (s *service) POS(ctx context.Context, warehouse string, orders []POSOrderRequest) []POSOrderResult {
+	results := make([]POSOrderResult, 0, len(orders))
+	for _, req := range orders {
+		res := POSOrderResult{ID: req.ID}
+		conflicts, err := s.posOne(ctx, warehouse, req)
+		switch {
+		case err == nil:
+			res.Status = ImportStatusCreated
+			res.Conflicts = conflicts
+		case errors.Is(err, ErrorDuplicateExternal):
+			res.Status = ImportStatusDuplicate
+		default:
+			s.log.Error("pos order", zap.String("order_id", req.ID.String()), zap.Error(err))
+			res.Status = ImportStatusFailed
+			res.Error = err.Error()
+		}
+		results = append(results, res)
+	}
+	return results
+}
+
+func (s *service) posOne(ctx context.Context, warehouse string, req POSOrderRequest) ([]POSConflict, error) {
+	if _, _, err := s.repo.GetOrder(ctx, req.ID); err == nil {
+		return nil, ErrorDuplicateExternal
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	items := make([]OrderItem, 0, len(req.Items))
+	for _, it := range req.Items {
+		items = append(items, OrderItem{
+			ProductID:    it.ProductID,
+			VariantID:    it.VariantID,
+			SKU:          it.SKU,
+			Name:         it.Name,
+			UnitPrice:    it.UnitPrice,
+			Quantity:     it.Quantity,
+			LineTotal:    it.UnitPrice.Mul(decimal.NewFromInt(int64(it.Quantity))),
+			GiftWrap:     it.GiftWrap,
+			GiftMessage:  it.GiftMessage,
+			CustomerNote: it.CustomerNote,
+		})
+	}
+	order := newOrder(req.CustomerID, items, req.Currency)
+	order.ID = req.ID
+	order.Status = "COMPLETED"
+	// a till clock running ahead must not date sales in the future
+	order.CreatedAt = req.CreatedAt.UTC()
+	if now := time.Now().UTC(); order.CreatedAt.After(now) {
+		order.CreatedAt = now
+	}
+	reference := req.ID.String()
+	source := SourcePOS
+	order.ExternalReference = &reference
+	order.Source = &source
+	order.Channel = ChannelPOS
+	order.Warehouse = &warehouse
+
+	if err := s.record(ctx, order, items); err != nil {
+		// the same till batch synced concurrently
+		if _, _, gerr := s.repo.GetOrder(ctx, req.ID); gerr == nil {
+			return nil, ErrorDuplicateExternal
+		}
+		return nil, err
+	}
+	s.placed(ctx, order)
+	return s.deduct(ctx, order, items, warehouse), nil
+}
+
+// record persists an order without reserving stock
+func (s *service) record(ctx context.Context, order *Order, items []OrderItem) (err error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	if err = s.repo.CreateOrderTx(ctx, tx, order, items); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// deduct takes the sold quantities out of the warehouse, one adjustment per
+// stock item, and returns the items that were oversold. The order is already
+// recorded, so a failed adjustment is logged rather than returned.
+func (s *service) deduct(ctx context.Context, order *Order, items []OrderItem, warehouse string) []POSConflict {
+	var stock []uuid.UUID
+	quantities := map[uuid.UUID]int{}
+	first := map[uuid.UUID]OrderItem{}
+	for _, it := range items {
+		id := it.stockID()
+		if _, ok := quantities[id]; !ok {
+			stock = append(stock, id)
+			first[id] = it
+		}
+		quantities[id] += it.Quantity
+	}
+	var conflicts []POSConflict
+	for _, id := range stock {
+		qty := quantities[id]
+		available, err := s.inv.Sell(ctx, id, qty, warehouse, order.ID.String())
+		if err != nil {
+			s.log.Error("deduct pos stock", zap.String("order_id", order.ID.String()), zap.String("stock_id", id.String()), zap.Error(err))
+			continue
+		}
+		if available < qty {
+			it := first[id]
+			conflicts = append(conflicts, POSConflict{ProductID: *it.ProductID, VariantID: it.VariantID, SKU: it.SKU, Requested: qty, Available: max(available, 0), Oversold: qty - max(available, 0)})
+		}
+	}
+	return conflicts
+}
@@ -0,0 +1,154 @@
+package Orders
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"savannah/src/Analytics"
+	"savannah/src/Inventory"
+)
+
+// StatusPreorder is the status of an order for pre-order products: nothing
+// is reserved or charged until AllocatePreorders allocates it.
+const StatusPreorder = "PREORDER"
+
+// preorderBatch caps how many pre-orders one allocation run looks at
+const preorderBatch = 200
+
+// PreorderGroup is the queue of pre-orders waiting for one product
+type PreorderGroup struct {
+	ProductID   uuid.UUID  `db:"product_id" json:"product_id"`
+	Name        string     `db:"name" json:"name"`
+	ReleaseDate *time.Time `db:"release_date" json:"release_date,omitempty"`
+	Orders      int        `db:"orders" json:"orders"`
+	Quantity    int        `db:"quantity" json:"quantity"`
+	OldestAt    time.Time  `db:"oldest_at" json:"oldest_at"`
+}
+
+// isPreorder reports whether items are for pre-order products. A cart can't
+// mix them with products that ship now, since the order would wait on the
+// slowest item.
+func (s *service) isPreorder(ctx context.Context, items []OrderItem) (bool, error) {
+	ids := []uuid.UUID{}
+	for _, it := range items {
+		if it.ProductID != nil {
+			ids = append(ids, *it.ProductID)
+		}
+	}
+	if len(ids) == 0 {
+		return false, nil
+	}
+	preorder, err := s.repo.PreorderProducts(ctx, ids)
+	if err != nil || len(preorder) == 0 {
+		return false, err
+	}
+	set := make(map[uuid.UUID]bool, len(preorder))
+	for _, id := range preorder {
+		set[id] = true
+	}
+	for _, id := range ids {
+		if !set[id] {
+			return false, ErrorMixedPreorder
+		}
+	}
+	return true, nil
+}
+
+// Preorders lists the waiting pre-orders grouped per product
+func (s *service) Preorders(ctx context.Context) ([]PreorderGroup, error) {
+	return s.repo.PreorderGroups(ctx)
+}
+
+// AllocatePreorders reserves stock for the pre-orders whose products are
+// released (or have no release date) and charges them, moving them to
+// PROCESSING; run by the scheduler. Pre-orders are served oldest first: once
+// a product runs out, younger pre-orders for it wait for the next run even
+// if they would fit elsewhere. A declined payment leaves the order
+// PAYMENT_FAILED like at checkout.
+func (s *service) AllocatePreorders(ctx context.Context) error {
+	orders, err := s.repo.ReleasedPreorders(ctx, preorderBatch)
+	if err != nil || len(orders) == 0 {
+		return err
+	}
+	ids := make([]uuid.UUID, len(orders))
+	for i, o := range orders {
+		ids[i] = o.ID
+	}
+	all, err := s.repo.ItemsForOrders(ctx, ids)
+	if err != nil {
+		return err
+	}
+	items := map[uuid.UUID][]OrderItem{}
+	for _, it := range all {
+		items[it.OrderID] = append(items[it.OrderID], it)
+	}
+
+	exhausted := map[uuid.UUID]bool{}
+	for i := range orders {
+		o := &orders[i]
+		if waiting(items[o.ID], exhausted) {
+			continue
+		}
+		err := s.allocate(ctx, o, items[o.ID])
+		switch {
+		case errors.Is(err, Inventory.ErrorInsufficientStock), errors.Is(err, Inventory.ErrorNoWarehouse):
+			for _, it := range items[o.ID] {
+				exhausted[it.stockID()] = true
+			}
+		case err != nil:
+			s.log.Error("allocate pre-order", zap.String("order_id", o.ID.String()), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// waiting reports whether an older pre-order is still waiting for one of items
+func waiting(items []OrderItem, exhausted map[uuid.UUID]bool) bool {
+	for _, it := range items {
+		if exhausted[it.stockID()] {
+			return true
+		}
+	}
+	return false
+}
+
+// allocate routes and reserves a pre-order, then pays for it as checkout
+// would have
+func (s *service) allocate(ctx context.Context, o *Order, items []OrderItem) error {
+	warehouse, err := s.route(ctx, deref(o.Warehouse), deref(o.Country), deref(o.Region), items)
+	if err != nil {
+		return err
+	}
+	stock, err := s.stocked(ctx, items)
+	if err != nil {
+		return err
+	}
+	for i, it := range stock {
+		if err := s.inv.Reserve(ctx, it.stockID(), it.Quantity, warehouse); err != nil {
+			s.release(ctx, o.ID, stock[:i], warehouse)
+			return err
+		}
+	}
+	if err := s.repo.SetWarehouse(ctx, o.ID, warehouse); err != nil {
+		s.release(ctx, o.ID, items, warehouse)
+		return err
+	}
+	o.Warehouse = &warehouse
+	if err := s.pay(ctx, o, deref(o.PaymentMethod)); err != nil {
+		s.log.Warn("pre-order payment failed", zap.String("order_id", o.ID.String()), zap.Error(err))
+		s.abandon(ctx, o, items, warehouse)
+		return nil
+	}
+	if err := s.Transition(ctx, o.ID, "PROCESSING"); err != nil {
+		// cancelled or frozen while being allocated
+		s.release(ctx, o.ID, items, warehouse)
+		return err
+	}
+	s.captureUnshipped(ctx, o, items, deref(o.PaymentMethod))
+	s.track(ctx, Analytics.EventPreorderAllocated, o.CustomerID, map[string]interface{}{"order_id": o.ID, "warehouse": warehouse})
+	s.placed(ctx, o)
+	return nil
+}
@@ -0,0 +1,90 @@
+package Orders
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"savannah/src/Notifications"
+)
+
+// Recall finds the orders and customers holding a recalled SKU and, as
+// asked, tags the orders and emails every customer once about all of their
+// affected orders. A failed email doesn't stop the others; the customers
+// missed are listed and their messages can be resent from the notification
+// log.
+func (s *service) Recall(ctx context.Context, req RecallRequest) (*Recall, error) {
+	if !req.From.Before(req.To) {
+		return nil, ErrorInvalidRange
+	}
+	orders, err := s.repo.RecallOrders(ctx, req.SKU, req.Warehouse, req.From, req.To)
+	if err != nil {
+		return nil, err
+	}
+	rc := &Recall{SKU: req.SKU, Lot: req.Lot, From: req.From, To: req.To, Orders: orders, Customers: []uuid.UUID{}}
+	byCustomer := map[uuid.UUID][]uuid.UUID{}
+	for _, o := range orders {
+		if o.CustomerID == nil {
+			rc.GuestOrders++
+			continue
+		}
+		if _, seen := byCustomer[*o.CustomerID]; !seen {
+			rc.Customers = append(rc.Customers, *o.CustomerID)
+		}
+		byCustomer[*o.CustomerID] = append(byCustomer[*o.CustomerID], o.OrderID)
+	}
+	if req.Tag != "" && len(orders) > 0 {
+		ids := make([]uuid.UUID, len(orders))
+		for i, o := range orders {
+			ids[i] = o.OrderID
+		}
+		rc.Tag = req.Tag
+		tagged, err := s.repo.TagOrders(ctx, ids, req.Tag, map[string]interface{}{"tag": req.Tag, "reason": "recall", "sku": req.SKU, "lot": req.Lot})
+		if err != nil {
+			return nil, err
+		}
+		rc.Tagged = len(tagged)
+	}
+	if req.Notify {
+		for _, customerID := range rc.Customers {
+			if err := s.notifyRecall(ctx, req, customerID, byCustomer[customerID]); err != nil {
+				s.log.Error("send recall notice", zap.String("customer_id", customerID.String()), zap.String("sku", req.SKU), zap.Error(err))
+				rc.NotifyFailed = append(rc.NotifyFailed, customerID)
+				continue
+			}
+			rc.Notified++
+		}
+	}
+	return rc, nil
+}
+
+func (s *service) notifyRecall(ctx context.Context, req RecallRequest, customerID uuid.UUID, orderIDs []uuid.UUID) error {
+	if s.notifier == nil || s.customers == nil {
+		return fmt.Errorf("notifications are not configured")
+	}
+	email, err := s.customers.Email(ctx, customerID)
+	if err != nil {
+		return err
+	}
+	subject := req.Subject
+	if subject == "" {
+		subject = "Important: product recall of " + req.SKU
+	}
+	var body strings.Builder
+	body.WriteString(req.Message)
+	body.WriteString("\n\nYour affected orders:\n")
+	for _, id := range orderIDs {
+		fmt.Fprintf(&body, "%s\n", id)
+	}
+	return s.notifier.Send(ctx, Notifications.Message{
+		Channel:  Notifications.ChannelEmail,
+		To:       email,
+		Subject:  subject,
+		Body:     body.String(),
+		Template: "product_recall",
+		Data:     map[string]interface{}{"sku": req.SKU, "lot": req.Lot, "order_ids": orderIDs},
+		Critical: true,
+	})
+}
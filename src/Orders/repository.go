@@ -3,18 +3,83 @@ package Orders
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"go.uber.org/zap"
 )
 
 type Repository interface {
 	CreateOrderTx(ctx context.Context, tx *sqlx.Tx, o *Order, items []OrderItem) error
+	// GetOrder falls back to the archive, marking the order Archived
 	GetOrder(ctx context.Context, id uuid.UUID) (*Order, []OrderItem, error)
+	ArchiveOrders(ctx context.Context, before time.Time, statuses []string, limit int) (int, error)
+	List(ctx context.Context, q ListOrdersQuery) ([]Order, error)
+	Count(ctx context.Context, q ListOrdersQuery) (int, error)
+	Changes(ctx context.Context, since time.Time, afterID uuid.UUID, until time.Time, limit int) ([]Order, error)
+	ItemsForOrders(ctx context.Context, ids []uuid.UUID) ([]OrderItem, error)
+	DiscountsForOrders(ctx context.Context, ids []uuid.UUID) ([]OrderDiscount, error)
+	SaveClient(ctx context.Context, c *ClientContext) error
+	GetClient(ctx context.Context, orderID uuid.UUID) (*ClientContext, error)
+	GetOrderByExternalReference(ctx context.Context, source, reference string) (*Order, error)
+	AddNote(ctx context.Context, n *OrderNote) error
+	CreateAttachment(ctx context.Context, a *OrderAttachment) error
+	ListAttachments(ctx context.Context, orderID uuid.UUID) ([]OrderAttachment, error)
+	GetAttachment(ctx context.Context, orderID, id uuid.UUID) (*OrderAttachment, error)
+	DeleteAttachment(ctx context.Context, orderID, id uuid.UUID) error
+	AddEvent(ctx context.Context, orderID uuid.UUID, eventType string, data map[string]interface{}) error
+	SetPaymentMethod(ctx context.Context, id uuid.UUID, method string) error
+	Timeline(ctx context.Context, orderID uuid.UUID) ([]TimelineEntry, error)
 	UpdateOrderStatusTx(ctx context.Context, tx *sqlx.Tx, id uuid.UUID, status string, version int) error
+	SetSLADeadlineTx(ctx context.Context, tx *sqlx.Tx, id uuid.UUID, dueAt *time.Time) error
+	RecordSLAResultTx(ctx context.Context, tx *sqlx.Tx, o *Order, completedAt time.Time) error
+	ListSLAOrders(ctx context.Context, before time.Time, statuses []string) ([]SLAOrder, error)
+	SLAAttainment(ctx context.Context, from, to time.Time) ([]SLAAttainment, error)
+	SetFrozen(ctx context.Context, id uuid.UUID, reason *string) error
+
+	CreateDownloadLinks(ctx context.Context, orderID uuid.UUID, expiresAt time.Time, maxDownloads int) error
+	ListDownloadLinks(ctx context.Context, orderID uuid.UUID) ([]DownloadLink, error)
+	ConsumeDownloadLink(ctx context.Context, id uuid.UUID) (*DownloadLink, error)
+	GetCustomerName(ctx context.Context, customerID uuid.UUID) (string, error)
+	CatalogPrices(ctx context.Context, productIDs []uuid.UUID) ([]CatalogPrice, error)
+	VariantPrices(ctx context.Context, variantIDs []uuid.UUID) ([]CatalogPrice, error)
+
+	ListPurchaseLimits(ctx context.Context) ([]PurchaseLimit, error)
+	PurchaseLimits(ctx context.Context, productIDs []uuid.UUID) ([]PurchaseLimit, error)
+	UpsertPurchaseLimit(ctx context.Context, l *PurchaseLimit) error
+	DeletePurchaseLimit(ctx context.Context, productID uuid.UUID) error
+	PurchasedQuantity(ctx context.Context, customerID, productID uuid.UUID, since time.Time, excludeStatuses []string) (int, error)
+	CountOrders(ctx context.Context, customerID uuid.UUID, statuses []string) (int, error)
+	PickList(ctx context.Context, warehouse string, statuses []string) ([]PickListLine, error)
+	PreorderProducts(ctx context.Context, productIDs []uuid.UUID) ([]uuid.UUID, error)
+	UnstockedProducts(ctx context.Context, productIDs []uuid.UUID) ([]uuid.UUID, error)
+	ShippingData(ctx context.Context, productIDs []uuid.UUID) ([]ShippingItem, error)
+	RecallOrders(ctx context.Context, sku, warehouse string, from, to time.Time) ([]RecallOrder, error)
+	// TagOrders tags the orders and records it on their timelines, returning
+	// those tagged; orders already tagged or not found are left out
+	TagOrders(ctx context.Context, ids []uuid.UUID, tag string, data map[string]interface{}) ([]uuid.UUID, error)
+	// UntagOrders is TagOrders in reverse
+	UntagOrders(ctx context.Context, ids []uuid.UUID, tag string, data map[string]interface{}) ([]uuid.UUID, error)
+	// OrderTags reports, for those of ids that are orders, whether they have tag
+	OrderTags(ctx context.Context, ids []uuid.UUID, tag string) (map[uuid.UUID]bool, error)
+	ReleasedPreorders(ctx context.Context, limit int) ([]Order, error)
+	PreorderGroups(ctx context.Context) ([]PreorderGroup, error)
+	SetWarehouse(ctx context.Context, id uuid.UUID, warehouse string) error
+
+	WaitingRoomTargets(ctx context.Context) ([]WaitingRoomTarget, error)
+	WaitingRoomProducts(ctx context.Context) ([]uuid.UUID, error)
+	AddWaitingRoomTarget(ctx context.Context, t *WaitingRoomTarget) error
+	DeleteWaitingRoomTarget(ctx context.Context, targetType string, id uuid.UUID) error
+
+	RefreshDailyStats(ctx context.Context, from, to time.Time) error
+	DailyStats(ctx context.Context, from, to time.Time) ([]DailyOrderStats, error)
+	WeeklyStats(ctx context.Context, from, to time.Time, zone string, shift int, excludeStatuses []string) ([]WeeklyOrderTotals, error)
+	OrderMargins(ctx context.Context, from, to time.Time, excludeStatuses []string, limit, offset int) ([]OrderMargin, error)
+	ProductMargins(ctx context.Context, from, to time.Time, excludeStatuses []string, limit, offset int) ([]ProductMargin, error)
 }
 
 type repository struct {
@@ -25,18 +90,38 @@ type repository struct {
 func NewRepository(db *sqlx.DB, log *zap.Logger) Repository { return &repository{db: db, log: log} }
 
 func (r *repository) CreateOrderTx(ctx context.Context, tx *sqlx.Tx, o *Order, items []OrderItem) error {
-	o.ID = uuid.New()
+	// POS tills generate their own ids and timestamps
+	if o.ID == uuid.Nil {
+		o.ID = uuid.New()
+	}
 	now := time.Now().UTC()
-	o.CreatedAt = now
+	if o.CreatedAt.IsZero() {
+		o.CreatedAt = now
+	}
 	o.UpdatedAt = now
-	_, err := tx.ExecContext(ctx, `INSERT INTO orders (id,customer_id,status,subtotal,tax,shipping,total,currency,created_at,updated_at,version) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)`, o.ID, o.CustomerID, o.Status, o.Subtotal, o.Tax, o.Shipping, o.Total, o.Currency, o.CreatedAt, o.UpdatedAt, o.Version)
+	_, err := tx.ExecContext(ctx, `INSERT INTO orders (id,customer_id,status,subtotal,tax,shipping,discount,total,currency,external_reference,source,channel,warehouse,country,region,payment_method,po_number,payment_terms,priority,sla_due_at,created_at,updated_at,version) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19,$20,$21,$22,$23)`, o.ID, o.CustomerID, o.Status, o.Subtotal, o.Tax, o.Shipping, o.Discount, o.Total, o.Currency, o.ExternalReference, o.Source, o.Channel, o.Warehouse, o.Country, o.Region, o.PaymentMethod, o.PONumber, o.PaymentTerms, o.Priority, o.SLADueAt, o.CreatedAt, o.UpdatedAt, o.Version)
 	if err != nil {
 		return err
 	}
+	event := EventOrderCreated
+	if o.Source != nil {
+		event = EventOrderImported
+	}
+	if err := addEventTx(ctx, tx, o.ID, event, o.Status, now); err != nil {
+		return err
+	}
+	for i := range o.Discounts {
+		d := &o.Discounts[i]
+		d.ID, d.OrderID, d.CreatedAt = uuid.New(), o.ID, now
+		if _, err := tx.NamedExecContext(ctx, `INSERT INTO order_discounts (id,order_id,coupon_id,gift_card_id,code,description,amount,created_at) VALUES (:id,:order_id,:coupon_id,:gift_card_id,:code,:description,:amount,:created_at)`, d); err != nil {
+			return err
+		}
+	}
 	for i := range items {
 		items[i].ID = uuid.New()
 		items[i].OrderID = o.ID
-		if _, err := tx.ExecContext(ctx, `INSERT INTO order_items (id,order_id,product_id,sku,name,unit_price,quantity,line_total) VALUES ($1,$2,$3,$4,$5,$6,$7,$8)`, items[i].ID, items[i].OrderID, items[i].ProductID, items[i].SKU, items[i].Name, items[i].UnitPrice, items[i].Quantity, items[i].LineTotal); err != nil {
+		// the product's current cost is frozen onto the item for margin reporting
+		if err := tx.QueryRowxContext(ctx, `INSERT INTO order_items (id,order_id,product_id,variant_id,sku,name,unit_price,quantity,line_total,gift_wrap,gift_message,customer_note,slot_id,unit_cost) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,(SELECT cost FROM products WHERE id=$3)) RETURNING unit_cost`, items[i].ID, items[i].OrderID, items[i].ProductID, items[i].VariantID, items[i].SKU, items[i].Name, items[i].UnitPrice, items[i].Quantity, items[i].LineTotal, items[i].GiftWrap, items[i].GiftMessage, items[i].CustomerNote, items[i].SlotID).Scan(&items[i].UnitCost); err != nil {
 			return err
 		}
 	}
@@ -44,20 +129,200 @@ func (r *repository) CreateOrderTx(ctx context.Context, tx *sqlx.Tx, o *Order, i
 }
 
 func (r *repository) GetOrder(ctx context.Context, id uuid.UUID) (*Order, []OrderItem, error) {
-	var o Order
-	if err := r.db.GetContext(ctx, &o, `SELECT id,customer_id,status,subtotal,tax,shipping,total,currency,created_at,updated_at,version FROM orders WHERE id=$1`, id); err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil, sql.ErrNoRows
+	o, items, err := r.getOrder(ctx, id, "orders", "order_items")
+	if err == sql.ErrNoRows {
+		// finished orders move to the archive after a few years
+		if o, items, err = r.getOrder(ctx, id, "orders_archive", "order_items_archive"); o != nil {
+			o.Archived = true
 		}
+	}
+	return o, items, err
+}
+
+func (r *repository) getOrder(ctx context.Context, id uuid.UUID, ordersTable, itemsTable string) (*Order, []OrderItem, error) {
+	var o Order
+	if err := r.db.GetContext(ctx, &o, `SELECT id,customer_id,status,subtotal,tax,shipping,discount,total,currency,external_reference,source,channel,warehouse,country,region,payment_method,po_number,payment_terms,priority,sla_due_at,frozen_at,frozen_reason,created_at,updated_at,version FROM `+ordersTable+` WHERE id=$1`, id); err != nil {
 		return nil, nil, err
 	}
 	var items []OrderItem
-	if err := r.db.SelectContext(ctx, &items, `SELECT id,order_id,product_id,sku,name,unit_price,quantity,line_total FROM order_items WHERE order_id=$1`, id); err != nil {
+	if err := r.db.SelectContext(ctx, &items, `SELECT id,order_id,product_id,variant_id,sku,name,unit_price,quantity,line_total,gift_wrap,gift_message,customer_note,unit_cost,slot_id FROM `+itemsTable+` WHERE order_id=$1`, id); err != nil {
 		return &o, nil, err
 	}
+	discounts, err := r.DiscountsForOrders(ctx, []uuid.UUID{id})
+	if err != nil {
+		return &o, items, err
+	}
+	if len(discounts) > 0 {
+		o.Discounts = discounts
+	}
 	return &o, items, nil
 }
 
+// DiscountsForOrders returns the discount lines of the orders, archived or not
+func (r *repository) DiscountsForOrders(ctx context.Context, ids []uuid.UUID) ([]OrderDiscount, error) {
+	out := []OrderDiscount{}
+	if len(ids) == 0 {
+		return out, nil
+	}
+	err := r.db.SelectContext(ctx, &out, `SELECT id,order_id,coupon_id,gift_card_id,code,description,amount,created_at FROM order_discounts WHERE order_id = ANY($1::uuid[]) ORDER BY created_at, id`, pq.Array(ids))
+	return out, err
+}
+
+// ArchiveOrders moves up to limit orders in one of statuses, unchanged since
+// before and not frozen, with their items to the archive tables, oldest
+// first, and returns how many it moved.
+func (r *repository) ArchiveOrders(ctx context.Context, before time.Time, statuses []string, limit int) (n int, err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	query, args, err := sqlx.In(`SELECT id FROM orders WHERE updated_at < ? AND status IN (?) AND frozen_at IS NULL
+		ORDER BY updated_at LIMIT ? FOR UPDATE SKIP LOCKED`, before, statuses, limit)
+	if err != nil {
+		return 0, err
+	}
+	var ids []uuid.UUID
+	if err = tx.SelectContext(ctx, &ids, tx.Rebind(query), args...); err != nil || len(ids) == 0 {
+		return 0, err
+	}
+	for _, stmt := range []string{
+		`INSERT INTO order_items_archive SELECT * FROM order_items WHERE order_id IN (?)`,
+		`INSERT INTO orders_archive SELECT * FROM orders WHERE id IN (?)`,
+		`DELETE FROM order_items WHERE order_id IN (?)`,
+		`DELETE FROM orders WHERE id IN (?)`,
+	} {
+		if query, args, err = sqlx.In(stmt, ids); err != nil {
+			return 0, err
+		}
+		if _, err = tx.ExecContext(ctx, tx.Rebind(query), args...); err != nil {
+			return 0, err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
+
+func (r *repository) List(ctx context.Context, q ListOrdersQuery) ([]Order, error) {
+	where, args := listFilter(q)
+	query := `SELECT id,customer_id,status,subtotal,tax,shipping,discount,total,currency,external_reference,source,channel,warehouse,country,region,payment_method,po_number,payment_terms,priority,sla_due_at,frozen_at,frozen_reason,created_at,updated_at,version FROM orders` + where
+	query += fmt.Sprintf(" ORDER BY created_at DESC LIMIT $%d OFFSET $%d", len(args)+1, len(args)+2)
+	args = append(args, q.Limit, q.Offset)
+
+	orders := []Order{}
+	err := r.db.SelectContext(ctx, &orders, query, args...)
+	return orders, err
+}
+
+// Count counts the orders List pages through
+func (r *repository) Count(ctx context.Context, q ListOrdersQuery) (int, error) {
+	where, args := listFilter(q)
+	var n int
+	err := r.db.GetContext(ctx, &n, `SELECT COUNT(*) FROM orders`+where, args...)
+	return n, err
+}
+
+// listFilter is the WHERE clause of the order list filters
+func listFilter(q ListOrdersQuery) (string, []interface{}) {
+	base := ` WHERE 1=1`
+	args := []interface{}{}
+	add := func(cond string, v interface{}) {
+		args = append(args, v)
+		base += fmt.Sprintf(" AND "+cond, len(args))
+	}
+	if q.Status != "" {
+		add("status = $%d", q.Status)
+	}
+	if q.Warehouse != "" {
+		add("warehouse = $%d", q.Warehouse)
+	}
+	if q.Priority != "" {
+		add("priority = $%d", q.Priority)
+	}
+	if q.Source != "" {
+		add("source = $%d", q.Source)
+	}
+	if q.Channel != "" {
+		add("channel = $%d", q.Channel)
+	}
+	if q.CustomerID != nil {
+		add("customer_id = $%d", *q.CustomerID)
+	}
+	if q.SKU != "" {
+		add("EXISTS (SELECT 1 FROM order_items oi WHERE oi.order_id = orders.id AND oi.sku = $%d)", q.SKU)
+	}
+	if q.ProductID != nil {
+		add("EXISTS (SELECT 1 FROM order_items oi WHERE oi.order_id = orders.id AND oi.product_id = $%d)", *q.ProductID)
+	}
+	if q.Tag != "" {
+		add("EXISTS (SELECT 1 FROM order_tags t WHERE t.order_id = orders.id AND t.tag = $%d)", q.Tag)
+	}
+	if q.From != nil {
+		add("created_at >= $%d", *q.From)
+	}
+	if q.To != nil {
+		add("created_at < $%d", *q.To)
+	}
+	return base, args
+}
+
+// Changes lists orders updated after the (since, afterID) watermark and
+// before until, in watermark order.
+func (r *repository) Changes(ctx context.Context, since time.Time, afterID uuid.UUID, until time.Time, limit int) ([]Order, error) {
+	out := []Order{}
+	err := r.db.SelectContext(ctx, &out, `SELECT id,customer_id,status,subtotal,tax,shipping,discount,total,currency,external_reference,source,channel,warehouse,country,region,payment_method,po_number,payment_terms,priority,sla_due_at,frozen_at,frozen_reason,created_at,updated_at,version FROM orders
+		WHERE (updated_at, id) > ($1, $2) AND updated_at < $3 ORDER BY updated_at, id LIMIT $4`, since, afterID, until, limit)
+	return out, err
+}
+
+func (r *repository) ItemsForOrders(ctx context.Context, ids []uuid.UUID) ([]OrderItem, error) {
+	items := []OrderItem{}
+	if len(ids) == 0 {
+		return items, nil
+	}
+	query, args, err := sqlx.In(`SELECT id,order_id,product_id,variant_id,sku,name,unit_price,quantity,line_total,gift_wrap,gift_message,customer_note,unit_cost,slot_id FROM order_items WHERE order_id IN (?)`, ids)
+	if err != nil {
+		return nil, err
+	}
+	err = r.db.SelectContext(ctx, &items, r.db.Rebind(query), args...)
+	return items, err
+}
+
+func (r *repository) SaveClient(ctx context.Context, c *ClientContext) error {
+	_, err := r.db.NamedExecContext(ctx, `INSERT INTO order_clients (order_id,ip,user_agent,device_id,created_at) VALUES (:order_id,:ip,:user_agent,:device_id,:created_at)
+		ON CONFLICT (order_id) DO NOTHING`, c)
+	return err
+}
+
+// GetClient returns the order's client context, nil when none was recorded
+func (r *repository) GetClient(ctx context.Context, orderID uuid.UUID) (*ClientContext, error) {
+	var c ClientContext
+	if err := r.db.GetContext(ctx, &c, `SELECT order_id,ip,user_agent,device_id,created_at FROM order_clients WHERE order_id=$1`, orderID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *repository) GetOrderByExternalReference(ctx context.Context, source, reference string) (*Order, error) {
+	var o Order
+	if err := r.db.GetContext(ctx, &o, `SELECT id,customer_id,status,subtotal,tax,shipping,discount,total,currency,external_reference,source,channel,warehouse,country,region,payment_method,po_number,payment_terms,priority,sla_due_at,frozen_at,frozen_reason,created_at,updated_at,version FROM orders WHERE source=$1 AND external_reference=$2`, source, reference); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrorNotFound
+		}
+		return nil, err
+	}
+	return &o, nil
+}
+
 func (r *repository) UpdateOrderStatusTx(ctx context.Context, tx *sqlx.Tx, id uuid.UUID, status string, version int) error {
 	res, err := tx.ExecContext(ctx, `UPDATE orders SET status=$1, version=version+1, updated_at=NOW() WHERE id=$2 AND version=$3`, status, id, version)
 	if err != nil {
@@ -65,7 +330,600 @@ func (r *repository) UpdateOrderStatusTx(ctx context.Context, tx *sqlx.Tx, id uu
 	}
 	n, _ := res.RowsAffected()
 	if n == 0 {
-		return fmt.Errorf("version conflict")
+		return ErrorVersionConflict
+	}
+	return addEventTx(ctx, tx, id, EventStatusChanged, status, time.Now().UTC())
+}
+
+// SetFrozen freezes the order with reason, or unfreezes it when reason is nil
+func (r *repository) SetFrozen(ctx context.Context, id uuid.UUID, reason *string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	now := time.Now().UTC()
+	var frozenAt *time.Time
+	event := EventUnfrozen
+	if reason != nil {
+		frozenAt = &now
+		event = EventFrozen
+	}
+	res, err := tx.ExecContext(ctx, `UPDATE orders SET frozen_at=$1, frozen_reason=$2, updated_at=$3 WHERE id=$4`, frozenAt, reason, now, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		err = sql.ErrNoRows
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, `INSERT INTO order_events (id,order_id,event_type,data,created_at) VALUES ($1,$2,$3,jsonb_build_object('reason', $4::text),$5)`, uuid.New(), id, event, reason, now); err != nil {
+		return err
+	}
+	err = tx.Commit()
+	return err
+}
+
+func addEventTx(ctx context.Context, tx *sqlx.Tx, orderID uuid.UUID, eventType, status string, at time.Time) error {
+	_, err := tx.ExecContext(ctx, `INSERT INTO order_events (id,order_id,event_type,status,created_at) VALUES ($1,$2,$3,$4,$5)`, uuid.New(), orderID, eventType, status, at)
+	return err
+}
+
+// AddEvent records an event outside a status change, with its details in data
+func (r *repository) AddEvent(ctx context.Context, orderID uuid.UUID, eventType string, data map[string]interface{}) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, `INSERT INTO order_events (id,order_id,event_type,data,created_at) VALUES ($1,$2,$3,$4,$5)`, uuid.New(), orderID, eventType, string(b), time.Now().UTC())
+	return err
+}
+
+func (r *repository) SetPaymentMethod(ctx context.Context, id uuid.UUID, method string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE orders SET payment_method=$1, updated_at=NOW() WHERE id=$2`, method, id)
+	return err
+}
+
+func (r *repository) AddNote(ctx context.Context, n *OrderNote) error {
+	_, err := r.db.ExecContext(ctx, `INSERT INTO order_notes (id,order_id,author,body,created_at) VALUES ($1,$2,$3,$4,$5)`, n.ID, n.OrderID, n.Author, n.Body, n.CreatedAt)
+	return err
+}
+
+const attachmentColumns = `id,order_id,kind,file_name,file_key,content_type,size_bytes,sha256,description,roles,uploaded_by,created_at`
+
+func (r *repository) CreateAttachment(ctx context.Context, a *OrderAttachment) error {
+	_, err := r.db.NamedExecContext(ctx, `INSERT INTO order_attachments (`+attachmentColumns+`) VALUES (:id,:order_id,:kind,:file_name,:file_key,:content_type,:size_bytes,:sha256,:description,:roles,:uploaded_by,:created_at)`, a)
+	return err
+}
+
+func (r *repository) ListAttachments(ctx context.Context, orderID uuid.UUID) ([]OrderAttachment, error) {
+	out := []OrderAttachment{}
+	err := r.db.SelectContext(ctx, &out, `SELECT `+attachmentColumns+` FROM order_attachments WHERE order_id=$1 ORDER BY created_at, id`, orderID)
+	return out, err
+}
+
+func (r *repository) GetAttachment(ctx context.Context, orderID, id uuid.UUID) (*OrderAttachment, error) {
+	var a OrderAttachment
+	if err := r.db.GetContext(ctx, &a, `SELECT `+attachmentColumns+` FROM order_attachments WHERE order_id=$1 AND id=$2`, orderID, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrorAttachmentNotFound
+		}
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *repository) DeleteAttachment(ctx context.Context, orderID, id uuid.UUID) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM order_attachments WHERE order_id=$1 AND id=$2`, orderID, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrorAttachmentNotFound
+	}
+	return nil
+}
+
+// Timeline merges every source of order history into one feed, oldest first.
+// Customer notes captured on items are shown as notes at order creation.
+func (r *repository) Timeline(ctx context.Context, orderID uuid.UUID) ([]TimelineEntry, error) {
+	entries := []TimelineEntry{}
+	err := r.db.SelectContext(ctx, &entries, `
+		SELECT 'event' AS type, e.id, e.created_at AS at, e.event_type AS summary,
+			jsonb_build_object('status', e.status) || COALESCE(e.data, '{}'::jsonb) AS data
+		FROM order_events e WHERE e.order_id=$1
+		UNION ALL
+		SELECT 'payment', p.id, p.created_at, p.status,
+			jsonb_build_object('provider', p.provider, 'provider_payment_id', p.provider_payment_id, 'amount', p.amount, 'currency', p.currency, 'invoice_id', i.id, 'invoice_number', i.invoice_number)
+		FROM payments p JOIN invoices i ON i.id = p.invoice_id WHERE i.order_id=$1
+		UNION ALL
+		SELECT 'refund', rf.id, rf.created_at, rf.status,
+			jsonb_build_object('provider', rf.provider, 'provider_refund_id', rf.provider_refund_id, 'payment_id', rf.payment_id, 'amount', rf.amount, 'currency', rf.currency, 'reason', rf.reason)
+		FROM refunds rf JOIN invoices i ON i.id = rf.invoice_id WHERE i.order_id=$1
+		UNION ALL
+		SELECT 'shipment', s.id, s.created_at, 'PENDING',
+			jsonb_build_object('carrier', s.carrier, 'service_level', s.service_level, 'status', s.status)
+		FROM shipments s WHERE s.order_id=$1
+		UNION ALL
+		SELECT 'shipment', s.id, s.label_purchased_at, 'LABEL_PURCHASED',
+			jsonb_build_object('carrier', s.carrier, 'tracking_number', s.tracking_number, 'status', s.status)
+		FROM shipments s WHERE s.order_id=$1 AND s.label_purchased_at IS NOT NULL
+		UNION ALL
+		SELECT 'note', n.id, n.created_at, n.body, jsonb_build_object('author', n.author)
+		FROM order_notes n WHERE n.order_id=$1
+		UNION ALL
+		SELECT 'note', oi.id, o.created_at, oi.customer_note, jsonb_build_object('author', 'customer', 'order_item_id', oi.id, 'sku', oi.sku)
+		FROM order_items oi JOIN orders o ON o.id = oi.order_id WHERE oi.order_id=$1 AND oi.customer_note IS NOT NULL
+		UNION ALL
+		SELECT 'notification', l.id, l.created_at, COALESCE(l.subject, l.template, l.channel),
+			jsonb_build_object('channel', l.channel, 'recipient', l.recipient, 'template', l.template, 'status', l.status, 'error', l.error)
+		FROM notification_log l WHERE l.order_id=$1
+		ORDER BY at, type`, orderID)
+	return entries, err
+}
+
+// CreateDownloadLinks issues one link per file of every digital product in the
+// order. Existing links are kept, so calling it twice is harmless.
+func (r *repository) CreateDownloadLinks(ctx context.Context, orderID uuid.UUID, expiresAt time.Time, maxDownloads int) error {
+	var fileIDs []uuid.UUID
+	if err := r.db.SelectContext(ctx, &fileIDs, `SELECT DISTINCT f.id FROM order_items oi JOIN products p ON p.id = oi.product_id JOIN product_files f ON f.product_id = p.id WHERE oi.order_id=$1 AND p.product_type='DIGITAL'`, orderID); err != nil {
+		return err
+	}
+	for _, fileID := range fileIDs {
+		if _, err := r.db.ExecContext(ctx, `INSERT INTO download_links (id,order_id,file_id,expires_at,max_downloads,download_count,created_at) VALUES ($1,$2,$3,$4,$5,0,$6) ON CONFLICT (order_id,file_id) DO NOTHING`, uuid.New(), orderID, fileID, expiresAt, maxDownloads, time.Now().UTC()); err != nil {
+			return err
+		}
 	}
 	return nil
 }
+
+func (r *repository) ListDownloadLinks(ctx context.Context, orderID uuid.UUID) ([]DownloadLink, error) {
+	links := []DownloadLink{}
+	err := r.db.SelectContext(ctx, &links, `SELECT l.id,l.order_id,l.file_id,f.file_name,f.file_key,l.expires_at,l.max_downloads,l.download_count,l.created_at FROM download_links l JOIN product_files f ON f.id = l.file_id WHERE l.order_id=$1 ORDER BY f.file_name`, orderID)
+	return links, err
+}
+
+// ConsumeDownloadLink counts a download atomically, refusing expired or exhausted links.
+func (r *repository) ConsumeDownloadLink(ctx context.Context, id uuid.UUID) (*DownloadLink, error) {
+	var l DownloadLink
+	err := r.db.GetContext(ctx, &l, `UPDATE download_links l SET download_count = l.download_count + 1 FROM product_files f
+		WHERE f.id = l.file_id AND l.id=$1 AND l.expires_at > NOW() AND l.download_count < l.max_downloads
+		RETURNING l.id,l.order_id,l.file_id,f.file_name,f.file_key,l.expires_at,l.max_downloads,l.download_count,l.created_at`, id)
+	if err == sql.ErrNoRows {
+		var exists bool
+		if err := r.db.GetContext(ctx, &exists, `SELECT EXISTS (SELECT 1 FROM download_links WHERE id=$1)`, id); err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, ErrorDownloadExpired
+		}
+		return nil, ErrorDownloadNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+func (r *repository) GetCustomerName(ctx context.Context, customerID uuid.UUID) (string, error) {
+	var name string
+	err := r.db.GetContext(ctx, &name, `SELECT first_name || ' ' || last_name FROM customers WHERE id=$1`, customerID)
+	return name, err
+}
+
+// PickList aggregates physical items of open orders in a warehouse by bin and product.
+func (r *repository) PickList(ctx context.Context, warehouse string, statuses []string) ([]PickListLine, error) {
+	query, args, err := sqlx.In(`SELECT oi.product_id, COALESCE(MAX(oi.sku), p.sku) AS sku, COALESCE(MAX(oi.name), p.name) AS name,
+		COALESCE(i.bin_location, '') AS bin_location, SUM(oi.quantity) AS quantity,
+		COALESCE(SUM(CASE WHEN oi.gift_wrap THEN oi.quantity END), 0) AS gift_wrap, COUNT(DISTINCT o.id) AS orders
+		FROM orders o
+		JOIN order_items oi ON oi.order_id = o.id
+		JOIN products p ON p.id = oi.product_id
+		LEFT JOIN inventory i ON i.product_id = oi.product_id AND i.warehouse = o.warehouse
+		WHERE o.warehouse = ? AND o.status IN (?) AND p.product_type = 'PHYSICAL'
+		GROUP BY oi.product_id, p.sku, p.name, i.bin_location
+		ORDER BY bin_location, sku`, warehouse, statuses)
+	if err != nil {
+		return nil, err
+	}
+	lines := []PickListLine{}
+	err = r.db.SelectContext(ctx, &lines, r.db.Rebind(query), args...)
+	return lines, err
+}
+
+// PreorderProducts returns which of the products are on pre-order: flagged
+// and not yet released
+func (r *repository) PreorderProducts(ctx context.Context, productIDs []uuid.UUID) ([]uuid.UUID, error) {
+	query, args, err := sqlx.In(`SELECT id FROM products WHERE id IN (?) AND preorder AND (release_date IS NULL OR release_date > NOW())`, productIDs)
+	if err != nil {
+		return nil, err
+	}
+	ids := []uuid.UUID{}
+	err = r.db.SelectContext(ctx, &ids, r.db.Rebind(query), args...)
+	return ids, err
+}
+
+// UnstockedProducts returns which of the products are not physical: digital
+// goods and services hold no stock and are not shipped
+func (r *repository) UnstockedProducts(ctx context.Context, productIDs []uuid.UUID) ([]uuid.UUID, error) {
+	query, args, err := sqlx.In(`SELECT id FROM products WHERE id IN (?) AND product_type <> 'PHYSICAL'`, productIDs)
+	if err != nil {
+		return nil, err
+	}
+	ids := []uuid.UUID{}
+	err = r.db.SelectContext(ctx, &ids, r.db.Rebind(query), args...)
+	return ids, err
+}
+
+// ShippingData returns the weight, dimensions and shipping class of those of
+// the products that are physical
+func (r *repository) ShippingData(ctx context.Context, productIDs []uuid.UUID) ([]ShippingItem, error) {
+	query, args, err := sqlx.In(`SELECT id, weight_grams, length_mm, width_mm, height_mm, shipping_class FROM products WHERE id IN (?) AND product_type = 'PHYSICAL'`, productIDs)
+	if err != nil {
+		return nil, err
+	}
+	out := []ShippingItem{}
+	err = r.db.SelectContext(ctx, &out, r.db.Rebind(query), args...)
+	return out, err
+}
+
+// RecallOrders lists the orders, oldest first, with items of sku placed in
+// [from, to), leaving out those cancelled or never paid
+func (r *repository) RecallOrders(ctx context.Context, sku, warehouse string, from, to time.Time) ([]RecallOrder, error) {
+	out := []RecallOrder{}
+	err := r.db.SelectContext(ctx, &out, `SELECT o.id AS order_id, o.customer_id, o.status, o.warehouse, o.created_at, SUM(oi.quantity)::int AS quantity
+		FROM orders o JOIN order_items oi ON oi.order_id = o.id
+		WHERE oi.sku = $1 AND o.created_at >= $2 AND o.created_at < $3 AND ($4 = '' OR o.warehouse = $4)
+		AND o.status NOT IN ('CANCELLED', 'PAYMENT_FAILED')
+		GROUP BY o.id ORDER BY o.created_at, o.id`, sku, from, to, warehouse)
+	return out, err
+}
+
+func (r *repository) TagOrders(ctx context.Context, ids []uuid.UUID, tag string, data map[string]interface{}) ([]uuid.UUID, error) {
+	return r.retag(ctx, EventTagged, data, `INSERT INTO order_tags (order_id, tag) SELECT id, $2 FROM orders WHERE id = ANY($1::uuid[])
+		ON CONFLICT DO NOTHING RETURNING order_id`, pq.Array(ids), tag)
+}
+
+func (r *repository) UntagOrders(ctx context.Context, ids []uuid.UUID, tag string, data map[string]interface{}) ([]uuid.UUID, error) {
+	return r.retag(ctx, EventUntagged, data, `DELETE FROM order_tags WHERE order_id = ANY($1::uuid[]) AND tag = $2 RETURNING order_id`, pq.Array(ids), tag)
+}
+
+// retag runs a tag change returning the orders it changed and records
+// eventType on their timelines in the same transaction
+func (r *repository) retag(ctx context.Context, eventType string, data map[string]interface{}, query string, args ...interface{}) (changed []uuid.UUID, err error) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	changed = []uuid.UUID{}
+	if err = tx.SelectContext(ctx, &changed, query, args...); err != nil {
+		return nil, err
+	}
+	if len(changed) > 0 {
+		eventIDs := make([]uuid.UUID, len(changed))
+		for i := range eventIDs {
+			eventIDs[i] = uuid.New()
+		}
+		if _, err = tx.ExecContext(ctx, `INSERT INTO order_events (id, order_id, event_type, data, created_at)
+			SELECT unnest($1::uuid[]), unnest($2::uuid[]), $3, $4, $5`, pq.Array(eventIDs), pq.Array(changed), eventType, string(b), time.Now().UTC()); err != nil {
+			return nil, err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return changed, nil
+}
+
+func (r *repository) OrderTags(ctx context.Context, ids []uuid.UUID, tag string) (map[uuid.UUID]bool, error) {
+	rows := []struct {
+		ID     uuid.UUID `db:"id"`
+		Tagged bool      `db:"tagged"`
+	}{}
+	err := r.db.SelectContext(ctx, &rows, `SELECT o.id, EXISTS (SELECT 1 FROM order_tags t WHERE t.order_id = o.id AND t.tag = $2) AS tagged
+		FROM orders o WHERE o.id = ANY($1::uuid[])`, pq.Array(ids), tag)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[uuid.UUID]bool, len(rows))
+	for _, row := range rows {
+		out[row.ID] = row.Tagged
+	}
+	return out, nil
+}
+
+// ReleasedPreorders lists pre-orders, oldest first, none of whose products
+// has a release date still ahead
+func (r *repository) ReleasedPreorders(ctx context.Context, limit int) ([]Order, error) {
+	orders := []Order{}
+	err := r.db.SelectContext(ctx, &orders, `SELECT id,customer_id,status,subtotal,tax,shipping,discount,total,currency,external_reference,source,channel,warehouse,country,region,payment_method,po_number,payment_terms,priority,sla_due_at,frozen_at,frozen_reason,created_at,updated_at,version
+		FROM orders o WHERE status = 'PREORDER' AND frozen_at IS NULL
+		AND NOT EXISTS (SELECT 1 FROM order_items oi JOIN products p ON p.id = oi.product_id WHERE oi.order_id = o.id AND p.release_date > NOW())
+		ORDER BY created_at, id LIMIT $1`, limit)
+	return orders, err
+}
+
+func (r *repository) PreorderGroups(ctx context.Context) ([]PreorderGroup, error) {
+	groups := []PreorderGroup{}
+	err := r.db.SelectContext(ctx, &groups, `SELECT oi.product_id, p.name, p.release_date, COUNT(DISTINCT o.id) AS orders,
+		SUM(oi.quantity) AS quantity, MIN(o.created_at) AS oldest_at
+		FROM orders o
+		JOIN order_items oi ON oi.order_id = o.id
+		JOIN products p ON p.id = oi.product_id
+		WHERE o.status = 'PREORDER'
+		GROUP BY oi.product_id, p.name, p.release_date
+		ORDER BY p.release_date NULLS LAST, oldest_at`)
+	return groups, err
+}
+
+func (r *repository) SetWarehouse(ctx context.Context, id uuid.UUID, warehouse string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE orders SET warehouse=$1, updated_at=NOW() WHERE id=$2`, warehouse, id)
+	return err
+}
+
+func (r *repository) WaitingRoomTargets(ctx context.Context) ([]WaitingRoomTarget, error) {
+	targets := []WaitingRoomTarget{}
+	err := r.db.SelectContext(ctx, &targets, `SELECT target_type,target_id,created_at FROM waiting_room_targets ORDER BY created_at`)
+	return targets, err
+}
+
+// WaitingRoomProducts resolves the targets to products: designated products
+// and the products of designated campaigns running now
+func (r *repository) WaitingRoomProducts(ctx context.Context) ([]uuid.UUID, error) {
+	ids := []uuid.UUID{}
+	err := r.db.SelectContext(ctx, &ids, `SELECT target_id FROM waiting_room_targets WHERE target_type = 'product'
+		UNION
+		SELECT cp.product_id FROM waiting_room_targets t
+		JOIN campaigns c ON c.id = t.target_id AND c.starts_at <= NOW() AND c.ends_at > NOW()
+		JOIN campaign_products cp ON cp.campaign_id = c.id
+		WHERE t.target_type = 'campaign'
+		UNION
+		SELECT p.id FROM waiting_room_targets t
+		JOIN campaigns c ON c.id = t.target_id AND c.starts_at <= NOW() AND c.ends_at > NOW()
+		JOIN campaign_categories cc ON cc.campaign_id = c.id
+		JOIN products p ON p.category_id = cc.category_id
+		WHERE t.target_type = 'campaign'`)
+	return ids, err
+}
+
+// AddWaitingRoomTarget designates an existing product or campaign; adding
+// one twice keeps the first. Unknown ids are sql.ErrNoRows.
+func (r *repository) AddWaitingRoomTarget(ctx context.Context, t *WaitingRoomTarget) error {
+	table := "products"
+	if t.Type == TargetCampaign {
+		table = "campaigns"
+	}
+	err := r.db.GetContext(ctx, &t.CreatedAt, `INSERT INTO waiting_room_targets (target_type,target_id,created_at)
+		SELECT $1, id, NOW() FROM `+table+` WHERE id = $2
+		ON CONFLICT (target_type, target_id) DO UPDATE SET created_at = waiting_room_targets.created_at
+		RETURNING created_at`, t.Type, t.ID)
+	return err
+}
+
+func (r *repository) DeleteWaitingRoomTarget(ctx context.Context, targetType string, id uuid.UUID) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM waiting_room_targets WHERE target_type=$1 AND target_id=$2`, targetType, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *repository) CatalogPrices(ctx context.Context, productIDs []uuid.UUID) ([]CatalogPrice, error) {
+	query, args, err := sqlx.In(`SELECT p.id, p.sku, p.name, COALESCE(ROUND(p.price * (100 - d.discount_percent) / 100, 2), p.price) AS price, p.currency
+		FROM products p LEFT JOIN active_product_discounts d ON d.product_id = p.id WHERE p.id IN (?) AND p.deleted_at IS NULL AND p.published`, productIDs)
+	if err != nil {
+		return nil, err
+	}
+	var out []CatalogPrice
+	err = r.db.SelectContext(ctx, &out, r.db.Rebind(query), args...)
+	return out, err
+}
+
+// VariantPrices prices variants like CatalogPrices prices products; the name
+// combines the product and variant names.
+func (r *repository) VariantPrices(ctx context.Context, variantIDs []uuid.UUID) ([]CatalogPrice, error) {
+	query, args, err := sqlx.In(`SELECT v.product_id AS id, v.id AS variant_id, v.sku, p.name || ' - ' || v.name AS name,
+		COALESCE(ROUND(COALESCE(v.price, p.price) * (100 - d.discount_percent) / 100, 2), COALESCE(v.price, p.price)) AS price, p.currency
+		FROM product_variants v JOIN products p ON p.id = v.product_id LEFT JOIN active_product_discounts d ON d.product_id = p.id
+		WHERE v.id IN (?) AND p.deleted_at IS NULL AND p.published`, variantIDs)
+	if err != nil {
+		return nil, err
+	}
+	var out []CatalogPrice
+	err = r.db.SelectContext(ctx, &out, r.db.Rebind(query), args...)
+	return out, err
+}
+
+func (r *repository) ListPurchaseLimits(ctx context.Context) ([]PurchaseLimit, error) {
+	out := []PurchaseLimit{}
+	err := r.db.SelectContext(ctx, &out, `SELECT product_id,max_quantity,period_hours,created_at,updated_at FROM purchase_limits ORDER BY created_at`)
+	return out, err
+}
+
+func (r *repository) PurchaseLimits(ctx context.Context, productIDs []uuid.UUID) ([]PurchaseLimit, error) {
+	query, args, err := sqlx.In(`SELECT product_id,max_quantity,period_hours,created_at,updated_at FROM purchase_limits WHERE product_id IN (?)`, productIDs)
+	if err != nil {
+		return nil, err
+	}
+	var out []PurchaseLimit
+	err = r.db.SelectContext(ctx, &out, r.db.Rebind(query), args...)
+	return out, err
+}
+
+func (r *repository) UpsertPurchaseLimit(ctx context.Context, l *PurchaseLimit) error {
+	now := time.Now().UTC()
+	return r.db.GetContext(ctx, l, `INSERT INTO purchase_limits (product_id,max_quantity,period_hours,created_at,updated_at) VALUES ($1,$2,$3,$4,$4)
+		ON CONFLICT (product_id) DO UPDATE SET max_quantity=EXCLUDED.max_quantity, period_hours=EXCLUDED.period_hours, updated_at=EXCLUDED.updated_at
+		RETURNING product_id,max_quantity,period_hours,created_at,updated_at`, l.ProductID, l.MaxQuantity, l.PeriodHours, now)
+}
+
+func (r *repository) DeletePurchaseLimit(ctx context.Context, productID uuid.UUID) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM purchase_limits WHERE product_id=$1`, productID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// PurchasedQuantity sums the units of a product the customer ordered since the given time
+func (r *repository) PurchasedQuantity(ctx context.Context, customerID, productID uuid.UUID, since time.Time, excludeStatuses []string) (int, error) {
+	query, args, err := sqlx.In(`SELECT COALESCE(SUM(oi.quantity), 0) FROM order_items oi JOIN orders o ON o.id = oi.order_id
+		WHERE o.customer_id=? AND oi.product_id=? AND o.created_at >= ? AND o.status NOT IN (?)`, customerID, productID, since, excludeStatuses)
+	if err != nil {
+		return 0, err
+	}
+	var n int
+	err = r.db.GetContext(ctx, &n, r.db.Rebind(query), args...)
+	return n, err
+}
+
+func (r *repository) CountOrders(ctx context.Context, customerID uuid.UUID, statuses []string) (int, error) {
+	query, args, err := sqlx.In(`SELECT COUNT(*) FROM orders WHERE customer_id=? AND status IN (?)`, customerID, statuses)
+	if err != nil {
+		return 0, err
+	}
+	var n int
+	err = r.db.GetContext(ctx, &n, r.db.Rebind(query), args...)
+	return n, err
+}
+
+func (r *repository) SetSLADeadlineTx(ctx context.Context, tx *sqlx.Tx, id uuid.UUID, dueAt *time.Time) error {
+	_, err := tx.ExecContext(ctx, `UPDATE orders SET sla_due_at=$1 WHERE id=$2`, dueAt, id)
+	return err
+}
+
+// RecordSLAResultTx stores whether the order left its current status before o.SLADueAt
+func (r *repository) RecordSLAResultTx(ctx context.Context, tx *sqlx.Tx, o *Order, completedAt time.Time) error {
+	_, err := tx.ExecContext(ctx, `INSERT INTO order_sla_results (id,order_id,priority,status,due_at,completed_at,met) VALUES ($1,$2,$3,$4,$5,$6,$7)`,
+		uuid.New(), o.ID, o.Priority, o.Status, *o.SLADueAt, completedAt, !completedAt.After(*o.SLADueAt))
+	return err
+}
+
+// ListSLAOrders returns orders in statuses whose SLA deadline falls before the given time, most overdue first
+func (r *repository) ListSLAOrders(ctx context.Context, before time.Time, statuses []string) ([]SLAOrder, error) {
+	query, args, err := sqlx.In(`SELECT id,status,priority,warehouse,sla_due_at,created_at FROM orders
+		WHERE sla_due_at IS NOT NULL AND sla_due_at < ? AND status IN (?) ORDER BY sla_due_at`, before, statuses)
+	if err != nil {
+		return nil, err
+	}
+	out := []SLAOrder{}
+	err = r.db.SelectContext(ctx, &out, r.db.Rebind(query), args...)
+	return out, err
+}
+
+func (r *repository) SLAAttainment(ctx context.Context, from, to time.Time) ([]SLAAttainment, error) {
+	out := []SLAAttainment{}
+	err := r.db.SelectContext(ctx, &out, `SELECT priority, status, COUNT(*) AS total, COUNT(*) FILTER (WHERE met) AS met
+		FROM order_sla_results WHERE completed_at >= $1 AND completed_at < $2 GROUP BY priority, status ORDER BY priority, status`, from, to)
+	return out, err
+}
+
+// RefreshDailyStats recomputes the rollup rows of the days in [from, to)
+func (r *repository) RefreshDailyStats(ctx context.Context, from, to time.Time) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	if _, err = tx.ExecContext(ctx, `DELETE FROM order_daily_stats WHERE day >= $1::date AND day < $2::date`, from, to); err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, `INSERT INTO order_daily_stats (day,currency,status,channel,orders,revenue,cost,costed_sales,uncosted_items,refreshed_at)
+		SELECT (o.created_at AT TIME ZONE 'UTC')::date, o.currency, o.status, o.channel, COUNT(*), COALESCE(SUM(o.total), 0),
+			COALESCE(SUM(c.cost), 0), COALESCE(SUM(c.costed_sales), 0), COALESCE(SUM(c.uncosted_items), 0), NOW()
+		FROM orders o LEFT JOIN (
+			SELECT oi.order_id, SUM(oi.unit_cost * oi.quantity) AS cost, SUM(oi.line_total) FILTER (WHERE oi.unit_cost IS NOT NULL) AS costed_sales,
+				COUNT(*) FILTER (WHERE oi.unit_cost IS NULL) AS uncosted_items
+			FROM order_items oi JOIN orders io ON io.id = oi.order_id WHERE io.created_at >= $1 AND io.created_at < $2 GROUP BY oi.order_id
+		) c ON c.order_id = o.id
+		WHERE o.created_at >= $1 AND o.created_at < $2
+		GROUP BY 1, o.currency, o.status, o.channel
+		ON CONFLICT (day, currency, status, channel) DO UPDATE SET orders=EXCLUDED.orders, revenue=EXCLUDED.revenue, cost=EXCLUDED.cost,
+			costed_sales=EXCLUDED.costed_sales, uncosted_items=EXCLUDED.uncosted_items, refreshed_at=EXCLUDED.refreshed_at`, from, to); err != nil {
+		return err
+	}
+	err = tx.Commit()
+	return err
+}
+
+func (r *repository) DailyStats(ctx context.Context, from, to time.Time) ([]DailyOrderStats, error) {
+	out := []DailyOrderStats{}
+	err := r.db.SelectContext(ctx, &out, `SELECT day,currency,status,channel,orders,revenue,cost,costed_sales,uncosted_items FROM order_daily_stats
+		WHERE day >= $1::date AND day < $2::date ORDER BY day, currency, status, channel`, from, to)
+	return out, err
+}
+
+// WeeklyStats sums the orders placed in [from, to) per currency and week of
+// zone. Postgres weeks begin on Monday; shifting by shift days first makes
+// them begin that many days earlier.
+func (r *repository) WeeklyStats(ctx context.Context, from, to time.Time, zone string, shift int, excludeStatuses []string) ([]WeeklyOrderTotals, error) {
+	query, args, err := sqlx.In(`SELECT (date_trunc('week', (o.created_at AT TIME ZONE ?) + make_interval(days => ?)) - make_interval(days => ?))::date AS week,
+			o.currency, COUNT(*) AS orders, COALESCE(SUM(o.total), 0) AS revenue
+		FROM orders o
+		WHERE o.created_at >= ? AND o.created_at < ? AND o.status NOT IN (?)
+		GROUP BY 1, o.currency ORDER BY 1, o.currency`, zone, shift, shift, from, to, excludeStatuses)
+	if err != nil {
+		return nil, err
+	}
+	out := []WeeklyOrderTotals{}
+	err = r.db.SelectContext(ctx, &out, r.db.Rebind(query), args...)
+	return out, err
+}
+
+// OrderMargins sums the item costs of each order placed in [from, to), newest first
+func (r *repository) OrderMargins(ctx context.Context, from, to time.Time, excludeStatuses []string, limit, offset int) ([]OrderMargin, error) {
+	query, args, err := sqlx.In(`SELECT o.id AS order_id, o.created_at, o.status, o.channel, o.currency, o.total AS revenue,
+			COALESCE(SUM(oi.line_total) FILTER (WHERE oi.unit_cost IS NOT NULL), 0) AS costed_sales,
+			COALESCE(SUM(oi.unit_cost * oi.quantity), 0) AS cost,
+			COUNT(oi.id) FILTER (WHERE oi.unit_cost IS NULL) AS uncosted_items
+		FROM orders o LEFT JOIN order_items oi ON oi.order_id = o.id
+		WHERE o.created_at >= ? AND o.created_at < ? AND o.status NOT IN (?)
+		GROUP BY o.id ORDER BY o.created_at DESC, o.id LIMIT ? OFFSET ?`, from, to, excludeStatuses, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	out := []OrderMargin{}
+	err = r.db.SelectContext(ctx, &out, r.db.Rebind(query), args...)
+	return out, err
+}
+
+// ProductMargins sums the items of each product over orders placed in
+// [from, to), per currency, best selling first
+func (r *repository) ProductMargins(ctx context.Context, from, to time.Time, excludeStatuses []string, limit, offset int) ([]ProductMargin, error) {
+	query, args, err := sqlx.In(`SELECT oi.product_id, MAX(oi.sku) AS sku, MAX(oi.name) AS name, o.currency, SUM(oi.quantity) AS quantity, SUM(oi.line_total) AS sales,
+			COALESCE(SUM(oi.line_total) FILTER (WHERE oi.unit_cost IS NOT NULL), 0) AS costed_sales,
+			COALESCE(SUM(oi.unit_cost * oi.quantity), 0) AS cost,
+			COUNT(*) FILTER (WHERE oi.unit_cost IS NULL) AS uncosted_items
+		FROM order_items oi JOIN orders o ON o.id = oi.order_id
+		WHERE oi.product_id IS NOT NULL AND o.created_at >= ? AND o.created_at < ? AND o.status NOT IN (?)
+		GROUP BY oi.product_id, o.currency ORDER BY sales DESC, oi.product_id LIMIT ? OFFSET ?`, from, to, excludeStatuses, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	out := []ProductMargin{}
+	err = r.db.SelectContext(ctx, &out, r.db.Rebind(query), args...)
+	return out, err
+}
@@ -3,32 +3,327 @@ package Orders
 import (
 	"context"
 	"errors"
-	
+	"io"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 	"github.com/shopspring/decimal"
 	"go.uber.org/zap"
+	"savannah/src/Analytics"
+	"savannah/src/Billing"
+	"savannah/src/Inventory"
 )
 
 type InventoryService interface {
 	Reserve(ctx context.Context, productID uuid.UUID, qty int, warehouse string) error
 	Release(ctx context.Context, productID uuid.UUID, qty int, warehouse string) error
+	Sell(ctx context.Context, productID uuid.UUID, qty int, warehouse, reference string) (int, error)
+	SelectWarehouse(ctx context.Context, country, region string, quantities map[uuid.UUID]int) (string, error)
+	GetHold(ctx context.Context, id uuid.UUID) (*Inventory.Hold, error)
+	ConvertHold(ctx context.Context, id uuid.UUID, warehouse string) (map[uuid.UUID]int, error)
+}
+
+// Payments authorizes order payments at checkout and releases them on cancellation
+type Payments interface {
+	AuthorizeOrder(ctx context.Context, orderID uuid.UUID, amount decimal.Decimal, currency, provider string) (*Billing.Payment, error)
+	OpenOfflineInvoice(ctx context.Context, orderID uuid.UUID, amount decimal.Decimal, currency, method, warehouse, region string) (*Billing.Invoice, error)
+	OpenTermsInvoice(ctx context.Context, orderID, customerID uuid.UUID, amount decimal.Decimal, currency, terms string) (*Billing.Invoice, error)
+	CaptureOrder(ctx context.Context, orderID uuid.UUID) (*Billing.Payment, error)
+	OrderCancelled(ctx context.Context, orderID uuid.UUID) error
+	RefundOrder(ctx context.Context, orderID uuid.UUID, amount *decimal.Decimal, reason *string) ([]Billing.Refund, error)
+}
+
+// EventTracker records checkout funnel analytics events
+type EventTracker interface {
+	Track(ctx context.Context, name string, customerID *uuid.UUID, properties map[string]interface{})
+}
+
+// OrderListener is told about every order placed: checkout orders once their
+// payment is authorized, imported and till orders once they are recorded
+type OrderListener interface {
+	OrderPlaced(ctx context.Context, orderID uuid.UUID, total decimal.Decimal, currency string)
+}
+
+type Service interface {
+	Create(ctx context.Context, customerID *uuid.UUID, items []OrderItem, checkout Checkout) (*Order, error)
+	Checkout(ctx context.Context, req CreateOrderRequest) (*Order, error)
+	Get(ctx context.Context, id uuid.UUID) (*Order, []OrderItem, error)
+	Client(ctx context.Context, orderID uuid.UUID) (*ClientContext, error)
+	List(ctx context.Context, q ListOrdersQuery) ([]Order, error)
+	Count(ctx context.Context, q ListOrdersQuery) (int, error)
+	Recall(ctx context.Context, req RecallRequest) (*Recall, error)
+	TagOrders(ctx context.Context, ids []uuid.UUID, tag, reason string) ([]uuid.UUID, error)
+	UntagOrders(ctx context.Context, ids []uuid.UUID, tag, reason string) ([]uuid.UUID, error)
+	OrderTags(ctx context.Context, ids []uuid.UUID, tag string) (map[uuid.UUID]bool, error)
+	Changes(ctx context.Context, since time.Time, afterID uuid.UUID, until time.Time, limit int) ([]OrderResponse, error)
+	UpdateStatus(ctx context.Context, id uuid.UUID, status string, version int) error
+	CustomerCancel(ctx context.Context, id, customerID uuid.UUID, reason string) (*Cancellation, error)
+	RetryPayment(ctx context.Context, id uuid.UUID, paymentMethod string) (*Order, error)
+	Transition(ctx context.Context, id uuid.UUID, status string) error
+	CheckTransition(ctx context.Context, id uuid.UUID, status string) (string, error)
+	Freeze(ctx context.Context, id uuid.UUID, reason string) error
+	ArchiveOrders(ctx context.Context, before time.Time) error
+	Unfreeze(ctx context.Context, id uuid.UUID) error
+	Import(ctx context.Context, orders []ImportOrderRequest) []ImportOrderResult
+	POS(ctx context.Context, warehouse string, orders []POSOrderRequest) []POSOrderResult
+	AddNote(ctx context.Context, orderID uuid.UUID, req CreateNoteRequest) (*OrderNote, error)
+	Attach(ctx context.Context, orderID uuid.UUID, req AttachRequest, body io.Reader, uploadedBy string) (*OrderAttachment, error)
+	Attachments(ctx context.Context, orderID uuid.UUID) ([]OrderAttachment, error)
+	Attachment(ctx context.Context, orderID, id uuid.UUID) (*OrderAttachment, error)
+	DeleteAttachment(ctx context.Context, orderID, id uuid.UUID) error
+	Timeline(ctx context.Context, orderID uuid.UUID) ([]TimelineEntry, error)
+
+	InvoicePaid(ctx context.Context, orderID uuid.UUID) error
+	OrderDelivered(ctx context.Context, orderID uuid.UUID) error
+	DownloadLinks(ctx context.Context, orderID uuid.UUID) ([]DownloadLink, error)
+	ConsumeDownload(ctx context.Context, id uuid.UUID, expires int64, signature string) (*DownloadLink, error)
+
+	PackingSlip(ctx context.Context, id uuid.UUID) (*PackingSlip, error)
+	PickList(ctx context.Context, warehouse string) ([]PickListLine, error)
+	Preorders(ctx context.Context) ([]PreorderGroup, error)
+	AllocatePreorders(ctx context.Context) error
+
+	ListPurchaseLimits(ctx context.Context) ([]PurchaseLimit, error)
+	SetPurchaseLimit(ctx context.Context, productID uuid.UUID, req SetPurchaseLimitRequest) (*PurchaseLimit, error)
+	DeletePurchaseLimit(ctx context.Context, productID uuid.UUID) error
+
+	SLAOrders(ctx context.Context, within time.Duration) ([]SLAOrder, error)
+	SLAMetrics(ctx context.Context, from, to time.Time) ([]SLAAttainment, error)
+
+	GetOrderStatistics(ctx context.Context, from, to time.Time) (*OrderStatistics, error)
+	WeeklyStatistics(ctx context.Context, from, to time.Time, loc *time.Location, weekStart time.Weekday) ([]WeeklyOrderTotals, error)
+	RefreshStatistics(ctx context.Context) error
+	BackfillStatistics(ctx context.Context, from, to time.Time) error
+	OrderMargins(ctx context.Context, from, to time.Time, limit, offset int) ([]OrderMargin, error)
+	ProductMargins(ctx context.Context, from, to time.Time, limit, offset int) ([]ProductMargin, error)
+}
+
+// Options are the service's optional collaborators; each one left nil turns
+// its feature off
+type Options struct {
+	Payments    Payments // orders are placed unpaid without one
+	Tracker     EventTracker
+	Notifier    Notifier // customers are notified only with Customers as well
+	Customers   Customers
+	Shipping    ShippingCalculator // shipping is free without one
+	Listener    OrderListener
+	Slots       Slots
+	Coupons     CouponRedeemer
+	GiftCards   GiftCardRedeemer
+	Fraud       FraudChecker // orders are placed unscreened without one
+	Attachments AttachmentStore
 }
 
 type service struct {
-	repo Repository
-	db   *sqlx.DB
-	inv  InventoryService
-	log  *zap.Logger
+	repo        Repository
+	db          *sqlx.DB
+	inv         InventoryService
+	payments    Payments
+	tracker     EventTracker
+	notifier    Notifier
+	customers   Customers
+	downloads   DownloadConfig
+	limits      Limits
+	sla         SLAPolicy
+	shipping    ShippingCalculator
+	listener    OrderListener
+	slots       Slots
+	coupons     CouponRedeemer
+	giftCards   GiftCardRedeemer
+	fraud       FraudChecker
+	attachments AttachmentStore
+	log         *zap.Logger
+}
+
+func NewService(r Repository, db *sqlx.DB, inv InventoryService, downloads DownloadConfig, limits Limits, sla SLAPolicy, opts Options, log *zap.Logger) Service {
+	return &service{repo: r, db: db, inv: inv, payments: opts.Payments, tracker: opts.Tracker, notifier: opts.Notifier, customers: opts.Customers, downloads: downloads, limits: limits, sla: sla, shipping: opts.Shipping, listener: opts.Listener, slots: opts.Slots, coupons: opts.Coupons, giftCards: opts.GiftCards, fraud: opts.Fraud, attachments: opts.Attachments, log: log}
 }
 
-func NewService(r Repository, db *sqlx.DB, inv InventoryService, log *zap.Logger) *service {
-	return &service{repo: r, db: db, inv: inv, log: log}
+func (s *service) placed(ctx context.Context, order *Order) {
+	if s.listener != nil {
+		s.listener.OrderPlaced(ctx, order.ID, order.Total, order.Currency)
+	}
+}
+
+func (s *service) track(ctx context.Context, name string, customerID *uuid.UUID, properties map[string]interface{}) {
+	if s.tracker != nil {
+		s.tracker.Track(ctx, name, customerID, properties)
+	}
 }
 
-func (s *service) Create(ctx context.Context, customerID *uuid.UUID, items []OrderItem, warehouse string) (*Order, error) {
-	// calculate totals
+// Create places the order and authorizes (but does not capture) its payment;
+// the funds are captured when the order ships, or at once when nothing in it
+// ships (digital goods and services). Orders paid offline are
+// confirmed straight away with an unpaid invoice. When the payment fails the
+// order is kept as PAYMENT_FAILED and returned in a PaymentError, so the
+// client can retry with RetryPayment. Shipping is quoted from the weight and
+// size of the physical items.
+// Orders for pre-order products are taken as PREORDER without reserving or
+// charging anything; AllocatePreorders does both later.
+func (s *service) Create(ctx context.Context, customerID *uuid.UUID, items []OrderItem, checkout Checkout) (*Order, error) {
+	if err := checkTerms(customerID, &checkout); err != nil {
+		return nil, err
+	}
+	if customerID != nil {
+		if err := s.checkLimits(ctx, *customerID, items); err != nil {
+			return nil, err
+		}
+	}
+	preorder, err := s.isPreorder(ctx, items)
+	if err != nil {
+		return nil, err
+	}
+	// a checkout holding its stock is placed from the hold's warehouse
+	if checkout.HoldID != nil && checkout.Warehouse == "" && !preorder {
+		if h, err := s.inv.GetHold(ctx, *checkout.HoldID); err == nil && h.Status == Inventory.HoldActive {
+			checkout.Warehouse = h.Warehouse
+		}
+	}
+	// pre-orders are routed when they are allocated
+	warehouse := checkout.Warehouse
+	if !preorder {
+		if warehouse, err = s.route(ctx, checkout.Warehouse, checkout.Country, checkout.Region, items); err != nil {
+			return nil, err
+		}
+	}
+	s.track(ctx, Analytics.EventCheckoutStarted, customerID, map[string]interface{}{"items": len(items), "warehouse": warehouse})
+	currency := checkout.Currency
+	if currency == "" {
+		currency = "USD"
+	}
+	order := newOrder(customerID, items, currency)
+	if order.Shipping, err = s.shippingCost(ctx, items, currency, checkout.Country, checkout.Region); err != nil {
+		return nil, err
+	}
+	order.Total = order.Total.Add(order.Shipping)
+	if err := s.applyCoupon(ctx, order, items, checkout.CouponCode); err != nil {
+		return nil, err
+	}
+	order.Status = "CREATED"
+	order.Channel = checkout.Channel
+	if order.Channel == "" {
+		order.Channel = ChannelWeb
+	}
+	order.Warehouse = optional(warehouse)
+	order.Country = optional(checkout.Country)
+	order.Region = optional(checkout.Region)
+	order.PaymentMethod = &checkout.PaymentMethod
+	order.PONumber = optional(checkout.PONumber)
+	order.PaymentTerms = optional(checkout.PaymentTerms)
+	switch {
+	case preorder:
+		order.Status = StatusPreorder
+	case Billing.IsOfflineMethod(checkout.PaymentMethod):
+		order.Status = "CONFIRMED"
+	}
+	s.applySLA(order, checkout.Priority)
+	if err := s.screen(ctx, order, items, checkout.Client); err != nil {
+		s.releaseDiscounts(ctx, order)
+		return nil, err
+	}
+	if err := s.applyGiftCard(ctx, order, checkout.GiftCardCode); err != nil {
+		s.releaseDiscounts(ctx, order)
+		return nil, err
+	}
+	if err := s.place(ctx, order, items, warehouse, checkout.HoldID); err != nil {
+		s.releaseDiscounts(ctx, order)
+		return nil, err
+	}
+	s.recordClient(ctx, order, checkout.Client)
+	if preorder {
+		s.track(ctx, Analytics.EventPreorderPlaced, customerID, map[string]interface{}{"order_id": order.ID, "total": order.Total, "currency": order.Currency, "items": len(items)})
+		return order, nil
+	}
+	if err := s.pay(ctx, order, checkout.PaymentMethod); err != nil {
+		s.abandon(ctx, order, items, warehouse)
+		return nil, &PaymentError{Order: order, Err: err}
+	}
+	s.captureUnshipped(ctx, order, items, checkout.PaymentMethod)
+	s.track(ctx, Analytics.EventOrderCompleted, customerID, map[string]interface{}{"order_id": order.ID, "total": order.Total, "currency": order.Currency, "items": len(items)})
+	s.placed(ctx, order)
+	return order, nil
+}
+
+// route returns warehouse when given, otherwise asks the routing rules for
+// one that can ship every stocked item to country/region. An order with
+// nothing to ship needs no warehouse.
+func (s *service) route(ctx context.Context, warehouse, country, region string, items []OrderItem) (string, error) {
+	if warehouse != "" {
+		return warehouse, nil
+	}
+	stock, err := s.stocked(ctx, items)
+	if err != nil || len(stock) == 0 {
+		return "", err
+	}
+	quantities := make(map[uuid.UUID]int, len(stock))
+	for _, it := range stock {
+		if it.ProductID != nil {
+			quantities[it.stockID()] += it.Quantity
+		}
+	}
+	return s.inv.SelectWarehouse(ctx, country, region, quantities)
+}
+
+func optional(v string) *string {
+	if v == "" {
+		return nil
+	}
+	return &v
+}
+
+// abandon releases the reservations and booked slots of an order whose
+// payment was declined and marks it PAYMENT_FAILED, refreshing order with
+// the stored status.
+func (s *service) abandon(ctx context.Context, order *Order, items []OrderItem, warehouse string) {
+	s.release(ctx, order.ID, items, warehouse)
+	s.freeSlots(ctx, order.ID, items)
+	if err := s.Transition(ctx, order.ID, "PAYMENT_FAILED"); err != nil {
+		s.log.Error("mark order payment failed", zap.String("order_id", order.ID.String()), zap.Error(err))
+		return
+	}
+	if stored, _, err := s.repo.GetOrder(ctx, order.ID); err == nil {
+		*order = *stored
+	}
+}
+
+func (s *service) release(ctx context.Context, orderID uuid.UUID, items []OrderItem, warehouse string) {
+	stock, err := s.stocked(ctx, items)
+	if err != nil {
+		s.log.Error("release inventory", zap.String("order_id", orderID.String()), zap.Error(err))
+		return
+	}
+	for _, it := range stock {
+		if err := s.inv.Release(ctx, it.stockID(), it.Quantity, warehouse); err != nil {
+			s.log.Error("release inventory", zap.String("order_id", orderID.String()), zap.Error(err))
+		}
+	}
+}
+
+// checkTerms settles the payment method of a checkout on payment terms,
+// which only a known customer can use; whether they are approved and have
+// the credit is up to Billing when the order is paid
+func checkTerms(customerID *uuid.UUID, checkout *Checkout) error {
+	if checkout.PaymentTerms == "" {
+		if checkout.PaymentMethod == Billing.MethodTerms {
+			return ErrorTermsNeedCustomer
+		}
+		return nil
+	}
+	if _, err := Billing.ParseTerms(checkout.PaymentTerms); err != nil {
+		return err
+	}
+	if customerID == nil || (checkout.PaymentMethod != "" && checkout.PaymentMethod != Billing.MethodTerms) {
+		return ErrorTermsNeedCustomer
+	}
+	checkout.PaymentTerms = strings.ToUpper(checkout.PaymentTerms)
+	checkout.PaymentMethod = Billing.MethodTerms
+	return nil
+}
+
+// newOrder calculates totals for the given items
+func newOrder(customerID *uuid.UUID, items []OrderItem, currency string) *Order {
 	sub := decimal.NewFromInt(0)
 	for i := range items {
 		sub = sub.Add(items[i].LineTotal)
@@ -36,12 +331,63 @@ func (s *service) Create(ctx context.Context, customerID *uuid.UUID, items []Ord
 	tax := decimal.NewFromFloat(0)
 	shipping := decimal.NewFromFloat(0)
 	total := sub.Add(tax).Add(shipping)
-	order := &Order{CustomerID: customerID, Status: "CREATED", Subtotal: sub, Tax: tax, Shipping: shipping, Total: total, Currency: "USD", Version: 1}
+	return &Order{CustomerID: customerID, Subtotal: sub, Tax: tax, Shipping: shipping, Total: total, Currency: currency, Version: 1}
+}
+
+// place books the order's slots, reserves its stock and stores it;
+// pre-orders are stored without reserving. With a hold, the stock held is
+// converted into the order's reservations first and only the rest is
+// reserved; held stock the order doesn't need is released. A hold that
+// expired or was released leaves the order to reserve everything itself.
+func (s *service) place(ctx context.Context, order *Order, items []OrderItem, warehouse string, hold *uuid.UUID) error {
+	stock, err := s.stocked(ctx, items)
+	if err != nil {
+		return err
+	}
+	for _, it := range items {
+		if it.ProductID == nil {
+			return errors.New("product_id required")
+		}
+	}
+	if err = s.bookSlots(ctx, order.ID, items); err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			s.freeSlots(ctx, order.ID, items)
+		}
+	}()
+
+	// stock item -> quantity reserved for the order, released unless it is placed
+	reserved := map[uuid.UUID]int{}
+	if hold != nil && order.Status != StatusPreorder {
+		held, herr := s.inv.ConvertHold(ctx, *hold, warehouse)
+		if herr != nil {
+			s.log.Warn("stock hold not converted", zap.String("hold_id", hold.String()), zap.String("order_id", order.ID.String()), zap.Error(herr))
+		}
+		for id, qty := range held {
+			reserved[id] = qty
+		}
+	}
+	needed := map[uuid.UUID]int{}
+	defer func() {
+		for id, qty := range reserved {
+			if err == nil {
+				qty -= needed[id]
+			}
+			if qty <= 0 {
+				continue
+			}
+			if rerr := s.inv.Release(ctx, id, qty, warehouse); rerr != nil {
+				s.log.Error("release inventory", zap.String("order_id", order.ID.String()), zap.Error(rerr))
+			}
+		}
+	}()
 
 	// begin tx
 	tx, err := s.db.BeginTxx(ctx, nil)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer func() {
 		if err != nil {
@@ -50,46 +396,187 @@ func (s *service) Create(ctx context.Context, customerID *uuid.UUID, items []Ord
 	}()
 
 	// reserve inventory for each item
-	for _, it := range items {
-		if it.ProductID == nil {
-			err = errors.New("product_id required")
-			return nil, err
+	for _, it := range stock {
+		if order.Status == StatusPreorder {
+			break
 		}
-		if perr := s.inv.Reserve(ctx, *it.ProductID, it.Quantity, warehouse); perr != nil {
-			s.log.Error("reserve failed", zap.Error(perr))
-			err = perr
-			return nil, err
+		id := it.stockID()
+		needed[id] += it.Quantity
+		if short := needed[id] - reserved[id]; short > 0 {
+			if perr := s.inv.Reserve(ctx, id, short, warehouse); perr != nil {
+				s.log.Error("reserve failed", zap.Error(perr))
+				err = perr
+				return err
+			}
+			reserved[id] += short
 		}
 	}
 
 	if err = s.repo.CreateOrderTx(ctx, tx, order, items); err != nil {
-		return nil, err
+		return err
 	}
 	if err = tx.Commit(); err != nil {
-		return nil, err
+		return err
 	}
-	return order, nil
+	return nil
 }
 
 func (s *service) Get(ctx context.Context, id uuid.UUID) (*Order, []OrderItem, error) {
 	return s.repo.GetOrder(ctx, id)
 }
 
+func (s *service) List(ctx context.Context, q ListOrdersQuery) ([]Order, error) {
+	if q.Limit <= 0 {
+		q.Limit = 20
+	}
+	return s.repo.List(ctx, q)
+}
+
+// Count counts the orders matching the list filters, ignoring the page
+func (s *service) Count(ctx context.Context, q ListOrdersQuery) (int, error) {
+	return s.repo.Count(ctx, q)
+}
+
+// Changes returns orders, with their items, updated after the (since,
+// afterID) watermark and before until, oldest change first.
+func (s *service) Changes(ctx context.Context, since time.Time, afterID uuid.UUID, until time.Time, limit int) ([]OrderResponse, error) {
+	orders, err := s.repo.Changes(ctx, since, afterID, until, limit)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uuid.UUID, len(orders))
+	for i, o := range orders {
+		ids[i] = o.ID
+	}
+	items, err := s.repo.ItemsForOrders(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byOrder := map[uuid.UUID][]OrderItem{}
+	for _, it := range items {
+		byOrder[it.OrderID] = append(byOrder[it.OrderID], it)
+	}
+	discounts, err := s.repo.DiscountsForOrders(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	discountsOf := map[uuid.UUID][]OrderDiscount{}
+	for _, d := range discounts {
+		discountsOf[d.OrderID] = append(discountsOf[d.OrderID], d)
+	}
+	out := make([]OrderResponse, len(orders))
+	for i, o := range orders {
+		o.Discounts = discountsOf[o.ID]
+		out[i] = OrderResponse{Order: o, Items: byOrder[o.ID]}
+		if out[i].Items == nil {
+			out[i].Items = []OrderItem{}
+		}
+	}
+	return out, nil
+}
+
+// UpdateStatus is a client edit: it fails with ErrorVersionConflict when the
+// order changed since the caller read it at version.
 func (s *service) UpdateStatus(ctx context.Context, id uuid.UUID, status string, version int) error {
-	tx, err := s.db.BeginTxx(ctx, nil)
+	order, _, err := s.repo.GetOrder(ctx, id)
 	if err != nil {
 		return err
 	}
-	defer func() {
-		if err != nil {
-			_ = tx.Rollback()
-		}
-	}()
-	if err = s.repo.UpdateOrderStatusTx(ctx, tx, id, status, version); err != nil {
+	if order.Version != version {
+		return ErrorVersionConflict
+	}
+	return s.applyStatus(ctx, order, status)
+}
+
+// Freeze blocks status changes on the order, e.g. while a payment dispute is open
+func (s *service) Freeze(ctx context.Context, id uuid.UUID, reason string) error {
+	order, _, err := s.repo.GetOrder(ctx, id)
+	if err != nil || order.FrozenAt != nil {
 		return err
 	}
-	if err = tx.Commit(); err != nil {
+	if order.Archived {
+		return ErrorArchived
+	}
+	return s.repo.SetFrozen(ctx, id, &reason)
+}
+
+func (s *service) Unfreeze(ctx context.Context, id uuid.UUID) error {
+	order, _, err := s.repo.GetOrder(ctx, id)
+	if err != nil || order.FrozenAt == nil {
 		return err
 	}
-	return nil
+	return s.repo.SetFrozen(ctx, id, nil)
+}
+
+// Import persists orders captured by external channels. It is idempotent by
+// source + external reference and never calls the payment provider: the
+// marketplace has already collected payment.
+func (s *service) Import(ctx context.Context, orders []ImportOrderRequest) []ImportOrderResult {
+	results := make([]ImportOrderResult, 0, len(orders))
+	for _, req := range orders {
+		res := ImportOrderResult{ExternalReference: req.ExternalReference}
+		order, err := s.importOne(ctx, req)
+		switch {
+		case err == nil:
+			res.Status = ImportStatusCreated
+			res.OrderID = &order.ID
+		case errors.Is(err, ErrorDuplicateExternal):
+			res.Status = ImportStatusDuplicate
+			res.OrderID = &order.ID
+		default:
+			s.log.Error("import order", zap.String("external_reference", req.ExternalReference), zap.Error(err))
+			res.Status = ImportStatusFailed
+			res.Error = err.Error()
+		}
+		results = append(results, res)
+	}
+	return results
+}
+
+func (s *service) importOne(ctx context.Context, req ImportOrderRequest) (*Order, error) {
+	existing, err := s.repo.GetOrderByExternalReference(ctx, req.Source, req.ExternalReference)
+	if err == nil {
+		return existing, ErrorDuplicateExternal
+	}
+	if !errors.Is(err, ErrorNotFound) {
+		return nil, err
+	}
+	items := make([]OrderItem, 0, len(req.Items))
+	for _, it := range req.Items {
+		items = append(items, OrderItem{
+			ProductID:    it.ProductID,
+			VariantID:    it.VariantID,
+			SKU:          it.SKU,
+			Name:         it.Name,
+			UnitPrice:    it.UnitPrice,
+			Quantity:     it.Quantity,
+			LineTotal:    it.UnitPrice.Mul(decimal.NewFromInt(int64(it.Quantity))),
+			GiftWrap:     it.GiftWrap,
+			GiftMessage:  it.GiftMessage,
+			CustomerNote: it.CustomerNote,
+			SlotID:       it.SlotID,
+		})
+	}
+	warehouse, err := s.route(ctx, req.Warehouse, req.Country, req.Region, items)
+	if err != nil {
+		return nil, err
+	}
+	order := newOrder(req.CustomerID, items, req.Currency)
+	order.Status = "CONFIRMED"
+	order.ExternalReference = &req.ExternalReference
+	order.Source = &req.Source
+	order.Channel = MarketplaceChannel(req.Source)
+	order.Warehouse = optional(warehouse)
+	order.Country = optional(req.Country)
+	order.Region = optional(req.Region)
+	s.applySLA(order, req.Priority)
+	if err := s.place(ctx, order, items, warehouse, nil); err != nil {
+		// a concurrent import of the same reference won the unique index
+		if existing, gerr := s.repo.GetOrderByExternalReference(ctx, req.Source, req.ExternalReference); gerr == nil {
+			return existing, ErrorDuplicateExternal
+		}
+		return nil, err
+	}
+	s.placed(ctx, order)
+	return order, nil
 }
@@ -0,0 +1,102 @@
+package Orders
+
+import (
+	"context"
+	"math"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// ShippingItem is a physical product in an order with what its shipping is
+// priced on; the fields are nil where the catalog has no data
+type ShippingItem struct {
+	ProductID     uuid.UUID `db:"id"`
+	Quantity      int       `db:"-"`
+	WeightGrams   *int      `db:"weight_grams"`
+	LengthMM      *int      `db:"length_mm"`
+	WidthMM       *int      `db:"width_mm"`
+	HeightMM      *int      `db:"height_mm"`
+	ShippingClass *string   `db:"shipping_class"`
+}
+
+// ShippingCalculator prices shipping the physical items of an order to
+// country/region; it is not asked about orders that ship nothing
+type ShippingCalculator interface {
+	Quote(ctx context.Context, currency, country, region string, items []ShippingItem) (decimal.Decimal, error)
+}
+
+// WeightRates charges Base plus PerKg for every started kilogram of billable
+// weight, plus the surcharge of each shipping class in the order. An item's
+// billable weight is the greater of its weight and its volumetric weight,
+// its volume over VolumetricDivisor (in cm³ per kg, 5000 for most carriers;
+// zero ignores volume). An order with an item missing its weight pays
+// Fallback instead. Orders in another currency than Currency ship free.
+type WeightRates struct {
+	Currency          string
+	Base              decimal.Decimal
+	PerKg             decimal.Decimal
+	Classes           map[string]decimal.Decimal
+	Fallback          decimal.Decimal
+	VolumetricDivisor int
+}
+
+// Quote implements ShippingCalculator.
+func (r WeightRates) Quote(_ context.Context, currency, _, _ string, items []ShippingItem) (decimal.Decimal, error) {
+	if currency != r.Currency || len(items) == 0 {
+		return decimal.Zero, nil
+	}
+	var grams int64
+	classes := map[string]bool{}
+	for _, it := range items {
+		if it.WeightGrams == nil {
+			return r.Fallback, nil
+		}
+		weight := int64(*it.WeightGrams)
+		if r.VolumetricDivisor > 0 && it.LengthMM != nil && it.WidthMM != nil && it.HeightMM != nil {
+			// mm³ over cm³ per kg is grams
+			if v := int64(*it.LengthMM) * int64(*it.WidthMM) * int64(*it.HeightMM) / int64(r.VolumetricDivisor); v > weight {
+				weight = v
+			}
+		}
+		grams += weight * int64(it.Quantity)
+		if it.ShippingClass != nil {
+			classes[*it.ShippingClass] = true
+		}
+	}
+	cost := r.Base.Add(r.PerKg.Mul(decimal.NewFromFloat(math.Ceil(float64(grams) / 1000))))
+	for class := range classes {
+		cost = cost.Add(r.Classes[class])
+	}
+	return cost, nil
+}
+
+// shippingCost quotes shipping the order's physical items; without a
+// calculator shipping is free
+func (s *service) shippingCost(ctx context.Context, items []OrderItem, currency, country, region string) (decimal.Decimal, error) {
+	if s.shipping == nil {
+		return decimal.Zero, nil
+	}
+	quantities := map[uuid.UUID]int{}
+	ids := []uuid.UUID{}
+	for _, it := range items {
+		if it.ProductID == nil {
+			continue
+		}
+		if _, seen := quantities[*it.ProductID]; !seen {
+			ids = append(ids, *it.ProductID)
+		}
+		quantities[*it.ProductID] += it.Quantity
+	}
+	if len(ids) == 0 {
+		return decimal.Zero, nil
+	}
+	data, err := s.repo.ShippingData(ctx, ids)
+	if err != nil || len(data) == 0 {
+		return decimal.Zero, err
+	}
+	for i := range data {
+		data[i].Quantity = quantities[data[i].ProductID]
+	}
+	return s.shipping.Quote(ctx, currency, country, region, data)
+}
@@ -0,0 +1,84 @@
+package Orders
+
+import (
+	"context"
+	"time"
+)
+
+// order priorities
+const (
+	PriorityStandard = "standard"
+	PriorityExpress  = "express"
+)
+
+// SLAPolicy is how long an order of a priority may stay in a status.
+// Statuses without an entry carry no deadline.
+type SLAPolicy map[string]map[string]time.Duration
+
+// DefaultSLAPolicy ships standard orders within two days and express orders
+// within 24 hours of being placed.
+var DefaultSLAPolicy = SLAPolicy{
+	PriorityStandard: {"CREATED": 12 * time.Hour, "CONFIRMED": 12 * time.Hour, "PROCESSING": 24 * time.Hour},
+	PriorityExpress:  {"CREATED": 2 * time.Hour, "CONFIRMED": 4 * time.Hour, "PROCESSING": 18 * time.Hour},
+}
+
+// Deadline is when an order entering status at from must have left it, or nil
+func (p SLAPolicy) Deadline(priority, status string, from time.Time) *time.Time {
+	d, ok := p[priority][status]
+	if !ok {
+		return nil
+	}
+	due := from.Add(d)
+	return &due
+}
+
+// statuses tracked by the policy
+func (p SLAPolicy) statuses() []string {
+	seen := map[string]bool{}
+	out := []string{}
+	for _, byStatus := range p {
+		for status := range byStatus {
+			if !seen[status] {
+				seen[status] = true
+				out = append(out, status)
+			}
+		}
+	}
+	return out
+}
+
+// applySLA sets the priority default and the deadline for the order's initial status
+func (s *service) applySLA(o *Order, priority string) {
+	if priority == "" {
+		priority = PriorityStandard
+	}
+	o.Priority = priority
+	o.SLADueAt = s.sla.Deadline(priority, o.Status, time.Now().UTC())
+}
+
+// SLAOrders lists open orders already past their SLA deadline or due within the given window
+func (s *service) SLAOrders(ctx context.Context, within time.Duration) ([]SLAOrder, error) {
+	now := time.Now().UTC()
+	orders, err := s.repo.ListSLAOrders(ctx, now.Add(within), s.sla.statuses())
+	if err != nil {
+		return nil, err
+	}
+	for i := range orders {
+		orders[i].Breached = orders[i].SLADueAt.Before(now)
+	}
+	return orders, nil
+}
+
+// SLAMetrics reports SLA attainment per priority and status for statuses left in [from, to)
+func (s *service) SLAMetrics(ctx context.Context, from, to time.Time) ([]SLAAttainment, error) {
+	rows, err := s.repo.SLAAttainment(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	for i := range rows {
+		if rows[i].Total > 0 {
+			rows[i].Attainment = float64(rows[i].Met) / float64(rows[i].Total)
+		}
+	}
+	return rows, nil
+}
@@ -0,0 +1,76 @@
+package Orders
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"savannah/src/Booking"
+)
+
+// Slots books the time slots of bookable products (rentals, appointments)
+type Slots interface {
+	Book(ctx context.Context, slotID, productID uuid.UUID, qty int) error
+	Unbook(ctx context.Context, slotID uuid.UUID, qty int) error
+	Bookable(ctx context.Context, productIDs []uuid.UUID) (map[uuid.UUID]bool, error)
+}
+
+// requireSlots rejects a checkout ordering a bookable product without
+// naming the slot it books
+func (s *service) requireSlots(ctx context.Context, items []OrderItem) error {
+	if s.slots == nil {
+		return nil
+	}
+	ids := make([]uuid.UUID, 0, len(items))
+	for _, it := range items {
+		if it.ProductID != nil && it.SlotID == nil {
+			ids = append(ids, *it.ProductID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	bookable, err := s.slots.Bookable(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if bookable[id] {
+			return Booking.ErrorSlotRequired
+		}
+	}
+	return nil
+}
+
+// bookSlots takes the slot capacity of the items naming a slot; when one
+// can't be booked those booked already are freed
+func (s *service) bookSlots(ctx context.Context, orderID uuid.UUID, items []OrderItem) error {
+	if s.slots == nil {
+		return nil
+	}
+	for i, it := range items {
+		if it.SlotID == nil || it.ProductID == nil {
+			continue
+		}
+		if err := s.slots.Book(ctx, *it.SlotID, *it.ProductID, it.Quantity); err != nil {
+			s.freeSlots(ctx, orderID, items[:i])
+			return err
+		}
+	}
+	return nil
+}
+
+// freeSlots gives back the slot capacity the items booked
+func (s *service) freeSlots(ctx context.Context, orderID uuid.UUID, items []OrderItem) {
+	if s.slots == nil {
+		return
+	}
+	for _, it := range items {
+		if it.SlotID == nil {
+			continue
+		}
+		if err := s.slots.Unbook(ctx, *it.SlotID, it.Quantity); err != nil {
+			s.log.Error("free booking slot", zap.String("order_id", orderID.String()), zap.String("slot_id", it.SlotID.String()), zap.Error(err))
+		}
+	}
+}
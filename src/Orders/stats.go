@@ -0,0 +1,203 @@
+package Orders
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// MaxStatsDays is the widest window GetOrderStatistics and BackfillStatistics accept
+	MaxStatsDays = 366
+	// days re-aggregated by every RefreshStatistics run, to pick up late status changes
+	statsLookbackDays = 2
+)
+
+// statsWindow truncates [from, to) to whole UTC days and enforces MaxStatsDays
+func statsWindow(from, to time.Time) (time.Time, time.Time, error) {
+	from = from.UTC().Truncate(24 * time.Hour)
+	to = to.UTC().Truncate(24 * time.Hour)
+	if !from.Before(to) {
+		return from, to, ErrorInvalidRange
+	}
+	if days := int(to.Sub(from).Hours() / 24); days > MaxStatsDays {
+		return from, to, &StatsRangeError{MaxDays: MaxStatsDays, RequestedDays: days}
+	}
+	return from, to, nil
+}
+
+// GetOrderStatistics summarises orders placed in the days [from, to) from the
+// daily rollups. Today's rollup is refreshed first so the figures are current.
+func (s *service) GetOrderStatistics(ctx context.Context, from, to time.Time) (*OrderStatistics, error) {
+	from, to, err := statsWindow(from, to)
+	if err != nil {
+		return nil, err
+	}
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	if to.After(today) {
+		if err := s.repo.RefreshDailyStats(ctx, today, today.AddDate(0, 0, 1)); err != nil {
+			return nil, err
+		}
+	}
+	daily, err := s.repo.DailyStats(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	stats := &OrderStatistics{From: from, To: to, Totals: []CurrencyTotals{}, ByChannel: []ChannelTotals{}, ByStatus: map[string]int{}, Daily: daily}
+	totals := map[string]*CurrencyTotals{}
+	channels := map[[2]string]*ChannelTotals{}
+	for _, d := range daily {
+		stats.ByStatus[d.Status] += d.Orders
+		if d.Status == "CANCELLED" || d.Status == "PAYMENT_FAILED" {
+			continue
+		}
+		t, ok := totals[d.Currency]
+		if !ok {
+			t = &CurrencyTotals{Currency: d.Currency, Revenue: decimal.Zero}
+			totals[d.Currency] = t
+		}
+		t.Orders += d.Orders
+		t.Revenue = t.Revenue.Add(d.Revenue)
+		t.add(d.CostedSales, d.Cost, d.UncostedItems)
+		c, ok := channels[[2]string{d.Channel, d.Currency}]
+		if !ok {
+			c = &ChannelTotals{Channel: d.Channel, Currency: d.Currency, Revenue: decimal.Zero}
+			channels[[2]string{d.Channel, d.Currency}] = c
+		}
+		c.Orders += d.Orders
+		c.Revenue = c.Revenue.Add(d.Revenue)
+		c.add(d.CostedSales, d.Cost, d.UncostedItems)
+	}
+	for _, t := range totals {
+		if t.Orders > 0 {
+			t.AverageOrderValue = t.Revenue.Div(decimal.NewFromInt(int64(t.Orders))).Round(2)
+		}
+		t.finish()
+		stats.Totals = append(stats.Totals, *t)
+	}
+	sort.Slice(stats.Totals, func(i, j int) bool { return stats.Totals[i].Currency < stats.Totals[j].Currency })
+	for _, c := range channels {
+		if c.Orders > 0 {
+			c.AverageOrderValue = c.Revenue.Div(decimal.NewFromInt(int64(c.Orders))).Round(2)
+		}
+		c.finish()
+		stats.ByChannel = append(stats.ByChannel, *c)
+	}
+	sort.Slice(stats.ByChannel, func(i, j int) bool {
+		a, b := stats.ByChannel[i], stats.ByChannel[j]
+		if a.Channel != b.Channel {
+			return a.Channel < b.Channel
+		}
+		return a.Currency < b.Currency
+	})
+	return stats, nil
+}
+
+// WeeklyStatistics sums orders placed in the days [from, to) per currency and
+// week of loc, weeks beginning on weekStart. Unlike the daily rollups, which
+// are UTC days, the weeks follow loc's midnights; the first and last are cut
+// to the window.
+func (s *service) WeeklyStatistics(ctx context.Context, from, to time.Time, loc *time.Location, weekStart time.Weekday) ([]WeeklyOrderTotals, error) {
+	from, to, err := statsWindow(from, to)
+	if err != nil {
+		return nil, err
+	}
+	weeks, err := s.repo.WeeklyStats(ctx, from, to, loc.String(), (8-int(weekStart))%7, voidStatuses)
+	if err != nil {
+		return nil, err
+	}
+	for i := range weeks {
+		d := weeks[i].Week
+		start := time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, loc)
+		end := start.AddDate(0, 0, 7)
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		weeks[i].Start, weeks[i].End = start.UTC(), end.UTC()
+	}
+	return weeks, nil
+}
+
+// ParseWeekday reads a day name such as "monday" or "Sun"
+func ParseWeekday(s string) (time.Weekday, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := strings.ToLower(d.String())
+		if len(s) >= 3 && strings.HasPrefix(name, s) {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown weekday %q", s)
+}
+
+func (m *Margin) add(costedSales, cost decimal.Decimal, uncostedItems int) {
+	m.CostedSales = m.CostedSales.Add(costedSales)
+	m.Cost = m.Cost.Add(cost)
+	m.UncostedItems += uncostedItems
+}
+
+// finish derives the margin from the summed sales and cost; the percentage
+// is left out when nothing with a known cost was sold
+func (m *Margin) finish() {
+	m.GrossMargin = m.CostedSales.Sub(m.Cost)
+	if m.CostedSales.IsPositive() {
+		pct, _ := m.GrossMargin.Div(m.CostedSales).Mul(decimal.NewFromInt(100)).Round(2).Float64()
+		m.MarginPercent = &pct
+	}
+}
+
+// RefreshStatistics re-aggregates the last few days; run periodically by the scheduler.
+func (s *service) RefreshStatistics(ctx context.Context) error {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	return s.repo.RefreshDailyStats(ctx, today.AddDate(0, 0, -statsLookbackDays), today.AddDate(0, 0, 1))
+}
+
+// BackfillStatistics rebuilds the rollups of [from, to), e.g. after importing history
+func (s *service) BackfillStatistics(ctx context.Context, from, to time.Time) error {
+	from, to, err := statsWindow(from, to)
+	if err != nil {
+		return err
+	}
+	return s.repo.RefreshDailyStats(ctx, from, to)
+}
+
+// OrderMargins reports the gross margin of each order placed in the days
+// [from, to), leaving out cancelled and unpaid orders
+func (s *service) OrderMargins(ctx context.Context, from, to time.Time, limit, offset int) ([]OrderMargin, error) {
+	from, to, err := statsWindow(from, to)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.repo.OrderMargins(ctx, from, to, voidStatuses, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	for i := range rows {
+		rows[i].finish()
+	}
+	return rows, nil
+}
+
+// ProductMargins reports the gross margin of each product sold in the days
+// [from, to), leaving out cancelled and unpaid orders
+func (s *service) ProductMargins(ctx context.Context, from, to time.Time, limit, offset int) ([]ProductMargin, error) {
+	from, to, err := statsWindow(from, to)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.repo.ProductMargins(ctx, from, to, voidStatuses, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	for i := range rows {
+		rows[i].finish()
+	}
+	return rows, nil
+}
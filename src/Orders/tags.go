@@ -0,0 +1,23 @@
+package Orders
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// TagOrders tags the orders, noting reason on their timelines, and returns
+// those tagged; orders already tagged or not found are left out
+func (s *service) TagOrders(ctx context.Context, ids []uuid.UUID, tag, reason string) ([]uuid.UUID, error) {
+	return s.repo.TagOrders(ctx, ids, tag, map[string]interface{}{"tag": tag, "reason": reason})
+}
+
+// UntagOrders removes tag from the orders and returns those that had it
+func (s *service) UntagOrders(ctx context.Context, ids []uuid.UUID, tag, reason string) ([]uuid.UUID, error) {
+	return s.repo.UntagOrders(ctx, ids, tag, map[string]interface{}{"tag": tag, "reason": reason})
+}
+
+// OrderTags reports, for those of ids that are orders, whether they have tag
+func (s *service) OrderTags(ctx context.Context, ids []uuid.UUID, tag string) (map[uuid.UUID]bool, error) {
+	return s.repo.OrderTags(ctx, ids, tag)
+}
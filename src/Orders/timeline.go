@@ -0,0 +1,28 @@
+package Orders
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func (s *service) AddNote(ctx context.Context, orderID uuid.UUID, req CreateNoteRequest) (*OrderNote, error) {
+	if _, _, err := s.repo.GetOrder(ctx, orderID); err != nil {
+		return nil, err
+	}
+	n := &OrderNote{ID: uuid.New(), OrderID: orderID, Author: req.Author, Body: req.Body, CreatedAt: time.Now().UTC()}
+	if err := s.repo.AddNote(ctx, n); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// Timeline returns the order's full history (events, payments, shipments,
+// notes and notifications) sorted chronologically.
+func (s *service) Timeline(ctx context.Context, orderID uuid.UUID) ([]TimelineEntry, error) {
+	if _, _, err := s.repo.GetOrder(ctx, orderID); err != nil {
+		return nil, err
+	}
+	return s.repo.Timeline(ctx, orderID)
+}
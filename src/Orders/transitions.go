@@ -0,0 +1,199 @@
+package Orders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"savannah/src/Notifications"
+)
+
+// transitions lists the statuses an order may move to from each status.
+// Only orders with nothing to ship complete without being SHIPPED.
+var transitions = map[string][]string{
+	"PREORDER":       {"PROCESSING", "CANCELLED", "PAYMENT_FAILED"},
+	"CREATED":        {"CONFIRMED", "PROCESSING", "COMPLETED", "CANCELLED", "PAYMENT_FAILED"},
+	"CONFIRMED":      {"PROCESSING", "SHIPPED", "COMPLETED", "CANCELLED", "PAYMENT_FAILED"},
+	"PROCESSING":     {"SHIPPED", "COMPLETED", "CANCELLED"},
+	"SHIPPED":        {"COMPLETED"},
+	"PAYMENT_FAILED": {"CREATED", "CONFIRMED", "CANCELLED"},
+}
+
+func canTransition(from, to string) bool {
+	for _, s := range transitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	maxTransitionAttempts = 4
+	transitionBackoff     = 25 * time.Millisecond
+)
+
+// Transition moves the order to status on behalf of the server (payment
+// callbacks, webhooks, jobs). A concurrent edit is not an error here: the
+// order is reloaded, the transition re-validated against its new status and
+// reapplied, a bounded number of times. Already being in status is a no-op.
+func (s *service) Transition(ctx context.Context, id uuid.UUID, status string) error {
+	var err error
+	for attempt := 1; attempt <= maxTransitionAttempts; attempt++ {
+		var order *Order
+		if order, _, err = s.repo.GetOrder(ctx, id); err != nil {
+			return err
+		}
+		if order.Status == status {
+			return nil
+		}
+		if err = s.applyStatus(ctx, order, status); !errors.Is(err, ErrorVersionConflict) {
+			return err
+		}
+		s.log.Debug("order transition conflict, retrying", zap.String("order_id", id.String()), zap.String("status", status), zap.Int("attempt", attempt))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt) * transitionBackoff):
+		}
+	}
+	return err
+}
+
+// CheckTransition is a dry run of Transition: it returns the order's status
+// and the error moving it to status would fail with, changing nothing.
+func (s *service) CheckTransition(ctx context.Context, id uuid.UUID, status string) (string, error) {
+	order, _, err := s.repo.GetOrder(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	if order.Status == status {
+		return order.Status, nil
+	}
+	return order.Status, s.checkStatus(ctx, order, status)
+}
+
+// applyStatus validates and persists the move of order (as loaded, at its
+// version) to status, closing the current SLA and starting the next one.
+// A cancelled order gives back its reserved stock, slots and discounts.
+func (s *service) applyStatus(ctx context.Context, order *Order, status string) error {
+	if err := s.checkStatus(ctx, order, status); err != nil {
+		return err
+	}
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	if err = s.repo.UpdateOrderStatusTx(ctx, tx, order.ID, status, order.Version); err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	if order.SLADueAt != nil {
+		if err = s.repo.RecordSLAResultTx(ctx, tx, order, now); err != nil {
+			return err
+		}
+	}
+	if err = s.repo.SetSLADeadlineTx(ctx, tx, order.ID, s.sla.Deadline(order.Priority, status, now)); err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+	// a failed payment already gave back the order's stock and slots
+	if status == "CANCELLED" && order.Status != "PAYMENT_FAILED" {
+		if items, ierr := s.repo.ItemsForOrders(ctx, []uuid.UUID{order.ID}); ierr == nil {
+			// a pre-order reserves nothing until it is allocated
+			if order.Status != StatusPreorder {
+				s.release(ctx, order.ID, items, deref(order.Warehouse))
+			}
+			s.freeSlots(ctx, order.ID, items)
+		} else {
+			s.log.Error("release order on cancellation", zap.String("order_id", order.ID.String()), zap.Error(ierr))
+		}
+	}
+	if status == "CANCELLED" {
+		s.releaseDiscounts(ctx, order)
+	}
+	if status == "CANCELLED" && s.payments != nil {
+		if perr := s.payments.OrderCancelled(ctx, order.ID); perr != nil {
+			s.log.Error("void payment on cancellation", zap.String("order_id", order.ID.String()), zap.Error(perr))
+		}
+	}
+	s.notifyStatus(ctx, order, status)
+	return nil
+}
+
+// checkStatus returns why order (as loaded) can't move to status, if it can't
+func (s *service) checkStatus(ctx context.Context, order *Order, status string) error {
+	if order.Archived {
+		return ErrorArchived
+	}
+	if order.FrozenAt != nil {
+		return ErrorOrderFrozen
+	}
+	if !canTransition(order.Status, status) {
+		return ErrorInvalidTransition
+	}
+	if status == "COMPLETED" && order.Status != "SHIPPED" {
+		items, err := s.repo.ItemsForOrders(ctx, []uuid.UUID{order.ID})
+		if err != nil {
+			return err
+		}
+		if none, err := s.shipsNothing(ctx, items); err != nil || !none {
+			if err != nil {
+				return err
+			}
+			return ErrorInvalidTransition
+		}
+	}
+	return nil
+}
+
+// customer-facing wording per status; statuses not listed aren't announced
+var statusMessages = map[string]string{
+	"CONFIRMED":      "has been confirmed",
+	"PROCESSING":     "is being prepared",
+	"SHIPPED":        "has shipped",
+	"COMPLETED":      "has been delivered",
+	"CANCELLED":      "has been cancelled",
+	"PAYMENT_FAILED": "is on hold because the payment failed; you can retry the payment",
+}
+
+// statuses the customer must hear about right away, even when digests are on
+var criticalStatuses = map[string]bool{"CANCELLED": true, "PAYMENT_FAILED": true}
+
+// notifyStatus emails the customer about the status change; failures are only logged
+func (s *service) notifyStatus(ctx context.Context, order *Order, status string) {
+	text, ok := statusMessages[status]
+	if !ok || order.CustomerID == nil || s.notifier == nil || s.customers == nil {
+		return
+	}
+	email, err := s.customers.Email(ctx, *order.CustomerID)
+	if err != nil {
+		s.log.Error("status notification recipient", zap.String("order_id", order.ID.String()), zap.Error(err))
+		return
+	}
+	ref := strings.ToUpper(order.ID.String()[:8])
+	msg := Notifications.Message{
+		Channel:  Notifications.ChannelEmail,
+		To:       email,
+		Subject:  fmt.Sprintf("Order %s %s", ref, text),
+		Body:     fmt.Sprintf("Your order %s %s.", ref, text),
+		Template: "order_status_changed",
+		Data:     map[string]interface{}{"order_id": order.ID, "reference": ref, "status": status},
+		OrderID:  &order.ID,
+		Critical: criticalStatuses[status],
+	}
+	if err := s.notifier.Send(ctx, msg); err != nil {
+		s.log.Error("send status notification", zap.String("order_id", order.ID.String()), zap.Error(err))
+	}
+}
@@ -0,0 +1,189 @@
+package Orders
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// txDB is a database whose transactions commit without doing anything; the
+// fake repository below keeps the data
+type txDB struct{}
+
+func (txDB) Connect(context.Context) (driver.Conn, error) { return txConn{}, nil }
+func (txDB) Driver() driver.Driver                        { return txDriver{} }
+
+type txDriver struct{}
+
+func (txDriver) Open(string) (driver.Conn, error) { return txConn{}, nil }
+
+type txConn struct{}
+
+func (txConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("no statements") }
+func (txConn) Close() error                        { return nil }
+func (txConn) Begin() (driver.Tx, error)           { return txConn{}, nil }
+func (txConn) Commit() error                       { return nil }
+func (txConn) Rollback() error                     { return nil }
+
+// fakeRepository keeps orders in memory; the methods it doesn't override
+// panic through the nil Repository
+type fakeRepository struct {
+	Repository
+	mu     sync.Mutex
+	orders map[uuid.UUID]*Order
+	items  map[uuid.UUID][]OrderItem
+}
+
+func (r *fakeRepository) add(o Order, items ...OrderItem) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range items {
+		items[i].OrderID = o.ID
+	}
+	r.orders[o.ID], r.items[o.ID] = &o, items
+}
+
+func (r *fakeRepository) GetOrder(ctx context.Context, id uuid.UUID) (*Order, []OrderItem, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	o, ok := r.orders[id]
+	if !ok {
+		return nil, nil, ErrorNotFound
+	}
+	copied := *o
+	return &copied, r.items[id], nil
+}
+
+func (r *fakeRepository) ItemsForOrders(ctx context.Context, ids []uuid.UUID) ([]OrderItem, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []OrderItem
+	for _, id := range ids {
+		out = append(out, r.items[id]...)
+	}
+	return out, nil
+}
+
+func (r *fakeRepository) UnstockedProducts(ctx context.Context, productIDs []uuid.UUID) ([]uuid.UUID, error) {
+	return nil, nil
+}
+
+func (r *fakeRepository) UpdateOrderStatusTx(ctx context.Context, tx *sqlx.Tx, id uuid.UUID, status string, version int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	o := r.orders[id]
+	if o.Version != version {
+		return ErrorVersionConflict
+	}
+	o.Status, o.Version = status, o.Version+1
+	return nil
+}
+
+func (r *fakeRepository) SetSLADeadlineTx(ctx context.Context, tx *sqlx.Tx, id uuid.UUID, dueAt *time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.orders[id].SLADueAt = dueAt
+	return nil
+}
+
+func (r *fakeRepository) RecordSLAResultTx(ctx context.Context, tx *sqlx.Tx, o *Order, completedAt time.Time) error {
+	return nil
+}
+
+// fakeInventory counts the units reserved per warehouse and stock item
+type fakeInventory struct {
+	InventoryService
+	mu       sync.Mutex
+	reserved map[string]map[uuid.UUID]int
+}
+
+func (f *fakeInventory) Reserve(ctx context.Context, productID uuid.UUID, qty int, warehouse string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.reserved[warehouse] == nil {
+		f.reserved[warehouse] = map[uuid.UUID]int{}
+	}
+	f.reserved[warehouse][productID] += qty
+	return nil
+}
+
+func (f *fakeInventory) Release(ctx context.Context, productID uuid.UUID, qty int, warehouse string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.reserved[warehouse][productID] < qty {
+		return errors.New("release quantity exceeds reserved")
+	}
+	f.reserved[warehouse][productID] -= qty
+	return nil
+}
+
+func (f *fakeInventory) Reserved(warehouse string, productID uuid.UUID) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.reserved[warehouse][productID]
+}
+
+func newTestService(t *testing.T) (*service, *fakeRepository, *fakeInventory) {
+	t.Helper()
+	db := sqlx.NewDb(sql.OpenDB(txDB{}), "postgres")
+	t.Cleanup(func() { db.Close() })
+	repo := &fakeRepository{orders: map[uuid.UUID]*Order{}, items: map[uuid.UUID][]OrderItem{}}
+	inv := &fakeInventory{reserved: map[string]map[uuid.UUID]int{}}
+	s := NewService(repo, db, inv, DownloadConfig{}, Limits{}, DefaultSLAPolicy, Options{}, zap.NewNop()).(*service)
+	return s, repo, inv
+}
+
+// placeTestOrder stores an order in status with its stock reserved the way
+// checkout reserves it
+func placeTestOrder(t *testing.T, s *service, repo *fakeRepository, status, warehouse string, qty int) (Order, uuid.UUID) {
+	t.Helper()
+	productID := uuid.New()
+	o := Order{ID: uuid.New(), Status: status, Warehouse: &warehouse, Priority: PriorityStandard, Version: 1}
+	repo.add(o, OrderItem{ID: uuid.New(), ProductID: &productID, Quantity: qty})
+	if status != StatusPreorder {
+		if err := s.inv.Reserve(context.Background(), productID, qty, warehouse); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return o, productID
+}
+
+func TestCancelReleasesReservedStock(t *testing.T) {
+	for _, status := range []string{"CREATED", "CONFIRMED", "PROCESSING"} {
+		t.Run(status, func(t *testing.T) {
+			s, repo, inv := newTestService(t)
+			o, productID := placeTestOrder(t, s, repo, status, "NBO", 3)
+
+			if err := s.Transition(context.Background(), o.ID, "CANCELLED"); err != nil {
+				t.Fatalf("cancel: %v", err)
+			}
+			if got := inv.Reserved("NBO", productID); got != 0 {
+				t.Errorf("reserved after cancel = %d, want 0", got)
+			}
+		})
+	}
+}
+
+func TestCancelPreorderReleasesNothing(t *testing.T) {
+	s, repo, inv := newTestService(t)
+	o, productID := placeTestOrder(t, s, repo, StatusPreorder, "NBO", 2)
+	// stock of the same item reserved by another order stays reserved
+	if err := inv.Reserve(context.Background(), productID, 2, "NBO"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Transition(context.Background(), o.ID, "CANCELLED"); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	if got := inv.Reserved("NBO", productID); got != 2 {
+		t.Errorf("reserved after cancelling a pre-order = %d, want 2", got)
+	}
+}
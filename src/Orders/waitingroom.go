@@ -0,0 +1,251 @@
+package Orders
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// waiting room targets
+const (
+	TargetProduct  = "product"
+	TargetCampaign = "campaign"
+)
+
+const (
+	ticketIdle    = 2 * time.Minute  // a waiting ticket not polled for this long is dropped
+	admitWindow   = 2 * time.Minute  // how long an admitted ticket holds its slot
+	targetsMaxAge = 30 * time.Second // how long the designated products are cached
+	pollAfter     = 5 * time.Second
+)
+
+// WaitingRoomTarget designates a product, or every product of a campaign
+// while it runs, for the waiting room
+type WaitingRoomTarget struct {
+	Type      string    `db:"target_type" json:"type"`
+	ID        uuid.UUID `db:"target_id" json:"id"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+// QueueTicket is a checkout's place in the waiting room. Once admitted the
+// checkout is retried with the token in X-Queue-Token before ExpiresAt.
+type QueueTicket struct {
+	Token      string    `json:"token"`
+	Status     string    `json:"status"`             // waiting or admitted
+	Position   int       `json:"position,omitempty"` // 1 is next
+	RetryAfter int       `json:"retry_after"`        // seconds until polling again
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+type WaitingRoomStatus struct {
+	Capacity int                 `json:"capacity"`
+	Running  int                 `json:"running"`
+	Admitted int                 `json:"admitted"`
+	Waiting  int                 `json:"waiting"`
+	Targets  []WaitingRoomTarget `json:"targets"`
+}
+
+type ticket struct {
+	token    string
+	seen     time.Time
+	admitted time.Time // zero while waiting
+}
+
+// WaitingRoom caps how many checkouts of designated products run at once.
+// A checkout over the cap is queued with a ticket instead of competing for
+// stock rows and connections; the client polls its position and retries
+// once admitted, first come first served. Zero capacity lets everything
+// through. Like Middleware.Concurrency the room is per instance, so tickets
+// need sticky routing when several instances serve checkouts.
+type WaitingRoom struct {
+	repo     Repository
+	capacity int
+	log      *zap.Logger
+
+	mu       sync.Mutex
+	running  int
+	admitted int
+	waiting  []*ticket
+	tickets  map[string]*ticket
+
+	targetsMu sync.Mutex
+	targets   map[uuid.UUID]bool
+	loadedAt  time.Time
+}
+
+func NewWaitingRoom(r Repository, capacity int, log *zap.Logger) *WaitingRoom {
+	return &WaitingRoom{repo: r, capacity: capacity, log: log, tickets: map[string]*ticket{}}
+}
+
+// Enter lets a checkout of productIDs through, returning the func to call
+// when it is done, or queues it and returns its ticket. token is the ticket
+// of a retried checkout, if any.
+func (w *WaitingRoom) Enter(ctx context.Context, productIDs []uuid.UUID, token string) (func(), *QueueTicket, error) {
+	if w.capacity <= 0 {
+		return func() {}, nil, nil
+	}
+	designated, err := w.designated(ctx, productIDs)
+	if err != nil || !designated {
+		return func() {}, nil, err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := time.Now().UTC()
+	w.expire(now)
+	if t, ok := w.tickets[token]; ok {
+		if t.admitted.IsZero() {
+			t.seen = now
+			return nil, w.view(t), nil
+		}
+		delete(w.tickets, token)
+		w.admitted--
+		w.running++
+		return w.leave, nil, nil
+	}
+	if len(w.waiting) == 0 && w.running+w.admitted < w.capacity {
+		w.running++
+		return w.leave, nil, nil
+	}
+	t := &ticket{token: newToken(), seen: now}
+	w.waiting = append(w.waiting, t)
+	w.tickets[t.token] = t
+	return nil, w.view(t), nil
+}
+
+func (w *WaitingRoom) leave() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.running--
+	w.admit(time.Now().UTC())
+}
+
+// Ticket reports where a ticket stands; polling keeps a waiting ticket
+// alive
+func (w *WaitingRoom) Ticket(token string) (*QueueTicket, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.expire(time.Now().UTC())
+	t, ok := w.tickets[token]
+	if !ok {
+		return nil, false
+	}
+	if t.admitted.IsZero() {
+		t.seen = time.Now().UTC()
+	}
+	return w.view(t), true
+}
+
+func (w *WaitingRoom) Status(ctx context.Context) (*WaitingRoomStatus, error) {
+	targets, err := w.repo.WaitingRoomTargets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.expire(time.Now().UTC())
+	return &WaitingRoomStatus{Capacity: w.capacity, Running: w.running, Admitted: w.admitted, Waiting: len(w.waiting), Targets: targets}, nil
+}
+
+func (w *WaitingRoom) AddTarget(ctx context.Context, targetType string, id uuid.UUID) (*WaitingRoomTarget, error) {
+	t := &WaitingRoomTarget{Type: targetType, ID: id}
+	if err := w.repo.AddWaitingRoomTarget(ctx, t); err != nil {
+		return nil, err
+	}
+	w.invalidate()
+	return t, nil
+}
+
+func (w *WaitingRoom) RemoveTarget(ctx context.Context, targetType string, id uuid.UUID) error {
+	if err := w.repo.DeleteWaitingRoomTarget(ctx, targetType, id); err != nil {
+		return err
+	}
+	w.invalidate()
+	return nil
+}
+
+// expire drops waiting tickets no longer polled and frees the slots of
+// admitted tickets not used in time
+func (w *WaitingRoom) expire(now time.Time) {
+	kept := w.waiting[:0]
+	for _, t := range w.waiting {
+		if now.Sub(t.seen) > ticketIdle {
+			delete(w.tickets, t.token)
+			continue
+		}
+		kept = append(kept, t)
+	}
+	clear(w.waiting[len(kept):])
+	w.waiting = kept
+	for token, t := range w.tickets {
+		if !t.admitted.IsZero() && now.Sub(t.admitted) > admitWindow {
+			delete(w.tickets, token)
+			w.admitted--
+		}
+	}
+	w.admit(now)
+}
+
+// admit hands free slots to the front of the queue
+func (w *WaitingRoom) admit(now time.Time) {
+	for len(w.waiting) > 0 && w.running+w.admitted < w.capacity {
+		t := w.waiting[0]
+		w.waiting[0] = nil
+		w.waiting = w.waiting[1:]
+		t.admitted = now
+		w.admitted++
+	}
+}
+
+func (w *WaitingRoom) view(t *ticket) *QueueTicket {
+	if !t.admitted.IsZero() {
+		return &QueueTicket{Token: t.token, Status: "admitted", ExpiresAt: t.admitted.Add(admitWindow)}
+	}
+	q := &QueueTicket{Token: t.token, Status: "waiting", RetryAfter: int(pollAfter.Seconds()), ExpiresAt: t.seen.Add(ticketIdle)}
+	for i, other := range w.waiting {
+		if other == t {
+			q.Position = i + 1
+			break
+		}
+	}
+	return q
+}
+
+// designated reports whether any of productIDs goes through the room
+func (w *WaitingRoom) designated(ctx context.Context, productIDs []uuid.UUID) (bool, error) {
+	w.targetsMu.Lock()
+	defer w.targetsMu.Unlock()
+	if w.targets == nil || time.Since(w.loadedAt) > targetsMaxAge {
+		ids, err := w.repo.WaitingRoomProducts(ctx)
+		if err != nil {
+			return false, err
+		}
+		w.targets = make(map[uuid.UUID]bool, len(ids))
+		for _, id := range ids {
+			w.targets[id] = true
+		}
+		w.loadedAt = time.Now()
+	}
+	for _, id := range productIDs {
+		if w.targets[id] {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (w *WaitingRoom) invalidate() {
+	w.targetsMu.Lock()
+	w.targets = nil
+	w.targetsMu.Unlock()
+}
+
+func newToken() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,75 @@
+package Pagination
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Params reads the page a list is asked for: limit, then either offset or
+// page (1-based, in pages of limit items), page winning when both are
+// given. A limit outside 1..max falls back to def; an unreadable offset or
+// page counts as the first page.
+func Params(q url.Values, def, max int) (limit, offset int) {
+	limit, _ = strconv.Atoi(q.Get("limit"))
+	if limit <= 0 || limit > max {
+		limit = def
+	}
+	if page, err := strconv.Atoi(q.Get("page")); err == nil && page > 1 {
+		return limit, (page - 1) * limit
+	}
+	if offset, _ = strconv.Atoi(q.Get("offset")); offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
+
+// Page is where a page of limit items from offset sits in a list of total
+type Page struct {
+	Limit      int
+	Offset     int
+	Total      int
+	Page       int // 1-based; a page starting mid-way counts as the one it starts in
+	TotalPages int
+}
+
+func New(limit, offset, total int) Page {
+	p := Page{Limit: max(limit, 1), Offset: max(offset, 0), Total: max(total, 0)}
+	p.Page = p.Offset/p.Limit + 1
+	p.TotalPages = (p.Total + p.Limit - 1) / p.Limit
+	return p
+}
+
+// Write describes the page in the response headers every paged list
+// shares, ahead of the list itself: X-Total-Count, X-Page and X-Total-Pages,
+// and a Link header (RFC 5988) to the first, previous, next and last pages.
+// The links keep the request's other parameters and page by offset, so
+// they follow on from a page starting at any offset.
+func Write(w http.ResponseWriter, r *http.Request, limit, offset, total int) {
+	p := New(limit, offset, total)
+	h := w.Header()
+	h.Set("X-Total-Count", strconv.Itoa(p.Total))
+	h.Set("X-Page", strconv.Itoa(p.Page))
+	h.Set("X-Total-Pages", strconv.Itoa(p.TotalPages))
+	links := []string{link(r, "first", p.Limit, 0)}
+	if p.Offset > 0 {
+		links = append(links, link(r, "prev", p.Limit, max(p.Offset-p.Limit, 0)))
+	}
+	if p.Offset+p.Limit < p.Total {
+		links = append(links, link(r, "next", p.Limit, p.Offset+p.Limit))
+	}
+	if p.TotalPages > 0 {
+		links = append(links, link(r, "last", p.Limit, (p.TotalPages-1)*p.Limit))
+	}
+	h.Set("Link", strings.Join(links, ", "))
+}
+
+func link(r *http.Request, rel string, limit, offset int) string {
+	q := r.URL.Query()
+	q.Del("page")
+	q.Set("limit", strconv.Itoa(limit))
+	q.Set("offset", strconv.Itoa(offset))
+	return fmt.Sprintf(`<%s?%s>; rel="%s"`, r.URL.Path, q.Encode(), rel)
+}
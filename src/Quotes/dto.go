@@ -0,0 +1,51 @@
+package Quotes
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+type ItemRequest struct {
+	ProductID uuid.UUID  `json:"product_id" validate:"required"`
+	VariantID *uuid.UUID `json:"variant_id,omitempty"`
+	Quantity  int        `json:"quantity" validate:"required,min=1"`
+	// negotiated price; the catalog price when left out
+	UnitPrice *decimal.Decimal `json:"unit_price,omitempty"`
+}
+
+type CreateQuoteRequest struct {
+	CustomerID uuid.UUID     `json:"customer_id" validate:"required"`
+	Currency   string        `json:"currency,omitempty" validate:"omitempty,len=3"`
+	Items      []ItemRequest `json:"items" validate:"required,min=1,max=200,dive"`
+	Note       *string       `json:"note,omitempty" validate:"omitempty,max=2000"`
+	// defaults to the configured validity from now
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// ReviseQuoteRequest replaces the items and, when given, the note and expiry
+type ReviseQuoteRequest struct {
+	Items     []ItemRequest `json:"items" validate:"required,min=1,max=200,dive"`
+	Note      *string       `json:"note,omitempty" validate:"omitempty,max=2000"`
+	ExpiresAt *time.Time    `json:"expires_at,omitempty"`
+}
+
+// AcceptQuoteRequest is the customer's acceptance. Revision, when sent, must
+// be the one they were shown.
+type AcceptQuoteRequest struct {
+	Revision      *int   `json:"revision,omitempty"`
+	PaymentMethod string `json:"payment_method" validate:"required_without=PaymentTerms,max=50"`
+	Country       string `json:"country,omitempty" validate:"omitempty,len=2"`
+	Region        string `json:"region,omitempty" validate:"omitempty,max=100"`
+	Warehouse     string `json:"warehouse,omitempty" validate:"omitempty,max=50"`
+	PONumber      string `json:"po_number,omitempty" validate:"omitempty,max=64"`
+	PaymentTerms  string `json:"payment_terms,omitempty" validate:"omitempty,max=10"`
+}
+
+type ListQuotesQuery struct {
+	Status     string
+	CustomerID *uuid.UUID
+	Limit      int
+	Offset     int
+}
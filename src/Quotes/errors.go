@@ -0,0 +1,29 @@
+package Quotes
+
+import (
+	"errors"
+
+	"savannah/src/ErrorCodes"
+)
+
+var (
+	ErrorNotFound         = errors.New("quote not found")
+	ErrorInvalidPayload   = errors.New("invalid quote payload")
+	ErrorUnknownCustomer  = errors.New("customer not found")
+	ErrorUnknownProduct   = errors.New("product or variant not found")
+	ErrorNotOpen          = errors.New("quote is no longer open")
+	ErrorExpired          = errors.New("quote has expired")
+	ErrorRevisionMismatch = errors.New("quote was revised since it was viewed")
+)
+
+func init() {
+	ErrorCodes.Register("QUOTES",
+		ErrorCodes.Def{N: 1, Slug: "NOT_FOUND", Err: ErrorNotFound},
+		ErrorCodes.Def{N: 2, Slug: "INVALID_PAYLOAD", Err: ErrorInvalidPayload},
+		ErrorCodes.Def{N: 3, Slug: "UNKNOWN_CUSTOMER", Err: ErrorUnknownCustomer},
+		ErrorCodes.Def{N: 4, Slug: "UNKNOWN_PRODUCT", Err: ErrorUnknownProduct},
+		ErrorCodes.Def{N: 5, Slug: "NOT_OPEN", Err: ErrorNotOpen},
+		ErrorCodes.Def{N: 6, Slug: "EXPIRED", Err: ErrorExpired},
+		ErrorCodes.Def{N: 7, Slug: "REVISION_MISMATCH", Err: ErrorRevisionMismatch},
+	)
+}
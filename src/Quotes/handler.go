@@ -0,0 +1,328 @@
+package Quotes
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"savannah/src/Billing"
+	"savannah/src/ErrorCodes"
+	"savannah/src/Inventory"
+	"savannah/src/Orders"
+	"savannah/src/Pagination"
+)
+
+type Handler struct {
+	svc Service
+	log *zap.Logger
+	v   *validator.Validate
+}
+
+func NewHandler(s Service, log *zap.Logger) *Handler {
+	return &Handler{svc: s, log: log, v: validator.New()}
+}
+
+// AcceptResponse is an accepted quote with the order it was turned into
+type AcceptResponse struct {
+	Quote *Quote        `json:"quote"`
+	Order *Orders.Order `json:"order"`
+}
+
+// List godoc
+// @Summary      List quotes
+// @Description  Newest first, without their items. An open quote past its expiry is listed as EXPIRED; filtering by status matches the stored status (OPEN, ACCEPTED or CANCELLED).
+// @Tags         quotes
+// @Produce      json
+// @Param        status       query     string  false  "OPEN, ACCEPTED or CANCELLED"
+// @Param        customer_id  query     string  false  "Customer ID"
+// @Param        limit        query     int     false  "Page size (max 100)"
+// @Param        offset       query     int     false  "Offset"
+// @Param        page         query     int     false  "Page of limit quotes, 1-based; instead of offset"
+// @Success      200          {array}   Quote
+// @Header       200          {string}  Link     "first, prev, next and last pages (RFC 5988), with X-Total-Count, X-Page and X-Total-Pages"
+// @Failure      400          {object}  map[string]interface{}
+// @Router       /quotes [get]
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	v := r.URL.Query()
+	q := ListQuotesQuery{Status: v.Get("status")}
+	if s := v.Get("customer_id"); s != "" {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			h.writeError(w, http.StatusBadRequest, "invalid customer_id")
+			return
+		}
+		q.CustomerID = &id
+	}
+	q.Limit, q.Offset = Pagination.Params(v, 50, 100)
+	quotes, err := h.svc.List(r.Context(), q)
+	var total int
+	if err == nil {
+		total, err = h.svc.Count(r.Context(), q)
+	}
+	if err != nil {
+		h.log.Error("list quotes", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to list quotes")
+		return
+	}
+	Pagination.Write(w, r, q.Limit, q.Offset, total)
+	h.writeJSON(w, http.StatusOK, quotes)
+}
+
+// Create godoc
+// @Summary      Create a quote
+// @Description  Prices each line at its unit_price, or at the catalog price in the quote's currency (USD by default) when none is negotiated. The quote stays open until expires_at (30 days by default). The response carries accept_url, the link the customer accepts the quote through; it is not shown again.
+// @Tags         quotes
+// @Accept       json
+// @Produce      json
+// @Param        payload  body      CreateQuoteRequest  true  "Quote"
+// @Success      201      {object}  Quote
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      422      {object}  map[string]interface{}
+// @Router       /quotes [post]
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	var dto CreateQuoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if err := h.v.Struct(dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	q, err := h.svc.Create(r.Context(), dto)
+	if err != nil {
+		h.quoteError(w, "create quote", err)
+		return
+	}
+	h.writeJSON(w, http.StatusCreated, q)
+}
+
+// Get godoc
+// @Summary      Get a quote
+// @Tags         quotes
+// @Produce      json
+// @Param        id   path      string  true  "Quote ID"
+// @Success      200  {object}  Quote
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /quotes/{id} [get]
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.id(w, r)
+	if !ok {
+		return
+	}
+	q, err := h.svc.Get(r.Context(), id)
+	if err != nil {
+		h.quoteError(w, "get quote", err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, q)
+}
+
+// Revise godoc
+// @Summary      Revise an open quote
+// @Description  Replaces the items (and the note and expiry when given) as a new revision; earlier revisions stay in the history. The accept link keeps working, but an acceptance naming an older revision is refused.
+// @Tags         quotes
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string              true  "Quote ID"
+// @Param        payload  body      ReviseQuoteRequest  true  "New terms"
+// @Success      200      {object}  Quote
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      404      {object}  map[string]interface{}
+// @Failure      409      {object}  map[string]interface{}
+// @Failure      422      {object}  map[string]interface{}
+// @Router       /quotes/{id} [put]
+func (h *Handler) Revise(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.id(w, r)
+	if !ok {
+		return
+	}
+	var dto ReviseQuoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if err := h.v.Struct(dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	q, err := h.svc.Revise(r.Context(), id, dto)
+	if err != nil {
+		h.quoteError(w, "revise quote", err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, q)
+}
+
+// Cancel godoc
+// @Summary      Cancel an open quote
+// @Tags         quotes
+// @Produce      json
+// @Param        id   path      string  true  "Quote ID"
+// @Success      200  {object}  Quote
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      409  {object}  map[string]interface{}
+// @Router       /quotes/{id}/cancel [post]
+func (h *Handler) Cancel(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.id(w, r)
+	if !ok {
+		return
+	}
+	q, err := h.svc.Cancel(r.Context(), id)
+	if err != nil {
+		h.quoteError(w, "cancel quote", err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, q)
+}
+
+// NewLink godoc
+// @Summary      Issue a new accept link
+// @Description  Replaces the accept link of an open quote, for when the original was lost or leaked; the old link stops working.
+// @Tags         quotes
+// @Produce      json
+// @Param        id   path      string  true  "Quote ID"
+// @Success      200  {object}  Quote
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      409  {object}  map[string]interface{}
+// @Router       /quotes/{id}/link [post]
+func (h *Handler) NewLink(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.id(w, r)
+	if !ok {
+		return
+	}
+	q, err := h.svc.NewLink(r.Context(), id)
+	if err != nil {
+		h.quoteError(w, "issue quote link", err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, q)
+}
+
+// Revisions godoc
+// @Summary      Quote revision history
+// @Description  Every revision of the quote, oldest first, with the items and terms it offered.
+// @Tags         quotes
+// @Produce      json
+// @Param        id   path      string  true  "Quote ID"
+// @Success      200  {array}   Revision
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /quotes/{id}/revisions [get]
+func (h *Handler) Revisions(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.id(w, r)
+	if !ok {
+		return
+	}
+	revs, err := h.svc.Revisions(r.Context(), id)
+	if err != nil {
+		h.quoteError(w, "list quote revisions", err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, revs)
+}
+
+// View godoc
+// @Summary      View a quote through its accept link
+// @Description  Public: the token in the accept link is the only credential.
+// @Tags         quotes
+// @Produce      json
+// @Param        token  path      string  true  "Accept token"
+// @Success      200    {object}  Quote
+// @Failure      404    {object}  map[string]interface{}
+// @Router       /quote-links/{token} [get]
+func (h *Handler) View(w http.ResponseWriter, r *http.Request) {
+	q, err := h.svc.View(r.Context(), chi.URLParam(r, "token"))
+	if err != nil {
+		h.quoteError(w, "get quote", err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, q)
+}
+
+// Accept godoc
+// @Summary      Accept a quote
+// @Description  Public: places an order for the quote's customer at the quoted prices, at most once per quote. Send the revision that was viewed to be sure the terms did not change in between (409 otherwise). When the payment fails the answer is 402 with the order, which stays linked to the quote and can be paid again.
+// @Tags         quotes
+// @Accept       json
+// @Produce      json
+// @Param        token    path      string              true  "Accept token"
+// @Param        payload  body      AcceptQuoteRequest  true  "Payment and delivery"
+// @Success      201      {object}  AcceptResponse
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      402      {object}  map[string]interface{}
+// @Failure      404      {object}  map[string]interface{}
+// @Failure      409      {object}  map[string]interface{}
+// @Failure      410      {object}  map[string]interface{}
+// @Failure      422      {object}  map[string]interface{}
+// @Router       /quote-links/{token}/accept [post]
+func (h *Handler) Accept(w http.ResponseWriter, r *http.Request) {
+	var dto AcceptQuoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if err := h.v.Struct(dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	q, o, err := h.svc.Accept(r.Context(), chi.URLParam(r, "token"), dto)
+	var payment *Orders.PaymentError
+	var limit *Orders.LimitError
+	switch {
+	case errors.As(err, &payment):
+		code, _ := ErrorCodes.For(payment)
+		h.writeJSON(w, http.StatusPaymentRequired, map[string]interface{}{"error": payment.Error(), "code": code, "quote": q, "order": payment.Order, "timestamp": time.Now().UTC()})
+	case errors.Is(err, Inventory.ErrorInsufficientStock):
+		h.writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, Billing.ErrorInvalidTerms):
+		h.writeError(w, http.StatusBadRequest, err.Error())
+	case errors.As(err, &limit), errors.Is(err, Inventory.ErrorNoWarehouse), errors.Is(err, Orders.ErrorTermsNeedCustomer), errors.Is(err, Orders.ErrorMixedPreorder):
+		h.writeError(w, http.StatusUnprocessableEntity, err.Error())
+	case err != nil:
+		h.quoteError(w, "accept quote", err)
+	default:
+		h.writeJSON(w, http.StatusCreated, AcceptResponse{Quote: q, Order: o})
+	}
+}
+
+func (h *Handler) id(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func (h *Handler) quoteError(w http.ResponseWriter, op string, err error) {
+	switch {
+	case errors.Is(err, ErrorNotFound):
+		h.writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrorInvalidPayload):
+		h.writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, ErrorUnknownCustomer), errors.Is(err, ErrorUnknownProduct):
+		h.writeError(w, http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, ErrorNotOpen), errors.Is(err, ErrorRevisionMismatch):
+		h.writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, ErrorExpired):
+		h.writeError(w, http.StatusGone, err.Error())
+	default:
+		h.log.Error(op, zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to "+op)
+	}
+}
+
+func (h *Handler) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (h *Handler) writeError(w http.ResponseWriter, status int, msg string) {
+	h.writeJSON(w, status, map[string]interface{}{"error": msg, "code": ErrorCodes.Resolve("QUOTES", status, msg), "timestamp": time.Now().UTC()})
+}
@@ -0,0 +1,80 @@
+package Quotes
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx/types"
+	"github.com/shopspring/decimal"
+)
+
+// quote statuses; StatusExpired is never stored, an open quote past its
+// expiry is reported as expired
+const (
+	StatusOpen      = "OPEN"
+	StatusAccepted  = "ACCEPTED"
+	StatusCancelled = "CANCELLED"
+	StatusExpired   = "EXPIRED"
+)
+
+// Quote is a priced offer to a customer. Accepting it places an order at the
+// quoted prices.
+type Quote struct {
+	ID         uuid.UUID       `db:"id" json:"id"`
+	CustomerID uuid.UUID       `db:"customer_id" json:"customer_id"`
+	Status     string          `db:"status" json:"status"`
+	Currency   string          `db:"currency" json:"currency"`
+	Subtotal   decimal.Decimal `db:"subtotal" json:"subtotal"`
+	Revision   int             `db:"revision" json:"revision"`
+	Note       *string         `db:"note" json:"note,omitempty"`
+	ExpiresAt  time.Time       `db:"expires_at" json:"expires_at"`
+	TokenHash  string          `db:"token_hash" json:"-"`
+	OrderID    *uuid.UUID      `db:"order_id" json:"order_id,omitempty"`
+	AcceptedAt *time.Time      `db:"accepted_at" json:"accepted_at,omitempty"`
+	CreatedAt  time.Time       `db:"created_at" json:"created_at"`
+	UpdatedAt  time.Time       `db:"updated_at" json:"updated_at"`
+	Items      []Item          `db:"-" json:"items"`
+	// only set when a link is issued; the token is not stored
+	AcceptURL string `db:"-" json:"accept_url,omitempty"`
+}
+
+// effectiveStatus reports an open quote past its expiry as expired
+func (q *Quote) effectiveStatus(now time.Time) string {
+	if q.Status == StatusOpen && !now.Before(q.ExpiresAt) {
+		return StatusExpired
+	}
+	return q.Status
+}
+
+type Item struct {
+	QuoteID   uuid.UUID       `db:"quote_id" json:"-"`
+	Line      int             `db:"line" json:"line"`
+	ProductID uuid.UUID       `db:"product_id" json:"product_id"`
+	VariantID *uuid.UUID      `db:"variant_id" json:"variant_id,omitempty"`
+	SKU       *string         `db:"sku" json:"sku,omitempty"`
+	Name      *string         `db:"name" json:"name,omitempty"`
+	UnitPrice decimal.Decimal `db:"unit_price" json:"unit_price"`
+	Quantity  int             `db:"quantity" json:"quantity"`
+}
+
+func (it Item) total() decimal.Decimal {
+	return it.UnitPrice.Mul(decimal.NewFromInt(int64(it.Quantity)))
+}
+
+// Revision is a quote as it stood at one revision
+type Revision struct {
+	QuoteID   uuid.UUID       `db:"quote_id" json:"quote_id"`
+	Revision  int             `db:"revision" json:"revision"`
+	Items     types.JSONText  `db:"items" json:"items"`
+	Subtotal  decimal.Decimal `db:"subtotal" json:"subtotal"`
+	Currency  string          `db:"currency" json:"currency"`
+	Note      *string         `db:"note" json:"note,omitempty"`
+	ExpiresAt time.Time       `db:"expires_at" json:"expires_at"`
+	CreatedAt time.Time       `db:"created_at" json:"created_at"`
+}
+
+const (
+	TableName     = "quotes"
+	ItemTable     = "quote_items"
+	RevisionTable = "quote_revisions"
+)
@@ -0,0 +1,206 @@
+package Quotes
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+type Repository interface {
+	Create(ctx context.Context, q *Quote) error
+	Get(ctx context.Context, id uuid.UUID) (*Quote, error)
+	GetByToken(ctx context.Context, tokenHash string) (*Quote, error)
+	List(ctx context.Context, q ListQuotesQuery) ([]Quote, error)
+	Count(ctx context.Context, q ListQuotesQuery) (int, error)
+	Revise(ctx context.Context, q *Quote) error
+	SetToken(ctx context.Context, id uuid.UUID, tokenHash string) error
+	Cancel(ctx context.Context, id uuid.UUID) error
+	Claim(ctx context.Context, id uuid.UUID, revision int, now time.Time) error
+	Release(ctx context.Context, id uuid.UUID) error
+	SetOrder(ctx context.Context, id, orderID uuid.UUID) error
+	Revisions(ctx context.Context, id uuid.UUID) ([]Revision, error)
+}
+
+type repository struct {
+	db  *sqlx.DB
+	log *zap.Logger
+}
+
+func NewRepository(db *sqlx.DB, log *zap.Logger) Repository { return &repository{db: db, log: log} }
+
+const (
+	quoteColumns = `id,customer_id,status,currency,subtotal,revision,note,expires_at,token_hash,order_id,accepted_at,created_at,updated_at`
+	itemColumns  = `quote_id,line,product_id,variant_id,sku,name,unit_price,quantity`
+)
+
+// Create stores the quote, its items and its first revision
+func (r *repository) Create(ctx context.Context, q *Quote) (err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	query := fmt.Sprintf(`INSERT INTO %s (%s) VALUES (:id,:customer_id,:status,:currency,:subtotal,:revision,:note,:expires_at,:token_hash,:order_id,:accepted_at,:created_at,:updated_at)`, TableName, quoteColumns)
+	if _, err = tx.NamedExecContext(ctx, query, q); err != nil {
+		return err
+	}
+	if err = saveItems(ctx, tx, q); err != nil {
+		return err
+	}
+	if err = saveRevision(ctx, tx, q); err != nil {
+		return err
+	}
+	err = tx.Commit()
+	return err
+}
+
+func saveItems(ctx context.Context, tx *sqlx.Tx, q *Quote) error {
+	query := fmt.Sprintf(`INSERT INTO %s (%s) VALUES (:quote_id,:line,:product_id,:variant_id,:sku,:name,:unit_price,:quantity)`, ItemTable, itemColumns)
+	for i := range q.Items {
+		q.Items[i].QuoteID = q.ID
+		if _, err := tx.NamedExecContext(ctx, query, q.Items[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func saveRevision(ctx context.Context, tx *sqlx.Tx, q *Quote) error {
+	items, err := json.Marshal(q.Items)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`INSERT INTO %s (quote_id,revision,items,subtotal,currency,note,expires_at,created_at) VALUES ($1,$2,$3,$4,$5,$6,$7,$8)`, RevisionTable),
+		q.ID, q.Revision, items, q.Subtotal, q.Currency, q.Note, q.ExpiresAt, q.UpdatedAt)
+	return err
+}
+
+func (r *repository) Get(ctx context.Context, id uuid.UUID) (*Quote, error) {
+	return r.get(ctx, `id=$1`, id)
+}
+
+func (r *repository) GetByToken(ctx context.Context, tokenHash string) (*Quote, error) {
+	return r.get(ctx, `token_hash=$1`, tokenHash)
+}
+
+func (r *repository) get(ctx context.Context, where string, arg interface{}) (*Quote, error) {
+	var q Quote
+	if err := r.db.GetContext(ctx, &q, fmt.Sprintf(`SELECT %s FROM %s WHERE %s`, quoteColumns, TableName, where), arg); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrorNotFound
+		}
+		return nil, err
+	}
+	q.Items = []Item{}
+	if err := r.db.SelectContext(ctx, &q.Items, fmt.Sprintf(`SELECT %s FROM %s WHERE quote_id=$1 ORDER BY line`, itemColumns, ItemTable), q.ID); err != nil {
+		return nil, err
+	}
+	return &q, nil
+}
+
+// List returns quotes newest first without their items
+func (r *repository) List(ctx context.Context, q ListQuotesQuery) ([]Quote, error) {
+	out := []Quote{}
+	err := r.db.SelectContext(ctx, &out, fmt.Sprintf(`SELECT %s FROM %s
+		WHERE ($1::text = '' OR status = $1) AND ($2::uuid IS NULL OR customer_id = $2)
+		ORDER BY created_at DESC LIMIT $3 OFFSET $4`, quoteColumns, TableName), q.Status, q.CustomerID, q.Limit, q.Offset)
+	return out, err
+}
+
+// Count counts the quotes List pages through
+func (r *repository) Count(ctx context.Context, q ListQuotesQuery) (int, error) {
+	var n int
+	err := r.db.GetContext(ctx, &n, fmt.Sprintf(`SELECT COUNT(*) FROM %s
+		WHERE ($1::text = '' OR status = $1) AND ($2::uuid IS NULL OR customer_id = $2)`, TableName), q.Status, q.CustomerID)
+	return n, err
+}
+
+// Revise replaces the items of an open quote and records the new revision.
+// q.Revision is the revision being replaced; it is bumped on success.
+func (r *repository) Revise(ctx context.Context, q *Quote) (err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	res, err := tx.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET subtotal=$1, note=$2, expires_at=$3, revision=revision+1, updated_at=$4
+		WHERE id=$5 AND status=$6 AND revision=$7`, TableName),
+		q.Subtotal, q.Note, q.ExpiresAt, q.UpdatedAt, q.ID, StatusOpen, q.Revision)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		err = ErrorNotOpen
+		return err
+	}
+	q.Revision++
+	if _, err = tx.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE quote_id=$1`, ItemTable), q.ID); err != nil {
+		return err
+	}
+	if err = saveItems(ctx, tx, q); err != nil {
+		return err
+	}
+	if err = saveRevision(ctx, tx, q); err != nil {
+		return err
+	}
+	err = tx.Commit()
+	return err
+}
+
+func (r *repository) SetToken(ctx context.Context, id uuid.UUID, tokenHash string) error {
+	return r.transition(ctx, fmt.Sprintf(`UPDATE %s SET token_hash=$2, updated_at=NOW() WHERE id=$1 AND status='%s'`, TableName, StatusOpen), id, tokenHash)
+}
+
+func (r *repository) Cancel(ctx context.Context, id uuid.UUID) error {
+	return r.transition(ctx, fmt.Sprintf(`UPDATE %s SET status='%s', updated_at=NOW() WHERE id=$1 AND status='%s'`, TableName, StatusCancelled, StatusOpen), id)
+}
+
+// Claim marks an open, unexpired quote at revision accepted, so only one
+// acceptance can go on to place an order
+func (r *repository) Claim(ctx context.Context, id uuid.UUID, revision int, now time.Time) error {
+	return r.transition(ctx, fmt.Sprintf(`UPDATE %s SET status='%s', accepted_at=$3, updated_at=$3
+		WHERE id=$1 AND revision=$2 AND status='%s' AND expires_at > $3`, TableName, StatusAccepted, StatusOpen), id, revision, now)
+}
+
+// Release reopens a claimed quote whose order could not be placed
+func (r *repository) Release(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET status='%s', accepted_at=NULL, updated_at=NOW() WHERE id=$1 AND status='%s' AND order_id IS NULL`, TableName, StatusOpen, StatusAccepted), id)
+	return err
+}
+
+func (r *repository) SetOrder(ctx context.Context, id, orderID uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET order_id=$2, updated_at=NOW() WHERE id=$1`, TableName), id, orderID)
+	return err
+}
+
+func (r *repository) transition(ctx context.Context, query string, args ...interface{}) error {
+	res, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrorNotOpen
+	}
+	return nil
+}
+
+func (r *repository) Revisions(ctx context.Context, id uuid.UUID) ([]Revision, error) {
+	out := []Revision{}
+	err := r.db.SelectContext(ctx, &out, fmt.Sprintf(`SELECT quote_id,revision,items,subtotal,currency,note,expires_at,created_at FROM %s WHERE quote_id=$1 ORDER BY revision`, RevisionTable), id)
+	return out, err
+}
@@ -0,0 +1,361 @@
+package Quotes
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+	"savannah/src/Catalog"
+	"savannah/src/Customer"
+	"savannah/src/Orders"
+)
+
+// Service runs the B2B quotation workflow: staff price a quote for a
+// customer, revise it while it is open, and the customer accepts it through
+// a tokenized link, which places an order at the quoted prices.
+type Service interface {
+	Create(ctx context.Context, req CreateQuoteRequest) (*Quote, error)
+	Get(ctx context.Context, id uuid.UUID) (*Quote, error)
+	List(ctx context.Context, q ListQuotesQuery) ([]Quote, error)
+	Count(ctx context.Context, q ListQuotesQuery) (int, error)
+	Revise(ctx context.Context, id uuid.UUID, req ReviseQuoteRequest) (*Quote, error)
+	Cancel(ctx context.Context, id uuid.UUID) (*Quote, error)
+	NewLink(ctx context.Context, id uuid.UUID) (*Quote, error)
+	Revisions(ctx context.Context, id uuid.UUID) ([]Revision, error)
+
+	View(ctx context.Context, token string) (*Quote, error)
+	Accept(ctx context.Context, token string, req AcceptQuoteRequest) (*Quote, *Orders.Order, error)
+}
+
+// Products is what quotes need from the catalog to default prices and names
+type Products interface {
+	GetProduct(ctx context.Context, id uuid.UUID, pricing Catalog.Pricing) (*Catalog.Product, error)
+	GetVariant(ctx context.Context, productID, id uuid.UUID) (*Catalog.ProductVariant, error)
+}
+
+type Customers interface {
+	Get(ctx context.Context, id uuid.UUID) (*Customer.Customer, error)
+}
+
+// OrderPlacer places the order an accepted quote turns into
+type OrderPlacer interface {
+	Create(ctx context.Context, customerID *uuid.UUID, items []Orders.OrderItem, checkout Orders.Checkout) (*Orders.Order, error)
+}
+
+// Config is where accept links point and how long a quote stays open by default
+type Config struct {
+	BaseURL  string
+	Validity time.Duration
+}
+
+type service struct {
+	repo      Repository
+	products  Products
+	customers Customers
+	orders    OrderPlacer
+	cfg       Config
+	log       *zap.Logger
+}
+
+func NewService(r Repository, products Products, customers Customers, orders OrderPlacer, cfg Config, log *zap.Logger) Service {
+	if cfg.Validity <= 0 {
+		cfg.Validity = 30 * 24 * time.Hour
+	}
+	return &service{repo: r, products: products, customers: customers, orders: orders, cfg: cfg, log: log}
+}
+
+// Create prices the quote and returns it with its accept link; the link is
+// not shown again, NewLink issues a fresh one
+func (s *service) Create(ctx context.Context, req CreateQuoteRequest) (*Quote, error) {
+	if _, err := s.customers.Get(ctx, req.CustomerID); err != nil {
+		if errors.Is(err, Customer.ErrorNotFound) {
+			return nil, ErrorUnknownCustomer
+		}
+		return nil, err
+	}
+	now := time.Now().UTC()
+	q := &Quote{ID: uuid.New(), CustomerID: req.CustomerID, Status: StatusOpen, Currency: strings.ToUpper(req.Currency), Revision: 1, Note: req.Note, CreatedAt: now, UpdatedAt: now}
+	if q.Currency == "" {
+		q.Currency = "USD"
+	}
+	if err := s.price(ctx, q, req.Items); err != nil {
+		return nil, err
+	}
+	if err := s.expiry(q, req.ExpiresAt, now); err != nil {
+		return nil, err
+	}
+	token, hash, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	q.TokenHash = hash
+	if err := s.repo.Create(ctx, q); err != nil {
+		return nil, err
+	}
+	q.AcceptURL = s.link(token)
+	return q, nil
+}
+
+// price fills q.Items from the request, taking the catalog price in the
+// quote's currency for lines without a negotiated one
+func (s *service) price(ctx context.Context, q *Quote, reqs []ItemRequest) error {
+	items := make([]Item, 0, len(reqs))
+	sub := decimal.Zero
+	for i, r := range reqs {
+		p, err := s.products.GetProduct(ctx, r.ProductID, Catalog.Pricing{Currency: q.Currency})
+		if errors.Is(err, Catalog.PriceListErrorNoPrice) && r.UnitPrice != nil {
+			p, err = s.products.GetProduct(ctx, r.ProductID, Catalog.Pricing{})
+		}
+		switch {
+		case errors.Is(err, Catalog.ProductErrorNotFound):
+			return ErrorUnknownProduct
+		case errors.Is(err, Catalog.PriceListErrorNoPrice):
+			return fmt.Errorf("%w: line %d has no %s catalog price, give a unit_price", ErrorInvalidPayload, i+1, q.Currency)
+		case err != nil:
+			return err
+		}
+		sku, name, price := p.SKU, p.Name, p.Price
+		if r.VariantID != nil {
+			v, err := s.products.GetVariant(ctx, r.ProductID, *r.VariantID)
+			if err != nil {
+				if errors.Is(err, Catalog.VariantErrorNotFound) {
+					return ErrorUnknownProduct
+				}
+				return err
+			}
+			sku, name = v.SKU, v.Name
+			if v.Price != nil && p.PriceListID == nil {
+				price = *v.Price
+			}
+		}
+		if r.UnitPrice != nil {
+			if r.UnitPrice.IsNegative() {
+				return fmt.Errorf("%w: line %d has a negative unit_price", ErrorInvalidPayload, i+1)
+			}
+			price = *r.UnitPrice
+		}
+		it := Item{Line: i + 1, ProductID: r.ProductID, VariantID: r.VariantID, SKU: &sku, Name: &name, UnitPrice: price, Quantity: r.Quantity}
+		sub = sub.Add(it.total())
+		items = append(items, it)
+	}
+	q.Items, q.Subtotal = items, sub
+	return nil
+}
+
+func (s *service) expiry(q *Quote, expiresAt *time.Time, now time.Time) error {
+	if expiresAt == nil {
+		if q.ExpiresAt.IsZero() {
+			q.ExpiresAt = now.Add(s.cfg.Validity)
+		}
+		return nil
+	}
+	if !expiresAt.After(now) {
+		return fmt.Errorf("%w: expires_at must be in the future", ErrorInvalidPayload)
+	}
+	q.ExpiresAt = expiresAt.UTC()
+	return nil
+}
+
+func (s *service) Get(ctx context.Context, id uuid.UUID) (*Quote, error) {
+	q, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	q.Status = q.effectiveStatus(time.Now())
+	return q, nil
+}
+
+func (s *service) List(ctx context.Context, lq ListQuotesQuery) ([]Quote, error) {
+	if lq.Limit <= 0 || lq.Limit > 100 {
+		lq.Limit = 50
+	}
+	quotes, err := s.repo.List(ctx, lq)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for i := range quotes {
+		quotes[i].Status = quotes[i].effectiveStatus(now)
+	}
+	return quotes, nil
+}
+
+// Count counts the quotes matching the list filters, ignoring the page
+func (s *service) Count(ctx context.Context, lq ListQuotesQuery) (int, error) {
+	return s.repo.Count(ctx, lq)
+}
+
+// Revise reprices an open quote as a new revision; the old one stays in the
+// history. A revised quote keeps its link, and acceptances that name the old
+// revision are refused.
+func (s *service) Revise(ctx context.Context, id uuid.UUID, req ReviseQuoteRequest) (*Quote, error) {
+	q, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if q.Status != StatusOpen {
+		return nil, ErrorNotOpen
+	}
+	now := time.Now().UTC()
+	if err := s.price(ctx, q, req.Items); err != nil {
+		return nil, err
+	}
+	if err := s.expiry(q, req.ExpiresAt, now); err != nil {
+		return nil, err
+	}
+	if req.Note != nil {
+		q.Note = req.Note
+	}
+	q.UpdatedAt = now
+	if err := s.repo.Revise(ctx, q); err != nil {
+		return nil, err
+	}
+	q.Status = q.effectiveStatus(now)
+	return q, nil
+}
+
+func (s *service) Cancel(ctx context.Context, id uuid.UUID) (*Quote, error) {
+	if err := s.repo.Cancel(ctx, id); err != nil {
+		if errors.Is(err, ErrorNotOpen) {
+			if _, gerr := s.repo.Get(ctx, id); gerr != nil {
+				return nil, gerr
+			}
+		}
+		return nil, err
+	}
+	return s.Get(ctx, id)
+}
+
+// NewLink replaces the accept link of an open quote; the old one stops working
+func (s *service) NewLink(ctx context.Context, id uuid.UUID) (*Quote, error) {
+	token, hash, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.SetToken(ctx, id, hash); err != nil {
+		if errors.Is(err, ErrorNotOpen) {
+			if _, gerr := s.repo.Get(ctx, id); gerr != nil {
+				return nil, gerr
+			}
+		}
+		return nil, err
+	}
+	q, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	q.AcceptURL = s.link(token)
+	return q, nil
+}
+
+func (s *service) Revisions(ctx context.Context, id uuid.UUID) ([]Revision, error) {
+	if _, err := s.repo.Get(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.repo.Revisions(ctx, id)
+}
+
+// View is the quote as the customer holding the link sees it
+func (s *service) View(ctx context.Context, token string) (*Quote, error) {
+	q, err := s.repo.GetByToken(ctx, hashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	q.Status = q.effectiveStatus(time.Now())
+	return q, nil
+}
+
+// Accept claims the quote and places its order at the quoted prices. The
+// claim is atomic, so a link followed twice places one order. When the order
+// cannot be placed the quote is reopened; when only its payment failed the
+// quote stays accepted with the order, which is retried like any other, and
+// the *Orders.PaymentError is returned alongside it.
+func (s *service) Accept(ctx context.Context, token string, req AcceptQuoteRequest) (*Quote, *Orders.Order, error) {
+	q, err := s.repo.GetByToken(ctx, hashToken(token))
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now().UTC()
+	if req.Revision != nil && *req.Revision != q.Revision {
+		return nil, nil, ErrorRevisionMismatch
+	}
+	switch q.effectiveStatus(now) {
+	case StatusExpired:
+		return nil, nil, ErrorExpired
+	case StatusOpen:
+	default:
+		return nil, nil, ErrorNotOpen
+	}
+	if err := s.repo.Claim(ctx, q.ID, q.Revision, now); err != nil {
+		if errors.Is(err, ErrorNotOpen) && req.Revision != nil {
+			// revised or accepted between reading and claiming
+			return nil, nil, ErrorRevisionMismatch
+		}
+		return nil, nil, err
+	}
+	items := make([]Orders.OrderItem, 0, len(q.Items))
+	for _, it := range q.Items {
+		productID := it.ProductID
+		items = append(items, Orders.OrderItem{
+			ProductID: &productID,
+			VariantID: it.VariantID,
+			SKU:       it.SKU,
+			Name:      it.Name,
+			UnitPrice: it.UnitPrice,
+			Quantity:  it.Quantity,
+			LineTotal: it.total(),
+		})
+	}
+	customerID := q.CustomerID
+	order, err := s.orders.Create(ctx, &customerID, items, Orders.Checkout{
+		Warehouse:     req.Warehouse,
+		Country:       req.Country,
+		Region:        req.Region,
+		PaymentMethod: req.PaymentMethod,
+		Channel:       Orders.ChannelQuote,
+		Currency:      q.Currency,
+		PONumber:      req.PONumber,
+		PaymentTerms:  req.PaymentTerms,
+	})
+	var payment *Orders.PaymentError
+	if errors.As(err, &payment) {
+		order = payment.Order
+	} else if err != nil {
+		if rerr := s.repo.Release(ctx, q.ID); rerr != nil {
+			s.log.Error("reopen quote", zap.String("quote_id", q.ID.String()), zap.Error(rerr))
+		}
+		return nil, nil, err
+	}
+	if lerr := s.repo.SetOrder(ctx, q.ID, order.ID); lerr != nil {
+		s.log.Error("link quote to order", zap.String("quote_id", q.ID.String()), zap.String("order_id", order.ID.String()), zap.Error(lerr))
+	}
+	q.Status, q.AcceptedAt, q.OrderID = StatusAccepted, &now, &order.ID
+	return q, order, err
+}
+
+func (s *service) link(token string) string {
+	return strings.TrimRight(s.cfg.BaseURL, "/") + "/api/v1/quote-links/" + token
+}
+
+// newToken returns an accept token and the hash it is stored as
+func newToken() (string, string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token := hex.EncodeToString(b)
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,11 @@
+package Restock
+
+import "github.com/google/uuid"
+
+// RegisterRequest asks to be told by email or by SMS when the product, or
+// the variant given, is back in stock
+type RegisterRequest struct {
+	VariantID *uuid.UUID `json:"variant_id,omitempty"`
+	Email     *string    `json:"email,omitempty" validate:"omitempty,email,max=255"`
+	Phone     *string    `json:"phone,omitempty" validate:"omitempty,e164"`
+}
@@ -0,0 +1,23 @@
+package Restock
+
+import (
+	"errors"
+
+	"savannah/src/ErrorCodes"
+)
+
+var (
+	ErrorUnknownProduct  = errors.New("product not found")
+	ErrorUnknownVariant  = errors.New("variant not found")
+	ErrorInStock         = errors.New("the product is in stock")
+	ErrorContactRequired = errors.New("give either an email or a phone number")
+)
+
+func init() {
+	ErrorCodes.Register("RESTOCK",
+		ErrorCodes.Def{N: 1, Slug: "UNKNOWN_PRODUCT", Err: ErrorUnknownProduct},
+		ErrorCodes.Def{N: 2, Slug: "UNKNOWN_VARIANT", Err: ErrorUnknownVariant},
+		ErrorCodes.Def{N: 3, Slug: "IN_STOCK", Err: ErrorInStock},
+		ErrorCodes.Def{N: 4, Slug: "CONTACT_REQUIRED", Err: ErrorContactRequired},
+	)
+}
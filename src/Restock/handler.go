@@ -0,0 +1,125 @@
+package Restock
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"savannah/src/ErrorCodes"
+	"savannah/src/Pagination"
+)
+
+type Handler struct {
+	svc Service
+	log *zap.Logger
+	v   *validator.Validate
+}
+
+func NewHandler(s Service, log *zap.Logger) *Handler {
+	return &Handler{svc: s, log: log, v: validator.New()}
+}
+
+// Register godoc
+// @Summary      Ask to be notified when a product is back in stock
+// @Description  Registers an email or a phone number (E.164) for an out-of-stock product, or one of its variants. Once it has stock again in any warehouse one message is sent and the registration ends. Registering again while waiting returns the existing registration with 200.
+// @Tags         restock
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string           true  "Product ID"
+// @Param        payload  body      RegisterRequest  true  "Contact"
+// @Success      201      {object}  Registration
+// @Success      200      {object}  Registration
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      404      {object}  map[string]interface{}
+// @Failure      409      {object}  map[string]interface{}
+// @Router       /products/{id}/notify-me [post]
+func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
+	productID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var dto RegisterRequest
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if err := h.v.Struct(dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	reg, created, err := h.svc.Register(r.Context(), productID, dto)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrorContactRequired):
+			h.writeError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, ErrorUnknownProduct), errors.Is(err, ErrorUnknownVariant):
+			h.writeError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, ErrorInStock):
+			h.writeError(w, http.StatusConflict, err.Error())
+		default:
+			h.log.Error("register restock notification", zap.Error(err))
+			h.writeError(w, http.StatusInternalServerError, "failed to register")
+		}
+		return
+	}
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	h.writeJSON(w, status, reg)
+}
+
+// Demand godoc
+// @Summary      Back-in-stock demand per product
+// @Description  Shoppers waiting for each product or variant and those already notified, most waited for first
+// @Tags         restock
+// @Produce      json
+// @Param        product_id  query     string  false  "Only this product's variants"
+// @Param        limit       query     int     false  "Page size (max 200)"
+// @Param        offset      query     int     false  "Offset"
+// @Param        page        query     int     false  "Page of limit rows, 1-based; instead of offset"
+// @Success      200         {array}   Demand
+// @Header       200         {string}  Link    "first, prev, next and last pages (RFC 5988), with X-Total-Count, X-Page and X-Total-Pages"
+// @Failure      400         {object}  map[string]interface{}
+// @Router       /restock/demand [get]
+func (h *Handler) Demand(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var productID *uuid.UUID
+	if v := q.Get("product_id"); v != "" {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			h.writeError(w, http.StatusBadRequest, "invalid product_id")
+			return
+		}
+		productID = &id
+	}
+	limit, offset := Pagination.Params(q, 50, 200)
+	demand, err := h.svc.Demand(r.Context(), productID, limit, offset)
+	var total int
+	if err == nil {
+		total, err = h.svc.CountDemand(r.Context(), productID)
+	}
+	if err != nil {
+		h.log.Error("restock demand", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to list restock demand")
+		return
+	}
+	Pagination.Write(w, r, limit, offset, total)
+	h.writeJSON(w, http.StatusOK, demand)
+}
+
+func (h *Handler) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (h *Handler) writeError(w http.ResponseWriter, status int, msg string) {
+	h.writeJSON(w, status, map[string]interface{}{"error": msg, "code": ErrorCodes.Resolve("RESTOCK", status, msg), "timestamp": time.Now().UTC()})
+}
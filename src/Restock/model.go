@@ -0,0 +1,49 @@
+package Restock
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Registration is a shopper's request to hear when an out-of-stock product,
+// or one variant of it, can be bought again. It is WAITING until then and
+// NOTIFIED once the message went out, which ends it.
+type Registration struct {
+	ID         uuid.UUID  `db:"id" json:"id"`
+	ProductID  uuid.UUID  `db:"product_id" json:"product_id"`
+	VariantID  *uuid.UUID `db:"variant_id" json:"variant_id,omitempty"`
+	Channel    string     `db:"channel" json:"channel"`
+	Recipient  string     `db:"-" json:"recipient"`
+	Status     string     `db:"status" json:"status"`
+	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+	NotifiedAt *time.Time `db:"notified_at" json:"notified_at,omitempty"`
+}
+
+const (
+	StatusWaiting  = "WAITING"
+	StatusNotified = "NOTIFIED"
+)
+
+const TableName = "restock_registrations"
+
+// Demand sums up the registrations of a product or variant: how many
+// shoppers are waiting for it and how many were told it is back
+type Demand struct {
+	ProductID     uuid.UUID  `db:"product_id" json:"product_id"`
+	VariantID     *uuid.UUID `db:"variant_id" json:"variant_id,omitempty"`
+	Name          string     `db:"name" json:"name"`
+	SKU           string     `db:"sku" json:"sku"`
+	Waiting       int        `db:"waiting" json:"waiting"`
+	Notified      int        `db:"notified" json:"notified"`
+	WaitingSince  *time.Time `db:"waiting_since" json:"waiting_since,omitempty"`
+	LastRequestAt time.Time  `db:"last_request_at" json:"last_request_at"`
+}
+
+// Restocked is a registration whose product is available again, with what
+// the message says about the product
+type Restocked struct {
+	Registration
+	Name string `db:"name"`
+	SKU  string `db:"sku"`
+}
@@ -0,0 +1,181 @@
+package Restock
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"savannah/src/Storage"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+type Repository interface {
+	Register(ctx context.Context, reg *Registration) error
+	ProductExists(ctx context.Context, productID uuid.UUID) (bool, error)
+	VariantExists(ctx context.Context, productID, variantID uuid.UUID) (bool, error)
+	Available(ctx context.Context, productID uuid.UUID, variantID *uuid.UUID) (int, error)
+	ClaimRestocked(ctx context.Context, now time.Time, limit int) ([]Restocked, error)
+	Demand(ctx context.Context, productID *uuid.UUID, limit, offset int) ([]Demand, error)
+	CountDemand(ctx context.Context, productID *uuid.UUID) (int, error)
+}
+
+// repository keeps recipients encrypted with keys, like customer contact
+// details; without a keyring they are stored in plaintext. The recipient
+// index finds a shopper's waiting registration either way.
+type repository struct {
+	db   *sqlx.DB
+	keys *Storage.Keyring
+	log  *zap.Logger
+}
+
+func NewRepository(db *sqlx.DB, keys *Storage.Keyring, log *zap.Logger) Repository {
+	return &repository{db: db, keys: keys, log: log}
+}
+
+// stored is a registration's recipient as kept in the table
+type stored struct {
+	Recipient string  `db:"recipient"`
+	KeyID     *string `db:"key_id"`
+}
+
+func normalize(channel, recipient string) string {
+	recipient = strings.TrimSpace(recipient)
+	if channel == "email" {
+		return strings.ToLower(recipient)
+	}
+	return recipient
+}
+
+func (r *repository) seal(channel, recipient string) (value, index string, keyID *string, err error) {
+	norm := normalize(channel, recipient)
+	if r.keys == nil {
+		return norm, norm, nil, nil
+	}
+	if value, err = r.keys.Encrypt(norm); err != nil {
+		return "", "", nil, err
+	}
+	id := r.keys.ActiveKeyID()
+	return value, r.keys.BlindIndex(norm), &id, nil
+}
+
+func (r *repository) open(s stored) (string, error) {
+	if s.KeyID == nil {
+		return s.Recipient, nil
+	}
+	if r.keys == nil {
+		return "", errors.New("restock recipient is encrypted but no keyring is configured")
+	}
+	return r.keys.Decrypt(s.Recipient)
+}
+
+// Register stores reg unless the recipient already waits for the same
+// product or variant, in which case reg becomes that registration
+func (r *repository) Register(ctx context.Context, reg *Registration) error {
+	value, index, keyID, err := r.seal(reg.Channel, reg.Recipient)
+	if err != nil {
+		return err
+	}
+	err = r.db.GetContext(ctx, reg, fmt.Sprintf(`INSERT INTO %s (id,product_id,variant_id,channel,recipient,recipient_index,key_id,status,created_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)
+		ON CONFLICT (product_id, COALESCE(variant_id, product_id), channel, recipient_index) WHERE status = 'WAITING' DO NOTHING
+		RETURNING id,product_id,variant_id,channel,status,created_at,notified_at`, TableName),
+		reg.ID, reg.ProductID, reg.VariantID, reg.Channel, value, index, keyID, reg.Status, reg.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		err = r.db.GetContext(ctx, reg, fmt.Sprintf(`SELECT id,product_id,variant_id,channel,status,created_at,notified_at FROM %s
+			WHERE product_id=$1 AND variant_id IS NOT DISTINCT FROM $2 AND channel=$3 AND recipient_index=$4 AND status = 'WAITING'`, TableName),
+			reg.ProductID, reg.VariantID, reg.Channel, index)
+	}
+	return err
+}
+
+func (r *repository) ProductExists(ctx context.Context, productID uuid.UUID) (bool, error) {
+	var ok bool
+	err := r.db.GetContext(ctx, &ok, `SELECT EXISTS (SELECT 1 FROM products WHERE id=$1 AND deleted_at IS NULL)`, productID)
+	return ok, err
+}
+
+func (r *repository) VariantExists(ctx context.Context, productID, variantID uuid.UUID) (bool, error) {
+	var ok bool
+	err := r.db.GetContext(ctx, &ok, `SELECT EXISTS (SELECT 1 FROM product_variants WHERE id=$1 AND product_id=$2)`, variantID, productID)
+	return ok, err
+}
+
+// Available sums the unreserved stock of the variant, or of every stock
+// item of the product, across warehouses
+func (r *repository) Available(ctx context.Context, productID uuid.UUID, variantID *uuid.UUID) (int, error) {
+	var n int
+	err := r.db.GetContext(ctx, &n, `SELECT COALESCE(SUM(GREATEST(quantity - reserved, 0)), 0) FROM inventory
+		WHERE CASE WHEN $2::uuid IS NULL THEN product_id = $1 ELSE variant_id = $2 END`, productID, variantID)
+	return n, err
+}
+
+// ClaimRestocked marks up to limit waiting registrations whose product or
+// variant has stock again as notified and returns them, oldest first.
+// Claiming before sending means a registration is messaged at most once,
+// even with several instances sweeping.
+func (r *repository) ClaimRestocked(ctx context.Context, now time.Time, limit int) ([]Restocked, error) {
+	var rows []struct {
+		Restocked
+		stored
+	}
+	err := r.db.SelectContext(ctx, &rows, fmt.Sprintf(`WITH due AS (
+			SELECT r.id, p.name || COALESCE(' - ' || v.name, '') AS name, COALESCE(v.sku, p.sku) AS sku
+			FROM %[1]s r
+			JOIN products p ON p.id = r.product_id
+			LEFT JOIN product_variants v ON v.id = r.variant_id
+			WHERE r.status = 'WAITING' AND EXISTS (
+				SELECT 1 FROM inventory i WHERE i.quantity > i.reserved
+				AND CASE WHEN r.variant_id IS NULL THEN i.product_id = r.product_id ELSE i.variant_id = r.variant_id END)
+			ORDER BY r.created_at
+			LIMIT $2
+			FOR UPDATE OF r SKIP LOCKED)
+		UPDATE %[1]s r SET status = 'NOTIFIED', notified_at = $1 FROM due WHERE r.id = due.id
+		RETURNING r.id, r.product_id, r.variant_id, r.channel, r.recipient, r.key_id, r.status, r.created_at, r.notified_at, due.name, due.sku`, TableName),
+		now, limit)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Restocked, 0, len(rows))
+	for _, row := range rows {
+		recipient, err := r.open(row.stored)
+		if err != nil {
+			r.log.Error("open restock recipient", zap.String("registration_id", row.ID.String()), zap.Error(err))
+			continue
+		}
+		row.Restocked.Recipient = recipient
+		out = append(out, row.Restocked)
+	}
+	return out, nil
+}
+
+// Demand lists the products and variants shoppers registered for, most
+// waited for first
+func (r *repository) Demand(ctx context.Context, productID *uuid.UUID, limit, offset int) ([]Demand, error) {
+	out := []Demand{}
+	err := r.db.SelectContext(ctx, &out, fmt.Sprintf(`SELECT r.product_id, r.variant_id,
+			p.name || COALESCE(' - ' || v.name, '') AS name, COALESCE(v.sku, p.sku) AS sku,
+			COUNT(*) FILTER (WHERE r.status = 'WAITING') AS waiting,
+			COUNT(*) FILTER (WHERE r.status = 'NOTIFIED') AS notified,
+			MIN(r.created_at) FILTER (WHERE r.status = 'WAITING') AS waiting_since,
+			MAX(r.created_at) AS last_request_at
+		FROM %s r
+		JOIN products p ON p.id = r.product_id
+		LEFT JOIN product_variants v ON v.id = r.variant_id
+		WHERE $1::uuid IS NULL OR r.product_id = $1
+		GROUP BY r.product_id, r.variant_id, p.name, p.sku, v.name, v.sku
+		ORDER BY waiting DESC, last_request_at DESC
+		LIMIT $2 OFFSET $3`, TableName), productID, limit, offset)
+	return out, err
+}
+
+func (r *repository) CountDemand(ctx context.Context, productID *uuid.UUID) (int, error) {
+	var n int
+	err := r.db.GetContext(ctx, &n, fmt.Sprintf(`SELECT COUNT(*) FROM (SELECT DISTINCT product_id, variant_id FROM %s WHERE $1::uuid IS NULL OR product_id = $1) d`, TableName), productID)
+	return n, err
+}
@@ -0,0 +1,119 @@
+package Restock
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"savannah/src/Notifications"
+)
+
+// Notifier sends the back-in-stock messages
+type Notifier interface {
+	Send(ctx context.Context, m Notifications.Message) error
+}
+
+type Service interface {
+	Register(ctx context.Context, productID uuid.UUID, req RegisterRequest) (*Registration, bool, error)
+	NotifyRestocked(ctx context.Context) error
+	Demand(ctx context.Context, productID *uuid.UUID, limit, offset int) ([]Demand, error)
+	CountDemand(ctx context.Context, productID *uuid.UUID) (int, error)
+}
+
+type service struct {
+	repo     Repository
+	notifier Notifier
+	log      *zap.Logger
+}
+
+func NewService(r Repository, notifier Notifier, log *zap.Logger) Service {
+	return &service{repo: r, notifier: notifier, log: log}
+}
+
+// notifyBatch caps the registrations one sweep messages
+const notifyBatch = 200
+
+// Register signs a shopper up for a product, or one of its variants, that
+// is out of stock in every warehouse. Signing up again while waiting
+// returns the registration already there, and false.
+func (s *service) Register(ctx context.Context, productID uuid.UUID, req RegisterRequest) (*Registration, bool, error) {
+	reg := &Registration{ID: uuid.New(), ProductID: productID, VariantID: req.VariantID, Status: StatusWaiting, CreatedAt: time.Now().UTC()}
+	switch {
+	case req.Email != nil && req.Phone == nil:
+		reg.Channel, reg.Recipient = Notifications.ChannelEmail, *req.Email
+	case req.Phone != nil && req.Email == nil:
+		reg.Channel, reg.Recipient = Notifications.ChannelSMS, *req.Phone
+	default:
+		return nil, false, ErrorContactRequired
+	}
+	if ok, err := s.repo.ProductExists(ctx, productID); err != nil || !ok {
+		if err == nil {
+			err = ErrorUnknownProduct
+		}
+		return nil, false, err
+	}
+	if req.VariantID != nil {
+		if ok, err := s.repo.VariantExists(ctx, productID, *req.VariantID); err != nil || !ok {
+			if err == nil {
+				err = ErrorUnknownVariant
+			}
+			return nil, false, err
+		}
+	}
+	available, err := s.repo.Available(ctx, productID, req.VariantID)
+	if err != nil {
+		return nil, false, err
+	}
+	if available > 0 {
+		return nil, false, ErrorInStock
+	}
+	id, recipient := reg.ID, reg.Recipient
+	if err := s.repo.Register(ctx, reg); err != nil {
+		return nil, false, err
+	}
+	reg.Recipient = recipient
+	return reg, reg.ID == id, nil
+}
+
+// NotifyRestocked messages the shoppers waiting for products that have
+// stock again. Stock also arrives in the inventory table from outside the
+// service, so waiting registrations are matched against stock levels here
+// rather than on each stock change. Each registration is ended before its
+// message is sent; a failed message is only logged and can be resent from
+// the notification log.
+func (s *service) NotifyRestocked(ctx context.Context) error {
+	due, err := s.repo.ClaimRestocked(ctx, time.Now().UTC(), notifyBatch)
+	if err != nil {
+		return err
+	}
+	for _, r := range due {
+		if err := s.notify(ctx, r); err != nil {
+			s.log.Error("send back in stock notice", zap.String("registration_id", r.ID.String()), zap.String("product_id", r.ProductID.String()), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+func (s *service) notify(ctx context.Context, r Restocked) error {
+	data := map[string]interface{}{"product_id": r.ProductID, "name": r.Name, "sku": r.SKU}
+	if r.VariantID != nil {
+		data["variant_id"] = *r.VariantID
+	}
+	return s.notifier.Send(ctx, Notifications.Message{
+		Channel:  r.Channel,
+		To:       r.Recipient,
+		Subject:  r.Name + " is back in stock",
+		Body:     "Good news: " + r.Name + " is back in stock. Stock is limited, so order soon.",
+		Template: "back_in_stock",
+		Data:     data,
+	})
+}
+
+func (s *service) Demand(ctx context.Context, productID *uuid.UUID, limit, offset int) ([]Demand, error) {
+	return s.repo.Demand(ctx, productID, limit, offset)
+}
+
+func (s *service) CountDemand(ctx context.Context, productID *uuid.UUID) (int, error) {
+	return s.repo.CountDemand(ctx, productID)
+}
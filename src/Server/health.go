@@ -0,0 +1,155 @@
+package Server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// dependency names services ask Degraded about
+const (
+	DependencyDatabase = "database"
+	DependencyReplica  = "database_replica"
+	DependencyRedis    = "redis"
+	DependencyPayments = "payments"
+	DependencySMS      = "sms"
+	DependencySearch   = "search"
+)
+
+// checkTimeout bounds one dependency check
+const checkTimeout = 2 * time.Second
+
+// Dependency is an external service the instance relies on. A required one
+// failing makes the instance unready; an optional one failing only degrades
+// it, and services skip the steps that need it.
+type Dependency struct {
+	Name     string
+	Required bool
+	Check    func(ctx context.Context) error
+}
+
+// Health tracks the dependencies. Optional ones are checked by Refresh, run
+// by the scheduler, so a slow provider doesn't slow every probe; required
+// ones are checked on each readiness probe.
+type Health struct {
+	deps []Dependency
+
+	mu      sync.RWMutex
+	results map[string]error
+}
+
+func NewHealth() *Health {
+	return &Health{results: map[string]error{}}
+}
+
+// Add registers a dependency; it must be called before serving.
+func (h *Health) Add(d Dependency) {
+	h.deps = append(h.deps, d)
+}
+
+// Refresh checks the optional dependencies at once and returns the failures
+func (h *Health) Refresh(ctx context.Context) error {
+	results := map[string]error{}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, d := range h.deps {
+		if d.Required {
+			continue
+		}
+		wg.Add(1)
+		go func(d Dependency) {
+			defer wg.Done()
+			err := check(ctx, d)
+			mu.Lock()
+			results[d.Name] = err
+			mu.Unlock()
+		}(d)
+	}
+	wg.Wait()
+	h.mu.Lock()
+	h.results = results
+	h.mu.Unlock()
+	var failed []error
+	for name, err := range results {
+		if err != nil {
+			failed = append(failed, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	return errors.Join(failed...)
+}
+
+// Degraded reports whether the optional dependency failed its last check.
+// Unknown or unchecked dependencies are not degraded.
+func (h *Health) Degraded(name string) bool {
+	if h == nil {
+		return false
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.results[name] != nil
+}
+
+// report checks the required dependencies and adds the last results of the
+// optional ones. The probe is public, so checks only say ok, failing or
+// unchecked; Refresh errors are logged by the scheduler.
+func (h *Health) report(ctx context.Context) (ready, degraded bool, checks map[string]string) {
+	ready = true
+	checks = map[string]string{}
+	for _, d := range h.deps {
+		if !d.Required {
+			continue
+		}
+		checks[d.Name] = "ok"
+		if err := check(ctx, d); err != nil {
+			ready = false
+			checks[d.Name] = "failing"
+		}
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, d := range h.deps {
+		if d.Required {
+			continue
+		}
+		err, checked := h.results[d.Name]
+		switch {
+		case !checked:
+			checks[d.Name] = "unchecked"
+		case err != nil:
+			degraded = true
+			checks[d.Name] = "failing"
+		default:
+			checks[d.Name] = "ok"
+		}
+	}
+	return ready, degraded, checks
+}
+
+func check(ctx context.Context, d Dependency) error {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+	return d.Check(ctx)
+}
+
+// HTTPCheck checks a provider's status endpoint: any answer below 500 means
+// it is up.
+func HTTPCheck(client *http.Client, url string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 500 {
+			return fmt.Errorf("status %d", resp.StatusCode)
+		}
+		return nil
+	}
+}
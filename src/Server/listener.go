@@ -0,0 +1,51 @@
+package Server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+)
+
+// ErrorReusePortUnsupported is returned by Listen on platforms without SO_REUSEPORT
+var ErrorReusePortUnsupported = errors.New("SO_REUSEPORT is not supported on this platform")
+
+// Listen returns the listener to serve on. A socket handed over by systemd
+// socket activation (LISTEN_PID/LISTEN_FDS) wins, so the socket outlives
+// restarts and queued connections wait for the new process. Otherwise addr
+// is bound, with SO_REUSEPORT when reusePort is set so the next release can
+// bind the same port before this one stops accepting.
+func Listen(ctx context.Context, addr string, reusePort bool) (net.Listener, error) {
+	if l, err := activated(); l != nil || err != nil {
+		return l, err
+	}
+	lc := net.ListenConfig{}
+	if reusePort {
+		lc.Control = reusePortControl
+	}
+	return lc.Listen(ctx, "tcp", addr)
+}
+
+// activated returns the first socket passed by systemd, or nil when the
+// process was not socket activated.
+func activated() (net.Listener, error) {
+	if pid, _ := strconv.Atoi(os.Getenv("LISTEN_PID")); pid != os.Getpid() {
+		return nil, nil
+	}
+	fds, _ := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if fds < 1 {
+		return nil, nil
+	}
+	// file descriptors 0-2 are stdio, activated sockets start at 3
+	f := os.NewFile(3, "listen-fd-3")
+	defer f.Close()
+	l, err := net.FileListener(f)
+	if err != nil {
+		return nil, fmt.Errorf("socket activation: %w", err)
+	}
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	return l, nil
+}
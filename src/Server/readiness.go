@@ -0,0 +1,56 @@
+package Server
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// Readiness answers the load balancer's readiness probe. It fails once Drain
+// is called, so traffic moves to other instances before the server stops
+// accepting, and while a required dependency (e.g. the database) fails. A
+// failing optional dependency answers 200 with status degraded.
+type Readiness struct {
+	draining atomic.Bool
+	health   *Health
+}
+
+func NewReadiness(health *Health) *Readiness {
+	return &Readiness{health: health}
+}
+
+// Drain marks the instance as going away
+func (r *Readiness) Drain() { r.draining.Store(true) }
+
+func (r *Readiness) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if r.draining.Load() {
+		write(w, http.StatusServiceUnavailable, "draining", nil)
+		return
+	}
+	ready, degraded, checks := r.health.report(req.Context())
+	switch {
+	case !ready:
+		write(w, http.StatusServiceUnavailable, "unavailable", checks)
+	case degraded:
+		write(w, http.StatusOK, "degraded", checks)
+	default:
+		write(w, http.StatusOK, "ready", checks)
+	}
+}
+
+// Live answers the liveness probe: the process is up and serving
+func Live(w http.ResponseWriter, _ *http.Request) {
+	write(w, http.StatusOK, "alive", nil)
+}
+
+func write(w http.ResponseWriter, status int, state string, checks map[string]string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	body := map[string]interface{}{"status": state, "timestamp": time.Now().UTC()}
+	if checks != nil {
+		body["checks"] = checks
+	}
+	_ = json.NewEncoder(w).Encode(body)
+}
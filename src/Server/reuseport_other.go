@@ -0,0 +1,11 @@
+//go:build !linux && !darwin
+
+package Server
+
+import (
+	"syscall"
+)
+
+func reusePortControl(network, address string, c syscall.RawConn) error {
+	return ErrorReusePortUnsupported
+}
@@ -0,0 +1,70 @@
+package Shipping
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// Label is a purchased shipping label and its printable document
+type Label struct {
+	CarrierShipmentID string
+	TrackingNumber    string
+	TrackingURL       string // public tracking page, when the carrier has one
+	Format            string // PDF, PNG, ZPL
+	Document          []byte
+	Cost              decimal.Decimal
+	Currency          string
+}
+
+// Carrier is implemented by every shipping carrier integration
+type Carrier interface {
+	Name() string
+	PurchaseLabel(ctx context.Context, s *Shipment) (*Label, error)
+}
+
+// LocalCarrier is used for own-fleet deliveries: it issues a tracking number
+// and a ZPL label without calling any external API. Drivers report
+// deliveries, so it doesn't track parcels.
+type LocalCarrier struct {
+	From Address
+	// TrackingURL, when set, is the fleet's tracking page with %s for the
+	// tracking number
+	TrackingURL string
+}
+
+func NewLocalCarrier(from Address) *LocalCarrier { return &LocalCarrier{From: from} }
+
+func (c *LocalCarrier) Name() string { return "local" }
+
+func (c *LocalCarrier) PurchaseLabel(ctx context.Context, s *Shipment) (*Label, error) {
+	tracking := "LC" + strings.ToUpper(strings.ReplaceAll(uuid.New().String(), "-", "")[:12])
+	var b strings.Builder
+	b.WriteString("^XA\n")
+	fmt.Fprintf(&b, "^FO30,30^A0N,28,28^FDFROM: %s^FS\n", c.From.RecipientName)
+	fmt.Fprintf(&b, "^FO30,70^A0N,24,24^FD%s, %s %s^FS\n", c.From.Line1, c.From.City, c.From.Country)
+	fmt.Fprintf(&b, "^FO30,140^A0N,36,36^FDTO: %s^FS\n", s.RecipientName)
+	fmt.Fprintf(&b, "^FO30,190^A0N,30,30^FD%s^FS\n", s.Line1)
+	if s.Line2 != nil {
+		fmt.Fprintf(&b, "^FO30,230^A0N,30,30^FD%s^FS\n", *s.Line2)
+	}
+	fmt.Fprintf(&b, "^FO30,270^A0N,30,30^FD%s %s %s^FS\n", s.City, deref(s.PostalCode), s.Country)
+	fmt.Fprintf(&b, "^FO30,330^A0N,24,24^FDWeight: %dg  Order: %s^FS\n", s.WeightGrams, s.OrderID)
+	fmt.Fprintf(&b, "^FO30,380^BY3^BCN,120,Y,N,N^FD%s^FS\n", tracking)
+	b.WriteString("^XZ\n")
+	var url string
+	if c.TrackingURL != "" {
+		url = fmt.Sprintf(c.TrackingURL, tracking)
+	}
+	return &Label{CarrierShipmentID: tracking, TrackingNumber: tracking, TrackingURL: url, Format: "ZPL", Document: []byte(b.String()), Cost: decimal.Zero}, nil
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,8 @@
+package Shipping
+
+type CreateShipmentRequest struct {
+	Carrier      string  `json:"carrier" validate:"required"`
+	ServiceLevel *string `json:"service_level,omitempty"`
+	Address      Address `json:"ship_to" validate:"required"`
+	WeightGrams  int     `json:"weight_grams" validate:"required,min=1"`
+}
@@ -0,0 +1,245 @@
+package Shipping
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// EasyPostCarrier buys labels through EasyPost, which fronts most parcel
+// carriers. The cheapest rate is bought unless the shipment names a service.
+type EasyPostCarrier struct {
+	APIKey string
+	From   Address
+	client *http.Client
+}
+
+func NewEasyPostCarrier(apiKey string, from Address) *EasyPostCarrier {
+	return &EasyPostCarrier{APIKey: apiKey, From: from, client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+func (c *EasyPostCarrier) Name() string { return "easypost" }
+
+type easyPostRate struct {
+	ID       string `json:"id"`
+	Service  string `json:"service"`
+	Rate     string `json:"rate"`
+	Currency string `json:"currency"`
+}
+
+func easyPostAddress(a Address) map[string]interface{} {
+	return map[string]interface{}{
+		"name": a.RecipientName, "street1": a.Line1, "street2": deref(a.Line2), "city": a.City,
+		"state": deref(a.Region), "zip": deref(a.PostalCode), "country": a.Country, "phone": deref(a.Phone),
+	}
+}
+
+func (c *EasyPostCarrier) PurchaseLabel(ctx context.Context, s *Shipment) (*Label, error) {
+	var created struct {
+		ID    string         `json:"id"`
+		Rates []easyPostRate `json:"rates"`
+	}
+	ounces := float64(s.WeightGrams) / 28.3495
+	shipment := map[string]interface{}{
+		"to_address":   easyPostAddress(s.Address),
+		"from_address": easyPostAddress(c.From),
+		"parcel":       map[string]interface{}{"weight": ounces},
+		"reference":    s.OrderID.String(),
+	}
+	if s.International() {
+		shipment["customs_info"] = c.customsInfo(s, ounces)
+	}
+	err := c.call(ctx, http.MethodPost, "/v2/shipments", map[string]interface{}{"shipment": shipment}, &created)
+	if err != nil {
+		return nil, err
+	}
+	rate, err := pickRate(created.Rates, s.ServiceLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	var bought struct {
+		TrackingCode string `json:"tracking_code"`
+		PostageLabel struct {
+			LabelURL      string `json:"label_url"`
+			LabelFileType string `json:"label_file_type"`
+		} `json:"postage_label"`
+		Tracker easyPostTracker `json:"tracker"`
+	}
+	if err := c.call(ctx, http.MethodPost, "/v2/shipments/"+created.ID+"/buy", map[string]interface{}{"rate": map[string]string{"id": rate.ID}}, &bought); err != nil {
+		return nil, err
+	}
+	doc, err := c.download(ctx, bought.PostageLabel.LabelURL)
+	if err != nil {
+		return nil, err
+	}
+	cost, _ := decimal.NewFromString(rate.Rate)
+	format := strings.ToUpper(strings.TrimPrefix(bought.PostageLabel.LabelFileType, "image/"))
+	if strings.HasSuffix(format, "PDF") {
+		format = "PDF"
+	}
+	return &Label{CarrierShipmentID: created.ID, TrackingNumber: bought.TrackingCode, TrackingURL: bought.Tracker.PublicURL, Format: format, Document: doc, Cost: cost, Currency: rate.Currency}, nil
+}
+
+type easyPostTracker struct {
+	TrackingCode string    `json:"tracking_code"`
+	Status       string    `json:"status"`
+	StatusDetail string    `json:"status_detail"`
+	PublicURL    string    `json:"public_url"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+func (t easyPostTracker) tracking() *Tracking {
+	status := TrackingUnknown
+	switch t.Status {
+	case "pre_transit":
+		status = TrackingPreTransit
+	case "in_transit":
+		status = TrackingInTransit
+	case "out_for_delivery":
+		status = TrackingOutForDelivery
+	case "available_for_pickup":
+		status = TrackingPickup
+	case "delivered":
+		status = TrackingDelivered
+	case "return_to_sender", "failure", "cancelled", "error":
+		status = TrackingException
+	}
+	return &Tracking{Status: status, Detail: t.StatusDetail, URL: t.PublicURL, At: t.UpdatedAt}
+}
+
+// Track reads the tracker EasyPost keeps for the shipment's label
+func (c *EasyPostCarrier) Track(ctx context.Context, s *Shipment) (*Tracking, error) {
+	if s.CarrierShipmentID == nil {
+		return nil, ErrorNoLabel
+	}
+	var shipment struct {
+		Tracker easyPostTracker `json:"tracker"`
+	}
+	if err := c.call(ctx, http.MethodGet, "/v2/shipments/"+*s.CarrierShipmentID, nil, &shipment); err != nil {
+		return nil, err
+	}
+	return shipment.Tracker.tracking(), nil
+}
+
+// ParseEasyPostEvent verifies an EasyPost webhook event, signed in
+// X-Hmac-Signature as "hmac-sha256-hex=" and the HMAC-SHA256 of the body,
+// and returns the tracking number and progress of a tracker update. Other
+// events come back as a nil Tracking.
+func ParseEasyPostEvent(payload []byte, signature string, secret []byte) (string, *Tracking, error) {
+	hexSig, ok := strings.CutPrefix(signature, "hmac-sha256-hex=")
+	if len(secret) == 0 || !ok {
+		return "", nil, ErrorInvalidSignature
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	if !hmac.Equal([]byte(strings.ToLower(hexSig)), []byte(hex.EncodeToString(mac.Sum(nil)))) {
+		return "", nil, ErrorInvalidSignature
+	}
+	var event struct {
+		Description string          `json:"description"`
+		Result      easyPostTracker `json:"result"`
+	}
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return "", nil, err
+	}
+	if event.Description != "tracker.created" && event.Description != "tracker.updated" {
+		return "", nil, nil
+	}
+	return event.Result.TrackingCode, event.Result.tracking(), nil
+}
+
+// customsInfo declares the shipment's items; the parcel weight is split
+// across them by quantity since items carry no weight of their own
+func (c *EasyPostCarrier) customsInfo(s *Shipment, ounces float64) map[string]interface{} {
+	units := 0
+	for _, it := range s.CustomsItems {
+		units += it.Quantity
+	}
+	items := make([]map[string]interface{}, 0, len(s.CustomsItems))
+	for _, it := range s.CustomsItems {
+		value, _ := it.Value().Float64()
+		items = append(items, map[string]interface{}{
+			"description": it.Description, "quantity": it.Quantity, "value": value, "currency": it.Currency,
+			"weight": ounces * float64(it.Quantity) / float64(max(units, 1)),
+			"hs_tariff_number": deref(it.HSCode), "origin_country": deref(it.CountryOfOrigin), "code": deref(it.SKU),
+		})
+	}
+	return map[string]interface{}{
+		"contents_type": "merchandise", "customs_certify": true, "customs_signer": c.From.RecipientName,
+		"eel_pfc": "NOEEI 30.37(a)", "non_delivery_option": "return", "customs_items": items,
+	}
+}
+
+func pickRate(rates []easyPostRate, service *string) (*easyPostRate, error) {
+	var best *easyPostRate
+	for i := range rates {
+		r := &rates[i]
+		if service != nil {
+			if strings.EqualFold(r.Service, *service) {
+				return r, nil
+			}
+			continue
+		}
+		if best == nil || decimal.RequireFromString(r.Rate).LessThan(decimal.RequireFromString(best.Rate)) {
+			best = r
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("easypost: no matching rate")
+	}
+	return best, nil
+}
+
+func (c *EasyPostCarrier) call(ctx context.Context, method, path string, body interface{}, out interface{}) error {
+	var payload io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		payload = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, "https://api.easypost.com"+path, payload)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(c.APIKey, "")
+	req.Header.Set("Content-Type", "application/json")
+	res, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("easypost %s: status %d: %s", path, res.StatusCode, msg)
+	}
+	return json.NewDecoder(res.Body).Decode(out)
+}
+
+func (c *EasyPostCarrier) download(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		return nil, fmt.Errorf("easypost label download: status %d", res.StatusCode)
+	}
+	return io.ReadAll(res.Body)
+}
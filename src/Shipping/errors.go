@@ -0,0 +1,31 @@
+package Shipping
+
+import (
+	"errors"
+
+	"savannah/src/ErrorCodes"
+)
+
+var (
+	ErrorNotFound         = errors.New("shipment not found")
+	ErrorUnknownCarrier   = errors.New("unknown carrier")
+	ErrorLabelExists      = errors.New("label already purchased")
+	ErrorNoLabel          = errors.New("no label purchased for shipment")
+	ErrorVersionConflict  = errors.New("version conflict")
+	ErrorMissingCustoms   = errors.New("international shipments need an hs code and country of origin for every item")
+	ErrorNotInternational = errors.New("shipment is not international")
+	ErrorInvalidSignature = errors.New("invalid webhook signature")
+)
+
+func init() {
+	ErrorCodes.Register("SHIPPING",
+		ErrorCodes.Def{N: 1, Slug: "NOT_FOUND", Err: ErrorNotFound},
+		ErrorCodes.Def{N: 2, Slug: "UNKNOWN_CARRIER", Err: ErrorUnknownCarrier},
+		ErrorCodes.Def{N: 3, Slug: "LABEL_EXISTS", Err: ErrorLabelExists},
+		ErrorCodes.Def{N: 4, Slug: "NO_LABEL", Err: ErrorNoLabel},
+		ErrorCodes.Def{N: 5, Slug: "VERSION_CONFLICT", Err: ErrorVersionConflict},
+		ErrorCodes.Def{N: 6, Slug: "MISSING_CUSTOMS_DATA", Err: ErrorMissingCustoms},
+		ErrorCodes.Def{N: 7, Slug: "NOT_INTERNATIONAL", Err: ErrorNotInternational},
+		ErrorCodes.Def{N: 8, Slug: "INVALID_SIGNATURE", Err: ErrorInvalidSignature},
+	)
+}
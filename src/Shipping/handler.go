@@ -0,0 +1,343 @@
+package Shipping
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"savannah/src/ErrorCodes"
+	"savannah/src/Storage"
+)
+
+type Handler struct {
+	svc      Service
+	webhooks WebhookSecrets
+	log      *zap.Logger
+	v        *validator.Validate
+}
+
+// WebhookSecrets verify the tracking updates carriers push; a carrier
+// without a secret has its webhook refused
+type WebhookSecrets struct {
+	EasyPost []byte
+}
+
+func NewHandler(s Service, webhooks WebhookSecrets, log *zap.Logger) *Handler {
+	return &Handler{svc: s, webhooks: webhooks, log: log, v: validator.New()}
+}
+
+// CreateShipment godoc
+// @Summary      Create a shipment for an order
+// @Description  The order's physical items are copied onto the shipment as customs lines. When the ship-to country differs from the country shipped from, every item's product needs an HS code and country of origin, otherwise 422 names the items missing them.
+// @Tags         shipping
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                 true  "Order ID"
+// @Param        payload  body      CreateShipmentRequest  true  "Shipment"
+// @Success      201      {object}  Shipment
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      404      {object}  map[string]interface{}
+// @Failure      422      {object}  map[string]interface{}
+// @Router       /orders/{id}/shipments [post]
+func (h *Handler) CreateShipment(w http.ResponseWriter, r *http.Request) {
+	orderID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var req CreateShipmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	if err := h.v.Struct(req); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	sh, err := h.svc.CreateShipment(r.Context(), orderID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			h.writeError(w, http.StatusNotFound, "order not found")
+		case errors.Is(err, ErrorUnknownCarrier):
+			h.writeError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, ErrorMissingCustoms):
+			h.writeError(w, http.StatusUnprocessableEntity, err.Error())
+		default:
+			h.log.Error("create shipment", zap.Error(err))
+			h.writeError(w, http.StatusInternalServerError, "failed to create shipment")
+		}
+		return
+	}
+	h.writeJSON(w, http.StatusCreated, sh)
+}
+
+// ListShipments godoc
+// @Summary      List shipments for an order
+// @Tags         shipping
+// @Produce      json
+// @Param        id   path      string  true  "Order ID"
+// @Success      200  {array}   Shipment
+// @Router       /orders/{id}/shipments [get]
+func (h *Handler) ListShipments(w http.ResponseWriter, r *http.Request) {
+	orderID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	list, err := h.svc.ListShipments(r.Context(), orderID)
+	if err != nil {
+		h.log.Error("list shipments", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to list shipments")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, list)
+}
+
+// PurchaseLabel godoc
+// @Summary      Buy a shipping label
+// @Description  Purchases a label from the shipment's carrier and stores the label document and tracking number
+// @Tags         shipping
+// @Produce      json
+// @Param        id   path      string  true  "Order ID"
+// @Param        sid  path      string  true  "Shipment ID"
+// @Success      200  {object}  Shipment
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      409  {object}  map[string]interface{}
+// @Failure      502  {object}  map[string]interface{}
+// @Router       /orders/{id}/shipments/{sid}/label [post]
+func (h *Handler) PurchaseLabel(w http.ResponseWriter, r *http.Request) {
+	orderID, id, ok := h.ids(w, r)
+	if !ok {
+		return
+	}
+	sh, err := h.svc.PurchaseLabel(r.Context(), orderID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrorNotFound):
+			h.writeError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, ErrorLabelExists), errors.Is(err, ErrorVersionConflict):
+			h.writeError(w, http.StatusConflict, err.Error())
+		case errors.Is(err, ErrorUnknownCarrier):
+			h.writeError(w, http.StatusBadRequest, err.Error())
+		default:
+			h.log.Error("purchase label", zap.Error(err))
+			h.writeError(w, http.StatusBadGateway, "failed to purchase label")
+		}
+		return
+	}
+	h.writeJSON(w, http.StatusOK, sh)
+}
+
+// MarkShipped godoc
+// @Summary      Mark a shipment as shipped
+// @Description  Records hand-over to the carrier and captures the order's payment authorization
+// @Tags         shipping
+// @Produce      json
+// @Param        id   path      string  true  "Order ID"
+// @Param        sid  path      string  true  "Shipment ID"
+// @Success      200  {object}  Shipment
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      409  {object}  map[string]interface{}
+// @Router       /orders/{id}/shipments/{sid}/ship [post]
+func (h *Handler) MarkShipped(w http.ResponseWriter, r *http.Request) {
+	orderID, id, ok := h.ids(w, r)
+	if !ok {
+		return
+	}
+	sh, err := h.svc.MarkShipped(r.Context(), orderID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrorNotFound):
+			h.writeError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, ErrorNoLabel), errors.Is(err, ErrorVersionConflict):
+			h.writeError(w, http.StatusConflict, err.Error())
+		default:
+			h.log.Error("mark shipped", zap.Error(err))
+			h.writeError(w, http.StatusInternalServerError, "failed to update shipment")
+		}
+		return
+	}
+	h.writeJSON(w, http.StatusOK, sh)
+}
+
+// MarkDelivered godoc
+// @Summary      Mark a shipment as delivered
+// @Description  For carriers that don't report tracking, such as the own fleet. Once every shipment of the order is delivered the order is completed; a shipment never marked shipped has the order's payment captured first.
+// @Tags         shipping
+// @Produce      json
+// @Param        id   path      string  true  "Order ID"
+// @Param        sid  path      string  true  "Shipment ID"
+// @Success      200  {object}  Shipment
+// @Failure      404  {object}  map[string]interface{}
+// @Failure      409  {object}  map[string]interface{}
+// @Router       /orders/{id}/shipments/{sid}/deliver [post]
+func (h *Handler) MarkDelivered(w http.ResponseWriter, r *http.Request) {
+	orderID, id, ok := h.ids(w, r)
+	if !ok {
+		return
+	}
+	sh, err := h.svc.MarkDelivered(r.Context(), orderID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrorNotFound):
+			h.writeError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, ErrorNoLabel), errors.Is(err, ErrorVersionConflict):
+			h.writeError(w, http.StatusConflict, err.Error())
+		default:
+			h.log.Error("mark delivered", zap.Error(err))
+			h.writeError(w, http.StatusInternalServerError, "failed to update shipment")
+		}
+		return
+	}
+	h.writeJSON(w, http.StatusOK, sh)
+}
+
+// EasyPostWebhook godoc
+// @Summary      EasyPost tracking webhook
+// @Description  Receives tracker.created and tracker.updated events, signed in X-Hmac-Signature; other events and parcels that aren't ours are acknowledged and ignored
+// @Tags         webhooks
+// @Accept       json
+// @Success      200
+// @Failure      400  {object}  map[string]interface{}
+// @Failure      401  {object}  map[string]interface{}
+// @Failure      409  {object}  map[string]interface{}
+// @Router       /webhooks/easypost [post]
+func (h *Handler) EasyPostWebhook(w http.ResponseWriter, r *http.Request) {
+	payload, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid payload")
+		return
+	}
+	trackingNumber, t, err := ParseEasyPostEvent(payload, r.Header.Get("X-Hmac-Signature"), h.webhooks.EasyPost)
+	if errors.Is(err, ErrorInvalidSignature) {
+		h.writeError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if t != nil {
+		if _, err := h.svc.RecordTracking(r.Context(), "easypost", trackingNumber, *t); err != nil {
+			switch {
+			case errors.Is(err, ErrorNotFound), errors.Is(err, ErrorNoLabel):
+				// not a parcel of ours, or one we can't track; nothing to retry
+			case errors.Is(err, ErrorVersionConflict):
+				// EasyPost retries the event
+				h.writeError(w, http.StatusConflict, err.Error())
+				return
+			default:
+				h.log.Error("record easypost tracking", zap.String("tracking_number", trackingNumber), zap.Error(err))
+				h.writeError(w, http.StatusInternalServerError, "failed to record tracking")
+				return
+			}
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// GetLabel godoc
+// @Summary      Print a shipping label
+// @Description  Returns the stored label document (PDF, PNG or ZPL)
+// @Tags         shipping
+// @Param        id   path  string  true  "Order ID"
+// @Param        sid  path  string  true  "Shipment ID"
+// @Success      200
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /orders/{id}/shipments/{sid}/label [get]
+func (h *Handler) GetLabel(w http.ResponseWriter, r *http.Request) {
+	orderID, id, ok := h.ids(w, r)
+	if !ok {
+		return
+	}
+	rc, contentType, err := h.svc.OpenLabel(r.Context(), orderID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrorNotFound), errors.Is(err, ErrorNoLabel), errors.Is(err, Storage.ErrBlobNotFound):
+			h.writeError(w, http.StatusNotFound, err.Error())
+		default:
+			h.log.Error("open label", zap.Error(err))
+			h.writeError(w, http.StatusInternalServerError, "failed to open label")
+		}
+		return
+	}
+	defer rc.Close()
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", "label-"+id.String()))
+	w.WriteHeader(http.StatusOK)
+	_, _ = io.Copy(w, rc)
+}
+
+// CommercialInvoice godoc
+// @Summary      Commercial invoice for customs
+// @Description  Shipper, consignee and the declared items with HS codes, countries of origin and values of an international shipment, as printable HTML (default) or JSON
+// @Tags         shipping
+// @Produce      html
+// @Produce      json
+// @Param        id      path   string  true   "Order ID"
+// @Param        sid     path   string  true   "Shipment ID"
+// @Param        format  query  string  false  "html (default) or json"
+// @Success      200     {object}  CommercialInvoice
+// @Failure      404     {object}  map[string]interface{}
+// @Failure      409     {object}  map[string]interface{}
+// @Router       /orders/{id}/shipments/{sid}/commercial-invoice [get]
+func (h *Handler) CommercialInvoice(w http.ResponseWriter, r *http.Request) {
+	orderID, id, ok := h.ids(w, r)
+	if !ok {
+		return
+	}
+	inv, err := h.svc.CommercialInvoice(r.Context(), orderID, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrorNotFound):
+			h.writeError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, ErrorNotInternational):
+			h.writeError(w, http.StatusConflict, err.Error())
+		default:
+			h.log.Error("commercial invoice", zap.Error(err))
+			h.writeError(w, http.StatusInternalServerError, "failed to render commercial invoice")
+		}
+		return
+	}
+	if r.URL.Query().Get("format") == "json" {
+		h.writeJSON(w, http.StatusOK, inv)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := inv.WriteHTML(w); err != nil {
+		h.log.Error("render commercial invoice", zap.Error(err))
+	}
+}
+
+func (h *Handler) ids(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	orderID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return uuid.Nil, uuid.Nil, false
+	}
+	id, err := uuid.Parse(chi.URLParam(r, "sid"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid shipment id")
+		return uuid.Nil, uuid.Nil, false
+	}
+	return orderID, id, true
+}
+
+func (h *Handler) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (h *Handler) writeError(w http.ResponseWriter, status int, msg string) {
+	h.writeJSON(w, status, map[string]interface{}{"error": msg, "code": ErrorCodes.Resolve("SHIPPING", status, msg), "timestamp": time.Now().UTC()})
+}
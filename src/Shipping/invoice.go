@@ -0,0 +1,62 @@
+package Shipping
+
+import (
+	"context"
+	"html/template"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// CommercialInvoice is the customs document that travels with an
+// international parcel: who ships what to whom, and what it is worth
+type CommercialInvoice struct {
+	Shipment   Shipment        `json:"shipment"`
+	Shipper    Address         `json:"shipper"`
+	Items      []CustomsItem   `json:"items"`
+	TotalValue decimal.Decimal `json:"total_value"`
+	Currency   string          `json:"currency"`
+	Reason     string          `json:"reason_for_export"`
+	IssuedAt   time.Time       `json:"issued_at"`
+}
+
+// CommercialInvoice lists the customs lines captured when the shipment was
+// created; domestic shipments have none to declare
+func (s *service) CommercialInvoice(ctx context.Context, orderID, id uuid.UUID) (*CommercialInvoice, error) {
+	sh, err := s.repo.Get(ctx, orderID, id)
+	if err != nil {
+		return nil, err
+	}
+	if !sh.International() {
+		return nil, ErrorNotInternational
+	}
+	inv := &CommercialInvoice{Shipment: *sh, Shipper: s.from, Items: sh.CustomsItems, TotalValue: decimal.Zero, Reason: "Sale", IssuedAt: time.Now().UTC()}
+	for _, it := range sh.CustomsItems {
+		inv.TotalValue = inv.TotalValue.Add(it.Value())
+		inv.Currency = it.Currency
+	}
+	return inv, nil
+}
+
+var commercialInvoiceHTML = template.Must(template.New("commercial-invoice").Funcs(template.FuncMap{"deref": deref}).Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Commercial invoice {{.Shipment.OrderID}}</title>
+<style>body{font-family:sans-serif;font-size:12px}table{border-collapse:collapse;width:100%}td,th{border:1px solid #999;padding:4px;text-align:left}.num{text-align:right}.parties td{border:none;vertical-align:top;width:50%}</style>
+</head><body>
+<h1>Commercial invoice</h1>
+<p>Date: {{.IssuedAt.Format "2006-01-02"}}<br>Order: {{.Shipment.OrderID}}<br>Shipment: {{.Shipment.ID}}{{if .Shipment.TrackingNumber}}<br>Tracking number: {{deref .Shipment.TrackingNumber}}{{end}}<br>Reason for export: {{.Reason}}</p>
+<table class="parties"><tr>
+<td><strong>Shipper</strong><br>{{.Shipper.RecipientName}}<br>{{.Shipper.Line1}}<br>{{.Shipper.City}} {{deref .Shipper.PostalCode}}<br>{{.Shipper.Country}}</td>
+<td><strong>Consignee</strong><br>{{.Shipment.RecipientName}}<br>{{.Shipment.Line1}}{{if .Shipment.Line2}}<br>{{deref .Shipment.Line2}}{{end}}<br>{{.Shipment.City}} {{deref .Shipment.Region}} {{deref .Shipment.PostalCode}}<br>{{.Shipment.Country}}{{if .Shipment.Phone}}<br>{{deref .Shipment.Phone}}{{end}}</td>
+</tr></table>
+<table><tr><th>#</th><th>Description</th><th>SKU</th><th>HS code</th><th>Origin</th><th class="num">Qty</th><th class="num">Unit value</th><th class="num">Value</th></tr>
+{{range .Items}}<tr><td>{{.Line}}</td><td>{{.Description}}</td><td>{{deref .SKU}}</td><td>{{deref .HSCode}}</td><td>{{deref .CountryOfOrigin}}</td><td class="num">{{.Quantity}}</td><td class="num">{{.UnitValue.StringFixed 2}}</td><td class="num">{{.Value.StringFixed 2}}</td></tr>
+{{end}}<tr><td colspan="7"><strong>Total ({{.Currency}})</strong></td><td class="num"><strong>{{.TotalValue.StringFixed 2}}</strong></td></tr></table>
+<p>Gross weight: {{.Shipment.WeightGrams}} g</p>
+<p>I declare that the information in this invoice is true and correct.</p>
+</body></html>`))
+
+func (c *CommercialInvoice) WriteHTML(w io.Writer) error {
+	return commercialInvoiceHTML.Execute(w, c)
+}
@@ -0,0 +1,86 @@
+package Shipping
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// shipment statuses
+const (
+	StatusPending        = "PENDING"
+	StatusLabelPurchased = "LABEL_PURCHASED"
+	StatusShipped        = "SHIPPED"
+	StatusDelivered      = "DELIVERED"
+	StatusCancelled      = "CANCELLED"
+)
+
+type Address struct {
+	RecipientName string  `db:"recipient_name" json:"recipient_name" validate:"required,max=200"`
+	Line1         string  `db:"address_line1" json:"line1" validate:"required,max=255"`
+	Line2         *string `db:"address_line2" json:"line2,omitempty" validate:"omitempty,max=255"`
+	City          string  `db:"city" json:"city" validate:"required,max=100"`
+	Region        *string `db:"region" json:"region,omitempty"`
+	PostalCode    *string `db:"postal_code" json:"postal_code,omitempty"`
+	Country       string  `db:"country" json:"country" validate:"required,len=2"`
+	Phone         *string `db:"phone" json:"phone,omitempty"`
+}
+
+type Shipment struct {
+	ID           uuid.UUID `db:"id" json:"id"`
+	OrderID      uuid.UUID `db:"order_id" json:"order_id"`
+	Carrier      string    `db:"carrier" json:"carrier"`
+	ServiceLevel *string   `db:"service_level" json:"service_level,omitempty"`
+	Status       string    `db:"status" json:"status"`
+	Address                // ship-to
+	// country the parcel leaves from; when it differs from the ship-to
+	// country the shipment is international and declares CustomsItems
+	OriginCountry     *string          `db:"origin_country" json:"origin_country,omitempty"`
+	WeightGrams       int              `db:"weight_grams" json:"weight_grams"`
+	TrackingNumber    *string          `db:"tracking_number" json:"tracking_number,omitempty"`
+	TrackingURL       *string          `db:"tracking_url" json:"tracking_url,omitempty"`
+	TrackingStatus    *string          `db:"tracking_status" json:"tracking_status,omitempty"` // latest reported by the carrier
+	TrackingDetail    *string          `db:"tracking_detail" json:"tracking_detail,omitempty"`
+	TrackedAt         *time.Time       `db:"tracked_at" json:"tracked_at,omitempty"`
+	DeliveredAt       *time.Time       `db:"delivered_at" json:"delivered_at,omitempty"`
+	CarrierShipmentID *string          `db:"carrier_shipment_id" json:"-"`
+	LabelKey          *string          `db:"label_key" json:"-"`
+	LabelFormat       *string          `db:"label_format" json:"label_format,omitempty"`
+	LabelCost         *decimal.Decimal `db:"label_cost" json:"label_cost,omitempty"`
+	LabelCurrency     *string          `db:"label_currency" json:"label_currency,omitempty"`
+	LabelPurchasedAt  *time.Time       `db:"label_purchased_at" json:"label_purchased_at,omitempty"`
+	CreatedAt         time.Time        `db:"created_at" json:"created_at"`
+	UpdatedAt         time.Time        `db:"updated_at" json:"updated_at"`
+	Version           int              `db:"version" json:"version"`
+	CustomsItems      []CustomsItem    `db:"-" json:"customs_items,omitempty"`
+}
+
+// International reports whether the parcel crosses a border
+func (s *Shipment) International() bool {
+	return s.OriginCountry != nil && *s.OriginCountry != s.Country
+}
+
+// CustomsItem is one line of the customs declaration and commercial invoice
+type CustomsItem struct {
+	ShipmentID      uuid.UUID       `db:"shipment_id" json:"-"`
+	Line            int             `db:"line" json:"line"`
+	ProductID       *uuid.UUID      `db:"product_id" json:"product_id,omitempty"`
+	SKU             *string         `db:"sku" json:"sku,omitempty"`
+	Description     string          `db:"description" json:"description"`
+	Quantity        int             `db:"quantity" json:"quantity"`
+	UnitValue       decimal.Decimal `db:"unit_value" json:"unit_value"`
+	Currency        string          `db:"currency" json:"currency"`
+	HSCode          *string         `db:"hs_code" json:"hs_code,omitempty"`
+	CountryOfOrigin *string         `db:"country_of_origin" json:"country_of_origin,omitempty"`
+}
+
+// Value is the declared value of the line
+func (c CustomsItem) Value() decimal.Decimal {
+	return c.UnitValue.Mul(decimal.NewFromInt(int64(c.Quantity)))
+}
+
+const (
+	TableName        = "shipments"
+	CustomsItemTable = "shipment_customs_items"
+)
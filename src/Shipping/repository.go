@@ -0,0 +1,213 @@
+package Shipping
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+type Repository interface {
+	Create(ctx context.Context, s *Shipment) error
+	Get(ctx context.Context, orderID, id uuid.UUID) (*Shipment, error)
+	ListByOrder(ctx context.Context, orderID uuid.UUID) ([]Shipment, error)
+	SaveLabel(ctx context.Context, s *Shipment) error
+	UpdateStatus(ctx context.Context, s *Shipment) error
+	SaveTracking(ctx context.Context, s *Shipment) error
+	GetByTracking(ctx context.Context, carrier, trackingNumber string) (*Shipment, error)
+	// InTransit lists shipments of carriers whose parcels are on their way,
+	// not tracked since before and labelled after labelledAfter
+	InTransit(ctx context.Context, carriers []string, before, labelledAfter time.Time, limit int) ([]Shipment, error)
+	OrderExists(ctx context.Context, orderID uuid.UUID) (bool, error)
+	// OrderCustomsItems builds the customs lines of the order's physical items
+	// from the products' current customs data
+	OrderCustomsItems(ctx context.Context, orderID uuid.UUID) ([]CustomsItem, error)
+}
+
+type repository struct {
+	db  *sqlx.DB
+	log *zap.Logger
+}
+
+func NewRepository(db *sqlx.DB, log *zap.Logger) Repository { return &repository{db: db, log: log} }
+
+const shipmentColumns = `id,order_id,carrier,service_level,status,recipient_name,address_line1,address_line2,city,region,postal_code,country,phone,origin_country,weight_grams,tracking_number,tracking_url,tracking_status,tracking_detail,tracked_at,delivered_at,carrier_shipment_id,label_key,label_format,label_cost,label_currency,label_purchased_at,created_at,updated_at,version`
+
+// Create stores the shipment with its customs lines
+func (r *repository) Create(ctx context.Context, s *Shipment) (err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	query := fmt.Sprintf(`INSERT INTO %s (id,order_id,carrier,service_level,status,recipient_name,address_line1,address_line2,city,region,postal_code,country,phone,origin_country,weight_grams,created_at,updated_at,version)
+		VALUES (:id,:order_id,:carrier,:service_level,:status,:recipient_name,:address_line1,:address_line2,:city,:region,:postal_code,:country,:phone,:origin_country,:weight_grams,:created_at,:updated_at,:version)`, TableName)
+	if _, err = tx.NamedExecContext(ctx, query, s); err != nil {
+		return err
+	}
+	itemQuery := fmt.Sprintf(`INSERT INTO %s (%s) VALUES (:shipment_id,:line,:product_id,:sku,:description,:quantity,:unit_value,:currency,:hs_code,:country_of_origin)`, CustomsItemTable, customsItemColumns)
+	for i := range s.CustomsItems {
+		s.CustomsItems[i].ShipmentID = s.ID
+		if _, err = tx.NamedExecContext(ctx, itemQuery, &s.CustomsItems[i]); err != nil {
+			return err
+		}
+	}
+	err = tx.Commit()
+	return err
+}
+
+const customsItemColumns = `shipment_id,line,product_id,sku,description,quantity,unit_value,currency,hs_code,country_of_origin`
+
+// customsItems loads the customs lines of the shipments
+func (r *repository) customsItems(ctx context.Context, shipments []Shipment) error {
+	if len(shipments) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, len(shipments))
+	for i := range shipments {
+		ids[i] = shipments[i].ID
+	}
+	query, args, err := sqlx.In(fmt.Sprintf(`SELECT %s FROM %s WHERE shipment_id IN (?) ORDER BY shipment_id, line`, customsItemColumns, CustomsItemTable), ids)
+	if err != nil {
+		return err
+	}
+	items := []CustomsItem{}
+	if err := r.db.SelectContext(ctx, &items, r.db.Rebind(query), args...); err != nil {
+		return err
+	}
+	byShipment := map[uuid.UUID][]CustomsItem{}
+	for _, it := range items {
+		byShipment[it.ShipmentID] = append(byShipment[it.ShipmentID], it)
+	}
+	for i := range shipments {
+		shipments[i].CustomsItems = byShipment[shipments[i].ID]
+	}
+	return nil
+}
+
+func (r *repository) Get(ctx context.Context, orderID, id uuid.UUID) (*Shipment, error) {
+	var s Shipment
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE id=$1 AND order_id=$2`, shipmentColumns, TableName)
+	if err := r.db.GetContext(ctx, &s, query, id, orderID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrorNotFound
+		}
+		return nil, err
+	}
+	one := []Shipment{s}
+	if err := r.customsItems(ctx, one); err != nil {
+		return nil, err
+	}
+	return &one[0], nil
+}
+
+func (r *repository) ListByOrder(ctx context.Context, orderID uuid.UUID) ([]Shipment, error) {
+	out := []Shipment{}
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE order_id=$1 ORDER BY created_at`, shipmentColumns, TableName)
+	if err := r.db.SelectContext(ctx, &out, query, orderID); err != nil {
+		return nil, err
+	}
+	return out, r.customsItems(ctx, out)
+}
+
+// SaveLabel stores the purchased label details, guarded by the shipment version
+func (r *repository) SaveLabel(ctx context.Context, s *Shipment) error {
+	now := time.Now().UTC()
+	query := fmt.Sprintf(`UPDATE %s SET status=$1, tracking_number=$2, carrier_shipment_id=$3, label_key=$4, label_format=$5, label_cost=$6, label_currency=$7, label_purchased_at=$8, updated_at=$8, tracking_url=$11, version=version+1
+		WHERE id=$9 AND version=$10`, TableName)
+	res, err := r.db.ExecContext(ctx, query, s.Status, s.TrackingNumber, s.CarrierShipmentID, s.LabelKey, s.LabelFormat, s.LabelCost, s.LabelCurrency, now, s.ID, s.Version, s.TrackingURL)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrorVersionConflict
+	}
+	s.LabelPurchasedAt = &now
+	s.UpdatedAt = now
+	s.Version++
+	return nil
+}
+
+func (r *repository) UpdateStatus(ctx context.Context, s *Shipment) error {
+	now := time.Now().UTC()
+	query := fmt.Sprintf(`UPDATE %s SET status=$1, updated_at=$2, version=version+1 WHERE id=$3 AND version=$4`, TableName)
+	res, err := r.db.ExecContext(ctx, query, s.Status, now, s.ID, s.Version)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrorVersionConflict
+	}
+	s.UpdatedAt = now
+	s.Version++
+	return nil
+}
+
+// SaveTracking stores the carrier's progress and the status it leads to,
+// guarded by the shipment version
+func (r *repository) SaveTracking(ctx context.Context, s *Shipment) error {
+	now := time.Now().UTC()
+	query := fmt.Sprintf(`UPDATE %s SET status=$1, tracking_url=$2, tracking_status=$3, tracking_detail=$4, tracked_at=$5, delivered_at=$6, updated_at=$5, version=version+1
+		WHERE id=$7 AND version=$8`, TableName)
+	res, err := r.db.ExecContext(ctx, query, s.Status, s.TrackingURL, s.TrackingStatus, s.TrackingDetail, now, s.DeliveredAt, s.ID, s.Version)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrorVersionConflict
+	}
+	s.TrackedAt = &now
+	s.UpdatedAt = now
+	s.Version++
+	return nil
+}
+
+func (r *repository) GetByTracking(ctx context.Context, carrier, trackingNumber string) (*Shipment, error) {
+	var s Shipment
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE carrier=$1 AND tracking_number=$2 ORDER BY created_at DESC LIMIT 1`, shipmentColumns, TableName)
+	if err := r.db.GetContext(ctx, &s, query, carrier, trackingNumber); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrorNotFound
+		}
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *repository) InTransit(ctx context.Context, carriers []string, before, labelledAfter time.Time, limit int) ([]Shipment, error) {
+	out := []Shipment{}
+	query, args, err := sqlx.In(fmt.Sprintf(`SELECT %s FROM %s
+		WHERE carrier IN (?) AND status IN (?, ?) AND tracking_number IS NOT NULL
+		AND (tracked_at IS NULL OR tracked_at < ?) AND label_purchased_at > ?
+		ORDER BY tracked_at NULLS FIRST LIMIT ?`, shipmentColumns, TableName), carriers, StatusLabelPurchased, StatusShipped, before, labelledAfter, limit)
+	if err != nil {
+		return nil, err
+	}
+	err = r.db.SelectContext(ctx, &out, r.db.Rebind(query), args...)
+	return out, err
+}
+
+func (r *repository) OrderExists(ctx context.Context, orderID uuid.UUID) (bool, error) {
+	var exists bool
+	err := r.db.GetContext(ctx, &exists, `SELECT EXISTS (SELECT 1 FROM orders WHERE id=$1)`, orderID)
+	return exists, err
+}
+
+func (r *repository) OrderCustomsItems(ctx context.Context, orderID uuid.UUID) ([]CustomsItem, error) {
+	items := []CustomsItem{}
+	err := r.db.SelectContext(ctx, &items, `SELECT ROW_NUMBER() OVER (ORDER BY oi.id)::int AS line, oi.product_id, oi.sku,
+			COALESCE(oi.name, oi.sku, 'item') AS description, oi.quantity, oi.unit_price AS unit_value, o.currency,
+			p.hs_code, p.country_of_origin
+		FROM order_items oi JOIN orders o ON o.id = oi.order_id LEFT JOIN products p ON p.id = oi.product_id
+		WHERE oi.order_id=$1 AND COALESCE(p.product_type, 'PHYSICAL') = 'PHYSICAL'
+		ORDER BY oi.id`, orderID)
+	return items, err
+}
@@ -0,0 +1,217 @@
+package Shipping
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// BlobStore keeps purchased label documents
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader, contentType string) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+}
+
+// ShipmentListener is told when an order's parcel leaves the warehouse
+type ShipmentListener interface {
+	OrderShipped(ctx context.Context, orderID uuid.UUID) error
+}
+
+type Service interface {
+	CreateShipment(ctx context.Context, orderID uuid.UUID, req CreateShipmentRequest) (*Shipment, error)
+	ListShipments(ctx context.Context, orderID uuid.UUID) ([]Shipment, error)
+	PurchaseLabel(ctx context.Context, orderID, id uuid.UUID) (*Shipment, error)
+	OpenLabel(ctx context.Context, orderID, id uuid.UUID) (io.ReadCloser, string, error)
+	MarkShipped(ctx context.Context, orderID, id uuid.UUID) (*Shipment, error)
+	CommercialInvoice(ctx context.Context, orderID, id uuid.UUID) (*CommercialInvoice, error)
+
+	MarkDelivered(ctx context.Context, orderID, id uuid.UUID) (*Shipment, error)
+	RecordTracking(ctx context.Context, carrier, trackingNumber string, t Tracking) (*Shipment, error)
+	PollTracking(ctx context.Context) error
+}
+
+type service struct {
+	repo       Repository
+	carriers   map[string]Carrier
+	blobs      BlobStore
+	listener   ShipmentListener
+	deliveries DeliveryListener
+	from       Address
+	log        *zap.Logger
+}
+
+// NewService ships from the from address; its country decides which
+// shipments are international
+func NewService(r Repository, carriers []Carrier, blobs BlobStore, listener ShipmentListener, deliveries DeliveryListener, from Address, log *zap.Logger) Service {
+	byName := make(map[string]Carrier, len(carriers))
+	for _, c := range carriers {
+		byName[c.Name()] = c
+	}
+	return &service{repo: r, carriers: byName, blobs: blobs, listener: listener, deliveries: deliveries, from: from, log: log}
+}
+
+func (s *service) CreateShipment(ctx context.Context, orderID uuid.UUID, req CreateShipmentRequest) (*Shipment, error) {
+	if _, ok := s.carriers[req.Carrier]; !ok {
+		return nil, ErrorUnknownCarrier
+	}
+	exists, err := s.repo.OrderExists(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, sql.ErrNoRows
+	}
+	now := time.Now().UTC()
+	sh := &Shipment{
+		ID:           uuid.New(),
+		OrderID:      orderID,
+		Carrier:      req.Carrier,
+		ServiceLevel: req.ServiceLevel,
+		Status:       StatusPending,
+		Address:      req.Address,
+		WeightGrams:  req.WeightGrams,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+		Version:      1,
+	}
+	sh.Country = strings.ToUpper(sh.Country)
+	if origin := strings.ToUpper(s.from.Country); origin != "" {
+		sh.OriginCountry = &origin
+	}
+	if sh.CustomsItems, err = s.repo.OrderCustomsItems(ctx, orderID); err != nil {
+		return nil, err
+	}
+	if sh.International() {
+		if err := checkCustoms(sh.CustomsItems); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.repo.Create(ctx, sh); err != nil {
+		return nil, err
+	}
+	return sh, nil
+}
+
+func (s *service) ListShipments(ctx context.Context, orderID uuid.UUID) ([]Shipment, error) {
+	return s.repo.ListByOrder(ctx, orderID)
+}
+
+// PurchaseLabel buys the label from the shipment's carrier and stores the
+// document so it can be reprinted without going back to the carrier.
+func (s *service) PurchaseLabel(ctx context.Context, orderID, id uuid.UUID) (*Shipment, error) {
+	sh, err := s.repo.Get(ctx, orderID, id)
+	if err != nil {
+		return nil, err
+	}
+	if sh.LabelKey != nil {
+		return nil, ErrorLabelExists
+	}
+	carrier, ok := s.carriers[sh.Carrier]
+	if !ok {
+		return nil, ErrorUnknownCarrier
+	}
+	label, err := carrier.PurchaseLabel(ctx, sh)
+	if err != nil {
+		return nil, fmt.Errorf("purchase label from %s: %w", carrier.Name(), err)
+	}
+
+	key := fmt.Sprintf("labels/%s/%s.%s", sh.OrderID, sh.ID, strings.ToLower(label.Format))
+	if err := s.blobs.Put(ctx, key, bytes.NewReader(label.Document), labelContentType(label.Format)); err != nil {
+		// the label is paid for at this point, keep enough in the log to recover it
+		s.log.Error("store shipping label", zap.String("shipment_id", sh.ID.String()), zap.String("tracking_number", label.TrackingNumber), zap.Error(err))
+		return nil, err
+	}
+
+	sh.Status = StatusLabelPurchased
+	sh.TrackingNumber = &label.TrackingNumber
+	sh.CarrierShipmentID = &label.CarrierShipmentID
+	sh.LabelKey = &key
+	sh.LabelFormat = &label.Format
+	sh.LabelCost = &label.Cost
+	if label.Currency != "" {
+		sh.LabelCurrency = &label.Currency
+	}
+	if label.TrackingURL != "" {
+		sh.TrackingURL = &label.TrackingURL
+	}
+	if err := s.repo.SaveLabel(ctx, sh); err != nil {
+		s.log.Error("save shipping label", zap.String("shipment_id", sh.ID.String()), zap.String("tracking_number", label.TrackingNumber), zap.Error(err))
+		return nil, err
+	}
+	return sh, nil
+}
+
+func (s *service) OpenLabel(ctx context.Context, orderID, id uuid.UUID) (io.ReadCloser, string, error) {
+	sh, err := s.repo.Get(ctx, orderID, id)
+	if err != nil {
+		return nil, "", err
+	}
+	if sh.LabelKey == nil {
+		return nil, "", ErrorNoLabel
+	}
+	rc, err := s.blobs.Open(ctx, *sh.LabelKey)
+	if err != nil {
+		return nil, "", err
+	}
+	return rc, labelContentType(deref(sh.LabelFormat)), nil
+}
+
+// MarkShipped records the hand-over to the carrier; the order's payment is
+// captured at this point.
+func (s *service) MarkShipped(ctx context.Context, orderID, id uuid.UUID) (*Shipment, error) {
+	sh, err := s.repo.Get(ctx, orderID, id)
+	if err != nil {
+		return nil, err
+	}
+	if sh.Status != StatusLabelPurchased {
+		return nil, ErrorNoLabel
+	}
+	sh.Status = StatusShipped
+	if err := s.repo.UpdateStatus(ctx, sh); err != nil {
+		return nil, err
+	}
+	s.shipped(ctx, orderID)
+	return sh, nil
+}
+
+func (s *service) shipped(ctx context.Context, orderID uuid.UUID) {
+	if s.listener != nil {
+		if err := s.listener.OrderShipped(ctx, orderID); err != nil {
+			s.log.Error("shipment listener", zap.String("order_id", orderID.String()), zap.Error(err))
+		}
+	}
+}
+
+// checkCustoms names the items customs would refuse for lack of an HS code
+// or country of origin
+func checkCustoms(items []CustomsItem) error {
+	var missing []string
+	for _, it := range items {
+		if it.HSCode == nil || it.CountryOfOrigin == nil {
+			missing = append(missing, it.Description)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: missing on %s", ErrorMissingCustoms, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func labelContentType(format string) string {
+	switch strings.ToUpper(format) {
+	case "PDF":
+		return "application/pdf"
+	case "PNG":
+		return "image/png"
+	case "ZPL":
+		return "application/x-zpl"
+	}
+	return "application/octet-stream"
+}
@@ -0,0 +1,160 @@
+package Shipping
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// tracking statuses, as carriers' own are mapped to them
+const (
+	TrackingPreTransit     = "PRE_TRANSIT"
+	TrackingInTransit      = "IN_TRANSIT"
+	TrackingOutForDelivery = "OUT_FOR_DELIVERY"
+	TrackingPickup         = "AVAILABLE_FOR_PICKUP"
+	TrackingDelivered      = "DELIVERED"
+	TrackingException      = "EXCEPTION" // returned, lost or undeliverable
+	TrackingUnknown        = "UNKNOWN"
+)
+
+// Tracking is a parcel's progress as a carrier reports it
+type Tracking struct {
+	Status string
+	Detail string
+	URL    string    // public tracking page, when the carrier has one
+	At     time.Time // when the carrier recorded Status
+}
+
+// ShipmentTracker is implemented by carriers that can report where a parcel
+// is; the others are delivered by hand with MarkDelivered
+type ShipmentTracker interface {
+	Track(ctx context.Context, s *Shipment) (*Tracking, error)
+}
+
+// DeliveryListener is told when every shipment of an order is delivered
+type DeliveryListener interface {
+	OrderDelivered(ctx context.Context, orderID uuid.UUID) error
+}
+
+const (
+	// trackEvery is how often a parcel in transit is polled; carriers that
+	// push updates to the tracking webhook keep it fresher
+	trackEvery = 2 * time.Hour
+	// trackFor is how long after its label a parcel is polled at all
+	trackFor   = 60 * 24 * time.Hour
+	trackBatch = 100
+)
+
+// PollTracking asks the carriers for the progress of parcels in transit
+// that were not updated within trackEvery
+func (s *service) PollTracking(ctx context.Context) error {
+	var carriers []string
+	for name, c := range s.carriers {
+		if _, ok := c.(ShipmentTracker); ok {
+			carriers = append(carriers, name)
+		}
+	}
+	if len(carriers) == 0 {
+		return nil
+	}
+	now := time.Now().UTC()
+	due, err := s.repo.InTransit(ctx, carriers, now.Add(-trackEvery), now.Add(-trackFor), trackBatch)
+	if err != nil {
+		return err
+	}
+	for i := range due {
+		sh := &due[i]
+		t, err := s.carriers[sh.Carrier].(ShipmentTracker).Track(ctx, sh)
+		if err != nil {
+			s.log.Warn("track shipment", zap.String("shipment_id", sh.ID.String()), zap.String("carrier", sh.Carrier), zap.Error(err))
+			continue
+		}
+		if err := s.applyTracking(ctx, sh, *t); err != nil {
+			s.log.Error("save shipment tracking", zap.String("shipment_id", sh.ID.String()), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// RecordTracking applies an update a carrier pushed for one of its parcels
+func (s *service) RecordTracking(ctx context.Context, carrier, trackingNumber string, t Tracking) (*Shipment, error) {
+	sh, err := s.repo.GetByTracking(ctx, carrier, trackingNumber)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyTracking(ctx, sh, t); err != nil {
+		return nil, err
+	}
+	return sh, nil
+}
+
+// MarkDelivered records the delivery of a parcel its carrier doesn't track,
+// such as one of the own fleet
+func (s *service) MarkDelivered(ctx context.Context, orderID, id uuid.UUID) (*Shipment, error) {
+	sh, err := s.repo.Get(ctx, orderID, id)
+	if err != nil {
+		return nil, err
+	}
+	if sh.Status == StatusDelivered {
+		return sh, nil
+	}
+	if err := s.applyTracking(ctx, sh, Tracking{Status: TrackingDelivered, At: time.Now().UTC()}); err != nil {
+		return nil, err
+	}
+	return sh, nil
+}
+
+// applyTracking stores the carrier's progress on the shipment. The first
+// delivery marks the shipment DELIVERED, capturing the payment first if it
+// was never marked shipped, and once the order's other shipments are
+// delivered too the order is told.
+func (s *service) applyTracking(ctx context.Context, sh *Shipment, t Tracking) error {
+	if sh.Status != StatusLabelPurchased && sh.Status != StatusShipped && sh.Status != StatusDelivered {
+		return ErrorNoLabel
+	}
+	delivered := t.Status == TrackingDelivered && sh.Status != StatusDelivered
+	wasShipped := sh.Status != StatusLabelPurchased
+	if t.At.IsZero() {
+		t.At = time.Now().UTC()
+	}
+	sh.TrackingStatus = &t.Status
+	sh.TrackingDetail = nil
+	if t.Detail != "" {
+		sh.TrackingDetail = &t.Detail
+	}
+	if t.URL != "" {
+		sh.TrackingURL = &t.URL
+	}
+	if delivered {
+		sh.Status = StatusDelivered
+		sh.DeliveredAt = &t.At
+	}
+	if err := s.repo.SaveTracking(ctx, sh); err != nil {
+		return err
+	}
+	if !delivered {
+		return nil
+	}
+	if !wasShipped {
+		s.shipped(ctx, sh.OrderID)
+	}
+	if s.deliveries == nil {
+		return nil
+	}
+	shipments, err := s.repo.ListByOrder(ctx, sh.OrderID)
+	if err != nil {
+		s.log.Error("check order delivery", zap.String("order_id", sh.OrderID.String()), zap.Error(err))
+		return nil
+	}
+	for _, other := range shipments {
+		if other.Status != StatusDelivered && other.Status != StatusCancelled {
+			return nil
+		}
+	}
+	if err := s.deliveries.OrderDelivered(ctx, sh.OrderID); err != nil {
+		s.log.Error("delivery listener", zap.String("order_id", sh.OrderID.String()), zap.Error(err))
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package Storage
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrorCiphertext = errors.New("malformed ciphertext")
+
+// Keyring encrypts values at rest with envelope encryption: every value gets
+// its own data key, which is wrapped by a named key-encryption key. Values
+// stay readable with any key still in the ring, so keys are rotated by
+// putting the new key first and re-encrypting rows whose key id differs from
+// ActiveKeyID. The index key is separate and never rotates, because blind
+// indexes of existing rows would stop matching.
+type Keyring struct {
+	active   string
+	keys     map[string]cipher.AEAD
+	indexKey []byte
+}
+
+// ParseKeyring reads keys as "id:base64key,id:base64key" with the active key
+// first; each key and the index key are 32 bytes, base64 encoded.
+func ParseKeyring(keys, indexKey string) (*Keyring, error) {
+	k := &Keyring{keys: map[string]cipher.AEAD{}}
+	for _, entry := range strings.Split(keys, ",") {
+		id, encoded, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || id == "" || strings.Contains(id, ".") {
+			return nil, fmt.Errorf("keyring: want id:base64key, got %q", entry)
+		}
+		aead, err := newAEAD(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("keyring: key %s: %w", id, err)
+		}
+		if k.active == "" {
+			k.active = id
+		}
+		k.keys[id] = aead
+	}
+	idx, err := base64.StdEncoding.DecodeString(indexKey)
+	if err != nil || len(idx) < 32 {
+		return nil, errors.New("keyring: index key must be at least 32 bytes, base64 encoded")
+	}
+	k.indexKey = idx
+	return k, nil
+}
+
+func newAEAD(encoded string) (cipher.AEAD, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("want 32 bytes, got %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// ActiveKeyID is the key new values are encrypted with
+func (k *Keyring) ActiveKeyID() string { return k.active }
+
+// Encrypt returns "keyID.wrappedDataKey.ciphertext", base64 encoded parts
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", err
+	}
+	wrapped, err := seal(k.keys[k.active], dataKey)
+	if err != nil {
+		return "", err
+	}
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		return "", err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	ct, err := seal(aead, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+	enc := base64.RawStdEncoding
+	return k.active + "." + enc.EncodeToString(wrapped) + "." + enc.EncodeToString(ct), nil
+}
+
+// Decrypt opens a value produced by Encrypt with any key in the ring
+func (k *Keyring) Decrypt(value string) (string, error) {
+	parts := strings.Split(value, ".")
+	if len(parts) != 3 {
+		return "", ErrorCiphertext
+	}
+	kek, ok := k.keys[parts[0]]
+	if !ok {
+		return "", fmt.Errorf("keyring: unknown key %s", parts[0])
+	}
+	enc := base64.RawStdEncoding
+	wrapped, err := enc.DecodeString(parts[1])
+	if err != nil {
+		return "", ErrorCiphertext
+	}
+	ct, err := enc.DecodeString(parts[2])
+	if err != nil {
+		return "", ErrorCiphertext
+	}
+	dataKey, err := open(kek, wrapped)
+	if err != nil {
+		return "", err
+	}
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		return "", err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := open(aead, ct)
+	return string(plaintext), err
+}
+
+// KeyID returns the id of the key that encrypted value
+func KeyID(value string) string {
+	id, _, _ := strings.Cut(value, ".")
+	return id
+}
+
+// BlindIndex is a keyed hash of value for equality lookups on encrypted
+// columns; callers normalise value first (e.g. lower-case emails).
+func (k *Keyring) BlindIndex(value string) string {
+	mac := hmac.New(sha256.New, k.indexKey)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// seal prefixes the ciphertext with its random nonce
+func seal(aead cipher.AEAD, plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func open(aead cipher.AEAD, sealed []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrorCiphertext
+	}
+	nonce, ct := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ct, nil)
+}
@@ -1,7 +1,9 @@
 package Storage
 
 import (
-	
+	"fmt"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/jmoiron/sqlx"
@@ -23,3 +25,21 @@ func NewPostgres(dsn string) (*sqlx.DB, error) {
 	}
 	return db, nil
 }
+
+// WithStatementTimeout sets the server-side statement_timeout of every
+// connection opened with dsn (a URL or key=value list), so no statement runs
+// longer than d whoever issued it.
+func WithStatementTimeout(dsn string, d time.Duration) (string, error) {
+	ms := fmt.Sprint(d.Milliseconds())
+	if !strings.Contains(dsn, "://") {
+		return dsn + " statement_timeout=" + ms, nil
+	}
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	q.Set("statement_timeout", ms)
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
@@ -0,0 +1,62 @@
+package Sync
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+	"savannah/src/ErrorCodes"
+)
+
+type Handler struct {
+	svc Service
+	log *zap.Logger
+}
+
+func NewHandler(s Service, log *zap.Logger) *Handler {
+	return &Handler{svc: s, log: log}
+}
+
+// Changes godoc
+// @Summary      Incremental sync feed
+// @Description  Returns products or orders (with items) changed since the change token, oldest first. Omit token for a full sync, then pass next_token from each response; keep paging while has_more is true. Changes from the last few seconds are held back until they settle.
+// @Tags         sync
+// @Produce      json
+// @Param        resource  path      string  true   "products or orders"
+// @Param        token     query     string  false  "next_token from the previous page"
+// @Param        limit     query     int     false  "page size, default 100, max 500"
+// @Success      200       {object}  Page
+// @Failure      400       {object}  map[string]interface{}
+// @Failure      404       {object}  map[string]interface{}
+// @Router       /sync/{resource} [get]
+func (h *Handler) Changes(w http.ResponseWriter, r *http.Request) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	page, err := h.svc.Changes(r.Context(), chi.URLParam(r, "resource"), r.URL.Query().Get("token"), limit)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrorUnknownResource):
+			h.writeError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, ErrorInvalidToken):
+			h.writeError(w, http.StatusBadRequest, err.Error())
+		default:
+			h.log.Error("sync changes", zap.String("resource", chi.URLParam(r, "resource")), zap.Error(err))
+			h.writeError(w, http.StatusInternalServerError, "failed to list changes")
+		}
+		return
+	}
+	h.writeJSON(w, http.StatusOK, page)
+}
+
+func (h *Handler) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (h *Handler) writeError(w http.ResponseWriter, status int, msg string) {
+	h.writeJSON(w, status, map[string]interface{}{"error": msg, "code": ErrorCodes.Resolve("SYNC", status, msg), "timestamp": time.Now().UTC()})
+}
@@ -0,0 +1,15 @@
+package Sync
+
+// resources with a change feed
+const (
+	ResourceProducts = "products"
+	ResourceOrders   = "orders"
+)
+
+// Page is one page of changes. Clients store NextToken and send it with the
+// next request; HasMore tells them to ask again right away.
+type Page struct {
+	Items     []interface{} `json:"items"`
+	NextToken string        `json:"next_token"`
+	HasMore   bool          `json:"has_more"`
+}
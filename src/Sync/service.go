@@ -0,0 +1,77 @@
+package Sync
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+	"savannah/src/ErrorCodes"
+)
+
+var ErrorUnknownResource = errors.New("unknown sync resource")
+
+func init() {
+	ErrorCodes.Register("SYNC",
+		ErrorCodes.Def{N: 1, Slug: "UNKNOWN_RESOURCE", Err: ErrorUnknownResource},
+		ErrorCodes.Def{N: 2, Slug: "INVALID_TOKEN", Err: ErrorInvalidToken},
+	)
+}
+
+// settle keeps the newest changes out of a page: a transaction stamps
+// updated_at when it starts but becomes visible when it commits, so a row
+// stamped just before the watermark could otherwise appear after a client
+// has moved past it.
+const settle = 5 * time.Second
+
+const (
+	defaultPageSize = 100
+	maxPageSize     = 500
+)
+
+type Service interface {
+	Changes(ctx context.Context, resource, token string, limit int) (*Page, error)
+}
+
+type service struct {
+	sources map[string]Source
+	log     *zap.Logger
+}
+
+func NewService(sources map[string]Source, log *zap.Logger) Service {
+	return &service{sources: sources, log: log}
+}
+
+// Changes returns up to limit rows of resource changed since token, oldest
+// first. A row changed several times appears once, at its latest version.
+func (s *service) Changes(ctx context.Context, resource, token string, limit int) (*Page, error) {
+	src, ok := s.sources[resource]
+	if !ok {
+		return nil, ErrorUnknownResource
+	}
+	after, err := ParseToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+	changes, err := src.Changes(ctx, after, time.Now().UTC().Add(-settle), limit+1)
+	if err != nil {
+		return nil, err
+	}
+	page := &Page{Items: []interface{}{}, NextToken: token}
+	if len(changes) > limit {
+		changes, page.HasMore = changes[:limit], true
+	}
+	for _, c := range changes {
+		page.Items = append(page.Items, c.item)
+	}
+	if len(changes) > 0 {
+		page.NextToken = changes[len(changes)-1].token.String()
+	}
+	return page, nil
+}
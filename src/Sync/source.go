@@ -0,0 +1,56 @@
+package Sync
+
+import (
+	"context"
+	"time"
+
+	"savannah/src/Catalog"
+	"savannah/src/Orders"
+)
+
+// change is one changed row with its watermark
+type change struct {
+	token Token
+	item  interface{}
+}
+
+// Source lists changes after a watermark and before until, in watermark order
+type Source interface {
+	Changes(ctx context.Context, after Token, until time.Time, limit int) ([]change, error)
+}
+
+// NewSources wires the product and order change feeds
+func NewSources(products Catalog.Service, orders Orders.Service) map[string]Source {
+	return map[string]Source{
+		ResourceProducts: productSource{products},
+		ResourceOrders:   orderSource{orders},
+	}
+}
+
+type productSource struct{ svc Catalog.Service }
+
+func (s productSource) Changes(ctx context.Context, after Token, until time.Time, limit int) ([]change, error) {
+	products, err := s.svc.ProductChanges(ctx, after.UpdatedAt, after.ID, until, limit)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]change, len(products))
+	for i, p := range products {
+		out[i] = change{Token{p.UpdatedAt, p.ID}, p}
+	}
+	return out, nil
+}
+
+type orderSource struct{ svc Orders.Service }
+
+func (s orderSource) Changes(ctx context.Context, after Token, until time.Time, limit int) ([]change, error) {
+	orders, err := s.svc.Changes(ctx, after.UpdatedAt, after.ID, until, limit)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]change, len(orders))
+	for i, o := range orders {
+		out[i] = change{Token{o.UpdatedAt, o.ID}, o}
+	}
+	return out, nil
+}
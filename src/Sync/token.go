@@ -0,0 +1,52 @@
+package Sync
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrorInvalidToken = errors.New("invalid change token")
+
+// Token is the watermark of the last change a client has seen: rows are
+// ordered by (updated_at, id), so the next page starts strictly after it.
+// Clients treat the encoded token as opaque.
+type Token struct {
+	UpdatedAt time.Time
+	ID        uuid.UUID
+}
+
+const tokenVersion = "1"
+
+func (t Token) String() string {
+	raw := tokenVersion + "|" + strconv.FormatInt(t.UpdatedAt.UnixMicro(), 10) + "|" + t.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseToken decodes a token; the empty token starts from the beginning
+func ParseToken(s string) (Token, error) {
+	if s == "" {
+		return Token{}, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Token{}, ErrorInvalidToken
+	}
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != 3 || parts[0] != tokenVersion {
+		return Token{}, ErrorInvalidToken
+	}
+	micros, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return Token{}, ErrorInvalidToken
+	}
+	id, err := uuid.Parse(parts[2])
+	if err != nil {
+		return Token{}, ErrorInvalidToken
+	}
+	return Token{UpdatedAt: time.UnixMicro(micros).UTC(), ID: id}, nil
+}
@@ -0,0 +1,21 @@
+package Usage
+
+import (
+	"errors"
+
+	"savannah/src/ErrorCodes"
+)
+
+var (
+	ErrorInvalidBucket = errors.New("bucket must be minute, hour or day")
+	ErrorInvalidRange  = errors.New("from must be before to")
+	ErrorRangeTooLarge = errors.New("range too large for the bucket size")
+)
+
+func init() {
+	ErrorCodes.Register("USAGE",
+		ErrorCodes.Def{N: 1, Slug: "INVALID_BUCKET", Err: ErrorInvalidBucket},
+		ErrorCodes.Def{N: 2, Slug: "INVALID_RANGE", Err: ErrorInvalidRange},
+		ErrorCodes.Def{N: 3, Slug: "RANGE_TOO_LARGE", Err: ErrorRangeTooLarge},
+	)
+}
@@ -0,0 +1,84 @@
+package Usage
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+	"savannah/src/ErrorCodes"
+)
+
+type Handler struct {
+	svc Service
+	log *zap.Logger
+}
+
+func NewHandler(s Service, log *zap.Logger) *Handler {
+	return &Handler{svc: s, log: log}
+}
+
+// Stats godoc
+// @Summary      API usage per client
+// @Description  Request counts, error rates and latency per API client and time bucket for [from, to), by default the last 24 hours in hourly buckets. Clients are identified by a fingerprint of their API key (key:...), else the X-Client-ID they send (client:...), else their IP (ip:...). Figures lag by up to a minute.
+// @Tags         admin
+// @Produce      json
+// @Param        from       query     string  false  "RFC3339 start"
+// @Param        to         query     string  false  "RFC3339 end, exclusive"
+// @Param        bucket     query     string  false  "minute, hour (default) or day"
+// @Param        client_id  query     string  false  "Only this client"
+// @Param        limit      query     int     false  "Page size (max 1000)"
+// @Param        offset     query     int     false  "Offset"
+// @Success      200        {array}   Bucket
+// @Failure      400        {object}  map[string]interface{}
+// @Router       /admin/api-usage [get]
+func (h *Handler) Stats(w http.ResponseWriter, r *http.Request) {
+	v := r.URL.Query()
+	q := Query{To: time.Now().UTC(), Bucket: BucketHour, ClientID: v.Get("client_id")}
+	q.From = q.To.Add(-24 * time.Hour)
+	var err error
+	if s := v.Get("from"); s != "" {
+		if q.From, err = time.Parse(time.RFC3339, s); err != nil {
+			h.writeError(w, http.StatusBadRequest, "invalid from, expected RFC3339")
+			return
+		}
+	}
+	if s := v.Get("to"); s != "" {
+		if q.To, err = time.Parse(time.RFC3339, s); err != nil {
+			h.writeError(w, http.StatusBadRequest, "invalid to, expected RFC3339")
+			return
+		}
+	}
+	if s := v.Get("bucket"); s != "" {
+		q.Bucket = s
+	}
+	q.Limit, _ = strconv.Atoi(v.Get("limit"))
+	q.Offset, _ = strconv.Atoi(v.Get("offset"))
+	if q.Offset < 0 {
+		q.Offset = 0
+	}
+	stats, err := h.svc.Stats(r.Context(), q)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrorInvalidBucket), errors.Is(err, ErrorInvalidRange), errors.Is(err, ErrorRangeTooLarge):
+			h.writeError(w, http.StatusBadRequest, err.Error())
+		default:
+			h.log.Error("api usage", zap.Error(err))
+			h.writeError(w, http.StatusInternalServerError, "failed to get api usage")
+		}
+		return
+	}
+	h.writeJSON(w, http.StatusOK, stats)
+}
+
+func (h *Handler) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (h *Handler) writeError(w http.ResponseWriter, status int, msg string) {
+	h.writeJSON(w, status, map[string]interface{}{"error": msg, "code": ErrorCodes.Resolve("USAGE", status, msg), "timestamp": time.Now().UTC()})
+}
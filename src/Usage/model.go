@@ -0,0 +1,34 @@
+package Usage
+
+import "time"
+
+// Bucket is the traffic of one client over one time bucket. Client errors
+// are 4xx responses (including 401 and 429), server errors 5xx.
+type Bucket struct {
+	Start          time.Time `db:"bucket" json:"bucket"`
+	ClientID       string    `db:"client_id" json:"client_id"`
+	Requests       int64     `db:"requests" json:"requests"`
+	ClientErrors   int64     `db:"client_errors" json:"client_errors"`
+	ServerErrors   int64     `db:"server_errors" json:"server_errors"`
+	ErrorRate      float64   `db:"-" json:"error_rate"`
+	AvgLatencyMs   float64   `db:"-" json:"avg_latency_ms"`
+	MaxLatencyMs   int64     `db:"max_latency_ms" json:"max_latency_ms"`
+	TotalLatencyMs int64     `db:"total_latency_ms" json:"-"`
+}
+
+// bucket sizes the usage report can group by
+const (
+	BucketMinute = "minute"
+	BucketHour   = "hour"
+	BucketDay    = "day"
+)
+
+// Query selects usage in [From, To), optionally for one client
+type Query struct {
+	From     time.Time
+	To       time.Time
+	Bucket   string
+	ClientID string
+	Limit    int
+	Offset   int
+}
@@ -0,0 +1,67 @@
+package Usage
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+type Repository interface {
+	Add(ctx context.Context, buckets []Bucket) error
+	Stats(ctx context.Context, q Query) ([]Bucket, error)
+	Prune(ctx context.Context, before time.Time) (int64, error)
+}
+
+type repository struct {
+	db  *sqlx.DB
+	log *zap.Logger
+}
+
+func NewRepository(db *sqlx.DB, log *zap.Logger) Repository { return &repository{db: db, log: log} }
+
+// Add merges the buckets into the stored ones, so instances flushing the same
+// minute add up instead of overwriting each other
+func (r *repository) Add(ctx context.Context, buckets []Bucket) (err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	for _, b := range buckets {
+		if _, err = tx.ExecContext(ctx, `INSERT INTO api_usage (bucket,client_id,requests,client_errors,server_errors,total_latency_ms,max_latency_ms)
+			VALUES ($1,$2,$3,$4,$5,$6,$7)
+			ON CONFLICT (bucket, client_id) DO UPDATE SET requests=api_usage.requests+EXCLUDED.requests,
+				client_errors=api_usage.client_errors+EXCLUDED.client_errors, server_errors=api_usage.server_errors+EXCLUDED.server_errors,
+				total_latency_ms=api_usage.total_latency_ms+EXCLUDED.total_latency_ms, max_latency_ms=GREATEST(api_usage.max_latency_ms, EXCLUDED.max_latency_ms)`,
+			b.Start, b.ClientID, b.Requests, b.ClientErrors, b.ServerErrors, b.TotalLatencyMs, b.MaxLatencyMs); err != nil {
+			return err
+		}
+	}
+	err = tx.Commit()
+	return err
+}
+
+// Stats sums the stored minutes into q.Bucket sized UTC buckets, oldest first
+func (r *repository) Stats(ctx context.Context, q Query) ([]Bucket, error) {
+	out := []Bucket{}
+	err := r.db.SelectContext(ctx, &out, `SELECT date_trunc($1, bucket AT TIME ZONE 'UTC') AT TIME ZONE 'UTC' AS bucket, client_id, SUM(requests) AS requests,
+			SUM(client_errors) AS client_errors, SUM(server_errors) AS server_errors,
+			SUM(total_latency_ms) AS total_latency_ms, MAX(max_latency_ms) AS max_latency_ms
+		FROM api_usage WHERE bucket >= $2 AND bucket < $3 AND ($4::text = '' OR client_id = $4)
+		GROUP BY 1, client_id ORDER BY 1, client_id LIMIT $5 OFFSET $6`, q.Bucket, q.From, q.To, q.ClientID, q.Limit, q.Offset)
+	return out, err
+}
+
+func (r *repository) Prune(ctx context.Context, before time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM api_usage WHERE bucket < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,148 @@
+package Usage
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Service counts API traffic per client for partner billing and abuse
+// detection. Requests are counted in memory per minute and written out by
+// Flush, so recording never waits on the database.
+type Service interface {
+	Record(clientID string, status int, elapsed time.Duration)
+	Flush(ctx context.Context) error
+	Stats(ctx context.Context, q Query) ([]Bucket, error)
+	Prune(ctx context.Context) error
+}
+
+// maxBuckets caps how many buckets one report may span per client
+const maxBuckets = 1500
+
+var bucketSizes = map[string]time.Duration{BucketMinute: time.Minute, BucketHour: time.Hour, BucketDay: 24 * time.Hour}
+
+type key struct {
+	minute time.Time
+	client string
+}
+
+type service struct {
+	repo      Repository
+	retention time.Duration
+	log       *zap.Logger
+
+	mu      sync.Mutex
+	pending map[key]*Bucket
+}
+
+// NewService keeps stored usage for retention; zero keeps it forever
+func NewService(r Repository, retention time.Duration, log *zap.Logger) Service {
+	return &service{repo: r, retention: retention, log: log, pending: map[key]*Bucket{}}
+}
+
+func (s *service) Record(clientID string, status int, elapsed time.Duration) {
+	now := time.Now().UTC()
+	k := key{minute: now.Truncate(time.Minute), client: clientID}
+	ms := elapsed.Milliseconds()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.pending[k]
+	if !ok {
+		b = &Bucket{Start: k.minute, ClientID: clientID}
+		s.pending[k] = b
+	}
+	b.Requests++
+	switch {
+	case status >= 500:
+		b.ServerErrors++
+	case status >= 400:
+		b.ClientErrors++
+	}
+	b.TotalLatencyMs += ms
+	if ms > b.MaxLatencyMs {
+		b.MaxLatencyMs = ms
+	}
+}
+
+// Flush writes the counted minutes; on failure they are kept for the next run
+func (s *service) Flush(ctx context.Context) error {
+	s.mu.Lock()
+	pending := s.pending
+	s.pending = map[key]*Bucket{}
+	s.mu.Unlock()
+	if len(pending) == 0 {
+		return nil
+	}
+	buckets := make([]Bucket, 0, len(pending))
+	for _, b := range pending {
+		buckets = append(buckets, *b)
+	}
+	if err := s.repo.Add(ctx, buckets); err != nil {
+		s.mu.Lock()
+		for k, b := range pending {
+			if cur, ok := s.pending[k]; ok {
+				merge(cur, b)
+			} else {
+				s.pending[k] = b
+			}
+		}
+		s.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+func merge(dst, src *Bucket) {
+	dst.Requests += src.Requests
+	dst.ClientErrors += src.ClientErrors
+	dst.ServerErrors += src.ServerErrors
+	dst.TotalLatencyMs += src.TotalLatencyMs
+	dst.MaxLatencyMs = max(dst.MaxLatencyMs, src.MaxLatencyMs)
+}
+
+// Stats reports usage in [q.From, q.To) per client and bucket, with error
+// rates and average latency
+func (s *service) Stats(ctx context.Context, q Query) ([]Bucket, error) {
+	size, ok := bucketSizes[q.Bucket]
+	if !ok {
+		return nil, ErrorInvalidBucket
+	}
+	if !q.From.Before(q.To) {
+		return nil, ErrorInvalidRange
+	}
+	if q.To.Sub(q.From) > maxBuckets*size {
+		return nil, ErrorRangeTooLarge
+	}
+	if q.Limit <= 0 || q.Limit > 1000 {
+		q.Limit = 1000
+	}
+	buckets, err := s.repo.Stats(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	for i := range buckets {
+		b := &buckets[i]
+		if b.Requests > 0 {
+			b.ErrorRate = float64(b.ClientErrors+b.ServerErrors) / float64(b.Requests)
+			b.AvgLatencyMs = float64(b.TotalLatencyMs) / float64(b.Requests)
+		}
+	}
+	return buckets, nil
+}
+
+// Prune deletes usage older than the retention; run daily by the scheduler
+func (s *service) Prune(ctx context.Context) error {
+	if s.retention <= 0 {
+		return nil
+	}
+	n, err := s.repo.Prune(ctx, time.Now().UTC().Add(-s.retention))
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		s.log.Info("pruned api usage", zap.Int64("rows", n))
+	}
+	return nil
+}
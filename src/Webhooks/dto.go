@@ -0,0 +1,22 @@
+package Webhooks
+
+import (
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+type CreateSubscriptionRequest struct {
+	URL       string          `json:"url" validate:"required,max=2000"`
+	Event     string          `json:"event" validate:"required"`
+	Threshold decimal.Decimal `json:"threshold"`
+	ProductID *uuid.UUID      `json:"product_id,omitempty"`
+	Warehouse *string         `json:"warehouse,omitempty" validate:"omitempty,max=50"`
+	Currency  *string         `json:"currency,omitempty" validate:"omitempty,len=3"`
+}
+
+// UpdateSubscriptionRequest changes only the fields that are set
+type UpdateSubscriptionRequest struct {
+	URL       *string          `json:"url,omitempty" validate:"omitempty,max=2000"`
+	Threshold *decimal.Decimal `json:"threshold,omitempty"`
+	Active    *bool            `json:"active,omitempty"`
+}
@@ -0,0 +1,23 @@
+package Webhooks
+
+import (
+	"errors"
+
+	"savannah/src/ErrorCodes"
+)
+
+var (
+	ErrorNotFound         = errors.New("webhook subscription not found")
+	ErrorUnknownEvent     = errors.New("event must be stock.below, order.value_above or refund.above")
+	ErrorInvalidURL       = errors.New("url must be an absolute http or https url")
+	ErrorInvalidThreshold = errors.New("threshold must not be negative")
+)
+
+func init() {
+	ErrorCodes.Register("WEBHOOKS",
+		ErrorCodes.Def{N: 1, Slug: "SUBSCRIPTION_NOT_FOUND", Err: ErrorNotFound},
+		ErrorCodes.Def{N: 2, Slug: "UNKNOWN_EVENT", Err: ErrorUnknownEvent},
+		ErrorCodes.Def{N: 3, Slug: "INVALID_URL", Err: ErrorInvalidURL},
+		ErrorCodes.Def{N: 4, Slug: "INVALID_THRESHOLD", Err: ErrorInvalidThreshold},
+	)
+}
@@ -0,0 +1,207 @@
+package Webhooks
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"savannah/src/ErrorCodes"
+	"savannah/src/Pagination"
+)
+
+type Handler struct {
+	svc Service
+	log *zap.Logger
+	v   *validator.Validate
+}
+
+func NewHandler(s Service, log *zap.Logger) *Handler {
+	return &Handler{svc: s, log: log, v: validator.New()}
+}
+
+// ListSubscriptions godoc
+// @Summary      List webhook subscriptions
+// @Tags         webhooks
+// @Produce      json
+// @Success      200  {array}  Subscription
+// @Router       /webhook-subscriptions [get]
+func (h *Handler) ListSubscriptions(w http.ResponseWriter, r *http.Request) {
+	subs, err := h.svc.ListSubscriptions(r.Context())
+	if err != nil {
+		h.log.Error("list webhook subscriptions", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to list webhook subscriptions")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, subs)
+}
+
+// GetSubscription godoc
+// @Summary      Get a webhook subscription
+// @Tags         webhooks
+// @Produce      json
+// @Param        id   path      string  true  "Subscription ID"
+// @Success      200  {object}  Subscription
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /webhook-subscriptions/{id} [get]
+func (h *Handler) GetSubscription(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.id(w, r)
+	if !ok {
+		return
+	}
+	sub, err := h.svc.GetSubscription(r.Context(), id)
+	if err != nil {
+		h.subscriptionError(w, "get webhook subscription", err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, sub)
+}
+
+// CreateSubscription godoc
+// @Summary      Subscribe to a threshold alert
+// @Description  Posts a signed JSON alert to url when the event crosses threshold: stock.below when a stock item's available quantity drops below it (once per crossing, optionally for one product_id and/or warehouse), order.value_above for every order placed with a total above it, refund.above for every refund above it (both optionally in one currency). Deliveries carry X-Webhook-Event, X-Webhook-Delivery, X-Webhook-Timestamp and X-Webhook-Signature (sha256=hex HMAC-SHA256 of "<timestamp>.<body>" keyed with the secret, which is only returned here) and are retried with backoff until the receiver answers 2xx.
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param        payload  body      CreateSubscriptionRequest  true  "Subscription"
+// @Success      201      {object}  Subscription
+// @Failure      400      {object}  map[string]interface{}
+// @Router       /webhook-subscriptions [post]
+func (h *Handler) CreateSubscription(w http.ResponseWriter, r *http.Request) {
+	var dto CreateSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if err := h.v.Struct(dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	sub, err := h.svc.CreateSubscription(r.Context(), dto)
+	if err != nil {
+		h.subscriptionError(w, "create webhook subscription", err)
+		return
+	}
+	h.writeJSON(w, http.StatusCreated, sub)
+}
+
+// UpdateSubscription godoc
+// @Summary      Update a webhook subscription
+// @Description  Changes the url, threshold or active flag; fields left out are kept
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                     true  "Subscription ID"
+// @Param        payload  body      UpdateSubscriptionRequest  true  "Changes"
+// @Success      200      {object}  Subscription
+// @Failure      400      {object}  map[string]interface{}
+// @Failure      404      {object}  map[string]interface{}
+// @Router       /webhook-subscriptions/{id} [patch]
+func (h *Handler) UpdateSubscription(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.id(w, r)
+	if !ok {
+		return
+	}
+	var dto UpdateSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if err := h.v.Struct(dto); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	sub, err := h.svc.UpdateSubscription(r.Context(), id, dto)
+	if err != nil {
+		h.subscriptionError(w, "update webhook subscription", err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, sub)
+}
+
+// DeleteSubscription godoc
+// @Summary      Delete a webhook subscription
+// @Description  Deletes the subscription with its delivery history
+// @Tags         webhooks
+// @Param        id   path  string  true  "Subscription ID"
+// @Success      204
+// @Failure      404  {object}  map[string]interface{}
+// @Router       /webhook-subscriptions/{id} [delete]
+func (h *Handler) DeleteSubscription(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.id(w, r)
+	if !ok {
+		return
+	}
+	if err := h.svc.DeleteSubscription(r.Context(), id); err != nil {
+		h.subscriptionError(w, "delete webhook subscription", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListDeliveries godoc
+// @Summary      List a subscription's deliveries
+// @Description  Alerts sent or queued for the subscription, newest first, with their status (PENDING, DELIVERED, FAILED), attempts and the receiver's last response
+// @Tags         webhooks
+// @Produce      json
+// @Param        id      path      string  true   "Subscription ID"
+// @Param        limit   query     int     false  "Page size (max 200)"
+// @Param        offset  query     int     false  "Offset"
+// @Param        page    query     int     false  "Page of limit deliveries, 1-based; instead of offset"
+// @Success      200     {array}   Delivery
+// @Header       200     {string}  Link    "first, prev, next and last pages (RFC 5988), with X-Total-Count, X-Page and X-Total-Pages"
+// @Failure      404     {object}  map[string]interface{}
+// @Router       /webhook-subscriptions/{id}/deliveries [get]
+func (h *Handler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.id(w, r)
+	if !ok {
+		return
+	}
+	limit, offset := Pagination.Params(r.URL.Query(), 50, 200)
+	deliveries, err := h.svc.ListDeliveries(r.Context(), id, limit, offset)
+	var total int
+	if err == nil {
+		total, err = h.svc.CountDeliveries(r.Context(), id)
+	}
+	if err != nil {
+		h.subscriptionError(w, "list webhook deliveries", err)
+		return
+	}
+	Pagination.Write(w, r, limit, offset, total)
+	h.writeJSON(w, http.StatusOK, deliveries)
+}
+
+func (h *Handler) id(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func (h *Handler) subscriptionError(w http.ResponseWriter, op string, err error) {
+	switch {
+	case errors.Is(err, ErrorNotFound):
+		h.writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrorUnknownEvent), errors.Is(err, ErrorInvalidURL), errors.Is(err, ErrorInvalidThreshold):
+		h.writeError(w, http.StatusBadRequest, err.Error())
+	default:
+		h.log.Error(op, zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to "+op)
+	}
+}
+
+func (h *Handler) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (h *Handler) writeError(w http.ResponseWriter, status int, msg string) {
+	h.writeJSON(w, status, map[string]interface{}{"error": msg, "code": ErrorCodes.Resolve("WEBHOOKS", status, msg), "timestamp": time.Now().UTC()})
+}
@@ -0,0 +1,77 @@
+package Webhooks
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx/types"
+	"github.com/shopspring/decimal"
+)
+
+// events a subscription can be triggered by
+const (
+	// EventStockBelow fires when a stock item's available quantity drops
+	// below the threshold; it fires again only after stock recovers.
+	EventStockBelow = "stock.below"
+	// EventOrderValueAbove fires for each placed order whose total exceeds the threshold
+	EventOrderValueAbove = "order.value_above"
+	// EventRefundAbove fires for each refund whose amount exceeds the threshold
+	EventRefundAbove = "refund.above"
+)
+
+var events = map[string]bool{EventStockBelow: true, EventOrderValueAbove: true, EventRefundAbove: true}
+
+// delivery statuses
+const (
+	StatusPending   = "PENDING"
+	StatusDelivered = "DELIVERED"
+	StatusFailed    = "FAILED"
+)
+
+// Subscription posts an alert to URL whenever its event crosses Threshold.
+// ProductID and Warehouse narrow stock alerts, Currency narrows order and
+// refund alerts. The secret is only shown when the subscription is created.
+type Subscription struct {
+	ID        uuid.UUID       `db:"id" json:"id"`
+	URL       string          `db:"url" json:"url"`
+	Secret    string          `db:"secret" json:"secret,omitempty"`
+	Event     string          `db:"event" json:"event"`
+	Threshold decimal.Decimal `db:"threshold" json:"threshold"`
+	ProductID *uuid.UUID      `db:"product_id" json:"product_id,omitempty"`
+	Warehouse *string         `db:"warehouse" json:"warehouse,omitempty"`
+	Currency  *string         `db:"currency" json:"currency,omitempty"`
+	Active    bool            `db:"active" json:"active"`
+	CreatedAt time.Time       `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time       `db:"updated_at" json:"updated_at"`
+}
+
+// Delivery is one alert sent (or still to be sent) to a subscription
+type Delivery struct {
+	ID             uuid.UUID      `db:"id" json:"id"`
+	SubscriptionID uuid.UUID      `db:"subscription_id" json:"subscription_id"`
+	Event          string         `db:"event" json:"event"`
+	Payload        types.JSONText `db:"payload" json:"payload"`
+	Status         string         `db:"status" json:"status"`
+	Attempts       int            `db:"attempts" json:"attempts"`
+	NextAttemptAt  time.Time      `db:"next_attempt_at" json:"next_attempt_at"`
+	ResponseStatus *int           `db:"response_status" json:"response_status,omitempty"`
+	LastError      *string        `db:"last_error" json:"last_error,omitempty"`
+	DeliveredAt    *time.Time     `db:"delivered_at" json:"delivered_at,omitempty"`
+	CreatedAt      time.Time      `db:"created_at" json:"created_at"`
+}
+
+// Alert is what a trigger reports; subscriptions matching it get a delivery
+type Alert struct {
+	Event     string
+	Value     decimal.Decimal
+	Previous  *decimal.Decimal
+	ProductID *uuid.UUID
+	Warehouse string
+	Currency  string
+	Data      map[string]interface{}
+}
+
+const (
+	SubscriptionTable = "webhook_subscriptions"
+	DeliveryTable     = "webhook_deliveries"
+)
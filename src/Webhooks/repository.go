@@ -0,0 +1,159 @@
+package Webhooks
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+type Repository interface {
+	CreateSubscription(ctx context.Context, sub *Subscription) error
+	GetSubscription(ctx context.Context, id uuid.UUID) (*Subscription, error)
+	ListSubscriptions(ctx context.Context) ([]Subscription, error)
+	UpdateSubscription(ctx context.Context, sub *Subscription) error
+	DeleteSubscription(ctx context.Context, id uuid.UUID) error
+	Matching(ctx context.Context, a Alert) ([]Subscription, error)
+
+	Enqueue(ctx context.Context, deliveries []Delivery) error
+	ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]Delivery, error)
+	MarkDelivered(ctx context.Context, id uuid.UUID, responseStatus int) error
+	MarkFailed(ctx context.Context, id uuid.UUID, responseStatus *int, cause string, nextAttempt time.Time, dead bool) error
+	ListDeliveries(ctx context.Context, subscriptionID uuid.UUID, limit, offset int) ([]Delivery, error)
+	CountDeliveries(ctx context.Context, subscriptionID uuid.UUID) (int, error)
+}
+
+type repository struct {
+	db  *sqlx.DB
+	log *zap.Logger
+}
+
+func NewRepository(db *sqlx.DB, log *zap.Logger) Repository { return &repository{db: db, log: log} }
+
+const (
+	subscriptionColumns = `id,url,secret,event,threshold,product_id,warehouse,currency,active,created_at,updated_at`
+	deliveryColumns     = `id,subscription_id,event,payload,status,attempts,next_attempt_at,response_status,last_error,delivered_at,created_at`
+)
+
+func (r *repository) CreateSubscription(ctx context.Context, sub *Subscription) error {
+	query := fmt.Sprintf(`INSERT INTO %s (%s) VALUES (:id,:url,:secret,:event,:threshold,:product_id,:warehouse,:currency,:active,:created_at,:updated_at)`, SubscriptionTable, subscriptionColumns)
+	_, err := r.db.NamedExecContext(ctx, query, sub)
+	return err
+}
+
+func (r *repository) GetSubscription(ctx context.Context, id uuid.UUID) (*Subscription, error) {
+	var sub Subscription
+	if err := r.db.GetContext(ctx, &sub, fmt.Sprintf(`SELECT %s FROM %s WHERE id=$1`, subscriptionColumns, SubscriptionTable), id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrorNotFound
+		}
+		return nil, err
+	}
+	return &sub, nil
+}
+
+func (r *repository) ListSubscriptions(ctx context.Context) ([]Subscription, error) {
+	subs := []Subscription{}
+	err := r.db.SelectContext(ctx, &subs, fmt.Sprintf(`SELECT %s FROM %s ORDER BY created_at`, subscriptionColumns, SubscriptionTable))
+	return subs, err
+}
+
+func (r *repository) UpdateSubscription(ctx context.Context, sub *Subscription) error {
+	query := fmt.Sprintf(`UPDATE %s SET url=:url, threshold=:threshold, active=:active, updated_at=:updated_at WHERE id=:id`, SubscriptionTable)
+	res, err := r.db.NamedExecContext(ctx, query, sub)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrorNotFound
+	}
+	return nil
+}
+
+func (r *repository) DeleteSubscription(ctx context.Context, id uuid.UUID) error {
+	res, err := r.db.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE id=$1`, SubscriptionTable), id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrorNotFound
+	}
+	return nil
+}
+
+// Matching returns the active subscriptions to the alert's event whose
+// filters allow it; the threshold is checked by the caller
+func (r *repository) Matching(ctx context.Context, a Alert) ([]Subscription, error) {
+	subs := []Subscription{}
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE active AND event=$1
+		AND (product_id IS NULL OR product_id=$2)
+		AND (warehouse IS NULL OR warehouse=$3)
+		AND (currency IS NULL OR currency=$4)`, subscriptionColumns, SubscriptionTable)
+	err := r.db.SelectContext(ctx, &subs, query, a.Event, a.ProductID, a.Warehouse, a.Currency)
+	return subs, err
+}
+
+func (r *repository) Enqueue(ctx context.Context, deliveries []Delivery) (err error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+	query := fmt.Sprintf(`INSERT INTO %s (id,subscription_id,event,payload,status,attempts,next_attempt_at,created_at) VALUES (:id,:subscription_id,:event,:payload,:status,0,:next_attempt_at,:created_at)`, DeliveryTable)
+	for i := range deliveries {
+		if _, err = tx.NamedExecContext(ctx, query, &deliveries[i]); err != nil {
+			return err
+		}
+	}
+	err = tx.Commit()
+	return err
+}
+
+// ClaimDue leases due deliveries by pushing next_attempt_at forward, so two
+// instances never post the same alert at once
+func (r *repository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]Delivery, error) {
+	query := fmt.Sprintf(`UPDATE %[1]s SET next_attempt_at=$1 WHERE id IN (
+		SELECT id FROM %[1]s WHERE status=$2 AND next_attempt_at <= NOW() ORDER BY next_attempt_at LIMIT $3 FOR UPDATE SKIP LOCKED
+	) RETURNING %[2]s`, DeliveryTable, deliveryColumns)
+	out := []Delivery{}
+	err := r.db.SelectContext(ctx, &out, query, time.Now().UTC().Add(lease), StatusPending, limit)
+	return out, err
+}
+
+func (r *repository) MarkDelivered(ctx context.Context, id uuid.UUID, responseStatus int) error {
+	query := fmt.Sprintf(`UPDATE %s SET status=$1, attempts=attempts+1, response_status=$2, last_error=NULL, delivered_at=NOW() WHERE id=$3`, DeliveryTable)
+	_, err := r.db.ExecContext(ctx, query, StatusDelivered, responseStatus, id)
+	return err
+}
+
+func (r *repository) MarkFailed(ctx context.Context, id uuid.UUID, responseStatus *int, cause string, nextAttempt time.Time, dead bool) error {
+	status := StatusPending
+	if dead {
+		status = StatusFailed
+	}
+	query := fmt.Sprintf(`UPDATE %s SET status=$1, attempts=attempts+1, response_status=$2, last_error=$3, next_attempt_at=$4 WHERE id=$5`, DeliveryTable)
+	_, err := r.db.ExecContext(ctx, query, status, responseStatus, cause, nextAttempt, id)
+	return err
+}
+
+func (r *repository) ListDeliveries(ctx context.Context, subscriptionID uuid.UUID, limit, offset int) ([]Delivery, error) {
+	out := []Delivery{}
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE subscription_id=$1 ORDER BY created_at DESC LIMIT $2 OFFSET $3`, deliveryColumns, DeliveryTable)
+	err := r.db.SelectContext(ctx, &out, query, subscriptionID, limit, offset)
+	return out, err
+}
+
+func (r *repository) CountDeliveries(ctx context.Context, subscriptionID uuid.UUID) (int, error) {
+	var n int
+	err := r.db.GetContext(ctx, &n, fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE subscription_id=$1`, DeliveryTable), subscriptionID)
+	return n, err
+}
@@ -0,0 +1,278 @@
+package Webhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+// Service manages outbound webhook subscriptions and alerts them when stock,
+// order values or refunds cross their thresholds. Alerts are queued and
+// posted by Deliver, so a slow receiver never holds up the triggering request.
+type Service interface {
+	ListSubscriptions(ctx context.Context) ([]Subscription, error)
+	GetSubscription(ctx context.Context, id uuid.UUID) (*Subscription, error)
+	CreateSubscription(ctx context.Context, req CreateSubscriptionRequest) (*Subscription, error)
+	UpdateSubscription(ctx context.Context, id uuid.UUID, req UpdateSubscriptionRequest) (*Subscription, error)
+	DeleteSubscription(ctx context.Context, id uuid.UUID) error
+	ListDeliveries(ctx context.Context, subscriptionID uuid.UUID, limit, offset int) ([]Delivery, error)
+	CountDeliveries(ctx context.Context, subscriptionID uuid.UUID) (int, error)
+
+	StockChanged(ctx context.Context, productID uuid.UUID, warehouse string, before, after int)
+	OrderPlaced(ctx context.Context, orderID uuid.UUID, total decimal.Decimal, currency string)
+	Refunded(ctx context.Context, orderID uuid.UUID, amount decimal.Decimal, currency string)
+
+	Deliver(ctx context.Context) error
+}
+
+type service struct {
+	repo   Repository
+	sender *Sender
+	log    *zap.Logger
+}
+
+func NewService(r Repository, sender *Sender, log *zap.Logger) Service {
+	return &service{repo: r, sender: sender, log: log}
+}
+
+const (
+	batchSize   = 50
+	leaseFor    = 5 * time.Minute
+	maxAttempts = 8
+	maxBackoff  = 6 * time.Hour
+)
+
+func (s *service) ListSubscriptions(ctx context.Context) ([]Subscription, error) {
+	subs, err := s.repo.ListSubscriptions(ctx)
+	for i := range subs {
+		subs[i].Secret = ""
+	}
+	return subs, err
+}
+
+func (s *service) GetSubscription(ctx context.Context, id uuid.UUID) (*Subscription, error) {
+	sub, err := s.repo.GetSubscription(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	sub.Secret = ""
+	return sub, nil
+}
+
+// CreateSubscription returns the new subscription with the secret its
+// deliveries are signed with; it is not shown again
+func (s *service) CreateSubscription(ctx context.Context, req CreateSubscriptionRequest) (*Subscription, error) {
+	if !events[req.Event] {
+		return nil, ErrorUnknownEvent
+	}
+	if err := validURL(req.URL); err != nil {
+		return nil, err
+	}
+	if req.Threshold.IsNegative() {
+		return nil, ErrorInvalidThreshold
+	}
+	secret, err := newSecret()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	sub := &Subscription{ID: uuid.New(), URL: req.URL, Secret: secret, Event: req.Event, Threshold: req.Threshold,
+		ProductID: req.ProductID, Warehouse: req.Warehouse, Active: true, CreatedAt: now, UpdatedAt: now}
+	if req.Currency != nil {
+		currency := strings.ToUpper(*req.Currency)
+		sub.Currency = &currency
+	}
+	if err := s.repo.CreateSubscription(ctx, sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+func (s *service) UpdateSubscription(ctx context.Context, id uuid.UUID, req UpdateSubscriptionRequest) (*Subscription, error) {
+	sub, err := s.repo.GetSubscription(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if req.URL != nil {
+		if err := validURL(*req.URL); err != nil {
+			return nil, err
+		}
+		sub.URL = *req.URL
+	}
+	if req.Threshold != nil {
+		if req.Threshold.IsNegative() {
+			return nil, ErrorInvalidThreshold
+		}
+		sub.Threshold = *req.Threshold
+	}
+	if req.Active != nil {
+		sub.Active = *req.Active
+	}
+	sub.UpdatedAt = time.Now().UTC()
+	if err := s.repo.UpdateSubscription(ctx, sub); err != nil {
+		return nil, err
+	}
+	sub.Secret = ""
+	return sub, nil
+}
+
+func (s *service) DeleteSubscription(ctx context.Context, id uuid.UUID) error {
+	return s.repo.DeleteSubscription(ctx, id)
+}
+
+func (s *service) ListDeliveries(ctx context.Context, subscriptionID uuid.UUID, limit, offset int) ([]Delivery, error) {
+	if _, err := s.repo.GetSubscription(ctx, subscriptionID); err != nil {
+		return nil, err
+	}
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	return s.repo.ListDeliveries(ctx, subscriptionID, limit, offset)
+}
+
+func (s *service) CountDeliveries(ctx context.Context, subscriptionID uuid.UUID) (int, error) {
+	return s.repo.CountDeliveries(ctx, subscriptionID)
+}
+
+// StockChanged alerts when available stock falls below a threshold. Only the
+// change that crosses it alerts, so a subscriber is not paged for every sale
+// while stock stays low.
+func (s *service) StockChanged(ctx context.Context, productID uuid.UUID, warehouse string, before, after int) {
+	if after >= before {
+		return
+	}
+	previous := decimal.NewFromInt(int64(before))
+	s.notify(ctx, Alert{Event: EventStockBelow, Value: decimal.NewFromInt(int64(after)), Previous: &previous,
+		ProductID: &productID, Warehouse: warehouse,
+		Data: map[string]interface{}{"product_id": productID, "warehouse": warehouse, "available": after, "previous": before}})
+}
+
+func (s *service) OrderPlaced(ctx context.Context, orderID uuid.UUID, total decimal.Decimal, currency string) {
+	s.notify(ctx, Alert{Event: EventOrderValueAbove, Value: total, Currency: currency,
+		Data: map[string]interface{}{"order_id": orderID, "total": total, "currency": currency}})
+}
+
+func (s *service) Refunded(ctx context.Context, orderID uuid.UUID, amount decimal.Decimal, currency string) {
+	s.notify(ctx, Alert{Event: EventRefundAbove, Value: amount, Currency: currency,
+		Data: map[string]interface{}{"order_id": orderID, "amount": amount, "currency": currency}})
+}
+
+// notify queues a delivery for every subscription the alert trips. Failing
+// to queue is logged, never returned: the triggering operation has already
+// happened.
+func (s *service) notify(ctx context.Context, a Alert) {
+	subs, err := s.repo.Matching(ctx, a)
+	if err != nil {
+		s.log.Error("match webhook subscriptions", zap.String("event", a.Event), zap.Error(err))
+		return
+	}
+	now := time.Now().UTC()
+	var deliveries []Delivery
+	for _, sub := range subs {
+		if !trips(sub, a) {
+			continue
+		}
+		d := Delivery{ID: uuid.New(), SubscriptionID: sub.ID, Event: a.Event, Status: StatusPending, NextAttemptAt: now, CreatedAt: now}
+		payload, err := json.Marshal(map[string]interface{}{"id": d.ID, "event": a.Event, "threshold": sub.Threshold, "occurred_at": now, "data": a.Data})
+		if err != nil {
+			s.log.Error("encode webhook payload", zap.String("event", a.Event), zap.Error(err))
+			return
+		}
+		d.Payload = payload
+		deliveries = append(deliveries, d)
+	}
+	if len(deliveries) == 0 {
+		return
+	}
+	if err := s.repo.Enqueue(ctx, deliveries); err != nil {
+		s.log.Error("queue webhook deliveries", zap.String("event", a.Event), zap.Int("subscriptions", len(deliveries)), zap.Error(err))
+	}
+}
+
+// trips reports whether the alert crosses the subscription's threshold
+func trips(sub Subscription, a Alert) bool {
+	switch a.Event {
+	case EventStockBelow:
+		return a.Value.LessThan(sub.Threshold) && (a.Previous == nil || !a.Previous.LessThan(sub.Threshold))
+	default:
+		return a.Value.GreaterThan(sub.Threshold)
+	}
+}
+
+// Deliver posts every due alert; failures are retried with exponential
+// backoff and given up as FAILED after maxAttempts
+func (s *service) Deliver(ctx context.Context) error {
+	due, err := s.repo.ClaimDue(ctx, batchSize, leaseFor)
+	if err != nil {
+		return err
+	}
+	subs := map[uuid.UUID]*Subscription{}
+	for i := range due {
+		d := &due[i]
+		sub, ok := subs[d.SubscriptionID]
+		if !ok {
+			if sub, err = s.repo.GetSubscription(ctx, d.SubscriptionID); err != nil {
+				s.log.Error("load webhook subscription", zap.String("id", d.SubscriptionID.String()), zap.Error(err))
+				continue
+			}
+			subs[d.SubscriptionID] = sub
+		}
+		if !sub.Active {
+			s.fail(ctx, d, nil, "subscription is inactive", true)
+			continue
+		}
+		status, err := s.sender.Post(ctx, sub, d)
+		if err == nil {
+			if err := s.repo.MarkDelivered(ctx, d.ID, status); err != nil {
+				s.log.Error("mark webhook delivered", zap.String("id", d.ID.String()), zap.Error(err))
+			}
+			continue
+		}
+		var code *int
+		if status != 0 {
+			code = &status
+		}
+		attempts := d.Attempts + 1
+		s.log.Warn("webhook delivery failed", zap.String("id", d.ID.String()), zap.String("event", d.Event), zap.Int("attempts", attempts), zap.Error(err))
+		s.fail(ctx, d, code, err.Error(), attempts >= maxAttempts)
+	}
+	return nil
+}
+
+func (s *service) fail(ctx context.Context, d *Delivery, status *int, cause string, dead bool) {
+	if err := s.repo.MarkFailed(ctx, d.ID, status, cause, time.Now().UTC().Add(backoff(d.Attempts+1)), dead); err != nil {
+		s.log.Error("mark webhook failed", zap.String("id", d.ID.String()), zap.Error(err))
+	}
+}
+
+func backoff(attempts int) time.Duration {
+	d := time.Minute << uint(attempts)
+	if d <= 0 || d > maxBackoff {
+		return maxBackoff
+	}
+	return d
+}
+
+func validURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrorInvalidURL
+	}
+	return nil
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,7 @@
+-- Orders captured outside checkout (marketplaces, POS) keep their origin reference
+ALTER TABLE orders
+    ADD COLUMN external_reference VARCHAR(128),
+    ADD COLUMN source VARCHAR(50);
+
+CREATE UNIQUE INDEX idx_orders_external_reference ON orders(source, external_reference)
+    WHERE external_reference IS NOT NULL;
@@ -0,0 +1,8 @@
+-- Cursor per marketplace connector so each sync only pulls new orders
+CREATE TABLE connector_sync_state (
+    connector VARCHAR(50) PRIMARY KEY,
+    orders_synced_at TIMESTAMPTZ,
+    stock_synced_at TIMESTAMPTZ,
+    last_error TEXT,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
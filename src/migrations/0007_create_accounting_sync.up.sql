@@ -0,0 +1,21 @@
+-- Outbound sync of invoices and payments to the accounting system (QuickBooks/Xero/file export)
+ALTER TABLE invoices ADD COLUMN erp_sync_status VARCHAR(20) NOT NULL DEFAULT 'PENDING';
+ALTER TABLE payments ADD COLUMN erp_sync_status VARCHAR(20) NOT NULL DEFAULT 'PENDING';
+
+CREATE TABLE accounting_sync_queue (
+    id UUID PRIMARY KEY,
+    document_type VARCHAR(20) NOT NULL,
+    -- INVOICE, PAYMENT
+    document_id UUID NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'PENDING',
+    -- PENDING, SYNCED, FAILED
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    external_id VARCHAR(255),
+    last_error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (document_type, document_id)
+);
+
+CREATE INDEX idx_accounting_sync_queue_due ON accounting_sync_queue(next_attempt_at) WHERE status = 'PENDING';
@@ -0,0 +1,17 @@
+CREATE TABLE product_images (
+    id UUID PRIMARY KEY,
+    product_id UUID NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+    original_key VARCHAR(512) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'PROCESSING',
+    -- PROCESSING, READY, FAILED
+    url TEXT NOT NULL,
+    thumbnail_url TEXT,
+    medium_url TEXT,
+    large_url TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_product_images_product ON product_images(product_id);
+CREATE INDEX idx_product_images_processing ON product_images(created_at) WHERE status = 'PROCESSING';
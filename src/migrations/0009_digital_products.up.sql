@@ -0,0 +1,25 @@
+-- Digital products: downloadable files delivered through expiring signed links
+ALTER TABLE products ADD COLUMN product_type VARCHAR(20) NOT NULL DEFAULT 'PHYSICAL';
+-- PHYSICAL, DIGITAL
+
+CREATE TABLE product_files (
+    id UUID PRIMARY KEY,
+    product_id UUID NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+    file_key VARCHAR(512) NOT NULL,
+    file_name VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_product_files_product ON product_files(product_id);
+
+CREATE TABLE download_links (
+    id UUID PRIMARY KEY,
+    order_id UUID NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+    file_id UUID NOT NULL REFERENCES product_files (id) ON DELETE CASCADE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    max_downloads INT NOT NULL,
+    download_count INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (order_id, file_id)
+);
@@ -0,0 +1,4 @@
+ALTER TABLE order_items
+    ADD COLUMN gift_wrap BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN gift_message VARCHAR(500),
+    ADD COLUMN customer_note VARCHAR(1000);
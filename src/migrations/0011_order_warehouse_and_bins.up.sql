@@ -0,0 +1,5 @@
+-- Fulfilment documents: orders remember their warehouse, stock has a bin location
+ALTER TABLE orders ADD COLUMN warehouse VARCHAR(100);
+CREATE INDEX idx_orders_warehouse_status ON orders(warehouse, status);
+
+ALTER TABLE inventory ADD COLUMN bin_location VARCHAR(50);
@@ -0,0 +1,30 @@
+CREATE TABLE shipments (
+    id UUID PRIMARY KEY,
+    order_id UUID NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+    carrier VARCHAR(50) NOT NULL,
+    service_level VARCHAR(50),
+    status VARCHAR(30) NOT NULL DEFAULT 'PENDING',
+    -- PENDING, LABEL_PURCHASED, SHIPPED, DELIVERED, CANCELLED
+    recipient_name VARCHAR(200) NOT NULL,
+    address_line1 VARCHAR(255) NOT NULL,
+    address_line2 VARCHAR(255),
+    city VARCHAR(100) NOT NULL,
+    region VARCHAR(100),
+    postal_code VARCHAR(20),
+    country CHAR(2) NOT NULL,
+    phone VARCHAR(20),
+    weight_grams INT NOT NULL,
+    tracking_number VARCHAR(100),
+    carrier_shipment_id VARCHAR(255),
+    label_key VARCHAR(512),
+    label_format VARCHAR(10),
+    label_cost NUMERIC(18, 4),
+    label_currency CHAR(3),
+    label_purchased_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    version INT NOT NULL DEFAULT 1
+);
+
+CREATE INDEX idx_shipments_order ON shipments(order_id);
+CREATE INDEX idx_shipments_tracking ON shipments(tracking_number);
@@ -0,0 +1,42 @@
+CREATE TABLE order_events (
+    id UUID PRIMARY KEY,
+    order_id UUID NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+    event_type VARCHAR(50) NOT NULL,
+    -- ORDER_CREATED, ORDER_IMPORTED, STATUS_CHANGED
+    status VARCHAR(30),
+    data JSONB,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_order_events_order ON order_events(order_id, created_at);
+
+-- existing orders get their creation event
+INSERT INTO order_events (id, order_id, event_type, status, created_at)
+SELECT gen_random_uuid(), id, CASE WHEN source IS NULL THEN 'ORDER_CREATED' ELSE 'ORDER_IMPORTED' END, status, created_at
+FROM orders;
+
+CREATE TABLE order_notes (
+    id UUID PRIMARY KEY,
+    order_id UUID NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+    author VARCHAR(200) NOT NULL,
+    body TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_order_notes_order ON order_notes(order_id);
+
+CREATE TABLE notification_log (
+    id UUID PRIMARY KEY,
+    order_id UUID REFERENCES orders (id) ON DELETE SET NULL,
+    channel VARCHAR(20) NOT NULL,
+    recipient VARCHAR(255) NOT NULL,
+    template VARCHAR(100),
+    subject VARCHAR(255),
+    status VARCHAR(20) NOT NULL,
+    -- SENT, FAILED
+    provider_reference VARCHAR(255),
+    error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_notification_log_order ON notification_log(order_id);
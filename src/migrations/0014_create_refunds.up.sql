@@ -0,0 +1,17 @@
+-- invoices.status gains PARTIALLY_REFUNDED
+CREATE TABLE refunds (
+    id UUID PRIMARY KEY,
+    payment_id UUID NOT NULL REFERENCES payments (id) ON DELETE CASCADE,
+    invoice_id UUID NOT NULL REFERENCES invoices (id) ON DELETE CASCADE,
+    provider VARCHAR(50) NOT NULL,
+    provider_refund_id VARCHAR(255),
+    amount NUMERIC(18, 4) NOT NULL,
+    currency CHAR(3) NOT NULL,
+    status VARCHAR(30) NOT NULL,
+    -- SUCCESS, FAILED
+    reason TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_refunds_payment ON refunds(payment_id);
+CREATE INDEX idx_refunds_invoice ON refunds(invoice_id);
@@ -0,0 +1,25 @@
+ALTER TABLE orders ADD COLUMN frozen_at TIMESTAMPTZ;
+ALTER TABLE orders ADD COLUMN frozen_reason VARCHAR(255);
+
+CREATE TABLE disputes (
+    id UUID PRIMARY KEY,
+    provider VARCHAR(50) NOT NULL,
+    provider_dispute_id VARCHAR(255) NOT NULL,
+    payment_id UUID REFERENCES payments (id) ON DELETE SET NULL,
+    invoice_id UUID REFERENCES invoices (id) ON DELETE SET NULL,
+    order_id UUID REFERENCES orders (id) ON DELETE SET NULL,
+    provider_payment_id VARCHAR(255),
+    reason VARCHAR(100),
+    status VARCHAR(30) NOT NULL,
+    -- NEEDS_RESPONSE, UNDER_REVIEW, WARNING_NEEDS_RESPONSE, WARNING_UNDER_REVIEW, WARNING_CLOSED, WON, LOST
+    amount NUMERIC(18, 4) NOT NULL,
+    currency CHAR(3) NOT NULL,
+    evidence_due_by TIMESTAMPTZ,
+    closed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_disputes_provider_ref ON disputes(provider, provider_dispute_id);
+CREATE INDEX idx_disputes_order ON disputes(order_id);
+CREATE INDEX idx_disputes_open_due ON disputes(evidence_due_by) WHERE closed_at IS NULL;
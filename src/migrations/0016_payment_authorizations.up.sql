@@ -0,0 +1,7 @@
+-- payments.status gains AUTHORIZED, VOIDED, EXPIRED; a captured authorization becomes SUCCESS
+ALTER TABLE payments ADD COLUMN authorized_at TIMESTAMPTZ;
+ALTER TABLE payments ADD COLUMN authorization_expires_at TIMESTAMPTZ;
+ALTER TABLE payments ADD COLUMN captured_at TIMESTAMPTZ;
+ALTER TABLE payments ADD COLUMN voided_at TIMESTAMPTZ;
+
+CREATE INDEX idx_payments_authorized ON payments(authorization_expires_at) WHERE status = 'AUTHORIZED';
@@ -0,0 +1,15 @@
+CREATE TABLE payment_attempts (
+    id UUID PRIMARY KEY,
+    invoice_id UUID NOT NULL REFERENCES invoices (id) ON DELETE CASCADE,
+    attempt INT NOT NULL,
+    idempotency_key VARCHAR(100) NOT NULL UNIQUE,
+    provider VARCHAR(50) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    -- PENDING, SUCCEEDED, FAILED
+    provider_payment_id VARCHAR(255),
+    payment_id UUID REFERENCES payments (id) ON DELETE SET NULL,
+    error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (invoice_id, attempt)
+);
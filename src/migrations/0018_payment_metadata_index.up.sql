@@ -0,0 +1,3 @@
+UPDATE payments SET metadata = '{}'::jsonb WHERE metadata IS NULL OR jsonb_typeof(metadata) <> 'object';
+
+CREATE INDEX idx_payments_metadata ON payments USING GIN (metadata jsonb_path_ops);
@@ -0,0 +1,20 @@
+-- invoices.status gains PARTIALLY_PAID
+ALTER TABLE orders ADD COLUMN region VARCHAR(100);
+ALTER TABLE orders ADD COLUMN payment_method VARCHAR(50);
+
+CREATE TABLE offline_payment_methods (
+    id UUID PRIMARY KEY,
+    method VARCHAR(30) NOT NULL,
+    -- COD, BANK_TRANSFER
+    warehouse VARCHAR(50),
+    region VARCHAR(100),
+    -- NULL warehouse / region matches any
+    instructions TEXT,
+    due_in_days INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_offline_payment_methods_method ON offline_payment_methods(method);
+
+-- an offline payment's reference is recorded once per invoice
+CREATE UNIQUE INDEX idx_payments_offline_reference ON payments(invoice_id, provider_payment_id) WHERE provider IN ('COD', 'BANK_TRANSFER');
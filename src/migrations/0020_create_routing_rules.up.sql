@@ -0,0 +1,14 @@
+ALTER TABLE orders ADD COLUMN country CHAR(2);
+
+CREATE TABLE routing_rules (
+    id UUID PRIMARY KEY,
+    country CHAR(2),
+    region VARCHAR(100),
+    -- NULL country / region matches any address
+    warehouse VARCHAR(50) NOT NULL,
+    priority INT NOT NULL DEFAULT 100,
+    -- lower is tried first among equally specific rules
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_routing_rules_country ON routing_rules(country, region);
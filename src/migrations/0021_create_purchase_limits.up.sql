@@ -0,0 +1,9 @@
+CREATE TABLE purchase_limits (
+    product_id UUID PRIMARY KEY REFERENCES products (id) ON DELETE CASCADE,
+    max_quantity INT NOT NULL,
+    period_hours INT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_orders_customer_created ON orders(customer_id, created_at);
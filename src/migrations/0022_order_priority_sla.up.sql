@@ -0,0 +1,19 @@
+ALTER TABLE orders ADD COLUMN priority VARCHAR(20) NOT NULL DEFAULT 'standard';
+-- standard, express
+ALTER TABLE orders ADD COLUMN sla_due_at TIMESTAMPTZ;
+-- deadline for leaving the current status, NULL once no SLA applies
+
+CREATE INDEX idx_orders_sla_due ON orders(sla_due_at) WHERE sla_due_at IS NOT NULL;
+
+-- one row per SLA-tracked status an order has left
+CREATE TABLE order_sla_results (
+    id UUID PRIMARY KEY,
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    priority VARCHAR(20) NOT NULL,
+    status VARCHAR(30) NOT NULL,
+    due_at TIMESTAMPTZ NOT NULL,
+    completed_at TIMESTAMPTZ NOT NULL,
+    met BOOLEAN NOT NULL
+);
+
+CREATE INDEX idx_order_sla_results_completed ON order_sla_results(completed_at);
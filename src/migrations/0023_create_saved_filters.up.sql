@@ -0,0 +1,22 @@
+CREATE TABLE saved_filters (
+    id UUID PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    resource VARCHAR(20) NOT NULL,
+    -- orders, products, customers
+    filters JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE export_schedules (
+    id UUID PRIMARY KEY,
+    filter_id UUID NOT NULL REFERENCES saved_filters (id) ON DELETE CASCADE,
+    recipients TEXT[] NOT NULL,
+    interval_hours INT NOT NULL,
+    next_run_at TIMESTAMPTZ NOT NULL,
+    last_run_at TIMESTAMPTZ,
+    last_error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_export_schedules_due ON export_schedules(next_run_at);
@@ -0,0 +1,12 @@
+-- pre-aggregated order counts and revenue per UTC day, refreshed by the orders.stats-rollup job
+CREATE TABLE order_daily_stats (
+    day DATE NOT NULL,
+    currency CHAR(3) NOT NULL,
+    status VARCHAR(30) NOT NULL,
+    orders INT NOT NULL,
+    revenue NUMERIC(18, 4) NOT NULL,
+    refreshed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (day, currency, status)
+);
+
+CREATE INDEX idx_orders_created ON orders(created_at);
@@ -0,0 +1,14 @@
+-- non-critical notifications held back to be sent as one summary per recipient
+CREATE TABLE notification_digest_queue (
+    id UUID PRIMARY KEY,
+    channel VARCHAR(20) NOT NULL,
+    recipient VARCHAR(255) NOT NULL,
+    template VARCHAR(100) NOT NULL,
+    subject TEXT,
+    body TEXT NOT NULL,
+    order_id UUID REFERENCES orders(id) ON DELETE SET NULL,
+    send_after TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_notification_digest_recipient ON notification_digest_queue(channel, recipient);
@@ -0,0 +1,4 @@
+ALTER TABLE notification_log ADD COLUMN body TEXT;
+ALTER TABLE notification_log ADD COLUMN data JSONB;
+-- set on attempts made through the resend endpoint
+ALTER TABLE notification_log ADD COLUMN resent_from UUID REFERENCES notification_log (id) ON DELETE SET NULL;
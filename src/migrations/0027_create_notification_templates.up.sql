@@ -0,0 +1,16 @@
+-- versioned, DB-managed copy for notification templates; the active version of
+-- the most specific (tenant, locale) match overrides the built-in text
+CREATE TABLE notification_templates (
+    id UUID PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    tenant VARCHAR(50) NOT NULL DEFAULT '',
+    locale VARCHAR(10) NOT NULL DEFAULT '',
+    version INT NOT NULL,
+    subject TEXT NOT NULL DEFAULT '',
+    body TEXT NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (name, tenant, locale, version)
+);
+
+CREATE UNIQUE INDEX idx_notification_templates_active ON notification_templates(name, tenant, locale) WHERE active;
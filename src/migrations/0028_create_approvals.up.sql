@@ -0,0 +1,30 @@
+CREATE TABLE approvals (
+    id UUID PRIMARY KEY,
+    action VARCHAR(50) NOT NULL,
+    -- refund, bulk_price_change, bulk_cancel
+    summary TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    -- PENDING, APPROVED (executing), REJECTED, EXECUTED, FAILED
+    requested_by VARCHAR(200) NOT NULL,
+    decided_by VARCHAR(200),
+    decision_note TEXT,
+    result JSONB,
+    error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    decided_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_approvals_status ON approvals(status, created_at);
+
+-- audit trail of every step of an approval
+CREATE TABLE approval_events (
+    id UUID PRIMARY KEY,
+    approval_id UUID NOT NULL REFERENCES approvals (id) ON DELETE CASCADE,
+    event VARCHAR(30) NOT NULL,
+    actor VARCHAR(200) NOT NULL,
+    data JSONB,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_approval_events_approval ON approval_events(approval_id, created_at);
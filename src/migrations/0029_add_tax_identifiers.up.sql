@@ -0,0 +1,18 @@
+-- B2B customers: company and tax registration (VAT number, KRA PIN)
+ALTER TABLE customers
+    ADD COLUMN company_name VARCHAR(200),
+    ADD COLUMN tax_id VARCHAR(30),
+    ADD COLUMN tax_id_type VARCHAR(10),
+    -- VAT, PIN
+    ADD COLUMN tax_country CHAR(2);
+
+-- buyer and seller tax identity as issued; later customer edits do not change an invoice
+ALTER TABLE invoices
+    ADD COLUMN buyer_name VARCHAR(200),
+    ADD COLUMN buyer_company VARCHAR(200),
+    ADD COLUMN buyer_tax_id VARCHAR(30),
+    ADD COLUMN buyer_tax_id_type VARCHAR(10),
+    ADD COLUMN buyer_country CHAR(2),
+    ADD COLUMN seller_tax_id VARCHAR(30),
+    ADD COLUMN reverse_charge BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN tax_note TEXT;
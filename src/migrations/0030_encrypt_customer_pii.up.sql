@@ -0,0 +1,16 @@
+-- email and phone are encrypted by the application (envelope encryption);
+-- the plaintext columns are cleared once a row has been encrypted
+ALTER TABLE customers
+    ALTER COLUMN email DROP NOT NULL,
+    ALTER COLUMN phone DROP NOT NULL,
+    ADD COLUMN email_encrypted TEXT,
+    ADD COLUMN phone_encrypted TEXT,
+    -- keyed hashes for lookups by email or phone
+    ADD COLUMN email_index CHAR(64),
+    ADD COLUMN phone_index CHAR(64),
+    -- key that encrypted the row, rows not on the active key are re-encrypted
+    ADD COLUMN pii_key_id VARCHAR(50);
+
+CREATE UNIQUE INDEX idx_customers_email_index ON customers(email_index);
+CREATE INDEX idx_customers_phone_index ON customers(phone_index);
+CREATE INDEX idx_customers_pii_key ON customers(pii_key_id);
@@ -0,0 +1,3 @@
+-- change feeds page through rows by (updated_at, id)
+CREATE INDEX idx_products_updated ON products(updated_at, id);
+CREATE INDEX idx_orders_updated ON orders(updated_at, id);
@@ -0,0 +1,35 @@
+CREATE TABLE campaigns (
+    id UUID PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    discount_percent NUMERIC(5,2) NOT NULL CHECK (discount_percent > 0 AND discount_percent <= 100),
+    starts_at TIMESTAMPTZ NOT NULL,
+    ends_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (ends_at >= starts_at)
+);
+CREATE INDEX idx_campaigns_window ON campaigns(starts_at, ends_at);
+
+CREATE TABLE campaign_products (
+    campaign_id UUID NOT NULL REFERENCES campaigns (id) ON DELETE CASCADE,
+    product_id UUID NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+    PRIMARY KEY (campaign_id, product_id)
+);
+CREATE INDEX idx_campaign_products_product ON campaign_products(product_id);
+
+CREATE TABLE campaign_categories (
+    campaign_id UUID NOT NULL REFERENCES campaigns (id) ON DELETE CASCADE,
+    category_id UUID NOT NULL REFERENCES categories (id) ON DELETE CASCADE,
+    PRIMARY KEY (campaign_id, category_id)
+);
+CREATE INDEX idx_campaign_categories_category ON campaign_categories(category_id);
+
+-- the best running discount of every product on sale, evaluated at query
+-- time so prices revert by themselves when a campaign ends
+CREATE VIEW active_product_discounts AS
+SELECT DISTINCT ON (p.id) p.id AS product_id, c.id AS campaign_id, c.discount_percent
+FROM products p
+JOIN campaigns c ON c.starts_at <= NOW() AND c.ends_at > NOW()
+WHERE EXISTS (SELECT 1 FROM campaign_products cp WHERE cp.campaign_id = c.id AND cp.product_id = p.id)
+   OR EXISTS (SELECT 1 FROM campaign_categories cc WHERE cc.campaign_id = c.id AND cc.category_id = p.category_id)
+ORDER BY p.id, c.discount_percent DESC, c.starts_at;
@@ -0,0 +1,10 @@
+-- storefront gallery order and the image shown in listings
+ALTER TABLE product_images ADD COLUMN position INT NOT NULL DEFAULT 0;
+ALTER TABLE product_images ADD COLUMN is_primary BOOLEAN NOT NULL DEFAULT FALSE;
+-- status gains UPLOADING: a signed upload the client has not confirmed yet
+
+UPDATE product_images pi SET position = o.position, is_primary = o.position = 0
+FROM (SELECT id, ROW_NUMBER() OVER (PARTITION BY product_id ORDER BY created_at, id) - 1 AS position FROM product_images) o
+WHERE pi.id = o.id;
+
+CREATE INDEX idx_product_images_gallery ON product_images(product_id, position);
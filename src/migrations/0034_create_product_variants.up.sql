@@ -0,0 +1,22 @@
+CREATE TABLE product_variants (
+    id UUID PRIMARY KEY,
+    product_id UUID NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+    sku VARCHAR(64) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL,
+    -- e.g. {"size": "M", "color": "red"}
+    options JSONB NOT NULL DEFAULT '{}',
+    -- NULL sells at the product price
+    price NUMERIC(18, 4),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    version INT NOT NULL DEFAULT 1
+);
+CREATE INDEX idx_product_variants_product ON product_variants(product_id);
+
+-- products sold in variants keep stock per variant
+ALTER TABLE inventory ADD COLUMN variant_id UUID REFERENCES product_variants (id) ON DELETE CASCADE;
+ALTER TABLE inventory DROP CONSTRAINT IF EXISTS inventory_product_id_warehouse_key;
+CREATE UNIQUE INDEX idx_inventory_product_warehouse ON inventory(product_id, warehouse) WHERE variant_id IS NULL;
+CREATE UNIQUE INDEX idx_inventory_variant_warehouse ON inventory(variant_id, warehouse) WHERE variant_id IS NOT NULL;
+
+ALTER TABLE order_items ADD COLUMN variant_id UUID REFERENCES product_variants (id) ON DELETE SET NULL;
@@ -0,0 +1,26 @@
+-- attribute schema of a category; its products may carry these attributes
+CREATE TABLE attribute_definitions (
+    id UUID PRIMARY KEY,
+    category_id UUID NOT NULL REFERENCES categories (id) ON DELETE CASCADE,
+    key VARCHAR(64) NOT NULL,
+    label VARCHAR(100) NOT NULL,
+    type VARCHAR(10) NOT NULL CHECK (type IN ('string', 'number', 'bool', 'enum')),
+    enum_values TEXT[] NOT NULL DEFAULT '{}',
+    required BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (category_id, key)
+);
+
+-- value holds the canonical text of every type (numbers as decimals, bools as
+-- true/false); number_value is set for numbers so they can be range filtered
+CREATE TABLE product_attributes (
+    product_id UUID NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+    key VARCHAR(64) NOT NULL,
+    type VARCHAR(10) NOT NULL,
+    value TEXT NOT NULL,
+    number_value NUMERIC,
+    PRIMARY KEY (product_id, key)
+);
+CREATE INDEX idx_product_attributes_value ON product_attributes(key, value);
+CREATE INDEX idx_product_attributes_number ON product_attributes(key, number_value) WHERE number_value IS NOT NULL;
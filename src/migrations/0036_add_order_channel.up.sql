@@ -0,0 +1,13 @@
+-- sales channel an order was placed through: web, mobile, pos or marketplace-<source>
+ALTER TABLE orders ADD COLUMN channel VARCHAR(64) NOT NULL DEFAULT 'web';
+UPDATE orders SET channel = CASE WHEN source = 'pos' THEN 'pos' ELSE 'marketplace-' || source END WHERE source IS NOT NULL;
+CREATE INDEX idx_orders_channel_created ON orders(channel, created_at);
+
+-- rollups gain the channel dimension; rebuilt from orders since the old rows have none
+ALTER TABLE order_daily_stats ADD COLUMN channel VARCHAR(64) NOT NULL DEFAULT 'web';
+ALTER TABLE order_daily_stats DROP CONSTRAINT order_daily_stats_pkey;
+ALTER TABLE order_daily_stats ADD PRIMARY KEY (day, currency, status, channel);
+DELETE FROM order_daily_stats;
+INSERT INTO order_daily_stats (day, currency, status, channel, orders, revenue, refreshed_at)
+SELECT (created_at AT TIME ZONE 'UTC')::date, currency, status, channel, COUNT(*), COALESCE(SUM(total), 0), NOW()
+FROM orders GROUP BY 1, currency, status, channel;
@@ -0,0 +1,9 @@
+-- per sales channel visibility; a product without a row for a channel is listed in it
+CREATE TABLE product_channels (
+    product_id UUID NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+    channel VARCHAR(30) NOT NULL,
+    enabled BOOLEAN NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (product_id, channel)
+);
+CREATE INDEX idx_product_channels_disabled ON product_channels(channel, product_id) WHERE NOT enabled;
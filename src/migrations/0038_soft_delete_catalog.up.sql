@@ -0,0 +1,4 @@
+-- products and categories are soft deleted so historical orders keep resolving them
+ALTER TABLE products ADD COLUMN deleted_at TIMESTAMPTZ;
+ALTER TABLE categories ADD COLUMN deleted_at TIMESTAMPTZ;
+CREATE INDEX idx_products_live_created ON products(created_at) WHERE deleted_at IS NULL;
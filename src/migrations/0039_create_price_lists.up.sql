@@ -0,0 +1,21 @@
+-- prices in other currencies or for customer groups; a list without a group
+-- is the default for its currency
+CREATE TABLE price_lists (
+    id UUID PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    currency CHAR(3) NOT NULL,
+    customer_group VARCHAR(64),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE UNIQUE INDEX idx_price_lists_currency_group ON price_lists(currency, COALESCE(customer_group, ''));
+
+-- a product's entry prices the product and all of its variants
+CREATE TABLE price_list_entries (
+    price_list_id UUID NOT NULL REFERENCES price_lists (id) ON DELETE CASCADE,
+    product_id UUID NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+    price NUMERIC(18, 4) NOT NULL CHECK (price >= 0),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (price_list_id, product_id)
+);
+CREATE INDEX idx_price_list_entries_product ON price_list_entries(product_id);
@@ -0,0 +1,8 @@
+-- what a unit costs the business; captured onto order_items when an order is placed
+ALTER TABLE products ADD COLUMN cost NUMERIC(18, 4);
+ALTER TABLE order_items ADD COLUMN unit_cost NUMERIC(18, 4);
+
+-- margin figures of the rollup cover only items with a known cost; the rest are counted in uncosted_items
+ALTER TABLE order_daily_stats ADD COLUMN cost NUMERIC(18, 4) NOT NULL DEFAULT 0;
+ALTER TABLE order_daily_stats ADD COLUMN costed_sales NUMERIC(18, 4) NOT NULL DEFAULT 0;
+ALTER TABLE order_daily_stats ADD COLUMN uncosted_items INT NOT NULL DEFAULT 0;
@@ -0,0 +1,13 @@
+-- per-minute request counts by API client, flushed from memory by the usage.flush job
+CREATE TABLE api_usage (
+    bucket TIMESTAMPTZ NOT NULL,
+    client_id VARCHAR(100) NOT NULL,
+    requests BIGINT NOT NULL DEFAULT 0,
+    client_errors BIGINT NOT NULL DEFAULT 0,
+    server_errors BIGINT NOT NULL DEFAULT 0,
+    total_latency_ms BIGINT NOT NULL DEFAULT 0,
+    max_latency_ms BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (bucket, client_id)
+);
+
+CREATE INDEX idx_api_usage_client_bucket ON api_usage(client_id, bucket);
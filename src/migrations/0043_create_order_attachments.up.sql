@@ -0,0 +1,19 @@
+-- documents attached to orders (customs forms, proofs of delivery, purchase
+-- orders), stored in object storage under file_key. No foreign key, since
+-- orders are archived.
+CREATE TABLE order_attachments (
+    id UUID PRIMARY KEY,
+    order_id UUID NOT NULL,
+    kind VARCHAR(20) NOT NULL,
+    file_name VARCHAR(255) NOT NULL,
+    file_key TEXT NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    sha256 CHAR(64) NOT NULL,
+    description VARCHAR(500),
+    roles TEXT[] NOT NULL DEFAULT '{}',
+    uploaded_by VARCHAR(50),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_order_attachments_order ON order_attachments(order_id, created_at);
@@ -0,0 +1,20 @@
+-- customs data declared on international shipments
+ALTER TABLE products ADD COLUMN hs_code VARCHAR(10);
+ALTER TABLE products ADD COLUMN country_of_origin CHAR(2);
+
+-- what a shipment declares to customs, copied from the order when it is created
+CREATE TABLE shipment_customs_items (
+    shipment_id UUID NOT NULL REFERENCES shipments(id) ON DELETE CASCADE,
+    line INT NOT NULL,
+    product_id UUID,
+    sku VARCHAR(64),
+    description VARCHAR(255) NOT NULL,
+    quantity INT NOT NULL,
+    unit_value NUMERIC(18,4) NOT NULL,
+    currency CHAR(3) NOT NULL,
+    hs_code VARCHAR(10),
+    country_of_origin CHAR(2),
+    PRIMARY KEY (shipment_id, line)
+);
+
+ALTER TABLE shipments ADD COLUMN origin_country CHAR(2);
@@ -0,0 +1,45 @@
+-- B2B quotations: a priced offer a customer accepts through a link, turning it into an order
+CREATE TABLE quotes (
+    id UUID PRIMARY KEY,
+    customer_id UUID NOT NULL REFERENCES customers(id),
+    status VARCHAR(20) NOT NULL,
+    -- OPEN, ACCEPTED, CANCELLED; an OPEN quote past expires_at is reported EXPIRED
+    currency CHAR(3) NOT NULL,
+    subtotal NUMERIC(18,4) NOT NULL,
+    revision INT NOT NULL DEFAULT 1,
+    note TEXT,
+    expires_at TIMESTAMPTZ NOT NULL,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    order_id UUID REFERENCES orders(id) ON DELETE SET NULL,
+    accepted_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_quotes_customer ON quotes(customer_id, created_at DESC);
+CREATE INDEX idx_quotes_status ON quotes(status, expires_at);
+
+CREATE TABLE quote_items (
+    quote_id UUID NOT NULL REFERENCES quotes(id) ON DELETE CASCADE,
+    line INT NOT NULL,
+    product_id UUID NOT NULL REFERENCES products(id),
+    variant_id UUID,
+    sku VARCHAR(64),
+    name VARCHAR(255),
+    unit_price NUMERIC(18,4) NOT NULL,
+    quantity INT NOT NULL,
+    PRIMARY KEY (quote_id, line)
+);
+
+-- every version of a quote as the customer could have seen it
+CREATE TABLE quote_revisions (
+    quote_id UUID NOT NULL REFERENCES quotes(id) ON DELETE CASCADE,
+    revision INT NOT NULL,
+    items JSONB NOT NULL,
+    subtotal NUMERIC(18,4) NOT NULL,
+    currency CHAR(3) NOT NULL,
+    note TEXT,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (quote_id, revision)
+);
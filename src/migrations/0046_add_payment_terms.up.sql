@@ -0,0 +1,13 @@
+-- B2B orders carry the buyer's purchase order number and the net terms they are invoiced on
+ALTER TABLE orders ADD COLUMN po_number VARCHAR(64);
+ALTER TABLE orders ADD COLUMN payment_terms VARCHAR(10);
+
+-- customers approved to pay on terms, up to a credit limit of unpaid invoices
+CREATE TABLE credit_accounts (
+    customer_id UUID PRIMARY KEY REFERENCES customers(id) ON DELETE CASCADE,
+    payment_terms VARCHAR(10) NOT NULL,
+    credit_limit NUMERIC(18,4) NOT NULL,
+    currency CHAR(3) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
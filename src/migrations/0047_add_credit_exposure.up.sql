@@ -0,0 +1,4 @@
+-- what happens to a terms order that would exceed the credit limit: BLOCK
+-- refuses it, FLAG lets it through with its invoice flagged for finance
+ALTER TABLE credit_accounts ADD COLUMN on_exceed VARCHAR(10) NOT NULL DEFAULT 'BLOCK';
+ALTER TABLE invoices ADD COLUMN over_credit_limit BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,6 @@
+-- pre-order products: orders for them are taken without stock and allocated
+-- once the product is released (release_date passed, or none) and in stock
+ALTER TABLE products ADD COLUMN preorder BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE products ADD COLUMN release_date TIMESTAMPTZ;
+
+CREATE INDEX idx_orders_preorder ON orders(created_at) WHERE status = 'PREORDER';
@@ -0,0 +1,4 @@
+-- stock items whose available count lives in Redis during a flash sale
+ALTER TABLE inventory ADD COLUMN flash_sale BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX idx_inventory_flash_sale ON inventory(warehouse) WHERE flash_sale;
@@ -0,0 +1,8 @@
+-- products, and campaigns while they run, whose checkouts go through the
+-- waiting room
+CREATE TABLE waiting_room_targets (
+    target_type TEXT NOT NULL CHECK (target_type IN ('product', 'campaign')),
+    target_id UUID NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (target_type, target_id)
+);
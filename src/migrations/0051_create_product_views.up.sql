@@ -0,0 +1,6 @@
+-- product page views, flushed in batches; feeds the popularity sort
+CREATE TABLE product_views (
+    product_id UUID PRIMARY KEY REFERENCES products (id) ON DELETE CASCADE,
+    views BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,18 @@
+-- embargoed products stay hidden from channels and checkout until published;
+-- publication schedules flip products and campaigns at a set moment
+ALTER TABLE products ADD COLUMN published BOOLEAN NOT NULL DEFAULT TRUE;
+
+CREATE TABLE publication_schedules (
+    id UUID PRIMARY KEY,
+    target_type TEXT NOT NULL CHECK (target_type IN ('product', 'campaign')),
+    target_id UUID NOT NULL,
+    action TEXT NOT NULL CHECK (action IN ('publish', 'unpublish')),
+    run_at TIMESTAMPTZ NOT NULL,
+    timezone TEXT NOT NULL DEFAULT 'UTC',
+    status TEXT NOT NULL DEFAULT 'PENDING',
+    executed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_publication_schedules_due ON publication_schedules (run_at) WHERE status = 'PENDING';
+CREATE INDEX idx_publication_schedules_target ON publication_schedules (target_type, target_id);
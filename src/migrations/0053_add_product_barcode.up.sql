@@ -0,0 +1,5 @@
+-- GTIN barcodes (EAN-8, UPC-A, EAN-13, GTIN-14) for scanning; codes are
+-- unique once zero-padded to 14 digits, so a UPC-A and its EAN-13 clash
+ALTER TABLE products ADD COLUMN barcode TEXT;
+
+CREATE UNIQUE INDEX products_gtin_key ON products ((lpad(barcode, 14, '0')));
@@ -0,0 +1,4 @@
+-- display order of a category among its siblings, for storefront menus
+ALTER TABLE categories ADD COLUMN position INT NOT NULL DEFAULT 0;
+
+CREATE INDEX idx_categories_parent_position ON categories (parent_id, position) WHERE deleted_at IS NULL;
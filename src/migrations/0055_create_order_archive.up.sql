@@ -0,0 +1,23 @@
+-- cold storage for finished orders; the archive tables mirror orders and
+-- order_items column for column, so a column added to one must be added to
+-- its archive too
+CREATE TABLE orders_archive (LIKE orders INCLUDING DEFAULTS INCLUDING CONSTRAINTS);
+ALTER TABLE orders_archive ADD PRIMARY KEY (id);
+CREATE INDEX idx_orders_archive_customer ON orders_archive(customer_id, created_at);
+
+CREATE TABLE order_items_archive (LIKE order_items INCLUDING DEFAULTS INCLUDING CONSTRAINTS);
+ALTER TABLE order_items_archive ADD PRIMARY KEY (id);
+CREATE INDEX idx_order_items_archive_order ON order_items_archive(order_id);
+
+-- invoices, payments, events and the rest outlive an archived order, so
+-- they no longer reference orders (orders are never deleted otherwise)
+DO $$
+DECLARE
+    c record;
+BEGIN
+    FOR c IN SELECT conrelid::regclass AS tbl, conname FROM pg_constraint
+             WHERE contype = 'f' AND confrelid = 'orders'::regclass AND conrelid <> 'order_items'::regclass
+    LOOP
+        EXECUTE format('ALTER TABLE %s DROP CONSTRAINT %I', c.tbl, c.conname);
+    END LOOP;
+END $$;
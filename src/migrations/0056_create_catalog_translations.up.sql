@@ -0,0 +1,24 @@
+-- product and category names, descriptions and slugs per locale (BCP 47)
+CREATE TABLE product_translations (
+    product_id UUID NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+    locale VARCHAR(35) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    slug VARCHAR(100),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (product_id, locale)
+);
+
+CREATE UNIQUE INDEX product_translations_slug_key ON product_translations (locale, slug) WHERE slug IS NOT NULL;
+
+CREATE TABLE category_translations (
+    category_id UUID NOT NULL REFERENCES categories (id) ON DELETE CASCADE,
+    locale VARCHAR(35) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    slug VARCHAR(100),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (category_id, locale)
+);
+
+CREATE UNIQUE INDEX category_translations_slug_key ON category_translations (locale, slug) WHERE slug IS NOT NULL;
@@ -0,0 +1,4 @@
+-- order items are looked up by order and, for recalls, by SKU or product
+CREATE INDEX IF NOT EXISTS idx_order_items_order ON order_items (order_id);
+CREATE INDEX IF NOT EXISTS idx_order_items_sku ON order_items (sku);
+CREATE INDEX IF NOT EXISTS idx_order_items_product ON order_items (product_id);
@@ -0,0 +1,9 @@
+-- labels set on orders in bulk, such as the orders hit by a recall. No
+-- foreign key to orders, so tags outlive archiving like the timeline does.
+CREATE TABLE order_tags (
+    order_id UUID NOT NULL,
+    tag VARCHAR(64) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (order_id, tag)
+);
+CREATE INDEX idx_order_tags_tag ON order_tags (tag);
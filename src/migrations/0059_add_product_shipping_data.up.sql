@@ -0,0 +1,8 @@
+-- physical data shipping is priced on; products without it are charged the
+-- flat fallback rate
+ALTER TABLE products
+    ADD COLUMN weight_grams INT CHECK (weight_grams > 0),
+    ADD COLUMN length_mm INT CHECK (length_mm > 0),
+    ADD COLUMN width_mm INT CHECK (width_mm > 0),
+    ADD COLUMN height_mm INT CHECK (height_mm > 0),
+    ADD COLUMN shipping_class VARCHAR(32);
@@ -0,0 +1,2 @@
+-- category lists count the live, published products under each category
+CREATE INDEX idx_products_category_live ON products (category_id) WHERE deleted_at IS NULL AND published;
@@ -0,0 +1,2 @@
+-- the notification rate limit counts what each recipient was recently sent
+CREATE INDEX idx_notification_log_recipient ON notification_log (channel, recipient, created_at);
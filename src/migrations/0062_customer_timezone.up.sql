@@ -0,0 +1,2 @@
+-- IANA zone a customer's times are shown in; NULL uses the tenant's
+ALTER TABLE customers ADD COLUMN timezone VARCHAR(64);
@@ -0,0 +1,33 @@
+-- shopping carts ahead of checkout; guest carts have no customer until they are merged on login
+CREATE TABLE carts (
+    id UUID PRIMARY KEY,
+    customer_id UUID REFERENCES customers(id),
+    status VARCHAR(20) NOT NULL,
+    -- ACTIVE, MERGED, CHECKED_OUT, EXPIRED
+    merged_into UUID REFERENCES carts(id),
+    order_id UUID REFERENCES orders(id) ON DELETE SET NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- a customer has one active cart
+CREATE UNIQUE INDEX idx_carts_customer_active ON carts(customer_id) WHERE status = 'ACTIVE';
+CREATE INDEX idx_carts_expiry ON carts(expires_at) WHERE status = 'ACTIVE';
+
+-- unit_price, sku and name are snapshots taken when the line was added or repriced
+CREATE TABLE cart_items (
+    id UUID PRIMARY KEY,
+    cart_id UUID NOT NULL REFERENCES carts(id) ON DELETE CASCADE,
+    product_id UUID NOT NULL REFERENCES products(id),
+    variant_id UUID,
+    sku VARCHAR(64),
+    name VARCHAR(255),
+    unit_price NUMERIC(18,4) NOT NULL,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    priced_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- one line per product and variant
+CREATE UNIQUE INDEX idx_cart_items_product ON cart_items(cart_id, product_id, (COALESCE(variant_id, '00000000-0000-0000-0000-000000000000')));
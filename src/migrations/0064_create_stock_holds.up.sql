@@ -0,0 +1,22 @@
+-- stock reserved for a checkout before its order is placed; the reservation itself is in inventory.reserved
+CREATE TABLE stock_holds (
+    id UUID PRIMARY KEY,
+    reference VARCHAR(100),
+    warehouse VARCHAR(50) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    -- ACTIVE, CONVERTED, RELEASED, EXPIRED
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_stock_holds_expiry ON stock_holds(expires_at) WHERE status = 'ACTIVE';
+
+CREATE TABLE stock_hold_items (
+    hold_id UUID NOT NULL REFERENCES stock_holds(id) ON DELETE CASCADE,
+    product_id UUID NOT NULL REFERENCES products(id),
+    variant_id UUID,
+    quantity INT NOT NULL CHECK (quantity > 0)
+);
+
+CREATE INDEX idx_stock_hold_items_hold ON stock_hold_items(hold_id);
@@ -0,0 +1,21 @@
+-- periods a rental or service can be ordered for; booked counts the units of
+-- placed orders, which can't exceed the slot's capacity
+CREATE TABLE booking_slots (
+    id UUID PRIMARY KEY,
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    starts_at TIMESTAMPTZ NOT NULL,
+    ends_at TIMESTAMPTZ NOT NULL,
+    capacity INT NOT NULL CHECK (capacity > 0),
+    booked INT NOT NULL DEFAULT 0 CHECK (booked >= 0 AND booked <= capacity),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (ends_at > starts_at),
+    UNIQUE (product_id, starts_at)
+);
+
+CREATE INDEX idx_booking_slots_product_end ON booking_slots(product_id, ends_at);
+
+-- the slot an item books; no foreign key, since an unbooked slot may be
+-- deleted while its cancelled orders remain
+ALTER TABLE order_items ADD COLUMN slot_id UUID;
+ALTER TABLE order_items_archive ADD COLUMN slot_id UUID;
@@ -0,0 +1,20 @@
+-- shoppers waiting to hear that an out-of-stock product or variant is back;
+-- recipient is encrypted like customer contact details when PII keys are
+-- set (key_id names the key), and recipient_index matches it either way
+CREATE TABLE restock_registrations (
+    id UUID PRIMARY KEY,
+    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    variant_id UUID REFERENCES product_variants(id) ON DELETE CASCADE,
+    channel VARCHAR(10) NOT NULL,
+    recipient TEXT NOT NULL,
+    recipient_index TEXT NOT NULL,
+    key_id VARCHAR(50),
+    status VARCHAR(20) NOT NULL DEFAULT 'WAITING',
+    -- WAITING, NOTIFIED
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    notified_at TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX idx_restock_registrations_waiting ON restock_registrations
+    (product_id, COALESCE(variant_id, product_id), channel, recipient_index) WHERE status = 'WAITING';
+CREATE INDEX idx_restock_registrations_due ON restock_registrations(created_at) WHERE status = 'WAITING';
@@ -0,0 +1,11 @@
+-- carrier tracking of shipments: the public tracking page and the latest
+-- progress the carrier reported, polled or pushed to its webhook
+ALTER TABLE shipments ADD COLUMN tracking_url VARCHAR(512);
+ALTER TABLE shipments ADD COLUMN tracking_status VARCHAR(30);
+ALTER TABLE shipments ADD COLUMN tracking_detail VARCHAR(255);
+ALTER TABLE shipments ADD COLUMN tracked_at TIMESTAMPTZ;
+ALTER TABLE shipments ADD COLUMN delivered_at TIMESTAMPTZ;
+
+CREATE INDEX idx_shipments_in_transit ON shipments(tracked_at NULLS FIRST) WHERE status IN ('LABEL_PURCHASED', 'SHIPPED');
+DROP INDEX idx_shipments_tracking;
+CREATE INDEX idx_shipments_tracking ON shipments(carrier, tracking_number);
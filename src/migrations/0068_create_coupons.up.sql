@@ -0,0 +1,62 @@
+-- codes shoppers enter at checkout for money off; a coupon with no products
+-- or categories applies to every item
+CREATE TABLE coupons (
+    id UUID PRIMARY KEY,
+    code VARCHAR(40) NOT NULL UNIQUE,
+    type VARCHAR(10) NOT NULL CHECK (type IN ('PERCENT', 'FIXED')),
+    value NUMERIC(18,4) NOT NULL CHECK (value > 0),
+    currency CHAR(3),
+    max_uses INT CHECK (max_uses > 0),
+    max_uses_per_customer INT CHECK (max_uses_per_customer > 0),
+    uses INT NOT NULL DEFAULT 0 CHECK (uses >= 0),
+    starts_at TIMESTAMPTZ,
+    ends_at TIMESTAMPTZ,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (type <> 'PERCENT' OR value <= 100),
+    CHECK (type <> 'FIXED' OR currency IS NOT NULL),
+    CHECK (ends_at > starts_at)
+);
+
+CREATE TABLE coupon_products (
+    coupon_id UUID NOT NULL REFERENCES coupons (id) ON DELETE CASCADE,
+    product_id UUID NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+    PRIMARY KEY (coupon_id, product_id)
+);
+
+CREATE TABLE coupon_categories (
+    coupon_id UUID NOT NULL REFERENCES coupons (id) ON DELETE CASCADE,
+    category_id UUID NOT NULL REFERENCES categories (id) ON DELETE CASCADE,
+    PRIMARY KEY (coupon_id, category_id)
+);
+
+-- one row per order using a coupon, counted against its limits; removed
+-- when the order is cancelled. No foreign key to orders, which are archived.
+CREATE TABLE coupon_redemptions (
+    coupon_id UUID NOT NULL REFERENCES coupons (id) ON DELETE CASCADE,
+    order_id UUID NOT NULL,
+    customer_id UUID,
+    amount NUMERIC(18,4) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (coupon_id, order_id)
+);
+CREATE INDEX idx_coupon_redemptions_customer ON coupon_redemptions(coupon_id, customer_id);
+CREATE INDEX idx_coupon_redemptions_order ON coupon_redemptions(order_id);
+
+-- what the order's discounts took off its subtotal; total is net of it
+ALTER TABLE orders ADD COLUMN discount NUMERIC(18,4) NOT NULL DEFAULT 0;
+ALTER TABLE orders_archive ADD COLUMN discount NUMERIC(18,4) NOT NULL DEFAULT 0;
+
+-- the discount lines of an order as they were applied, kept when the
+-- coupon changes or is deleted
+CREATE TABLE order_discounts (
+    id UUID PRIMARY KEY,
+    order_id UUID NOT NULL,
+    coupon_id UUID,
+    code VARCHAR(40) NOT NULL,
+    description VARCHAR(255) NOT NULL,
+    amount NUMERIC(18,4) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_order_discounts_order ON order_discounts(order_id);
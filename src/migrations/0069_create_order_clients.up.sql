@@ -0,0 +1,13 @@
+-- where each order was placed from, for fraud analysis and support. Kept
+-- apart from orders so only the roles allowed to see it read it; no foreign
+-- key, since orders are archived.
+CREATE TABLE order_clients (
+    order_id UUID PRIMARY KEY,
+    ip VARCHAR(45),
+    user_agent VARCHAR(512),
+    device_id VARCHAR(128),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_order_clients_ip ON order_clients(ip, created_at) WHERE ip IS NOT NULL;
+CREATE INDEX idx_order_clients_device ON order_clients(device_id, created_at) WHERE device_id IS NOT NULL;
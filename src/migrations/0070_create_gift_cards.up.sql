@@ -0,0 +1,34 @@
+-- prepaid cards spent at checkout; the code is a bearer secret, so only its
+-- hash and last four characters are kept
+CREATE TABLE gift_cards (
+    id UUID PRIMARY KEY,
+    code_hash CHAR(64) NOT NULL UNIQUE,
+    last4 CHAR(4) NOT NULL,
+    currency CHAR(3) NOT NULL,
+    initial_balance NUMERIC(18,4) NOT NULL CHECK (initial_balance > 0),
+    balance NUMERIC(18,4) NOT NULL CHECK (balance >= 0),
+    status VARCHAR(10) NOT NULL DEFAULT 'ACTIVE' CHECK (status IN ('ACTIVE', 'DISABLED')),
+    expires_at TIMESTAMPTZ,
+    note VARCHAR(255),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_gift_cards_created ON gift_cards(created_at);
+
+-- every change to a card's balance; amount is negative for debits. No
+-- foreign key to orders, which are archived.
+CREATE TABLE gift_card_transactions (
+    id UUID PRIMARY KEY,
+    gift_card_id UUID NOT NULL REFERENCES gift_cards (id) ON DELETE CASCADE,
+    type VARCHAR(10) NOT NULL CHECK (type IN ('ISSUE', 'REDEEM', 'RELEASE', 'ADJUST')),
+    amount NUMERIC(18,4) NOT NULL,
+    balance_after NUMERIC(18,4) NOT NULL,
+    order_id UUID,
+    reference VARCHAR(255),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_gift_card_transactions_card ON gift_card_transactions(gift_card_id, created_at);
+CREATE INDEX idx_gift_card_transactions_order ON gift_card_transactions(order_id) WHERE order_id IS NOT NULL;
+
+-- a gift card paying part of an order shows as one of its discount lines
+ALTER TABLE order_discounts ADD COLUMN gift_card_id UUID;
@@ -0,0 +1,12 @@
+-- marketplace orders a connector sync could not import, with the order as
+-- the marketplace reported it, retried on every sync until they import
+CREATE TABLE connector_failed_orders (
+    connector VARCHAR(50) NOT NULL,
+    reference VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL,
+    error TEXT NOT NULL,
+    attempts INT NOT NULL DEFAULT 1,
+    first_failed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_failed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (connector, reference)
+);